@@ -0,0 +1,90 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// actionRoutes maps each ISO action to the request that performs it.
+var actionRoutes = map[string]models.ActionRoute{
+	models.ActionRetry:  {Method: http.MethodPost, Path: "/api/isos/{id}/retry"},
+	models.ActionCancel: {Method: http.MethodPost, Path: "/api/isos/{id}/cancel"},
+	models.ActionVerify: {Method: http.MethodPost, Path: "/api/isos/{id}/verify"},
+	models.ActionShare:  {Method: http.MethodPost, Path: "/api/download-links", Body: `{"path":"{file_path}"}`},
+	models.ActionDelete: {Method: http.MethodDelete, Path: "/api/isos/{id}"},
+}
+
+// allowedActions returns the actions of status that role may take here. A
+// guest may take none, and a replica none that ReadOnlyReplicaMiddleware
+// would refuse.
+func (h *Handlers) allowedActions(role string, status models.ISOStatus) []string {
+	actions := []string{}
+	if role != models.ActionRoleOperator {
+		return actions
+	}
+	for _, action := range status.Actions() {
+		route := actionRoutes[action]
+		if h.readOnly && replicaBlockedRoutes[route.Method+" "+strings.ReplaceAll(route.Path, "{id}", ":id")] {
+			continue
+		}
+		actions = append(actions, action)
+	}
+	return actions
+}
+
+// ListActions returns the actions the caller can take on each ISO, given its
+// status, the caller's role, and whether this instance is a read-only
+// replica, so clients don't have to repeat those rules.
+// Query params: ids (comma-separated; default all ISOs)
+func (h *Handlers) ListActions(c *gin.Context) {
+	role := models.ActionRoleOperator
+	if h.authEnabled && CurrentUser(c) == nil {
+		role = models.ActionRoleGuest
+	}
+
+	var isos []models.ISO
+	if ids := c.Query("ids"); ids != "" {
+		for _, id := range strings.Split(ids, ",") {
+			if id = strings.TrimSpace(id); id == "" {
+				continue
+			}
+			iso, err := h.isoService.GetISO(id)
+			if errors.Is(err, db.ErrISONotFound) {
+				ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "ISO not found: "+id)
+				return
+			}
+			if err != nil {
+				ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to retrieve ISO")
+				return
+			}
+			isos = append(isos, *iso)
+		}
+	} else {
+		var err error
+		if isos, err = h.isoService.ListISOs(); err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to list ISOs")
+			return
+		}
+	}
+
+	set := &models.ActionSet{
+		Role:     role,
+		ReadOnly: h.readOnly,
+		Routes:   make(map[string]models.ActionRoute),
+		ISOs:     make([]models.ISOActions, 0, len(isos)),
+	}
+	for _, iso := range isos {
+		actions := h.allowedActions(role, iso.Status)
+		for _, action := range actions {
+			set.Routes[action] = actionRoutes[action]
+		}
+		set.ISOs = append(set.ISOs, models.ISOActions{ID: iso.ID, Status: iso.Status, Actions: actions})
+	}
+	SuccessResponse(c, http.StatusOK, set)
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestListActions(t *testing.T) {
+	handlers, database, _, _, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	ids := make(map[models.ISOStatus]string)
+	for _, status := range []models.ISOStatus{models.StatusFailed, models.StatusQueued, models.StatusDownloading, models.StatusComplete} {
+		iso := &models.ISO{
+			ID:          uuid.New().String(),
+			Name:        "test-" + string(status),
+			Version:     "1.0",
+			Arch:        "x86_64",
+			FileType:    "iso",
+			DownloadURL: "http://example.com/" + string(status) + ".iso",
+			Status:      status,
+			CreatedAt:   time.Now(),
+		}
+		iso.ComputeFields()
+		if err := database.CreateISO(iso); err != nil {
+			t.Fatalf("CreateISO() failed: %v", err)
+		}
+		ids[iso.Status] = iso.ID
+	}
+
+	router := gin.New()
+	router.GET("/api/actions", handlers.ListActions)
+	listActions := func(query string) map[string]string {
+		t.Helper()
+		w := doCredentialRequest(router, http.MethodGet, "/api/actions"+query, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got: %d (%s)", w.Code, w.Body.String())
+		}
+		data := parseAPIResponse(t, w.Body.Bytes()).Data.(map[string]any)
+		got := map[string]string{"role": data["role"].(string)}
+		for _, entry := range data["isos"].([]any) {
+			entry := entry.(map[string]any)
+			var actions []string
+			for _, action := range entry["actions"].([]any) {
+				actions = append(actions, action.(string))
+			}
+			got[entry["id"].(string)] = strings.Join(actions, ",")
+		}
+		return got
+	}
+
+	got := listActions("")
+	want := map[string]string{
+		"role":                        models.ActionRoleOperator,
+		ids[models.StatusFailed]:      "retry,delete",
+		ids[models.StatusQueued]:      "cancel,delete",
+		ids[models.StatusDownloading]: "cancel,delete",
+		ids[models.StatusComplete]:    "verify,share,delete",
+	}
+	for key, actions := range want {
+		if got[key] != actions {
+			t.Errorf("Actions of %s = %q, want %q", key, got[key], actions)
+		}
+	}
+
+	if got := listActions("?ids=" + ids[models.StatusComplete]); len(got) != 2 {
+		t.Errorf("Expected only the requested ISO, got: %v", got)
+	}
+	if w := doCredentialRequest(router, http.MethodGet, "/api/actions?ids=missing", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown ISO, got: %d", w.Code)
+	}
+
+	handlers.SetAccessConfig(false, true)
+	got = listActions("")
+	if got[ids[models.StatusFailed]] != "" || got[ids[models.StatusComplete]] != "verify,share" {
+		t.Errorf("Expected a replica to offer only verify and share, got: %v", got)
+	}
+
+	handlers.SetAccessConfig(true, false)
+	got = listActions("")
+	if got["role"] != models.ActionRoleGuest || got[ids[models.StatusComplete]] != "" {
+		t.Errorf("Expected a guest to get no actions, got: %v", got)
+	}
+}
+
+func TestCancelISONotRunning(t *testing.T) {
+	handlers, database, _, _, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "test",
+		Version:     "1.0",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: "http://example.com/test.iso",
+		Status:      models.StatusComplete,
+		CreatedAt:   time.Now(),
+	}
+	iso.ComputeFields()
+	if err := database.CreateISO(iso); err != nil {
+		t.Fatalf("CreateISO() failed: %v", err)
+	}
+
+	router := gin.New()
+	router.POST("/api/isos/:id/cancel", handlers.CancelISO)
+	if w := doCredentialRequest(router, http.MethodPost, "/api/isos/"+iso.ID+"/cancel", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a complete ISO, got: %d", w.Code)
+	}
+	if w := doCredentialRequest(router, http.MethodPost, "/api/isos/missing/cancel", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown ISO, got: %d", w.Code)
+	}
+}
+
+func TestCancelISOWithoutWorker(t *testing.T) {
+	handlers, database, _, _, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	// Left downloading by an earlier run; no worker here holds it
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "test",
+		Version:     "1.0",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: "http://example.com/test.iso",
+		Status:      models.StatusDownloading,
+		CreatedAt:   time.Now(),
+	}
+	iso.ComputeFields()
+	if err := database.CreateISO(iso); err != nil {
+		t.Fatalf("CreateISO() failed: %v", err)
+	}
+
+	router := gin.New()
+	router.POST("/api/isos/:id/cancel", handlers.CancelISO)
+	w := doCredentialRequest(router, http.MethodPost, "/api/isos/"+iso.ID+"/cancel", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"status":"canceled"`) {
+		t.Errorf("Expected the ISO canceled, got: %d (%s)", w.Code, w.Body.String())
+	}
+	if got, _ := database.GetISO(iso.ID); got.Status != models.StatusCanceled {
+		t.Errorf("Stored status = %s, want canceled", got.Status)
+	}
+}
+
+func TestListActionsDatabaseError(t *testing.T) {
+	handlers, database, _, _, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	router := gin.New()
+	router.GET("/api/actions", handlers.ListActions)
+	database.Close()
+
+	if w := doCredentialRequest(router, http.MethodGet, "/api/actions?ids="+uuid.New().String(), ""); w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500 when the database fails, got: %d", w.Code)
+	}
+}
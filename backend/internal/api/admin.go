@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+
+	"github.com/aloks98/isoman/backend/internal/config"
+	"github.com/aloks98/isoman/backend/internal/diag"
+	"github.com/aloks98/isoman/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupAdminRoutes configures the admin listener on ADMIN_ADDR: the
+// net/http/pprof profiles and a runtime snapshot, for diagnosing stuck
+// workers and leaked goroutines in production. They are never served on the
+// public port, and need a session like /api when AUTH_ENABLED is set.
+// authService must be the one SetupRoutes was given, so sessions revoked
+// through /api are revoked here too and failed logins share one lockout.
+func SetupAdminRoutes(authService *service.AuthService, cfg *config.Config) *gin.Engine {
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.Use(RequestIDMiddleware())
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		_ = router.SetTrustedProxies(nil) // Already logged by SetupRoutes
+	}
+
+	debug := router.Group("/debug")
+	if cfg.Auth.Enabled {
+		debug.Use(RequireAuthMiddleware(authService))
+	}
+	debug.Use(CSRFMiddleware())
+	{
+		debug.GET("/runtime", GetRuntimeSnapshot)
+
+		// pprof.Index serves the named profiles (goroutine, heap, allocs,
+		// block, mutex, threadcreate) under its own path
+		debug.GET("/pprof/", gin.WrapF(pprof.Index))
+		debug.GET("/pprof/cmdline", gin.WrapF(pprof.Cmdline))
+		debug.GET("/pprof/profile", gin.WrapF(pprof.Profile))
+		debug.GET("/pprof/symbol", gin.WrapF(pprof.Symbol))
+		debug.POST("/pprof/symbol", gin.WrapF(pprof.Symbol))
+		debug.GET("/pprof/trace", gin.WrapF(pprof.Trace))
+		debug.GET("/pprof/:profile", gin.WrapF(pprof.Index))
+	}
+
+	return router
+}
+
+// GetRuntimeSnapshot returns the goroutines, grouped by where they are
+// blocked, and memory statistics. ?gc=true collects garbage first, so the
+// heap figures count only live objects.
+func GetRuntimeSnapshot(c *gin.Context) {
+	if c.Query("gc") == "true" {
+		runtime.GC()
+	}
+	SuccessResponse(c, http.StatusOK, diag.Snapshot())
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/service"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+)
+
+func TestSetupAdminRoutes(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	router := SetupAdminRoutes(service.NewAuthService(env.DB, time.Hour), env.Config)
+	get := func(path, bearer string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, http.NoBody)
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/debug/runtime?gc=true", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got: %d (%s)", w.Code, w.Body.String())
+	}
+	var response struct {
+		Data models.RuntimeSnapshot `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Data.Goroutines == 0 || len(response.Data.GoroutineGroups) == 0 || response.Data.Heap.LastGC == nil {
+		t.Errorf("Expected goroutines and a collection, got: %+v", response.Data)
+	}
+
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/cmdline", "/debug/pprof/heap", "/debug/pprof/goroutine?debug=1"} {
+		if w := get(path, ""); w.Code != http.StatusOK {
+			t.Errorf("GET %s = %d, want 200", path, w.Code)
+		}
+	}
+	if w := get("/debug/pprof/goroutine?debug=1", ""); !strings.Contains(w.Body.String(), "goroutine profile:") {
+		t.Errorf("Expected a goroutine profile, got: %q", w.Body.String())
+	}
+	if w := get("/api/isos", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected the public API to be absent from the admin listener, got: %d", w.Code)
+	}
+}
+
+func TestSetupAdminRoutes_Auth(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	env.Config.Auth.Enabled = true
+
+	authService := service.NewAuthService(env.DB, time.Hour)
+	if _, err := authService.CreateUser("admin", "correct horse"); err != nil {
+		t.Fatalf("CreateUser() failed: %v", err)
+	}
+	login, err := authService.Login("admin", "correct horse", "", "127.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("Login() failed: %v", err)
+	}
+
+	router := SetupAdminRoutes(authService, env.Config)
+	for _, path := range []string{"/debug/runtime", "/debug/pprof/", "/debug/pprof/heap"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, http.NoBody))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("GET %s without a session = %d, want 401", path, w.Code)
+		}
+
+		w = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, http.NoBody)
+		req.Header.Set("Authorization", "Bearer "+login.Token)
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s with a session = %d, want 200", path, w.Code)
+		}
+	}
+
+	// Signing out through /api revokes the session here at once, without
+	// waiting for a separate session cache to expire
+	if err := authService.Logout(login.Token); err != nil {
+		t.Fatalf("Logout() failed: %v", err)
+	}
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/debug/runtime", http.NoBody)
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("GET /debug/runtime after logout = %d, want 401", w.Code)
+	}
+}
@@ -0,0 +1,312 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionCookieName is the cookie carrying the session token for the web UI.
+const SessionCookieName = "isoman_session"
+
+// contextUserKey stores the signed-in *models.User in the gin context.
+const contextUserKey = "user"
+
+// AuthHandlers holds references to the auth service.
+type AuthHandlers struct {
+	authService  *service.AuthService
+	cookieSecure bool
+}
+
+// NewAuthHandlers creates a new AuthHandlers instance. cookieSecure marks the
+// session cookie Secure so browsers only send it over HTTPS.
+func NewAuthHandlers(authService *service.AuthService, cookieSecure bool) *AuthHandlers {
+	return &AuthHandlers{
+		authService:  authService,
+		cookieSecure: cookieSecure,
+	}
+}
+
+// Login checks a username and password, sets the session cookie, and returns
+// the session token for clients that don't keep cookies.
+func (h *AuthHandlers) Login(c *gin.Context) {
+	var req models.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+		return
+	}
+
+	resp, err := h.authService.Login(req.Username, req.Password, req.Code, c.ClientIP(), c.Request.UserAgent())
+	if errors.Is(err, service.ErrInvalidLogin) {
+		ErrorResponse(c, http.StatusUnauthorized, ErrCodeUnauthorized, "Invalid username or password")
+		return
+	}
+	if errors.Is(err, service.ErrTOTPRequired) {
+		ErrorResponse(c, http.StatusUnauthorized, ErrCodeTOTPRequired, "Two-factor code required")
+		return
+	}
+	var throttledErr *service.ThrottledError
+	if errors.As(err, &throttledErr) {
+		throttledResponse(c, throttledErr)
+		return
+	}
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to sign in")
+		return
+	}
+
+	h.setSessionCookies(c, resp.Token, resp.CSRFToken, int(h.authService.SessionTTL().Seconds()))
+	SuccessResponse(c, http.StatusOK, resp)
+}
+
+// Logout ends the current session and clears the session cookie.
+func (h *AuthHandlers) Logout(c *gin.Context) {
+	if err := h.authService.Logout(sessionToken(c)); err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to sign out")
+		return
+	}
+
+	h.setSessionCookies(c, "", "", -1)
+	SuccessResponseWithMessage(c, http.StatusOK, nil, "Signed out")
+}
+
+// Me returns the signed-in user.
+func (h *AuthHandlers) Me(c *gin.Context) {
+	user, ok := h.user(c)
+	if !ok {
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, user)
+}
+
+// BeginTOTP starts two-factor enrollment and returns the new secret.
+func (h *AuthHandlers) BeginTOTP(c *gin.Context) {
+	user, ok := h.user(c)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.authService.BeginTOTP(user.ID)
+	if err != nil {
+		totpErrorResponse(c, err, "Failed to start two-factor enrollment")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, enrollment)
+}
+
+// ConfirmTOTP enables two-factor authentication and returns recovery codes.
+func (h *AuthHandlers) ConfirmTOTP(c *gin.Context) {
+	user, ok := h.user(c)
+	if !ok {
+		return
+	}
+	var req models.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+		return
+	}
+
+	codes, err := h.authService.ConfirmTOTP(user.ID, req.Code)
+	if err != nil {
+		totpErrorResponse(c, err, "Failed to enable two-factor authentication")
+		return
+	}
+
+	SuccessResponseWithMessage(c, http.StatusOK, codes, "Two-factor authentication enabled")
+}
+
+// DisableTOTP turns two-factor authentication off.
+func (h *AuthHandlers) DisableTOTP(c *gin.Context) {
+	user, ok := h.user(c)
+	if !ok {
+		return
+	}
+	var req models.DisableTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+		return
+	}
+
+	if err := h.authService.DisableTOTP(user.ID, req.Password, req.Code); err != nil {
+		totpErrorResponse(c, err, "Failed to disable two-factor authentication")
+		return
+	}
+
+	SuccessResponseWithMessage(c, http.StatusOK, nil, "Two-factor authentication disabled")
+}
+
+// RegenerateRecoveryCodes replaces the signed-in user's recovery codes.
+func (h *AuthHandlers) RegenerateRecoveryCodes(c *gin.Context) {
+	user, ok := h.user(c)
+	if !ok {
+		return
+	}
+	var req models.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(user.ID, req.Code)
+	if err != nil {
+		totpErrorResponse(c, err, "Failed to regenerate recovery codes")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, codes)
+}
+
+// ListSessions returns every active session, marking the caller's own.
+func (h *AuthHandlers) ListSessions(c *gin.Context) {
+	sessions, err := h.authService.ListSessions(sessionToken(c))
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to retrieve sessions")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, sessions)
+}
+
+// RevokeSession ends a session by ID.
+func (h *AuthHandlers) RevokeSession(c *gin.Context) {
+	err := h.authService.RevokeSession(c.Param("id"))
+	if errors.Is(err, db.ErrSessionNotFound) {
+		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "Session not found")
+		return
+	}
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to revoke session")
+		return
+	}
+
+	SuccessResponseWithMessage(c, http.StatusOK, nil, "Session revoked")
+}
+
+// RevokeSessions ends every session except the caller's, optionally limited
+// to one user with ?user_id=.
+func (h *AuthHandlers) RevokeSessions(c *gin.Context) {
+	n, err := h.authService.RevokeSessions(c.Query("user_id"), sessionToken(c))
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to revoke sessions")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, gin.H{"revoked": n})
+}
+
+// user returns the signed-in user, authenticating the request itself when
+// RequireAuthMiddleware didn't run. It writes a 401 and returns false when
+// there is no valid session.
+func (h *AuthHandlers) user(c *gin.Context) (*models.User, bool) {
+	if user := CurrentUser(c); user != nil {
+		return user, true
+	}
+	user, _, err := h.authService.AuthenticateFrom(sessionToken(c), c.ClientIP())
+	if err != nil {
+		authErrorResponse(c, err)
+		return nil, false
+	}
+	return user, true
+}
+
+// setSessionCookies sets the HttpOnly session cookie and the script-readable
+// CSRF cookie, or clears both with a negative maxAge.
+func (h *AuthHandlers) setSessionCookies(c *gin.Context, token, csrfToken string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(SessionCookieName, token, maxAge, "/", "", h.cookieSecure, true)
+	c.SetCookie(CSRFCookieName, csrfToken, maxAge, "/", "", h.cookieSecure, false)
+}
+
+// clearSessionCookies expires the session and CSRF cookies.
+func clearSessionCookies(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(SessionCookieName, "", -1, "/", "", c.Request.TLS != nil, true)
+	c.SetCookie(CSRFCookieName, "", -1, "/", "", c.Request.TLS != nil, false)
+}
+
+// RequireAuthMiddleware rejects requests without a valid session token, sent
+// either as the session cookie or as an "Authorization: Bearer" header, and
+// stores the signed-in user for CurrentUser. A session cookie that is refused
+// is cleared, so the browser stops sending a revoked or expired session.
+func RequireAuthMiddleware(authService *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := sessionToken(c)
+		user, _, err := authService.AuthenticateFrom(token, c.ClientIP())
+		if err != nil {
+			if cookie, _ := c.Cookie(SessionCookieName); cookie != "" && cookie == token && errors.Is(err, service.ErrUnauthenticated) {
+				clearSessionCookies(c)
+			}
+			authErrorResponse(c, err)
+			c.Abort()
+			return
+		}
+
+		c.Set(contextUserKey, user)
+		c.Next()
+	}
+}
+
+// CurrentUser returns the user stored by RequireAuthMiddleware, or nil when
+// authentication is disabled.
+func CurrentUser(c *gin.Context) *models.User {
+	if v, ok := c.Get(contextUserKey); ok {
+		if user, ok := v.(*models.User); ok {
+			return user
+		}
+	}
+	return nil
+}
+
+// sessionToken extracts the session token from the Authorization header or,
+// failing that, the session cookie.
+func sessionToken(c *gin.Context) string {
+	if header := c.GetHeader("Authorization"); header != "" {
+		if scheme, token, ok := strings.Cut(header, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	token, _ := c.Cookie(SessionCookieName)
+	return token
+}
+
+func authErrorResponse(c *gin.Context, err error) {
+	var throttledErr *service.ThrottledError
+	switch {
+	case errors.Is(err, service.ErrUnauthenticated):
+		ErrorResponse(c, http.StatusUnauthorized, ErrCodeUnauthorized, "Authentication required")
+	case errors.As(err, &throttledErr):
+		throttledResponse(c, throttledErr)
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to check session")
+	}
+}
+
+// throttledResponse answers 429 with a Retry-After header in whole seconds.
+func throttledResponse(c *gin.Context, err *service.ThrottledError) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(err.RetryAfter.Seconds()))))
+	ErrorResponse(c, http.StatusTooManyRequests, ErrCodeTooManyAttempts, err.Error())
+}
+
+func totpErrorResponse(c *gin.Context, err error, message string) {
+	var stateErr *service.TOTPStateError
+	switch {
+	case errors.As(err, &stateErr):
+		ErrorResponse(c, http.StatusConflict, ErrCodeConflict, stateErr.Message)
+	case errors.Is(err, service.ErrInvalidTOTPCode):
+		ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid two-factor code")
+	case errors.Is(err, service.ErrInvalidLogin):
+		ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid password")
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, message)
+	}
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/download"
+	"github.com/aloks98/isoman/backend/internal/service"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+	"github.com/aloks98/isoman/backend/internal/ws"
+)
+
+func TestAuthRoutes(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	env.Config.Auth.Enabled = true
+
+	if _, err := service.NewAuthService(env.DB, time.Hour).CreateUser("admin", "correct horse"); err != nil {
+		t.Fatalf("CreateUser() failed: %v", err)
+	}
+
+	manager := download.NewManager(env.DB, env.ISODir, 1)
+	defer manager.Stop()
+	router := setupTestRouter(env, service.NewISOService(env.DB, manager, env.ISODir), ws.NewHub())
+
+	var csrfToken string
+	do := func(method, path, body string, cookie *http.Cookie, bearer string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if cookie != nil {
+			req.AddCookie(cookie)
+			req.Header.Set(CSRFHeader, csrfToken)
+		}
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodGet, "/api/isos", "", nil, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a session, got: %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/images/", "", nil, ""); w.Code != http.StatusOK {
+		t.Errorf("Expected /images to stay public, got: %d", w.Code)
+	}
+
+	if w := do(http.MethodPost, "/api/auth/login", `{"username":"admin","password":"wrong"}`, nil, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a wrong password, got: %d", w.Code)
+	}
+
+	w := do(http.MethodPost, "/api/auth/login", `{"username":"admin","password":"correct horse"}`, nil, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "password") {
+		t.Errorf("Response leaks the password hash: %s", w.Body.String())
+	}
+	var resp struct {
+		Data struct {
+			Token     string `json:"token"`
+			CSRFToken string `json:"csrf_token"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Data.Token == "" {
+		t.Fatalf("Expected a session token, got %s (%v)", w.Body.String(), err)
+	}
+	csrfToken = resp.Data.CSRFToken
+	var cookie *http.Cookie
+	for _, ck := range w.Result().Cookies() {
+		if ck.Name == SessionCookieName {
+			cookie = ck
+		}
+	}
+	if cookie == nil || !cookie.HttpOnly || cookie.Value != resp.Data.Token {
+		t.Fatalf("Expected an HttpOnly session cookie, got %+v", cookie)
+	}
+
+	if w := do(http.MethodGet, "/api/isos", "", cookie, ""); w.Code != http.StatusOK {
+		t.Errorf("Expected 200 with the session cookie, got: %d", w.Code)
+	}
+	w = do(http.MethodGet, "/api/auth/me", "", nil, resp.Data.Token)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"username":"admin"`) {
+		t.Errorf("Expected /api/auth/me to return the user, got: %d %s", w.Code, w.Body.String())
+	}
+
+	w = do(http.MethodGet, "/api/sessions", "", cookie, "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"current":true`) {
+		t.Errorf("Expected the session list to mark the caller, got: %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodDelete, "/api/sessions/missing", "", cookie, ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 revoking an unknown session, got: %d", w.Code)
+	}
+	if w := do(http.MethodDelete, "/api/sessions", "", cookie, ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"revoked":0`) {
+		t.Errorf("Expected bulk revoke to spare the caller, got: %d %s", w.Code, w.Body.String())
+	}
+
+	if w := do(http.MethodPost, "/api/auth/logout", "", cookie, ""); w.Code != http.StatusOK {
+		t.Errorf("Expected 200 on logout, got: %d", w.Code)
+	}
+	w = do(http.MethodGet, "/api/isos", "", cookie, "")
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 after logout, got: %d", w.Code)
+	}
+	cleared := false
+	for _, ck := range w.Result().Cookies() {
+		if ck.Name == SessionCookieName && ck.Value == "" && ck.MaxAge < 0 {
+			cleared = true
+		}
+	}
+	if !cleared {
+		t.Errorf("Expected the dead session cookie to be cleared, got %v", w.Result().Cookies())
+	}
+	if w := do(http.MethodGet, "/api/isos", "", nil, resp.Data.Token); w.Code != http.StatusUnauthorized || len(w.Result().Cookies()) != 0 {
+		t.Errorf("Expected a bearer token to get a 401 without cookies, got: %d %v", w.Code, w.Result().Cookies())
+	}
+}
+
+func TestAuthDisabledLeavesAPIOpen(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	manager := download.NewManager(env.DB, env.ISODir, 1)
+	defer manager.Stop()
+	router := setupTestRouter(env, service.NewISOService(env.DB, manager, env.ISODir), ws.NewHub())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/isos", http.NoBody))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200 with auth disabled, got: %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/auth/me", http.NoBody))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected /api/auth/me to require a session, got: %d", w.Code)
+	}
+}
+
+func TestTOTPLoginRequiresCode(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	authService := service.NewAuthService(env.DB, time.Hour)
+	user, err := authService.CreateUser("admin", "correct horse")
+	if err != nil {
+		t.Fatalf("CreateUser() failed: %v", err)
+	}
+	if err := env.DB.SetUserTOTP(user.ID, "JBSWY3DPEHPK3PXP", true); err != nil {
+		t.Fatalf("SetUserTOTP() failed: %v", err)
+	}
+
+	manager := download.NewManager(env.DB, env.ISODir, 1)
+	defer manager.Stop()
+	router := setupTestRouter(env, service.NewISOService(env.DB, manager, env.ISODir), ws.NewHub())
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(`{"username":"admin","password":"correct horse"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), ErrCodeTOTPRequired) {
+		t.Errorf("Expected 401 %s, got: %d %s", ErrCodeTOTPRequired, w.Code, w.Body.String())
+	}
+}
+
+func TestLoginThrottled(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	env.Config.Auth.MaxFailures = 2
+	env.Config.Auth.Lockout = time.Hour
+
+	manager := download.NewManager(env.DB, env.ISODir, 1)
+	defer manager.Stop()
+	router := setupTestRouter(env, service.NewISOService(env.DB, manager, env.ISODir), ws.NewHub())
+
+	var w *httptest.ResponseRecorder
+	for i := 0; i < 3; i++ {
+		w = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(`{"username":"nobody","password":"wrong"}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+	}
+
+	if w.Code != http.StatusTooManyRequests || !strings.Contains(w.Body.String(), ErrCodeTooManyAttempts) {
+		t.Errorf("Expected 429 %s, got: %d %s", ErrCodeTooManyAttempts, w.Code, w.Body.String())
+	}
+	if w.Header().Get("Retry-After") != "3600" {
+		t.Errorf("Retry-After = %q, want 3600", w.Header().Get("Retry-After"))
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scopeRoutes lists the /api routes each public scope opens, as
+// "METHOD pattern". Only read-only routes belong here.
+var scopeRoutes = map[string][]string{
+	constants.AuthScopeStats: {"GET /api/stats", "GET /api/stats/trends", "GET /api/stats/live"},
+	constants.AuthScopeISOs:  {"GET /api/isos", "GET /api/isos/:id", "GET /api/isos/:id/verification", "GET /api/actions"},
+}
+
+// publicScopeSet returns the valid scopes in scopes as a set, warning about
+// unknown ones.
+func publicScopeSet(scopes []string) map[string]bool {
+	set := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		if !constants.IsValidAuthScope(scope) {
+			slog.Warn("unknown public endpoint scope, ignoring", slog.String("scope", scope))
+			continue
+		}
+		set[strings.ToLower(scope)] = true
+	}
+	return set
+}
+
+// ScopedAuthMiddleware is RequireAuthMiddleware for the /api group, except
+// that routes opened by the public scopes are served without a session. A
+// valid session on those routes is still stored for CurrentUser.
+func ScopedAuthMiddleware(authService *service.AuthService, publicScopes map[string]bool) gin.HandlerFunc {
+	public := make(map[string]bool)
+	for scope := range publicScopes {
+		for _, route := range scopeRoutes[scope] {
+			public[route] = true
+		}
+	}
+	requireAuth := RequireAuthMiddleware(authService)
+
+	return func(c *gin.Context) {
+		if !public[c.Request.Method+" "+c.FullPath()] {
+			requireAuth(c)
+			return
+		}
+
+		if token := sessionToken(c); token != "" {
+			if user, _, err := authService.Authenticate(token); err == nil {
+				c.Set(contextUserKey, user)
+			}
+		}
+		c.Next()
+	}
+}
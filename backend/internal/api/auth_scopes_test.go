@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/download"
+	"github.com/aloks98/isoman/backend/internal/service"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+	"github.com/aloks98/isoman/backend/internal/ws"
+)
+
+func TestPublicScopes(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	env.Config.Auth.Enabled = true
+	env.Config.Auth.PublicScopes = []string{"stats", "bogus"}
+
+	manager := download.NewManager(env.DB, env.ISODir, 1)
+	defer manager.Stop()
+	router := setupTestRouter(env, service.NewISOService(env.DB, manager, env.ISODir), ws.NewHub())
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/api/stats", http.StatusOK},
+		{http.MethodGet, "/api/stats/trends", http.StatusOK},
+		{http.MethodPost, "/api/isos/some-id/stats/reset", http.StatusUnauthorized},
+		{http.MethodGet, "/api/isos", http.StatusUnauthorized},
+		{http.MethodGet, "/api/audit", http.StatusUnauthorized},
+		{http.MethodGet, "/images/", http.StatusUnauthorized},
+		{http.MethodGet, "/ws", http.StatusUnauthorized},
+		{http.MethodGet, "/feed.xml", http.StatusUnauthorized},
+		{http.MethodGet, "/api/hooks", http.StatusUnauthorized},
+		{http.MethodPost, "/api/hooks/unknown", http.StatusNotFound}, // Deliveries are checked by signature
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, http.NoBody))
+		if w.Code != tt.want {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, w.Code, tt.want)
+		}
+	}
+}
+
+func TestPublicScopeSet(t *testing.T) {
+	set := publicScopeSet([]string{"Images", "isos", "admin"})
+	if !set["images"] || !set["isos"] || set["admin"] || len(set) != 2 {
+		t.Errorf("publicScopeSet() = %v, want images and isos", set)
+	}
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"fmt"
+	"html"
+)
+
+// Badge colors, matching the usual shields.io palette.
+const (
+	badgeGreen  = "#4c1"
+	badgeOrange = "#fe7d37"
+	badgeRed    = "#e05d44"
+	badgeGray   = "#555"
+)
+
+// badgeCharWidth approximates the width of one character of 11px Verdana,
+// which is close enough for the short ASCII strings badges carry.
+const badgeCharWidth = 7
+
+// renderBadge draws a flat two-part badge with label on gray and message on color.
+func renderBadge(label, message, color string) []byte {
+	labelWidth := len(label)*badgeCharWidth + 10
+	messageWidth := len(message)*badgeCharWidth + 10
+	width := labelWidth + messageWidth
+	label, message = html.EscapeString(label), html.EscapeString(message)
+
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">
+<title>%[4]s: %[5]s</title>
+<rect width="%[2]d" height="20" fill="%[7]s"/>
+<rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="%[8]d" y="14">%[4]s</text>
+<text x="%[9]d" y="14">%[5]s</text>
+</g>
+</svg>
+`, width, labelWidth, messageWidth, label, message, color, badgeGray, labelWidth/2, labelWidth+messageWidth/2))
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// uploadRoutes stream their body to disk instead of decoding it in memory,
+// so they get the upload limit rather than the JSON body limit.
+var uploadRoutes = map[string]bool{
+	"POST /api/bundles/import": true,
+}
+
+// bodyLimitKey holds the *limitedBody of an upload route.
+const bodyLimitKey = "bodyLimit"
+
+// BodyLimitMiddleware rejects request bodies over maxBody bytes with 413
+// before a handler sees them. Bodies are read up front, so handlers binding
+// JSON never hold more than maxBody in memory. Upload routes are instead
+// streamed through a reader that fails past maxUpload bytes, and checked
+// with bodyTooLarge. A limit <= 0 disables it.
+func BodyLimitMiddleware(maxBody, maxUpload int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		upload := uploadRoutes[c.Request.Method+" "+c.FullPath()]
+		limit := maxBody
+		if upload {
+			limit = maxUpload
+		}
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > limit {
+			bodyTooLargeResponse(c, limit)
+			return
+		}
+
+		if upload {
+			body := &limitedBody{ReadCloser: http.MaxBytesReader(c.Writer, c.Request.Body, limit), limit: limit}
+			c.Request.Body = body
+			c.Set(bodyLimitKey, body)
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, limit))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			bodyTooLargeResponse(c, limit)
+			return
+		}
+		if err != nil {
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeBadRequest, "Failed to read request body")
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Next()
+	}
+}
+
+// limitedBody remembers whether an upload ran past its limit, since the
+// error is usually wrapped, or reworded, by whatever parsed the stream.
+type limitedBody struct {
+	io.ReadCloser
+	limit    int64
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		b.exceeded = true
+	}
+	return n, err
+}
+
+// bodyTooLarge answers 413 and reports true if the upload of the request
+// ran past its limit. Upload handlers call it when reading the body failed.
+func bodyTooLarge(c *gin.Context) bool {
+	value, ok := c.Get(bodyLimitKey)
+	if !ok {
+		return false
+	}
+	body := value.(*limitedBody)
+	if !body.exceeded {
+		return false
+	}
+	bodyTooLargeResponse(c, body.limit)
+	return true
+}
+
+// bodyTooLargeResponse answers 413 for a body over limit bytes.
+func bodyTooLargeResponse(c *gin.Context, limit int64) {
+	c.Header("Connection", "close") // The rest of the body is never read
+	ErrorResponse(c, http.StatusRequestEntityTooLarge, ErrCodePayloadTooLarge, fmt.Sprintf("Request body is larger than %d bytes", limit))
+	c.Abort()
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	router := gin.New()
+	router.Use(BodyLimitMiddleware(16, 64))
+	router.POST("/api/isos", func(c *gin.Context) {
+		var req map[string]string
+		if err := c.ShouldBindJSON(&req); err != nil {
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	router.POST("/api/bundles/import", func(c *gin.Context) {
+		if _, err := io.Copy(io.Discard, c.Request.Body); err != nil {
+			if !bodyTooLarge(c) {
+				ErrorResponse(c, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+			}
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		chunked  bool // Sent without Content-Length, so only reading finds the size
+		wantCode int
+	}{
+		{"SmallJSON", "/api/isos", `{"name":"a"}`, false, http.StatusOK},
+		{"LargeJSON", "/api/isos", `{"name":"alpine-linux"}`, false, http.StatusRequestEntityTooLarge},
+		{"LargeChunkedJSON", "/api/isos", `{"name":"alpine-linux"}`, true, http.StatusRequestEntityTooLarge},
+		{"UploadOverJSONLimit", "/api/bundles/import", strings.Repeat("x", 48), false, http.StatusOK},
+		{"LargeUpload", "/api/bundles/import", strings.Repeat("x", 65), false, http.StatusRequestEntityTooLarge},
+		{"LargeChunkedUpload", "/api/bundles/import", strings.Repeat("x", 65), true, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("Expected status %d, got: %d (%s)", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantCode == http.StatusRequestEntityTooLarge && !strings.Contains(w.Body.String(), ErrCodePayloadTooLarge) {
+				t.Errorf("Expected code %s, got: %s", ErrCodePayloadTooLarge, w.Body.String())
+			}
+		})
+	}
+}
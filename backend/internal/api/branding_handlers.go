@@ -0,0 +1,89 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BrandingHandlers holds a reference to the branding service.
+type BrandingHandlers struct {
+	brandingService *service.BrandingService
+}
+
+// NewBrandingHandlers creates a new BrandingHandlers instance.
+func NewBrandingHandlers(brandingService *service.BrandingService) *BrandingHandlers {
+	return &BrandingHandlers{
+		brandingService: brandingService,
+	}
+}
+
+// GetListingBranding returns the branding of the /images directory listing.
+func (h *BrandingHandlers) GetListingBranding(c *gin.Context) {
+	branding, err := h.brandingService.ListingBranding()
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to retrieve branding")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, branding)
+}
+
+// UpdateListingBranding changes the branding fields the request sets.
+func (h *BrandingHandlers) UpdateListingBranding(c *gin.Context) {
+	var req models.UpdateListingBrandingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+		return
+	}
+
+	branding, err := h.brandingService.UpdateListingBranding(req)
+	if err != nil {
+		var invalidErr *service.InvalidBrandingError
+		if errors.As(err, &invalidErr) {
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, invalidErr.Error())
+			return
+		}
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to update branding")
+		return
+	}
+
+	SuccessResponseWithMessage(c, http.StatusOK, branding, "Branding updated")
+}
+
+// GetListingColumns returns the columns of the /images directory listing.
+func (h *BrandingHandlers) GetListingColumns(c *gin.Context) {
+	columns, err := h.brandingService.ListingColumns()
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to retrieve listing columns")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, models.ListingColumns{Columns: columns})
+}
+
+// UpdateListingColumns chooses the columns of the /images directory listing.
+func (h *BrandingHandlers) UpdateListingColumns(c *gin.Context) {
+	var req models.ListingColumns
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+		return
+	}
+
+	columns, err := h.brandingService.SetListingColumns(req.Columns)
+	if err != nil {
+		var invalidErr *service.InvalidBrandingError
+		if errors.As(err, &invalidErr) {
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, invalidErr.Error())
+			return
+		}
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to update listing columns")
+		return
+	}
+
+	SuccessResponseWithMessage(c, http.StatusOK, models.ListingColumns{Columns: columns}, "Listing columns updated")
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/service"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBrandingHandlers(t *testing.T) {
+	database, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	brandingHandlers := NewBrandingHandlers(service.NewBrandingService(database))
+
+	router := gin.New()
+	router.GET("/api/branding", brandingHandlers.GetListingBranding)
+	router.PUT("/api/branding", brandingHandlers.UpdateListingBranding)
+	router.GET("/api/listing/columns", brandingHandlers.GetListingColumns)
+	router.PUT("/api/listing/columns", brandingHandlers.UpdateListingColumns)
+
+	w := doCredentialRequest(router, http.MethodGet, "/api/branding", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", w.Code, w.Body.String())
+	}
+	if branding := parseAPIResponse(t, w.Body.Bytes()).Data.(map[string]any); branding["title"] != "" || branding["updated_at"] != nil {
+		t.Errorf("Expected no branding by default, got %v", branding)
+	}
+
+	w = doCredentialRequest(router, http.MethodPut, "/api/branding", `{"title":"Example Corp Mirror","primary_color":"#B91C1C"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", w.Code, w.Body.String())
+	}
+	w = doCredentialRequest(router, http.MethodGet, "/api/branding", "")
+	branding := parseAPIResponse(t, w.Body.Bytes()).Data.(map[string]any)
+	if branding["title"] != "Example Corp Mirror" || branding["primary_color"] != "#b91c1c" || branding["updated_at"] == nil {
+		t.Errorf("Expected the stored branding, got %v", branding)
+	}
+
+	if w := doCredentialRequest(router, http.MethodPut, "/api/branding", `{"logo_url":"javascript:alert(1)"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid logo URL, got: %d", w.Code)
+	}
+	if w := doCredentialRequest(router, http.MethodPut, "/api/branding", `{"title":`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a malformed body, got: %d", w.Code)
+	}
+
+	w = doCredentialRequest(router, http.MethodPut, "/api/listing/columns", `{"columns":["size","checksum"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", w.Code, w.Body.String())
+	}
+	w = doCredentialRequest(router, http.MethodGet, "/api/listing/columns", "")
+	if columns := parseAPIResponse(t, w.Body.Bytes()).Data.(map[string]any)["columns"]; !reflect.DeepEqual(columns, []any{"checksum", "size"}) {
+		t.Errorf("Expected the columns in display order, got %v", columns)
+	}
+	if w := doCredentialRequest(router, http.MethodPut, "/api/listing/columns", `{"columns":["owner"]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown column, got: %d", w.Code)
+	}
+	if w := doCredentialRequest(router, http.MethodPut, "/api/listing/columns", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without columns, got: %d", w.Code)
+	}
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChecksumHandlers holds a reference to the service managing known checksums.
+type ChecksumHandlers struct {
+	checksumService *service.ChecksumService
+}
+
+// NewChecksumHandlers creates a new ChecksumHandlers instance.
+func NewChecksumHandlers(checksumService *service.ChecksumService) *ChecksumHandlers {
+	return &ChecksumHandlers{checksumService: checksumService}
+}
+
+// ListKnownChecksums returns the known checksums, optionally of one checksum file.
+func (h *ChecksumHandlers) ListKnownChecksums(c *gin.Context) {
+	checksums, err := h.checksumService.ListKnownChecksums(c.Query("checksum_url"))
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to retrieve known checksums")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, checksums)
+}
+
+// ImportChecksums stores a batch of published checksums for offline verification.
+func (h *ChecksumHandlers) ImportChecksums(c *gin.Context) {
+	var req models.ChecksumImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+		return
+	}
+
+	result, err := h.checksumService.ImportChecksums(req)
+	if err != nil {
+		var invalidErr *service.InvalidChecksumImportError
+		if errors.As(err, &invalidErr) {
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, invalidErr.Error())
+			return
+		}
+
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to import checksums")
+		return
+	}
+
+	SuccessResponseWithMessage(c, http.StatusOK, result, fmt.Sprintf("Imported %d checksums", result.Imported))
+}
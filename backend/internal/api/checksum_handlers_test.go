@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestChecksumHandlers(t *testing.T) {
+	_, database, _, _, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	checksumHandlers := NewChecksumHandlers(service.NewChecksumService(database))
+
+	router := gin.New()
+	router.GET("/api/checksums", checksumHandlers.ListKnownChecksums)
+	router.POST("/api/checksums/import", checksumHandlers.ImportChecksums)
+
+	w := doCredentialRequest(router, http.MethodPost, "/api/checksums/import", `{
+		"source": "bundle",
+		"checksums": [
+			{"checksum_url": "https://example.com/SHA256SUMS", "filename": "a.iso", "checksum": "`+strings.Repeat("a", 64)+`"},
+			{"checksum_url": "https://example.com/SHA512SUMS", "filename": "a.iso", "checksum": "`+strings.Repeat("b", 128)+`"}
+		]
+	}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"imported":2`) {
+		t.Fatalf("Expected 2 imported, got: %d (%s)", w.Code, w.Body.String())
+	}
+	if w := doCredentialRequest(router, http.MethodPost, "/api/checksums/import", `{"checksums":[{"checksum_url":"https://example.com/SUMS","filename":"a.iso","checksum":"xyz"}]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a bad digest, got: %d", w.Code)
+	}
+	if w := doCredentialRequest(router, http.MethodPost, "/api/checksums/import", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without checksums, got: %d", w.Code)
+	}
+
+	w = doCredentialRequest(router, http.MethodGet, "/api/checksums?checksum_url=https://example.com/SHA512SUMS", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", w.Code)
+	}
+	listed := parseAPIResponse(t, w.Body.Bytes()).Data.([]any)
+	if len(listed) != 1 || listed[0].(map[string]any)["source"] != "bundle" {
+		t.Errorf("Unexpected checksums: %v", listed)
+	}
+}
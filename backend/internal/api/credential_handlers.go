@@ -0,0 +1,114 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CredentialHandlers holds references to the credential service.
+type CredentialHandlers struct {
+	credentialService *service.CredentialService
+}
+
+// NewCredentialHandlers creates a new CredentialHandlers instance.
+func NewCredentialHandlers(credentialService *service.CredentialService) *CredentialHandlers {
+	return &CredentialHandlers{
+		credentialService: credentialService,
+	}
+}
+
+// ListCredentials returns all credentials without their secrets.
+func (h *CredentialHandlers) ListCredentials(c *gin.Context) {
+	creds, err := h.credentialService.ListCredentials()
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to retrieve credentials")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, creds)
+}
+
+// GetCredential returns a single credential without its secret.
+func (h *CredentialHandlers) GetCredential(c *gin.Context) {
+	cred, err := h.credentialService.GetCredential(c.Param("name"))
+	if err != nil {
+		credentialErrorResponse(c, err, "Failed to retrieve credential")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, cred)
+}
+
+// CreateCredential stores a new credential.
+func (h *CredentialHandlers) CreateCredential(c *gin.Context) {
+	var req models.CreateCredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+		return
+	}
+
+	cred, err := h.credentialService.CreateCredential(req)
+	if err != nil {
+		credentialErrorResponse(c, err, "Failed to store credential")
+		return
+	}
+
+	SuccessResponseWithMessage(c, http.StatusCreated, cred, "Credential stored")
+}
+
+// UpdateCredential changes the host of a credential or replaces its secret.
+func (h *CredentialHandlers) UpdateCredential(c *gin.Context) {
+	var req models.UpdateCredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+		return
+	}
+
+	cred, err := h.credentialService.UpdateCredential(c.Param("name"), req)
+	if err != nil {
+		credentialErrorResponse(c, err, "Failed to update credential")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, cred)
+}
+
+// DeleteCredential removes a credential no ISO references.
+func (h *CredentialHandlers) DeleteCredential(c *gin.Context) {
+	if err := h.credentialService.DeleteCredential(c.Param("name")); err != nil {
+		credentialErrorResponse(c, err, "Failed to delete credential")
+		return
+	}
+
+	NoContentResponse(c)
+}
+
+// credentialErrorResponse maps credential service errors to responses.
+func credentialErrorResponse(c *gin.Context, err error, fallback string) {
+	var invalidErr *service.InvalidCredentialError
+	var existsErr *service.CredentialExistsError
+	var inUseErr *service.CredentialInUseError
+
+	switch {
+	case errors.Is(err, db.ErrCredentialNotFound):
+		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "Credential not found")
+	case errors.Is(err, service.ErrCredentialsDisabled):
+		ErrorResponse(c, http.StatusServiceUnavailable, ErrCodeCredentialsOff, err.Error())
+	case errors.As(err, &invalidErr):
+		ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, invalidErr.Error())
+	case errors.As(err, &existsErr):
+		ErrorResponse(c, http.StatusConflict, ErrCodeConflict, existsErr.Error())
+	case errors.As(err, &inUseErr):
+		ProblemResponse(c, http.StatusConflict, &APIError{Code: ErrCodeConflict, Message: inUseErr.Error()}, nil, gin.H{
+			"iso_count": inUseErr.ISOCount,
+		})
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, fallback)
+	}
+}
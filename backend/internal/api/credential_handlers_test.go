@@ -0,0 +1,109 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/secrets"
+	"github.com/aloks98/isoman/backend/internal/service"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupCredentialRouter serves the credential routes backed by a test database.
+func setupCredentialRouter(t *testing.T, withKey bool) (*gin.Engine, *testutil.TestEnv) {
+	t.Helper()
+
+	env := testutil.SetupTestEnvironment(t)
+	var box *secrets.Box
+	if withKey {
+		var err error
+		if box, err = secrets.NewBox(bytes.Repeat([]byte{1}, secrets.KeySize)); err != nil {
+			t.Fatalf("NewBox() failed: %v", err)
+		}
+	}
+	handlers := NewCredentialHandlers(service.NewCredentialService(env.DB, box))
+
+	router := gin.New()
+	router.GET("/api/credentials", handlers.ListCredentials)
+	router.GET("/api/credentials/:name", handlers.GetCredential)
+	router.POST("/api/credentials", handlers.CreateCredential)
+	router.PUT("/api/credentials/:name", handlers.UpdateCredential)
+	router.DELETE("/api/credentials/:name", handlers.DeleteCredential)
+	return router, env
+}
+
+func doCredentialRequest(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCredentialHandlers(t *testing.T) {
+	router, env := setupCredentialRouter(t, true)
+	defer env.Cleanup()
+
+	w := doCredentialRequest(router, http.MethodPost, "/api/credentials",
+		`{"name":"mirror","type":"basic","host":"mirror.example.com","secret":{"username":"user","password":"hunter2"}}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "hunter2") || strings.Contains(w.Body.String(), "secret") {
+		t.Errorf("Response leaks the secret: %s", w.Body.String())
+	}
+
+	w = doCredentialRequest(router, http.MethodPost, "/api/credentials", `{"name":"mirror","type":"token","secret":{"token":"t"}}`)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for a duplicate, got: %d", w.Code)
+	}
+
+	w = doCredentialRequest(router, http.MethodPost, "/api/credentials", `{"name":"s3","type":"s3","secret":{}}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a missing secret, got: %d", w.Code)
+	}
+
+	w = doCredentialRequest(router, http.MethodGet, "/api/credentials", "")
+	var response APIResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if list, ok := response.Data.([]any); !ok || len(list) != 1 {
+		t.Errorf("Expected 1 credential, got: %s", w.Body.String())
+	}
+
+	w = doCredentialRequest(router, http.MethodPut, "/api/credentials/mirror", `{"host":""}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"host":""`) {
+		t.Errorf("Expected host to be cleared, got: %d (%s)", w.Code, w.Body.String())
+	}
+
+	w = doCredentialRequest(router, http.MethodDelete, "/api/credentials/mirror", "")
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got: %d", w.Code)
+	}
+
+	w = doCredentialRequest(router, http.MethodGet, "/api/credentials/mirror", "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 after delete, got: %d", w.Code)
+	}
+}
+
+func TestCredentialHandlersWithoutKey(t *testing.T) {
+	router, env := setupCredentialRouter(t, false)
+	defer env.Cleanup()
+
+	w := doCredentialRequest(router, http.MethodPost, "/api/credentials", `{"name":"mirror","type":"token","secret":{"token":"t"}}`)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503, got: %d", w.Code)
+	}
+
+	var problem Problem
+	json.Unmarshal(w.Body.Bytes(), &problem)
+	if problem.Code != ErrCodeCredentialsOff {
+		t.Errorf("Expected code %s, got: %s", ErrCodeCredentialsOff, problem.Code)
+	}
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/aloks98/isoman/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CSRFCookieName is the cookie the UI reads the CSRF token from. Unlike the
+// session cookie it is readable by scripts.
+const CSRFCookieName = "isoman_csrf"
+
+// CSRFHeader carries the CSRF token on mutating requests.
+const CSRFHeader = "X-CSRF-Token"
+
+// CSRFMiddleware requires the CSRF token on requests that authenticate with
+// the session cookie and may change state. Safe methods, requests without a
+// session cookie, and requests with an Authorization header pass untouched,
+// since a foreign page can't make the browser add a header.
+func CSRFMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if c.GetHeader("Authorization") != "" {
+			c.Next()
+			return
+		}
+		token, err := c.Cookie(SessionCookieName)
+		if err != nil || token == "" {
+			c.Next()
+			return
+		}
+
+		want := service.CSRFToken(token)
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader(CSRFHeader)), []byte(want)) != 1 {
+			ErrorResponse(c, http.StatusForbidden, ErrCodeCSRFFailed, "Missing or invalid "+CSRFHeader+" header")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCSRFMiddleware(t *testing.T) {
+	router := gin.New()
+	router.Use(CSRFMiddleware())
+	router.GET("/thing", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/thing", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	const token = "session-token"
+	tests := []struct {
+		name   string
+		method string
+		cookie bool
+		bearer bool
+		csrf   string
+		want   int
+	}{
+		{"safe method with cookie", http.MethodGet, true, false, "", http.StatusOK},
+		{"cookie without header", http.MethodPost, true, false, "", http.StatusForbidden},
+		{"cookie with wrong header", http.MethodPost, true, false, "guess", http.StatusForbidden},
+		{"cookie with header", http.MethodPost, true, false, service.CSRFToken(token), http.StatusOK},
+		{"bearer token", http.MethodPost, true, true, "", http.StatusOK},
+		{"no session", http.MethodPost, false, false, "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/thing", http.NoBody)
+			if tt.cookie {
+				req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: token})
+			}
+			if tt.bearer {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			if tt.csrf != "" {
+				req.Header.Set(CSRFHeader, tt.csrf)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
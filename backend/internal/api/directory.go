@@ -1,27 +1,55 @@
 package api
 
 import (
+	"bytes"
 	_ "embed"
+	"errors"
 	"fmt"
 	"html/template"
 	"io/fs"
 	"log/slog"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/aloks98/isoman/backend/internal/constants"
 	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/i18n"
+	"github.com/aloks98/isoman/backend/internal/models"
 	"github.com/aloks98/isoman/backend/internal/service"
+	"github.com/aloks98/isoman/backend/internal/throughput"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Listing formats, chosen with ?format=.
+const (
+	listingFormatHTML = "html"
+	listingFormatText = "txt"
+)
+
 //go:embed templates/directory.html
 var directoryTemplateContent string
 
+// directoryTemplate is parsed once at startup rather than per request.
+var directoryTemplate = template.Must(template.New("directory").Funcs(template.FuncMap{
+	"hasSuffix": func(s, suffix string) bool {
+		return len(s) >= len(suffix) && s[len(s)-len(suffix):] == suffix
+	},
+	// shortHash abbreviates a hex digest for the checksum column
+	"shortHash": func(s string) string {
+		if len(s) <= 12 {
+			return s
+		}
+		return s[:12] + "…"
+	},
+}).Parse(directoryTemplateContent))
+
 // FileInfo represents a file in the directory listing.
 type FileInfo struct {
 	ModifiedTime time.Time
@@ -31,13 +59,42 @@ type FileInfo struct {
 	Path         string
 	SizeBytes    int64
 	IsDir        bool
+
+	// Catalog columns, set for files isoman manages
+	Managed       bool
+	Status        string
+	Verification  string // verified, unverified, or failed
+	Checksum      string
+	ChecksumType  string
+	DownloadCount int64
 }
 
 // DirectoryHandlerConfig holds dependencies for the directory handler.
 type DirectoryHandlerConfig struct {
-	ISODir       string
-	StatsService *service.StatsService
-	DB           *db.DB
+	ISODir           string
+	TempDir          string            // Hidden when inside ISODir
+	HiddenFiles      []string          // Glob patterns to hide; nil hides dotfiles
+	SymlinkPolicy    string            // within, deny; empty uses the default
+	ListingCacheTTL  time.Duration     // Zero disables listing caching
+	EgressMeter      *throughput.Meter // Counts bytes of served files; nil disables
+	VerifyOnServe    bool              // Hash complete ISOs as they are served and flag mismatches
+	StreamInProgress bool              // Serve ISOs still downloading from their temp file
+	StatsService     *service.StatsService
+	Links            *service.DownloadLinkService // Checks ?token= download links; nil ignores them
+	Branding         *service.BrandingService     // Title, logo, and colors of listings; nil lists unbranded
+	DB               *db.DB
+
+	// Messages listings are rendered with; nil uses the built-in catalog in
+	// English. With NegotiateLanguage, each listing is in the language the
+	// request's Accept-Language prefers, else the catalog's default.
+	Catalog           *i18n.Catalog
+	NegotiateLanguage bool
+
+	// Cache-Control sent with successful responses for a CDN or caching
+	// proxy; empty sends none. Files served through a download link are
+	// always private.
+	FileCacheControl    string
+	ListingCacheControl string
 }
 
 // isTrackableFile checks if the file should be tracked for download statistics.
@@ -53,8 +110,8 @@ func isTrackableFile(filename string) bool {
 	return false
 }
 
-// trackDownload records the download asynchronously.
-func trackDownload(cfg *DirectoryHandlerConfig, filePath string) {
+// trackDownload records the download by a client asynchronously.
+func trackDownload(cfg *DirectoryHandlerConfig, filePath, clientIP, userAgent string) {
 	// Look up the ISO by file path
 	iso, err := cfg.DB.GetISOByFilePath(filePath)
 	if err != nil {
@@ -67,31 +124,96 @@ func trackDownload(cfg *DirectoryHandlerConfig, filePath string) {
 	}
 
 	// Record the download
-	if err := cfg.StatsService.RecordDownload(iso.ID); err != nil {
+	if err := cfg.StatsService.RecordDownloadFrom(iso.ID, clientIP, userAgent); err != nil {
 		slog.Warn("failed to record download", slog.String("iso_id", iso.ID), slog.Any("error", err))
 	}
 }
 
+// trackBytesServed adds the bytes sent of a file to its ISO's total.
+func trackBytesServed(cfg *DirectoryHandlerConfig, filePath string, n int64) {
+	iso, err := cfg.DB.GetISOByFilePath(filePath)
+	if err != nil {
+		slog.Warn("failed to lookup ISO for bandwidth tracking", slog.String("path", filePath), slog.Any("error", err))
+		return
+	}
+	if iso == nil {
+		return
+	}
+
+	if err := cfg.StatsService.RecordBytesServed(iso.ID, n); err != nil {
+		slog.Warn("failed to record bytes served", slog.String("iso_id", iso.ID), slog.Any("error", err))
+	}
+}
+
 // DirectoryHandler serves Apache-style directory listing for /images/.
 func DirectoryHandler(cfg *DirectoryHandlerConfig) gin.HandlerFunc {
+	hiddenFiles := cfg.HiddenFiles
+	if hiddenFiles == nil {
+		hiddenFiles = []string{constants.DefaultHiddenFiles}
+	}
+	hidePolicy := NewHidePolicy(hiddenFiles, cfg.ISODir, cfg.TempDir)
+	symlinkPolicy := symlinkPolicyOrDefault(cfg.SymlinkPolicy)
+	listings := newListingCache(cfg.ListingCacheTTL)
+	catalog := cfg.Catalog
+	if catalog == nil {
+		catalog = i18n.Builtin()
+	}
+
 	return func(c *gin.Context) {
-		// Get the requested path (Gin includes leading slash in wildcard)
-		requestPath := c.Param("filepath")
+		// Canonicalize the requested path (Gin includes leading slash in wildcard)
+		// and make sure it can't escape the ISO directory
+		requestPath, fullPath, ok := resolveRequestPath(cfg.ISODir, c.Param("filepath"))
+		if !ok {
+			c.String(http.StatusNotFound, "404 Not Found")
+			return
+		}
 
-		// Remove leading slash if present
-		if len(requestPath) > 0 && requestPath[0] == '/' {
-			requestPath = requestPath[1:]
+		// Hidden paths hold temp, quarantined, and archived files that must not be served
+		if hidePolicy.IsHiddenPath(requestPath) {
+			c.String(http.StatusNotFound, "404 Not Found")
+			return
+		}
+
+		// Follow symlinks per the policy; the target must not be hidden either.
+		// A file that doesn't exist yet may be an ISO still downloading
+		realPath, realRel, ok := resolveSymlinks(cfg.ISODir, fullPath, symlinkPolicy)
+		var inProgress *models.ISO
+		if !ok && cfg.StreamInProgress && cfg.DB != nil {
+			inProgress = inProgressISO(cfg.DB, requestPath)
+		}
+		if (!ok && inProgress == nil) || (ok && hidePolicy.IsHiddenPath(realRel)) {
+			c.String(http.StatusNotFound, "404 Not Found")
+			return
 		}
 
-		if requestPath == "" {
-			requestPath = "."
+		// A download link grants access to exactly the file it was issued for
+		var link *models.DownloadLink
+		if token := c.Query(downloadTokenParam); token != "" && cfg.Links != nil {
+			var err error
+			if link, err = cfg.Links.Resolve(token, requestPath); err != nil {
+				if !errors.Is(err, service.ErrDownloadLinkInvalid) {
+					slog.ErrorContext(c.Request.Context(), "failed to check download link", slog.Any("error", err))
+				}
+				c.String(http.StatusForbidden, "403 Forbidden: "+service.ErrDownloadLinkInvalid.Error())
+				return
+			}
 		}
 
-		// Construct full filesystem path
-		fullPath := filepath.Join(cfg.ISODir, requestPath)
+		// Stream an ISO still downloading once its size is known; holding
+		// for bytes can outlast the server's write timeout, so it is lifted
+		if inProgress != nil {
+			stream := openInProgress(c.Request.Context(), cfg, inProgress)
+			if stream == nil {
+				c.String(http.StatusNotFound, "404 Not Found")
+				return
+			}
+			http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}) //nolint:errcheck // Not every writer supports deadlines
+			serveFile(c, cfg, link, requestPath, stream.size, false, func() { stream.serve(c) })
+			return
+		}
 
 		// Check if path exists
-		info, err := os.Stat(fullPath)
+		info, err := os.Stat(realPath)
 		if err != nil {
 			c.String(http.StatusNotFound, "404 Not Found")
 			return
@@ -99,16 +221,37 @@ func DirectoryHandler(cfg *DirectoryHandlerConfig) gin.HandlerFunc {
 
 		// If it's a file, serve it directly
 		if !info.IsDir() {
-			// Track download if it's a trackable ISO file
-			if isTrackableFile(requestPath) && cfg.StatsService != nil && cfg.DB != nil {
-				go trackDownload(cfg, requestPath)
+			verify := cfg.VerifyOnServe && isTrackableFile(realRel)
+			serveFile(c, cfg, link, realRel, info.Size(), verify, func() { c.File(realPath) })
+			return
+		}
+
+		// Listings are HTML, rendered and cached per language, or plain text
+		format := c.DefaultQuery("format", listingFormatHTML)
+		var localizer *i18n.Localizer
+		var cacheKey, contentType string
+		switch format {
+		case listingFormatHTML:
+			localizer = catalog.Localizer("")
+			if cfg.NegotiateLanguage {
+				localizer = catalog.Negotiate(c.GetHeader("Accept-Language"))
+				c.Header("Vary", "Accept-Language")
 			}
-			c.File(fullPath)
+			cacheKey, contentType = localizer.Lang()+":"+requestPath, "text/html; charset=utf-8"
+		case listingFormatText:
+			cacheKey, contentType = format+":"+requestPath, "text/plain; charset=utf-8"
+		default:
+			c.String(http.StatusBadRequest, "400 Bad Request: format must be html or txt")
+			return
+		}
+
+		// If it's a directory, reuse the rendered listing while the directory is unchanged
+		if body, ok := listings.get(cacheKey, info.ModTime()); ok {
+			writeDirectoryListing(c, body, contentType, cfg.ListingCacheControl)
 			return
 		}
 
-		// If it's a directory, show listing
-		files, err := os.ReadDir(fullPath)
+		files, err := os.ReadDir(realPath)
 		if err != nil {
 			c.String(http.StatusInternalServerError, "Error reading directory")
 			return
@@ -116,9 +259,13 @@ func DirectoryHandler(cfg *DirectoryHandlerConfig) gin.HandlerFunc {
 
 		// Convert to FileInfo structs
 		var fileInfos []FileInfo
+		catalogPaths := make(map[int]string) // fileInfos index -> ISO file path
 		for _, file := range files {
-			// Skip hidden files and temp directory
-			if file.Name()[0] == '.' {
+			// Construct relative path for links
+			relativePath := filepath.Join(requestPath, file.Name())
+
+			// Skip hidden files and the temp directory
+			if hidePolicy.IsHiddenPath(filepath.ToSlash(relativePath)) {
 				continue
 			}
 
@@ -127,13 +274,23 @@ func DirectoryHandler(cfg *DirectoryHandlerConfig) gin.HandlerFunc {
 				continue
 			}
 
-			// Construct relative path for links
-			relativePath := filepath.Join(requestPath, file.Name())
+			// List symlinks as their target, skipping ones the policy won't serve
+			catalogPath := relativePath
+			if file.Type()&os.ModeSymlink != 0 {
+				target, targetRel, ok := resolveSymlinks(cfg.ISODir, filepath.Join(fullPath, file.Name()), symlinkPolicy)
+				if !ok || hidePolicy.IsHiddenPath(targetRel) {
+					continue
+				}
+				if fileInfo, err = os.Stat(target); err != nil {
+					continue
+				}
+				catalogPath = targetRel
+			}
 
 			// For directories, show "-" instead of directory entry size
 			size := formatSize(fileInfo.Size())
 			sizeBytes := fileInfo.Size()
-			if file.IsDir() {
+			if fileInfo.IsDir() {
 				size = "-"
 				sizeBytes = 0
 			}
@@ -144,9 +301,23 @@ func DirectoryHandler(cfg *DirectoryHandlerConfig) gin.HandlerFunc {
 				SizeBytes:    sizeBytes,
 				Modified:     fileInfo.ModTime().Format("2006-01-02 15:04:05"),
 				ModifiedTime: fileInfo.ModTime(),
-				IsDir:        file.IsDir(),
+				IsDir:        fileInfo.IsDir(),
 				Path:         "/images/" + filepath.ToSlash(relativePath),
 			})
+			if !fileInfo.IsDir() {
+				catalogPaths[len(fileInfos)-1] = filepath.FromSlash(catalogPath)
+			}
+		}
+
+		// Branding and columns changes show once cached listings expire; the
+		// text listing has neither
+		var branding *models.ListingBranding
+		var columns map[string]bool
+		if format == listingFormatHTML {
+			branding, columns = listingLook(c, cfg.Branding)
+			if cfg.DB != nil && (columns[constants.ListingColumnStatus] || columns[constants.ListingColumnChecksum] || columns[constants.ListingColumnDownloads]) {
+				annotateCatalog(cfg.DB, fileInfos, catalogPaths)
+			}
 		}
 
 		// Sort by name (directories first, then files)
@@ -157,11 +328,175 @@ func DirectoryHandler(cfg *DirectoryHandlerConfig) gin.HandlerFunc {
 			return fileInfos[i].Name < fileInfos[j].Name
 		})
 
-		// Render HTML template
-		renderDirectoryListing(c, requestPath, fileInfos)
+		// Render the listing
+		var body []byte
+		if format == listingFormatText {
+			body = renderTextListing(fileInfos)
+		} else if body, err = renderDirectoryListing(requestPath, fileInfos, localizer, branding, columns); err != nil {
+			slog.ErrorContext(c.Request.Context(), "failed to execute template", slog.Any("error", err))
+			ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to generate directory listing")
+			return
+		}
+		listings.put(cacheKey, info.ModTime(), body)
+		writeDirectoryListing(c, body, contentType, cfg.ListingCacheControl)
 	}
 }
 
+// serveFile sends the size-byte file at rel with serve, recording the download
+// and the bytes that went out, and verifying the transfer when verify is set.
+func serveFile(c *gin.Context, cfg *DirectoryHandlerConfig, link *models.DownloadLink, rel string, size int64, verify bool, serve func()) {
+	// Claim a single-use link before anything goes out, so requests overlapping
+	// a download through it can't share it. HEAD requests don't claim it
+	singleUse := link != nil && link.SingleUse && c.Request.Method == http.MethodGet
+	var counter *countingWriter
+	if singleUse {
+		claimed, err := cfg.Links.Claim(link.ID)
+		if err != nil {
+			slog.ErrorContext(c.Request.Context(), "failed to claim download link", slog.String("path", link.Path), slog.Any("error", err))
+			c.String(http.StatusInternalServerError, "500 Internal Server Error")
+			return
+		}
+		if !claimed {
+			c.String(http.StatusGone, "410 Gone: "+service.ErrDownloadLinkInvalid.Error())
+			return
+		}
+		// Range and interrupted requests hand the link back
+		defer func() {
+			if counter.Status() == http.StatusOK && counter.n == size {
+				return
+			}
+			if err := cfg.Links.Release(link.ID); err != nil {
+				slog.ErrorContext(c.Request.Context(), "failed to release download link", slog.String("path", link.Path), slog.Any("error", err))
+			}
+		}()
+	}
+
+	// Track download if it's a trackable ISO file, crediting the link target
+	trackable := isTrackableFile(rel) && cfg.StatsService != nil && cfg.DB != nil
+	if trackable {
+		go trackDownload(cfg, rel, c.ClientIP(), c.Request.UserAgent())
+	}
+	if cfg.EgressMeter != nil {
+		c.Writer = &meteredWriter{ResponseWriter: c.Writer, meter: cfg.EgressMeter}
+	}
+	var verifier *verifyingWriter
+	if verify && cfg.DB != nil {
+		if verifier = newVerifyingWriter(c, cfg.DB, rel); verifier != nil {
+			c.Writer = verifier
+		}
+	}
+	if trackable || singleUse {
+		counter = &countingWriter{ResponseWriter: c.Writer}
+		c.Writer = counter
+	}
+	if link != nil {
+		c.Header("Cache-Control", "private, no-store")
+	} else if cfg.FileCacheControl != "" {
+		c.Header("Cache-Control", cfg.FileCacheControl)
+	}
+	serve()
+	if verifier != nil {
+		verifier.check(c.Request.Context(), size)
+	}
+
+	// Count what actually went out, so partial and range
+	// transfers add their share rather than the whole file
+	if trackable && counter.n > 0 {
+		go trackBytesServed(cfg, rel, counter.n)
+	}
+}
+
+// listingLook returns the branding and the set of columns to list with,
+// falling back to the built-in ones when they are unset or can't be loaded.
+func listingLook(c *gin.Context, branding *service.BrandingService) (*models.ListingBranding, map[string]bool) {
+	look := &models.ListingBranding{}
+	names := constants.DefaultListingColumns
+	if branding != nil {
+		if stored, err := branding.ListingBranding(); err != nil {
+			slog.WarnContext(c.Request.Context(), "failed to load listing branding", slog.Any("error", err))
+		} else {
+			look = stored
+		}
+		if stored, err := branding.ListingColumns(); err != nil {
+			slog.WarnContext(c.Request.Context(), "failed to load listing columns", slog.Any("error", err))
+		} else {
+			names = stored
+		}
+	}
+
+	columns := make(map[string]bool, len(names))
+	for _, name := range names {
+		columns[name] = true
+	}
+	return look, columns
+}
+
+// annotateCatalog fills the catalog columns of listed files that match an ISO
+// record. paths maps indexes into files to the file path the ISO is stored at.
+func annotateCatalog(database *db.DB, files []FileInfo, paths map[int]string) {
+	if len(paths) == 0 {
+		return
+	}
+
+	lookup := make([]string, 0, len(paths))
+	for _, p := range paths {
+		lookup = append(lookup, p)
+	}
+	isos, err := database.GetISOsByFilePaths(lookup)
+	if err != nil {
+		slog.Warn("failed to look up ISOs for directory listing", slog.Any("error", err))
+		return
+	}
+
+	for i, p := range paths {
+		iso, ok := isos[p]
+		if !ok {
+			continue
+		}
+		files[i].Managed = true
+		files[i].Status = string(iso.Status)
+		files[i].Verification = string(iso.Verification)
+		files[i].Checksum = iso.Checksum
+		files[i].ChecksumType = iso.ChecksumType
+		files[i].DownloadCount = iso.DownloadCount
+	}
+}
+
+// meteredWriter counts the bytes of a response body towards a throughput meter.
+type meteredWriter struct {
+	gin.ResponseWriter
+	meter *throughput.Meter
+}
+
+func (w *meteredWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.meter.Add(int64(n))
+	return n, err
+}
+
+// resolveRequestPath cleans a user-supplied /images path and joins it onto isoDir.
+// It returns the cleaned relative path ("." for the root) and the filesystem path,
+// or false when the path is malformed or would resolve outside isoDir.
+func resolveRequestPath(isoDir, requestPath string) (string, string, bool) {
+	if strings.ContainsRune(requestPath, 0) {
+		return "", "", false
+	}
+
+	// Cleaning a rooted path drops any ".." that would climb above the root
+	rel := strings.TrimPrefix(path.Clean("/"+requestPath), "/")
+	if rel == "" {
+		rel = "."
+	}
+
+	root := filepath.Clean(isoDir)
+	fullPath := filepath.Join(root, filepath.FromSlash(rel))
+	if fullPath != root && !strings.HasPrefix(fullPath, root+string(filepath.Separator)) {
+		return "", "", false
+	}
+
+	return rel, fullPath, true
+}
+
 // formatSize converts bytes to human-readable format.
 func formatSize(bytes int64) string {
 	const unit = 1024
@@ -176,14 +511,13 @@ func formatSize(bytes int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
-// renderDirectoryListing renders the HTML directory listing.
-func renderDirectoryListing(c *gin.Context, path string, files []FileInfo) {
-	// Create template with custom functions
-	tmpl := template.Must(template.New("directory").Funcs(template.FuncMap{
-		"hasSuffix": func(s, suffix string) bool {
-			return len(s) >= len(suffix) && s[len(s)-len(suffix):] == suffix
-		},
-	}).Parse(directoryTemplateContent))
+// renderDirectoryListing renders the HTML directory listing with the messages
+// of l, the given branding, and the columns set in columns.
+func renderDirectoryListing(path string, files []FileInfo, l *i18n.Localizer, branding *models.ListingBranding, columns map[string]bool) ([]byte, error) {
+	// The root is resolved as "."; it has no parent and gets the heading
+	if path == "." {
+		path = ""
+	}
 
 	// Calculate parent path for "Parent Directory" link
 	var parentPath string
@@ -198,13 +532,43 @@ func renderDirectoryListing(c *gin.Context, path string, files []FileInfo) {
 		"Path":       path,
 		"ParentPath": parentPath,
 		"Files":      files,
+		"L":          l,
+		"Branding":   branding,
+		"Columns":    columns,
+	}
+
+	var buf bytes.Buffer
+	if err := directoryTemplate.Execute(&buf, data); err != nil {
+		return nil, err
 	}
+	return buf.Bytes(), nil
+}
+
+// renderTextListing renders a plain text listing for scripts and minimal
+// clients: one line per entry with the name (directories end in /), the size
+// in bytes ("-" for directories), and the UTC modification time, separated by
+// tabs. Names that contain tabs or line breaks are quoted, Go-style.
+func renderTextListing(files []FileInfo) []byte {
+	var buf bytes.Buffer
+	for _, f := range files {
+		name, size := f.Name, strconv.FormatInt(f.SizeBytes, 10)
+		if strings.ContainsAny(name, "\t\r\n") {
+			name = strconv.Quote(name)
+		}
+		if f.IsDir {
+			name, size = name+"/", "-"
+		}
+		fmt.Fprintf(&buf, "%s\t%s\t%s\n", name, size, f.ModifiedTime.UTC().Format(time.RFC3339))
+	}
+	return buf.Bytes()
+}
 
-	c.Header("Content-Type", "text/html; charset=utf-8")
-	if err := tmpl.Execute(c.Writer, data); err != nil {
-		slog.Error("failed to execute template", slog.Any("error", err))
-		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to generate directory listing")
+// writeDirectoryListing sends a rendered listing.
+func writeDirectoryListing(c *gin.Context, body []byte, contentType, cacheControl string) {
+	if cacheControl != "" {
+		c.Header("Cache-Control", cacheControl)
 	}
+	c.Data(http.StatusOK, contentType, body)
 }
 
 // WalkDirectory recursively walks a directory and returns all files.
@@ -3,10 +3,19 @@ package api
 import (
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/download"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/service"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+	"github.com/aloks98/isoman/backend/internal/throughput"
 
 	"github.com/gin-gonic/gin"
 )
@@ -120,6 +129,59 @@ func TestDirectoryHandlerServeFile(t *testing.T) {
 	}
 }
 
+// TestDirectoryHandlerEgressMeter tests that served file bytes are metered and listings are not.
+func TestDirectoryHandlerEgressMeter(t *testing.T) {
+	isoDir, cleanup := setupTestDirectory(t)
+	defer cleanup()
+
+	meter := &throughput.Meter{}
+	handler := DirectoryHandler(&DirectoryHandlerConfig{ISODir: isoDir, EgressMeter: meter})
+
+	for _, p := range []string{"/alpine/3.19.1/x86_64/alpine.iso", "/alpine/"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/images"+p, http.NoBody)
+		c.Params = gin.Params{{Key: "filepath", Value: p}}
+		handler(c)
+	}
+
+	if meter.Total() != int64(len("test alpine content")) {
+		t.Errorf("Expected %d metered bytes, got: %d", len("test alpine content"), meter.Total())
+	}
+}
+
+// TestDirectoryHandlerCacheControl tests that files, listings, and misses get their own Cache-Control.
+func TestDirectoryHandlerCacheControl(t *testing.T) {
+	isoDir, cleanup := setupTestDirectory(t)
+	defer cleanup()
+
+	handler := DirectoryHandler(&DirectoryHandlerConfig{
+		ISODir:              isoDir,
+		FileCacheControl:    "public, max-age=86400",
+		ListingCacheControl: "public, max-age=60",
+	})
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/alpine/3.19.1/x86_64/alpine.iso", "public, max-age=86400"},
+		{"/alpine/", "public, max-age=60"},
+		{"/nonexistent.iso", ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/images"+tt.path, http.NoBody)
+		c.Params = gin.Params{{Key: "filepath", Value: tt.path}}
+		handler(c)
+
+		if got := w.Header().Get("Cache-Control"); got != tt.want {
+			t.Errorf("%s: expected Cache-Control %q, got: %q", tt.path, tt.want, got)
+		}
+	}
+}
+
 // TestDirectoryHandlerFileNotFound tests 404 for non-existent file.
 func TestDirectoryHandlerFileNotFound(t *testing.T) {
 	isoDir, cleanup := setupTestDirectory(t)
@@ -184,6 +246,440 @@ func TestDirectoryHandlerHiddenFilesSkipped(t *testing.T) {
 	}
 }
 
+// TestDirectoryHandlerHiddenPathsNotServed tests that files under hidden directories return 404.
+func TestDirectoryHandlerHiddenPathsNotServed(t *testing.T) {
+	isoDir, cleanup := setupTestDirectory(t)
+	defer cleanup()
+
+	os.MkdirAll(filepath.Join(isoDir, ".quarantine", "alpine"), 0o755)
+	os.WriteFile(filepath.Join(isoDir, ".quarantine", "alpine", "alpine.iso"), []byte("flagged"), 0o644)
+
+	for _, path := range []string{"/.quarantine/alpine/alpine.iso", "/.quarantine/"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/images"+path, http.NoBody)
+		c.Params = gin.Params{{Key: "filepath", Value: path}}
+
+		handler := DirectoryHandler(&DirectoryHandlerConfig{ISODir: isoDir})
+		handler(c)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404 for %s, got: %d", path, w.Code)
+		}
+	}
+}
+
+// TestDirectoryHandlerPathTraversal tests that crafted paths can't reach files outside the ISO directory.
+func TestDirectoryHandlerPathTraversal(t *testing.T) {
+	root := t.TempDir()
+	isoDir := filepath.Join(root, "isos")
+	os.MkdirAll(filepath.Join(isoDir, "alpine"), 0o755)
+	os.WriteFile(filepath.Join(isoDir, "alpine", "alpine.iso"), []byte("alpine"), 0o644)
+	os.WriteFile(filepath.Join(root, "secret.txt"), []byte("secret"), 0o644)
+	os.MkdirAll(filepath.Join(root, "isos-other"), 0o755)
+	os.WriteFile(filepath.Join(root, "isos-other", "secret.txt"), []byte("secret"), 0o644)
+
+	router := gin.New()
+	router.GET("/images/*filepath", DirectoryHandler(&DirectoryHandlerConfig{ISODir: isoDir}))
+
+	tests := []string{
+		"/images/../secret.txt",
+		"/images/alpine/../../secret.txt",
+		"/images/..%2fsecret.txt",
+		"/images/%2e%2e/secret.txt",
+		"/images/%2e%2e%2fsecret.txt",
+		"/images/alpine/%2e%2e/%2e%2e/secret.txt",
+		"/images/../isos-other/secret.txt",
+		"/images/alpine%00.iso",
+	}
+	for _, target := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/images/", http.NoBody)
+		req.URL.RawPath = ""
+		req.URL.Path, _ = url.PathUnescape(target)
+		router.ServeHTTP(w, req)
+
+		if strings.Contains(w.Body.String(), "secret") {
+			t.Errorf("%s: file outside the ISO directory was served", target)
+		}
+		if w.Code == http.StatusOK && !strings.Contains(w.Body.String(), "alpine") {
+			t.Errorf("%s: unexpected 200 response: %s", target, w.Body.String())
+		}
+	}
+
+	// Redundant segments inside the ISO directory still resolve
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/images/", http.NoBody)
+	req.URL.Path = "/images/alpine/./alpine.iso"
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "alpine" {
+		t.Errorf("Expected in-tree path to be served, got: %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestResolveRequestPath(t *testing.T) {
+	isoDir := filepath.FromSlash("/data/isos")
+
+	tests := []struct {
+		input string
+		rel   string
+		ok    bool
+	}{
+		{"/", ".", true},
+		{"", ".", true},
+		{"/alpine/alpine.iso", "alpine/alpine.iso", true},
+		{"/../../etc/passwd", "etc/passwd", true}, // Clamped to the root
+		{"/alpine/./../ubuntu/", "ubuntu", true},
+		{"/alpine\x00.iso", "", false},
+	}
+
+	for _, tt := range tests {
+		rel, fullPath, ok := resolveRequestPath(isoDir, tt.input)
+		if ok != tt.ok || rel != tt.rel {
+			t.Errorf("resolveRequestPath(%q) = %q, %v; want %q, %v", tt.input, rel, ok, tt.rel, tt.ok)
+			continue
+		}
+		if ok && !strings.HasPrefix(fullPath, isoDir) {
+			t.Errorf("resolveRequestPath(%q) escaped the ISO dir: %s", tt.input, fullPath)
+		}
+	}
+}
+
+// TestDirectoryHandlerCatalogColumns tests that managed files show their ISO status and download count.
+func TestDirectoryHandlerCatalogColumns(t *testing.T) {
+	database, dbCleanup := testutil.SetupTestDB(t)
+	defer dbCleanup()
+
+	isoDir := t.TempDir()
+	iso := testutil.CreateAndInsertTestISO(t, database, &testutil.TestISO{Status: models.StatusComplete})
+	for range 3 {
+		if err := database.IncrementDownloadCount(iso.ID); err != nil {
+			t.Fatalf("IncrementDownloadCount() failed: %v", err)
+		}
+	}
+	testutil.CreateTestFile(t, filepath.Join(isoDir, filepath.Dir(iso.FilePath)), iso.Filename, "iso")
+	testutil.CreateTestFile(t, filepath.Join(isoDir, filepath.Dir(iso.FilePath)), "notes.txt", "unmanaged")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "filepath", Value: "/" + filepath.ToSlash(filepath.Dir(iso.FilePath))}}
+
+	handler := DirectoryHandler(&DirectoryHandlerConfig{ISODir: isoDir, DB: database})
+	handler(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", w.Code)
+	}
+	body := w.Body.String()
+
+	if !strings.Contains(body, ">Verified</span>") {
+		t.Error("Complete ISO with a checksum URL should be marked verified")
+	}
+	if !strings.Contains(body, ">complete</span>") {
+		t.Error("Listing should show the ISO status")
+	}
+	if !strings.Contains(body, ">3</span>") {
+		t.Error("Listing should show the download count")
+	}
+	if strings.Count(body, "title=\"Downloads\"") != 1 {
+		t.Error("Only the managed file should have catalog columns")
+	}
+}
+
+// TestDirectoryHandlerTextFormat tests the plain text listing.
+func TestDirectoryHandlerTextFormat(t *testing.T) {
+	isoDir, cleanup := setupTestDirectory(t)
+	defer cleanup()
+	dir := filepath.Join(isoDir, "alpine", "3.19.1", "x86_64")
+	os.Mkdir(filepath.Join(dir, "extras"), 0o755)
+	os.WriteFile(filepath.Join(dir, "odd\tname.txt"), []byte("x"), 0o644)
+	modified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	os.Chtimes(filepath.Join(dir, "alpine.iso"), modified, modified)
+
+	handler := DirectoryHandler(&DirectoryHandlerConfig{ISODir: isoDir, ListingCacheTTL: time.Minute})
+	list := func(format string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/images/alpine/3.19.1/x86_64/?format="+format, http.NoBody)
+		c.Params = gin.Params{{Key: "filepath", Value: "/alpine/3.19.1/x86_64/"}}
+		handler(c)
+		return w
+	}
+
+	for range 2 { // The second round comes from the cache
+		w := list("txt")
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+			t.Fatalf("Expected a plain text listing, got %d %s", w.Code, w.Header().Get("Content-Type"))
+		}
+		lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("Expected 3 entries, got:\n%s", w.Body.String())
+		}
+		if !strings.HasPrefix(lines[0], "extras/\t-\t") {
+			t.Errorf("Expected the directory first, got %q", lines[0])
+		}
+		if lines[1] != "alpine.iso\t19\t2024-03-01T12:00:00Z" {
+			t.Errorf("Expected name, size in bytes, and UTC time, got %q", lines[1])
+		}
+		if !strings.HasPrefix(lines[2], `"odd\tname.txt"`+"\t1\t") {
+			t.Errorf("Expected a name with a tab quoted, got %q", lines[2])
+		}
+
+		if body := list("html").Body.String(); !strings.Contains(body, "<!DOCTYPE html>") || !strings.Contains(body, `href="?format=txt"`) {
+			t.Error("Expected the HTML listing, linking the text one")
+		}
+	}
+
+	if w := list("json"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown format, got: %d", w.Code)
+	}
+}
+
+// TestDirectoryHandlerColumnSet tests that listings show only the chosen columns.
+func TestDirectoryHandlerColumnSet(t *testing.T) {
+	database, dbCleanup := testutil.SetupTestDB(t)
+	defer dbCleanup()
+
+	isoDir := t.TempDir()
+	iso := testutil.CreateAndInsertTestISO(t, database, &testutil.TestISO{Status: models.StatusComplete})
+	iso.Checksum = "9a4ac8ac0ea8d2a27ee6a4b5b5a0bb9a41c3d3c3e2a3fa7e0e2b4d2b7a1c0d9e"
+	if err := database.UpdateISO(iso); err != nil {
+		t.Fatalf("UpdateISO() failed: %v", err)
+	}
+	testutil.CreateTestFile(t, filepath.Join(isoDir, filepath.Dir(iso.FilePath)), iso.Filename, "iso")
+
+	branding := service.NewBrandingService(database)
+	handler := DirectoryHandler(&DirectoryHandlerConfig{ISODir: isoDir, DB: database, Branding: branding})
+	list := func() string {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/images/", http.NoBody)
+		c.Params = gin.Params{{Key: "filepath", Value: "/" + filepath.ToSlash(filepath.Dir(iso.FilePath))}}
+		handler(c)
+		return w.Body.String()
+	}
+
+	// The defaults leave out the checksum
+	body := list()
+	if !strings.Contains(body, ">complete</span>") || !strings.Contains(body, `title="Downloads"`) || !strings.Contains(body, "min-w-[80px]") || !strings.Contains(body, "min-w-[140px]") {
+		t.Error("Expected the default columns")
+	}
+	if strings.Contains(body, "sha256:9a4ac8ac0ea8") {
+		t.Error("The checksum column should be off by default")
+	}
+
+	if _, err := branding.SetListingColumns([]string{"checksum", "size"}); err != nil {
+		t.Fatalf("SetListingColumns() failed: %v", err)
+	}
+	body = list()
+	if !strings.Contains(body, "sha256:9a4ac8ac0ea8…") || !strings.Contains(body, iso.Checksum) {
+		t.Error("Expected the abbreviated checksum with the full one in its title")
+	}
+	if !strings.Contains(body, "min-w-[80px]") {
+		t.Error("Expected the size column")
+	}
+	for _, hidden := range []string{">complete</span>", `title="Downloads"`, "min-w-[140px]"} {
+		if strings.Contains(body, hidden) {
+			t.Errorf("Expected %s left out", hidden)
+		}
+	}
+
+	if _, err := branding.SetListingColumns([]string{}); err != nil {
+		t.Fatalf("SetListingColumns() failed: %v", err)
+	}
+	if body = list(); !strings.Contains(body, iso.Filename) || strings.Contains(body, "min-w-[80px]") || strings.Contains(body, "sha256:") {
+		t.Error("Expected names only")
+	}
+}
+
+// TestDirectoryHandlerLanguage tests that listings are rendered in the
+// language Accept-Language prefers, and cached per language.
+func TestDirectoryHandlerLanguage(t *testing.T) {
+	database, dbCleanup := testutil.SetupTestDB(t)
+	defer dbCleanup()
+
+	isoDir := t.TempDir()
+	iso := testutil.CreateAndInsertTestISO(t, database, &testutil.TestISO{Status: models.StatusComplete})
+	testutil.CreateTestFile(t, filepath.Join(isoDir, filepath.Dir(iso.FilePath)), iso.Filename, "iso")
+
+	handler := DirectoryHandler(&DirectoryHandlerConfig{
+		ISODir:            isoDir,
+		DB:                database,
+		ListingCacheTTL:   time.Minute,
+		NegotiateLanguage: true,
+	})
+	list := func(acceptLanguage string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/images/", http.NoBody)
+		c.Request.Header.Set("Accept-Language", acceptLanguage)
+		c.Params = gin.Params{{Key: "filepath", Value: "/" + filepath.ToSlash(filepath.Dir(iso.FilePath))}}
+		handler(c)
+		return w
+	}
+
+	for range 2 { // The second round comes from the cache
+		w := list("de-DE,de;q=0.9,en;q=0.8")
+		body := w.Body.String()
+		if !strings.Contains(body, `<html lang="de">`) || !strings.Contains(body, "Übergeordnetes Verzeichnis") {
+			t.Error("Expected a German listing")
+		}
+		if !strings.Contains(body, ">Verifiziert</span>") || !strings.Contains(body, ">fertig</span>") {
+			t.Error("Expected German badges")
+		}
+		if w.Header().Get("Vary") != "Accept-Language" {
+			t.Errorf("Expected Vary: Accept-Language, got %q", w.Header().Get("Vary"))
+		}
+
+		body = list("ja").Body.String()
+		if !strings.Contains(body, `<html lang="en">`) || !strings.Contains(body, "Parent Directory") {
+			t.Error("Expected an English listing for a language without messages")
+		}
+	}
+}
+
+// TestDirectoryHandlerBytesServed tests that the bytes sent of a managed file, not its size, are tracked.
+// TestDirectoryHandlerBranding tests that listings carry the stored branding.
+func TestDirectoryHandlerBranding(t *testing.T) {
+	isoDir, cleanup := setupTestDirectory(t)
+	defer cleanup()
+	database, dbCleanup := testutil.SetupTestDB(t)
+	defer dbCleanup()
+
+	branding := service.NewBrandingService(database)
+	handler := DirectoryHandler(&DirectoryHandlerConfig{ISODir: isoDir, DB: database, Branding: branding})
+	list := func() string {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/images/", http.NoBody)
+		c.Params = gin.Params{{Key: "filepath", Value: "/"}}
+		handler(c)
+		return w.Body.String()
+	}
+
+	body := list()
+	if !strings.Contains(body, `primary: "#2563eb"`) || !strings.Contains(body, "from-slate-50") || strings.Contains(body, "<img") {
+		t.Error("Expected the built-in look without branding")
+	}
+	if strings.Contains(body, "Parent Directory") {
+		t.Error("The root listing should have no parent directory link")
+	}
+
+	title, logo, footer, primary, background := "Example <Corp> Mirror", "https://cdn.example.com/logo.svg", "Operated by Example Corp IT", "#b91c1c", "#fafaf9"
+	if _, err := branding.UpdateListingBranding(models.UpdateListingBrandingRequest{
+		Title: &title, LogoURL: &logo, FooterText: &footer, PrimaryColor: &primary, BackgroundColor: &background,
+	}); err != nil {
+		t.Fatalf("UpdateListingBranding() failed: %v", err)
+	}
+
+	body = list()
+	for _, want := range []string{
+		"<title>Example &lt;Corp&gt; Mirror - Index of /images/</title>",
+		"Example &lt;Corp&gt; Mirror\n",
+		`<img src="https://cdn.example.com/logo.svg"`,
+		"<p class=\"mb-1 text-slate-600\">Operated by Example Corp IT</p>",
+		`primary: "#b91c1c"`,
+		`style="background-color: #fafaf9"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %s in the branded listing", want)
+		}
+	}
+	if strings.Contains(body, "from-slate-50") {
+		t.Error("Expected the background color to replace the gradient")
+	}
+}
+
+func TestDirectoryHandlerBytesServed(t *testing.T) {
+	database, dbCleanup := testutil.SetupTestDB(t)
+	defer dbCleanup()
+
+	isoDir := t.TempDir()
+	iso := testutil.CreateAndInsertTestISO(t, database, &testutil.TestISO{Status: models.StatusComplete})
+	testutil.CreateTestFile(t, filepath.Join(isoDir, filepath.Dir(iso.FilePath)), iso.Filename, "0123456789")
+
+	handler := DirectoryHandler(&DirectoryHandlerConfig{ISODir: isoDir, DB: database, StatsService: service.NewStatsService(database)})
+	for _, rangeHeader := range []string{"", "bytes=0-3"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/images/"+filepath.ToSlash(iso.FilePath), http.NoBody)
+		if rangeHeader != "" {
+			c.Request.Header.Set("Range", rangeHeader)
+		}
+		c.Params = gin.Params{{Key: "filepath", Value: "/" + filepath.ToSlash(iso.FilePath)}}
+		handler(c)
+	}
+
+	// Tracking is asynchronous
+	var served int64
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		retrieved, err := database.GetISO(iso.ID)
+		if err != nil {
+			t.Fatalf("GetISO() failed: %v", err)
+		}
+		if served = retrieved.BytesServed; served == 14 {
+			break
+		}
+	}
+	if served != 14 {
+		t.Errorf("Expected 14 bytes served for a full and a 4-byte range transfer, got %d", served)
+	}
+}
+
+// TestDirectoryHandlerVerifyOnServe tests that full transfers of a corrupt ISO are flagged.
+func TestDirectoryHandlerVerifyOnServe(t *testing.T) {
+	database, dbCleanup := testutil.SetupTestDB(t)
+	defer dbCleanup()
+
+	isoDir := t.TempDir()
+	iso := testutil.CreateAndInsertTestISO(t, database, &testutil.TestISO{Status: models.StatusComplete})
+	filePath := testutil.CreateTestFile(t, filepath.Join(isoDir, filepath.Dir(iso.FilePath)), iso.Filename, "intact")
+	integrityHash, _ := download.ComputeIntegrityHash(filePath, "sha256")
+	if err := database.UpdateISOIntegrityHash(iso.ID, integrityHash); err != nil {
+		t.Fatalf("UpdateISOIntegrityHash() failed: %v", err)
+	}
+
+	handler := DirectoryHandler(&DirectoryHandlerConfig{ISODir: isoDir, DB: database, VerifyOnServe: true})
+	serve := func(rangeHeader string) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/images/"+filepath.ToSlash(iso.FilePath), http.NoBody)
+		if rangeHeader != "" {
+			c.Request.Header.Set("Range", rangeHeader)
+		}
+		c.Params = gin.Params{{Key: "filepath", Value: "/" + filepath.ToSlash(iso.FilePath)}}
+		handler(c)
+	}
+	mismatches := func() int {
+		events, err := database.ListSystemEvents(db.SystemEventsParams{Limit: 10})
+		if err != nil {
+			t.Fatalf("ListSystemEvents() failed: %v", err)
+		}
+		count := 0
+		for _, event := range events {
+			if event.Type == models.EventIntegrityMismatch {
+				count++
+			}
+		}
+		return count
+	}
+
+	serve("")
+	if n := mismatches(); n != 0 {
+		t.Fatalf("Expected no mismatch for an intact file, got %d", n)
+	}
+
+	os.WriteFile(filePath, []byte("rotted"), 0o644)
+	serve("bytes=0-2")
+	if n := mismatches(); n != 0 {
+		t.Errorf("Expected range requests to be skipped, got %d mismatches", n)
+	}
+	serve("")
+	if n := mismatches(); n != 1 {
+		t.Errorf("Expected 1 mismatch for a corrupt file, got %d", n)
+	}
+}
+
 // TestFormatSize tests the formatSize function.
 func TestFormatSize(t *testing.T) {
 	tests := []struct {
@@ -419,3 +915,86 @@ func TestDirectoryHandlerDirectorySizeDisplay(t *testing.T) {
 		t.Error("HTML should show '7 B' for file size")
 	}
 }
+
+// TestDirectoryHandlerStreamInProgress tests that a downloading ISO is served as it grows, holding its last byte until complete.
+func TestDirectoryHandlerStreamInProgress(t *testing.T) {
+	database, dbCleanup := testutil.SetupTestDB(t)
+	defer dbCleanup()
+
+	isoDir, tmpDir := t.TempDir(), t.TempDir()
+	handler := DirectoryHandler(&DirectoryHandlerConfig{ISODir: isoDir, TempDir: tmpDir, DB: database, StreamInProgress: true})
+	start := func(iso *models.ISO) (*httptest.ResponseRecorder, chan struct{}) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/images/"+filepath.ToSlash(iso.FilePath), http.NoBody)
+		c.Params = gin.Params{{Key: "filepath", Value: "/" + filepath.ToSlash(iso.FilePath)}}
+		done := make(chan struct{})
+		go func() {
+			handler(c)
+			close(done)
+		}()
+		return w, done
+	}
+	waitFor := func(done chan struct{}, what string) {
+		t.Helper()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Stream didn't end after %s", what)
+		}
+	}
+
+	t.Run("complete", func(t *testing.T) {
+		iso := testutil.CreateAndInsertTestISO(t, database, &testutil.TestISO{Name: "alpine", Status: models.StatusDownloading})
+		database.UpdateISOSize(iso.ID, 10)
+		tmpFile := testutil.CreateTestFile(t, tmpDir, iso.Filename, "01234")
+		w, done := start(iso)
+
+		// Every byte has arrived, but the last one waits for the file to be in place
+		os.WriteFile(tmpFile, []byte("0123456789"), 0o644)
+		select {
+		case <-done:
+			t.Fatalf("Stream ended before the download completed: %q", w.Body.String())
+		case <-time.After(3 * inProgressPollInterval):
+		}
+
+		finalFile := filepath.Join(isoDir, iso.FilePath)
+		os.MkdirAll(filepath.Dir(finalFile), 0o755)
+		os.Rename(tmpFile, finalFile)
+		database.UpdateISOStatus(iso.ID, models.StatusComplete, "")
+		waitFor(done, "completing")
+
+		if w.Code != http.StatusOK || w.Body.String() != "0123456789" || w.Header().Get("Content-Length") != "10" {
+			t.Errorf("Expected the whole file with its length, got %d %q (Content-Length %s)", w.Code, w.Body.String(), w.Header().Get("Content-Length"))
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		iso := testutil.CreateAndInsertTestISO(t, database, &testutil.TestISO{Name: "debian", Status: models.StatusDownloading})
+		database.UpdateISOSize(iso.ID, 10)
+		testutil.CreateTestFile(t, tmpDir, iso.Filename, "01234")
+		w, done := start(iso)
+
+		time.Sleep(2 * inProgressPollInterval)
+		database.UpdateISOStatus(iso.ID, models.StatusFailed, "checksum mismatch")
+		waitFor(done, "failing")
+
+		if w.Body.String() != "01234" || w.Header().Get("Content-Length") != "10" {
+			t.Errorf("Expected the stream to stop short of its length, got %q (Content-Length %s)", w.Body.String(), w.Header().Get("Content-Length"))
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		iso := testutil.CreateAndInsertTestISO(t, database, &testutil.TestISO{Name: "fedora", Status: models.StatusDownloading})
+		testutil.CreateTestFile(t, tmpDir, iso.Filename, "01234")
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/images/"+filepath.ToSlash(iso.FilePath), http.NoBody)
+		c.Params = gin.Params{{Key: "filepath", Value: "/" + filepath.ToSlash(iso.FilePath)}}
+		DirectoryHandler(&DirectoryHandlerConfig{ISODir: isoDir, TempDir: tmpDir, DB: database})(c)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 without STREAM_IN_PROGRESS, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/aloks98/isoman/backend/internal/config"
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DiscoveryHandler serves /.well-known/isoman.json, describing where the API,
+// catalog, and feeds of this instance live and how to authenticate, with
+// URLs under EXTERNAL_URL or as the client reached the server. Like /health, it never requires a
+// session, so tools can read it before signing in.
+func DiscoveryHandler(cfg *config.Config, publicScopes map[string]bool, repoMetadata bool) gin.HandlerFunc {
+	auth := models.DiscoveryAuth{Enabled: cfg.Auth.Enabled, Modes: []string{}, PublicScopes: []string{}}
+	if cfg.Auth.Enabled {
+		auth.Modes = []string{models.AuthModeSession, models.AuthModeBearer, models.AuthModeDownloadToken}
+		for scope := range publicScopes {
+			if scope != constants.AuthScopeNone {
+				auth.PublicScopes = append(auth.PublicScopes, scope)
+			}
+		}
+		slices.Sort(auth.PublicScopes)
+	}
+
+	return func(c *gin.Context) {
+		base := publicBaseURL(cfg.Server.ExternalURL, c)
+		wsBase := "ws" + base[len("http"):]
+
+		doc := models.Discovery{
+			Name:      "isoman",
+			Version:   cfg.Version,
+			APIBase:   base + "/api",
+			WebSocket: wsBase + "/ws",
+			Auth:      auth,
+			Links: models.DiscoveryLinks{
+				Images: base + "/images/",
+				Feed:   base + "/feed.xml",
+				Status: base + "/status",
+				Health: base + "/health",
+			},
+			ReadOnly:   cfg.Replica.PrimaryURL != "",
+			PrimaryURL: cfg.Replica.PrimaryURL,
+		}
+		if cfg.Auth.Enabled {
+			doc.Auth.LoginURL = base + "/api/auth/login"
+		}
+		if repoMetadata {
+			doc.Links.RepoMetadata = base + "/repo/"
+		}
+
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, doc)
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/config"
+	"github.com/aloks98/isoman/backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDiscoveryHandler(t *testing.T) {
+	get := func(cfg *config.Config, repoMetadata bool) models.Discovery {
+		t.Helper()
+		router := gin.New()
+		router.GET("/.well-known/isoman.json", DiscoveryHandler(cfg, publicScopeSet(cfg.Auth.PublicScopes), repoMetadata))
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/.well-known/isoman.json", http.NoBody)
+		req.Host = "mirror.example.com"
+		req.Header.Set("X-Forwarded-Proto", "https")
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got: %d", w.Code)
+		}
+
+		var doc models.Discovery
+		if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+			t.Fatalf("Failed to decode discovery document: %v", err)
+		}
+		return doc
+	}
+
+	cfg := &config.Config{Version: "1.4.0"}
+	doc := get(cfg, false)
+	if doc.Name != "isoman" || doc.Version != "1.4.0" || doc.APIBase != "https://mirror.example.com/api" || doc.WebSocket != "wss://mirror.example.com/ws" {
+		t.Errorf("Unexpected discovery document: %+v", doc)
+	}
+	if doc.Links.Images != "https://mirror.example.com/images/" || doc.Links.Feed != "https://mirror.example.com/feed.xml" || doc.Links.RepoMetadata != "" {
+		t.Errorf("Unexpected links: %+v", doc.Links)
+	}
+	if doc.Auth.Enabled || len(doc.Auth.Modes) != 0 || doc.Auth.LoginURL != "" || doc.ReadOnly {
+		t.Errorf("Expected an open, writable instance, got %+v", doc)
+	}
+
+	cfg.Auth = config.AuthConfig{Enabled: true, PublicScopes: []string{"stats", "images", "none"}}
+	cfg.Replica.PrimaryURL = "https://primary.example.com"
+	doc = get(cfg, true)
+	if !doc.Auth.Enabled || len(doc.Auth.Modes) != 3 || doc.Auth.LoginURL != "https://mirror.example.com/api/auth/login" {
+		t.Errorf("Unexpected auth: %+v", doc.Auth)
+	}
+	if len(doc.Auth.PublicScopes) != 2 || doc.Auth.PublicScopes[0] != "images" || doc.Auth.PublicScopes[1] != "stats" {
+		t.Errorf("Expected public scopes [images stats], got %v", doc.Auth.PublicScopes)
+	}
+	if !doc.ReadOnly || doc.PrimaryURL != "https://primary.example.com" || doc.Links.RepoMetadata != "https://mirror.example.com/repo/" {
+		t.Errorf("Expected a replica with repo metadata, got %+v", doc)
+	}
+
+	cfg.Server.ExternalURL = "http://isos.internal:8080"
+	doc = get(cfg, false)
+	if doc.APIBase != "http://isos.internal:8080/api" || doc.WebSocket != "ws://isos.internal:8080/ws" {
+		t.Errorf("Expected URLs under the external URL, got %+v", doc)
+	}
+}
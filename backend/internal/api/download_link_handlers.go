@@ -0,0 +1,107 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// downloadTokenParam is the query parameter carrying a download link token.
+const downloadTokenParam = "token"
+
+// DownloadLinkHandlers holds references to the download link service.
+type DownloadLinkHandlers struct {
+	linkService *service.DownloadLinkService
+	externalURL string // Base of shared link URLs; empty uses the request's
+}
+
+// NewDownloadLinkHandlers creates a new DownloadLinkHandlers instance.
+func NewDownloadLinkHandlers(linkService *service.DownloadLinkService, externalURL string) *DownloadLinkHandlers {
+	return &DownloadLinkHandlers{
+		linkService: linkService,
+		externalURL: externalURL,
+	}
+}
+
+// CreateDownloadLink issues a link to one file. Its absolute URL, ready to
+// share, is only shown here.
+func (h *DownloadLinkHandlers) CreateDownloadLink(c *gin.Context) {
+	var req models.CreateDownloadLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+		return
+	}
+
+	createdBy := ""
+	if user := CurrentUser(c); user != nil {
+		createdBy = user.Username
+	}
+	link, err := h.linkService.CreateLink(req, createdBy)
+	var invalidErr *service.InvalidDownloadLinkError
+	if errors.As(err, &invalidErr) {
+		ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, invalidErr.Error())
+		return
+	}
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to create download link")
+		return
+	}
+	link.URL = publicBaseURL(h.externalURL, c) + link.URL
+
+	SuccessResponseWithMessage(c, http.StatusCreated, link, "Download link created")
+}
+
+// ListDownloadLinks returns the unexpired download links without their tokens.
+func (h *DownloadLinkHandlers) ListDownloadLinks(c *gin.Context) {
+	links, err := h.linkService.ListLinks()
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to retrieve download links")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, links)
+}
+
+// RevokeDownloadLink deletes a download link.
+func (h *DownloadLinkHandlers) RevokeDownloadLink(c *gin.Context) {
+	err := h.linkService.RevokeLink(c.Param("id"))
+	if errors.Is(err, db.ErrDownloadLinkNotFound) {
+		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "Download link not found")
+		return
+	}
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to revoke download link")
+		return
+	}
+
+	NoContentResponse(c)
+}
+
+// skipWithDownloadToken runs next unless the request carries a download link
+// token, which DirectoryHandler checks instead.
+func skipWithDownloadToken(next gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Query(downloadTokenParam) != "" {
+			c.Next()
+			return
+		}
+		next(c)
+	}
+}
+
+// countingWriter counts the bytes of a response body.
+type countingWriter struct {
+	gin.ResponseWriter
+	n int64
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.n += int64(n)
+	return n, err
+}
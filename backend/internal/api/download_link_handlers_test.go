@@ -0,0 +1,151 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aloks98/isoman/backend/internal/download"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/service"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+	"github.com/aloks98/isoman/backend/internal/ws"
+)
+
+func TestSingleUseDownloadLink(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	if err := os.MkdirAll(filepath.Join(env.ISODir, "alpine"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(env.ISODir, "alpine", "alpine.iso"), []byte("test alpine content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Private images, so only the link opens the file
+	env.Config.Auth.Enabled = true
+	env.Config.Auth.PublicScopes = []string{"none"}
+	env.Config.Server.ExternalURL = "https://isos.example.com"
+
+	manager := download.NewManager(env.DB, env.ISODir, 1)
+	defer manager.Stop()
+	router := setupTestRouter(env, service.NewISOService(env.DB, manager, env.ISODir), ws.NewHub())
+
+	authService := service.NewAuthService(env.DB, 0)
+	if _, err := authService.CreateUser("admin", "correct horse"); err != nil {
+		t.Fatalf("CreateUser() failed: %v", err)
+	}
+	login, err := authService.Login("admin", "correct horse", "", "", "")
+	if err != nil {
+		t.Fatalf("Login() failed: %v", err)
+	}
+
+	do := func(method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodGet, "/images/alpine/alpine.iso", "", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 without a link, got: %d", w.Code)
+	}
+
+	w := do(http.MethodPost, "/api/download-links", `{"path":"alpine/alpine.iso","single_use":true}`, map[string]string{
+		"Authorization": "Bearer " + login.Token,
+		"Content-Type":  "application/json",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data struct {
+			URL       string `json:"url"`
+			CreatedBy string `json:"created_by"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || !strings.HasPrefix(resp.Data.URL, "https://isos.example.com/images/alpine/alpine.iso?token=") || resp.Data.CreatedBy != "admin" {
+		t.Fatalf("Expected a shareable link URL created by admin, got %s (%v)", w.Body.String(), err)
+	}
+
+	if w := do(http.MethodGet, "/images/alpine/?token="+strings.SplitN(resp.Data.URL, "token=", 2)[1], "", nil); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 using the link on a directory, got: %d", w.Code)
+	}
+	if w := do(http.MethodGet, resp.Data.URL, "", map[string]string{"Range": "bytes=0-3"}); w.Code != http.StatusPartialContent {
+		t.Errorf("Expected 206 for a range request, got: %d", w.Code)
+	}
+	w = do(http.MethodGet, resp.Data.URL, "", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "test alpine content" {
+		t.Fatalf("Expected the file through the link, got: %d %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Cache-Control"); got != "private, no-store" {
+		t.Errorf("Expected a link response kept out of shared caches, got Cache-Control %q", got)
+	}
+	if w := do(http.MethodGet, resp.Data.URL, "", nil); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 reusing a single-use link, got: %d", w.Code)
+	}
+}
+
+func TestSingleUseDownloadLinkOverlap(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	if err := os.MkdirAll(filepath.Join(env.ISODir, "alpine"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	content := []byte("test alpine content")
+	if err := os.WriteFile(filepath.Join(env.ISODir, "alpine", "alpine.iso"), content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	links := service.NewDownloadLinkService(env.DB, env.ISODir)
+	created, err := links.CreateLink(models.CreateDownloadLinkRequest{Path: "alpine/alpine.iso", SingleUse: true}, "admin")
+	if err != nil {
+		t.Fatalf("CreateLink() failed: %v", err)
+	}
+	link, err := links.Resolve(strings.SplitN(created.URL, "token=", 2)[1], "alpine/alpine.iso")
+	if err != nil {
+		t.Fatalf("Resolve() failed: %v", err)
+	}
+	cfg := &DirectoryHandlerConfig{ISODir: env.ISODir, Links: links}
+
+	// get sends the file through the link, or the first sent bytes of it,
+	// calling during while the transfer is open
+	get := func(sent int, during func()) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/images/alpine/alpine.iso", http.NoBody)
+		serveFile(c, cfg, link, "alpine/alpine.iso", int64(len(content)), false, func() {
+			c.Status(http.StatusOK)
+			c.Writer.WriteHeaderNow()
+			if during != nil {
+				during()
+			}
+			_, _ = c.Writer.Write(content[:sent])
+		})
+		return w
+	}
+
+	var overlapped int
+	if w := get(4, func() { overlapped = get(len(content), nil).Code }); w.Code != http.StatusOK {
+		t.Fatalf("Expected the first download to start, got: %d", w.Code)
+	}
+	if overlapped != http.StatusGone {
+		t.Errorf("Expected 410 for a download overlapping the first, got: %d", overlapped)
+	}
+
+	// The first download ended short, so the link works again
+	if w := get(len(content), nil); w.Code != http.StatusOK || w.Body.String() != string(content) {
+		t.Fatalf("Expected the file after the short download, got: %d %s", w.Code, w.Body.String())
+	}
+	if w := get(len(content), nil); w.Code != http.StatusGone {
+		t.Errorf("Expected 410 once the whole file went out, got: %d", w.Code)
+	}
+}
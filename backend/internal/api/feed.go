@@ -0,0 +1,125 @@
+package api
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// feedEntryLimit is how many recently completed ISOs the feed lists.
+const feedEntryLimit = 50
+
+// atomFeed and its parts are the subset of RFC 4287 the ISO feed uses.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length int64  `xml:"length,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title   string     `xml:"title"`
+	ID      string     `xml:"id"`
+	Updated string     `xml:"updated"`
+	Links   []atomLink `xml:"link"`
+	Summary string     `xml:"summary"`
+}
+
+// FeedHandler serves /feed.xml, an Atom feed of the most recently completed
+// ISOs with absolute download links under externalURL, or the address the
+// request came in on when it is empty.
+func FeedHandler(database *db.DB, externalURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		isos, err := database.ListRecentlyCompletedISOs(feedEntryLimit)
+		if err != nil {
+			slog.Warn("failed to list ISOs for feed", slog.Any("error", err))
+			c.String(http.StatusInternalServerError, "Failed to build feed")
+			return
+		}
+
+		body, err := xml.MarshalIndent(buildFeed(publicBaseURL(externalURL, c), isos, time.Now()), "", "  ")
+		if err != nil {
+			c.String(http.StatusInternalServerError, "Failed to build feed")
+			return
+		}
+		c.Data(http.StatusOK, "application/atom+xml; charset=utf-8", append([]byte(xml.Header), body...))
+	}
+}
+
+// buildFeed turns ISOs, newest first, into an Atom feed rooted at baseURL.
+// An empty feed is dated now, since Atom requires an updated time.
+func buildFeed(baseURL string, isos []models.ISO, now time.Time) *atomFeed {
+	feed := &atomFeed{
+		Title:   "ISOMan: recently added ISOs",
+		ID:      baseURL + "/feed.xml",
+		Updated: now.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: baseURL + "/feed.xml", Rel: "self", Type: "application/atom+xml"},
+			{Href: baseURL + "/images/"},
+		},
+	}
+	if len(isos) > 0 && isos[0].CompletedAt != nil {
+		feed.Updated = isos[0].CompletedAt.UTC().Format(time.RFC3339)
+	}
+
+	for _, iso := range isos {
+		title := strings.Join(strings.Fields(strings.Join([]string{iso.Name, iso.Version, iso.Edition, iso.Arch}, " ")), " ")
+		summary := fmt.Sprintf("%s, %s", iso.Filename, formatSize(iso.SizeBytes))
+		if iso.Checksum != "" {
+			summary += fmt.Sprintf(", %s %s", iso.ChecksumType, iso.Checksum)
+		}
+
+		entry := atomEntry{
+			Title:   title,
+			ID:      "urn:isoman:iso:" + iso.ID,
+			Summary: summary,
+			Links: []atomLink{
+				{Href: baseURL + iso.DownloadLink, Rel: "enclosure", Length: iso.SizeBytes},
+				{Href: baseURL + iso.DownloadLink},
+			},
+		}
+		if iso.CompletedAt != nil {
+			entry.Updated = iso.CompletedAt.UTC().Format(time.RFC3339)
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed
+}
+
+// publicBaseURL returns the base of absolute links handed to clients:
+// externalURL (EXTERNAL_URL) when set, since behind a proxy that rewrites Host
+// the request can't tell the server's public address, and otherwise the
+// address the request came in on.
+func publicBaseURL(externalURL string, c *gin.Context) string {
+	if externalURL != "" {
+		return externalURL
+	}
+	return requestBaseURL(c)
+}
+
+// requestBaseURL returns the scheme and host the client used to reach the
+// server, honoring X-Forwarded-Proto from a TLS-terminating proxy.
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || strings.EqualFold(c.GetHeader("X-Forwarded-Proto"), "https") {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
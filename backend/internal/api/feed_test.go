@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestFeedHandler(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	completedAt := time.Date(2024, time.January, 15, 10, 35, 0, 0, time.UTC)
+	iso := testutil.CreateTestISO(&testutil.TestISO{Status: models.StatusComplete})
+	iso.CompletedAt = &completedAt
+	iso.Checksum = "abc123"
+	if err := env.DB.CreateISO(iso); err != nil {
+		t.Fatalf("CreateISO() failed: %v", err)
+	}
+	testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Version: "3.20.0"}) // Pending, not listed
+
+	router := gin.New()
+	router.GET("/feed.xml", FeedHandler(env.DB, ""))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/feed.xml", http.NoBody)
+	req.Host = "mirror.example.com"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/atom+xml") {
+		t.Fatalf("Expected an Atom feed, got: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	var feed atomFeed
+	if err := xml.Unmarshal(w.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("Failed to parse feed: %v\n%s", err, w.Body.String())
+	}
+	if len(feed.Entries) != 1 {
+		t.Fatalf("Expected one entry, got %d", len(feed.Entries))
+	}
+	entry := feed.Entries[0]
+	if entry.Title != "alpine-linux 3.19.1 standard x86_64" || entry.Updated != "2024-01-15T10:35:00Z" {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	if entry.Links[0].Rel != "enclosure" || entry.Links[0].Href != "https://mirror.example.com"+iso.DownloadLink || entry.Links[0].Length != iso.SizeBytes {
+		t.Errorf("Expected an absolute enclosure link, got %+v", entry.Links[0])
+	}
+	if !strings.Contains(entry.Summary, "1.0 MB") || !strings.Contains(entry.Summary, "abc123") {
+		t.Errorf("Expected size and checksum in the summary, got %q", entry.Summary)
+	}
+	if feed.Updated != entry.Updated {
+		t.Errorf("Feed updated = %s, want the newest entry's %s", feed.Updated, entry.Updated)
+	}
+
+	// A configured external URL wins over the address a proxy forwarded
+	router = gin.New()
+	router.GET("/feed.xml", FeedHandler(env.DB, "https://isos.example.com"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var external atomFeed
+	if err := xml.Unmarshal(w.Body.Bytes(), &external); err != nil {
+		t.Fatalf("Failed to parse feed: %v", err)
+	}
+	if href := external.Entries[0].Links[0].Href; href != "https://isos.example.com"+iso.DownloadLink {
+		t.Errorf("Expected a link under the external URL, got %s", href)
+	}
+}
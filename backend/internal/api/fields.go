@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fieldsParam trims ISO responses to the listed JSON fields, e.g.
+// ?fields=id,name,status, for dashboards polling big catalogs.
+const fieldsParam = "fields"
+
+// isoFields lists the JSON fields an ISO response can be trimmed to.
+var isoFields = jsonKeys(models.ISO{})
+
+// jsonKeys returns the keys v marshals to, sorted. v must marshal to an
+// object without omitempty fields.
+func jsonKeys(v any) []string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		panic(err)
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// parseFields returns the fields named by ?fields=, or nil when it is absent
+// or empty. Every name must be one of allowed.
+func parseFields(c *gin.Context, allowed []string) ([]string, error) {
+	var fields []string
+	errs := &validation.ValidationErrors{}
+	for _, field := range strings.Split(c.Query(fieldsParam), ",") {
+		field = strings.TrimSpace(field)
+		switch {
+		case field == "" || slices.Contains(fields, field):
+			continue
+		case !slices.Contains(allowed, field):
+			errs.Add(fieldsParam, fmt.Sprintf("unknown field %q", field))
+		default:
+			fields = append(fields, field)
+		}
+	}
+	if errs.HasErrors() {
+		return nil, errs
+	}
+	return fields, nil
+}
+
+// selectFields returns v, which marshals to an object or an array of objects,
+// with only fields kept. Nil fields returns v unchanged.
+func selectFields(v any, fields []string) (any, error) {
+	if fields == nil {
+		return v, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" {
+		return v, err
+	}
+
+	trim := func(object map[string]json.RawMessage) map[string]json.RawMessage {
+		kept := make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			if value, ok := object[field]; ok {
+				kept[field] = value
+			}
+		}
+		return kept
+	}
+
+	if len(data) > 0 && data[0] == '[' {
+		var objects []map[string]json.RawMessage
+		if err := json.Unmarshal(data, &objects); err != nil {
+			return nil, err
+		}
+		for i, object := range objects {
+			objects[i] = trim(object)
+		}
+		return objects, nil
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	return trim(object), nil
+}
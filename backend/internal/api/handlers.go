@@ -1,8 +1,13 @@
 package api
 
 import (
+	"context"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -18,23 +23,64 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// Handlers holds references to service layer and storage directory.
+// Handlers holds references to service layer and storage directories.
 type Handlers struct {
-	isoService *service.ISOService
-	isoDir     string
+	isoService     *service.ISOService
+	urlChecks      *validation.URLCheckOptions // nil skips live URL checks
+	isoDir         string
+	tmpDir         string
+	externalURL    string // Base of snippet URLs; empty uses the request's
+	imagesNeedAuth bool   // /images requires a session or download link token
+	database       *db.DB // Reported on by /health; nil omits the schema status
+	authEnabled    bool   // /api needs a session outside the public scopes
+	readOnly       bool   // A replica, which refuses catalog changes
 }
 
 // NewHandlers creates a new Handlers instance.
-func NewHandlers(isoService *service.ISOService, isoDir string) *Handlers {
+func NewHandlers(isoService *service.ISOService, isoDir, tmpDir string) *Handlers {
 	return &Handlers{
 		isoService: isoService,
 		isoDir:     isoDir,
+		tmpDir:     tmpDir,
 	}
 }
 
+// SetURLChecks sets the live checks run on download and checksum URLs when
+// ISOs are created or edited.
+func (h *Handlers) SetURLChecks(opts *validation.URLCheckOptions) {
+	h.urlChecks = opts
+}
+
+// SetSnippetConfig sets the base URL generated snippets point at and whether
+// fetching from /images needs authentication.
+func (h *Handlers) SetSnippetConfig(externalURL string, imagesNeedAuth bool) {
+	h.externalURL = externalURL
+	h.imagesNeedAuth = imagesNeedAuth
+}
+
+// SetAccessConfig sets whether the API requires a session and whether this
+// instance is a read-only replica, which /api/actions takes into account.
+func (h *Handlers) SetAccessConfig(authEnabled, readOnly bool) {
+	h.authEnabled = authEnabled
+	h.readOnly = readOnly
+}
+
+// SetSchemaSource sets the database whose schema version /health reports.
+func (h *Handlers) SetSchemaSource(database *db.DB) {
+	h.database = database
+}
+
 // ListISOs returns ISOs with optional pagination and sorting.
-// Query params: page (default 1), page_size (default 10), sort_by, sort_dir (asc/desc)
+// Query params: page (default 1), page_size (default 10), sort_by, sort_dir (asc/desc),
+// cursor (next_cursor of the previous page, instead of page), fields (comma-separated
+// ISO fields to return)
 func (h *Handlers) ListISOs(c *gin.Context) {
+	fields, err := parseFields(c, isoFields)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid fields", err)
+		return
+	}
+
 	// Parse pagination parameters
 	page := 1
 	if pageStr := c.Query("page"); pageStr != "" {
@@ -59,38 +105,204 @@ func (h *Handlers) ListISOs(c *gin.Context) {
 		PageSize: pageSize,
 		SortBy:   sortBy,
 		SortDir:  sortDir,
+		Cursor:   c.Query("cursor"),
 	}
 
 	result, err := h.isoService.ListISOsPaginated(params)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		ErrorResponse(c, http.StatusBadRequest, ErrCodeBadRequest, "Invalid cursor")
+		return
+	}
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to list ISOs")
+		return
+	}
+	isos, err := selectFields(result.ISOs, fields)
 	if err != nil {
 		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to list ISOs")
 		return
 	}
 
+	// A cursor page skips counting the catalog, so it has no page numbers
+	pagination := gin.H{
+		"page_size":   result.PageSize,
+		"next_cursor": result.NextCursor,
+	}
+	if params.Cursor == "" {
+		pagination["page"] = result.Page
+		pagination["total"] = result.Total
+		pagination["total_pages"] = result.TotalPages
+	}
+
 	SuccessResponse(c, http.StatusOK, gin.H{
-		"isos": result.ISOs,
-		"pagination": gin.H{
-			"page":        result.Page,
-			"page_size":   result.PageSize,
-			"total":       result.Total,
-			"total_pages": result.TotalPages,
-		},
+		"isos":       isos,
+		"pagination": pagination,
 	})
 }
 
-// GetISO returns a single ISO by ID.
+// GetISO returns a single ISO by ID, trimmed to ?fields= when given.
 func (h *Handlers) GetISO(c *gin.Context) {
 	id := c.Param("id")
+	fields, err := parseFields(c, isoFields)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid fields", err)
+		return
+	}
 
 	iso, err := h.isoService.GetISO(id)
 	if err != nil {
 		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "ISO not found")
 		return
 	}
+	data, err := selectFields(iso, fields)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to get ISO")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, data)
+}
+
+// WaitForISO holds the request until the ISO's download settles (complete,
+// failed, canceled, or quarantined) or ?timeout= seconds pass, so scripts can
+// wait for an image without a WebSocket client. It answers 200 with the
+// settled ISO, or 202 with the ISO as it is when the timeout runs out.
+func (h *Handlers) WaitForISO(c *gin.Context) {
+	timeout := constants.DefaultWaitTimeoutSec
+	if raw := c.Query("timeout"); raw != "" {
+		var err error
+		timeout, err = strconv.Atoi(raw)
+		if err != nil || timeout < 1 || timeout > constants.MaxWaitTimeoutSec {
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed,
+				fmt.Sprintf("timeout must be between 1 and %d seconds", constants.MaxWaitTimeoutSec))
+			return
+		}
+	}
+
+	// The wait can outlast the server's write timeout, so it is extended
+	wait := time.Duration(timeout) * time.Second
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(wait + 10*time.Second)) //nolint:errcheck // Not every writer supports deadlines
 
+	ctx, cancel := context.WithTimeout(c.Request.Context(), wait)
+	defer cancel()
+	iso, settled, err := h.isoService.WaitForISO(ctx, c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "ISO not found")
+		return
+	}
+	if !settled {
+		SuccessResponseWithMessage(c, http.StatusAccepted, iso, "Still "+string(iso.Status)+" after "+strconv.Itoa(timeout)+"s")
+		return
+	}
 	SuccessResponse(c, http.StatusOK, iso)
 }
 
+// FindISOsByChecksum returns the complete ISOs whose file has the SHA-256 in
+// the path, or 404 when the image isn't mirrored.
+func (h *Handlers) FindISOsByChecksum(c *gin.Context) {
+	sum := c.Param("sha256")
+	if _, err := hex.DecodeString(sum); err != nil || len(sum) != 64 {
+		ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, "sha256 must be a 64-character hexadecimal digest")
+		return
+	}
+
+	isos, err := h.isoService.FindISOsBySHA256(sum)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to look up checksum")
+		return
+	}
+	if len(isos) == 0 {
+		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "No complete ISO has this checksum")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, isos)
+}
+
+// GetISOSnippets returns ready-to-use client configuration for an ISO. With
+// ?type= it sends just that snippet as a file to download.
+func (h *Handlers) GetISOSnippets(c *gin.Context) {
+	iso, err := h.isoService.GetISO(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "ISO not found")
+		return
+	}
+
+	baseURL := publicBaseURL(h.externalURL, c)
+	snippets := service.BuildSnippets(iso, baseURL)
+
+	if snippetType := c.Query("type"); snippetType != "" {
+		for _, snippet := range snippets {
+			if snippet.Type == snippetType {
+				c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", snippet.Filename))
+				c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(snippet.Content))
+				return
+			}
+		}
+		ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, fmt.Sprintf("No %q snippet for this ISO", snippetType))
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, models.ISOSnippets{
+		ISOID:        iso.ID,
+		URL:          strings.TrimRight(baseURL, "/") + iso.DownloadLink,
+		RequiresAuth: h.imagesNeedAuth,
+		Snippets:     snippets,
+	})
+}
+
+// GetDownloadLog returns the log of an ISO's latest download run.
+func (h *Handlers) GetDownloadLog(c *gin.Context) {
+	entries, err := h.isoService.GetDownloadLog(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "ISO not found")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, entries)
+}
+
+// GetVerificationReport returns how an ISO's file was verified, for auditing
+// its provenance.
+func (h *Handlers) GetVerificationReport(c *gin.Context) {
+	report, err := h.isoService.VerificationReport(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "ISO not found")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, report)
+}
+
+// PreviewISO returns the normalized name, filename, path, and download link an
+// ISO would get, without creating anything.
+func (h *Handlers) PreviewISO(c *gin.Context) {
+	req := validation.ISOPreviewRequest{
+		Name:        c.Query("name"),
+		Version:     c.Query("version"),
+		Arch:        c.Query("arch"),
+		Edition:     c.Query("edition"),
+		DownloadURL: c.Query("download_url"),
+		FileType:    c.Query("file_type"),
+	}
+	if err := validation.ValidateISOPreviewRequest(&req); err != nil {
+		ValidationErrorResponse(c, "Validation failed", err)
+		return
+	}
+
+	preview, err := h.isoService.PreviewISO(service.PreviewISORequest(req))
+	if err != nil {
+		if strings.Contains(err.Error(), "invalid file type") {
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, err.Error())
+			return
+		}
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to preview ISO")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, preview)
+}
+
 // CreateISO creates a new ISO download.
 func (h *Handlers) CreateISO(c *gin.Context) {
 	var req validation.ISOCreateRequest
@@ -100,53 +312,182 @@ func (h *Handlers) CreateISO(c *gin.Context) {
 		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
 		return
 	}
+	req.Overwrite = c.Query("overwrite") == "true"
 
-	// Validate request
-	if err := validation.ValidateISOCreateRequest(&req); err != nil {
-		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Validation failed", err.Error())
+	h.createISO(c, req)
+}
+
+// CloneISO creates a new ISO download from an existing ISO's definition,
+// overridden by the fields of the request body.
+func (h *Handlers) CloneISO(c *gin.Context) {
+	var req models.UpdateISORequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
 		return
 	}
 
+	source, err := h.isoService.GetISO(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "ISO not found")
+		return
+	}
+
+	h.createISO(c, validation.ISOCreateRequest(service.CloneISORequest(source, req)))
+}
+
+// BumpVersion queues the given version of several ISOs, e.g. every edition
+// and arch of a distribution after a point release. Each ISO is handled on
+// its own, so one failure doesn't stop the others.
+func (h *Handlers) BumpVersion(c *gin.Context) {
+	var req models.VersionBumpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+		return
+	}
+
+	result := &models.VersionBumpResult{
+		Queued: []*models.ISO{},
+		Failed: []models.VersionBumpFailure{},
+	}
+	for _, id := range req.IDs {
+		iso, err := h.bumpVersion(c.Request.Context(), id, req.Version)
+		if err != nil {
+			result.Failed = append(result.Failed, models.VersionBumpFailure{ID: id, Error: err.Error()})
+			continue
+		}
+		result.Queued = append(result.Queued, iso)
+	}
+
+	message := fmt.Sprintf("Queued %d ISOs, %d failed", len(result.Queued), len(result.Failed))
+	SuccessResponseWithMessage(c, http.StatusOK, result, message)
+}
+
+// bumpVersion queues version of the ISO with id, validating the request
+// like CreateISO.
+func (h *Handlers) bumpVersion(ctx context.Context, id, version string) (*models.ISO, error) {
+	source, err := h.isoService.GetISO(id)
+	if err != nil {
+		return nil, errors.New("ISO not found")
+	}
+	createReq, err := h.isoService.VersionBumpRequest(source, version)
+	if err != nil {
+		return nil, err
+	}
+
+	req := validation.ISOCreateRequest(*createReq)
+	if err := validation.ValidateISOCreateRequestWithChecks(ctx, &req, h.urlChecks); err != nil {
+		return nil, err
+	}
+	iso, err := h.isoService.CreateISO(ctx, service.CreateISORequest(req))
+	var existsErr *service.ISOAlreadyExistsError
+	if errors.As(err, &existsErr) {
+		return nil, fmt.Errorf("ISO already exists (id=%s)", existsErr.ExistingISO.ID)
+	}
+	return iso, err
+}
+
+// createISO validates req, queues the download, and writes the response. It
+// returns the created ISO, or nil after writing an error.
+func (h *Handlers) createISO(c *gin.Context, req validation.ISOCreateRequest) *models.ISO {
+	// Validate request
+	if err := validation.ValidateISOCreateRequestWithChecks(c.Request.Context(), &req, h.urlChecks); err != nil {
+		ValidationErrorResponse(c, "Validation failed", err)
+		return nil
+	}
+
 	// Call service layer
-	iso, err := h.isoService.CreateISO(service.CreateISORequest{
-		Name:         req.Name,
-		Version:      req.Version,
-		Arch:         req.Arch,
-		Edition:      req.Edition,
-		DownloadURL:  req.DownloadURL,
-		ChecksumURL:  req.ChecksumURL,
-		ChecksumType: req.ChecksumType,
+	iso, err := h.isoService.CreateISO(c.Request.Context(), service.CreateISORequest{
+		Name:             req.Name,
+		Version:          req.Version,
+		Arch:             req.Arch,
+		Edition:          req.Edition,
+		DownloadURL:      req.DownloadURL,
+		ChecksumURL:      req.ChecksumURL,
+		ExpectedChecksum: req.ExpectedChecksum,
+		SignatureURL:     req.SignatureURL,
+		ChecksumType:     req.ChecksumType,
+		IPFamily:         req.IPFamily,
+		Credential:       req.Credential,
+		Preset:           req.Preset,
+		Overwrite:        req.Overwrite,
 	})
 	if err != nil {
 		// Check for specific error types
 		var existsErr *service.ISOAlreadyExistsError
 		if errors.As(err, &existsErr) {
-			c.JSON(http.StatusConflict, APIResponse{
-				Success: false,
-				Error: &APIError{
-					Code:    ErrCodeConflict,
-					Message: "ISO already exists",
-				},
-				Data: gin.H{
-					"existing": existsErr.ExistingISO,
-				},
+			message := "ISO already exists"
+			if req.Overwrite {
+				message = "ISO already exists and can only be overwritten when failed or canceled"
+			}
+			ProblemResponse(c, http.StatusConflict, &APIError{Code: ErrCodeConflict, Message: message}, nil, gin.H{
+				"existing": existsErr.ExistingISO,
+				"options":  conflictOptions(existsErr.ExistingISO),
 			})
-			return
+			return nil
+		}
+
+		var queueFullErr *service.QueueFullError
+		if errors.As(err, &queueFullErr) {
+			ErrorResponse(c, http.StatusTooManyRequests, ErrCodeQueueFull, queueFullErr.Error())
+			return nil
+		}
+
+		var credErr *service.InvalidCredentialError
+		if errors.As(err, &credErr) {
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, credErr.Error())
+			return nil
 		}
 
 		// Check if it's a validation error (invalid file type, etc.)
 		errMsg := err.Error()
 		if strings.Contains(errMsg, "unsupported file type") || strings.Contains(errMsg, "invalid file type") {
 			ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, err.Error())
-			return
+			return nil
 		}
 
 		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to create ISO")
-		return
+		return nil
 	}
 
 	// Return created ISO with 201 status
 	SuccessResponseWithMessage(c, http.StatusCreated, iso, "ISO download queued successfully")
+	return iso
+}
+
+// conflictOptions lists the requests that resolve a create conflicting with
+// existing, depending on its status.
+func conflictOptions(existing *models.ISO) []models.ConflictOption {
+	var options []models.ConflictOption
+	switch {
+	case existing.Status.IsRetryable():
+		options = append(options,
+			models.ConflictOption{
+				Action:      models.ConflictActionRetry,
+				Method:      http.MethodPost,
+				Path:        "/api/isos/" + existing.ID + "/retry",
+				Description: "Retry the existing download",
+			},
+			models.ConflictOption{
+				Action:      models.ConflictActionOverwrite,
+				Method:      http.MethodPost,
+				Path:        "/api/isos?overwrite=true",
+				Description: "Replace the existing ISO with this request",
+			},
+		)
+	case existing.Status == models.StatusComplete:
+		options = append(options, models.ConflictOption{
+			Action:      models.ConflictActionRefresh,
+			Method:      http.MethodPost,
+			Path:        "/api/isos/" + existing.ID + "/refresh",
+			Description: "Re-download the existing ISO if it changed upstream",
+		})
+	}
+	return append(options, models.ConflictOption{
+		Action:      models.ConflictActionEdition,
+		Method:      http.MethodPost,
+		Path:        "/api/isos",
+		Description: "Create it alongside the existing ISO with a different edition",
+	})
 }
 
 // DeleteISO deletes an ISO file and database record.
@@ -162,23 +503,38 @@ func (h *Handlers) DeleteISO(c *gin.Context) {
 
 	// Call service layer to delete ISO
 	if err := h.isoService.DeleteISO(id); err != nil {
+		if errors.Is(err, service.ErrCancellationPending) {
+			ErrorResponse(c, http.StatusConflict, ErrCodeConflict, err.Error())
+			return
+		}
 		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to delete ISO")
 		return
 	}
 
 	// Clean up files (best effort - files can be manually cleaned up later if needed)
 	filePath := pathutil.ConstructISOPath(h.isoDir, iso.FilePath)
-	tmpFile := pathutil.ConstructTempPath(h.isoDir, iso.Filename)
+	tmpFile := pathutil.ConstructTempPath(h.tmpDir, iso.Filename)
 
-	// Delete main ISO file and checksum files
+	// Delete main ISO file and its sidecar files
 	fileutil.DeleteFileSilently(filePath)
-	for _, ext := range constants.ChecksumExtensions {
+	for _, ext := range h.isoService.SidecarExtensions() {
 		fileutil.DeleteFileSilently(filePath + ext)
 	}
 
 	// Delete temp file if it exists
 	fileutil.DeleteFileSilently(tmpFile)
 
+	// Delete the quarantined file if the antivirus scan flagged it
+	fileutil.DeleteFileSilently(pathutil.ConstructQuarantinePath(h.isoDir, iso.FilePath))
+
+	// Delete versions kept by refreshes
+	if versions, err := filepath.Glob(pathutil.VersionGlob(h.isoDir, iso.FilePath)); err == nil {
+		for _, version := range versions {
+			fileutil.DeleteFileSilently(version)
+		}
+	}
+	h.isoService.Purger().PurgeISOFile(models.PurgeEventDeleted, iso.FilePath)
+
 	// Return success response
 	NoContentResponse(c)
 }
@@ -188,7 +544,7 @@ func (h *Handlers) RetryISO(c *gin.Context) {
 	id := c.Param("id")
 
 	// Call service layer to retry ISO
-	iso, err := h.isoService.RetryISO(id)
+	iso, err := h.isoService.RetryISO(c.Request.Context(), id)
 	if err != nil {
 		// Check for specific error types
 		var invalidStateErr *service.InvalidStateError
@@ -196,6 +552,16 @@ func (h *Handlers) RetryISO(c *gin.Context) {
 			ErrorResponse(c, http.StatusBadRequest, ErrCodeInvalidState, invalidStateErr.Error())
 			return
 		}
+		var conflictErr *service.DownloadConflictError
+		if errors.As(err, &conflictErr) {
+			ErrorResponse(c, http.StatusConflict, ErrCodeConflict, conflictErr.Error())
+			return
+		}
+		var queueFullErr *service.QueueFullError
+		if errors.As(err, &queueFullErr) {
+			ErrorResponse(c, http.StatusTooManyRequests, ErrCodeQueueFull, queueFullErr.Error())
+			return
+		}
 
 		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "ISO not found")
 		return
@@ -205,6 +571,370 @@ func (h *Handlers) RetryISO(c *gin.Context) {
 	SuccessResponseWithMessage(c, http.StatusOK, iso, "Download retry queued successfully")
 }
 
+// CancelISO stops an ISO's running download or verification.
+func (h *Handlers) CancelISO(c *gin.Context) {
+	id := c.Param("id")
+
+	iso, err := h.isoService.CancelISO(id)
+	if err != nil {
+		var invalidStateErr *service.InvalidStateError
+		if errors.As(err, &invalidStateErr) {
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeInvalidState, invalidStateErr.Error())
+			return
+		}
+		if errors.Is(err, service.ErrCancellationPending) {
+			ErrorResponse(c, http.StatusConflict, ErrCodeConflict, err.Error())
+			return
+		}
+
+		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "ISO not found")
+		return
+	}
+
+	SuccessResponseWithMessage(c, http.StatusOK, iso, "Download canceled")
+}
+
+// CheckUpstream checks whether an ISO's upstream file changed since it was downloaded.
+func (h *Handlers) CheckUpstream(c *gin.Context) {
+	id := c.Param("id")
+	refresh := c.Query("refresh") == "true"
+
+	if _, err := h.isoService.GetISO(id); err != nil {
+		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "ISO not found")
+		return
+	}
+
+	iso, err := h.isoService.CheckUpstream(c.Request.Context(), id, refresh)
+	if err != nil {
+		var invalidStateErr *service.InvalidStateError
+		if errors.As(err, &invalidStateErr) {
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeInvalidState, invalidStateErr.Error())
+			return
+		}
+		var conflictErr *service.DownloadConflictError
+		if errors.As(err, &conflictErr) {
+			ErrorResponse(c, http.StatusConflict, ErrCodeConflict, conflictErr.Error())
+			return
+		}
+		var queueFullErr *service.QueueFullError
+		if errors.As(err, &queueFullErr) {
+			ErrorResponse(c, http.StatusTooManyRequests, ErrCodeQueueFull, queueFullErr.Error())
+			return
+		}
+
+		ErrorResponseWithDetails(c, http.StatusBadGateway, ErrCodeUpstreamError, "Failed to check upstream", err.Error())
+		return
+	}
+
+	message := "Upstream unchanged"
+	if iso.UpstreamChanged {
+		message = "Upstream changed since download"
+	}
+	if iso.Status.IsActive() {
+		message = "Upstream changed, refresh queued"
+	}
+	SuccessResponseWithMessage(c, http.StatusOK, iso, message)
+}
+
+// RefreshISO re-downloads a complete ISO when its upstream file changed.
+func (h *Handlers) RefreshISO(c *gin.Context) {
+	id := c.Param("id")
+	force := c.Query("force") == "true"
+
+	if _, err := h.isoService.GetISO(id); err != nil {
+		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "ISO not found")
+		return
+	}
+
+	iso, err := h.isoService.RefreshISO(c.Request.Context(), id, force)
+	if err != nil {
+		var invalidStateErr *service.InvalidStateError
+		if errors.As(err, &invalidStateErr) {
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeInvalidState, invalidStateErr.Error())
+			return
+		}
+		var conflictErr *service.DownloadConflictError
+		if errors.As(err, &conflictErr) {
+			ErrorResponse(c, http.StatusConflict, ErrCodeConflict, conflictErr.Error())
+			return
+		}
+		var queueFullErr *service.QueueFullError
+		if errors.As(err, &queueFullErr) {
+			ErrorResponse(c, http.StatusTooManyRequests, ErrCodeQueueFull, queueFullErr.Error())
+			return
+		}
+
+		ErrorResponseWithDetails(c, http.StatusBadGateway, ErrCodeUpstreamError, "Failed to check upstream", err.Error())
+		return
+	}
+
+	message := "Upstream unchanged, nothing to refresh"
+	if iso.Status.IsActive() {
+		message = "Refresh queued successfully"
+	}
+	SuccessResponseWithMessage(c, http.StatusOK, iso, message)
+}
+
+// RedownloadISO downloads a complete ISO again, replacing its file only once
+// the new one is verified.
+func (h *Handlers) RedownloadISO(c *gin.Context) {
+	id := c.Param("id")
+
+	if _, err := h.isoService.GetISO(id); err != nil {
+		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "ISO not found")
+		return
+	}
+
+	iso, err := h.isoService.RedownloadISO(c.Request.Context(), id)
+	if err != nil {
+		var invalidStateErr *service.InvalidStateError
+		if errors.As(err, &invalidStateErr) {
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeInvalidState, invalidStateErr.Error())
+			return
+		}
+		var conflictErr *service.DownloadConflictError
+		if errors.As(err, &conflictErr) {
+			ErrorResponse(c, http.StatusConflict, ErrCodeConflict, conflictErr.Error())
+			return
+		}
+		var queueFullErr *service.QueueFullError
+		if errors.As(err, &queueFullErr) {
+			ErrorResponse(c, http.StatusTooManyRequests, ErrCodeQueueFull, queueFullErr.Error())
+			return
+		}
+
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to queue redownload")
+		return
+	}
+
+	SuccessResponseWithMessage(c, http.StatusOK, iso, "Redownload queued successfully")
+}
+
+// VerifyISO re-hashes an ISO's file on disk and compares it with the stored integrity hash.
+func (h *Handlers) VerifyISO(c *gin.Context) {
+	id := c.Param("id")
+
+	if _, err := h.isoService.GetISO(id); err != nil {
+		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "ISO not found")
+		return
+	}
+
+	result, err := h.isoService.VerifyISO(id)
+	if err != nil {
+		var invalidStateErr *service.InvalidStateError
+		if errors.As(err, &invalidStateErr) {
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeInvalidState, invalidStateErr.Error())
+			return
+		}
+
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to verify ISO")
+		return
+	}
+
+	message := "Integrity check passed"
+	switch {
+	case result.Baseline:
+		message = "Integrity hash recorded"
+	case !result.Valid:
+		message = "Integrity check failed"
+	}
+	SuccessResponseWithMessage(c, http.StatusOK, result, message)
+}
+
+// PinISO pins an ISO to the top of the list and exempts it from version pruning.
+func (h *Handlers) PinISO(c *gin.Context) {
+	h.setPinned(c, true, "ISO pinned")
+}
+
+// UnpinISO removes the pin from an ISO.
+func (h *Handlers) UnpinISO(c *gin.Context) {
+	h.setPinned(c, false, "ISO unpinned")
+}
+
+func (h *Handlers) setPinned(c *gin.Context, pinned bool, message string) {
+	id := c.Param("id")
+
+	if _, err := h.isoService.GetISO(id); err != nil {
+		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "ISO not found")
+		return
+	}
+
+	iso, err := h.isoService.SetPinned(id, pinned)
+	if err != nil {
+		ErrorResponseWithDetails(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to update ISO", err.Error())
+		return
+	}
+
+	SuccessResponseWithMessage(c, http.StatusOK, iso, message)
+}
+
+// ReleaseISO moves a quarantined ISO into place after an administrator has cleared it.
+func (h *Handlers) ReleaseISO(c *gin.Context) {
+	id := c.Param("id")
+
+	if _, err := h.isoService.GetISO(id); err != nil {
+		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "ISO not found")
+		return
+	}
+
+	iso, err := h.isoService.ReleaseISO(id)
+	if err != nil {
+		var invalidStateErr *service.InvalidStateError
+		if errors.As(err, &invalidStateErr) {
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeInvalidState, invalidStateErr.Error())
+			return
+		}
+
+		ErrorResponseWithDetails(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to release ISO", err.Error())
+		return
+	}
+
+	SuccessResponseWithMessage(c, http.StatusOK, iso, "ISO released from quarantine")
+}
+
+// AdoptDirectory registers image files from an existing mirror tree without downloading them.
+func (h *Handlers) AdoptDirectory(c *gin.Context) {
+	var req models.AdoptDirectoryRequest
+
+	// Parse JSON request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+		return
+	}
+
+	result, err := h.isoService.AdoptDirectory(req)
+	if err != nil {
+		var invalidErr *service.InvalidAdoptRequestError
+		if errors.As(err, &invalidErr) {
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, invalidErr.Error())
+			return
+		}
+
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to adopt directory")
+		return
+	}
+
+	message := fmt.Sprintf("Adopted %d files, skipped %d", len(result.Adopted), len(result.Skipped))
+	SuccessResponseWithMessage(c, http.StatusOK, result, message)
+}
+
+// ReconcilePaths fixes ISOs whose stored filename, path, or download link no
+// longer match their metadata, moving files to match.
+func (h *Handlers) ReconcilePaths(c *gin.Context) {
+	var req models.ReconcileRequest
+
+	// The body is optional; without it the fixes are applied
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+			return
+		}
+	}
+
+	result, err := h.isoService.ReconcilePaths(req.DryRun)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to reconcile ISO paths")
+		return
+	}
+
+	message := fmt.Sprintf("Fixed %d ISOs, skipped %d", len(result.Fixed), len(result.Skipped))
+	SuccessResponseWithMessage(c, http.StatusOK, result, message)
+}
+
+// ExportManifest returns a manifest of the ISO directory with file sizes and SHA-256 checksums.
+func (h *Handlers) ExportManifest(c *gin.Context) {
+	manifest, err := h.isoService.ExportManifest()
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to export manifest")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, manifest)
+}
+
+// ImportManifest verifies a copied data dir against a manifest and registers its ISOs.
+func (h *Handlers) ImportManifest(c *gin.Context) {
+	var req models.ManifestImportRequest
+
+	// Parse JSON request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+		return
+	}
+
+	result, err := h.isoService.ImportManifest(req)
+	if err != nil {
+		var invalidErr *service.InvalidAdoptRequestError
+		if errors.As(err, &invalidErr) {
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, invalidErr.Error())
+			return
+		}
+
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to import manifest")
+		return
+	}
+
+	message := fmt.Sprintf("Imported %d ISOs, skipped %d, %d failed verification", len(result.Imported), len(result.Skipped), len(result.Invalid))
+	SuccessResponseWithMessage(c, http.StatusOK, result, message)
+}
+
+// ExportBundle streams a tar archive of the selected ISOs, their checksum files, and a manifest.
+func (h *Handlers) ExportBundle(c *gin.Context) {
+	var req models.BundleExportRequest
+
+	// Parse JSON request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+		return
+	}
+
+	// Build the manifest first so missing or incomplete ISOs are reported as JSON errors
+	manifest, err := h.isoService.PrepareBundle(req.IDs)
+	if err != nil {
+		var invalidStateErr *service.InvalidStateError
+		if errors.As(err, &invalidStateErr) {
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeInvalidState, invalidStateErr.Error())
+			return
+		}
+
+		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("isoman-bundle-%s.tar", manifest.GeneratedAt.Format("20060102-150405"))
+	c.Header("Content-Type", "application/x-tar")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	// Headers are already sent, so a failure here can only be logged
+	if err := h.isoService.WriteBundle(c.Writer, manifest); err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to write bundle", slog.Any("error", err))
+	}
+}
+
+// ImportBundle ingests a tar bundle from the request body and registers its ISOs.
+func (h *Handlers) ImportBundle(c *gin.Context) {
+	dryRun := c.Query("dry_run") == "true"
+
+	result, err := h.isoService.ImportBundle(c.Request.Body, dryRun)
+	if err != nil {
+		if bodyTooLarge(c) {
+			return
+		}
+		var invalidErr *service.InvalidAdoptRequestError
+		if errors.As(err, &invalidErr) {
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, invalidErr.Error())
+			return
+		}
+
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to import bundle")
+		return
+	}
+
+	message := fmt.Sprintf("Imported %d ISOs, skipped %d, %d failed verification", len(result.Imported), len(result.Skipped), len(result.Invalid))
+	SuccessResponseWithMessage(c, http.StatusOK, result, message)
+}
+
 // UpdateISO updates an existing ISO.
 func (h *Handlers) UpdateISO(c *gin.Context) {
 	id := c.Param("id")
@@ -212,12 +942,39 @@ func (h *Handlers) UpdateISO(c *gin.Context) {
 	// Parse request body
 	var req models.UpdateISORequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		ErrorResponse(c, http.StatusBadRequest, ErrCodeBadRequest, "Invalid request body: "+err.Error())
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Run the live URL checks on changed URLs, as for new ISOs
+	errs := &validation.ValidationErrors{}
+	if req.DownloadURL != nil {
+		validation.CheckURLField(c.Request.Context(), errs, "download_url", *req.DownloadURL, h.urlChecks)
+	}
+	if req.ChecksumURL != nil && *req.ChecksumURL != "" {
+		validation.CheckURLField(c.Request.Context(), errs, "checksum_url", *req.ChecksumURL, h.urlChecks)
+	}
+	if req.SignatureURL != nil && *req.SignatureURL != "" {
+		validation.CheckURLField(c.Request.Context(), errs, "signature_url", *req.SignatureURL, h.urlChecks)
+	}
+	if req.ExpectedChecksum != nil && *req.ExpectedChecksum != "" {
+		if req.ChecksumURL != nil && *req.ChecksumURL != "" {
+			errs.Add("expected_checksum", "set either expected_checksum or checksum_url, not both")
+		} else {
+			checksumType := ""
+			if req.ChecksumType != nil {
+				checksumType = *req.ChecksumType
+			}
+			validation.CheckExpectedChecksum(errs, *req.ExpectedChecksum, checksumType)
+		}
+	}
+	if errs.HasErrors() {
+		ValidationErrorResponse(c, "Validation failed", errs)
 		return
 	}
 
 	// Call service layer to update ISO
-	iso, err := h.isoService.UpdateISO(id, req)
+	iso, err := h.isoService.UpdateISO(c.Request.Context(), id, req)
 	if err != nil {
 		// Check for specific error types
 		var invalidStateErr *service.InvalidStateError
@@ -225,18 +982,26 @@ func (h *Handlers) UpdateISO(c *gin.Context) {
 			ErrorResponse(c, http.StatusBadRequest, ErrCodeInvalidState, invalidStateErr.Error())
 			return
 		}
+		var conflictErr *service.DownloadConflictError
+		if errors.As(err, &conflictErr) {
+			ErrorResponse(c, http.StatusConflict, ErrCodeConflict, conflictErr.Error())
+			return
+		}
+		var queueFullErr *service.QueueFullError
+		if errors.As(err, &queueFullErr) {
+			ErrorResponse(c, http.StatusTooManyRequests, ErrCodeQueueFull, queueFullErr.Error())
+			return
+		}
+		var credErr *service.InvalidCredentialError
+		if errors.As(err, &credErr) {
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, credErr.Error())
+			return
+		}
 
 		var existsErr *service.ISOAlreadyExistsError
 		if errors.As(err, &existsErr) {
-			c.JSON(http.StatusConflict, APIResponse{
-				Success: false,
-				Error: &APIError{
-					Code:    ErrCodeConflict,
-					Message: "ISO already exists",
-				},
-				Data: gin.H{
-					"existing": existsErr.ExistingISO,
-				},
+			ProblemResponse(c, http.StatusConflict, &APIError{Code: ErrCodeConflict, Message: "ISO already exists"}, nil, gin.H{
+				"existing": existsErr.ExistingISO,
 			})
 			return
 		}
@@ -255,10 +1020,21 @@ func (h *Handlers) UpdateISO(c *gin.Context) {
 	SuccessResponseWithMessage(c, http.StatusOK, iso, "ISO updated successfully")
 }
 
-// HealthCheck returns server health status.
+// HealthCheck returns server health status, with the schema version when a
+// schema source is set. A schema that does not match this build leaves the
+// status "ok", since the server is still serving; check schema.compatible.
 func (h *Handlers) HealthCheck(c *gin.Context) {
-	SuccessResponse(c, http.StatusOK, gin.H{
+	resp := gin.H{
 		"status": "ok",
 		"time":   time.Now().Format(time.RFC3339),
-	})
+	}
+	if h.database != nil {
+		schema, err := schemaInfo(h.database)
+		if err != nil {
+			ErrorResponse(c, http.StatusServiceUnavailable, ErrCodeInternalError, "Failed to read schema version")
+			return
+		}
+		resp["schema"] = schema
+	}
+	SuccessResponse(c, http.StatusOK, resp)
 }
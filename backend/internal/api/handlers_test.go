@@ -8,6 +8,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,7 +16,9 @@ import (
 	"github.com/aloks98/isoman/backend/internal/db"
 	"github.com/aloks98/isoman/backend/internal/download"
 	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/pathutil"
 	"github.com/aloks98/isoman/backend/internal/service"
+	"github.com/aloks98/isoman/backend/internal/validation"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -46,7 +49,7 @@ func setupTestHandlers(t *testing.T) (*Handlers, *db.DB, *download.Manager, stri
 	isoService := service.NewISOService(database, manager, isoDir)
 
 	// Create handlers
-	handlers := NewHandlers(isoService, isoDir)
+	handlers := NewHandlers(isoService, isoDir, pathutil.GetTempDir(isoDir))
 
 	cleanup := func() {
 		manager.Stop()
@@ -160,6 +163,115 @@ func TestListISOsWithData(t *testing.T) {
 	}
 }
 
+// TestListISOsCursor tests following next_cursor through the list.
+func TestListISOsCursor(t *testing.T) {
+	handlers, database, _, _, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	for i := 0; i < 3; i++ {
+		iso := &models.ISO{
+			ID:          uuid.New().String(),
+			Name:        fmt.Sprintf("test-%d", i),
+			Version:     "1.0",
+			Arch:        "x86_64",
+			FileType:    "iso",
+			DownloadURL: "http://example.com/test.iso",
+			Status:      models.StatusPending,
+			CreatedAt:   time.Now(),
+		}
+		iso.ComputeFields()
+		database.CreateISO(iso)
+	}
+
+	list := func(query string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/api/isos?"+query, http.NoBody)
+		handlers.ListISOs(c)
+		data, _ := parseAPIResponse(t, w.Body.Bytes()).Data.(map[string]interface{})
+		return w.Code, data
+	}
+
+	code, data := list("page_size=2")
+	pagination, _ := data["pagination"].(map[string]interface{})
+	cursor, _ := pagination["next_cursor"].(string)
+	if code != http.StatusOK || cursor == "" {
+		t.Fatalf("Expected a next_cursor on the first page, got: %d %v", code, data)
+	}
+
+	code, data = list("page_size=2&cursor=" + cursor)
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", code)
+	}
+	isos, _ := data["isos"].([]interface{})
+	pagination, _ = data["pagination"].(map[string]interface{})
+	if len(isos) != 1 || pagination["next_cursor"] != "" {
+		t.Errorf("Expected the last ISO and no cursor, got: %v", data)
+	}
+	if _, ok := pagination["total"]; ok {
+		t.Errorf("Expected a cursor page without a total, got: %v", pagination)
+	}
+
+	if code, _ := list("cursor=bogus"); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid cursor, got: %d", code)
+	}
+}
+
+// TestISOFieldSelection tests trimming list and get responses with ?fields=.
+func TestISOFieldSelection(t *testing.T) {
+	handlers, database, _, _, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "test",
+		Version:     "1.0",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: "http://example.com/test.iso",
+		Status:      models.StatusPending,
+		CreatedAt:   time.Now(),
+	}
+	iso.ComputeFields()
+	database.CreateISO(iso)
+
+	get := func(handler gin.HandlerFunc, url string) (int, *APIResponse) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", url, http.NoBody)
+		c.Params = gin.Params{{Key: "id", Value: iso.ID}}
+		handler(c)
+		return w.Code, parseAPIResponse(t, w.Body.Bytes())
+	}
+	wantFields := func(object any) {
+		t.Helper()
+		got, _ := object.(map[string]interface{})
+		if len(got) != 2 || got["id"] != iso.ID || got["status"] != string(models.StatusPending) {
+			t.Errorf("Expected only id and status, got: %v", object)
+		}
+	}
+
+	code, response := get(handlers.ListISOs, "/api/isos?fields=id,status,id")
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", code)
+	}
+	isos, _ := response.Data.(map[string]interface{})["isos"].([]interface{})
+	if len(isos) != 1 {
+		t.Fatalf("Expected 1 ISO, got: %v", response.Data)
+	}
+	wantFields(isos[0])
+
+	code, response = get(handlers.GetISO, "/api/isos/"+iso.ID+"?fields=id,+status")
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", code)
+	}
+	wantFields(response.Data)
+
+	if code, _ := get(handlers.GetISO, "/api/isos/"+iso.ID+"?fields=id,secret"); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown field, got: %d", code)
+	}
+}
+
 // TestGetISOSuccess tests getting an ISO by ID.
 func TestGetISOSuccess(t *testing.T) {
 	handlers, database, _, _, cleanup := setupTestHandlers(t)
@@ -230,7 +342,162 @@ func TestGetISONotFound(t *testing.T) {
 	}
 }
 
+// TestGetDownloadLog tests retrieving the log of an ISO's latest download run.
+func TestGetDownloadLog(t *testing.T) {
+	handlers, database, _, _, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "alpine",
+		Version:     "3.19.1",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: "http://example.com/alpine.iso",
+		Status:      models.StatusFailed,
+		CreatedAt:   time.Now(),
+	}
+	iso.ComputeFields()
+	database.CreateISO(iso)
+	database.AppendDownloadLog(&models.DownloadLogEntry{ISOID: iso.ID, LoggedAt: time.Now(), Level: models.LogLevelError, Message: "Failed: server returned 404 Not Found"})
+
+	get := func(id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/api/isos/"+id+"/log", http.NoBody)
+		c.Params = gin.Params{{Key: "id", Value: id}}
+		handlers.GetDownloadLog(c)
+		return w
+	}
+
+	w := get(iso.ID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", w.Code)
+	}
+	entries, _ := parseAPIResponse(t, w.Body.Bytes()).Data.([]interface{})
+	if len(entries) != 1 || entries[0].(map[string]interface{})["level"] != models.LogLevelError {
+		t.Errorf("Expected the error entry, got %v", entries)
+	}
+
+	if w := get(uuid.New().String()); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown ISO, got: %d", w.Code)
+	}
+}
+
 // TestCreateISOSuccess tests creating a new ISO.
+func TestFindISOsByChecksum(t *testing.T) {
+	handlers, database, _, _, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	sum := strings.Repeat("ab", 32)
+	for _, iso := range []*models.ISO{
+		{Name: "alpine", Version: "3.19.1", Status: models.StatusComplete, SHA256: sum},
+		{Name: "alpine", Version: "3.19.0", Status: models.StatusComplete, Checksum: strings.ToUpper(sum), ChecksumType: "sha256"}, // Before digests were recorded
+		{Name: "alpine", Version: "3.20.0", Status: models.StatusDownloading, SHA256: sum},
+		{Name: "debian", Version: "12.5", Status: models.StatusComplete, SHA256: strings.Repeat("cd", 32)},
+	} {
+		iso.ID = uuid.New().String()
+		iso.Arch = "x86_64"
+		iso.FileType = "iso"
+		iso.DownloadURL = "http://example.com/" + iso.Name + ".iso"
+		iso.CreatedAt = time.Now()
+		iso.ComputeFields()
+		if err := database.CreateISO(iso); err != nil {
+			t.Fatalf("CreateISO() failed: %v", err)
+		}
+	}
+
+	get := func(sum string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/api/isos/by-checksum/"+sum, http.NoBody)
+		c.Params = gin.Params{{Key: "sha256", Value: sum}}
+		handlers.FindISOsByChecksum(c)
+		return w
+	}
+
+	w := get(strings.ToUpper(sum))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", w.Code, w.Body.String())
+	}
+	isos, _ := parseAPIResponse(t, w.Body.Bytes()).Data.([]interface{})
+	if len(isos) != 2 {
+		t.Fatalf("Expected the two complete ISOs, got %v", isos)
+	}
+	for _, iso := range isos {
+		if name := iso.(map[string]interface{})["name"]; name != "alpine" {
+			t.Errorf("Unexpected ISO %v", name)
+		}
+	}
+
+	if w := get(strings.Repeat("ef", 32)); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown checksum, got: %d", w.Code)
+	}
+	if w := get("abc"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a malformed checksum, got: %d", w.Code)
+	}
+}
+
+func TestGetISOSnippets(t *testing.T) {
+	handlers, database, _, _, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "alpine",
+		Version:     "3.19.1",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: "http://example.com/alpine.iso",
+		Status:      models.StatusComplete,
+		CreatedAt:   time.Now(),
+	}
+	iso.ComputeFields()
+	if err := database.CreateISO(iso); err != nil {
+		t.Fatalf("CreateISO() failed: %v", err)
+	}
+	handlers.SetSnippetConfig("https://isos.example.com", true)
+
+	get := func(id, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/api/isos/"+id+"/snippets"+query, http.NoBody)
+		c.Params = gin.Params{{Key: "id", Value: id}}
+		handlers.GetISOSnippets(c)
+		return w
+	}
+
+	w := get(iso.ID, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", w.Code, w.Body.String())
+	}
+	data, _ := parseAPIResponse(t, w.Body.Bytes()).Data.(map[string]interface{})
+	if data["url"] != "https://isos.example.com"+iso.DownloadLink || data["requires_auth"] != true {
+		t.Errorf("Unexpected snippet metadata: %v", data)
+	}
+	if snippets, _ := data["snippets"].([]interface{}); len(snippets) != 4 {
+		t.Errorf("Expected 4 snippets, got %v", snippets)
+	}
+
+	w = get(iso.ID, "?type=ipxe")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", w.Code)
+	}
+	if disposition := w.Header().Get("Content-Disposition"); !strings.Contains(disposition, "attachment") {
+		t.Errorf("Expected an attachment, got %q", disposition)
+	}
+	if !strings.Contains(w.Body.String(), "sanboot --no-describe https://isos.example.com"+iso.DownloadLink) {
+		t.Errorf("Unexpected iPXE script:\n%s", w.Body.String())
+	}
+
+	if w := get(iso.ID, "?type=ansible"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown snippet type, got: %d", w.Code)
+	}
+	if w := get(uuid.New().String(), ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a missing ISO, got: %d", w.Code)
+	}
+}
+
 func TestCreateISOSuccess(t *testing.T) {
 	handlers, database, _, _, cleanup := setupTestHandlers(t)
 	defer cleanup()
@@ -291,8 +558,8 @@ func TestCreateISOSuccess(t *testing.T) {
 		t.Errorf("DownloadLink mismatch, got: %s", response.DownloadLink)
 	}
 
-	if response.Status != models.StatusPending {
-		t.Errorf("Status should be 'pending', got: %s", response.Status)
+	if response.Status != models.StatusQueued {
+		t.Errorf("Status should be 'queued', got: %s", response.Status)
 	}
 
 	// Verify ISO was created in database
@@ -361,6 +628,136 @@ func TestCreateISODuplicate(t *testing.T) {
 	if !ok || data["existing"] == nil {
 		t.Error("Expected existing ISO in response data")
 	}
+	// A pending ISO can only be sidestepped with another edition
+	if options, _ := data["options"].([]interface{}); len(options) != 1 || options[0].(map[string]interface{})["action"] != models.ConflictActionEdition {
+		t.Errorf("Expected only the edition option, got %v", data["options"])
+	}
+}
+
+// TestCreateISOOverwrite tests replacing a failed ISO with overwrite=true.
+func TestCreateISOOverwrite(t *testing.T) {
+	handlers, database, _, _, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	failed := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "alpine-linux",
+		Version:     "3.19.1",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: "http://example.com/alpine.iso",
+		Status:      models.StatusFailed,
+		CreatedAt:   time.Now(),
+	}
+	failed.ComputeFields()
+	database.CreateISO(failed)
+
+	create := func(url string) *httptest.ResponseRecorder {
+		body := `{"name":"Alpine Linux","version":"3.19.1","arch":"x86_64","download_url":"http://mirror.example.com/alpine.iso"}`
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", url, strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handlers.CreateISO(c)
+		return w
+	}
+
+	w := create("/api/isos")
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409, got: %d", w.Code)
+	}
+	var actions []string
+	for _, option := range parseAPIResponse(t, w.Body.Bytes()).Data.(map[string]interface{})["options"].([]interface{}) {
+		actions = append(actions, option.(map[string]interface{})["action"].(string))
+	}
+	if strings.Join(actions, ",") != "retry,overwrite,edition" {
+		t.Errorf("Expected retry, overwrite, and edition options, got %v", actions)
+	}
+
+	w = create("/api/isos?overwrite=true")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", w.Code, w.Body.String())
+	}
+	if _, err := database.GetISO(failed.ID); err == nil {
+		t.Error("Expected the failed ISO replaced")
+	}
+
+	// The replacement is pending, so it can't be overwritten again
+	if w := create("/api/isos?overwrite=true"); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 overwriting a pending ISO, got: %d", w.Code)
+	}
+}
+
+// TestPreviewISO tests previewing where an ISO would land without creating it.
+func TestPreviewISO(t *testing.T) {
+	handlers, database, _, _, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	existing := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "alpine-linux",
+		Version:     "3.19.1",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: "http://example.com/alpine.iso",
+		Status:      models.StatusComplete,
+		CreatedAt:   time.Now(),
+	}
+	existing.ComputeFields()
+	database.CreateISO(existing)
+
+	router := gin.New()
+	router.GET("/api/isos/preview", handlers.PreviewISO)
+	preview := func(query string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/isos/preview?"+query, nil)
+		router.ServeHTTP(w, req)
+		data, _ := parseAPIResponse(t, w.Body.Bytes()).Data.(map[string]interface{})
+		return w, data
+	}
+
+	w, data := preview("name=Ubuntu+Server&version=24.04&arch=x86_64&edition=live&download_url=https://example.com/ubuntu.ISO")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", w.Code, w.Body.String())
+	}
+	want := map[string]interface{}{
+		"name":          "ubuntu-server",
+		"file_type":     "iso",
+		"filename":      "ubuntu-server-24.04-live-x86_64.iso",
+		"file_path":     "ubuntu-server/24.04/x86_64/ubuntu-server-24.04-live-x86_64.iso",
+		"download_link": "/images/ubuntu-server/24.04/x86_64/ubuntu-server-24.04-live-x86_64.iso",
+	}
+	for key, value := range want {
+		if data[key] != value {
+			t.Errorf("Expected %s %v, got: %v", key, value, data[key])
+		}
+	}
+	if _, ok := data["existing_id"]; ok {
+		t.Errorf("Expected no existing_id for a new ISO, got: %v", data["existing_id"])
+	}
+
+	// file_type stands in for the URL and reports the ISO it would conflict with
+	w, data = preview("name=Alpine+Linux&version=3.19.1&arch=x86_64&file_type=iso")
+	if w.Code != http.StatusOK || data["existing_id"] != existing.ID {
+		t.Errorf("Expected existing_id %s, got: %d %v", existing.ID, w.Code, data["existing_id"])
+	}
+
+	isos, _ := database.ListISOs()
+	if len(isos) != 1 {
+		t.Errorf("Preview should not create anything, got: %d ISOs", len(isos))
+	}
+
+	for _, query := range []string{
+		"version=1&arch=x86_64&file_type=iso",           // No name
+		"name=a&version=1&arch=x86_64",                  // Neither URL nor file type
+		"name=a&version=1&arch=x86_64&file_type=exe",    // Unsupported file type
+		"name=a&version=1&arch=x86_64&download_url=x.y", // Not an HTTP URL
+		"name=a&version=1&arch=x86_64&download_url=https://example.com/a.txt",
+	} {
+		if w, _ := preview(query); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %q, got: %d", query, w.Code)
+		}
+	}
 }
 
 // TestCreateISOInvalidRequest tests creating ISO with invalid request.
@@ -389,6 +786,51 @@ func TestCreateISOInvalidRequest(t *testing.T) {
 	}
 }
 
+// TestCreateISOValidationProblem tests that validation failures are RFC 7807 problems with field errors.
+func TestCreateISOValidationProblem(t *testing.T) {
+	handlers, _, _, _, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	bodyJSON, _ := json.Marshal(map[string]string{
+		"name":          "Test",
+		"version":       "1.0",
+		"arch":          "x86_64",
+		"download_url":  "http://example.com/test.iso",
+		"checksum_type": "md4",
+	})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/api/isos", bytes.NewBuffer(bodyJSON))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handlers.CreateISO(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got: %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, ProblemContentType) {
+		t.Errorf("Expected Content-Type %s, got: %s", ProblemContentType, ct)
+	}
+
+	var problem Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Failed to parse problem: %v", err)
+	}
+	if problem.Type != "urn:isoman:error:validation-failed" || problem.Title != "Bad Request" || problem.Status != http.StatusBadRequest {
+		t.Errorf("Unexpected problem members: %+v", problem)
+	}
+	if problem.Instance != "/api/isos" || problem.Code != ErrCodeValidationFailed {
+		t.Errorf("Expected instance /api/isos and code %s, got %q and %q", ErrCodeValidationFailed, problem.Instance, problem.Code)
+	}
+	if len(problem.Errors) != 1 || problem.Errors[0].Field != "checksum_type" {
+		t.Errorf("Expected a checksum_type field error, got: %+v", problem.Errors)
+	}
+	if problem.Success || problem.Error == nil || problem.Error.Code != ErrCodeValidationFailed {
+		t.Errorf("Expected the legacy error envelope alongside the problem, got: %+v", problem.Error)
+	}
+}
+
 // TestCreateISOUnsupportedFileType tests creating ISO with unsupported file type.
 func TestCreateISOUnsupportedFileType(t *testing.T) {
 	handlers, _, _, _, cleanup := setupTestHandlers(t)
@@ -539,9 +981,9 @@ func TestRetryISOSuccess(t *testing.T) {
 	var response models.ISO
 	json.Unmarshal(dataBytes, &response)
 
-	// CRITICAL TEST: Verify status was reset to pending
-	if response.Status != models.StatusPending {
-		t.Errorf("Status should be reset to 'pending', got: %s", response.Status)
+	// CRITICAL TEST: Verify status was reset and re-queued
+	if response.Status != models.StatusQueued {
+		t.Errorf("Status should be reset to 'queued', got: %s", response.Status)
 	}
 
 	// Verify progress was reset
@@ -556,8 +998,8 @@ func TestRetryISOSuccess(t *testing.T) {
 
 	// Verify database was updated
 	dbISO, _ := database.GetISO(iso.ID)
-	if dbISO.Status != models.StatusPending {
-		t.Errorf("Database status should be 'pending', got: %s", dbISO.Status)
+	if dbISO.Status != models.StatusQueued {
+		t.Errorf("Database status should be 'queued', got: %s", dbISO.Status)
 	}
 }
 
@@ -616,9 +1058,148 @@ func TestRetryISONotFound(t *testing.T) {
 	}
 }
 
+// TestCloneISO tests creating an ISO from an existing one with a new version.
+func TestCloneISO(t *testing.T) {
+	handlers, database, _, _, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	source := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "alpine-linux",
+		Version:     "3.19.1",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: "http://example.com/v3.19/alpine-3.19.1-x86_64.iso",
+		Status:      models.StatusComplete,
+		CreatedAt:   time.Now(),
+	}
+	source.ComputeFields()
+	database.CreateISO(source)
+
+	clone := func(id, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/api/isos/"+id+"/clone", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "id", Value: id}}
+		handlers.CloneISO(c)
+		return w
+	}
+
+	w := clone(source.ID, `{"version":"3.19.2"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", w.Code, w.Body.String())
+	}
+	data := parseAPIResponse(t, w.Body.Bytes()).Data.(map[string]interface{})
+	if data["version"] != "3.19.2" || data["download_url"] != "http://example.com/v3.19/alpine-3.19.2-x86_64.iso" || data["id"] == source.ID {
+		t.Errorf("Unexpected clone: %v", data)
+	}
+
+	if w := clone(source.ID, `{}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 cloning without changes, got: %d", w.Code)
+	}
+	if w := clone(uuid.New().String(), `{"version":"1"}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown source, got: %d", w.Code)
+	}
+}
+
+// TestBumpVersion tests queueing a new version of several ISOs at once.
+func TestBumpVersion(t *testing.T) {
+	handlers, database, _, _, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	var ids []string
+	for _, arch := range []string{"x86_64", "aarch64"} {
+		iso := &models.ISO{
+			ID:          uuid.New().String(),
+			Name:        "alpine-linux",
+			Version:     "3.19.1",
+			Arch:        arch,
+			FileType:    "iso",
+			DownloadURL: "http://example.com/alpine-3.19.1-" + arch + ".iso",
+			Status:      models.StatusComplete,
+			CreatedAt:   time.Now(),
+		}
+		iso.ComputeFields()
+		database.CreateISO(iso)
+		ids = append(ids, iso.ID)
+	}
+	missing := uuid.New().String()
+
+	body, _ := json.Marshal(models.VersionBumpRequest{IDs: append(ids, missing), Version: "3.19.2"})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/api/isos/bump", bytes.NewBuffer(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handlers.BumpVersion(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", w.Code, w.Body.String())
+	}
+	var response struct {
+		Data models.VersionBumpResult `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	result := response.Data
+	if len(result.Queued) != 2 || len(result.Failed) != 1 || result.Failed[0].ID != missing {
+		t.Fatalf("Expected 2 queued and the unknown ID failed, got %+v", result)
+	}
+	for _, iso := range result.Queued {
+		if iso.Version != "3.19.2" || iso.DownloadURL != "http://example.com/alpine-3.19.2-"+iso.Arch+".iso" {
+			t.Errorf("Unexpected bumped ISO: %+v", iso)
+		}
+	}
+}
+
+// TestPinISO tests pinning and unpinning an ISO.
+func TestPinISO(t *testing.T) {
+	handlers, database, _, _, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "alpine-linux",
+		Version:     "3.19.1",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: "http://example.com/alpine-3.19.1-x86_64.iso",
+		Status:      models.StatusComplete,
+		CreatedAt:   time.Now(),
+	}
+	iso.ComputeFields()
+	database.CreateISO(iso)
+
+	pin := func(method, id string, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(method, "/api/isos/"+id+"/pin", nil)
+		c.Params = gin.Params{{Key: "id", Value: id}}
+		handler(c)
+		return w
+	}
+
+	if w := pin("PUT", iso.ID, handlers.PinISO); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", w.Code, w.Body.String())
+	}
+	if stored, _ := database.GetISO(iso.ID); !stored.Pinned {
+		t.Error("Expected the ISO to be pinned")
+	}
+	if w := pin("DELETE", iso.ID, handlers.UnpinISO); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", w.Code, w.Body.String())
+	}
+	if stored, _ := database.GetISO(iso.ID); stored.Pinned {
+		t.Error("Expected the ISO to be unpinned")
+	}
+	if w := pin("PUT", uuid.New().String(), handlers.PinISO); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown ISO, got: %d", w.Code)
+	}
+}
+
 // TestHealthCheck tests health check endpoint.
 func TestHealthCheck(t *testing.T) {
-	handlers, _, _, _, cleanup := setupTestHandlers(t)
+	handlers, database, _, _, cleanup := setupTestHandlers(t)
 	defer cleanup()
 
 	// Create test request
@@ -652,6 +1233,25 @@ func TestHealthCheck(t *testing.T) {
 	if data["time"] == nil {
 		t.Error("Response should include time")
 	}
+	if _, ok := data["schema"]; ok {
+		t.Error("Schema should be omitted without a schema source")
+	}
+
+	// With a schema source, the migrated test database reports compatible
+	handlers.SetSchemaSource(database)
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/health", http.NoBody)
+	handlers.HealthCheck(c)
+
+	data, _ = parseAPIResponse(t, w.Body.Bytes()).Data.(map[string]interface{})
+	schema, ok := data["schema"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Response should include schema, got: %s", w.Body.String())
+	}
+	if schema["compatible"] != true || schema["pending"] != float64(0) || schema["version"] != schema["latest"] {
+		t.Errorf("Expected a compatible schema, got: %v", schema)
+	}
 }
 
 // TestCreateISOWithEdition tests creating ISO with edition field.
@@ -700,6 +1300,56 @@ func TestCreateISOWithEdition(t *testing.T) {
 	}
 }
 
+// TestCreateISOExpectedChecksum tests creating an ISO with an inline checksum
+// instead of a checksum URL.
+func TestCreateISOExpectedChecksum(t *testing.T) {
+	handlers, _, _, _, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	create := func(body map[string]string) *httptest.ResponseRecorder {
+		bodyJSON, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/api/isos", bytes.NewBuffer(bodyJSON))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handlers.CreateISO(c)
+		return w
+	}
+
+	checksum := "E3B0C44298FC1C149AFBF4C8996FB92427AE41E4649B934CA495991B7852B855"
+	w := create(map[string]string{
+		"name":              "Netboot",
+		"version":           "1.0",
+		"arch":              "x86_64",
+		"download_url":      "http://example.com/netboot.iso",
+		"expected_checksum": checksum,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d: %s", w.Code, w.Body.String())
+	}
+	dataBytes, _ := json.Marshal(parseAPIResponse(t, w.Body.Bytes()).Data)
+	var iso models.ISO
+	json.Unmarshal(dataBytes, &iso)
+	if iso.Checksum != strings.ToLower(checksum) || iso.ChecksumType != "sha256" || iso.ChecksumURL != "" {
+		t.Errorf("Expected the expected checksum stored as sha256, got %q of type %q", iso.Checksum, iso.ChecksumType)
+	}
+
+	// Not together with a checksum URL
+	w = create(map[string]string{
+		"name":              "Netboot",
+		"version":           "2.0",
+		"arch":              "x86_64",
+		"download_url":      "http://example.com/netboot.iso",
+		"checksum_url":      "http://example.com/SHA256SUMS",
+		"expected_checksum": checksum,
+	})
+	var problem Problem
+	json.Unmarshal(w.Body.Bytes(), &problem)
+	if w.Code != http.StatusBadRequest || len(problem.Errors) != 1 || problem.Errors[0].Field != "expected_checksum" {
+		t.Errorf("Expected an expected_checksum field error, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
 // TestDeleteISOWithChecksumFile tests deleting ISO with checksum file cleanup.
 func TestDeleteISOWithChecksumFile(t *testing.T) {
 	handlers, database, _, isoDir, cleanup := setupTestHandlers(t)
@@ -829,9 +1479,9 @@ func TestUpdateISOSuccessFailedISO(t *testing.T) {
 	if response.DownloadURL != newURL {
 		t.Errorf("DownloadURL should be updated, got: %s", response.DownloadURL)
 	}
-	// Failed ISO update should reset status to pending
-	if response.Status != models.StatusPending {
-		t.Errorf("Status should be reset to 'pending', got: %s", response.Status)
+	// Failed ISO update should re-queue the download
+	if response.Status != models.StatusQueued {
+		t.Errorf("Status should be reset to 'queued', got: %s", response.Status)
 	}
 }
 
@@ -1004,6 +1654,53 @@ func TestUpdateISOInvalidRequestBody(t *testing.T) {
 	}
 }
 
+// TestUpdateISOURLChecks tests that edited URLs go through the live URL checks.
+func TestUpdateISOURLChecks(t *testing.T) {
+	handlers, database, _, _, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	handlers.SetURLChecks(&validation.URLCheckOptions{BlockPrivateIPs: true})
+
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "test",
+		Version:     "1.0",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: "http://example.com/test.iso",
+		Status:      models.StatusFailed,
+		CreatedAt:   time.Now(),
+	}
+	iso.ComputeFields()
+	database.CreateISO(iso)
+
+	newURL := "http://169.254.169.254/test.iso"
+	bodyJSON, _ := json.Marshal(models.UpdateISORequest{DownloadURL: &newURL})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("PUT", fmt.Sprintf("/api/isos/%s", iso.ID), bytes.NewBuffer(bodyJSON))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: iso.ID}}
+
+	handlers.UpdateISO(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got: %d", w.Code)
+	}
+	var problem Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Failed to parse problem: %v", err)
+	}
+	if len(problem.Errors) != 1 || problem.Errors[0].Field != "download_url" {
+		t.Errorf("Expected a download_url field error, got: %+v", problem.Errors)
+	}
+
+	unchanged, _ := database.GetISO(iso.ID)
+	if unchanged.DownloadURL != "http://example.com/test.iso" {
+		t.Errorf("Rejected URL should not be saved, got: %s", unchanged.DownloadURL)
+	}
+}
+
 // TestDeleteISOWithMultipleChecksumTypes tests cleanup of different checksum types.
 func TestDeleteISOWithMultipleChecksumTypes(t *testing.T) {
 	handlers, database, _, isoDir, cleanup := setupTestHandlers(t)
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+)
+
+// HidePolicy decides which files and directories under /images are left out of
+// listings and never served.
+type HidePolicy struct {
+	patterns []string // Configured glob patterns, matched against each path segment
+	tempRel  string   // Slash-separated TMP_DIR relative to the ISO dir, when it lies inside it
+}
+
+// NewHidePolicy builds a policy from glob patterns such as ".*". tmpDir is hidden
+// too when it lies inside isoDir, so a custom TMP_DIR can't be browsed either.
+func NewHidePolicy(patterns []string, isoDir, tmpDir string) *HidePolicy {
+	policy := &HidePolicy{patterns: patterns}
+	if tmpDir != "" {
+		if rel, err := filepath.Rel(isoDir, tmpDir); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			policy.tempRel = filepath.ToSlash(rel)
+		}
+	}
+	return policy
+}
+
+// IsHidden reports whether a single file or directory name is hidden.
+func (p *HidePolicy) IsHidden(name string) bool {
+	// Reserved names are matched case-insensitively for case-insensitive filesystems
+	lower := strings.ToLower(name)
+	for _, pattern := range constants.ReservedHiddenNames {
+		if matched, _ := path.Match(pattern, lower); matched {
+			return true
+		}
+	}
+	for _, pattern := range p.patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// IsHiddenPath reports whether a slash-separated path relative to the ISO dir is
+// hidden or lies inside a hidden directory. The path is cleaned first, so
+// "alpine/../.tmp/x" is caught like ".tmp/x".
+func (p *HidePolicy) IsHiddenPath(rel string) bool {
+	clean := strings.TrimPrefix(path.Clean("/"+rel), "/")
+	if clean == "" {
+		return false
+	}
+	if p.tempRel != "" && (clean == p.tempRel || strings.HasPrefix(clean, p.tempRel+"/")) {
+		return true
+	}
+	for _, segment := range strings.Split(clean, "/") {
+		if p.IsHidden(segment) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHidePolicyIsHiddenPath(t *testing.T) {
+	isoDir := "/data/isos"
+	policy := NewHidePolicy([]string{"*.bak"}, isoDir, "/data/isos/scratch")
+
+	tests := []struct {
+		path   string
+		hidden bool
+	}{
+		{".", false},
+		{"alpine/3.19.1/x86_64/alpine.iso", false},
+		{".well-known/security.txt", false}, // Dotfiles are only hidden by the default pattern
+		{"alpine/old.iso.bak", true},
+		{".tmp/alpine.iso", true},
+		{".TRASH", true},
+		{".quarantine/alpine/alpine.iso", true},
+		{"alpine/.versions/alpine.iso", true},
+		{"alpine/.alpine.iso.partial", true},
+		{"alpine/../.tmp/alpine.iso", true},
+		{"scratch/alpine.iso", true},
+		{"scratchpad/alpine.iso", false},
+	}
+
+	for _, tt := range tests {
+		if got := policy.IsHiddenPath(tt.path); got != tt.hidden {
+			t.Errorf("IsHiddenPath(%q) = %v, want %v", tt.path, got, tt.hidden)
+		}
+	}
+}
+
+// TestDirectoryHandlerShowDotfiles tests that clearing the hide list exposes dotfiles but not reserved directories.
+func TestDirectoryHandlerShowDotfiles(t *testing.T) {
+	isoDir, cleanup := setupTestDirectory(t)
+	defer cleanup()
+
+	os.WriteFile(filepath.Join(isoDir, ".banner"), []byte("welcome"), 0o644)
+	os.MkdirAll(filepath.Join(isoDir, ".tmp"), 0o755)
+	os.WriteFile(filepath.Join(isoDir, ".tmp", "partial.iso"), []byte("partial"), 0o644)
+
+	handler := DirectoryHandler(&DirectoryHandlerConfig{ISODir: isoDir, HiddenFiles: []string{}})
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/images"+path, http.NoBody)
+		c.Params = gin.Params{{Key: "filepath", Value: path}}
+		handler(c)
+		return w
+	}
+
+	body := serve("/").Body.String()
+	if !strings.Contains(body, ".banner") {
+		t.Error("Dotfiles should be listed when the hide list is empty")
+	}
+	if strings.Contains(body, ".tmp") {
+		t.Error("Reserved directories should never be listed")
+	}
+
+	if w := serve("/.banner"); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for dotfile, got: %d", w.Code)
+	}
+	if w := serve("/.tmp/partial.iso"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for reserved directory, got: %d", w.Code)
+	}
+}
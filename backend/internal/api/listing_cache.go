@@ -0,0 +1,65 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// maxCachedListings bounds the listing cache; it is simply emptied when full.
+const maxCachedListings = 1024
+
+// listingCache holds rendered directory listings keyed by language and request
+// path. An entry is only used while the directory's mtime is unchanged, which
+// catches files being added, removed, or renamed, and for at most ttl, which
+// bounds how stale sizes and dates of entries can get.
+type listingCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cachedListing
+}
+
+type cachedListing struct {
+	dirModTime time.Time
+	renderedAt time.Time
+	body       []byte
+}
+
+// newListingCache creates a cache; a zero ttl disables caching.
+func newListingCache(ttl time.Duration) *listingCache {
+	return &listingCache{ttl: ttl, entries: make(map[string]cachedListing)}
+}
+
+// get returns the cached listing for path if it is still valid for dirModTime.
+func (lc *listingCache) get(path string, dirModTime time.Time) ([]byte, bool) {
+	if lc.ttl <= 0 {
+		return nil, false
+	}
+
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	entry, ok := lc.entries[path]
+	if !ok {
+		return nil, false
+	}
+	if !entry.dirModTime.Equal(dirModTime) || time.Since(entry.renderedAt) > lc.ttl {
+		delete(lc.entries, path)
+		return nil, false
+	}
+	return entry.body, true
+}
+
+// put stores a rendered listing for path.
+func (lc *listingCache) put(path string, dirModTime time.Time, body []byte) {
+	if lc.ttl <= 0 {
+		return
+	}
+
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if len(lc.entries) >= maxCachedListings {
+		lc.entries = make(map[string]cachedListing)
+	}
+	lc.entries[path] = cachedListing{dirModTime: dirModTime, renderedAt: time.Now(), body: body}
+}
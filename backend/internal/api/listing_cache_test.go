@@ -0,0 +1,70 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListingCache(t *testing.T) {
+	modTime := time.Now()
+
+	cache := newListingCache(time.Minute)
+	cache.put("alpine", modTime, []byte("listing"))
+
+	if body, ok := cache.get("alpine", modTime); !ok || string(body) != "listing" {
+		t.Errorf("Expected cache hit, got: %q %v", body, ok)
+	}
+	if _, ok := cache.get("alpine", modTime.Add(time.Second)); ok {
+		t.Error("Changed directory mtime should invalidate the entry")
+	}
+	if _, ok := cache.get("alpine", modTime); ok {
+		t.Error("Invalidated entry should be dropped")
+	}
+
+	expired := newListingCache(time.Nanosecond)
+	expired.put("alpine", modTime, []byte("listing"))
+	time.Sleep(time.Millisecond)
+	if _, ok := expired.get("alpine", modTime); ok {
+		t.Error("Entry older than the TTL should not be used")
+	}
+
+	disabled := newListingCache(0)
+	disabled.put("alpine", modTime, []byte("listing"))
+	if _, ok := disabled.get("alpine", modTime); ok {
+		t.Error("Zero TTL should disable caching")
+	}
+}
+
+// TestDirectoryHandlerListingCache tests that listings are cached until the directory changes.
+func TestDirectoryHandlerListingCache(t *testing.T) {
+	isoDir, cleanup := setupTestDirectory(t)
+	defer cleanup()
+
+	dir := filepath.Join(isoDir, "alpine", "3.19.1", "x86_64")
+	handler := DirectoryHandler(&DirectoryHandlerConfig{ISODir: isoDir, ListingCacheTTL: time.Minute})
+	list := func() string {
+		return serveImages(handler, "/alpine/3.19.1/x86_64/").Body.String()
+	}
+
+	if !strings.Contains(list(), "alpine.iso") {
+		t.Fatal("Expected alpine.iso in listing")
+	}
+
+	// Rewriting a file in place leaves the directory mtime alone, so the cached listing is served
+	os.WriteFile(filepath.Join(dir, "alpine.iso"), []byte(strings.Repeat("x", 4096)), 0o644)
+	if strings.Contains(list(), "4.0 KB") {
+		t.Error("Expected the cached listing while the directory is unchanged")
+	}
+
+	// Adding a file changes the directory mtime and invalidates the cache
+	os.WriteFile(filepath.Join(dir, "alpine.iso.sha256"), []byte("abc"), 0o644)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(dir, future, future)
+	body := list()
+	if !strings.Contains(body, "alpine.iso.sha256") || !strings.Contains(body, "4.0 KB") {
+		t.Error("Expected a fresh listing after the directory changed")
+	}
+}
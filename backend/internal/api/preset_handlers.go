@@ -0,0 +1,219 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/service"
+	"github.com/aloks98/isoman/backend/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PresetHandlers holds references to the preset service and the ISO
+// handlers that queue the downloads presets describe.
+type PresetHandlers struct {
+	presetService *service.PresetService
+	isoHandlers   *Handlers
+}
+
+// NewPresetHandlers creates a new PresetHandlers instance.
+func NewPresetHandlers(presetService *service.PresetService, isoHandlers *Handlers) *PresetHandlers {
+	return &PresetHandlers{
+		presetService: presetService,
+		isoHandlers:   isoHandlers,
+	}
+}
+
+// ListPresets returns all presets.
+func (h *PresetHandlers) ListPresets(c *gin.Context) {
+	presets, err := h.presetService.ListPresets()
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to retrieve presets")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, presets)
+}
+
+// GetPreset returns a single preset.
+func (h *PresetHandlers) GetPreset(c *gin.Context) {
+	preset, err := h.presetService.GetPreset(c.Param("name"))
+	if err != nil {
+		presetErrorResponse(c, err, "Failed to retrieve preset")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, preset)
+}
+
+// CreatePreset stores a new preset.
+func (h *PresetHandlers) CreatePreset(c *gin.Context) {
+	var req models.CreatePresetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+		return
+	}
+
+	preset, err := h.presetService.CreatePreset(req)
+	if err != nil {
+		presetErrorResponse(c, err, "Failed to create preset")
+		return
+	}
+
+	SuccessResponseWithMessage(c, http.StatusCreated, preset, "Preset created")
+}
+
+// UpdatePreset changes the fields of a preset the request sets.
+func (h *PresetHandlers) UpdatePreset(c *gin.Context) {
+	var req models.UpdatePresetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+		return
+	}
+
+	preset, err := h.presetService.UpdatePreset(c.Param("name"), req)
+	if err != nil {
+		presetErrorResponse(c, err, "Failed to update preset")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, preset)
+}
+
+// DeletePreset removes a preset.
+func (h *PresetHandlers) DeletePreset(c *gin.Context) {
+	if err := h.presetService.DeletePreset(c.Param("name")); err != nil {
+		presetErrorResponse(c, err, "Failed to delete preset")
+		return
+	}
+
+	NoContentResponse(c)
+}
+
+// ExportPresets returns the presets named by ?names=, or all of them, as a
+// YAML preset pack to import on another instance.
+func (h *PresetHandlers) ExportPresets(c *gin.Context) {
+	var names []string
+	for _, name := range strings.Split(c.Query("names"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	pack, err := h.presetService.ExportPresets(names)
+	if err != nil {
+		presetErrorResponse(c, err, "Failed to export presets")
+		return
+	}
+	data, err := service.EncodePresetPack(pack)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to export presets")
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="isoman-presets.yaml"`)
+	c.Data(http.StatusOK, "application/yaml; charset=utf-8", data)
+}
+
+// ImportPresets stores the presets of a YAML preset pack. ?existing=replace
+// overwrites presets whose name is taken instead of skipping them.
+func (h *PresetHandlers) ImportPresets(c *gin.Context) {
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, ErrCodeBadRequest, "Failed to read request body")
+		return
+	}
+	pack, err := service.DecodePresetPack(data)
+	if err != nil {
+		presetErrorResponse(c, err, "Failed to import presets")
+		return
+	}
+
+	result, err := h.presetService.ImportPresets(pack, c.Query("existing"))
+	if err != nil {
+		presetErrorResponse(c, err, "Failed to import presets")
+		return
+	}
+
+	message := fmt.Sprintf("Created %d presets, replaced %d, skipped %d", len(result.Created), len(result.Replaced), len(result.Skipped))
+	SuccessResponseWithMessage(c, http.StatusOK, result, message)
+}
+
+// ApplyPreset creates an ISO download from a preset and a version. It
+// answers like CreateISO.
+func (h *PresetHandlers) ApplyPreset(c *gin.Context) {
+	var req models.ApplyPresetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+		return
+	}
+
+	preset, err := h.presetService.GetPreset(c.Param("name"))
+	if err != nil {
+		presetErrorResponse(c, err, "Failed to retrieve preset")
+		return
+	}
+	isoReq, err := service.ExpandPreset(preset, req)
+	if err != nil {
+		presetErrorResponse(c, err, "Failed to apply preset")
+		return
+	}
+
+	h.isoHandlers.createISO(c, validation.ISOCreateRequest(*isoReq))
+}
+
+// DownloadFromCatalog applies the preset named by the distro in the path to
+// its version and arch, so a release can be queued without a request body.
+// It answers like CreateISO.
+func (h *PresetHandlers) DownloadFromCatalog(c *gin.Context) {
+	var req models.CatalogDownloadRequest
+
+	// The body is optional; without it the preset's edition is used. An empty
+	// body reads as io.EOF whether or not its length was announced
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+		return
+	}
+
+	preset, err := h.presetService.GetPreset(c.Param("distro"))
+	if err != nil {
+		presetErrorResponse(c, err, "Failed to retrieve preset")
+		return
+	}
+	isoReq, err := service.ExpandPreset(preset, models.ApplyPresetRequest{
+		Version:    c.Param("version"),
+		Arch:       c.Param("arch"),
+		Edition:    req.Edition,
+		IPFamily:   req.IPFamily,
+		Credential: req.Credential,
+	})
+	if err != nil {
+		presetErrorResponse(c, err, "Failed to apply preset")
+		return
+	}
+
+	h.isoHandlers.createISO(c, validation.ISOCreateRequest(*isoReq))
+}
+
+// presetErrorResponse maps preset service errors to responses.
+func presetErrorResponse(c *gin.Context, err error, fallback string) {
+	var invalidErr *service.InvalidPresetError
+	var existsErr *service.PresetExistsError
+
+	switch {
+	case errors.Is(err, db.ErrPresetNotFound):
+		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "Preset not found")
+	case errors.As(err, &invalidErr):
+		ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, invalidErr.Error())
+	case errors.As(err, &existsErr):
+		ErrorResponse(c, http.StatusConflict, ErrCodeConflict, existsErr.Error())
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, fallback)
+	}
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPresetHandlers(t *testing.T) {
+	handlers, database, _, _, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	presetHandlers := NewPresetHandlers(service.NewPresetService(database), handlers)
+
+	router := gin.New()
+	router.GET("/api/presets", presetHandlers.ListPresets)
+	router.POST("/api/presets", presetHandlers.CreatePreset)
+	router.PUT("/api/presets/:name", presetHandlers.UpdatePreset)
+	router.DELETE("/api/presets/:name", presetHandlers.DeletePreset)
+	router.POST("/api/presets/:name/isos", presetHandlers.ApplyPreset)
+	router.POST("/api/catalog/:distro/:version/:arch/download", presetHandlers.DownloadFromCatalog)
+	router.GET("/api/presets/export", presetHandlers.ExportPresets)
+	router.POST("/api/presets/import", presetHandlers.ImportPresets)
+
+	w := doCredentialRequest(router, http.MethodPost, "/api/presets", `{
+		"name": "rocky-minimal",
+		"iso_name": "rocky",
+		"arch": "x86_64",
+		"edition": "minimal",
+		"download_url": "https://download.rockylinux.org/pub/rocky/{major}/isos/{arch}/Rocky-{version}-{arch}-{edition}.iso",
+		"checksum_url": "https://download.rockylinux.org/pub/rocky/{major}/isos/{arch}/CHECKSUM",
+		"checksum_type": "sha256"
+	}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", w.Code, w.Body.String())
+	}
+	if w := doCredentialRequest(router, http.MethodPost, "/api/presets", `{"name":"rocky-minimal","iso_name":"rocky","download_url":"https://example.com/{version}.iso"}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for a duplicate, got: %d", w.Code)
+	}
+	if w := doCredentialRequest(router, http.MethodPost, "/api/presets", `{"name":"bad","iso_name":"bad","download_url":"https://example.com/{release}.iso"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown placeholder, got: %d", w.Code)
+	}
+
+	w = doCredentialRequest(router, http.MethodPost, "/api/presets/rocky-minimal/isos", `{"version":"9.4"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", w.Code, w.Body.String())
+	}
+	iso := parseAPIResponse(t, w.Body.Bytes()).Data.(map[string]any)
+	if iso["download_url"] != "https://download.rockylinux.org/pub/rocky/9/isos/x86_64/Rocky-9.4-x86_64-minimal.iso" || iso["edition"] != "minimal" {
+		t.Errorf("Unexpected ISO from preset: %v", iso)
+	}
+
+	if w := doCredentialRequest(router, http.MethodPost, "/api/presets/rocky-minimal/isos", `{"version":"9.4"}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for an existing ISO, got: %d", w.Code)
+	}
+	if w := doCredentialRequest(router, http.MethodPost, "/api/presets/rocky-minimal/isos", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without a version, got: %d", w.Code)
+	}
+	if w := doCredentialRequest(router, http.MethodPost, "/api/presets/missing/isos", `{"version":"1"}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown preset, got: %d", w.Code)
+	}
+
+	w = doCredentialRequest(router, http.MethodPost, "/api/catalog/rocky-minimal/9.4/aarch64/download", "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 from the catalog, got: %d (%s)", w.Code, w.Body.String())
+	}
+	iso = parseAPIResponse(t, w.Body.Bytes()).Data.(map[string]any)
+	if iso["download_url"] != "https://download.rockylinux.org/pub/rocky/9/isos/aarch64/Rocky-9.4-aarch64-minimal.iso" || iso["preset"] != "rocky-minimal" {
+		t.Errorf("Unexpected ISO from the catalog: %v", iso)
+	}
+	w = doCredentialRequest(router, http.MethodPost, "/api/catalog/rocky-minimal/9.4/aarch64/download", `{"edition":"dvd"}`)
+	if w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), "Rocky-9.4-aarch64-dvd.iso") {
+		t.Errorf("Expected the edition from the body, got: %d (%s)", w.Code, w.Body.String())
+	}
+	chunked := httptest.NewRequest(http.MethodPost, "/api/catalog/rocky-minimal/9.5/aarch64/download", http.NoBody)
+	chunked.ContentLength = -1 // Sent without a length, as with chunked encoding
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, chunked)
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201 for an empty body without a length, got: %d (%s)", w.Code, w.Body.String())
+	}
+	if w := doCredentialRequest(router, http.MethodPost, "/api/catalog/missing/1/x86_64/download", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown distro, got: %d", w.Code)
+	}
+
+	w = doCredentialRequest(router, http.MethodPut, "/api/presets/rocky-minimal", `{"edition":"dvd"}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"edition":"dvd"`) {
+		t.Errorf("Expected the updated preset, got: %d (%s)", w.Code, w.Body.String())
+	}
+
+	w = doCredentialRequest(router, http.MethodGet, "/api/presets/export", "")
+	pack := w.Body.String()
+	if w.Code != http.StatusOK || !strings.Contains(pack, "name: rocky-minimal") || !strings.Contains(pack, "edition: dvd") {
+		t.Fatalf("Expected the preset pack, got: %d (%s)", w.Code, pack)
+	}
+	if w := doCredentialRequest(router, http.MethodGet, "/api/presets/export?names=missing", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 exporting an unknown preset, got: %d", w.Code)
+	}
+	w = doCredentialRequest(router, http.MethodPost, "/api/presets/import", pack)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"skipped":["rocky-minimal"]`) {
+		t.Errorf("Expected the existing preset to be skipped, got: %d (%s)", w.Code, w.Body.String())
+	}
+	w = doCredentialRequest(router, http.MethodPost, "/api/presets/import?existing=replace", `version: 1
+presets:
+  - name: rocky-minimal
+    iso_name: rocky
+    download_url: https://example.com/rocky-{version}.iso
+  - name: alpine
+    iso_name: alpine
+    download_url: https://example.com/alpine-{version}.iso
+`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"created":["alpine"]`) || !strings.Contains(w.Body.String(), `"replaced":["rocky-minimal"]`) {
+		t.Errorf("Expected one preset created and one replaced, got: %d (%s)", w.Code, w.Body.String())
+	}
+	for _, body := range []string{
+		"version: 2\npresets: []\n",
+		"version: 1\npresets:\n  - name: x\n    iso_name: x\n    download_url: https://example.com/x.iso\n    mirror: true\n",
+		"version: 1\npresets:\n  - name: x\n    iso_name: x\n    download_url: https://example.com/{release}.iso\n",
+	} {
+		if w := doCredentialRequest(router, http.MethodPost, "/api/presets/import", body); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 importing %q, got: %d", body, w.Code)
+		}
+	}
+	if w := doCredentialRequest(router, http.MethodPost, "/api/presets/import?existing=merge", pack); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown existing mode, got: %d", w.Code)
+	}
+
+	for _, name := range []string{"rocky-minimal", "alpine"} {
+		if w := doCredentialRequest(router, http.MethodDelete, "/api/presets/"+name, ""); w.Code != http.StatusOK {
+			t.Errorf("Expected status 200 deleting %s, got: %d", name, w.Code)
+		}
+	}
+	w = doCredentialRequest(router, http.MethodGet, "/api/presets", "")
+	if !strings.Contains(w.Body.String(), `"data":[]`) {
+		t.Errorf("Expected no presets after delete, got: %s", w.Body.String())
+	}
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// replicaBlockedRoutes are the routes that add, change, or fetch catalog
+// entries. A replica's catalog follows its primary, so it refuses them;
+// sessions, download links, and per-instance download stats still work.
+var replicaBlockedRoutes = map[string]bool{
+	"POST /api/isos":                                    true,
+	"POST /api/isos/adopt":                              true,
+	"POST /api/isos/reconcile":                          true,
+	"POST /api/isos/bump":                               true,
+	"PUT /api/isos/:id":                                 true,
+	"DELETE /api/isos/:id":                              true,
+	"POST /api/isos/:id/retry":                          true,
+	"POST /api/isos/:id/cancel":                         true,
+	"POST /api/isos/:id/clone":                          true,
+	"POST /api/isos/:id/check-upstream":                 true,
+	"POST /api/isos/:id/refresh":                        true,
+	"POST /api/isos/:id/redownload":                     true,
+	"POST /api/isos/:id/release":                        true,
+	"PUT /api/isos/:id/pin":                             true,
+	"DELETE /api/isos/:id/pin":                          true,
+	"POST /api/presets/:name/isos":                      true,
+	"POST /api/catalog/:distro/:version/:arch/download": true,
+	"POST /api/manifest/import":                         true,
+	"POST /api/bundles/import":                          true,
+	"POST /api/storage/relocation":                      true,
+	"POST /api/hooks/:name":                             true,
+}
+
+// ReadOnlyReplicaMiddleware rejects the routes in replicaBlockedRoutes with
+// 403, pointing the client at the primary.
+func ReadOnlyReplicaMiddleware(primaryURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if replicaBlockedRoutes[c.Request.Method+" "+c.FullPath()] {
+			ErrorResponse(c, http.StatusForbidden, ErrCodeReadOnlyReplica, "This instance is a read-only replica; make changes on "+primaryURL)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// GetReplicaStatus returns the state of the replica sync.
+func (h *StatsHandlers) GetReplicaStatus(c *gin.Context) {
+	SuccessResponse(c, http.StatusOK, h.statsService.ReplicaStatus())
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/aloks98/isoman/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RepoMetadataHandler serves the signed repository metadata under /repo/:
+// root.json, targets.json, SHA256SUMS with its detached signature
+// SHA256SUMS.sig, and the public key key.pub for checking it.
+func RepoMetadataHandler(repo *service.RepoMetadataService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var (
+			body        []byte
+			contentType = "application/json"
+			err         error
+		)
+		switch c.Param("file") {
+		case "root.json":
+			body = repo.Root()
+		case "targets.json":
+			body, err = repo.Targets()
+		case "SHA256SUMS":
+			body, _, err = repo.Checksums()
+			contentType = "text/plain; charset=utf-8"
+		case "SHA256SUMS.sig":
+			_, body, err = repo.Checksums()
+			contentType = "application/octet-stream"
+		case "key.pub":
+			body, err = repo.PublicKeyPEM()
+			contentType = "application/x-pem-file"
+		default:
+			ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "Resource not found")
+			return
+		}
+		if err != nil {
+			slog.Warn("failed to build repository metadata", slog.String("file", c.Param("file")), slog.Any("error", err))
+			c.String(http.StatusInternalServerError, "Failed to build repository metadata")
+			return
+		}
+
+		// Clients must see a new version as soon as it is signed
+		c.Header("Cache-Control", "no-cache")
+		c.Data(http.StatusOK, contentType, body)
+	}
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aloks98/isoman/backend/internal/logger"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	router := gin.New()
+	router.Use(RequestIDMiddleware())
+	router.GET("/id", func(c *gin.Context) {
+		c.String(http.StatusOK, logger.RequestID(c.Request.Context()))
+	})
+	router.GET("/fail", func(c *gin.Context) {
+		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "ISO not found")
+	})
+
+	tests := []struct {
+		name     string
+		incoming string
+		reuse    bool
+	}{
+		{"Generated", "", false},
+		{"Reused", "client-abc-123", true},
+		{"TooLong", strings.Repeat("a", maxRequestIDLength+1), false},
+		{"ControlCharacters", "bad\tid", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/id", http.NoBody)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			got := w.Header().Get(RequestIDHeader)
+			if got == "" {
+				t.Fatal("Expected X-Request-ID in the response")
+			}
+			if w.Body.String() != got {
+				t.Errorf("Expected the context to carry %q, got %q", got, w.Body.String())
+			}
+			if tt.reuse != (got == tt.incoming) {
+				t.Errorf("Incoming %q, response %q, expected reuse=%v", tt.incoming, got, tt.reuse)
+			}
+		})
+	}
+
+	t.Run("ErrorBody", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/fail", http.NoBody)
+		req.Header.Set(RequestIDHeader, "trace-me")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var problem Problem
+		if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+			t.Fatalf("Failed to parse problem: %v", err)
+		}
+		if problem.RequestID != "trace-me" {
+			t.Errorf("Expected request_id trace-me in the error body, got %q", problem.RequestID)
+		}
+	})
+}
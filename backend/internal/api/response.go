@@ -1,11 +1,22 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"strings"
+
+	"github.com/aloks98/isoman/backend/internal/logger"
+	"github.com/aloks98/isoman/backend/internal/validation"
 
 	"github.com/gin-gonic/gin"
 )
 
+// ProblemContentType is the media type of error responses (RFC 7807).
+const ProblemContentType = "application/problem+json"
+
+// problemTypePrefix prefixes the error code in a problem's type URI.
+const problemTypePrefix = "urn:isoman:error:"
+
 // APIResponse represents a standard API response structure.
 type APIResponse struct {
 	Data    interface{} `json:"data,omitempty"`
@@ -21,6 +32,28 @@ type APIError struct {
 	Details string `json:"details,omitempty"`
 }
 
+// FieldError describes why a single request field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Problem is an RFC 7807 problem details body. It also carries the success and
+// error members of APIResponse, so clients reading the envelope keep working.
+type Problem struct {
+	Data      interface{}  `json:"data,omitempty"`
+	Error     *APIError    `json:"error"`
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"` // Quote it when reporting a problem
+	Errors    []FieldError `json:"errors,omitempty"`
+	Status    int          `json:"status"`
+	Success   bool         `json:"success"`
+}
+
 // SuccessResponse sends a successful response with data.
 func SuccessResponse(c *gin.Context, statusCode int, data interface{}) {
 	c.JSON(statusCode, APIResponse{
@@ -40,24 +73,47 @@ func SuccessResponseWithMessage(c *gin.Context, statusCode int, data interface{}
 
 // ErrorResponse sends an error response.
 func ErrorResponse(c *gin.Context, statusCode int, code string, message string) {
-	c.JSON(statusCode, APIResponse{
-		Success: false,
-		Error: &APIError{
-			Code:    code,
-			Message: message,
-		},
-	})
+	ProblemResponse(c, statusCode, &APIError{Code: code, Message: message}, nil, nil)
 }
 
 // ErrorResponseWithDetails sends an error response with additional details.
 func ErrorResponseWithDetails(c *gin.Context, statusCode int, code string, message string, details string) {
-	c.JSON(statusCode, APIResponse{
-		Success: false,
-		Error: &APIError{
-			Code:    code,
-			Message: message,
-			Details: details,
-		},
+	ProblemResponse(c, statusCode, &APIError{Code: code, Message: message, Details: details}, nil, nil)
+}
+
+// ValidationErrorResponse sends a 400 for a request that failed validation,
+// listing each rejected field when err is a validation.ValidationErrors.
+func ValidationErrorResponse(c *gin.Context, message string, err error) {
+	var fields []FieldError
+	var validationErrs *validation.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		for _, fe := range validationErrs.Errors {
+			fields = append(fields, FieldError{Field: fe.Field, Message: fe.Message})
+		}
+	}
+	ProblemResponse(c, http.StatusBadRequest, &APIError{Code: ErrCodeValidationFailed, Message: message, Details: err.Error()}, fields, nil)
+}
+
+// ProblemResponse sends apiErr as an RFC 7807 problem. fields and data are optional.
+func ProblemResponse(c *gin.Context, statusCode int, apiErr *APIError, fields []FieldError, data interface{}) {
+	detail := apiErr.Message
+	if apiErr.Details != "" {
+		detail += ": " + apiErr.Details
+	}
+
+	c.Header("Content-Type", ProblemContentType)
+	c.JSON(statusCode, Problem{
+		Type:      problemTypePrefix + strings.ToLower(strings.ReplaceAll(apiErr.Code, "_", "-")),
+		Title:     http.StatusText(statusCode),
+		Status:    statusCode,
+		Detail:    detail,
+		Instance:  c.Request.URL.Path,
+		Code:      apiErr.Code,
+		RequestID: logger.RequestID(c.Request.Context()),
+		Errors:    fields,
+		Data:      data,
+		Success:   false,
+		Error:     apiErr,
 	})
 }
 
@@ -69,6 +125,15 @@ const (
 	ErrCodeInternalError    = "INTERNAL_ERROR"
 	ErrCodeValidationFailed = "VALIDATION_FAILED"
 	ErrCodeInvalidState     = "INVALID_STATE"
+	ErrCodeUpstreamError    = "UPSTREAM_ERROR"
+	ErrCodeQueueFull        = "QUEUE_FULL"
+	ErrCodeCredentialsOff   = "CREDENTIALS_DISABLED"
+	ErrCodeUnauthorized     = "UNAUTHORIZED"
+	ErrCodeTOTPRequired     = "TOTP_REQUIRED"
+	ErrCodeTooManyAttempts  = "TOO_MANY_ATTEMPTS"
+	ErrCodeCSRFFailed       = "CSRF_FAILED"
+	ErrCodeReadOnlyReplica  = "READ_ONLY_REPLICA"
+	ErrCodePayloadTooLarge  = "PAYLOAD_TOO_LARGE"
 )
 
 // NoContentResponse sends a 204 No Content response (for DELETE operations).
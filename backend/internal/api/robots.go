@@ -0,0 +1,61 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+
+	"github.com/gin-gonic/gin"
+)
+
+// robotsPolicyOrDefault returns policy when valid, falling back to the default.
+func robotsPolicyOrDefault(policy string) string {
+	if constants.IsValidRobotsPolicy(policy) {
+		return strings.ToLower(policy)
+	}
+	if policy != "" {
+		slog.Warn("unknown robots policy, using default", slog.String("policy", policy), slog.String("default", constants.DefaultRobotsPolicy))
+	}
+	return constants.DefaultRobotsPolicy
+}
+
+// generateRobotsTxt builds the robots.txt body for a robots policy.
+func generateRobotsTxt(policy string) string {
+	switch robotsPolicyOrDefault(policy) {
+	case constants.RobotsPolicyAllow:
+		return "User-agent: *\nDisallow:\n"
+	case constants.RobotsPolicyDisallowAll:
+		return "User-agent: *\nDisallow: /\n"
+	default:
+		return "User-agent: *\nDisallow: /images/\n"
+	}
+}
+
+// RobotsHandler serves /robots.txt. A readable customFile is served verbatim;
+// otherwise the body is generated from policy. The body is built once.
+func RobotsHandler(policy, customFile string) gin.HandlerFunc {
+	body := generateRobotsTxt(policy)
+	if customFile != "" {
+		content, err := os.ReadFile(customFile)
+		if err != nil {
+			slog.Warn("failed to read robots.txt file, using generated policy", slog.String("path", customFile), slog.Any("error", err))
+		} else {
+			body = string(content)
+		}
+	}
+
+	return func(c *gin.Context) {
+		c.String(http.StatusOK, body)
+	}
+}
+
+// NoIndexMiddleware asks crawlers not to index or follow anything it serves.
+func NoIndexMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("X-Robots-Tag", "noindex, nofollow")
+		c.Next()
+	}
+}
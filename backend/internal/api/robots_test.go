@@ -0,0 +1,104 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/download"
+	"github.com/aloks98/isoman/backend/internal/service"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+	"github.com/aloks98/isoman/backend/internal/ws"
+)
+
+func TestRobotsHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		want   string
+	}{
+		{"Allow", constants.RobotsPolicyAllow, "User-agent: *\nDisallow:\n"},
+		{"DisallowImages", constants.RobotsPolicyDisallowImages, "User-agent: *\nDisallow: /images/\n"},
+		{"DisallowAll", "DISALLOW-ALL", "User-agent: *\nDisallow: /\n"},
+		{"UnknownUsesDefault", "bogus", "User-agent: *\nDisallow: /images/\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/robots.txt", http.NoBody)
+
+			RobotsHandler(tt.policy, "")(c)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got: %d", w.Code)
+			}
+			if w.Body.String() != tt.want {
+				t.Errorf("Expected body %q, got: %q", tt.want, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("CustomFile", func(t *testing.T) {
+		custom := filepath.Join(t.TempDir(), "robots.txt")
+		content := "User-agent: GoodBot\nAllow: /\n"
+		if err := os.WriteFile(custom, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write robots.txt: %v", err)
+		}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		RobotsHandler(constants.RobotsPolicyDisallowAll, custom)(c)
+
+		if w.Body.String() != content {
+			t.Errorf("Expected custom file to be served verbatim, got: %q", w.Body.String())
+		}
+	})
+
+	t.Run("MissingCustomFileFallsBack", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		RobotsHandler(constants.RobotsPolicyDisallowAll, filepath.Join(t.TempDir(), "missing.txt"))(c)
+
+		if w.Body.String() != "User-agent: *\nDisallow: /\n" {
+			t.Errorf("Expected generated policy, got: %q", w.Body.String())
+		}
+	})
+}
+
+func TestImagesNoIndexHeader(t *testing.T) {
+	for _, noIndex := range []bool{false, true} {
+		env := testutil.SetupTestEnvironment(t)
+		env.Config.Server.ImagesNoIndex = noIndex
+
+		manager := download.NewManager(env.DB, env.ISODir, 1)
+		isoService := service.NewISOService(env.DB, manager, env.ISODir)
+		router := setupTestRouter(env, isoService, ws.NewHub())
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/images/", http.NoBody))
+
+		got := w.Header().Get("X-Robots-Tag")
+		if noIndex && got != "noindex, nofollow" {
+			t.Errorf("Expected X-Robots-Tag with IMAGES_NOINDEX, got: %q", got)
+		}
+		if !noIndex && got != "" {
+			t.Errorf("Expected no X-Robots-Tag by default, got: %q", got)
+		}
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/robots.txt", http.NoBody))
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected /robots.txt to be served, got: %d", w.Code)
+		}
+
+		manager.Stop()
+		env.Cleanup()
+	}
+}
@@ -1,65 +1,301 @@
 package api
 
 import (
+	"log/slog"
+	"net/http"
+	"strings"
+
 	"github.com/aloks98/isoman/backend/internal/config"
+	"github.com/aloks98/isoman/backend/internal/constants"
 	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/i18n"
+	"github.com/aloks98/isoman/backend/internal/pathutil"
 	"github.com/aloks98/isoman/backend/internal/service"
+	"github.com/aloks98/isoman/backend/internal/validation"
 	"github.com/aloks98/isoman/backend/internal/ws"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
-// SetupRoutes configures all routes and middleware.
-func SetupRoutes(isoService *service.ISOService, statsService *service.StatsService, database *db.DB, isoDir string, wsHub *ws.Hub, cfg *config.Config) *gin.Engine {
+// SetupRoutes configures all routes and middleware. authService is shared with
+// the admin listener, so both see the same sessions and login lockouts.
+func SetupRoutes(isoService *service.ISOService, statsService *service.StatsService, authService *service.AuthService, database *db.DB, isoDir string, wsHub *ws.Hub, cfg *config.Config) *gin.Engine {
 	// Set Gin to release mode for production (can be overridden by GIN_MODE env var)
 	// gin.SetMode(gin.ReleaseMode)
 
 	router := gin.Default()
+	router.Use(RequestIDMiddleware())
+	router.Use(BodyLimitMiddleware(cfg.Server.MaxBodySize, cfg.Server.MaxUploadSize))
+
+	// Client IPs feed login throttling, download analytics, and the audit
+	// log, so forwarding headers are only believed from configured proxies
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		slog.Warn("invalid TRUSTED_PROXIES, trusting no proxies", slog.Any("error", err))
+		_ = router.SetTrustedProxies(nil)
+	}
 
 	// Configure CORS
 	corsConfig := cors.DefaultConfig()
 	corsConfig.AllowOrigins = cfg.Server.CORSOrigins
-	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept"}
+	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", CSRFHeader, RequestIDHeader}
+	corsConfig.ExposeHeaders = []string{RequestIDHeader}
+	corsConfig.AllowCredentials = cfg.Auth.Enabled // The dev UI on another port sends the session cookie
 	router.Use(cors.New(corsConfig))
 
+	// A replica's catalog is synced from its primary and can't be edited here
+	if cfg.Replica.PrimaryURL != "" {
+		router.Use(ReadOnlyReplicaMiddleware(cfg.Replica.PrimaryURL))
+	}
+
 	// Create handlers
-	handlers := NewHandlers(isoService, isoDir)
+	handlers := NewHandlers(isoService, isoDir, pathutil.ResolveTempDir(isoDir, cfg.Download.TempDir))
+	allowedNetworks, _ := validation.ParseNetworks(cfg.Download.HTTPAllowedNetworks) // Invalid entries are logged by httputil
+	handlers.SetURLChecks(&validation.URLCheckOptions{
+		AllowedSchemes:  cfg.Download.URLAllowedSchemes,
+		ResolveHost:     cfg.Download.URLCheckDNS,
+		BlockPrivateIPs: cfg.Download.URLBlockPrivateIPs,
+		AllowedNetworks: allowedNetworks,
+	})
 	statsHandlers := NewStatsHandlers(statsService)
+	storageHandlers := NewStorageHandlers(service.NewStorageService(isoDir))
+	credentialService := isoService.Credentials()
+	if credentialService == nil {
+		credentialService = service.NewCredentialService(database, nil)
+	}
+	credentialHandlers := NewCredentialHandlers(credentialService)
+	presetHandlers := NewPresetHandlers(service.NewPresetService(database), handlers)
+	checksumHandlers := NewChecksumHandlers(service.NewChecksumService(database))
+	brandingService := service.NewBrandingService(database)
+	brandingHandlers := NewBrandingHandlers(brandingService)
+	snapshotHandlers := NewSnapshotHandlers(service.NewSnapshotService(database, cfg.Snapshot.Retention))
+	webhookHandlers := NewWebhookHandlers(service.NewWebhookService(database, credentialService), handlers)
+	linkService := service.NewDownloadLinkService(database, isoDir)
+	linkHandlers := NewDownloadLinkHandlers(linkService, cfg.Server.ExternalURL)
+	authHandlers := NewAuthHandlers(authService, cfg.Auth.CookieSecure)
+	publicScopes := publicScopeSet(cfg.Auth.PublicScopes)
+	handlers.SetSnippetConfig(cfg.Server.ExternalURL, cfg.Auth.Enabled && !publicScopes[constants.AuthScopeImages])
+	handlers.SetSchemaSource(database)
+	handlers.SetAccessConfig(cfg.Auth.Enabled, cfg.Replica.PrimaryURL != "")
 
-	// API routes
+	// Sign-in is reachable without a session
+	authRoutes := router.Group("/api/auth", CSRFMiddleware())
+	{
+		authRoutes.POST("/login", authHandlers.Login)
+		authRoutes.POST("/logout", authHandlers.Logout)
+		authRoutes.GET("/me", authHandlers.Me)
+		authRoutes.POST("/totp", authHandlers.BeginTOTP)
+		authRoutes.POST("/totp/confirm", authHandlers.ConfirmTOTP)
+		authRoutes.POST("/totp/disable", authHandlers.DisableTOTP)
+		authRoutes.POST("/totp/recovery-codes", authHandlers.RegenerateRecoveryCodes)
+	}
+
+	// API routes; AUTH_PUBLIC_SCOPES can leave read-only ones open
 	api := router.Group("/api")
+	if cfg.Auth.Enabled {
+		api.Use(ScopedAuthMiddleware(authService, publicScopes))
+	}
+	api.Use(CSRFMiddleware())
 	{
 		// ISO management
 		api.GET("/isos", handlers.ListISOs)
+		api.GET("/isos/preview", handlers.PreviewISO)
+		api.GET("/isos/by-checksum/:sha256", handlers.FindISOsByChecksum)
 		api.GET("/isos/:id", handlers.GetISO)
+		api.GET("/isos/:id/log", handlers.GetDownloadLog)
+		api.GET("/isos/:id/wait", handlers.WaitForISO)
+		api.GET("/isos/:id/verification", handlers.GetVerificationReport)
+		api.GET("/isos/:id/snippets", handlers.GetISOSnippets)
 		api.POST("/isos", handlers.CreateISO)
+		api.POST("/isos/adopt", handlers.AdoptDirectory)
+		api.POST("/isos/reconcile", handlers.ReconcilePaths)
+		api.POST("/isos/bump", handlers.BumpVersion)
 		api.PUT("/isos/:id", handlers.UpdateISO)
 		api.DELETE("/isos/:id", handlers.DeleteISO)
 		api.POST("/isos/:id/retry", handlers.RetryISO)
+		api.POST("/isos/:id/cancel", handlers.CancelISO)
+		api.POST("/isos/:id/clone", handlers.CloneISO)
+		api.POST("/isos/:id/check-upstream", handlers.CheckUpstream)
+		api.POST("/isos/:id/refresh", handlers.RefreshISO)
+		api.POST("/isos/:id/redownload", handlers.RedownloadISO)
+		api.POST("/isos/:id/verify", handlers.VerifyISO)
+		api.POST("/isos/:id/release", handlers.ReleaseISO)
+		api.PUT("/isos/:id/pin", handlers.PinISO)
+		api.DELETE("/isos/:id/pin", handlers.UnpinISO)
+		api.GET("/actions", handlers.ListActions)
 
 		// Statistics
 		api.GET("/stats", statsHandlers.GetStats)
 		api.GET("/stats/trends", statsHandlers.GetDownloadTrends)
+		api.GET("/stats/live", statsHandlers.GetLiveThroughput)
+		api.GET("/downloads/active", statsHandlers.ListActiveDownloads)
+		api.GET("/downloads/queue", statsHandlers.GetQueueForecast)
+		api.GET("/downloads/state", statsHandlers.GetManagerState)
+		api.GET("/replica", statsHandlers.GetReplicaStatus)
+		api.POST("/isos/:id/stats/reset", statsHandlers.ResetDownloadStats)
+		api.POST("/isos/:id/stats/adjust", statsHandlers.AdjustDownloadCount)
+		api.GET("/isos/:id/stats/downloads", statsHandlers.ListDownloadEvents)
+
+		// Audit log
+		api.GET("/audit", statsHandlers.ListAuditEvents)
+
+		// Health history
+		api.GET("/system/events", statsHandlers.ListSystemEvents)
+
+		// Runtime log level, e.g. debug logging during an incident
+		api.GET("/admin/log-level", statsHandlers.GetLogSettings)
+		api.PATCH("/admin/log-level", statsHandlers.UpdateLogSettings)
+
+		// Files deleted by garbage collection
+		api.GET("/gc/reports", statsHandlers.ListGCReports)
+		api.GET("/gc/reports/:id", statsHandlers.GetGCReport)
+
+		// Catalog changelog
+		api.GET("/changes", snapshotHandlers.GetChanges)
+
+		// Mirror health
+		api.GET("/mirrors", statsHandlers.ListMirrors)
+
+		// Storage relocation
+		api.GET("/storage/relocation", storageHandlers.GetRelocation)
+		api.POST("/storage/relocation", storageHandlers.StartRelocation)
+
+		// Upstream credentials
+		api.GET("/credentials", credentialHandlers.ListCredentials)
+		api.GET("/credentials/:name", credentialHandlers.GetCredential)
+		api.POST("/credentials", credentialHandlers.CreateCredential)
+		api.PUT("/credentials/:name", credentialHandlers.UpdateCredential)
+		api.DELETE("/credentials/:name", credentialHandlers.DeleteCredential)
+
+		// Presets
+		api.GET("/presets", presetHandlers.ListPresets)
+		api.GET("/presets/export", presetHandlers.ExportPresets)
+		api.POST("/presets/import", presetHandlers.ImportPresets)
+		api.GET("/presets/:name", presetHandlers.GetPreset)
+		api.POST("/presets", presetHandlers.CreatePreset)
+		api.PUT("/presets/:name", presetHandlers.UpdatePreset)
+		api.DELETE("/presets/:name", presetHandlers.DeletePreset)
+		api.POST("/presets/:name/isos", presetHandlers.ApplyPreset)
+		api.POST("/catalog/:distro/:version/:arch/download", presetHandlers.DownloadFromCatalog)
+
+		// Known checksums for offline verification
+		api.GET("/checksums", checksumHandlers.ListKnownChecksums)
+		api.POST("/checksums/import", checksumHandlers.ImportChecksums)
+
+		// Directory listing branding
+		api.GET("/branding", brandingHandlers.GetListingBranding)
+		api.PUT("/branding", brandingHandlers.UpdateListingBranding)
+		api.GET("/listing/columns", brandingHandlers.GetListingColumns)
+		api.PUT("/listing/columns", brandingHandlers.UpdateListingColumns)
+
+		// Inbound webhooks
+		api.GET("/hooks", webhookHandlers.ListWebhooks)
+		api.GET("/hooks/:name", webhookHandlers.GetWebhook)
+		api.POST("/hooks", webhookHandlers.CreateWebhook)
+		api.PUT("/hooks/:name", webhookHandlers.UpdateWebhook)
+		api.DELETE("/hooks/:name", webhookHandlers.DeleteWebhook)
+
+		// Download links
+		api.GET("/download-links", linkHandlers.ListDownloadLinks)
+		api.POST("/download-links", linkHandlers.CreateDownloadLink)
+		api.DELETE("/download-links/:id", linkHandlers.RevokeDownloadLink)
+
+		// Sessions
+		api.GET("/sessions", authHandlers.ListSessions)
+		api.DELETE("/sessions", authHandlers.RevokeSessions)
+		api.DELETE("/sessions/:id", authHandlers.RevokeSession)
+
+		// Offline transfer
+		api.GET("/manifest", handlers.ExportManifest)
+		api.POST("/manifest/import", handlers.ImportManifest)
+		api.POST("/bundles/export", handlers.ExportBundle)
+		api.POST("/bundles/import", handlers.ImportBundle)
 	}
 
+	// Webhook deliveries are signed with the webhook's secret instead of
+	// carrying a session, so they stay outside the auth and CSRF middleware
+	router.POST("/api/hooks/:name", webhookHandlers.ReceiveWebhook)
+
 	// WebSocket endpoint
-	router.GET("/ws", func(c *gin.Context) {
+	wsHandlers := []gin.HandlerFunc{func(c *gin.Context) {
 		ws.ServeWS(wsHub, c)
-	})
+	}}
+	if cfg.Auth.Enabled && !publicScopes[constants.AuthScopeWS] {
+		wsHandlers = append([]gin.HandlerFunc{RequireAuthMiddleware(authService)}, wsHandlers...)
+	}
+	router.GET("/ws", wsHandlers...)
 
 	// Health check
 	router.GET("/health", handlers.HealthCheck)
 
+	// Build and schema version; public like /health so deploy tooling can
+	// compare replicas without a session
+	router.GET("/api/version", VersionHandler(cfg.Version, database))
+
+	// Public status summary and badge; like /health, never behind auth
+	router.GET("/status", statsHandlers.GetStatus)
+	router.GET("/status/badge.svg", statsHandlers.GetStatusBadge)
+
 	// Static file serving and directory listing with download tracking
 	// This handles both /images/ (directory listing) and /images/* (file downloads)
 	dirConfig := &DirectoryHandlerConfig{
-		ISODir:       isoDir,
-		StatsService: statsService,
-		DB:           database,
+		ISODir:           isoDir,
+		TempDir:          pathutil.ResolveTempDir(isoDir, cfg.Download.TempDir),
+		HiddenFiles:      cfg.Server.HiddenFiles,
+		SymlinkPolicy:    cfg.Server.SymlinkPolicy,
+		ListingCacheTTL:  cfg.Server.ListingCacheTTL,
+		VerifyOnServe:    cfg.Server.ServeVerify,
+		StreamInProgress: cfg.Server.StreamInProgress,
+		StatsService:     statsService,
+		Links:            linkService,
+		Branding:         brandingService,
+		DB:               database,
+
+		FileCacheControl:    cfg.CDN.FileCacheControl,
+		ListingCacheControl: cfg.CDN.ListingCacheControl,
+
+		NegotiateLanguage: cfg.Server.LocaleNegotiate,
 	}
-	router.GET("/images/*filepath", DirectoryHandler(dirConfig))
+	// main won't start with catalogs that don't load; without it, list in English
+	if catalog, err := i18n.Load(cfg.Server.Locale, cfg.Server.LocaleDir); err != nil {
+		slog.Warn("failed to load message catalogs, listing in English", slog.Any("error", err))
+	} else {
+		dirConfig.Catalog = catalog
+	}
+	if gauge := statsService.ThroughputGauge(); gauge != nil {
+		dirConfig.EgressMeter = &gauge.Egress
+	}
+	imageHandlers := []gin.HandlerFunc{DirectoryHandler(dirConfig)}
+	if cfg.Server.ImagesNoIndex {
+		imageHandlers = append([]gin.HandlerFunc{NoIndexMiddleware()}, imageHandlers...)
+	}
+	if cfg.Auth.Enabled && !publicScopes[constants.AuthScopeImages] {
+		imageHandlers = append([]gin.HandlerFunc{skipWithDownloadToken(RequireAuthMiddleware(authService))}, imageHandlers...)
+	}
+	router.GET("/images/*filepath", imageHandlers...)
+
+	// Atom feed of recently completed ISOs for RSS readers and chat integrations
+	feedHandlers := []gin.HandlerFunc{FeedHandler(database, cfg.Server.ExternalURL)}
+	if cfg.Auth.Enabled && !publicScopes[constants.AuthScopeFeed] {
+		feedHandlers = append([]gin.HandlerFunc{RequireAuthMiddleware(authService)}, feedHandlers...)
+	}
+	router.GET("/feed.xml", feedHandlers...)
+
+	// Signed metadata covering every file under /images, public whenever /images is
+	if repo := isoService.RepoMetadata(); repo != nil {
+		repoHandlers := []gin.HandlerFunc{RepoMetadataHandler(repo)}
+		if cfg.Auth.Enabled && !publicScopes[constants.AuthScopeImages] {
+			repoHandlers = append([]gin.HandlerFunc{RequireAuthMiddleware(authService)}, repoHandlers...)
+		}
+		router.GET("/repo/:file", repoHandlers...)
+	}
+
+	// Discovery document for client tools; public like /health
+	router.GET("/.well-known/isoman.json", DiscoveryHandler(cfg, publicScopes, isoService.RepoMetadata() != nil))
+
+	// Crawl control for publicly reachable instances
+	router.GET("/robots.txt", RobotsHandler(cfg.Server.RobotsPolicy, cfg.Server.RobotsTxtFile))
 
 	// Serve frontend static files
 	// In production, frontend is built into ui/dist
@@ -74,14 +310,14 @@ func SetupRoutes(isoService *service.ISOService, statsService *service.StatsServ
 
 	// Serve index.html for root and all other routes (for React Router)
 	router.NoRoute(func(c *gin.Context) {
-		// Don't serve index.html for API routes, WS, images, or health check
+		// Don't serve index.html for API routes, WS, images, health check, or status
 		path := c.Request.URL.Path
 		if len(path) >= 4 && path[:4] == "/api" {
-			ErrorResponse(c, 404, "NOT_FOUND", "API endpoint not found")
+			ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "API endpoint not found")
 			return
 		}
-		if path == "/ws" || (len(path) >= 7 && path[:7] == "/images") || path == "/health" {
-			ErrorResponse(c, 404, "NOT_FOUND", "Resource not found")
+		if path == "/ws" || (len(path) >= 7 && path[:7] == "/images") || path == "/health" || strings.HasPrefix(path, "/status/") {
+			ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "Resource not found")
 			return
 		}
 
@@ -1,15 +1,20 @@
 package api
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/aloks98/isoman/backend/internal/download"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/repometa"
 	"github.com/aloks98/isoman/backend/internal/service"
 	"github.com/aloks98/isoman/backend/internal/testutil"
 	"github.com/aloks98/isoman/backend/internal/ws"
@@ -18,7 +23,9 @@ import (
 // Helper function to create SetupRoutes with test defaults
 func setupTestRouter(env *testutil.TestEnv, isoService *service.ISOService, wsHub *ws.Hub) *gin.Engine {
 	statsService := service.NewStatsService(env.DB)
-	return SetupRoutes(isoService, statsService, env.DB, env.ISODir, wsHub, env.Config)
+	authService := service.NewAuthService(env.DB, env.Config.Auth.SessionTTL)
+	authService.SetLockout(env.Config.Auth.MaxFailures, env.Config.Auth.Lockout)
+	return SetupRoutes(isoService, statsService, authService, env.DB, env.ISODir, wsHub, env.Config)
 }
 
 func TestSetupRoutes(t *testing.T) {
@@ -67,6 +74,12 @@ func TestAPIRoutes(t *testing.T) {
 			path:       "/api/isos/test-id",
 			wantStatus: http.StatusNotFound, // ID doesn't exist, but route exists
 		},
+		{
+			name:       "GET /api/isos/preview - should not be taken for an ID",
+			method:     http.MethodGet,
+			path:       "/api/isos/preview?name=alpine&version=3.19.1&arch=x86_64&file_type=iso",
+			wantStatus: http.StatusOK,
+		},
 		{
 			name:       "POST /api/isos - should be registered",
 			method:     http.MethodPost,
@@ -128,8 +141,8 @@ func TestAPIRouteNotFound(t *testing.T) {
 	}
 
 	// Verify error response format
-	if w.Header().Get("Content-Type") != "application/json; charset=utf-8" {
-		t.Errorf("Expected JSON content type, got %s", w.Header().Get("Content-Type"))
+	if w.Header().Get("Content-Type") != ProblemContentType {
+		t.Errorf("Expected problem+json content type, got %s", w.Header().Get("Content-Type"))
 	}
 }
 
@@ -308,3 +321,125 @@ func TestImagesRoute(t *testing.T) {
 		t.Errorf("Expected HTML content type, got %s", contentType)
 	}
 }
+
+func TestReplicaRoutes(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	manager := download.NewManager(env.DB, env.ISODir, 1)
+	defer manager.Stop()
+	isoService := service.NewISOService(env.DB, manager, env.ISODir)
+
+	env.Config.Replica.PrimaryURL = "http://primary.example.com"
+	router := setupTestRouter(env, isoService, ws.NewHub())
+
+	// Every blocked route must exist, or a typo would leave it writable
+	registered := make(map[string]bool)
+	for _, route := range router.Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+	for route := range replicaBlockedRoutes {
+		if !registered[route] {
+			t.Errorf("Blocked route %q is not registered", route)
+		}
+	}
+
+	tests := []struct {
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{http.MethodPost, "/api/isos", http.StatusForbidden},
+		{http.MethodDelete, "/api/isos/some-id", http.StatusForbidden},
+		{http.MethodPost, "/api/hooks/github", http.StatusForbidden},
+		{http.MethodPost, "/api/catalog/rocky/9.4/x86_64/download", http.StatusForbidden},
+		{http.MethodGet, "/api/isos", http.StatusOK},
+		{http.MethodGet, "/api/replica", http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(tt.method, tt.path, http.NoBody)
+		router.ServeHTTP(w, req)
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, w.Code, tt.wantStatus)
+		}
+	}
+}
+
+func TestRepoMetadataRoutes(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	manager := download.NewManager(env.DB, env.ISODir, 1)
+	defer manager.Stop()
+	isoService := service.NewISOService(env.DB, manager, env.ISODir)
+
+	// Not served without a repository key
+	router := setupTestRouter(env, isoService, ws.NewHub())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/repo/root.json", http.NoBody))
+	if testutil.StringContains(w.Body.String(), `"signatures"`) {
+		t.Error("Expected no repository metadata without a key")
+	}
+
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := repometa.NewSigner(key)
+	repo, err := service.NewRepoMetadataService(env.DB, signer, time.Hour, time.Hour)
+	if err != nil {
+		t.Fatalf("NewRepoMetadataService() failed: %v", err)
+	}
+	isoService.SetRepoMetadata(repo)
+	router = setupTestRouter(env, isoService, ws.NewHub())
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{"/repo/root.json", http.StatusOK},
+		{"/repo/targets.json", http.StatusOK},
+		{"/repo/SHA256SUMS", http.StatusOK},
+		{"/repo/SHA256SUMS.sig", http.StatusOK},
+		{"/repo/key.pub", http.StatusOK},
+		{"/repo/snapshot.json", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, http.NoBody))
+		if w.Code != tt.wantStatus {
+			t.Errorf("GET %s = %d, want %d", tt.path, w.Code, tt.wantStatus)
+		}
+	}
+}
+
+func TestTrustedProxies(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	manager := download.NewManager(env.DB, env.ISODir, 1)
+	defer manager.Stop()
+	isoService := service.NewISOService(env.DB, manager, env.ISODir)
+
+	env.Config.Server.TrustedProxies = []string{"10.0.0.0/24"}
+	router := setupTestRouter(env, isoService, ws.NewHub())
+	iso := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Status: models.StatusComplete})
+
+	// The forwarded address is only believed from a trusted proxy
+	for _, remoteAddr := range []string{"10.0.0.5:41000", "198.51.100.9:41000"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/isos/"+iso.ID+"/stats/reset", http.NoBody)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got: %d (%s)", w.Code, w.Body.String())
+		}
+	}
+
+	events, err := env.DB.ListAuditEvents(10)
+	if err != nil {
+		t.Fatalf("ListAuditEvents() failed: %v", err)
+	}
+	if len(events) != 2 || events[1].ClientIP != "203.0.113.7" || events[0].ClientIP != "198.51.100.9" {
+		t.Errorf("Expected the forwarded then the direct client IP, got %+v", events)
+	}
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"hash"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/download"
+	"github.com/aloks98/isoman/backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// verifyingWriter hashes a served file as it is written, so a complete
+// transfer doubles as an integrity check of the file on disk. This catches
+// bit rot in popular files without waiting for a scrub to reach them.
+type verifyingWriter struct {
+	gin.ResponseWriter
+	hasher   hash.Hash
+	database *db.DB
+	iso      *models.ISO
+	n        int64
+}
+
+// newVerifyingWriter wraps the response writer of a request for the ISO at
+// filePath. It returns nil when the transfer can't be checked: the request
+// isn't a full GET, or the file isn't a complete ISO with an integrity hash.
+func newVerifyingWriter(c *gin.Context, database *db.DB, filePath string) *verifyingWriter {
+	if c.Request.Method != http.MethodGet || c.GetHeader("Range") != "" {
+		return nil
+	}
+	iso, err := database.GetISOByFilePath(filePath)
+	if err != nil {
+		slog.Warn("failed to look up ISO for serve verification", slog.String("path", filePath), slog.Any("error", err))
+		return nil
+	}
+	if iso == nil || iso.Status != models.StatusComplete || iso.IntegrityHash == "" {
+		return nil
+	}
+	hasher, err := download.NewIntegrityHasher(iso.IntegrityHash)
+	if err != nil {
+		return nil
+	}
+	return &verifyingWriter{ResponseWriter: c.Writer, hasher: hasher, database: database, iso: iso}
+}
+
+func (w *verifyingWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.hasher.Write(b[:n])
+	w.n += int64(n)
+	return n, err
+}
+
+// check compares the digest of the served bytes with the ISO's integrity hash
+// once the whole file of the given size went out. Partial, interrupted, and
+// not-modified responses are ignored. A mismatch is logged and recorded as a
+// system event; the file keeps being served until an administrator verifies it.
+func (w *verifyingWriter) check(ctx context.Context, size int64) {
+	if w.Status() != http.StatusOK || w.n != size {
+		return
+	}
+	algorithm, _, _ := strings.Cut(w.iso.IntegrityHash, ":")
+	actual := fmt.Sprintf("%s:%x", algorithm, w.hasher.Sum(nil))
+	if actual == w.iso.IntegrityHash {
+		return
+	}
+
+	slog.WarnContext(ctx, "served ISO file failed integrity check",
+		slog.String("iso_id", w.iso.ID),
+		slog.String("path", w.iso.FilePath),
+		slog.String("expected", w.iso.IntegrityHash),
+		slog.String("actual", actual),
+	)
+	event := &models.SystemEvent{
+		Type:      models.EventIntegrityMismatch,
+		Severity:  models.SeverityError,
+		Message:   fmt.Sprintf("%s no longer matches its integrity hash (ISO %s); verify it and re-download if corrupt", w.iso.FilePath, w.iso.ID),
+		CreatedAt: time.Now(),
+	}
+	if err := w.database.RecordSystemEvent(event); err != nil {
+		slog.WarnContext(ctx, "failed to record system event", slog.String("type", event.Type), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SnapshotHandlers holds a reference to the catalog snapshot service.
+type SnapshotHandlers struct {
+	snapshotService *service.SnapshotService
+}
+
+// NewSnapshotHandlers creates a new SnapshotHandlers instance.
+func NewSnapshotHandlers(snapshotService *service.SnapshotService) *SnapshotHandlers {
+	return &SnapshotHandlers{
+		snapshotService: snapshotService,
+	}
+}
+
+// GetChanges lists the ISOs added, removed, and updated since a time, given
+// as an RFC 3339 timestamp or a date (midnight UTC).
+func (h *SnapshotHandlers) GetChanges(c *gin.Context) {
+	since, err := parseSince(c.Query("since"))
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid since: must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		return
+	}
+
+	changes, err := h.snapshotService.Changes(since)
+	if err != nil {
+		if errors.Is(err, db.ErrCatalogSnapshotNotFound) {
+			ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "No catalog snapshot has been taken yet")
+			return
+		}
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to list catalog changes")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, changes)
+}
+
+// parseSince parses an RFC 3339 timestamp or a YYYY-MM-DD date.
+func parseSince(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
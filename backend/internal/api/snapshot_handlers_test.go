@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/service"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSnapshotHandlers_GetChanges(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	snapshotService := service.NewSnapshotService(env.DB, 0)
+
+	router := gin.New()
+	router.GET("/api/changes", NewSnapshotHandlers(snapshotService).GetChanges)
+
+	if w := doCredentialRequest(router, http.MethodGet, "/api/changes?since=2026-10-01", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 before any snapshot, got: %d", w.Code)
+	}
+
+	if _, err := snapshotService.Take(); err != nil {
+		t.Fatalf("Take() failed: %v", err)
+	}
+	added := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Status: models.StatusComplete})
+
+	for _, since := range []string{"2026-10-01", "2026-10-01T12:00:00Z"} {
+		w := doCredentialRequest(router, http.MethodGet, "/api/changes?since="+since, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for since=%s, got: %d (%s)", since, w.Code, w.Body.String())
+		}
+		changes := parseAPIResponse(t, w.Body.Bytes()).Data.(map[string]any)
+		if list := changes["added"].([]any); len(list) != 1 || list[0].(map[string]any)["id"] != added.ID {
+			t.Errorf("Expected %s added, got %v", added.ID, changes["added"])
+		}
+		if list := changes["removed"].([]any); len(list) != 0 {
+			t.Errorf("Expected nothing removed, got %v", list)
+		}
+	}
+
+	for _, query := range []string{"", "?since=yesterday", "?since=2026-13-01"} {
+		if w := doCredentialRequest(router, http.MethodGet, "/api/changes"+query, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %q, got: %d", query, w.Code)
+		}
+	}
+}
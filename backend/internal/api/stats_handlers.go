@@ -1,9 +1,14 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
 	"github.com/aloks98/isoman/backend/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -22,8 +27,28 @@ func NewStatsHandlers(statsService *service.StatsService) *StatsHandlers {
 }
 
 // GetStats returns aggregated statistics.
+// Query params: top (default 10, max 100), group_by (name/arch/edition/file_type), status
 func (h *StatsHandlers) GetStats(c *gin.Context) {
-	stats, err := h.statsService.GetStats()
+	params := db.StatsParams{
+		GroupBy: c.Query("group_by"),
+		Status:  c.Query("status"),
+	}
+	if topStr := c.Query("top"); topStr != "" {
+		if top, err := strconv.Atoi(topStr); err == nil && top > 0 {
+			params.Top = top
+		}
+	}
+
+	if params.GroupBy != "" && !db.IsValidStatsGroupBy(params.GroupBy) {
+		ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, "group_by must be one of name, arch, edition, file_type")
+		return
+	}
+	if params.Status != "" && !models.ISOStatus(params.Status).IsValid() {
+		ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, "Unknown status: "+params.Status)
+		return
+	}
+
+	stats, err := h.statsService.GetStatsWithParams(params)
 	if err != nil {
 		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to retrieve statistics")
 		return
@@ -32,6 +57,65 @@ func (h *StatsHandlers) GetStats(c *gin.Context) {
 	SuccessResponse(c, http.StatusOK, stats)
 }
 
+// GetStatus returns the public instance status: health, the number of complete
+// ISOs, and when the last download completed.
+func (h *StatsHandlers) GetStatus(c *gin.Context) {
+	status, err := h.statsService.Status()
+	if err != nil {
+		ErrorResponse(c, http.StatusServiceUnavailable, ErrCodeInternalError, "Status unavailable")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, status)
+}
+
+// GetStatusBadge renders the instance status as an SVG badge for embedding in
+// wikis and READMEs.
+func (h *StatsHandlers) GetStatusBadge(c *gin.Context) {
+	message, color := models.InstanceStatusDown, badgeRed
+	if status, err := h.statsService.Status(); err == nil {
+		message = fmt.Sprintf("%s, %d ISOs", status.Status, status.CompleteISOs)
+		color = badgeGreen
+		if status.Status == models.InstanceStatusDegraded {
+			color = badgeOrange
+		}
+	}
+
+	// Badge proxies cache aggressively unless told not to
+	c.Header("Cache-Control", "no-cache, max-age=0")
+	c.Data(http.StatusOK, "image/svg+xml; charset=utf-8", renderBadge("isoman", message, color))
+}
+
+// GetLiveThroughput returns the latest aggregate download and serve throughput.
+func (h *StatsHandlers) GetLiveThroughput(c *gin.Context) {
+	SuccessResponse(c, http.StatusOK, h.statsService.LiveThroughput())
+}
+
+// ListActiveDownloads returns the downloads workers are running or verifying.
+func (h *StatsHandlers) ListActiveDownloads(c *gin.Context) {
+	SuccessResponse(c, http.StatusOK, h.statsService.ActiveDownloads())
+}
+
+// GetManagerState dumps the download manager's state for debugging stuck downloads.
+func (h *StatsHandlers) GetManagerState(c *gin.Context) {
+	state, err := h.statsService.ManagerState()
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to read download manager state")
+		return
+	}
+	SuccessResponse(c, http.StatusOK, state)
+}
+
+// GetQueueForecast returns the estimated start and completion times of queued downloads.
+func (h *StatsHandlers) GetQueueForecast(c *gin.Context) {
+	forecast, err := h.statsService.QueueForecast()
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to estimate queue wait times")
+		return
+	}
+	SuccessResponse(c, http.StatusOK, forecast)
+}
+
 // GetDownloadTrends returns download trends over time.
 func (h *StatsHandlers) GetDownloadTrends(c *gin.Context) {
 	period := c.DefaultQuery("period", "daily") // daily or weekly
@@ -55,3 +139,205 @@ func (h *StatsHandlers) GetDownloadTrends(c *gin.Context) {
 
 	SuccessResponse(c, http.StatusOK, trends)
 }
+
+// ListMirrors returns health information for each upstream host.
+func (h *StatsHandlers) ListMirrors(c *gin.Context) {
+	mirrors, err := h.statsService.ListMirrorHealth()
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to retrieve mirror health")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, mirrors)
+}
+
+// ResetDownloadStats clears an ISO's download count and download events.
+func (h *StatsHandlers) ResetDownloadStats(c *gin.Context) {
+	var req models.StatsResetRequest
+
+	// The body is optional; it only carries the reason for the audit log
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+			return
+		}
+	}
+
+	iso, err := h.statsService.ResetDownloadStats(c.Param("id"), req.Reason, c.ClientIP())
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "ISO not found")
+		return
+	}
+
+	SuccessResponseWithMessage(c, http.StatusOK, iso, "Download statistics reset")
+}
+
+// AdjustDownloadCount adds a positive or negative delta to an ISO's download count.
+func (h *StatsHandlers) AdjustDownloadCount(c *gin.Context) {
+	var req models.StatsAdjustRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+		return
+	}
+
+	iso, err := h.statsService.AdjustDownloadCount(c.Param("id"), req.Delta, req.Reason, c.ClientIP())
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "ISO not found")
+		return
+	}
+
+	SuccessResponseWithMessage(c, http.StatusOK, iso, "Download count adjusted")
+}
+
+// GetLogSettings returns the current log level and format.
+func (h *StatsHandlers) GetLogSettings(c *gin.Context) {
+	settings, err := h.statsService.LogSettings()
+	if err != nil {
+		ErrorResponse(c, http.StatusServiceUnavailable, ErrCodeInvalidState, err.Error())
+		return
+	}
+	SuccessResponse(c, http.StatusOK, settings)
+}
+
+// UpdateLogSettings changes the log level and format until the next restart.
+func (h *StatsHandlers) UpdateLogSettings(c *gin.Context) {
+	var req models.LogSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+		return
+	}
+
+	settings, err := h.statsService.SetLogSettings(&req, c.ClientIP())
+	if err != nil {
+		var invalidErr *service.InvalidLogSettingsError
+		switch {
+		case errors.Is(err, service.ErrLogSettingsFixed):
+			ErrorResponse(c, http.StatusServiceUnavailable, ErrCodeInvalidState, err.Error())
+		case errors.As(err, &invalidErr):
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, invalidErr.Error())
+		default:
+			ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to record the log level change")
+		}
+		return
+	}
+
+	SuccessResponseWithMessage(c, http.StatusOK, settings, "Log settings changed")
+}
+
+// ListDownloadEvents returns an ISO's most recent download events.
+func (h *StatsHandlers) ListDownloadEvents(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		limit = 100
+	}
+
+	events, err := h.statsService.ListDownloadEvents(c.Param("id"), limit)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "ISO not found")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, events)
+}
+
+// ListAuditEvents returns the most recent audit events.
+func (h *StatsHandlers) ListAuditEvents(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		limit = 100
+	}
+
+	events, err := h.statsService.ListAuditEvents(limit)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to retrieve audit log")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, events)
+}
+
+// ListSystemEvents returns recorded health state changes, newest first.
+func (h *StatsHandlers) ListSystemEvents(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		limit = 100
+	}
+	params := db.SystemEventsParams{Limit: limit}
+
+	if since := c.Query("since"); since != "" {
+		params.Since, err = time.Parse(time.RFC3339, since)
+		if err != nil {
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid since: must be an RFC 3339 timestamp")
+			return
+		}
+	}
+	if severity := c.Query("severity"); severity != "" {
+		if !models.IsValidSeverity(severity) {
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid severity: must be one of info, warning, error")
+			return
+		}
+		params.Severity = severity
+	}
+
+	events, err := h.statsService.ListSystemEvents(params)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to retrieve system events")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, events)
+}
+
+// ListGCReports returns reports of files deleted by garbage collection, newest first.
+func (h *StatsHandlers) ListGCReports(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		limit = 100
+	}
+	params := db.GCReportsParams{Limit: limit, ISOID: c.Query("iso_id")}
+
+	if since := c.Query("since"); since != "" {
+		params.Since, err = time.Parse(time.RFC3339, since)
+		if err != nil {
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid since: must be an RFC 3339 timestamp")
+			return
+		}
+	}
+	if reason := c.Query("reason"); reason != "" {
+		if !models.IsValidGCReason(reason) {
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid reason: must be one of temp_expired, version_retention")
+			return
+		}
+		params.Reason = reason
+	}
+
+	reports, err := h.statsService.ListGCReports(params)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to retrieve GC reports")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, reports)
+}
+
+// GetGCReport returns a garbage collection report by ID.
+func (h *StatsHandlers) GetGCReport(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid report ID")
+		return
+	}
+
+	report, err := h.statsService.GetGCReport(id)
+	if err != nil {
+		if errors.Is(err, db.ErrGCReportNotFound) {
+			ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "GC report not found")
+			return
+		}
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to retrieve GC report")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, report)
+}
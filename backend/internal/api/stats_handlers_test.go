@@ -2,13 +2,18 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/aloks98/isoman/backend/internal/logger"
 	"github.com/aloks98/isoman/backend/internal/models"
 	"github.com/aloks98/isoman/backend/internal/service"
 	"github.com/aloks98/isoman/backend/internal/testutil"
+	"github.com/aloks98/isoman/backend/internal/throughput"
 
 	"github.com/gin-gonic/gin"
 )
@@ -520,3 +525,436 @@ func TestGetStats_TopDownloaded(t *testing.T) {
 		}
 	}
 }
+
+func TestListMirrors(t *testing.T) {
+	handlers, env := setupStatsHandlers(t)
+	defer env.Cleanup()
+
+	if err := env.DB.RecordMirrorSuccess("mirror.example.com", 50*time.Millisecond, time.Now()); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/api/mirrors", http.NoBody)
+
+	handlers.ListMirrors(c)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got: %d", w.Code)
+	}
+
+	var response struct {
+		Success bool                  `json:"success"`
+		Data    []models.MirrorHealth `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if !response.Success {
+		t.Error("Expected success response")
+	}
+	if len(response.Data) != 1 || response.Data[0].Host != "mirror.example.com" {
+		t.Errorf("Expected one mirror entry, got: %+v", response.Data)
+	}
+	if response.Data[0].SuccessRate != 1 {
+		t.Errorf("Expected success rate 1, got: %f", response.Data[0].SuccessRate)
+	}
+}
+
+func TestAdjustAndResetDownloadStats(t *testing.T) {
+	handlers, env := setupStatsHandlers(t)
+	defer env.Cleanup()
+
+	iso := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Status: models.StatusComplete})
+
+	call := func(handler gin.HandlerFunc, id, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/api/isos/"+id+"/stats", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "id", Value: id}}
+		handler(c)
+		return w
+	}
+
+	if w := call(handlers.AdjustDownloadCount, iso.ID, `{"reason":"no delta"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without delta, got: %d", w.Code)
+	}
+	if w := call(handlers.AdjustDownloadCount, "missing", `{"delta":1}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown ISO, got: %d", w.Code)
+	}
+
+	w := call(handlers.AdjustDownloadCount, iso.ID, `{"delta":7,"reason":"imported from old mirror"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got: %d (%s)", w.Code, w.Body.String())
+	}
+	var response struct {
+		Data models.ISO `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Data.DownloadCount != 7 {
+		t.Errorf("Expected download count 7, got: %d", response.Data.DownloadCount)
+	}
+
+	// Reset works without a body
+	if w := call(handlers.ResetDownloadStats, iso.ID, ""); w.Code != http.StatusOK {
+		t.Errorf("Expected 200 for reset, got: %d (%s)", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/api/audit", http.NoBody)
+	handlers.ListAuditEvents(c)
+
+	var audit struct {
+		Data []models.AuditEvent `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &audit); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(audit.Data) != 2 || audit.Data[1].Reason != "imported from old mirror" {
+		t.Errorf("Expected adjust and reset in the audit log, got: %+v", audit.Data)
+	}
+}
+
+func TestLogSettings(t *testing.T) {
+	handlers, env := setupStatsHandlers(t)
+	defer env.Cleanup()
+
+	call := func(handler gin.HandlerFunc, method, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(method, "/api/admin/log-level", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler(c)
+		return w
+	}
+
+	// Without a controller the level is fixed
+	if w := call(handlers.GetLogSettings, "GET", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without a log controller, got: %d", w.Code)
+	}
+
+	var buf strings.Builder
+	handlers.statsService.SetLogController(logger.NewController(&buf, "info", "text"))
+
+	for _, body := range []string{`{}`, `{"level":"verbose"}`, `{"format":"xml"}`, `not json`} {
+		if w := call(handlers.UpdateLogSettings, "PATCH", body); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got: %d", body, w.Code)
+		}
+	}
+
+	w := call(handlers.UpdateLogSettings, "PATCH", `{"level":"debug","format":"json","reason":"incident 42"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got: %d (%s)", w.Code, w.Body.String())
+	}
+	var response struct {
+		Data models.LogSettings `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Data != (models.LogSettings{Level: "debug", Format: "json"}) {
+		t.Errorf("Expected debug and json, got: %+v", response.Data)
+	}
+
+	w = call(handlers.GetLogSettings, "GET", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Data.Level != "debug" {
+		t.Errorf("Expected GET to report debug, got: %+v", response.Data)
+	}
+
+	events, err := env.DB.ListAuditEvents(10)
+	if err != nil {
+		t.Fatalf("ListAuditEvents() error = %v", err)
+	}
+	if len(events) != 1 || events[0].Action != models.AuditActionLogLevel || events[0].Reason != "incident 42" ||
+		events[0].Details != "level info -> debug, format text -> json" {
+		t.Errorf("Expected the change in the audit log, got: %+v", events)
+	}
+}
+
+func TestGetStats_Params(t *testing.T) {
+	handlers, env := setupStatsHandlers(t)
+	defer env.Cleanup()
+
+	testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Name: "alpine", Status: models.StatusComplete})
+	testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Name: "ubuntu", Status: models.StatusFailed})
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/api/stats?"+query, http.NoBody)
+		handlers.GetStats(c)
+		return w
+	}
+
+	if w := get("group_by=version"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unsupported group_by, got: %d", w.Code)
+	}
+	if w := get("status=bogus"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown status, got: %d", w.Code)
+	}
+
+	w := get("top=25&group_by=name&status=failed")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", w.Code)
+	}
+	var response struct {
+		Data models.Stats `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Data.GroupBy != "name" || len(response.Data.Groups) != 1 || response.Data.Groups[0].Key != "ubuntu" {
+		t.Errorf("Expected failed ISOs grouped by name, got: %+v", response.Data.Groups)
+	}
+}
+
+func TestGetLiveThroughput(t *testing.T) {
+	handlers, env := setupStatsHandlers(t)
+	defer env.Cleanup()
+
+	gauge := throughput.NewGauge()
+	handlers.statsService.SetThroughputGauge(gauge)
+	gauge.Egress.Add(4096)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/api/stats/live", http.NoBody)
+	handlers.GetLiveThroughput(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", w.Code)
+	}
+	var response struct {
+		Data models.LiveThroughput `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Data.EgressBytesTotal != 4096 {
+		t.Errorf("Expected egress total 4096, got: %d", response.Data.EgressBytesTotal)
+	}
+}
+
+func TestListActiveDownloads(t *testing.T) {
+	handlers, env := setupStatsHandlers(t)
+	defer env.Cleanup()
+
+	// Without a download manager the list is empty rather than null
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/api/downloads/active", http.NoBody)
+	handlers.ListActiveDownloads(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"data":[]`) {
+		t.Errorf("Expected an empty list, got: %s", w.Body.String())
+	}
+}
+
+func TestGetQueueForecast(t *testing.T) {
+	handlers, env := setupStatsHandlers(t)
+	defer env.Cleanup()
+
+	// Without a download manager the queue is empty rather than null
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/api/downloads/queue", http.NoBody)
+	handlers.GetQueueForecast(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"queue":[]`) {
+		t.Errorf("Expected an empty queue, got: %s", w.Body.String())
+	}
+}
+
+func TestGetManagerState(t *testing.T) {
+	handlers, env := setupStatsHandlers(t)
+	defer env.Cleanup()
+
+	// Without a download manager the lists are empty rather than null
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/api/downloads/state", http.NoBody)
+	handlers.GetManagerState(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", w.Code)
+	}
+	for _, field := range []string{`"stopped":true`, `"workers":[]`, `"in_flight":[]`, `"active":[]`, `"locks":[]`} {
+		if !strings.Contains(w.Body.String(), field) {
+			t.Errorf("Expected %s in %s", field, w.Body.String())
+		}
+	}
+}
+
+func TestListSystemEvents(t *testing.T) {
+	handlers, env := setupStatsHandlers(t)
+	defer env.Cleanup()
+
+	now := time.Now()
+	for _, e := range []*models.SystemEvent{
+		{Type: models.EventStorageLow, Severity: models.SeverityWarning, CreatedAt: now.Add(-time.Hour)},
+		{Type: models.EventServerStarted, Severity: models.SeverityInfo, CreatedAt: now},
+	} {
+		if err := env.DB.RecordSystemEvent(e); err != nil {
+			t.Fatalf("RecordSystemEvent() failed: %v", err)
+		}
+	}
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/api/system/events?"+query, http.NoBody)
+		handlers.ListSystemEvents(c)
+		return w
+	}
+
+	if w := get("since=yesterday"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid since, got: %d", w.Code)
+	}
+	if w := get("severity=fatal"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid severity, got: %d", w.Code)
+	}
+
+	w := get("severity=warning")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", w.Code)
+	}
+	var response struct {
+		Data []models.SystemEvent `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(response.Data) != 1 || response.Data[0].Type != models.EventStorageLow {
+		t.Errorf("Expected only the storage.low event, got: %+v", response.Data)
+	}
+}
+
+func TestGCReports(t *testing.T) {
+	handlers, env := setupStatsHandlers(t)
+	defer env.Cleanup()
+
+	router := gin.New()
+	router.GET("/api/gc/reports", handlers.ListGCReports)
+	router.GET("/api/gc/reports/:id", handlers.GetGCReport)
+
+	now := time.Now()
+	versions := &models.GCReport{
+		CreatedAt: now,
+		Reason:    models.GCReasonVersionRetention,
+		ISOID:     "iso-1",
+		Files:     []models.GCFile{{Path: "/data/isos/.versions/a.iso.20261001T000000Z", SizeBytes: 700}, {Path: "/data/isos/.versions/a.iso.20261002T000000Z", SizeBytes: 800}},
+	}
+	for _, r := range []*models.GCReport{
+		{CreatedAt: now.Add(-time.Hour), Reason: models.GCReasonTempExpired, Files: []models.GCFile{{Path: "/data/isos/.tmp/b.iso", SizeBytes: 100}}},
+		versions,
+	} {
+		if err := env.DB.CreateGCReport(r); err != nil {
+			t.Fatalf("CreateGCReport() failed: %v", err)
+		}
+	}
+
+	w := doCredentialRequest(router, http.MethodGet, "/api/gc/reports?reason=version_retention", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", w.Code)
+	}
+	var list struct {
+		Data []models.GCReport `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(list.Data) != 1 || list.Data[0].ISOID != "iso-1" || list.Data[0].FileCount != 2 || list.Data[0].BytesReclaimed != 1500 {
+		t.Errorf("Expected only the version retention report, got: %+v", list.Data)
+	}
+
+	w = doCredentialRequest(router, http.MethodGet, fmt.Sprintf("/api/gc/reports/%d", versions.ID), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", w.Code)
+	}
+	var one struct {
+		Data models.GCReport `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &one); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(one.Data.Files) != 2 || one.Data.Files[1].Path != versions.Files[1].Path {
+		t.Errorf("Expected the report's files, got: %+v", one.Data)
+	}
+
+	for query, want := range map[string]int{
+		"/api/gc/reports?reason=eviction": http.StatusBadRequest,
+		"/api/gc/reports?since=yesterday": http.StatusBadRequest,
+		"/api/gc/reports/abc":             http.StatusBadRequest,
+		"/api/gc/reports/999":             http.StatusNotFound,
+	} {
+		if w := doCredentialRequest(router, http.MethodGet, query, ""); w.Code != want {
+			t.Errorf("GET %s: expected status %d, got: %d", query, want, w.Code)
+		}
+	}
+}
+
+func TestGetStatus(t *testing.T) {
+	handlers, env := setupStatsHandlers(t)
+	defer env.Cleanup()
+
+	testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Status: models.StatusComplete})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/status", http.NoBody)
+	handlers.GetStatus(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", w.Code)
+	}
+	var response struct {
+		Data models.InstanceStatus `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Data.Status != models.InstanceStatusOK || response.Data.CompleteISOs != 1 {
+		t.Errorf("Expected ok with 1 complete ISO, got: %+v", response.Data)
+	}
+}
+
+func TestGetStatusBadge(t *testing.T) {
+	handlers, env := setupStatsHandlers(t)
+	defer env.Cleanup()
+
+	badge := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/status/badge.svg", http.NoBody)
+		handlers.GetStatusBadge(c)
+		return w
+	}
+
+	w := badge()
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "image/svg+xml") {
+		t.Fatalf("Expected an SVG, got: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if body := w.Body.String(); !strings.Contains(body, "ok, 0 ISOs") || !strings.Contains(body, badgeGreen) {
+		t.Errorf("Expected a green ok badge, got:\n%s", body)
+	}
+
+	env.DB.Close()
+	if body := badge().Body.String(); !strings.Contains(body, ">down<") || !strings.Contains(body, badgeRed) {
+		t.Errorf("Expected a red down badge with the database closed, got:\n%s", body)
+	}
+}
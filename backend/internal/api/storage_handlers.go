@@ -0,0 +1,61 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StorageHandlers holds a reference to the storage service.
+type StorageHandlers struct {
+	storageService *service.StorageService
+}
+
+// NewStorageHandlers creates a new StorageHandlers instance.
+func NewStorageHandlers(storageService *service.StorageService) *StorageHandlers {
+	return &StorageHandlers{
+		storageService: storageService,
+	}
+}
+
+// GetRelocation returns the progress of the last or running storage relocation.
+func (h *StorageHandlers) GetRelocation(c *gin.Context) {
+	rel := h.storageService.Relocation()
+	if rel == nil {
+		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "No storage relocation has run")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, rel)
+}
+
+// StartRelocation starts copying the ISO directory to a new location. The
+// server keeps serving from the current one until it is restarted with
+// ISO_DIR set to the target.
+func (h *StorageHandlers) StartRelocation(c *gin.Context) {
+	var req models.RelocateStorageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+		return
+	}
+
+	rel, err := h.storageService.StartRelocation(req.Target)
+	if err != nil {
+		var invalidErr *service.InvalidRelocationError
+		switch {
+		case errors.Is(err, service.ErrRelocationRunning):
+			ErrorResponse(c, http.StatusConflict, ErrCodeConflict, err.Error())
+		case errors.As(err, &invalidErr):
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, invalidErr.Error())
+		default:
+			ErrorResponseWithDetails(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to start relocation", err.Error())
+		}
+		return
+	}
+
+	SuccessResponseWithMessage(c, http.StatusAccepted, rel, "Relocation started; restart with ISO_DIR set to the target and STORAGE_RELOCATE=copy or move to switch over")
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStorageHandlers(t *testing.T) {
+	isoDir := filepath.Join(t.TempDir(), "isos")
+	storageHandlers := NewStorageHandlers(service.NewStorageService(isoDir))
+
+	router := gin.New()
+	router.GET("/api/storage/relocation", storageHandlers.GetRelocation)
+	router.POST("/api/storage/relocation", storageHandlers.StartRelocation)
+
+	if w := doCredentialRequest(router, http.MethodGet, "/api/storage/relocation", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 before any relocation, got: %d", w.Code)
+	}
+	if w := doCredentialRequest(router, http.MethodPost, "/api/storage/relocation", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without a target, got: %d", w.Code)
+	}
+	body := `{"target":"` + filepath.ToSlash(filepath.Join(isoDir, "nested")) + `"}`
+	if w := doCredentialRequest(router, http.MethodPost, "/api/storage/relocation", body); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a target inside the ISO directory, got: %d", w.Code)
+	}
+
+	body = `{"target":"` + filepath.ToSlash(filepath.Join(t.TempDir(), "bulk")) + `"}`
+	if w := doCredentialRequest(router, http.MethodPost, "/api/storage/relocation", body); w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got: %d (%s)", w.Code, w.Body.String())
+	}
+	if w := doCredentialRequest(router, http.MethodGet, "/api/storage/relocation", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got: %d", w.Code)
+	}
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/pathutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// inProgressPollInterval is how often a streamed download is checked for new
+// bytes and for the end of the download.
+const inProgressPollInterval = 200 * time.Millisecond
+
+// inProgressISO returns the ISO stored at filePath if it is still being
+// downloaded, or nil.
+func inProgressISO(database *db.DB, filePath string) *models.ISO {
+	iso, err := database.GetISOByFilePath(filePath)
+	if err != nil || iso == nil || !iso.Status.IsActive() {
+		return nil
+	}
+	return iso
+}
+
+// inProgressStream follows the file of an ISO being downloaded as it grows in
+// the temp directory and then moves into place.
+type inProgressStream struct {
+	database  *db.DB
+	isoID     string
+	tmpPath   string
+	finalPath string
+	file      *os.File
+	size      int64 // Content-Length sent to the client
+	complete  bool  // The file is verified and in place
+}
+
+// openInProgress waits until the file of iso exists and its size is known, so
+// the response can carry a Content-Length. A mirror that sends no length is
+// waited out until the download completes. It returns nil once the download
+// fails or ctx is done.
+func openInProgress(ctx context.Context, cfg *DirectoryHandlerConfig, iso *models.ISO) *inProgressStream {
+	s := &inProgressStream{
+		database:  cfg.DB,
+		isoID:     iso.ID,
+		tmpPath:   pathutil.ConstructTempPath(cfg.TempDir, iso.Filename),
+		finalPath: pathutil.ConstructISOPath(cfg.ISODir, iso.FilePath),
+	}
+
+	ticker := time.NewTicker(inProgressPollInterval)
+	defer ticker.Stop()
+	for {
+		current, ok := s.refresh()
+		if !ok {
+			s.close()
+			return nil
+		}
+		if s.file != nil && s.complete {
+			if info, err := s.file.Stat(); err == nil {
+				s.size = info.Size()
+				return s
+			}
+		}
+		if s.file != nil && current.SizeBytes > 0 {
+			s.size = current.SizeBytes
+			return s
+		}
+
+		select {
+		case <-ctx.Done():
+			s.close()
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh reloads the ISO and reopens its file if the download moved it or
+// started over. It reports false once the download ended without a file.
+func (s *inProgressStream) refresh() (*models.ISO, bool) {
+	iso, err := s.database.GetISO(s.isoID)
+	if err != nil {
+		return nil, false // Deleted while it downloaded
+	}
+	switch {
+	case iso.Status == models.StatusComplete:
+		s.complete = true
+	case !iso.Status.IsActive():
+		return iso, false
+	}
+
+	filePath := s.tmpPath
+	if s.complete {
+		filePath = s.finalPath
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return iso, true // Not started yet, or between attempts
+	}
+	if s.file != nil {
+		if open, err := s.file.Stat(); err == nil && os.SameFile(open, info) {
+			return iso, true
+		}
+		s.close()
+	}
+	if s.file, err = os.Open(filePath); err != nil {
+		s.file = nil
+	}
+	return iso, true
+}
+
+// serve sends the file as it is downloaded, holding at the bytes fetched so
+// far. The last byte is held until the file is verified and in place, and a
+// download that fails ends the response short of its Content-Length, which
+// drops the connection, so a client never mistakes a partial or rejected file
+// for a complete one.
+func (s *inProgressStream) serve(c *gin.Context) {
+	defer s.close()
+
+	contentType := mime.TypeByExtension(filepath.Ext(s.finalPath))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Length", strconv.FormatInt(s.size, 10))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+	if c.Request.Method == http.MethodHead {
+		return
+	}
+
+	ctx := c.Request.Context()
+	ticker := time.NewTicker(inProgressPollInterval)
+	defer ticker.Stop()
+	buf := make([]byte, 32*1024)
+	var offset int64
+	for offset < s.size {
+		limit := s.size
+		if !s.complete {
+			limit--
+		}
+		if s.file != nil && offset < limit {
+			n, err := s.file.ReadAt(buf[:min(int64(len(buf)), limit-offset)], offset)
+			if n > 0 {
+				if _, writeErr := c.Writer.Write(buf[:n]); writeErr != nil {
+					return
+				}
+				offset += int64(n)
+			}
+			if err == nil {
+				continue
+			}
+			if err != io.EOF || s.complete {
+				slog.ErrorContext(ctx, "failed to read downloading file", slog.String("iso_id", s.isoID), slog.Any("error", err))
+				return
+			}
+		}
+		c.Writer.Flush()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, ok := s.refresh(); !ok {
+			slog.WarnContext(ctx, "download ended before its file was streamed",
+				slog.String("iso_id", s.isoID),
+				slog.Int64("sent", offset),
+				slog.Int64("size", s.size),
+			)
+			return
+		}
+	}
+}
+
+func (s *inProgressStream) close() {
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+}
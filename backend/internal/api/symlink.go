@@ -0,0 +1,51 @@
+package api
+
+import (
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+)
+
+// symlinkPolicyOrDefault returns policy when valid, falling back to the default.
+func symlinkPolicyOrDefault(policy string) string {
+	if constants.IsValidSymlinkPolicy(policy) {
+		return strings.ToLower(policy)
+	}
+	if policy != "" {
+		slog.Warn("unknown symlink policy, using default", slog.String("policy", policy), slog.String("default", constants.DefaultSymlinkPolicy))
+	}
+	return constants.DefaultSymlinkPolicy
+}
+
+// resolveSymlinks applies the symlink policy to fullPath, a path already known
+// to be lexically inside isoDir. It returns the real path to serve and its
+// slash-separated path relative to the real ISO dir, or false when the path
+// goes through a symlink the policy doesn't allow or doesn't exist.
+func resolveSymlinks(isoDir, fullPath, policy string) (string, string, bool) {
+	// The ISO dir itself may live behind a symlink (e.g. /data -> /mnt/data)
+	realRoot, err := filepath.EvalSymlinks(isoDir)
+	if err != nil {
+		return "", "", false
+	}
+	realPath, err := filepath.EvalSymlinks(fullPath)
+	if err != nil {
+		return "", "", false
+	}
+
+	rel, err := filepath.Rel(realRoot, realPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", "", false
+	}
+
+	// Without symlinks the path resolves to itself relative to the real root
+	if policy == constants.SymlinkPolicyDeny {
+		lexicalRel, err := filepath.Rel(filepath.Clean(isoDir), fullPath)
+		if err != nil || lexicalRel != rel {
+			return "", "", false
+		}
+	}
+
+	return realPath, filepath.ToSlash(rel), true
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupSymlinkTree creates an ISO dir with links inside and outside of it.
+func setupSymlinkTree(t *testing.T) string {
+	t.Helper()
+
+	root := t.TempDir()
+	isoDir := filepath.Join(root, "isos")
+	os.MkdirAll(filepath.Join(isoDir, "alpine", "3.19.1"), 0o755)
+	os.MkdirAll(filepath.Join(isoDir, ".tmp"), 0o755)
+	os.WriteFile(filepath.Join(isoDir, "alpine", "3.19.1", "alpine.iso"), []byte("alpine"), 0o644)
+	os.WriteFile(filepath.Join(isoDir, ".tmp", "partial.iso"), []byte("partial"), 0o644)
+	os.WriteFile(filepath.Join(root, "secret.txt"), []byte("secret"), 0o644)
+
+	links := map[string]string{
+		"alpine/latest":     "3.19.1",
+		"alpine/latest.iso": "3.19.1/alpine.iso",
+		"escape.txt":        "../secret.txt",
+		"partial.iso":       ".tmp/partial.iso",
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, filepath.Join(isoDir, filepath.FromSlash(link))); err != nil {
+			t.Skipf("Symlinks not supported: %v", err)
+		}
+	}
+	return isoDir
+}
+
+func serveImages(handler gin.HandlerFunc, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/images"+path, http.NoBody)
+	c.Params = gin.Params{{Key: "filepath", Value: path}}
+	handler(c)
+	return w
+}
+
+func TestDirectoryHandlerSymlinkWithin(t *testing.T) {
+	isoDir := setupSymlinkTree(t)
+	handler := DirectoryHandler(&DirectoryHandlerConfig{ISODir: isoDir, SymlinkPolicy: constants.SymlinkPolicyWithin})
+
+	if w := serveImages(handler, "/alpine/latest.iso"); w.Code != http.StatusOK || w.Body.String() != "alpine" {
+		t.Errorf("Expected link inside the root to be served, got: %d %q", w.Code, w.Body.String())
+	}
+	if w := serveImages(handler, "/alpine/latest/alpine.iso"); w.Code != http.StatusOK {
+		t.Errorf("Expected file under a linked directory to be served, got: %d", w.Code)
+	}
+	if w := serveImages(handler, "/escape.txt"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected link escaping the root to return 404, got: %d", w.Code)
+	}
+	if w := serveImages(handler, "/partial.iso"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected link into a hidden directory to return 404, got: %d", w.Code)
+	}
+
+	root := serveImages(handler, "/").Body.String()
+	if strings.Contains(root, "escape.txt") || strings.Contains(root, "partial.iso") {
+		t.Error("Links the policy won't serve should not be listed")
+	}
+	listing := serveImages(handler, "/alpine/").Body.String()
+	if !strings.Contains(listing, "latest.iso") || !strings.Contains(listing, "/images/alpine/latest") {
+		t.Error("Links inside the root should be listed")
+	}
+}
+
+func TestDirectoryHandlerSymlinkDeny(t *testing.T) {
+	isoDir := setupSymlinkTree(t)
+	handler := DirectoryHandler(&DirectoryHandlerConfig{ISODir: isoDir, SymlinkPolicy: constants.SymlinkPolicyDeny})
+
+	for _, path := range []string{"/alpine/latest.iso", "/alpine/latest/alpine.iso", "/escape.txt"} {
+		if w := serveImages(handler, path); w.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for %s, got: %d", path, w.Code)
+		}
+	}
+	if w := serveImages(handler, "/alpine/3.19.1/alpine.iso"); w.Code != http.StatusOK {
+		t.Errorf("Expected regular file to be served, got: %d", w.Code)
+	}
+	if strings.Contains(serveImages(handler, "/alpine/").Body.String(), "latest") {
+		t.Error("Symlinks should not be listed when denied")
+	}
+}
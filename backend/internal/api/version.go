@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VersionHandler serves GET /api/version: the build version and where the
+// database schema stands against it. A database ahead of the build (another
+// replica already upgraded it) reports compatible=false with nothing pending.
+func VersionHandler(version string, database *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		schema, err := schemaInfo(database)
+		if err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to read schema version")
+			return
+		}
+		SuccessResponse(c, http.StatusOK, models.VersionInfo{Version: version, Schema: *schema})
+	}
+}
+
+// schemaInfo reads the schema status of database for the API.
+func schemaInfo(database *db.DB) (*models.SchemaInfo, error) {
+	status, err := database.SchemaStatus()
+	if err != nil {
+		return nil, err
+	}
+	info := &models.SchemaInfo{
+		Version:    status.Version,
+		Latest:     status.Latest,
+		Dirty:      status.Dirty,
+		Compatible: !status.Dirty && status.Version == status.Latest,
+	}
+	if status.Version < status.Latest {
+		info.Pending = status.Latest - status.Version
+	}
+	return info, nil
+}
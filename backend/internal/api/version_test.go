@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/download"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/service"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+	"github.com/aloks98/isoman/backend/internal/ws"
+)
+
+func TestVersionEndpoint(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	env.Config.Version = "1.4.0"
+	env.Config.Auth.Enabled = true
+
+	manager := download.NewManager(env.DB, env.ISODir, 1)
+	defer manager.Stop()
+	isoService := service.NewISOService(env.DB, manager, env.ISODir)
+	router := setupTestRouter(env, isoService, ws.NewHub())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/version", http.NoBody))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 without a session, got: %d (%s)", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Success bool               `json:"success"`
+		Data    models.VersionInfo `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	info := resp.Data
+	if info.Version != "1.4.0" {
+		t.Errorf("Expected version 1.4.0, got %q", info.Version)
+	}
+	if info.Schema.Latest == 0 || info.Schema.Version != info.Schema.Latest {
+		t.Errorf("Expected a fully migrated schema, got %+v", info.Schema)
+	}
+	if info.Schema.Dirty || info.Schema.Pending != 0 || !info.Schema.Compatible {
+		t.Errorf("Expected a compatible schema with nothing pending, got %+v", info.Schema)
+	}
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/service"
+	"github.com/aloks98/isoman/backend/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Headers of inbound webhook deliveries, as GitHub names them.
+const (
+	WebhookSignatureHeader = "X-Hub-Signature-256"
+	WebhookEventHeader     = "X-GitHub-Event"
+)
+
+// WebhookHandlers holds references to the webhook service and the ISO
+// handlers that queue the downloads deliveries describe.
+type WebhookHandlers struct {
+	webhookService *service.WebhookService
+	isoHandlers    *Handlers
+}
+
+// NewWebhookHandlers creates a new WebhookHandlers instance.
+func NewWebhookHandlers(webhookService *service.WebhookService, isoHandlers *Handlers) *WebhookHandlers {
+	return &WebhookHandlers{
+		webhookService: webhookService,
+		isoHandlers:    isoHandlers,
+	}
+}
+
+// ListWebhooks returns all webhooks without their secrets.
+func (h *WebhookHandlers) ListWebhooks(c *gin.Context) {
+	hooks, err := h.webhookService.ListWebhooks()
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, "Failed to retrieve webhooks")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, hooks)
+}
+
+// GetWebhook returns a single webhook without its secret.
+func (h *WebhookHandlers) GetWebhook(c *gin.Context) {
+	hook, err := h.webhookService.GetWebhook(c.Param("name"))
+	if err != nil {
+		webhookErrorResponse(c, err, "Failed to retrieve webhook")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, hook)
+}
+
+// CreateWebhook stores a new webhook. The response carries the generated
+// signing secret, which is not shown again.
+func (h *WebhookHandlers) CreateWebhook(c *gin.Context) {
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+		return
+	}
+
+	hook, err := h.webhookService.CreateWebhook(req)
+	if err != nil {
+		webhookErrorResponse(c, err, "Failed to create webhook")
+		return
+	}
+
+	SuccessResponseWithMessage(c, http.StatusCreated, hook, "Webhook created")
+}
+
+// UpdateWebhook replaces the template or filter of a webhook, or rotates its secret.
+func (h *WebhookHandlers) UpdateWebhook(c *gin.Context) {
+	var req models.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeValidationFailed, "Invalid request body", err.Error())
+		return
+	}
+
+	hook, err := h.webhookService.UpdateWebhook(c.Param("name"), req)
+	if err != nil {
+		webhookErrorResponse(c, err, "Failed to update webhook")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, hook)
+}
+
+// DeleteWebhook removes a webhook.
+func (h *WebhookHandlers) DeleteWebhook(c *gin.Context) {
+	if err := h.webhookService.DeleteWebhook(c.Param("name")); err != nil {
+		webhookErrorResponse(c, err, "Failed to delete webhook")
+		return
+	}
+
+	NoContentResponse(c)
+}
+
+// ReceiveWebhook handles a delivery to /api/hooks/:name. It is authenticated
+// by the signature of the body rather than a session, and queues the ISO the
+// webhook's templates render from the payload.
+func (h *WebhookHandlers) ReceiveWebhook(c *gin.Context) {
+	hook, err := h.webhookService.GetWebhook(c.Param("name"))
+	if err != nil {
+		webhookErrorResponse(c, err, "Failed to retrieve webhook")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, constants.MaxWebhookPayloadBytes))
+	if err != nil {
+		ErrorResponse(c, http.StatusRequestEntityTooLarge, ErrCodePayloadTooLarge, "Payload is too large")
+		return
+	}
+	if err := h.webhookService.Verify(hook, body, c.GetHeader(WebhookSignatureHeader)); err != nil {
+		webhookErrorResponse(c, err, "Failed to verify delivery")
+		return
+	}
+
+	// GitHub sends a ping when the webhook is added
+	if c.GetHeader(WebhookEventHeader) == "ping" {
+		SuccessResponseWithMessage(c, http.StatusOK, nil, "pong")
+		return
+	}
+
+	req, err := h.webhookService.Render(hook, body)
+	if errors.Is(err, service.ErrWebhookFiltered) {
+		SuccessResponseWithMessage(c, http.StatusAccepted, nil, "Delivery ignored by the webhook filter")
+		return
+	}
+	if err != nil {
+		webhookErrorResponse(c, err, "Failed to render delivery")
+		return
+	}
+
+	if iso := h.isoHandlers.createISO(c, validation.ISOCreateRequest(*req)); iso != nil {
+		h.webhookService.RecordDelivery(hook, iso, c.ClientIP())
+	}
+}
+
+// webhookErrorResponse maps webhook service errors to responses.
+func webhookErrorResponse(c *gin.Context, err error, fallback string) {
+	var invalidErr *service.InvalidWebhookError
+	var existsErr *service.WebhookExistsError
+
+	switch {
+	case errors.Is(err, db.ErrWebhookNotFound):
+		ErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "Webhook not found")
+	case errors.Is(err, service.ErrWebhooksDisabled):
+		ErrorResponse(c, http.StatusServiceUnavailable, ErrCodeCredentialsOff, err.Error())
+	case errors.Is(err, service.ErrWebhookSignature):
+		ErrorResponse(c, http.StatusUnauthorized, ErrCodeUnauthorized, err.Error())
+	case errors.As(err, &invalidErr):
+		ErrorResponse(c, http.StatusBadRequest, ErrCodeValidationFailed, invalidErr.Error())
+	case errors.As(err, &existsErr):
+		ErrorResponse(c, http.StatusConflict, ErrCodeConflict, existsErr.Error())
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, fallback)
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/secrets"
+	"github.com/aloks98/isoman/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testReleaseDelivery = `{"action":"published","release":{"tag_name":"v3.19.1"}}`
+
+func signDelivery(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func doDelivery(router *gin.Engine, event, signature, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/hooks/alpine", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, event)
+	req.Header.Set(WebhookSignatureHeader, signature)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestWebhookHandlers(t *testing.T) {
+	handlers, database, _, _, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	box, err := secrets.NewBox(bytes.Repeat([]byte{1}, secrets.KeySize))
+	if err != nil {
+		t.Fatalf("NewBox() failed: %v", err)
+	}
+	webhookHandlers := NewWebhookHandlers(service.NewWebhookService(database, service.NewCredentialService(database, box)), handlers)
+
+	router := gin.New()
+	router.POST("/api/hooks", webhookHandlers.CreateWebhook)
+	router.GET("/api/hooks", webhookHandlers.ListWebhooks)
+	router.POST("/api/hooks/:name", webhookHandlers.ReceiveWebhook)
+
+	w := doCredentialRequest(router, http.MethodPost, "/api/hooks", `{
+		"name": "alpine",
+		"filter": "{{ eq .action \"published\" }}",
+		"template": {
+			"name": "alpine",
+			"version": "{{ .release.tag_name | trimPrefix \"v\" }}",
+			"arch": "x86_64",
+			"download_url": "https://dl.example.com/alpine-{{ .release.tag_name | trimPrefix \"v\" }}-x86_64.iso"
+		}
+	}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", w.Code, w.Body.String())
+	}
+	var created struct {
+		Data models.Webhook `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	secret := created.Data.NewSecret
+	if secret == "" {
+		t.Fatalf("Expected the secret in the creation response: %s", w.Body.String())
+	}
+
+	w = doCredentialRequest(router, http.MethodGet, "/api/hooks", "")
+	if strings.Contains(w.Body.String(), secret) || strings.Contains(w.Body.String(), "v1:") {
+		t.Errorf("Listing leaks the secret: %s", w.Body.String())
+	}
+
+	if w := doDelivery(router, "release", signDelivery("wrong", testReleaseDelivery), testReleaseDelivery); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for a bad signature, got: %d", w.Code)
+	}
+	if w := doDelivery(router, "ping", signDelivery(secret, `{"zen":"hi"}`), `{"zen":"hi"}`); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for a ping, got: %d (%s)", w.Code, w.Body.String())
+	}
+	draft := strings.Replace(testReleaseDelivery, "published", "created", 1)
+	if w := doDelivery(router, "release", signDelivery(secret, draft), draft); w.Code != http.StatusAccepted {
+		t.Errorf("Expected status 202 for a filtered delivery, got: %d (%s)", w.Code, w.Body.String())
+	}
+
+	w = doDelivery(router, "release", signDelivery(secret, testReleaseDelivery), testReleaseDelivery)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", w.Code, w.Body.String())
+	}
+	iso := parseAPIResponse(t, w.Body.Bytes()).Data.(map[string]any)
+	if iso["version"] != "3.19.1" || iso["download_url"] != "https://dl.example.com/alpine-3.19.1-x86_64.iso" {
+		t.Errorf("Unexpected ISO from delivery: %v", iso)
+	}
+	events, _ := database.ListAuditEvents(10)
+	if len(events) != 1 || events[0].Action != models.AuditActionWebhook {
+		t.Errorf("Expected one webhook audit event, got %+v", events)
+	}
+
+	if w := doDelivery(router, "release", signDelivery(secret, testReleaseDelivery), testReleaseDelivery); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for a redelivery, got: %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/hooks/missing", strings.NewReader("{}"))
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown webhook, got: %d", w.Code)
+	}
+}
@@ -0,0 +1,171 @@
+// Package clamav scans files with a clamd daemon using its INSTREAM command.
+package clamav
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// chunkSize is the amount of file data sent per INSTREAM chunk.
+const chunkSize = 64 * 1024
+
+// Result is the verdict for a scanned file.
+type Result struct {
+	Signature string // Name of the detected signature, empty when clean
+	Infected  bool
+}
+
+// Scanner sends files to clamd for scanning.
+type Scanner struct {
+	address string
+	timeout time.Duration
+}
+
+// New creates a Scanner for a clamd address such as "unix:///run/clamav/clamd.ctl"
+// or "tcp://127.0.0.1:3310". timeout bounds each read or write on the connection,
+// not the whole scan. The address is parsed on use, so a bad address fails scans
+// rather than silently disabling them.
+func New(address string, timeout time.Duration) *Scanner {
+	return &Scanner{address: address, timeout: timeout}
+}
+
+// Ping checks that clamd is reachable and responding.
+func (s *Scanner) Ping(ctx context.Context) error {
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := s.write(conn, []byte("zPING\x00")); err != nil {
+		return err
+	}
+	reply, err := s.readReply(conn)
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("unexpected reply from clamd: %q", reply)
+	}
+	return nil
+}
+
+// ScanFile streams the file at path to clamd and returns its verdict.
+func (s *Scanner) ScanFile(ctx context.Context, path string) (*Result, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := s.write(conn, []byte("zINSTREAM\x00")); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 4+chunkSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		n, readErr := file.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if err := s.write(conn, buf[:4+n]); err != nil {
+				return nil, err
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read file: %w", readErr)
+		}
+	}
+
+	// A zero-length chunk ends the stream
+	if err := s.write(conn, []byte{0, 0, 0, 0}); err != nil {
+		return nil, err
+	}
+
+	reply, err := s.readReply(conn)
+	if err != nil {
+		return nil, err
+	}
+	return parseReply(reply)
+}
+
+// parseReply interprets an INSTREAM reply such as "stream: OK" or
+// "stream: Eicar-Signature FOUND".
+func parseReply(reply string) (*Result, error) {
+	status := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case status == "OK":
+		return &Result{}, nil
+	case strings.HasSuffix(status, " FOUND"):
+		return &Result{Infected: true, Signature: strings.TrimSuffix(status, " FOUND")}, nil
+	}
+	return nil, fmt.Errorf("clamd error: %s", strings.TrimSuffix(reply, " ERROR"))
+}
+
+// dial connects to the configured clamd address.
+func (s *Scanner) dial(ctx context.Context) (net.Conn, error) {
+	u, err := url.Parse(s.address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid clamd address %q: %w", s.address, err)
+	}
+
+	var network, addr string
+	switch u.Scheme {
+	case "unix":
+		network, addr = "unix", u.Path
+	case "tcp":
+		network, addr = "tcp", u.Host
+	default:
+		return nil, fmt.Errorf("invalid clamd address %q: scheme must be unix or tcp", s.address)
+	}
+
+	dialer := net.Dialer{Timeout: s.timeout}
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	return conn, nil
+}
+
+// write sends p to clamd, bounded by the I/O timeout.
+func (s *Scanner) write(conn net.Conn, p []byte) error {
+	if s.timeout > 0 {
+		_ = conn.SetWriteDeadline(time.Now().Add(s.timeout)) //nolint:errcheck // A failed deadline surfaces as a write error
+	}
+	if _, err := conn.Write(p); err != nil {
+		return fmt.Errorf("failed to send to clamd: %w", err)
+	}
+	return nil
+}
+
+// readReply reads a NUL-terminated reply from clamd.
+func (s *Scanner) readReply(conn net.Conn) (string, error) {
+	if s.timeout > 0 {
+		_ = conn.SetReadDeadline(time.Now().Add(s.timeout)) //nolint:errcheck // A failed deadline surfaces as a read error
+	}
+	reply, err := bufio.NewReader(io.LimitReader(conn, 4096)).ReadString(0)
+	if err != nil && (err != io.EOF || reply == "") {
+		return "", fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return strings.TrimSpace(strings.TrimSuffix(reply, "\x00")), nil
+}
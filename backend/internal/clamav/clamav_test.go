@@ -0,0 +1,149 @@
+package clamav
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startFakeClamd serves PING and INSTREAM like clamd, flagging streams that contain "EICAR".
+func startFakeClamd(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				cmd, err := r.ReadString(0)
+				if err != nil {
+					return
+				}
+
+				switch cmd {
+				case "zPING\x00":
+					conn.Write([]byte("PONG\x00"))
+				case "zINSTREAM\x00":
+					var data bytes.Buffer
+					for {
+						var size uint32
+						if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+							return
+						}
+						if size == 0 {
+							break
+						}
+						if _, err := io.CopyN(&data, r, int64(size)); err != nil {
+							return
+						}
+					}
+					if strings.Contains(data.String(), "EICAR") {
+						conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+					} else {
+						conn.Write([]byte("stream: OK\x00"))
+					}
+				default:
+					conn.Write([]byte("UNKNOWN COMMAND\x00"))
+				}
+			}(conn)
+		}
+	}()
+
+	return "tcp://" + ln.Addr().String()
+}
+
+func writeTestFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.iso")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	return path
+}
+
+func TestScanFile(t *testing.T) {
+	scanner := New(startFakeClamd(t), 5*time.Second)
+
+	if err := scanner.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+
+	// Larger than one chunk so the stream is split
+	clean := writeTestFile(t, strings.Repeat("a", chunkSize+100))
+	result, err := scanner.ScanFile(context.Background(), clean)
+	if err != nil {
+		t.Fatalf("ScanFile() error = %v", err)
+	}
+	if result.Infected {
+		t.Errorf("Clean file should not be flagged, got: %+v", result)
+	}
+
+	infected := writeTestFile(t, "X5O!P%@AP EICAR test")
+	result, err = scanner.ScanFile(context.Background(), infected)
+	if err != nil {
+		t.Fatalf("ScanFile() error = %v", err)
+	}
+	if !result.Infected || result.Signature != "Eicar-Test-Signature" {
+		t.Errorf("Expected Eicar-Test-Signature, got: %+v", result)
+	}
+}
+
+func TestScanFileErrors(t *testing.T) {
+	path := writeTestFile(t, "data")
+
+	if _, err := New("http://localhost:3310", time.Second).ScanFile(context.Background(), path); err == nil {
+		t.Error("Expected error for unsupported address scheme")
+	}
+
+	// Nothing listens on a closed listener's port
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := "tcp://" + ln.Addr().String()
+	ln.Close()
+	if _, err := New(addr, time.Second).ScanFile(context.Background(), path); err == nil {
+		t.Error("Expected error when clamd is unreachable")
+	}
+}
+
+func TestParseReply(t *testing.T) {
+	tests := []struct {
+		reply     string
+		infected  bool
+		signature string
+		wantErr   bool
+	}{
+		{reply: "stream: OK"},
+		{reply: "stream: Win.Test.EICAR_HDB-1 FOUND", infected: true, signature: "Win.Test.EICAR_HDB-1"},
+		{reply: "INSTREAM size limit exceeded. ERROR", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reply, func(t *testing.T) {
+			result, err := parseReply(tt.reply)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseReply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (result.Infected != tt.infected || result.Signature != tt.signature) {
+				t.Errorf("parseReply() = %+v", result)
+			}
+		})
+	}
+}
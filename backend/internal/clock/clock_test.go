@@ -0,0 +1,108 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 1, 26, 14, 45, 0, 0, time.UTC)
+
+func TestFakeTimers(t *testing.T) {
+	clock := NewFake(start)
+	early := clock.NewTimer(time.Minute)
+	late := clock.NewTimer(time.Hour)
+	stopped := clock.NewTimer(time.Minute)
+	if !stopped.Stop() {
+		t.Error("Stop() should report a pending timer as stopped")
+	}
+
+	clock.Advance(59 * time.Second)
+	select {
+	case <-early.C():
+		t.Fatal("Timer fired before its deadline")
+	default:
+	}
+
+	clock.Advance(time.Second)
+	select {
+	case at := <-early.C():
+		if !at.Equal(start.Add(time.Minute)) {
+			t.Errorf("Timer fired at %v, expected %v", at, start.Add(time.Minute))
+		}
+	default:
+		t.Fatal("Timer didn't fire at its deadline")
+	}
+	select {
+	case <-stopped.C():
+		t.Error("Stopped timer fired")
+	case <-late.C():
+		t.Error("Later timer fired early")
+	default:
+	}
+	if early.Stop() {
+		t.Error("Stop() should report a fired timer as already stopped")
+	}
+	if got := clock.Since(start); got != time.Minute {
+		t.Errorf("Since(start) = %v, expected 1m", got)
+	}
+}
+
+func TestFakeTicker(t *testing.T) {
+	clock := NewFake(start)
+	ticker := clock.NewTicker(time.Minute)
+
+	clock.Advance(time.Minute)
+	if at := <-ticker.C(); !at.Equal(start.Add(time.Minute)) {
+		t.Errorf("First tick at %v, expected %v", at, start.Add(time.Minute))
+	}
+
+	// Ticks the reader isn't ready for are dropped, not queued
+	clock.Advance(3 * time.Minute)
+	if at := <-ticker.C(); !at.Equal(start.Add(2 * time.Minute)) {
+		t.Errorf("Buffered tick at %v, expected %v", at, start.Add(2*time.Minute))
+	}
+	select {
+	case <-ticker.C():
+		t.Error("Missed ticks should be dropped")
+	default:
+	}
+
+	ticker.Stop()
+	clock.Advance(time.Hour)
+	select {
+	case <-ticker.C():
+		t.Error("Stopped ticker ticked")
+	default:
+	}
+}
+
+func TestFakeAfterFuncRunsInOrder(t *testing.T) {
+	clock := NewFake(start)
+	var order []string
+	clock.AfterFunc(2*time.Minute, func() { order = append(order, "second") })
+	clock.AfterFunc(time.Minute, func() {
+		order = append(order, "first")
+		// Scheduled while advancing and still due before it ends
+		clock.AfterFunc(30*time.Second, func() { order = append(order, "nested") })
+	})
+
+	clock.Advance(5 * time.Minute)
+	if got := len(order); got != 3 || order[0] != "first" || order[1] != "nested" || order[2] != "second" {
+		t.Errorf("AfterFunc order = %v, expected [first nested second]", order)
+	}
+}
+
+func TestFakeSleep(t *testing.T) {
+	clock := NewFake(start)
+	woke := make(chan time.Time)
+	go func() {
+		clock.Sleep(time.Hour)
+		woke <- clock.Now()
+	}()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+	if at := <-woke; !at.Equal(start.Add(time.Hour)) {
+		t.Errorf("Sleep woke at %v, expected %v", at, start.Add(time.Hour))
+	}
+}
@@ -0,0 +1,185 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake is a Clock that stands still until Advance moves it. Timers, tickers,
+// and sleeps fire as Advance passes their deadlines, in deadline order, so
+// code that schedules work can be tested without waiting for it.
+type Fake struct {
+	mu      sync.Mutex
+	changed *sync.Cond // Broadcast when waiters are added
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// fakeWaiter is a pending timer, ticker, or AfterFunc.
+type fakeWaiter struct {
+	clock  *Fake
+	at     time.Time
+	period time.Duration // Re-arms a ticker; zero for timers
+	c      chan time.Time
+	f      func()
+}
+
+// NewFake returns a fake clock reading start.
+func NewFake(start time.Time) *Fake {
+	f := &Fake{now: start}
+	f.changed = sync.NewCond(&f.mu)
+	return f
+}
+
+// Now returns the fake time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Since returns the fake time elapsed since t.
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// Until returns the fake time left until t.
+func (f *Fake) Until(t time.Time) time.Duration {
+	return t.Sub(f.Now())
+}
+
+// After returns a channel that receives the fake time once d has passed.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// Sleep blocks until Advance moves the clock d past the call.
+func (f *Fake) Sleep(d time.Duration) {
+	<-f.After(d)
+}
+
+// NewTimer returns a timer that fires once d has passed.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	return f.add(d, 0, nil)
+}
+
+// NewTicker returns a ticker that fires every d. Like a time.Ticker, it
+// drops ticks its reader isn't ready for.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	return fakeTicker{f.add(d, d, nil)}
+}
+
+// AfterFunc calls fn once d has passed, on the goroutine calling Advance, so
+// its effects are visible when Advance returns.
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	return f.add(d, 0, fn)
+}
+
+func (f *Fake) add(d, period time.Duration, fn func()) *fakeWaiter {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w := &fakeWaiter{clock: f, at: f.now.Add(d), period: period, f: fn}
+	if fn == nil {
+		w.c = make(chan time.Time, 1)
+	}
+	if d <= 0 && period == 0 {
+		w.fire(f.now)
+		return w
+	}
+	f.waiters = append(f.waiters, w)
+	f.changed.Broadcast()
+	return w
+}
+
+// Advance moves the clock forward by d, firing every timer and ticker that
+// falls due on the way.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	end := f.now.Add(d)
+	for {
+		w := f.next(end)
+		if w == nil {
+			break
+		}
+		f.now = w.at
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+		} else {
+			f.removeLocked(w)
+		}
+		if w.f != nil {
+			f.mu.Unlock()
+			w.f()
+			f.mu.Lock()
+			continue
+		}
+		w.fire(f.now)
+	}
+	f.now = end
+	f.mu.Unlock()
+}
+
+// next returns the earliest waiter due by end, or nil. Callers hold f.mu.
+func (f *Fake) next(end time.Time) *fakeWaiter {
+	sort.SliceStable(f.waiters, func(i, j int) bool { return f.waiters[i].at.Before(f.waiters[j].at) })
+	if len(f.waiters) == 0 || f.waiters[0].at.After(end) {
+		return nil
+	}
+	return f.waiters[0]
+}
+
+// BlockUntil waits until n timers, tickers, or sleeps are pending, so a test
+// can be sure the code under test is waiting before it calls Advance.
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.waiters) < n {
+		f.changed.Wait()
+	}
+}
+
+// removeLocked drops w from the pending waiters and reports whether it was
+// pending. Callers hold f.mu.
+func (f *Fake) removeLocked(w *fakeWaiter) bool {
+	for i, pending := range f.waiters {
+		if pending == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// fire sends at without blocking, dropping it if the last one wasn't read.
+// An AfterFunc that is already due runs on its own goroutine, as with
+// time.AfterFunc.
+func (w *fakeWaiter) fire(at time.Time) {
+	if w.f != nil {
+		go w.f()
+		return
+	}
+	select {
+	case w.c <- at:
+	default:
+	}
+}
+
+func (w *fakeWaiter) C() <-chan time.Time {
+	return w.c
+}
+
+func (w *fakeWaiter) Stop() bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+	return w.clock.removeLocked(w)
+}
+
+// fakeTicker adapts a waiter to Ticker, whose Stop reports nothing.
+type fakeTicker struct{ *fakeWaiter }
+
+func (t fakeTicker) Stop() { t.fakeWaiter.Stop() }
@@ -13,19 +13,64 @@ import (
 type Config struct {
 	Log       LogConfig
 	Server    ServerConfig
+	Auth      AuthConfig
 	Database  DatabaseConfig
 	Download  DownloadConfig
+	Report    ReportConfig
+	Notify    NotifyConfig
+	Snapshot  SnapshotConfig
+	Replica   ReplicaConfig
+	Repo      RepoConfig
+	CDN       CDNConfig
 	WebSocket WebSocketConfig
+	SelfTest  SelfTestConfig
+	Version   string // Build version, set by main rather than read from the environment
 }
 
 // ServerConfig holds HTTP server configuration.
 type ServerConfig struct {
-	Port            string
-	CORSOrigins     []string
-	ReadTimeout     time.Duration
-	WriteTimeout    time.Duration
-	IdleTimeout     time.Duration
-	ShutdownTimeout time.Duration
+	Port                     string
+	AdminAddr                string // Address of the admin listener serving pprof and runtime diagnostics; empty disables
+	ExternalURL              string // Scheme and host clients reach the server at, used in generated absolute links; empty uses the request's
+	CORSOrigins              []string
+	HiddenFiles              []string // Glob patterns hidden from /images on top of constants.ReservedHiddenNames
+	TrustedProxies           []string // IPs or CIDRs whose X-Forwarded-For is believed; empty trusts none
+	SymlinkPolicy            string   // within, deny
+	ListingCacheTTL          time.Duration
+	RobotsPolicy             string        // allow, disallow-images, disallow-all
+	RobotsTxtFile            string        // Served verbatim instead of the generated robots.txt
+	ImagesNoIndex            bool          // Send X-Robots-Tag: noindex on /images responses
+	Locale                   string        // Default language of directory listings and notifications
+	LocaleDir                string        // Directory of <lang>.json message catalogs adding or changing languages
+	LocaleNegotiate          bool          // Pick each listing's language from its request's Accept-Language
+	ServeVerify              bool          // Hash ISOs while serving them and flag integrity mismatches
+	StreamInProgress         bool          // Serve ISOs still downloading, holding at the bytes fetched so far
+	MaxBodySize              int64         // Largest JSON request body, in bytes
+	MaxUploadSize            int64         // Largest streamed upload, in bytes; 0 is unlimited
+	GeoIPDB                  string        // MMDB file resolving download client IPs to countries; empty disables
+	GeoIPSites               []string      // "name=cidr" entries grouping download clients into sites
+	AnalyticsClientIP        string        // full, truncate, hash, none: how much client IP download events keep
+	AnalyticsUserAgent       bool          // Keep the client's User-Agent with download events
+	AnalyticsRetention       time.Duration // Age after which download events are deleted; zero keeps them
+	ThroughputSampleInterval time.Duration // Zero disables live throughput sampling
+	HealthCheckInterval      time.Duration // Zero disables the health monitor
+	StorageLowThreshold      int64         // Free bytes in the ISO directory below which storage.low is recorded
+	ReadTimeout              time.Duration
+	WriteTimeout             time.Duration
+	IdleTimeout              time.Duration
+	ShutdownTimeout          time.Duration
+}
+
+// AuthConfig holds local user authentication configuration.
+type AuthConfig struct {
+	Enabled       bool // Require a session for /api and /ws
+	SessionTTL    time.Duration
+	CookieSecure  bool   // Only send the session cookie over HTTPS
+	AdminUsername string // First user, created when no user exists
+	AdminPassword string
+	PublicScopes  []string // Endpoint scopes served without a session, e.g. images, stats
+	MaxFailures   int      // Failed logins or invalid tokens per IP before a lockout; 0 disables
+	Lockout       time.Duration
 }
 
 // DatabaseConfig holds database configuration.
@@ -33,6 +78,9 @@ type DatabaseConfig struct {
 	Path            string
 	JournalMode     string
 	BusyTimeout     time.Duration
+	BusyRetries     int  // Retries of a write that still finds the database busy after BusyTimeout
+	SingleWriter    bool // Funnel all writes through one goroutine; reads stay concurrent
+	AutoMigrate     bool // Run pending migrations at startup; otherwise refuse to start until `server migrate` has
 	MaxOpenConns    int
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
@@ -42,14 +90,121 @@ type DatabaseConfig struct {
 // DownloadConfig holds download manager configuration.
 type DownloadConfig struct {
 	DataDir                  string
+	ISODir                   string // Empty uses {DATA_DIR}/isos
+	StorageRelocate          string // copy, move, or skip when ISO_DIR changed; empty refuses to start
+	TempDir                  string // Empty uses {ISO_DIR}/.tmp
 	WorkerCount              int
+	VerifyWorkerCount        int
+	FastLaneWorkers          int   // Workers that only take small downloads; zero disables the fast lane
+	FastLaneMaxSize          int64 // Largest download, in bytes, that goes in the fast lane
 	QueueBuffer              int
 	MaxRetries               int
 	RetryDelay               time.Duration
 	BufferSize               int
 	ProgressUpdateInterval   time.Duration
 	ProgressPercentThreshold int
+	ProgressPersistInterval  time.Duration
+	MaxDownloadDuration      time.Duration
+	StallTimeout             time.Duration
 	CancellationWait         time.Duration
+	UpstreamCheckInterval    time.Duration
+	UpstreamAutoRefresh      bool
+	KeepVersions             int
+	IntegrityHash            string // blake2b, sha256
+	ClamAVAddress            string // unix:///path or tcp://host:port; empty disables scanning
+	ClamAVTimeout            time.Duration
+	SignatureKeyring         string   // OpenPGP public keys that signature_url files must be signed by
+	ChecksumDBFile           string   // Published checksums imported at startup for offline verification
+	SidecarExtensions        []string // Files beside each ISO moved, deleted, bundled, and purged with it, on top of constants.SidecarExtensions
+	ReconcileOnStartup       bool     // Fix ISOs whose stored paths and links drifted from their metadata at startup
+	TempCleanupInterval      time.Duration
+	TempMaxAge               time.Duration // Orphaned temp files older than this are removed
+	NodeID                   string        // Names this instance in download locks; empty uses the hostname
+	DownloadLockTTL          time.Duration // Lease on an in-flight download, renewed while it runs
+	QueuePollInterval        time.Duration // How often idle workers take queued ISOs from the database; zero disables
+	StaleDownloadTimeout     time.Duration // Running downloads with no lock and no progress for this long are failed; zero disables
+	StaleDownloadRequeue     bool          // Queue stale downloads again instead of failing them
+	ChecksumRetryInterval    time.Duration // How often checksums that were unavailable at completion are retried; zero disables
+
+	// Upstream HTTP client tuning
+	HTTPConnectTimeout        time.Duration
+	HTTPTLSHandshakeTimeout   time.Duration
+	HTTPResponseHeaderTimeout time.Duration
+	HTTPIdleConnTimeout       time.Duration
+	HTTPMaxIdleConns          int
+	HTTPMaxIdleConnsPerHost   int
+	HTTPEnableHTTP2           bool
+	HTTPIPFamily              string // any, ipv4, ipv6
+	DNSCacheTTL               time.Duration
+	HTTPBlockPrivateNetworks  bool          // Refuse to connect to loopback, private, link-local, or reserved addresses
+	HTTPAllowedNetworks       []string      // CIDRs or IPs exempt from the private network block
+	HTTPMinHostInterval       time.Duration // Minimum time between requests to the same host; zero disables
+	HTTPRetryAfterMax         time.Duration // Longest Retry-After waited for on 429 and 503; zero disables
+
+	// Mirrors downloads can be sent to instead of the URL an ISO names
+	MirrorGroups   []string // "name=base URL" entries; a name repeats for each mirror of a group
+	MirrorRotation string   // none, round-robin, healthiest
+
+	// Master key sealing stored upstream credentials
+	CredentialsKey     string // Base64-encoded 32-byte key
+	CredentialsKeyFile string // Read when CredentialsKey is empty
+
+	// Checks of user-supplied URLs when ISOs are created or edited
+	URLAllowedSchemes  []string // Subset of http, https
+	URLCheckDNS        bool     // Reject URLs whose host doesn't resolve
+	URLBlockPrivateIPs bool     // Reject URLs whose host resolves to a private or reserved address
+}
+
+// ReportConfig holds scheduled report email configuration. Reports are sent
+// only when SMTPHost, Recipients, and Schedule are all set.
+type ReportConfig struct {
+	Schedule     string   // Five-field cron expression, in server local time
+	Recipients   []string // Addresses the report is mailed to
+	SMTPHost     string
+	SMTPPort     int    // 465 uses implicit TLS; other ports upgrade with STARTTLS when offered
+	SMTPUsername string // Empty skips SMTP authentication
+	SMTPPassword string
+	SMTPFrom     string
+	SMTPTimeout  time.Duration
+}
+
+// NotifyConfig holds failure notification email configuration. Notifications
+// use the report's SMTP settings and are sent only when SMTPHost and
+// Recipients are both set.
+type NotifyConfig struct {
+	Recipients   []string      // Addresses notifications are mailed to
+	DigestWindow time.Duration // Notifications within this long of the first share one email
+}
+
+// SnapshotConfig holds catalog snapshot configuration. Snapshots are the
+// baselines GET /api/changes compares the catalog against.
+type SnapshotConfig struct {
+	Schedule  string        // Five-field cron expression, in server local time; empty disables snapshots
+	Retention time.Duration // Age after which snapshots are deleted; zero keeps them
+}
+
+// ReplicaConfig holds read-only replica configuration. Setting PrimaryURL
+// makes this instance a replica: it never downloads, and its catalog follows
+// the primary's manifest.
+type ReplicaConfig struct {
+	PrimaryURL   string        // Base URL of the primary instance
+	PrimaryToken string        // Session token for the primary when it requires sign-in
+	SyncInterval time.Duration // How often the primary's manifest is fetched
+}
+
+// RepoConfig holds settings for signed repository metadata under /repo.
+type RepoConfig struct {
+	SigningKey    string        // PEM Ed25519 private key file; empty publishes no metadata
+	RootExpiry    time.Duration // Validity of root.json, signed at startup
+	TargetsExpiry time.Duration // Validity of each targets.json version
+}
+
+// CDNConfig holds settings for running behind a CDN or caching proxy.
+type CDNConfig struct {
+	FileCacheControl    string // Cache-Control for files served from /images; empty sends none
+	ListingCacheControl string // Cache-Control for /images directory listings; empty sends none
+	PurgeURL            string // Receives a POST naming the /images paths to purge; empty disables
+	PurgeToken          string // Sent as a bearer token with purge requests
 }
 
 // WebSocketConfig holds WebSocket configuration.
@@ -57,6 +212,12 @@ type WebSocketConfig struct {
 	BroadcastChannelSize int
 }
 
+// SelfTestConfig holds configuration of the --self-test run mode.
+type SelfTestConfig struct {
+	ProbeURL     string        // Fetched to check outbound connectivity; empty skips the check
+	ProbeTimeout time.Duration // How long the probe may take
+}
+
 // LogConfig holds logging configuration.
 type LogConfig struct {
 	Level  string // debug, info, warn, error
@@ -69,15 +230,52 @@ func Load() *Config {
 
 	// Set defaults for Server
 	v.SetDefault("PORT", constants.DefaultPort)
+	v.SetDefault("ADMIN_ADDR", "")
 	v.SetDefault("READ_TIMEOUT_SEC", constants.DefaultReadTimeoutSec)
 	v.SetDefault("WRITE_TIMEOUT_SEC", constants.DefaultWriteTimeoutSec)
 	v.SetDefault("IDLE_TIMEOUT_SEC", constants.DefaultIdleTimeoutSec)
 	v.SetDefault("SHUTDOWN_TIMEOUT_SEC", constants.DefaultShutdownTimeoutSec)
 	v.SetDefault("CORS_ORIGINS", "http://localhost:3000,http://localhost:5173,http://localhost:8080")
+	v.SetDefault("HIDDEN_FILES", constants.DefaultHiddenFiles)
+	v.SetDefault("TRUSTED_PROXIES", "")
+	v.SetDefault("SYMLINK_POLICY", constants.DefaultSymlinkPolicy)
+	v.SetDefault("LISTING_CACHE_TTL_SEC", constants.DefaultListingCacheTTLSec)
+	v.SetDefault("ROBOTS_POLICY", constants.DefaultRobotsPolicy)
+	v.SetDefault("ROBOTS_TXT_FILE", "")
+	v.SetDefault("IMAGES_NOINDEX", false)
+	v.SetDefault("LOCALE", constants.DefaultLocale)
+	v.SetDefault("LOCALE_DIR", "")
+	v.SetDefault("LOCALE_NEGOTIATE", true)
+	v.SetDefault("SERVE_VERIFY", false)
+	v.SetDefault("EXTERNAL_URL", "")
+	v.SetDefault("STREAM_IN_PROGRESS", false)
+	v.SetDefault("MAX_BODY_KB", constants.DefaultMaxBodyKB)
+	v.SetDefault("MAX_UPLOAD_MB", constants.DefaultMaxUploadMB)
+	v.SetDefault("GEOIP_DB", "")
+	v.SetDefault("GEOIP_SITES", "")
+	v.SetDefault("ANALYTICS_CLIENT_IP", constants.DefaultAnalyticsClientIP)
+	v.SetDefault("ANALYTICS_USER_AGENT", true)
+	v.SetDefault("ANALYTICS_RETENTION_DAYS", constants.DefaultAnalyticsRetentionDays)
+	v.SetDefault("THROUGHPUT_SAMPLE_INTERVAL_SEC", constants.DefaultThroughputSampleIntervalSec)
+	v.SetDefault("HEALTH_CHECK_INTERVAL_SEC", constants.DefaultHealthCheckIntervalSec)
+	v.SetDefault("STORAGE_LOW_THRESHOLD_MB", constants.DefaultStorageLowThresholdMB)
+
+	// Set defaults for Auth
+	v.SetDefault("AUTH_ENABLED", false)
+	v.SetDefault("AUTH_SESSION_TTL_HOURS", constants.DefaultSessionTTLHours)
+	v.SetDefault("AUTH_COOKIE_SECURE", false)
+	v.SetDefault("AUTH_ADMIN_USERNAME", "")
+	v.SetDefault("AUTH_ADMIN_PASSWORD", "")
+	v.SetDefault("AUTH_PUBLIC_SCOPES", constants.DefaultAuthPublicScopes)
+	v.SetDefault("AUTH_MAX_FAILURES", constants.DefaultAuthMaxFailures)
+	v.SetDefault("AUTH_LOCKOUT_MIN", constants.DefaultAuthLockoutMin)
 
 	// Set defaults for Database
 	v.SetDefault("DB_PATH", "")
 	v.SetDefault("DB_BUSY_TIMEOUT_MS", constants.DefaultBusyTimeoutMs)
+	v.SetDefault("DB_BUSY_RETRIES", constants.DefaultBusyRetries)
+	v.SetDefault("DB_SINGLE_WRITER", false)
+	v.SetDefault("DB_AUTO_MIGRATE", true)
 	v.SetDefault("DB_JOURNAL_MODE", constants.DefaultJournalMode)
 	v.SetDefault("DB_MAX_OPEN_CONNS", constants.DefaultMaxOpenConns)
 	v.SetDefault("DB_MAX_IDLE_CONNS", constants.DefaultMaxIdleConns)
@@ -86,18 +284,95 @@ func Load() *Config {
 
 	// Set defaults for Download
 	v.SetDefault("DATA_DIR", "./data")
+	v.SetDefault("ISO_DIR", "")
+	v.SetDefault("STORAGE_RELOCATE", "")
+	v.SetDefault("TMP_DIR", "")
 	v.SetDefault("WORKER_COUNT", constants.DefaultWorkerCount)
+	v.SetDefault("VERIFY_WORKER_COUNT", constants.DefaultVerifyWorkerCount)
+	v.SetDefault("FAST_LANE_WORKERS", constants.DefaultFastLaneWorkers)
+	v.SetDefault("FAST_LANE_MAX_MB", constants.DefaultFastLaneMaxMB)
 	v.SetDefault("QUEUE_BUFFER", constants.DefaultQueueBuffer)
 	v.SetDefault("MAX_RETRIES", constants.DefaultMaxRetries)
 	v.SetDefault("RETRY_DELAY_MS", constants.DefaultRetryDelayMs)
 	v.SetDefault("BUFFER_SIZE", constants.DefaultDownloadBufferSize)
 	v.SetDefault("PROGRESS_UPDATE_INTERVAL_SEC", 1)
 	v.SetDefault("PROGRESS_PERCENT_THRESHOLD", constants.DefaultProgressPercentThreshold)
+	v.SetDefault("PROGRESS_PERSIST_INTERVAL_SEC", constants.DefaultProgressPersistIntervalSec)
+	v.SetDefault("MAX_DOWNLOAD_DURATION_MIN", constants.DefaultMaxDownloadDurationMin)
+	v.SetDefault("STALL_TIMEOUT_SEC", constants.DefaultStallTimeoutSec)
 	v.SetDefault("CANCELLATION_WAIT_MS", constants.DefaultCancellationWaitMs)
+	v.SetDefault("UPSTREAM_CHECK_INTERVAL_MIN", constants.DefaultUpstreamCheckIntervalMin)
+	v.SetDefault("UPSTREAM_AUTO_REFRESH", false)
+	v.SetDefault("REFRESH_KEEP_VERSIONS", constants.DefaultKeepVersions)
+	v.SetDefault("INTEGRITY_HASH", constants.DefaultIntegrityHash)
+	v.SetDefault("CLAMAV_ADDRESS", "")
+	v.SetDefault("CLAMAV_TIMEOUT_SEC", constants.DefaultClamAVTimeoutSec)
+	v.SetDefault("GPG_KEYRING", "")
+	v.SetDefault("CHECKSUM_DB_FILE", "")
+	v.SetDefault("RECONCILE_ON_STARTUP", false)
+	v.SetDefault("SIDECAR_EXTENSIONS", "")
+	v.SetDefault("TEMP_CLEANUP_INTERVAL_MIN", constants.DefaultTempCleanupIntervalMin)
+	v.SetDefault("TEMP_MAX_AGE_HOURS", constants.DefaultTempMaxAgeHours)
+	v.SetDefault("NODE_ID", "")
+	v.SetDefault("DOWNLOAD_LOCK_TTL_SEC", constants.DefaultDownloadLockTTLSec)
+	v.SetDefault("QUEUE_POLL_INTERVAL_SEC", constants.DefaultQueuePollIntervalSec)
+	v.SetDefault("STALE_DOWNLOAD_TIMEOUT_MIN", constants.DefaultStaleDownloadTimeoutMin)
+	v.SetDefault("STALE_DOWNLOAD_REQUEUE", false)
+	v.SetDefault("CHECKSUM_RETRY_INTERVAL_MIN", constants.DefaultChecksumRetryIntervalMin)
+
+	// Set defaults for upstream HTTP client
+	v.SetDefault("HTTP_CONNECT_TIMEOUT_SEC", constants.DefaultHTTPConnectTimeoutSec)
+	v.SetDefault("HTTP_TLS_HANDSHAKE_TIMEOUT_SEC", constants.DefaultHTTPTLSHandshakeTimeoutSec)
+	v.SetDefault("HTTP_RESPONSE_HEADER_TIMEOUT_SEC", constants.DefaultHTTPResponseHeaderTimeoutSec)
+	v.SetDefault("HTTP_IDLE_CONN_TIMEOUT_SEC", constants.DefaultHTTPIdleConnTimeoutSec)
+	v.SetDefault("HTTP_MAX_IDLE_CONNS", constants.DefaultHTTPMaxIdleConns)
+	v.SetDefault("HTTP_MAX_IDLE_CONNS_PER_HOST", constants.DefaultHTTPMaxIdleConnsPerHost)
+	v.SetDefault("HTTP_ENABLE_HTTP2", true)
+	v.SetDefault("HTTP_IP_FAMILY", constants.IPFamilyAny)
+	v.SetDefault("DNS_CACHE_TTL_SEC", constants.DefaultDNSCacheTTLSec)
+	v.SetDefault("HTTP_BLOCK_PRIVATE_NETWORKS", true)
+	v.SetDefault("HTTP_ALLOWED_NETWORKS", "")
+	v.SetDefault("HTTP_MIN_HOST_INTERVAL_MS", constants.DefaultHTTPMinHostIntervalMs)
+	v.SetDefault("HTTP_RETRY_AFTER_MAX_SEC", constants.DefaultHTTPRetryAfterMaxSec)
+	v.SetDefault("MIRROR_GROUPS", "")
+	v.SetDefault("MIRROR_ROTATION", constants.DefaultMirrorRotation)
+	v.SetDefault("CREDENTIALS_KEY", "")
+	v.SetDefault("CREDENTIALS_KEY_FILE", "")
+	v.SetDefault("URL_ALLOWED_SCHEMES", constants.DefaultURLAllowedSchemes)
+	v.SetDefault("URL_CHECK_DNS", false)
+	v.SetDefault("URL_BLOCK_PRIVATE_IPS", false)
+
+	// Set defaults for scheduled reports
+	v.SetDefault("REPORT_SCHEDULE", constants.DefaultReportSchedule)
+	v.SetDefault("REPORT_RECIPIENTS", "")
+	v.SetDefault("NOTIFY_RECIPIENTS", "")
+	v.SetDefault("NOTIFY_DIGEST_WINDOW_SEC", constants.DefaultNotifyDigestWindowSec)
+	v.SetDefault("CATALOG_SNAPSHOT_SCHEDULE", constants.DefaultCatalogSnapshotSchedule)
+	v.SetDefault("CATALOG_SNAPSHOT_RETENTION_DAYS", constants.DefaultCatalogSnapshotRetentionDays)
+	v.SetDefault("REPLICA_PRIMARY_URL", "")
+	v.SetDefault("REPLICA_PRIMARY_TOKEN", "")
+	v.SetDefault("REPLICA_SYNC_INTERVAL_SEC", constants.DefaultReplicaSyncIntervalSec)
+	v.SetDefault("REPO_SIGNING_KEY", "")
+	v.SetDefault("REPO_ROOT_EXPIRY_DAYS", constants.DefaultRepoRootExpiryDays)
+	v.SetDefault("REPO_TARGETS_EXPIRY_DAYS", constants.DefaultRepoTargetsExpiryDays)
+	v.SetDefault("IMAGES_CACHE_CONTROL", "")
+	v.SetDefault("IMAGES_LISTING_CACHE_CONTROL", "")
+	v.SetDefault("CDN_PURGE_URL", "")
+	v.SetDefault("CDN_PURGE_TOKEN", "")
+	v.SetDefault("SMTP_HOST", "")
+	v.SetDefault("SMTP_PORT", constants.DefaultSMTPPort)
+	v.SetDefault("SMTP_USERNAME", "")
+	v.SetDefault("SMTP_PASSWORD", "")
+	v.SetDefault("SMTP_FROM", "isoman@localhost")
+	v.SetDefault("SMTP_TIMEOUT_SEC", constants.DefaultSMTPTimeoutSec)
 
 	// Set defaults for WebSocket
 	v.SetDefault("WS_BROADCAST_SIZE", constants.DefaultBroadcastChannelSize)
 
+	// Set defaults for the self-test
+	v.SetDefault("SELF_TEST_PROBE_URL", "")
+	v.SetDefault("SELF_TEST_PROBE_TIMEOUT_SEC", constants.DefaultSelfTestProbeTimeoutSec)
+
 	// Set defaults for Logging
 	v.SetDefault("LOG_LEVEL", "info")
 	v.SetDefault("LOG_FORMAT", "text")
@@ -109,18 +384,140 @@ func Load() *Config {
 	corsOriginsStr := v.GetString("CORS_ORIGINS")
 	corsOrigins := strings.Split(corsOriginsStr, ",")
 
+	// Parse allowed URL schemes; an empty value allows http and https
+	urlSchemes := []string{}
+	for _, scheme := range strings.Split(v.GetString("URL_ALLOWED_SCHEMES"), ",") {
+		if scheme = strings.ToLower(strings.TrimSpace(scheme)); scheme != "" {
+			urlSchemes = append(urlSchemes, scheme)
+		}
+	}
+
+	// Parse networks exempt from the private network block; validated where they're used
+	allowedNetworks := []string{}
+	for _, network := range strings.Split(v.GetString("HTTP_ALLOWED_NETWORKS"), ",") {
+		if network = strings.TrimSpace(network); network != "" {
+			allowedNetworks = append(allowedNetworks, network)
+		}
+	}
+
+	// Parse extra sidecar extensions, adding the leading dot where it's missing
+	sidecarExtensions := []string{}
+	for _, ext := range strings.Split(v.GetString("SIDECAR_EXTENSIONS"), ",") {
+		ext = strings.TrimSpace(ext)
+		if ext == "" || ext == "." || strings.ContainsAny(ext, `/\`) {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		sidecarExtensions = append(sidecarExtensions, ext)
+	}
+
+	// Parse trusted proxies; validated by gin at startup
+	trustedProxies := []string{}
+	for _, proxy := range strings.Split(v.GetString("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+
+	// Parse GeoIP sites; validated at startup
+	geoIPSites := []string{}
+	for _, site := range strings.Split(v.GetString("GEOIP_SITES"), ",") {
+		if site = strings.TrimSpace(site); site != "" {
+			geoIPSites = append(geoIPSites, site)
+		}
+	}
+
+	// Parse mirror groups; validated at startup
+	mirrorGroups := []string{}
+	for _, entry := range strings.Split(v.GetString("MIRROR_GROUPS"), ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			mirrorGroups = append(mirrorGroups, entry)
+		}
+	}
+
+	// Parse public endpoint scopes; validated where they're used
+	publicScopes := []string{}
+	for _, scope := range strings.Split(v.GetString("AUTH_PUBLIC_SCOPES"), ",") {
+		if scope = strings.ToLower(strings.TrimSpace(scope)); scope != "" {
+			publicScopes = append(publicScopes, scope)
+		}
+	}
+
+	// Parse report recipients; addresses are validated when a report is sent
+	reportRecipients := []string{}
+	for _, addr := range strings.Split(v.GetString("REPORT_RECIPIENTS"), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			reportRecipients = append(reportRecipients, addr)
+		}
+	}
+
+	// Parse notification recipients the same way
+	notifyRecipients := []string{}
+	for _, addr := range strings.Split(v.GetString("NOTIFY_RECIPIENTS"), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			notifyRecipients = append(notifyRecipients, addr)
+		}
+	}
+
+	// Parse hidden file patterns; an empty value hides only the reserved names
+	hiddenFiles := []string{}
+	for _, pattern := range strings.Split(v.GetString("HIDDEN_FILES"), ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			hiddenFiles = append(hiddenFiles, pattern)
+		}
+	}
+
 	return &Config{
 		Server: ServerConfig{
-			Port:            v.GetString("PORT"),
-			ReadTimeout:     time.Duration(v.GetInt("READ_TIMEOUT_SEC")) * time.Second,
-			WriteTimeout:    time.Duration(v.GetInt("WRITE_TIMEOUT_SEC")) * time.Second,
-			IdleTimeout:     time.Duration(v.GetInt("IDLE_TIMEOUT_SEC")) * time.Second,
-			ShutdownTimeout: time.Duration(v.GetInt("SHUTDOWN_TIMEOUT_SEC")) * time.Second,
-			CORSOrigins:     corsOrigins,
+			Port:                     v.GetString("PORT"),
+			AdminAddr:                strings.TrimSpace(v.GetString("ADMIN_ADDR")),
+			ReadTimeout:              time.Duration(v.GetInt("READ_TIMEOUT_SEC")) * time.Second,
+			WriteTimeout:             time.Duration(v.GetInt("WRITE_TIMEOUT_SEC")) * time.Second,
+			IdleTimeout:              time.Duration(v.GetInt("IDLE_TIMEOUT_SEC")) * time.Second,
+			ShutdownTimeout:          time.Duration(v.GetInt("SHUTDOWN_TIMEOUT_SEC")) * time.Second,
+			CORSOrigins:              corsOrigins,
+			HiddenFiles:              hiddenFiles,
+			TrustedProxies:           trustedProxies,
+			SymlinkPolicy:            strings.ToLower(v.GetString("SYMLINK_POLICY")),
+			ListingCacheTTL:          time.Duration(v.GetInt("LISTING_CACHE_TTL_SEC")) * time.Second,
+			RobotsPolicy:             strings.ToLower(v.GetString("ROBOTS_POLICY")),
+			RobotsTxtFile:            v.GetString("ROBOTS_TXT_FILE"),
+			ImagesNoIndex:            v.GetBool("IMAGES_NOINDEX"),
+			Locale:                   v.GetString("LOCALE"),
+			LocaleDir:                v.GetString("LOCALE_DIR"),
+			LocaleNegotiate:          v.GetBool("LOCALE_NEGOTIATE"),
+			ServeVerify:              v.GetBool("SERVE_VERIFY"),
+			ExternalURL:              strings.TrimRight(strings.TrimSpace(v.GetString("EXTERNAL_URL")), "/"),
+			StreamInProgress:         v.GetBool("STREAM_IN_PROGRESS"),
+			MaxBodySize:              max(v.GetInt64("MAX_BODY_KB"), 1) * 1024,
+			MaxUploadSize:            max(v.GetInt64("MAX_UPLOAD_MB"), 0) * 1024 * 1024,
+			GeoIPDB:                  v.GetString("GEOIP_DB"),
+			GeoIPSites:               geoIPSites,
+			AnalyticsClientIP:        strings.ToLower(v.GetString("ANALYTICS_CLIENT_IP")),
+			AnalyticsUserAgent:       v.GetBool("ANALYTICS_USER_AGENT"),
+			AnalyticsRetention:       time.Duration(v.GetInt("ANALYTICS_RETENTION_DAYS")) * 24 * time.Hour,
+			ThroughputSampleInterval: time.Duration(v.GetInt("THROUGHPUT_SAMPLE_INTERVAL_SEC")) * time.Second,
+			HealthCheckInterval:      time.Duration(v.GetInt("HEALTH_CHECK_INTERVAL_SEC")) * time.Second,
+			StorageLowThreshold:      v.GetInt64("STORAGE_LOW_THRESHOLD_MB") * 1024 * 1024,
+		},
+		Auth: AuthConfig{
+			Enabled:       v.GetBool("AUTH_ENABLED"),
+			SessionTTL:    time.Duration(v.GetInt("AUTH_SESSION_TTL_HOURS")) * time.Hour,
+			CookieSecure:  v.GetBool("AUTH_COOKIE_SECURE"),
+			AdminUsername: v.GetString("AUTH_ADMIN_USERNAME"),
+			AdminPassword: v.GetString("AUTH_ADMIN_PASSWORD"),
+			PublicScopes:  publicScopes,
+			MaxFailures:   v.GetInt("AUTH_MAX_FAILURES"),
+			Lockout:       time.Duration(v.GetInt("AUTH_LOCKOUT_MIN")) * time.Minute,
 		},
 		Database: DatabaseConfig{
 			Path:            v.GetString("DB_PATH"),
 			BusyTimeout:     time.Duration(v.GetInt("DB_BUSY_TIMEOUT_MS")) * time.Millisecond,
+			BusyRetries:     v.GetInt("DB_BUSY_RETRIES"),
+			SingleWriter:    v.GetBool("DB_SINGLE_WRITER"),
+			AutoMigrate:     v.GetBool("DB_AUTO_MIGRATE"),
 			JournalMode:     v.GetString("DB_JOURNAL_MODE"),
 			MaxOpenConns:    v.GetInt("DB_MAX_OPEN_CONNS"),
 			MaxIdleConns:    v.GetInt("DB_MAX_IDLE_CONNS"),
@@ -129,18 +526,104 @@ func Load() *Config {
 		},
 		Download: DownloadConfig{
 			DataDir:                  v.GetString("DATA_DIR"),
+			ISODir:                   v.GetString("ISO_DIR"),
+			StorageRelocate:          strings.ToLower(v.GetString("STORAGE_RELOCATE")),
+			TempDir:                  v.GetString("TMP_DIR"),
 			WorkerCount:              v.GetInt("WORKER_COUNT"),
+			VerifyWorkerCount:        v.GetInt("VERIFY_WORKER_COUNT"),
+			FastLaneWorkers:          v.GetInt("FAST_LANE_WORKERS"),
+			FastLaneMaxSize:          v.GetInt64("FAST_LANE_MAX_MB") * 1024 * 1024,
 			QueueBuffer:              v.GetInt("QUEUE_BUFFER"),
 			MaxRetries:               v.GetInt("MAX_RETRIES"),
 			RetryDelay:               time.Duration(v.GetInt("RETRY_DELAY_MS")) * time.Millisecond,
 			BufferSize:               v.GetInt("BUFFER_SIZE"),
 			ProgressUpdateInterval:   time.Duration(v.GetInt("PROGRESS_UPDATE_INTERVAL_SEC")) * time.Second,
 			ProgressPercentThreshold: v.GetInt("PROGRESS_PERCENT_THRESHOLD"),
+			ProgressPersistInterval:  time.Duration(v.GetInt("PROGRESS_PERSIST_INTERVAL_SEC")) * time.Second,
+			MaxDownloadDuration:      time.Duration(v.GetInt("MAX_DOWNLOAD_DURATION_MIN")) * time.Minute,
+			StallTimeout:             time.Duration(v.GetInt("STALL_TIMEOUT_SEC")) * time.Second,
 			CancellationWait:         time.Duration(v.GetInt("CANCELLATION_WAIT_MS")) * time.Millisecond,
+			UpstreamCheckInterval:    time.Duration(v.GetInt("UPSTREAM_CHECK_INTERVAL_MIN")) * time.Minute,
+			UpstreamAutoRefresh:      v.GetBool("UPSTREAM_AUTO_REFRESH"),
+			KeepVersions:             v.GetInt("REFRESH_KEEP_VERSIONS"),
+			IntegrityHash:            strings.ToLower(v.GetString("INTEGRITY_HASH")),
+			ClamAVAddress:            v.GetString("CLAMAV_ADDRESS"),
+			ClamAVTimeout:            time.Duration(v.GetInt("CLAMAV_TIMEOUT_SEC")) * time.Second,
+			SignatureKeyring:         v.GetString("GPG_KEYRING"),
+			ChecksumDBFile:           v.GetString("CHECKSUM_DB_FILE"),
+			ReconcileOnStartup:       v.GetBool("RECONCILE_ON_STARTUP"),
+			SidecarExtensions:        sidecarExtensions,
+			TempCleanupInterval:      time.Duration(v.GetInt("TEMP_CLEANUP_INTERVAL_MIN")) * time.Minute,
+			TempMaxAge:               time.Duration(v.GetInt("TEMP_MAX_AGE_HOURS")) * time.Hour,
+			NodeID:                   strings.TrimSpace(v.GetString("NODE_ID")),
+			DownloadLockTTL:          time.Duration(v.GetInt("DOWNLOAD_LOCK_TTL_SEC")) * time.Second,
+			QueuePollInterval:        time.Duration(v.GetInt("QUEUE_POLL_INTERVAL_SEC")) * time.Second,
+			StaleDownloadTimeout:     time.Duration(v.GetInt("STALE_DOWNLOAD_TIMEOUT_MIN")) * time.Minute,
+			StaleDownloadRequeue:     v.GetBool("STALE_DOWNLOAD_REQUEUE"),
+			ChecksumRetryInterval:    time.Duration(v.GetInt("CHECKSUM_RETRY_INTERVAL_MIN")) * time.Minute,
+
+			HTTPConnectTimeout:        time.Duration(v.GetInt("HTTP_CONNECT_TIMEOUT_SEC")) * time.Second,
+			HTTPTLSHandshakeTimeout:   time.Duration(v.GetInt("HTTP_TLS_HANDSHAKE_TIMEOUT_SEC")) * time.Second,
+			HTTPResponseHeaderTimeout: time.Duration(v.GetInt("HTTP_RESPONSE_HEADER_TIMEOUT_SEC")) * time.Second,
+			HTTPIdleConnTimeout:       time.Duration(v.GetInt("HTTP_IDLE_CONN_TIMEOUT_SEC")) * time.Second,
+			HTTPMaxIdleConns:          v.GetInt("HTTP_MAX_IDLE_CONNS"),
+			HTTPMaxIdleConnsPerHost:   v.GetInt("HTTP_MAX_IDLE_CONNS_PER_HOST"),
+			HTTPEnableHTTP2:           v.GetBool("HTTP_ENABLE_HTTP2"),
+			HTTPIPFamily:              strings.ToLower(v.GetString("HTTP_IP_FAMILY")),
+			DNSCacheTTL:               time.Duration(v.GetInt("DNS_CACHE_TTL_SEC")) * time.Second,
+			HTTPBlockPrivateNetworks:  v.GetBool("HTTP_BLOCK_PRIVATE_NETWORKS"),
+			HTTPAllowedNetworks:       allowedNetworks,
+			HTTPMinHostInterval:       time.Duration(v.GetInt("HTTP_MIN_HOST_INTERVAL_MS")) * time.Millisecond,
+			HTTPRetryAfterMax:         time.Duration(v.GetInt("HTTP_RETRY_AFTER_MAX_SEC")) * time.Second,
+			MirrorGroups:              mirrorGroups,
+			MirrorRotation:            strings.ToLower(v.GetString("MIRROR_ROTATION")),
+			CredentialsKey:            v.GetString("CREDENTIALS_KEY"),
+			CredentialsKeyFile:        v.GetString("CREDENTIALS_KEY_FILE"),
+			URLAllowedSchemes:         urlSchemes,
+			URLCheckDNS:               v.GetBool("URL_CHECK_DNS"),
+			URLBlockPrivateIPs:        v.GetBool("URL_BLOCK_PRIVATE_IPS"),
+		},
+		Report: ReportConfig{
+			Schedule:     strings.TrimSpace(v.GetString("REPORT_SCHEDULE")),
+			Recipients:   reportRecipients,
+			SMTPHost:     v.GetString("SMTP_HOST"),
+			SMTPPort:     v.GetInt("SMTP_PORT"),
+			SMTPUsername: v.GetString("SMTP_USERNAME"),
+			SMTPPassword: v.GetString("SMTP_PASSWORD"),
+			SMTPFrom:     v.GetString("SMTP_FROM"),
+			SMTPTimeout:  time.Duration(v.GetInt("SMTP_TIMEOUT_SEC")) * time.Second,
+		},
+		Notify: NotifyConfig{
+			Recipients:   notifyRecipients,
+			DigestWindow: time.Duration(v.GetInt("NOTIFY_DIGEST_WINDOW_SEC")) * time.Second,
+		},
+		Snapshot: SnapshotConfig{
+			Schedule:  strings.TrimSpace(v.GetString("CATALOG_SNAPSHOT_SCHEDULE")),
+			Retention: time.Duration(v.GetInt("CATALOG_SNAPSHOT_RETENTION_DAYS")) * 24 * time.Hour,
+		},
+		Replica: ReplicaConfig{
+			PrimaryURL:   strings.TrimSpace(v.GetString("REPLICA_PRIMARY_URL")),
+			PrimaryToken: v.GetString("REPLICA_PRIMARY_TOKEN"),
+			SyncInterval: time.Duration(v.GetInt("REPLICA_SYNC_INTERVAL_SEC")) * time.Second,
+		},
+		Repo: RepoConfig{
+			SigningKey:    strings.TrimSpace(v.GetString("REPO_SIGNING_KEY")),
+			RootExpiry:    time.Duration(v.GetInt("REPO_ROOT_EXPIRY_DAYS")) * 24 * time.Hour,
+			TargetsExpiry: time.Duration(v.GetInt("REPO_TARGETS_EXPIRY_DAYS")) * 24 * time.Hour,
+		},
+		CDN: CDNConfig{
+			FileCacheControl:    strings.TrimSpace(v.GetString("IMAGES_CACHE_CONTROL")),
+			ListingCacheControl: strings.TrimSpace(v.GetString("IMAGES_LISTING_CACHE_CONTROL")),
+			PurgeURL:            strings.TrimSpace(v.GetString("CDN_PURGE_URL")),
+			PurgeToken:          v.GetString("CDN_PURGE_TOKEN"),
 		},
 		WebSocket: WebSocketConfig{
 			BroadcastChannelSize: v.GetInt("WS_BROADCAST_SIZE"),
 		},
+		SelfTest: SelfTestConfig{
+			ProbeURL:     strings.TrimSpace(v.GetString("SELF_TEST_PROBE_URL")),
+			ProbeTimeout: time.Duration(v.GetInt("SELF_TEST_PROBE_TIMEOUT_SEC")) * time.Second,
+		},
 		Log: LogConfig{
 			Level:  v.GetString("LOG_LEVEL"),
 			Format: v.GetString("LOG_FORMAT"),
@@ -11,28 +11,232 @@ var SupportedFileTypes = []string{
 // Checksum types supported for file verification.
 var ChecksumTypes = []string{"sha256", "sha512", "md5"}
 
+// IP address families that upstream fetches can be restricted to.
+const (
+	IPFamilyAny  = "any"
+	IPFamilyIPv4 = "ipv4"
+	IPFamilyIPv6 = "ipv6"
+)
+
+// IPFamilies lists the valid IP family preferences.
+var IPFamilies = []string{IPFamilyAny, IPFamilyIPv4, IPFamilyIPv6}
+
+// How downloads pick among the mirrors of a MIRROR_GROUPS group.
+const (
+	MirrorRotationNone       = "none"        // Always the URL the ISO names
+	MirrorRotationRoundRobin = "round-robin" // Each download takes the next mirror in turn
+	MirrorRotationHealthiest = "healthiest"  // The mirror with the best recorded success rate, then latency
+)
+
+// MirrorRotations lists the valid mirror rotation policies.
+var MirrorRotations = []string{MirrorRotationNone, MirrorRotationRoundRobin, MirrorRotationHealthiest}
+
+// Integrity hash algorithms used to scrub files already on disk.
+const (
+	IntegrityHashBLAKE2b = "blake2b"
+	IntegrityHashSHA256  = "sha256"
+)
+
+// IntegrityHashes lists the valid integrity hash algorithms.
+var IntegrityHashes = []string{IntegrityHashBLAKE2b, IntegrityHashSHA256}
+
+// ProvenanceHeaders are the upstream response headers recorded with a
+// download, identifying the server, mirror, or CDN node that served it.
+var ProvenanceHeaders = []string{
+	"Server", "Date", "Content-Type", "Content-Length", "Content-Disposition",
+	"Via", "Age", "X-Cache", "X-Served-By", "CF-Ray", "X-Amz-Cf-Pop",
+}
+
+// What to do at startup when ISO_DIR no longer matches the directory the
+// database recorded the files in.
+const (
+	StorageRelocateCopy = "copy" // Copy and verify the files into ISO_DIR, keeping the old directory
+	StorageRelocateMove = "move" // Copy and verify, then remove the old files
+	StorageRelocateSkip = "skip" // Accept ISO_DIR as is, e.g. after moving the files by hand
+)
+
+// StorageRelocateModes lists the valid STORAGE_RELOCATE values.
+var StorageRelocateModes = []string{StorageRelocateCopy, StorageRelocateMove, StorageRelocateSkip}
+
+// How much of a download client's IP is kept with its download event.
+const (
+	AnalyticsClientIPFull     = "full"     // Keep the IP as is
+	AnalyticsClientIPTruncate = "truncate" // Zero all but the first 24 (IPv4) or 48 (IPv6) bits
+	AnalyticsClientIPHash     = "hash"     // Keep a keyed hash that tells clients apart without revealing them
+	AnalyticsClientIPNone     = "none"     // Keep nothing
+)
+
+// AnalyticsClientIPModes lists the valid ANALYTICS_CLIENT_IP values.
+var AnalyticsClientIPModes = []string{AnalyticsClientIPFull, AnalyticsClientIPTruncate, AnalyticsClientIPHash, AnalyticsClientIPNone}
+
+// Symlink policies for the /images tree.
+const (
+	SymlinkPolicyWithin = "within" // Follow symlinks whose target stays inside the ISO directory
+	SymlinkPolicyDeny   = "deny"   // Never follow symlinks
+)
+
+// SymlinkPolicies lists the valid symlink policies.
+var SymlinkPolicies = []string{SymlinkPolicyWithin, SymlinkPolicyDeny}
+
+// Robots policies for the generated /robots.txt.
+const (
+	RobotsPolicyAllow          = "allow"           // Allow crawling everything
+	RobotsPolicyDisallowImages = "disallow-images" // Keep crawlers out of /images
+	RobotsPolicyDisallowAll    = "disallow-all"    // Keep crawlers out entirely
+)
+
+// RobotsPolicies lists the valid robots policies.
+var RobotsPolicies = []string{RobotsPolicyAllow, RobotsPolicyDisallowImages, RobotsPolicyDisallowAll}
+
+// Columns of the /images directory listing. Status, checksum, and downloads
+// are only filled for files isoman manages.
+const (
+	ListingColumnStatus    = "status"    // ISO status and verification badges
+	ListingColumnChecksum  = "checksum"  // Checksum the ISO was verified against
+	ListingColumnDownloads = "downloads" // Download count
+	ListingColumnSize      = "size"
+	ListingColumnModified  = "modified"
+)
+
+// ListingColumns lists the valid listing columns in the order they are shown.
+var ListingColumns = []string{ListingColumnStatus, ListingColumnChecksum, ListingColumnDownloads, ListingColumnSize, ListingColumnModified}
+
+// DefaultListingColumns are the listing columns shown until others are chosen.
+var DefaultListingColumns = []string{ListingColumnStatus, ListingColumnDownloads, ListingColumnSize, ListingColumnModified}
+
+// Endpoint scopes that can stay public while authentication is enabled.
+const (
+	AuthScopeImages = "images" // /images listings and downloads
+	AuthScopeStats  = "stats"  // GET /api/stats, /api/stats/trends, /api/stats/live
+	AuthScopeISOs   = "isos"   // GET /api/isos and /api/isos/:id
+	AuthScopeWS     = "ws"     // /ws progress updates
+	AuthScopeFeed   = "feed"   // /feed.xml of recently completed ISOs
+	AuthScopeNone   = "none"   // Placeholder for requiring a session everywhere
+)
+
+// AuthScopes lists the valid public endpoint scopes.
+var AuthScopes = []string{AuthScopeImages, AuthScopeStats, AuthScopeISOs, AuthScopeWS, AuthScopeFeed, AuthScopeNone}
+
+// ReservedHiddenNames are always hidden under /images, whatever HIDDEN_FILES says,
+// because they hold temp, deleted, quarantined, archived, or partially copied files.
+var ReservedHiddenNames = []string{".tmp", ".trash", ".quarantine", ".versions", ".*.partial"}
+
 // Checksum file extensions.
 var ChecksumExtensions = []string{".sha256", ".sha512", ".md5"}
 
+// Detached signature file extensions: binary and ASCII-armored.
+var SignatureExtensions = []string{".sig", ".asc"}
+
+// SidecarExtensions are the checksum and signature files kept beside an ISO.
+// SIDECAR_EXTENSIONS adds to them.
+var SidecarExtensions = append(append([]string{}, ChecksumExtensions...), SignatureExtensions...)
+
 // Default configuration values.
 const (
 	// Download settings.
-	DefaultWorkerCount              = 2
-	DefaultQueueBuffer              = 100
-	DefaultDownloadBufferSize       = 32 * 1024 // 32KB
-	DefaultMaxRetries               = 5
-	DefaultRetryDelayMs             = 100
-	DefaultProgressPercentThreshold = 1
+	DefaultWorkerCount                = 2
+	DefaultVerifyWorkerCount          = 1
+	DefaultFastLaneWorkers            = 0 // 0 disables the fast lane for small downloads
+	DefaultFastLaneMaxMB              = 100
+	FastLaneProbeTimeoutSec           = 5 // HEAD request sizing a download for the fast lane
+	DefaultQueueBuffer                = 100
+	DefaultDownloadBufferSize         = 32 * 1024 // 32KB
+	DefaultMaxRetries                 = 5
+	DefaultRetryDelayMs               = 100
+	DefaultProgressPercentThreshold   = 1
+	DefaultProgressPersistIntervalSec = 3
+	DefaultMaxDownloadDurationMin     = 720 // 12 hours; 0 disables the limit
+	DefaultStallTimeoutSec            = 60
+	DefaultUpstreamCheckIntervalMin   = 0 // Disabled
+	DefaultKeepVersions               = 1 // Previous files kept when a refresh replaces an ISO
+	DefaultIntegrityHash              = IntegrityHashBLAKE2b
+	DefaultClamAVTimeoutSec           = 60
+	DefaultTempCleanupIntervalMin     = 60 // 0 disables the temp janitor
+	DefaultTempMaxAgeHours            = 24
+	DefaultDownloadLockTTLSec         = 60 // Renewed every third of this while a download runs
+	DefaultQueuePollIntervalSec       = 0  // 0 leaves each instance with only the ISOs it queued
+	DefaultStaleDownloadTimeoutMin    = 30 // 0 disables the stale download watchdog
+	DefaultChecksumRetryIntervalMin   = 30 // 0 leaves ISOs whose checksum file was unavailable unverified
+	StaleDownloadCheckIntervalSec     = 60
+
+	// Upstream HTTP client settings.
+	DefaultHTTPConnectTimeoutSec        = 30
+	DefaultHTTPTLSHandshakeTimeoutSec   = 10
+	DefaultHTTPResponseHeaderTimeoutSec = 60
+	DefaultHTTPIdleConnTimeoutSec       = 90
+	DefaultHTTPMaxIdleConns             = 100
+	DefaultHTTPMaxIdleConnsPerHost      = 4
+	DefaultDNSCacheTTLSec               = 0 // Disabled
+	DefaultHTTPMinHostIntervalMs        = 0 // Disabled
+	DefaultHTTPRetryAfterMaxSec         = 30
+	HTTPRetryAfterRetries               = 1 // Times a request is sent again after waiting out a Retry-After
+	DefaultMirrorRotation               = MirrorRotationNone
+	DefaultURLAllowedSchemes            = "http,https"
 
 	// HTTP server settings.
-	DefaultPort               = "8080"
-	DefaultReadTimeoutSec     = 15
-	DefaultWriteTimeoutSec    = 600 // 10 minutes — large cloud images can be 1-2GB
-	DefaultIdleTimeoutSec     = 60
-	DefaultShutdownTimeoutSec = 5
+	DefaultHiddenFiles                 = ".*" // Comma-separated glob patterns
+	DefaultSymlinkPolicy               = SymlinkPolicyWithin
+	DefaultListingCacheTTLSec          = 30 // 0 disables listing caching
+	DefaultRobotsPolicy                = RobotsPolicyDisallowImages
+	DefaultLocale                      = "en"
+	DefaultThroughputSampleIntervalSec = 2  // 0 disables live throughput sampling
+	DefaultHealthCheckIntervalSec      = 60 // 0 disables the health monitor
+	DefaultStorageLowThresholdMB       = 1024
+	DefaultPort                        = "8080"
+	DefaultReadTimeoutSec              = 15
+	DefaultWriteTimeoutSec             = 600 // 10 minutes — large cloud images can be 1-2GB
+	DefaultIdleTimeoutSec              = 60
+	DefaultShutdownTimeoutSec          = 5
+	DefaultMaxBodyKB                   = 4096      // JSON request bodies
+	DefaultMaxUploadMB                 = 64 * 1024 // Streamed uploads such as bundles; 0 disables the limit
+
+	// Authentication settings.
+	DefaultSessionTTLHours  = 24
+	MinPasswordLength       = 8
+	DefaultAuthPublicScopes = AuthScopeImages // Comma-separated
+	DefaultAuthMaxFailures  = 10              // Failures per IP before a lockout; 0 disables
+	DefaultAuthLockoutMin   = 15
+
+	// Download analytics.
+	DefaultAnalyticsClientIP      = AnalyticsClientIPTruncate
+	DefaultAnalyticsRetentionDays = 0 // 0 keeps download events forever
+
+	// Download links.
+	DefaultDownloadLinkTTLHours = 24
+	MaxDownloadLinkTTLHours     = 30 * 24
+
+	// Inbound webhooks.
+	MaxWebhookPayloadBytes = 1 << 20
+
+	// Scheduled report emails.
+	DefaultReportSchedule = "0 8 * * 1" // Mondays at 08:00 server time
+	DefaultSMTPPort       = 587
+	DefaultSMTPTimeoutSec = 30
+
+	// Failure notification emails.
+	DefaultNotifyDigestWindowSec = 300
+
+	// Catalog snapshots for the changelog.
+	DefaultCatalogSnapshotSchedule      = "0 0 * * *" // Daily at midnight server time
+	DefaultCatalogSnapshotRetentionDays = 90          // 0 keeps snapshots forever
+
+	// Read-only replicas.
+	DefaultReplicaSyncIntervalSec = 300
+	ReplicaFetchTimeoutSec        = 300 // The primary hashes sidecar files while building the manifest
+
+	// Startup self-test.
+	DefaultSelfTestProbeTimeoutSec = 10
+
+	// Signed repository metadata.
+	DefaultRepoRootExpiryDays    = 365
+	DefaultRepoTargetsExpiryDays = 7
+
+	// CDN purge hooks.
+	CDNPurgeTimeoutSec = 10
 
 	// Database settings.
 	DefaultBusyTimeoutMs      = 5000
+	DefaultBusyRetries        = 4 // After 50, 100, 200, and 400ms
 	DefaultJournalMode        = "WAL"
 	DefaultMaxOpenConns       = 25
 	DefaultMaxIdleConns       = 5
@@ -43,7 +247,12 @@ const (
 	DefaultBroadcastChannelSize = 256
 
 	// Cancellation settings.
-	DefaultCancellationWaitMs = 100
+	DefaultCancellationWaitMs = 5000 // Upper bound; DeleteISO returns as soon as the worker stops
+
+	// Long-polling GET /api/isos/:id/wait.
+	DefaultWaitTimeoutSec = 300
+	MaxWaitTimeoutSec     = 3600
+	WaitPollIntervalSec   = 5 // Rereads the ISO for changes made by other instances
 )
 
 // IsSupportedFileType checks if a file type is supported.
@@ -67,3 +276,116 @@ func IsValidChecksumType(checksumType string) bool {
 	}
 	return false
 }
+
+// ChecksumTypeForDigest returns the checksum type whose hex digests are as
+// long as digest, or "" if none is.
+func ChecksumTypeForDigest(digest string) string {
+	switch len(digest) {
+	case 32:
+		return "md5"
+	case 64:
+		return "sha256"
+	case 128:
+		return "sha512"
+	}
+	return ""
+}
+
+// IsValidIPFamily checks if an IP family preference is valid.
+func IsValidIPFamily(family string) bool {
+	family = strings.ToLower(family)
+	for _, valid := range IPFamilies {
+		if family == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidMirrorRotation checks if a mirror rotation policy is valid.
+func IsValidMirrorRotation(policy string) bool {
+	policy = strings.ToLower(policy)
+	for _, valid := range MirrorRotations {
+		if policy == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidIntegrityHash checks if an integrity hash algorithm is valid.
+func IsValidIntegrityHash(algorithm string) bool {
+	algorithm = strings.ToLower(algorithm)
+	for _, valid := range IntegrityHashes {
+		if algorithm == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidSymlinkPolicy checks if a symlink policy is valid.
+func IsValidSymlinkPolicy(policy string) bool {
+	policy = strings.ToLower(policy)
+	for _, valid := range SymlinkPolicies {
+		if policy == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidRobotsPolicy checks if a robots policy is valid.
+func IsValidRobotsPolicy(policy string) bool {
+	policy = strings.ToLower(policy)
+	for _, valid := range RobotsPolicies {
+		if policy == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidListingColumn checks if a listing column is valid.
+func IsValidListingColumn(column string) bool {
+	column = strings.ToLower(column)
+	for _, valid := range ListingColumns {
+		if column == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidAuthScope checks if a public endpoint scope is valid.
+func IsValidAuthScope(scope string) bool {
+	scope = strings.ToLower(scope)
+	for _, valid := range AuthScopes {
+		if scope == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidStorageRelocateMode checks if a STORAGE_RELOCATE value is valid.
+func IsValidStorageRelocateMode(mode string) bool {
+	mode = strings.ToLower(mode)
+	for _, valid := range StorageRelocateModes {
+		if mode == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidAnalyticsClientIPMode checks if an ANALYTICS_CLIENT_IP value is valid.
+func IsValidAnalyticsClientIPMode(mode string) bool {
+	mode = strings.ToLower(mode)
+	for _, valid := range AnalyticsClientIPModes {
+		if mode == valid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// RecordAuditEvent appends an event to the audit log.
+func (db *DB) RecordAuditEvent(event *models.AuditEvent) error {
+	query := `INSERT INTO audit_log (action, target_id, details, reason, client_ip, created_at) VALUES (?, ?, ?, ?, ?, ?)`
+	result, err := db.conn.Exec(query, event.Action, event.TargetID, event.Details, event.Reason, event.ClientIP, event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record audit event (action=%s): %w", event.Action, err)
+	}
+	if event.ID, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("failed to get audit event id: %w", err)
+	}
+	return nil
+}
+
+// ListAuditEvents retrieves the most recent audit events, newest first.
+func (db *DB) ListAuditEvents(limit int) ([]models.AuditEvent, error) {
+	query := `SELECT id, action, target_id, details, reason, client_ip, created_at FROM audit_log ORDER BY created_at DESC, id DESC LIMIT ?`
+	rows, err := db.conn.Query(query, limit) //nolint:sqlclosecheck
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer closeRows(rows)
+
+	events := make([]models.AuditEvent, 0)
+	for rows.Next() {
+		var e models.AuditEvent
+		if err := rows.Scan(&e.ID, &e.Action, &e.TargetID, &e.Details, &e.Reason, &e.ClientIP, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+func TestAuditLog(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	first := &models.AuditEvent{Action: models.AuditActionStatsAdjust, TargetID: "iso-1", Details: "download_count 5 -> 3", CreatedAt: now.Add(-time.Minute)}
+	second := &models.AuditEvent{Action: models.AuditActionStatsReset, TargetID: "iso-1", Reason: "test storm", CreatedAt: now}
+	for _, e := range []*models.AuditEvent{first, second} {
+		if err := db.RecordAuditEvent(e); err != nil {
+			t.Fatalf("RecordAuditEvent() failed: %v", err)
+		}
+		if e.ID == 0 {
+			t.Error("RecordAuditEvent() should set the event ID")
+		}
+	}
+
+	events, err := db.ListAuditEvents(10)
+	if err != nil {
+		t.Fatalf("ListAuditEvents() failed: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	if events[0].Action != models.AuditActionStatsReset || events[0].Reason != "test storm" {
+		t.Errorf("Expected newest event first, got %+v", events[0])
+	}
+	if events[1].Details != "download_count 5 -> 3" {
+		t.Errorf("Expected details to round-trip, got %q", events[1].Details)
+	}
+
+	events, err = db.ListAuditEvents(1)
+	if err != nil {
+		t.Fatalf("ListAuditEvents() failed: %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("Expected limit to be applied, got %d events", len(events))
+	}
+}
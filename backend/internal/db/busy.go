@@ -0,0 +1,107 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/clock"
+
+	sqlitedriver "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// busyRetryDelay is the wait before the first retry of a busy write; it
+// doubles with each further retry.
+const busyRetryDelay = 50 * time.Millisecond
+
+// busyConn is the connection pool. Its Exec retries statements that fail
+// because the database is busy, so every write made outside a transaction
+// survives a competing writer; transactions go through DB.inTx instead.
+type busyConn struct {
+	*sql.DB
+	retries int         // Retries after the first attempt; 0 disables retrying
+	writer  *writer     // Set in single-writer mode
+	clock   clock.Clock // Paces the retries
+}
+
+// Exec executes a statement, retrying while the database is busy.
+func (c *busyConn) Exec(query string, args ...any) (sql.Result, error) {
+	var result sql.Result
+	err := c.write(func() error {
+		var err error
+		result, err = c.DB.Exec(query, args...)
+		return err
+	})
+	return result, err
+}
+
+// retryBusy runs op, running it again with exponential backoff while it
+// fails because the database is busy. busy_timeout already waits inside
+// SQLite, but gives up early in some cases, e.g. when a transaction that
+// read first can't upgrade to a write lock, and a long write can outlast it.
+func (c *busyConn) retryBusy(op func() error) error {
+	delay := busyRetryDelay
+	err := op()
+	for i := 0; i < c.retries && isBusy(err); i++ {
+		slog.Debug("database busy, retrying", slog.Int("retry", i+1), slog.Duration("delay", delay), slog.Any("error", err))
+		c.clock.Sleep(delay)
+		delay *= 2
+		err = op()
+	}
+	return err
+}
+
+// SetClock replaces the clock that paces retries of busy writes, e.g. with a
+// clock.Fake in tests.
+func (db *DB) SetClock(c clock.Clock) {
+	db.conn.clock = c
+}
+
+// write runs op, which writes, with retries, on the single writer
+// goroutine in single-writer mode.
+func (c *busyConn) write(op func() error) error {
+	if c.writer == nil {
+		return c.retryBusy(op)
+	}
+	return c.writer.do(func() error { return c.retryBusy(op) })
+}
+
+// inTx runs fn in a transaction and commits it, rolling back if fn fails.
+// The whole transaction is run again while the database is busy, so fn must
+// only touch the database, and only through tx. what names the transaction
+// in errors and logs.
+func (db *DB) inTx(what string, fn func(tx *sql.Tx) error) error {
+	return db.conn.write(func() error {
+		tx, err := db.conn.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer func() {
+			if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+				slog.Warn("failed to roll back "+what, slog.Any("error", err))
+			}
+		}()
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit %s: %w", what, err)
+		}
+		return nil
+	})
+}
+
+// isBusy reports whether err is SQLite failing to get a lock: SQLITE_BUSY or
+// SQLITE_LOCKED, including their extended codes.
+func isBusy(err error) bool {
+	var sqliteErr *sqlitedriver.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	code := sqliteErr.Code() & 0xff // Extended codes keep the primary code in the low byte
+	return code == sqlite3.SQLITE_BUSY || code == sqlite3.SQLITE_LOCKED
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/clock"
+	"github.com/aloks98/isoman/backend/internal/config"
+)
+
+func TestBusyRetry(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	cfg := config.Load().Database
+	cfg.BusyTimeout = 10 * time.Millisecond
+	cfg.BusyRetries = 4
+	db, err := New(dbPath, &cfg)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer db.Close()
+	fake := clock.NewFake(time.Now())
+	db.SetClock(fake)
+
+	// Another process holding the write lock, e.g. a second instance
+	other, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open second connection: %v", err)
+	}
+	defer other.Close()
+	other.SetMaxOpenConns(1)
+	lock := func() *sql.Tx {
+		t.Helper()
+		tx, err := other.Begin()
+		if err != nil {
+			t.Fatalf("Begin() failed: %v", err)
+		}
+		if _, err := tx.Exec(`INSERT INTO settings (key, value, updated_at) VALUES ('lock', '', CURRENT_TIMESTAMP)`); err != nil {
+			t.Fatalf("Failed to take the write lock: %v", err)
+		}
+		return tx
+	}
+	// underLock runs write while the lock is held past busy_timeout, and
+	// releases it once write is waiting to retry
+	underLock := func(write func() error) error {
+		t.Helper()
+		tx := lock()
+		done := make(chan error, 1)
+		go func() { done <- write() }()
+		fake.BlockUntil(1)
+		_ = tx.Rollback()
+		fake.Advance(busyRetryDelay)
+		return <-done
+	}
+
+	// Plain writes and transactions wait out the lock
+	if err := underLock(func() error { return db.SetSetting("key", "value") }); err != nil {
+		t.Errorf("SetSetting() under a held lock failed: %v", err)
+	}
+	if err := underLock(func() error { return db.SwitchISODir("/srv/isos", nil) }); err != nil {
+		t.Errorf("SwitchISODir() under a held lock failed: %v", err)
+	}
+
+	// Without retries the busy error comes through
+	db.conn.retries = 0
+	tx := lock()
+	defer tx.Rollback() //nolint:errcheck
+	if err := db.SetSetting("key", "value"); !isBusy(err) {
+		t.Errorf("Expected a busy error without retries, got %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// ErrCredentialNotFound is returned when no credential has the requested name.
+var ErrCredentialNotFound = errors.New("credential not found")
+
+const credentialSelectFields = `name, type, host, secret, created_at, updated_at`
+
+func scanCredential(s scanner) (*models.Credential, error) {
+	cred := &models.Credential{}
+	if err := s.Scan(&cred.Name, &cred.Type, &cred.Host, &cred.Secret, &cred.CreatedAt, &cred.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return cred, nil
+}
+
+// CreateCredential inserts a new credential.
+func (db *DB) CreateCredential(cred *models.Credential) error {
+	query := `INSERT INTO credentials (name, type, host, secret, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`
+	if _, err := db.conn.Exec(query, cred.Name, cred.Type, cred.Host, cred.Secret, cred.CreatedAt, cred.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to insert credential (name=%s): %w", cred.Name, err)
+	}
+	return nil
+}
+
+// GetCredential retrieves a credential by name.
+func (db *DB) GetCredential(name string) (*models.Credential, error) {
+	query := fmt.Sprintf("SELECT %s FROM credentials WHERE name = ?", credentialSelectFields)
+	cred, err := scanCredential(db.conn.QueryRow(query, name))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w (name=%s)", ErrCredentialNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan credential (name=%s): %w", name, err)
+	}
+	return cred, nil
+}
+
+// GetCredentialForHost retrieves the credential bound to host, or nil when
+// there is none. When several match, the oldest wins.
+func (db *DB) GetCredentialForHost(host string) (*models.Credential, error) {
+	query := fmt.Sprintf("SELECT %s FROM credentials WHERE host = ? ORDER BY created_at, name LIMIT 1", credentialSelectFields)
+	cred, err := scanCredential(db.conn.QueryRow(query, host))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan credential (host=%s): %w", host, err)
+	}
+	return cred, nil
+}
+
+// ListCredentials retrieves all credentials ordered by name.
+func (db *DB) ListCredentials() ([]models.Credential, error) {
+	query := fmt.Sprintf("SELECT %s FROM credentials ORDER BY name", credentialSelectFields)
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query credentials: %w", err)
+	}
+	defer rows.Close()
+
+	creds := []models.Credential{}
+	for rows.Next() {
+		cred, err := scanCredential(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan credential: %w", err)
+		}
+		creds = append(creds, *cred)
+	}
+	return creds, rows.Err()
+}
+
+// UpdateCredential replaces the type, host, and secret of a credential.
+func (db *DB) UpdateCredential(cred *models.Credential) error {
+	query := `UPDATE credentials SET type = ?, host = ?, secret = ?, updated_at = ? WHERE name = ?`
+	result, err := db.conn.Exec(query, cred.Type, cred.Host, cred.Secret, cred.UpdatedAt, cred.Name)
+	if err != nil {
+		return fmt.Errorf("failed to update credential (name=%s): %w", cred.Name, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w (name=%s)", ErrCredentialNotFound, cred.Name)
+	}
+	return nil
+}
+
+// DeleteCredential removes a credential.
+func (db *DB) DeleteCredential(name string) error {
+	result, err := db.conn.Exec("DELETE FROM credentials WHERE name = ?", name)
+	if err != nil {
+		return fmt.Errorf("failed to delete credential (name=%s): %w", name, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w (name=%s)", ErrCredentialNotFound, name)
+	}
+	return nil
+}
+
+// CountISOsUsingCredential returns how many ISOs reference a credential by name.
+func (db *DB) CountISOsUsingCredential(name string) (int, error) {
+	var count int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM isos WHERE credential = ?", name).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count ISOs using credential (name=%s): %w", name, err)
+	}
+	return count, nil
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+func TestCredentials(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	mirror := &models.Credential{Name: "mirror", Type: models.CredentialTypeBasic, Host: "mirror.example.com", Secret: "v1:a", CreatedAt: now, UpdatedAt: now}
+	token := &models.Credential{Name: "api-token", Type: models.CredentialTypeToken, Secret: "v1:b", CreatedAt: now, UpdatedAt: now}
+	for _, cred := range []*models.Credential{mirror, token} {
+		if err := db.CreateCredential(cred); err != nil {
+			t.Fatalf("CreateCredential() failed: %v", err)
+		}
+	}
+	if err := db.CreateCredential(mirror); err == nil {
+		t.Error("CreateCredential() should reject a duplicate name")
+	}
+
+	creds, err := db.ListCredentials()
+	if err != nil {
+		t.Fatalf("ListCredentials() failed: %v", err)
+	}
+	if len(creds) != 2 || creds[0].Name != "api-token" {
+		t.Errorf("Expected 2 credentials ordered by name, got %+v", creds)
+	}
+
+	got, err := db.GetCredentialForHost("mirror.example.com")
+	if err != nil || got == nil || got.Name != "mirror" {
+		t.Errorf("GetCredentialForHost() = %+v, %v; want mirror", got, err)
+	}
+	if got, err := db.GetCredentialForHost("other.example.com"); err != nil || got != nil {
+		t.Errorf("GetCredentialForHost() = %+v, %v; want nil", got, err)
+	}
+
+	iso := createTestISO()
+	iso.Credential = "mirror"
+	if err := db.CreateISO(iso); err != nil {
+		t.Fatalf("CreateISO() failed: %v", err)
+	}
+	if stored, _ := db.GetISO(iso.ID); stored.Credential != "mirror" {
+		t.Errorf("Expected ISO credential to round-trip, got %q", stored.Credential)
+	}
+	if count, err := db.CountISOsUsingCredential("mirror"); err != nil || count != 1 {
+		t.Errorf("CountISOsUsingCredential() = %d, %v; want 1", count, err)
+	}
+
+	mirror.Host = ""
+	mirror.Secret = "v1:c"
+	if err := db.UpdateCredential(mirror); err != nil {
+		t.Fatalf("UpdateCredential() failed: %v", err)
+	}
+	got, err = db.GetCredential("mirror")
+	if err != nil {
+		t.Fatalf("GetCredential() failed: %v", err)
+	}
+	if got.Host != "" || got.Secret != "v1:c" {
+		t.Errorf("Expected updated host and secret, got %+v", got)
+	}
+
+	if err := db.DeleteCredential("api-token"); err != nil {
+		t.Fatalf("DeleteCredential() failed: %v", err)
+	}
+	if _, err := db.GetCredential("api-token"); !errors.Is(err, ErrCredentialNotFound) {
+		t.Errorf("GetCredential() error = %v, want ErrCredentialNotFound", err)
+	}
+	if err := db.DeleteCredential("api-token"); !errors.Is(err, ErrCredentialNotFound) {
+		t.Errorf("DeleteCredential() error = %v, want ErrCredentialNotFound", err)
+	}
+}
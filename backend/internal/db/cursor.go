@@ -0,0 +1,49 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// ErrInvalidCursor is returned for a pagination cursor that wasn't issued by
+// ListISOsPaginated.
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// isoCursor is the position after the last ISO of a page, in the
+// (pinned, created_at, id) order the list is walked in. Encoded, it is opaque
+// to clients.
+type isoCursor struct {
+	CreatedAt string `json:"c"` // As stored, so it compares exactly like the column
+	ID        string `json:"i"`
+	Pinned    bool   `json:"p"`
+	Asc       bool   `json:"a"` // created_at ascending; pinned ISOs always come first
+}
+
+// newISOCursor returns the cursor for the page after iso.
+func (db *DB) newISOCursor(iso *models.ISO, asc bool) (string, error) {
+	cursor := isoCursor{ID: iso.ID, Pinned: iso.Pinned, Asc: asc}
+	if err := db.conn.QueryRow(`SELECT CAST(created_at AS TEXT) FROM isos WHERE id = ?`, iso.ID).Scan(&cursor.CreatedAt); err != nil {
+		return "", fmt.Errorf("failed to read ISO position (id=%s): %w", iso.ID, err)
+	}
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func parseISOCursor(s string) (*isoCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor isoCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
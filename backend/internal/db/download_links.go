@@ -0,0 +1,108 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// ErrDownloadLinkNotFound is returned when a download link doesn't exist.
+var ErrDownloadLinkNotFound = errors.New("download link not found")
+
+const downloadLinkSelectFields = `id, path, single_use, created_by, created_at, expires_at, used_at`
+
+func scanDownloadLink(s scanner) (*models.DownloadLink, error) {
+	link := &models.DownloadLink{}
+	if err := s.Scan(&link.ID, &link.Path, &link.SingleUse, &link.CreatedBy, &link.CreatedAt, &link.ExpiresAt, &link.UsedAt); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// CreateDownloadLink inserts a new download link.
+func (db *DB) CreateDownloadLink(link *models.DownloadLink) error {
+	query := `INSERT INTO download_links (id, path, single_use, created_by, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)`
+	if _, err := db.conn.Exec(query, link.ID, link.Path, link.SingleUse, link.CreatedBy, link.CreatedAt, link.ExpiresAt); err != nil {
+		return fmt.Errorf("failed to insert download link (path=%s): %w", link.Path, err)
+	}
+	return nil
+}
+
+// GetDownloadLink retrieves a download link by ID, expired or used ones included.
+func (db *DB) GetDownloadLink(id string) (*models.DownloadLink, error) {
+	query := fmt.Sprintf("SELECT %s FROM download_links WHERE id = ?", downloadLinkSelectFields)
+	link, err := scanDownloadLink(db.conn.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrDownloadLinkNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan download link: %w", err)
+	}
+	return link, nil
+}
+
+// ListDownloadLinks retrieves the download links that haven't expired by now,
+// newest first.
+func (db *DB) ListDownloadLinks(now time.Time) ([]models.DownloadLink, error) {
+	query := fmt.Sprintf("SELECT %s FROM download_links WHERE expires_at > ? ORDER BY created_at DESC", downloadLinkSelectFields)
+	rows, err := db.conn.Query(query, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query download links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []models.DownloadLink{}
+	for rows.Next() {
+		link, err := scanDownloadLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan download link: %w", err)
+		}
+		links = append(links, *link)
+	}
+	return links, rows.Err()
+}
+
+// BurnDownloadLink marks a download link used at now. It reports false when
+// the link was already used or doesn't exist.
+func (db *DB) BurnDownloadLink(id string, now time.Time) (bool, error) {
+	result, err := db.conn.Exec("UPDATE download_links SET used_at = ? WHERE id = ? AND used_at IS NULL", now, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to burn download link: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// ReleaseDownloadLink clears a download link's used_at, so it can be used again.
+func (db *DB) ReleaseDownloadLink(id string) error {
+	if _, err := db.conn.Exec("UPDATE download_links SET used_at = NULL WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to release download link: %w", err)
+	}
+	return nil
+}
+
+// DeleteDownloadLink removes a download link, returning ErrDownloadLinkNotFound
+// when there is none.
+func (db *DB) DeleteDownloadLink(id string) error {
+	result, err := db.conn.Exec("DELETE FROM download_links WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete download link: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w (id=%s)", ErrDownloadLinkNotFound, id)
+	}
+	return nil
+}
+
+// DeleteExpiredDownloadLinks removes download links that expired before now
+// and returns how many were removed.
+func (db *DB) DeleteExpiredDownloadLinks(now time.Time) (int64, error) {
+	result, err := db.conn.Exec("DELETE FROM download_links WHERE expires_at <= ?", now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired download links: %w", err)
+	}
+	return result.RowsAffected()
+}
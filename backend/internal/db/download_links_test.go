@@ -0,0 +1,61 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+func TestDownloadLinks(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	live := &models.DownloadLink{ID: "live", Path: "alpine/alpine.iso", SingleUse: true, CreatedBy: "admin", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	expired := &models.DownloadLink{ID: "expired", Path: "alpine/alpine.iso", CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour)}
+	for _, link := range []*models.DownloadLink{live, expired} {
+		if err := db.CreateDownloadLink(link); err != nil {
+			t.Fatalf("CreateDownloadLink() failed: %v", err)
+		}
+	}
+
+	links, err := db.ListDownloadLinks(now)
+	if err != nil {
+		t.Fatalf("ListDownloadLinks() failed: %v", err)
+	}
+	if len(links) != 1 || links[0].ID != "live" || !links[0].SingleUse || links[0].UsedAt != nil {
+		t.Errorf("ListDownloadLinks() = %+v, want only the live link", links)
+	}
+
+	if burned, err := db.BurnDownloadLink("live", now); err != nil || !burned {
+		t.Errorf("BurnDownloadLink() = %v, %v; want true", burned, err)
+	}
+	if burned, _ := db.BurnDownloadLink("live", now); burned {
+		t.Error("BurnDownloadLink() burned a link twice")
+	}
+	got, err := db.GetDownloadLink("live")
+	if err != nil || got.UsedAt == nil {
+		t.Errorf("Expected a used_at after burning, got %+v (%v)", got, err)
+	}
+	if err := db.ReleaseDownloadLink("live"); err != nil {
+		t.Fatalf("ReleaseDownloadLink() failed: %v", err)
+	}
+	if burned, _ := db.BurnDownloadLink("live", now); !burned {
+		t.Error("Expected a released link to be burnable again")
+	}
+
+	if n, err := db.DeleteExpiredDownloadLinks(now); err != nil || n != 1 {
+		t.Errorf("DeleteExpiredDownloadLinks() = %d, %v; want 1", n, err)
+	}
+	if err := db.DeleteDownloadLink("live"); err != nil {
+		t.Fatalf("DeleteDownloadLink() failed: %v", err)
+	}
+	if _, err := db.GetDownloadLink("live"); !errors.Is(err, ErrDownloadLinkNotFound) {
+		t.Errorf("Expected ErrDownloadLinkNotFound, got: %v", err)
+	}
+	if err := db.DeleteDownloadLink("live"); !errors.Is(err, ErrDownloadLinkNotFound) {
+		t.Errorf("Expected ErrDownloadLinkNotFound deleting twice, got: %v", err)
+	}
+}
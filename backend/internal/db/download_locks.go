@@ -0,0 +1,120 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// ErrDownloadLocked is returned when another node holds an ISO's download lock.
+var ErrDownloadLocked = errors.New("download is locked by another node")
+
+// lockTime formats a lease time so that stored times compare as strings.
+func lockTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// AcquireDownloadLock leases an ISO's download to node for ttl. A node may
+// re-acquire its own lock, and anyone may take over an expired one; otherwise
+// it returns ErrDownloadLocked.
+func (db *DB) AcquireDownloadLock(isoID, node string, ttl time.Duration) error {
+	now := time.Now()
+	query := `
+		INSERT INTO download_locks (iso_id, node, acquired_at, expires_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(iso_id) DO UPDATE SET
+			node = excluded.node, acquired_at = excluded.acquired_at, expires_at = excluded.expires_at
+		WHERE download_locks.node = excluded.node OR download_locks.expires_at < excluded.acquired_at
+	`
+	result, err := db.conn.Exec(query, isoID, node, lockTime(now), lockTime(now.Add(ttl)))
+	if err != nil {
+		return fmt.Errorf("failed to acquire download lock (iso_id=%s): %w", isoID, err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to acquire download lock (iso_id=%s): %w", isoID, err)
+	} else if n == 0 {
+		return fmt.Errorf("%w (iso_id=%s)", ErrDownloadLocked, isoID)
+	}
+	return nil
+}
+
+// RenewDownloadLock extends node's lease on an ISO's download by ttl from
+// now. It returns ErrDownloadLocked if node no longer holds the lock.
+func (db *DB) RenewDownloadLock(isoID, node string, ttl time.Duration) error {
+	query := `UPDATE download_locks SET expires_at = ? WHERE iso_id = ? AND node = ?`
+	result, err := db.conn.Exec(query, lockTime(time.Now().Add(ttl)), isoID, node)
+	if err != nil {
+		return fmt.Errorf("failed to renew download lock (iso_id=%s): %w", isoID, err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to renew download lock (iso_id=%s): %w", isoID, err)
+	} else if n == 0 {
+		return fmt.Errorf("%w (iso_id=%s)", ErrDownloadLocked, isoID)
+	}
+	return nil
+}
+
+// ReleaseDownloadLock removes node's lock on an ISO's download. A lock held
+// by another node is left alone.
+func (db *DB) ReleaseDownloadLock(isoID, node string) error {
+	if _, err := db.conn.Exec(`DELETE FROM download_locks WHERE iso_id = ? AND node = ?`, isoID, node); err != nil {
+		return fmt.Errorf("failed to release download lock (iso_id=%s): %w", isoID, err)
+	}
+	return nil
+}
+
+// ListDownloadLocks retrieves the unexpired download locks, oldest first.
+func (db *DB) ListDownloadLocks() ([]models.DownloadLock, error) {
+	query := `SELECT iso_id, node, acquired_at, expires_at FROM download_locks WHERE expires_at >= ? ORDER BY acquired_at`
+	rows, err := db.conn.Query(query, lockTime(time.Now())) //nolint:sqlclosecheck
+	if err != nil {
+		return nil, fmt.Errorf("failed to query download locks: %w", err)
+	}
+	defer closeRows(rows)
+
+	locks := make([]models.DownloadLock, 0)
+	for rows.Next() {
+		var l models.DownloadLock
+		if err := rows.Scan(&l.ISOID, &l.Node, &l.AcquiredAt, &l.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan download lock: %w", err)
+		}
+		locks = append(locks, l)
+	}
+	return locks, rows.Err()
+}
+
+// ListUnlockedQueuedISOs retrieves up to limit queued ISOs that no node holds
+// an unexpired download lock on, oldest first.
+func (db *DB) ListUnlockedQueuedISOs(limit int) ([]models.ISO, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM isos
+		WHERE status = ? AND id NOT IN (SELECT iso_id FROM download_locks WHERE expires_at >= ?)
+		ORDER BY created_at ASC
+		LIMIT ?
+	`, isoSelectFields)
+	return db.queryISOs(query, models.StatusQueued, lockTime(time.Now()), limit)
+}
+
+// ListQueuedISOs retrieves every queued ISO, oldest first.
+func (db *DB) ListQueuedISOs() ([]models.ISO, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM isos
+		WHERE status = ?
+		ORDER BY created_at ASC
+	`, isoSelectFields)
+	return db.queryISOs(query, models.StatusQueued)
+}
+
+// ListStaleDownloads retrieves the downloading or verifying ISOs that no node
+// holds an unexpired download lock on and whose status or progress was last
+// written before the given time, oldest first.
+func (db *DB) ListStaleDownloads(before time.Time) ([]models.ISO, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM isos
+		WHERE status IN (?, ?) AND COALESCE(progress_at, created_at) < ?
+			AND id NOT IN (SELECT iso_id FROM download_locks WHERE expires_at >= ?)
+		ORDER BY created_at ASC
+	`, isoSelectFields)
+	return db.queryISOs(query, models.StatusDownloading, models.StatusVerifying, lockTime(before), lockTime(time.Now()))
+}
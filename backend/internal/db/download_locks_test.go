@@ -0,0 +1,87 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+func TestDownloadLock(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	iso := createTestISO()
+	db.CreateISO(iso)
+
+	if err := db.AcquireDownloadLock(iso.ID, "node-a", time.Minute); err != nil {
+		t.Fatalf("AcquireDownloadLock() failed: %v", err)
+	}
+	if err := db.AcquireDownloadLock(iso.ID, "node-a", time.Minute); err != nil {
+		t.Errorf("Expected the holder to re-acquire, got: %v", err)
+	}
+	if err := db.AcquireDownloadLock(iso.ID, "node-b", time.Minute); !errors.Is(err, ErrDownloadLocked) {
+		t.Errorf("Expected ErrDownloadLocked for another node, got: %v", err)
+	}
+	if err := db.RenewDownloadLock(iso.ID, "node-b", time.Minute); !errors.Is(err, ErrDownloadLocked) {
+		t.Errorf("Expected ErrDownloadLocked renewing another node's lock, got: %v", err)
+	}
+
+	locks, err := db.ListDownloadLocks()
+	if err != nil {
+		t.Fatalf("ListDownloadLocks() failed: %v", err)
+	}
+	if len(locks) != 1 || locks[0].Node != "node-a" || !locks[0].ExpiresAt.After(locks[0].AcquiredAt) {
+		t.Errorf("Expected node-a's lock, got %+v", locks)
+	}
+
+	// Another node's release leaves it alone, the holder's removes it
+	db.ReleaseDownloadLock(iso.ID, "node-b")
+	if err := db.AcquireDownloadLock(iso.ID, "node-b", time.Minute); !errors.Is(err, ErrDownloadLocked) {
+		t.Errorf("Expected the lock to survive another node's release, got: %v", err)
+	}
+	db.ReleaseDownloadLock(iso.ID, "node-a")
+	if err := db.AcquireDownloadLock(iso.ID, "node-b", time.Minute); err != nil {
+		t.Errorf("Expected a released lock to be free, got: %v", err)
+	}
+
+	// An expired lease can be taken over and isn't listed
+	if err := db.RenewDownloadLock(iso.ID, "node-b", -time.Hour); err != nil {
+		t.Fatalf("RenewDownloadLock() failed: %v", err)
+	}
+	if locks, _ := db.ListDownloadLocks(); len(locks) != 0 {
+		t.Errorf("Expected no unexpired locks, got %+v", locks)
+	}
+	if err := db.AcquireDownloadLock(iso.ID, "node-a", time.Minute); err != nil {
+		t.Errorf("Expected an expired lock to be taken over, got: %v", err)
+	}
+}
+
+func TestListUnlockedQueuedISOs(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	var ids []string
+	for i, version := range []string{"1.0", "2.0", "3.0"} {
+		iso := createTestISO()
+		iso.Version = version
+		iso.Status = models.StatusQueued
+		iso.CreatedAt = time.Now().Add(time.Duration(i) * time.Minute)
+		iso.ComputeFields()
+		db.CreateISO(iso)
+		ids = append(ids, iso.ID)
+	}
+	db.AcquireDownloadLock(ids[0], "node-a", time.Minute)
+
+	isos, err := db.ListUnlockedQueuedISOs(10)
+	if err != nil {
+		t.Fatalf("ListUnlockedQueuedISOs() failed: %v", err)
+	}
+	if len(isos) != 2 || isos[0].ID != ids[1] || isos[1].ID != ids[2] {
+		t.Errorf("Expected the unlocked ISOs oldest first, got %d", len(isos))
+	}
+	if isos, _ := db.ListUnlockedQueuedISOs(1); len(isos) != 1 {
+		t.Errorf("Expected the limit applied, got %d", len(isos))
+	}
+}
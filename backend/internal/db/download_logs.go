@@ -0,0 +1,49 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// AppendDownloadLog adds an entry to an ISO's download log and sets its ID.
+func (db *DB) AppendDownloadLog(entry *models.DownloadLogEntry) error {
+	query := `INSERT INTO download_logs (iso_id, logged_at, level, message) VALUES (?, ?, ?, ?)`
+	result, err := db.conn.Exec(query, entry.ISOID, entry.LoggedAt.Format(time.RFC3339), entry.Level, entry.Message)
+	if err != nil {
+		return fmt.Errorf("failed to append download log (iso_id=%s): %w", entry.ISOID, err)
+	}
+	if entry.ID, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("failed to get download log entry id: %w", err)
+	}
+	return nil
+}
+
+// ListDownloadLog retrieves an ISO's download log, oldest first.
+func (db *DB) ListDownloadLog(isoID string) ([]models.DownloadLogEntry, error) {
+	query := `SELECT id, iso_id, logged_at, level, message FROM download_logs WHERE iso_id = ? ORDER BY id`
+	rows, err := db.conn.Query(query, isoID) //nolint:sqlclosecheck
+	if err != nil {
+		return nil, fmt.Errorf("failed to query download log (iso_id=%s): %w", isoID, err)
+	}
+	defer closeRows(rows)
+
+	entries := make([]models.DownloadLogEntry, 0)
+	for rows.Next() {
+		var e models.DownloadLogEntry
+		if err := rows.Scan(&e.ID, &e.ISOID, &e.LoggedAt, &e.Level, &e.Message); err != nil {
+			return nil, fmt.Errorf("failed to scan download log entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// ClearDownloadLog deletes an ISO's download log, e.g. before a new run.
+func (db *DB) ClearDownloadLog(isoID string) error {
+	if _, err := db.conn.Exec(`DELETE FROM download_logs WHERE iso_id = ?`, isoID); err != nil {
+		return fmt.Errorf("failed to clear download log (iso_id=%s): %w", isoID, err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+func TestDownloadLog(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	iso := createTestISO()
+	db.CreateISO(iso)
+
+	for _, message := range []string{"Downloading", "Complete"} {
+		entry := &models.DownloadLogEntry{ISOID: iso.ID, LoggedAt: time.Now(), Level: models.LogLevelInfo, Message: message}
+		if err := db.AppendDownloadLog(entry); err != nil || entry.ID == 0 {
+			t.Fatalf("AppendDownloadLog() = %v, id %d", err, entry.ID)
+		}
+	}
+
+	entries, err := db.ListDownloadLog(iso.ID)
+	if err != nil {
+		t.Fatalf("ListDownloadLog() failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Message != "Downloading" || entries[1].Message != "Complete" || entries[0].LoggedAt.IsZero() {
+		t.Errorf("Expected both entries oldest first, got %+v", entries)
+	}
+
+	if err := db.DeleteISO(iso.ID); err != nil {
+		t.Fatalf("DeleteISO() failed: %v", err)
+	}
+	if entries, _ := db.ListDownloadLog(iso.ID); len(entries) != 0 {
+		t.Errorf("Expected the log deleted with the ISO, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// SystemEventsParams filters ListSystemEvents.
+type SystemEventsParams struct {
+	Since    time.Time // Zero returns events of any age
+	Severity string    // Empty returns every severity
+	Limit    int
+}
+
+// RecordSystemEvent appends an event to the system event log.
+func (db *DB) RecordSystemEvent(event *models.SystemEvent) error {
+	query := `INSERT INTO system_events (type, severity, message, created_at) VALUES (?, ?, ?, ?)`
+	result, err := db.conn.Exec(query, event.Type, event.Severity, event.Message, event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record system event (type=%s): %w", event.Type, err)
+	}
+	if event.ID, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("failed to get system event id: %w", err)
+	}
+	return nil
+}
+
+// ListSystemEvents retrieves the most recent system events, newest first.
+func (db *DB) ListSystemEvents(params SystemEventsParams) ([]models.SystemEvent, error) {
+	var where []string
+	var args []any
+	if !params.Since.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, params.Since)
+	}
+	if params.Severity != "" {
+		where = append(where, "severity = ?")
+		args = append(args, params.Severity)
+	}
+
+	query := `SELECT id, type, severity, message, created_at FROM system_events`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	args = append(args, params.Limit)
+
+	rows, err := db.conn.Query(query, args...) //nolint:sqlclosecheck
+	if err != nil {
+		return nil, fmt.Errorf("failed to query system events: %w", err)
+	}
+	defer closeRows(rows)
+
+	events := make([]models.SystemEvent, 0)
+	for rows.Next() {
+		var e models.SystemEvent
+		if err := rows.Scan(&e.ID, &e.Type, &e.Severity, &e.Message, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan system event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+func TestSystemEvents(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	old := &models.SystemEvent{Type: models.EventStorageLow, Severity: models.SeverityWarning, Message: "1 GiB free", CreatedAt: now.Add(-48 * time.Hour)}
+	recent := &models.SystemEvent{Type: models.EventServerStarted, Severity: models.SeverityInfo, CreatedAt: now}
+	for _, e := range []*models.SystemEvent{old, recent} {
+		if err := db.RecordSystemEvent(e); err != nil {
+			t.Fatalf("RecordSystemEvent() failed: %v", err)
+		}
+		if e.ID == 0 {
+			t.Error("RecordSystemEvent() should set the event ID")
+		}
+	}
+
+	events, err := db.ListSystemEvents(SystemEventsParams{Limit: 10})
+	if err != nil {
+		t.Fatalf("ListSystemEvents() failed: %v", err)
+	}
+	if len(events) != 2 || events[0].Type != models.EventServerStarted {
+		t.Fatalf("Expected 2 events, newest first, got %+v", events)
+	}
+
+	events, err = db.ListSystemEvents(SystemEventsParams{Since: now.Add(-time.Hour), Limit: 10})
+	if err != nil {
+		t.Fatalf("ListSystemEvents() failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Type != models.EventServerStarted {
+		t.Errorf("Expected since to drop the old event, got %+v", events)
+	}
+
+	events, err = db.ListSystemEvents(SystemEventsParams{Severity: models.SeverityWarning, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListSystemEvents() failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Message != "1 GiB free" {
+		t.Errorf("Expected only the warning, got %+v", events)
+	}
+}
+
+func TestPing(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := db.Ping(); err != nil {
+		t.Errorf("Ping() failed on an open database: %v", err)
+	}
+
+	db.Close()
+	if err := db.Ping(); err == nil {
+		t.Error("Ping() should fail on a closed database")
+	}
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// ErrGCReportNotFound is returned when a garbage collection report doesn't exist.
+var ErrGCReportNotFound = errors.New("gc report not found")
+
+// GCReportsParams filters ListGCReports.
+type GCReportsParams struct {
+	Since  time.Time // Zero returns reports of any age
+	Reason string    // Empty returns every reason
+	ISOID  string    // Empty returns reports for any ISO, or none
+	Limit  int
+}
+
+// CreateGCReport stores a garbage collection report and sets report.ID,
+// FileCount, and BytesReclaimed from its files.
+func (db *DB) CreateGCReport(report *models.GCReport) error {
+	report.FileCount = len(report.Files)
+	report.BytesReclaimed = 0
+	for _, f := range report.Files {
+		report.BytesReclaimed += f.SizeBytes
+	}
+	files, err := json.Marshal(report.Files)
+	if err != nil {
+		return fmt.Errorf("failed to encode gc report files: %w", err)
+	}
+
+	query := `INSERT INTO gc_reports (reason, iso_id, file_count, bytes_reclaimed, files, created_at) VALUES (?, ?, ?, ?, ?, ?)`
+	result, err := db.conn.Exec(query, report.Reason, report.ISOID, report.FileCount, report.BytesReclaimed, string(files), report.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create gc report (reason=%s): %w", report.Reason, err)
+	}
+	if report.ID, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("failed to get gc report id: %w", err)
+	}
+	return nil
+}
+
+// GetGCReport retrieves a garbage collection report by ID, returning
+// ErrGCReportNotFound when there is none.
+func (db *DB) GetGCReport(id int64) (*models.GCReport, error) {
+	row := db.conn.QueryRow(`
+		SELECT id, reason, iso_id, file_count, bytes_reclaimed, files, created_at
+		FROM gc_reports WHERE id = ?
+	`, id)
+	report, err := scanGCReport(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrGCReportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gc report (id=%d): %w", id, err)
+	}
+	return report, nil
+}
+
+// ListGCReports retrieves the most recent garbage collection reports, newest first.
+func (db *DB) ListGCReports(params GCReportsParams) ([]models.GCReport, error) {
+	var where []string
+	var args []any
+	if !params.Since.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, params.Since)
+	}
+	if params.Reason != "" {
+		where = append(where, "reason = ?")
+		args = append(args, params.Reason)
+	}
+	if params.ISOID != "" {
+		where = append(where, "iso_id = ?")
+		args = append(args, params.ISOID)
+	}
+
+	query := `SELECT id, reason, iso_id, file_count, bytes_reclaimed, files, created_at FROM gc_reports`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	args = append(args, params.Limit)
+
+	rows, err := db.conn.Query(query, args...) //nolint:sqlclosecheck
+	if err != nil {
+		return nil, fmt.Errorf("failed to query gc reports: %w", err)
+	}
+	defer closeRows(rows)
+
+	reports := make([]models.GCReport, 0)
+	for rows.Next() {
+		report, err := scanGCReport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan gc report: %w", err)
+		}
+		reports = append(reports, *report)
+	}
+	return reports, rows.Err()
+}
+
+func scanGCReport(row scanner) (*models.GCReport, error) {
+	var report models.GCReport
+	var files string
+	if err := row.Scan(&report.ID, &report.Reason, &report.ISOID, &report.FileCount, &report.BytesReclaimed, &files, &report.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(files), &report.Files); err != nil {
+		return nil, fmt.Errorf("invalid files in gc report %d: %w", report.ID, err)
+	}
+	return &report, nil
+}
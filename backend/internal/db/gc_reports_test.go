@@ -0,0 +1,65 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+func TestGCReports(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	old := &models.GCReport{CreatedAt: now.Add(-48 * time.Hour), Reason: models.GCReasonTempExpired, Files: []models.GCFile{{Path: "/tmp/a", SizeBytes: 10}}}
+	recent := &models.GCReport{CreatedAt: now, Reason: models.GCReasonVersionRetention, ISOID: "iso-1", Files: []models.GCFile{{Path: "/v/a", SizeBytes: 5}, {Path: "/v/b", SizeBytes: 7}}}
+	for _, r := range []*models.GCReport{old, recent} {
+		if err := db.CreateGCReport(r); err != nil {
+			t.Fatalf("CreateGCReport() failed: %v", err)
+		}
+	}
+	if recent.ID == 0 || recent.FileCount != 2 || recent.BytesReclaimed != 12 {
+		t.Errorf("Expected the ID and totals set, got %+v", recent)
+	}
+
+	got, err := db.GetGCReport(recent.ID)
+	if err != nil {
+		t.Fatalf("GetGCReport() failed: %v", err)
+	}
+	if got.ISOID != "iso-1" || len(got.Files) != 2 || got.Files[1].Path != "/v/b" {
+		t.Errorf("GetGCReport() = %+v", got)
+	}
+	if _, err := db.GetGCReport(999); !errors.Is(err, ErrGCReportNotFound) {
+		t.Errorf("Expected ErrGCReportNotFound, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		params GCReportsParams
+		want   []int64
+	}{
+		{"all, newest first", GCReportsParams{Limit: 10}, []int64{recent.ID, old.ID}},
+		{"since", GCReportsParams{Since: now.Add(-time.Hour), Limit: 10}, []int64{recent.ID}},
+		{"reason", GCReportsParams{Reason: models.GCReasonTempExpired, Limit: 10}, []int64{old.ID}},
+		{"iso", GCReportsParams{ISOID: "iso-2", Limit: 10}, nil},
+		{"limit", GCReportsParams{Limit: 1}, []int64{recent.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports, err := db.ListGCReports(tt.params)
+			if err != nil {
+				t.Fatalf("ListGCReports() failed: %v", err)
+			}
+			if len(reports) != len(tt.want) {
+				t.Fatalf("ListGCReports() = %d reports, want %d", len(reports), len(tt.want))
+			}
+			for i, r := range reports {
+				if r.ID != tt.want[i] {
+					t.Errorf("reports[%d].ID = %d, want %d", i, r.ID, tt.want[i])
+				}
+			}
+		})
+	}
+}
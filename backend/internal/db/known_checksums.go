@@ -0,0 +1,70 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+const knownChecksumSelectFields = `checksum_url, filename, checksum, source, updated_at`
+
+func scanKnownChecksum(s scanner) (*models.KnownChecksum, error) {
+	known := &models.KnownChecksum{}
+	if err := s.Scan(&known.ChecksumURL, &known.Filename, &known.Checksum, &known.Source, &known.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return known, nil
+}
+
+// SaveKnownChecksums inserts or replaces published checksums in a single
+// transaction.
+func (db *DB) SaveKnownChecksums(checksums []models.KnownChecksum) error {
+	query := `
+		INSERT INTO known_checksums (checksum_url, filename, checksum, source, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(checksum_url, filename) DO UPDATE SET checksum = excluded.checksum, source = excluded.source, updated_at = excluded.updated_at
+	`
+	return db.inTx("known checksums", func(tx *sql.Tx) error {
+		for _, known := range checksums {
+			if _, err := tx.Exec(query, known.ChecksumURL, known.Filename, known.Checksum, known.Source, known.UpdatedAt); err != nil {
+				return fmt.Errorf("failed to save known checksum (checksum_url=%s, filename=%s): %w", known.ChecksumURL, known.Filename, err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetKnownChecksum retrieves the checksum published for filename in the
+// checksum file at checksumURL, or nil if none is known.
+func (db *DB) GetKnownChecksum(checksumURL, filename string) (*models.KnownChecksum, error) {
+	query := fmt.Sprintf("SELECT %s FROM known_checksums WHERE checksum_url = ? AND filename = ?", knownChecksumSelectFields)
+	known, err := scanKnownChecksum(db.conn.QueryRow(query, checksumURL, filename))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan known checksum (checksum_url=%s, filename=%s): %w", checksumURL, filename, err)
+	}
+	return known, nil
+}
+
+// ListKnownChecksums retrieves the known checksums, all of them or those of
+// one checksum file, ordered by checksum file and filename.
+func (db *DB) ListKnownChecksums(checksumURL string) ([]models.KnownChecksum, error) {
+	query := fmt.Sprintf("SELECT %s FROM known_checksums WHERE ? = '' OR checksum_url = ? ORDER BY checksum_url, filename", knownChecksumSelectFields)
+	rows, err := db.conn.Query(query, checksumURL, checksumURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query known checksums: %w", err)
+	}
+	defer rows.Close()
+
+	checksums := []models.KnownChecksum{}
+	for rows.Next() {
+		known, err := scanKnownChecksum(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan known checksum: %w", err)
+		}
+		checksums = append(checksums, *known)
+	}
+	return checksums, rows.Err()
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+const mirrorHealthSelectFields = `host, success_count, failure_count, total_latency_ms, last_latency_ms,
+		last_error, last_success_at, last_failure_at`
+
+// scanMirrorHealth scans a mirror health row and derives computed fields.
+func scanMirrorHealth(s scanner) (*models.MirrorHealth, error) {
+	m := &models.MirrorHealth{}
+	err := s.Scan(
+		&m.Host,
+		&m.SuccessCount,
+		&m.FailureCount,
+		&m.TotalLatencyMs,
+		&m.LastLatencyMs,
+		&m.LastError,
+		&m.LastSuccessAt,
+		&m.LastFailureAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	m.ComputeFields()
+	return m, nil
+}
+
+// RecordMirrorSuccess records a successful transfer from host with its time to first byte.
+func (db *DB) RecordMirrorSuccess(host string, latency time.Duration, at time.Time) error {
+	query := `
+	INSERT INTO mirror_health (host, success_count, total_latency_ms, last_latency_ms, last_success_at)
+	VALUES (?, 1, ?, ?, ?)
+	ON CONFLICT(host) DO UPDATE SET
+		success_count = success_count + 1,
+		total_latency_ms = total_latency_ms + excluded.total_latency_ms,
+		last_latency_ms = excluded.last_latency_ms,
+		last_success_at = excluded.last_success_at
+	`
+	ms := latency.Milliseconds()
+	if _, err := db.conn.Exec(query, host, ms, ms, at); err != nil {
+		return fmt.Errorf("failed to record mirror success (host=%s): %w", host, err)
+	}
+	return nil
+}
+
+// RecordMirrorFailure records a failed transfer from host.
+func (db *DB) RecordMirrorFailure(host, errorMsg string, at time.Time) error {
+	query := `
+	INSERT INTO mirror_health (host, failure_count, last_error, last_failure_at)
+	VALUES (?, 1, ?, ?)
+	ON CONFLICT(host) DO UPDATE SET
+		failure_count = failure_count + 1,
+		last_error = excluded.last_error,
+		last_failure_at = excluded.last_failure_at
+	`
+	if _, err := db.conn.Exec(query, host, errorMsg, at); err != nil {
+		return fmt.Errorf("failed to record mirror failure (host=%s): %w", host, err)
+	}
+	return nil
+}
+
+// GetMirrorHealth retrieves health for a single host.
+func (db *DB) GetMirrorHealth(host string) (*models.MirrorHealth, error) {
+	query := fmt.Sprintf("SELECT %s FROM mirror_health WHERE host = ?", mirrorHealthSelectFields)
+	m, err := scanMirrorHealth(db.conn.QueryRow(query, host))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("mirror not found (host=%s)", host)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan mirror health (host=%s): %w", host, err)
+	}
+	return m, nil
+}
+
+// ListMirrorHealth retrieves health for all known hosts ordered by host name.
+func (db *DB) ListMirrorHealth() ([]models.MirrorHealth, error) {
+	query := fmt.Sprintf("SELECT %s FROM mirror_health ORDER BY host ASC", mirrorHealthSelectFields)
+	rows, err := db.conn.Query(query) //nolint:sqlclosecheck
+	if err != nil {
+		return nil, fmt.Errorf("failed to query mirror health: %w", err)
+	}
+	defer closeRows(rows)
+
+	mirrors := make([]models.MirrorHealth, 0)
+	for rows.Next() {
+		m, err := scanMirrorHealth(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan mirror health: %w", err)
+		}
+		mirrors = append(mirrors, *m)
+	}
+	return mirrors, rows.Err()
+}
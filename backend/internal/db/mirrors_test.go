@@ -0,0 +1,79 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordMirrorHealth(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	if err := db.RecordMirrorSuccess("mirror.example.com", 100*time.Millisecond, now); err != nil {
+		t.Fatalf("RecordMirrorSuccess() failed: %v", err)
+	}
+	if err := db.RecordMirrorSuccess("mirror.example.com", 300*time.Millisecond, now); err != nil {
+		t.Fatalf("RecordMirrorSuccess() failed: %v", err)
+	}
+	if err := db.RecordMirrorFailure("mirror.example.com", "server returned 503", now); err != nil {
+		t.Fatalf("RecordMirrorFailure() failed: %v", err)
+	}
+
+	m, err := db.GetMirrorHealth("mirror.example.com")
+	if err != nil {
+		t.Fatalf("GetMirrorHealth() failed: %v", err)
+	}
+
+	if m.SuccessCount != 2 {
+		t.Errorf("Expected SuccessCount 2, got %d", m.SuccessCount)
+	}
+	if m.FailureCount != 1 {
+		t.Errorf("Expected FailureCount 1, got %d", m.FailureCount)
+	}
+	if m.AvgLatencyMs != 200 {
+		t.Errorf("Expected AvgLatencyMs 200, got %d", m.AvgLatencyMs)
+	}
+	if m.LastLatencyMs != 300 {
+		t.Errorf("Expected LastLatencyMs 300, got %d", m.LastLatencyMs)
+	}
+	if m.LastError != "server returned 503" {
+		t.Errorf("Expected LastError to be recorded, got '%s'", m.LastError)
+	}
+	if m.SuccessRate < 0.66 || m.SuccessRate > 0.67 {
+		t.Errorf("Expected SuccessRate ~0.667, got %f", m.SuccessRate)
+	}
+	if m.LastSuccessAt == nil || m.LastFailureAt == nil {
+		t.Error("Expected last success and failure timestamps to be set")
+	}
+}
+
+func TestListMirrorHealth(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	mirrors, err := db.ListMirrorHealth()
+	if err != nil {
+		t.Fatalf("ListMirrorHealth() failed: %v", err)
+	}
+	if len(mirrors) != 0 {
+		t.Errorf("Expected no mirrors, got %d", len(mirrors))
+	}
+
+	db.RecordMirrorFailure("b.example.com", "timeout", time.Now())
+	db.RecordMirrorSuccess("a.example.com", time.Second, time.Now())
+
+	mirrors, err = db.ListMirrorHealth()
+	if err != nil {
+		t.Fatalf("ListMirrorHealth() failed: %v", err)
+	}
+	if len(mirrors) != 2 {
+		t.Fatalf("Expected 2 mirrors, got %d", len(mirrors))
+	}
+	if mirrors[0].Host != "a.example.com" || mirrors[1].Host != "b.example.com" {
+		t.Errorf("Expected mirrors ordered by host, got %s, %s", mirrors[0].Host, mirrors[1].Host)
+	}
+	if mirrors[1].LastSuccessAt != nil {
+		t.Error("Expected nil LastSuccessAt for a host that never succeeded")
+	}
+}
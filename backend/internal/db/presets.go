@@ -0,0 +1,90 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// ErrPresetNotFound is returned when no preset has the requested name.
+var ErrPresetNotFound = errors.New("preset not found")
+
+const presetSelectFields = `name, iso_name, arch, edition, download_url, checksum_url, checksum_type, created_at, updated_at`
+
+func scanPreset(s scanner) (*models.Preset, error) {
+	preset := &models.Preset{}
+	if err := s.Scan(&preset.Name, &preset.ISOName, &preset.Arch, &preset.Edition, &preset.DownloadURL, &preset.ChecksumURL, &preset.ChecksumType, &preset.CreatedAt, &preset.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return preset, nil
+}
+
+// CreatePreset inserts a new preset.
+func (db *DB) CreatePreset(preset *models.Preset) error {
+	query := `INSERT INTO presets (name, iso_name, arch, edition, download_url, checksum_url, checksum_type, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := db.conn.Exec(query, preset.Name, preset.ISOName, preset.Arch, preset.Edition, preset.DownloadURL, preset.ChecksumURL, preset.ChecksumType, preset.CreatedAt, preset.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert preset (name=%s): %w", preset.Name, err)
+	}
+	return nil
+}
+
+// GetPreset retrieves a preset by name.
+func (db *DB) GetPreset(name string) (*models.Preset, error) {
+	query := fmt.Sprintf("SELECT %s FROM presets WHERE name = ?", presetSelectFields)
+	preset, err := scanPreset(db.conn.QueryRow(query, name))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w (name=%s)", ErrPresetNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan preset (name=%s): %w", name, err)
+	}
+	return preset, nil
+}
+
+// ListPresets retrieves all presets ordered by name.
+func (db *DB) ListPresets() ([]models.Preset, error) {
+	query := fmt.Sprintf("SELECT %s FROM presets ORDER BY name", presetSelectFields)
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query presets: %w", err)
+	}
+	defer rows.Close()
+
+	presets := []models.Preset{}
+	for rows.Next() {
+		preset, err := scanPreset(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan preset: %w", err)
+		}
+		presets = append(presets, *preset)
+	}
+	return presets, rows.Err()
+}
+
+// UpdatePreset replaces every field of a preset but its name.
+func (db *DB) UpdatePreset(preset *models.Preset) error {
+	query := `UPDATE presets SET iso_name = ?, arch = ?, edition = ?, download_url = ?, checksum_url = ?, checksum_type = ?, updated_at = ? WHERE name = ?`
+	result, err := db.conn.Exec(query, preset.ISOName, preset.Arch, preset.Edition, preset.DownloadURL, preset.ChecksumURL, preset.ChecksumType, preset.UpdatedAt, preset.Name)
+	if err != nil {
+		return fmt.Errorf("failed to update preset (name=%s): %w", preset.Name, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w (name=%s)", ErrPresetNotFound, preset.Name)
+	}
+	return nil
+}
+
+// DeletePreset removes a preset.
+func (db *DB) DeletePreset(name string) error {
+	result, err := db.conn.Exec("DELETE FROM presets WHERE name = ?", name)
+	if err != nil {
+		return fmt.Errorf("failed to delete preset (name=%s): %w", name, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w (name=%s)", ErrPresetNotFound, name)
+	}
+	return nil
+}
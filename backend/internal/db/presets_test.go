@@ -0,0 +1,66 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+func TestPresets(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	rocky := &models.Preset{
+		Name:         "rocky-minimal",
+		ISOName:      "rocky",
+		Arch:         "x86_64",
+		Edition:      "minimal",
+		DownloadURL:  "https://download.rockylinux.org/pub/rocky/{version}/isos/{arch}/Rocky-{version}-{arch}-{edition}.iso",
+		ChecksumURL:  "https://download.rockylinux.org/pub/rocky/{version}/isos/{arch}/CHECKSUM",
+		ChecksumType: "sha256",
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	alpine := &models.Preset{Name: "alpine", ISOName: "alpine", DownloadURL: "https://dl.example.com/alpine-{version}-{arch}.iso", CreatedAt: now, UpdatedAt: now}
+	for _, preset := range []*models.Preset{rocky, alpine} {
+		if err := db.CreatePreset(preset); err != nil {
+			t.Fatalf("CreatePreset() failed: %v", err)
+		}
+	}
+	if err := db.CreatePreset(rocky); err == nil {
+		t.Error("CreatePreset() should reject a duplicate name")
+	}
+
+	presets, err := db.ListPresets()
+	if err != nil {
+		t.Fatalf("ListPresets() failed: %v", err)
+	}
+	if len(presets) != 2 || presets[0].Name != "alpine" {
+		t.Errorf("Expected 2 presets ordered by name, got %+v", presets)
+	}
+
+	rocky.Edition = "dvd"
+	if err := db.UpdatePreset(rocky); err != nil {
+		t.Fatalf("UpdatePreset() failed: %v", err)
+	}
+	got, err := db.GetPreset("rocky-minimal")
+	if err != nil {
+		t.Fatalf("GetPreset() failed: %v", err)
+	}
+	if got.Edition != "dvd" || got.ChecksumURL != rocky.ChecksumURL {
+		t.Errorf("Expected the updated preset, got %+v", got)
+	}
+
+	if err := db.DeletePreset("rocky-minimal"); err != nil {
+		t.Fatalf("DeletePreset() failed: %v", err)
+	}
+	if _, err := db.GetPreset("rocky-minimal"); !errors.Is(err, ErrPresetNotFound) {
+		t.Errorf("Expected ErrPresetNotFound after delete, got: %v", err)
+	}
+	if err := db.UpdatePreset(rocky); !errors.Is(err, ErrPresetNotFound) {
+		t.Errorf("Expected ErrPresetNotFound updating a deleted preset, got: %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// ErrReportRunNotFound is returned when no report has been sent yet.
+var ErrReportRunNotFound = errors.New("report run not found")
+
+// ListISOsCreatedBetween retrieves the ISOs created in [from, to), oldest first.
+func (db *DB) ListISOsCreatedBetween(from, to time.Time) ([]models.ISO, error) {
+	query := fmt.Sprintf("SELECT %s FROM isos WHERE created_at >= ? AND created_at < ? ORDER BY created_at ASC", isoSelectFields)
+	return db.queryISOs(query, from, to)
+}
+
+// ListISOsByStatus retrieves the ISOs with a status, newest first.
+func (db *DB) ListISOsByStatus(status models.ISOStatus) ([]models.ISO, error) {
+	query := fmt.Sprintf("SELECT %s FROM isos WHERE status = ? ORDER BY created_at DESC", isoSelectFields)
+	return db.queryISOs(query, status)
+}
+
+// ListCompleteISOsBySHA256 retrieves the complete ISOs whose file has the
+// given lowercase SHA-256, oldest first. ISOs downloaded before every digest
+// was recorded match by their SHA-256 checksum instead.
+func (db *DB) ListCompleteISOsBySHA256(sum string) ([]models.ISO, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM isos
+		WHERE status = 'complete'
+		AND (sha256 = ? OR (sha256 = '' AND LOWER(checksum_type) = 'sha256' AND LOWER(checksum) = ?))
+		ORDER BY created_at ASC
+	`, isoSelectFields)
+	return db.queryISOs(query, sum, sum)
+}
+
+// ListChecksumPendingISOs retrieves the complete ISOs whose checksum couldn't
+// be verified when they finished downloading, oldest first.
+func (db *DB) ListChecksumPendingISOs() ([]models.ISO, error) {
+	query := fmt.Sprintf("SELECT %s FROM isos WHERE status = 'complete' AND checksum_pending = 1 ORDER BY completed_at ASC", isoSelectFields)
+	return db.queryISOs(query)
+}
+
+// ListRecentlyCompletedISOs retrieves up to limit complete ISOs, most
+// recently completed first.
+func (db *DB) ListRecentlyCompletedISOs(limit int) ([]models.ISO, error) {
+	query := fmt.Sprintf("SELECT %s FROM isos WHERE status = 'complete' AND completed_at IS NOT NULL ORDER BY completed_at DESC LIMIT ?", isoSelectFields)
+	return db.queryISOs(query, limit)
+}
+
+func (db *DB) queryISOs(query string, args ...any) ([]models.ISO, error) {
+	rows, err := db.conn.Query(query, args...) //nolint:sqlclosecheck
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ISOs: %w", err)
+	}
+	defer closeRows(rows)
+
+	isos := make([]models.ISO, 0)
+	for rows.Next() {
+		iso, err := scanISO(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ISO record: %w", err)
+		}
+		isos = append(isos, *iso)
+	}
+	return isos, rows.Err()
+}
+
+// GetTopDownloadsBetween ranks ISOs by the download events recorded in
+// [from, to). DownloadCount holds the count for the period, not the lifetime total.
+func (db *DB) GetTopDownloadsBetween(from, to time.Time, limit int) ([]models.ISODownloadStat, error) {
+	rows, err := db.conn.Query(`
+		SELECT i.id, i.name, i.version, i.arch, COUNT(*) AS downloads, i.size_bytes
+		FROM download_events e
+		JOIN isos i ON i.id = e.iso_id
+		WHERE e.downloaded_at >= ? AND e.downloaded_at < ?
+		GROUP BY i.id
+		ORDER BY downloads DESC, i.name ASC
+		LIMIT ?
+	`, from.Format(time.RFC3339), to.Format(time.RFC3339), limit) //nolint:sqlclosecheck
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top downloads: %w", err)
+	}
+	defer closeRows(rows)
+
+	stats := make([]models.ISODownloadStat, 0)
+	for rows.Next() {
+		var stat models.ISODownloadStat
+		if err := rows.Scan(&stat.ID, &stat.Name, &stat.Version, &stat.Arch, &stat.DownloadCount, &stat.SizeBytes); err != nil {
+			return nil, err
+		}
+		stats = append(stats, stat)
+	}
+	return stats, rows.Err()
+}
+
+// RecordReportRun stores a sent report and sets run.ID.
+func (db *DB) RecordReportRun(run *models.ReportRun) error {
+	query := `INSERT INTO report_runs (period_start, period_end, storage_bytes, created_at) VALUES (?, ?, ?, ?)`
+	result, err := db.conn.Exec(query, run.PeriodStart, run.PeriodEnd, run.StorageBytes, run.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record report run: %w", err)
+	}
+	if run.ID, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("failed to get report run id: %w", err)
+	}
+	return nil
+}
+
+// GetLastReportRun retrieves the most recently sent report, returning
+// ErrReportRunNotFound when there is none.
+func (db *DB) GetLastReportRun() (*models.ReportRun, error) {
+	var run models.ReportRun
+	err := db.conn.QueryRow(`
+		SELECT id, period_start, period_end, storage_bytes, created_at
+		FROM report_runs ORDER BY id DESC LIMIT 1
+	`).Scan(&run.ID, &run.PeriodStart, &run.PeriodEnd, &run.StorageBytes, &run.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrReportRunNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last report run: %w", err)
+	}
+	return &run, nil
+}
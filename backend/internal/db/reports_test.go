@@ -0,0 +1,92 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+func TestReportQueries(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	weekAgo := now.AddDate(0, 0, -7)
+
+	old := createTestISO()
+	old.Version = "1"
+	old.Status = models.StatusFailed
+	old.CreatedAt = now.AddDate(0, 0, -30)
+	recent := createTestISO()
+	recent.Version = "2"
+	recent.Status = models.StatusComplete
+	recent.CreatedAt = now.Add(-time.Hour)
+	for _, iso := range []*models.ISO{old, recent} {
+		if err := db.CreateISO(iso); err != nil {
+			t.Fatalf("CreateISO() failed: %v", err)
+		}
+	}
+
+	created, err := db.ListISOsCreatedBetween(weekAgo, now)
+	if err != nil {
+		t.Fatalf("ListISOsCreatedBetween() failed: %v", err)
+	}
+	if len(created) != 1 || created[0].ID != recent.ID {
+		t.Errorf("ListISOsCreatedBetween() = %d ISOs, want only the recent one", len(created))
+	}
+
+	failed, err := db.ListISOsByStatus(models.StatusFailed)
+	if err != nil {
+		t.Fatalf("ListISOsByStatus() failed: %v", err)
+	}
+	if len(failed) != 1 || failed[0].ID != old.ID {
+		t.Errorf("ListISOsByStatus() = %d ISOs, want only the failed one", len(failed))
+	}
+
+	for _, at := range []time.Time{now.AddDate(0, 0, -10), now.Add(-time.Hour), now.Add(-time.Minute)} {
+		if err := db.RecordDownloadEvent(recent.ID, at); err != nil {
+			t.Fatalf("RecordDownloadEvent() failed: %v", err)
+		}
+	}
+	if err := db.RecordDownloadEvent(old.ID, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("RecordDownloadEvent() failed: %v", err)
+	}
+
+	top, err := db.GetTopDownloadsBetween(weekAgo, now, 10)
+	if err != nil {
+		t.Fatalf("GetTopDownloadsBetween() failed: %v", err)
+	}
+	if len(top) != 2 || top[0].ID != recent.ID || top[0].DownloadCount != 2 {
+		t.Errorf("GetTopDownloadsBetween() = %+v, want the recent ISO first with 2 downloads", top)
+	}
+}
+
+func TestReportRuns(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if _, err := db.GetLastReportRun(); !errors.Is(err, ErrReportRunNotFound) {
+		t.Errorf("Expected ErrReportRunNotFound, got: %v", err)
+	}
+
+	now := time.Now()
+	for _, size := range []int64{100, 250} {
+		run := &models.ReportRun{PeriodStart: now.AddDate(0, 0, -7), PeriodEnd: now, StorageBytes: size, CreatedAt: now}
+		if err := db.RecordReportRun(run); err != nil {
+			t.Fatalf("RecordReportRun() failed: %v", err)
+		}
+		if run.ID == 0 {
+			t.Error("RecordReportRun() didn't set the ID")
+		}
+	}
+
+	last, err := db.GetLastReportRun()
+	if err != nil {
+		t.Fatalf("GetLastReportRun() failed: %v", err)
+	}
+	if last.StorageBytes != 250 {
+		t.Errorf("GetLastReportRun().StorageBytes = %d, want 250", last.StorageBytes)
+	}
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrSettingNotFound is returned when a setting has never been stored.
+var ErrSettingNotFound = errors.New("setting not found")
+
+// Setting keys.
+const (
+	SettingISODir          = "iso_dir"          // ISO directory the files are stored in
+	SettingAnalyticsSalt   = "analytics_salt"   // Key for hashing download client IPs
+	SettingListingBranding = "listing_branding" // JSON branding of the /images listing
+	SettingListingColumns  = "listing_columns"  // JSON array of the /images listing's columns
+)
+
+const upsertSettingQuery = `INSERT INTO settings (key, value, updated_at) VALUES (?, ?, ?)
+	ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`
+
+// GetSetting retrieves the value of a setting.
+func (db *DB) GetSetting(key string) (string, error) {
+	var value string
+	err := db.conn.QueryRow(`SELECT value FROM settings WHERE key = ?`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("%w (key=%s)", ErrSettingNotFound, key)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get setting (key=%s): %w", key, err)
+	}
+	return value, nil
+}
+
+// SetSetting stores the value of a setting, replacing any previous one.
+func (db *DB) SetSetting(key, value string) error {
+	if _, err := db.conn.Exec(upsertSettingQuery, key, value, time.Now()); err != nil {
+		return fmt.Errorf("failed to set setting (key=%s): %w", key, err)
+	}
+	return nil
+}
+
+// SwitchISODir records isoDir as the ISO directory and the inodes of the
+// files moved into it, keyed by ISO ID, in one transaction, so a crash leaves
+// the database pointing at either the old directory or the new one.
+func (db *DB) SwitchISODir(isoDir string, inodes map[string]uint64) error {
+	return db.inTx("ISO directory switch", func(tx *sql.Tx) error {
+		for id, inode := range inodes {
+			if _, err := tx.Exec(`UPDATE isos SET file_inode = ? WHERE id = ?`, inode, id); err != nil {
+				return fmt.Errorf("failed to update ISO file inode (id=%s): %w", id, err)
+			}
+		}
+		if _, err := tx.Exec(upsertSettingQuery, SettingISODir, isoDir, time.Now()); err != nil {
+			return fmt.Errorf("failed to set setting (key=%s): %w", SettingISODir, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSettings(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if _, err := db.GetSetting(SettingISODir); !errors.Is(err, ErrSettingNotFound) {
+		t.Fatalf("Expected ErrSettingNotFound, got: %v", err)
+	}
+	for _, dir := range []string{"/data/isos", "/mnt/bulk/isos"} {
+		if err := db.SetSetting(SettingISODir, dir); err != nil {
+			t.Fatalf("SetSetting() failed: %v", err)
+		}
+		if got, err := db.GetSetting(SettingISODir); err != nil || got != dir {
+			t.Errorf("GetSetting() = %q, %v, want %q", got, err, dir)
+		}
+	}
+}
+
+func TestSwitchISODir(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	iso := createTestISO()
+	iso.FileInode = 100
+	if err := db.CreateISO(iso); err != nil {
+		t.Fatalf("CreateISO() failed: %v", err)
+	}
+
+	if err := db.SwitchISODir("/mnt/bulk/isos", map[string]uint64{iso.ID: 200}); err != nil {
+		t.Fatalf("SwitchISODir() failed: %v", err)
+	}
+	if got, _ := db.GetSetting(SettingISODir); got != "/mnt/bulk/isos" {
+		t.Errorf("Expected the new ISO directory recorded, got %q", got)
+	}
+	if stored, _ := db.GetISO(iso.ID); stored.FileInode != 200 {
+		t.Errorf("Expected the new inode recorded, got %d", stored.FileInode)
+	}
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// ErrCatalogSnapshotNotFound is returned when no catalog snapshot has been taken.
+var ErrCatalogSnapshotNotFound = errors.New("catalog snapshot not found")
+
+// CreateCatalogSnapshot stores a snapshot of the catalog and sets snapshot.ID.
+func (db *DB) CreateCatalogSnapshot(snapshot *models.CatalogSnapshot) error {
+	entries, err := json.Marshal(snapshot.Entries)
+	if err != nil {
+		return fmt.Errorf("failed to encode catalog snapshot: %w", err)
+	}
+	snapshot.ISOCount = len(snapshot.Entries)
+
+	// Format as RFC3339 in UTC so taken_at compares as text
+	query := `INSERT INTO catalog_snapshots (taken_at, iso_count, entries) VALUES (?, ?, ?)`
+	result, err := db.conn.Exec(query, snapshot.TakenAt.UTC().Format(time.RFC3339), snapshot.ISOCount, string(entries))
+	if err != nil {
+		return fmt.Errorf("failed to create catalog snapshot: %w", err)
+	}
+	if snapshot.ID, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("failed to get catalog snapshot id: %w", err)
+	}
+	return nil
+}
+
+// GetCatalogSnapshotAt retrieves the latest snapshot taken at or before t, or
+// the earliest one when all were taken after t. It returns
+// ErrCatalogSnapshotNotFound when there are none.
+func (db *DB) GetCatalogSnapshotAt(t time.Time) (*models.CatalogSnapshot, error) {
+	snapshot, err := db.getCatalogSnapshot(`
+		SELECT id, taken_at, iso_count, entries FROM catalog_snapshots
+		WHERE taken_at <= ? ORDER BY taken_at DESC, id DESC LIMIT 1
+	`, t.UTC().Format(time.RFC3339))
+	if !errors.Is(err, ErrCatalogSnapshotNotFound) {
+		return snapshot, err
+	}
+	return db.getCatalogSnapshot(`
+		SELECT id, taken_at, iso_count, entries FROM catalog_snapshots
+		ORDER BY taken_at ASC, id ASC LIMIT 1
+	`)
+}
+
+// GetLatestCatalogSnapshot retrieves the most recent snapshot, returning
+// ErrCatalogSnapshotNotFound when there is none.
+func (db *DB) GetLatestCatalogSnapshot() (*models.CatalogSnapshot, error) {
+	return db.getCatalogSnapshot(`
+		SELECT id, taken_at, iso_count, entries FROM catalog_snapshots
+		ORDER BY taken_at DESC, id DESC LIMIT 1
+	`)
+}
+
+func (db *DB) getCatalogSnapshot(query string, args ...any) (*models.CatalogSnapshot, error) {
+	var snapshot models.CatalogSnapshot
+	var entries string
+	err := db.conn.QueryRow(query, args...).Scan(&snapshot.ID, &snapshot.TakenAt, &snapshot.ISOCount, &entries)
+	if err == sql.ErrNoRows {
+		return nil, ErrCatalogSnapshotNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get catalog snapshot: %w", err)
+	}
+	if err := json.Unmarshal([]byte(entries), &snapshot.Entries); err != nil {
+		return nil, fmt.Errorf("failed to decode catalog snapshot %d: %w", snapshot.ID, err)
+	}
+	return &snapshot, nil
+}
+
+// DeleteCatalogSnapshotsBefore removes snapshots taken before cutoff, except
+// the latest one, and returns how many were removed.
+func (db *DB) DeleteCatalogSnapshotsBefore(cutoff time.Time) (int64, error) {
+	result, err := db.conn.Exec(`
+		DELETE FROM catalog_snapshots
+		WHERE taken_at < ? AND id <> (SELECT id FROM catalog_snapshots ORDER BY taken_at DESC, id DESC LIMIT 1)
+	`, cutoff.UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old catalog snapshots: %w", err)
+	}
+	return result.RowsAffected()
+}
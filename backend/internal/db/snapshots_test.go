@@ -0,0 +1,80 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+func TestCatalogSnapshots(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if _, err := db.GetLatestCatalogSnapshot(); !errors.Is(err, ErrCatalogSnapshotNotFound) {
+		t.Fatalf("Expected ErrCatalogSnapshotNotFound, got %v", err)
+	}
+	if _, err := db.GetCatalogSnapshotAt(time.Now()); !errors.Is(err, ErrCatalogSnapshotNotFound) {
+		t.Fatalf("Expected ErrCatalogSnapshotNotFound, got %v", err)
+	}
+
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		snapshot := &models.CatalogSnapshot{
+			TakenAt: day.AddDate(0, 0, i),
+			Entries: make([]models.CatalogEntry, i),
+		}
+		for j := range snapshot.Entries {
+			snapshot.Entries[j] = models.CatalogEntry{ID: "iso-" + string(rune('a'+j)), Name: "alpine", SizeBytes: 1024}
+		}
+		if err := db.CreateCatalogSnapshot(snapshot); err != nil {
+			t.Fatalf("CreateCatalogSnapshot() failed: %v", err)
+		}
+		if snapshot.ID == 0 || snapshot.ISOCount != i {
+			t.Errorf("Expected the ID and count set, got %+v", snapshot)
+		}
+	}
+
+	latest, err := db.GetLatestCatalogSnapshot()
+	if err != nil {
+		t.Fatalf("GetLatestCatalogSnapshot() failed: %v", err)
+	}
+	if !latest.TakenAt.Equal(day.AddDate(0, 0, 2)) || len(latest.Entries) != 2 || latest.Entries[1].ID != "iso-b" {
+		t.Errorf("Expected the last snapshot, got %+v", latest)
+	}
+
+	tests := []struct {
+		at   time.Time
+		want time.Time
+	}{
+		{day.AddDate(0, 0, 1).Add(12 * time.Hour), day.AddDate(0, 0, 1)}, // Latest at or before
+		{day.AddDate(0, 0, 1), day.AddDate(0, 0, 1)},                     // Exactly when taken
+		{day.AddDate(0, 0, -5), day},                                     // Earliest when all are later
+	}
+	for _, tt := range tests {
+		snapshot, err := db.GetCatalogSnapshotAt(tt.at)
+		if err != nil {
+			t.Fatalf("GetCatalogSnapshotAt(%v) failed: %v", tt.at, err)
+		}
+		if !snapshot.TakenAt.Equal(tt.want) {
+			t.Errorf("GetCatalogSnapshotAt(%v) taken at %v, want %v", tt.at, snapshot.TakenAt, tt.want)
+		}
+	}
+
+	n, err := db.DeleteCatalogSnapshotsBefore(day.AddDate(0, 0, 2))
+	if err != nil {
+		t.Fatalf("DeleteCatalogSnapshotsBefore() failed: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("DeleteCatalogSnapshotsBefore() = %d, want 2", n)
+	}
+
+	// The latest snapshot is kept however old it is
+	if n, _ := db.DeleteCatalogSnapshotsBefore(day.AddDate(1, 0, 0)); n != 0 {
+		t.Errorf("Expected the latest snapshot kept, deleted %d", n)
+	}
+	if _, err := db.GetLatestCatalogSnapshot(); err != nil {
+		t.Errorf("GetLatestCatalogSnapshot() failed: %v", err)
+	}
+}
@@ -2,29 +2,46 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"time"
 
+	"github.com/aloks98/isoman/backend/internal/clock"
 	"github.com/aloks98/isoman/backend/internal/config"
 	"github.com/aloks98/isoman/backend/internal/models"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
-	_ "modernc.org/sqlite"
+	sqlitedriver "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
 )
 
+// ErrISOExists is returned when an ISO with the same name, version, arch,
+// edition, and file type is already stored, e.g. when two identical creates
+// race past ISOExists.
+var ErrISOExists = errors.New("ISO already exists")
+
+// ErrISONotFound is returned when no ISO has the requested ID.
+var ErrISONotFound = errors.New("ISO not found")
+
 // SQL constants for ISO queries.
 const (
 	isoSelectFields = `id, name, version, arch, edition, file_type, filename, file_path, download_link,
-		size_bytes, checksum, checksum_type, download_url, checksum_url,
-		status, progress, error_message, created_at, completed_at, download_count`
+		size_bytes, checksum, checksum_type, download_url, checksum_url, ip_family,
+		status, progress, error_message, error_reason, created_at, completed_at, download_count,
+		upstream_etag, upstream_last_modified, upstream_changed, upstream_checked_at,
+		sha256, sha512, md5, integrity_hash, file_inode, file_mtime, credential, preset, pinned, bytes_served,
+		signature_url, signature_signer, verified_at, source_url, source_headers, download_started_at, redownload, checksum_pending`
 )
 
 // DB wraps the SQLite database connection.
 type DB struct {
-	conn *sql.DB
+	conn *busyConn
 	cfg  *config.DatabaseConfig
 }
 
@@ -33,9 +50,15 @@ type scanner interface {
 	Scan(dest ...interface{}) error
 }
 
+// execer is implemented by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
 // scanISO scans an ISO from a database row.
 func scanISO(s scanner) (*models.ISO, error) {
 	iso := &models.ISO{}
+	var sourceHeaders string
 	err := s.Scan(
 		&iso.ID,
 		&iso.Name,
@@ -51,22 +74,94 @@ func scanISO(s scanner) (*models.ISO, error) {
 		&iso.ChecksumType,
 		&iso.DownloadURL,
 		&iso.ChecksumURL,
+		&iso.IPFamily,
 		&iso.Status,
 		&iso.Progress,
 		&iso.ErrorMessage,
+		&iso.ErrorReason,
 		&iso.CreatedAt,
 		&iso.CompletedAt,
 		&iso.DownloadCount,
+		&iso.UpstreamETag,
+		&iso.UpstreamLastModified,
+		&iso.UpstreamChanged,
+		&iso.UpstreamCheckedAt,
+		&iso.SHA256,
+		&iso.SHA512,
+		&iso.MD5,
+		&iso.IntegrityHash,
+		&iso.FileInode,
+		&iso.FileModTime,
+		&iso.Credential,
+		&iso.Preset,
+		&iso.Pinned,
+		&iso.BytesServed,
+		&iso.SignatureURL,
+		&iso.SignatureSigner,
+		&iso.VerifiedAt,
+		&iso.SourceURL,
+		&sourceHeaders,
+		&iso.DownloadStartedAt,
+		&iso.Redownload,
+		&iso.ChecksumPending,
 	)
 	if err != nil {
 		return nil, err
 	}
+	if sourceHeaders != "" {
+		if err := json.Unmarshal([]byte(sourceHeaders), &iso.SourceHeaders); err != nil {
+			return nil, fmt.Errorf("failed to decode source headers (id=%s): %w", iso.ID, err)
+		}
+	}
+	iso.Verification = iso.VerificationState()
 	return iso, nil
 }
 
-// New creates a new database connection and runs migrations.
+// encodeSourceHeaders encodes an ISO's source headers for storage; none is "".
+func encodeSourceHeaders(iso *models.ISO) (string, error) {
+	if len(iso.SourceHeaders) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(iso.SourceHeaders)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode source headers (id=%s): %w", iso.ID, err)
+	}
+	return string(data), nil
+}
+
+// ErrMigrationsPending is returned by New when migrations don't run at
+// startup and the schema is behind the migrations shipped with this build.
+var ErrMigrationsPending = errors.New("database has pending migrations")
+
+// New opens the database and brings its schema up to date, or with
+// AutoMigrate off, checks that it already is.
 func New(dbPath string, cfg *config.DatabaseConfig) (*DB, error) {
-	conn, err := sql.Open("sqlite", dbPath)
+	db, err := Open(dbPath, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.AutoMigrate {
+		err = db.Migrate()
+	} else {
+		err = db.checkSchema()
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Open opens the database without touching its schema, for the migrate
+// command; the server uses New.
+func Open(dbPath string, cfg *config.DatabaseConfig) (*DB, error) {
+	// Set busy timeout (configurable, default: 5000ms). It goes in the DSN so
+	// every pooled connection waits for locks, not just the first one.
+	busyTimeoutMs := int(cfg.BusyTimeout.Milliseconds())
+	dsn := fmt.Sprintf("%s?_pragma=busy_timeout(%d)", dbPath, busyTimeoutMs)
+
+	conn, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -78,13 +173,6 @@ func New(dbPath string, cfg *config.DatabaseConfig) (*DB, error) {
 		return nil, fmt.Errorf("failed to set journal mode: %w", err)
 	}
 
-	// Set busy timeout (configurable, default: 5000ms)
-	busyTimeoutMs := int(cfg.BusyTimeout.Milliseconds())
-	if _, err := conn.Exec(fmt.Sprintf("PRAGMA busy_timeout=%d", busyTimeoutMs)); err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to set busy timeout: %w", err)
-	}
-
 	// Configure connection pool
 	conn.SetMaxOpenConns(cfg.MaxOpenConns)
 	conn.SetMaxIdleConns(cfg.MaxIdleConns)
@@ -92,37 +180,45 @@ func New(dbPath string, cfg *config.DatabaseConfig) (*DB, error) {
 	conn.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
 	db := &DB{
-		conn: conn,
+		conn: &busyConn{DB: conn, retries: max(cfg.BusyRetries, 0), clock: clock.Real()},
 		cfg:  cfg,
 	}
-	if err := db.migrate(); err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	if cfg.SingleWriter {
+		db.conn.writer = newWriter()
 	}
 
 	return db, nil
 }
 
-// Close closes the database connection.
+// Close closes the database connection, after the write in progress in
+// single-writer mode.
 func (db *DB) Close() error {
+	if db.conn.writer != nil {
+		db.conn.writer.stop()
+	}
 	return db.conn.Close()
 }
 
-// migrate runs database migrations using golang-migrate.
-func (db *DB) migrate() error {
+// Ping checks that the database still answers queries against the isos table.
+func (db *DB) Ping() error {
+	var count int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM isos`).Scan(&count); err != nil {
+		return fmt.Errorf("database health check failed: %w", err)
+	}
+	return nil
+}
+
+// Migrate runs pending database migrations using golang-migrate.
+func (db *DB) Migrate() error {
 	// Create a driver instance for golang-migrate
-	driver, err := sqlite.WithInstance(db.conn, &sqlite.Config{})
+	driver, err := sqlite.WithInstance(db.conn.DB, &sqlite.Config{})
 	if err != nil {
 		return fmt.Errorf("failed to create migration driver: %w", err)
 	}
 
-	// Find migrations directory - try multiple paths for production and tests
-	migrationsPath := findMigrationsPath()
-	sourceURL := fmt.Sprintf("file://%s", migrationsPath)
-
 	// Create migrate instance with file source
 	m, err := migrate.NewWithDatabaseInstance(
-		sourceURL,
+		migrationsSourceURL(),
 		"sqlite", driver)
 	if err != nil {
 		return fmt.Errorf("failed to create migrate instance: %w", err)
@@ -130,13 +226,89 @@ func (db *DB) migrate() error {
 
 	// Run migrations
 	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return fmt.Errorf("failed to run migrations: %w", err)
+		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
 	slog.Info("Database migrations completed successfully")
 	return nil
 }
 
+// SchemaStatus describes how the schema compares to this build's migrations.
+type SchemaStatus struct {
+	Version uint // Last applied migration; 0 for an empty database
+	Latest  uint // Last migration shipped with this build
+	Dirty   bool // A migration failed partway and needs fixing by hand
+}
+
+// Pending reports whether the schema needs migrating, or fixing, before the
+// server can use it.
+func (s *SchemaStatus) Pending() bool {
+	return s.Dirty || s.Version < s.Latest
+}
+
+// SchemaStatus reads the applied migration version without changing anything.
+func (db *DB) SchemaStatus() (*SchemaStatus, error) {
+	latest, err := latestMigration(migrationsSourceURL())
+	if err != nil {
+		return nil, err
+	}
+	status := &SchemaStatus{Latest: latest}
+
+	var tables int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`).Scan(&tables); err != nil {
+		return nil, fmt.Errorf("failed to look up schema_migrations: %w", err)
+	}
+	if tables == 0 {
+		return status, nil
+	}
+	err = db.conn.QueryRow(`SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&status.Version, &status.Dirty)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return status, nil
+}
+
+// checkSchema fails with ErrMigrationsPending unless the schema is current.
+func (db *DB) checkSchema() error {
+	status, err := db.SchemaStatus()
+	if err != nil {
+		return err
+	}
+	if status.Pending() {
+		return fmt.Errorf("%w (version=%d, latest=%d, dirty=%t)", ErrMigrationsPending, status.Version, status.Latest, status.Dirty)
+	}
+	return nil
+}
+
+// latestMigration returns the highest migration version at sourceURL.
+func latestMigration(sourceURL string) (uint, error) {
+	src, err := source.Open(sourceURL)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open migrations: %w", err)
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read migrations: %w", err)
+		}
+		version = next
+	}
+}
+
+// migrationsSourceURL locates the migrations directory for golang-migrate.
+func migrationsSourceURL() string {
+	return fmt.Sprintf("file://%s", findMigrationsPath())
+}
+
 // Checks multiple paths to work in both production and test environments.
 func findMigrationsPath() string {
 	paths := []string{
@@ -157,14 +329,49 @@ func findMigrationsPath() string {
 
 // CreateISO inserts a new ISO record into the database.
 func (db *DB) CreateISO(iso *models.ISO) error {
+	return insertISO(db.conn, iso)
+}
+
+// ReplaceISO deletes the failed or canceled ISO oldID and inserts iso in its
+// place in one transaction, so the unique combination is never left free for
+// a racing create. It returns ErrISOExists when oldID is gone or no longer
+// failed or canceled, e.g. because a retry started in the meantime.
+func (db *DB) ReplaceISO(oldID string, iso *models.ISO) error {
+	return db.inTx("ISO replacement", func(tx *sql.Tx) error {
+		result, err := tx.Exec(`DELETE FROM isos WHERE id = ? AND status IN (?, ?)`, oldID, models.StatusFailed, models.StatusCanceled)
+		if err != nil {
+			return fmt.Errorf("failed to delete ISO record (id=%s): %w", oldID, err)
+		}
+		if n, err := result.RowsAffected(); err != nil || n == 0 {
+			return fmt.Errorf("%w (id=%s is not failed or canceled)", ErrISOExists, oldID)
+		}
+		if _, err := tx.Exec(`DELETE FROM download_events WHERE iso_id = ?`, oldID); err != nil {
+			return fmt.Errorf("failed to delete download events (id=%s): %w", oldID, err)
+		}
+		if _, err := tx.Exec(`DELETE FROM download_logs WHERE iso_id = ?`, oldID); err != nil {
+			return fmt.Errorf("failed to delete download log (id=%s): %w", oldID, err)
+		}
+		return insertISO(tx, iso)
+	})
+}
+
+// insertISO inserts iso through exec.
+func insertISO(exec execer, iso *models.ISO) error {
 	query := `
 	INSERT INTO isos (
 		id, name, version, arch, edition, file_type, filename, file_path, download_link,
-		size_bytes, checksum, checksum_type, download_url, checksum_url,
-		status, progress, error_message, created_at, completed_at, download_count
-	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		size_bytes, checksum, checksum_type, download_url, checksum_url, ip_family,
+		status, progress, error_message, error_reason, created_at, completed_at, download_count,
+		upstream_etag, upstream_last_modified, upstream_changed, upstream_checked_at,
+		sha256, sha512, md5, integrity_hash, file_inode, file_mtime, credential, preset, pinned, bytes_served,
+		signature_url, signature_signer, verified_at, source_url, source_headers, download_started_at, redownload, checksum_pending
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	_, err := db.conn.Exec(
+	sourceHeaders, err := encodeSourceHeaders(iso)
+	if err != nil {
+		return err
+	}
+	_, err = exec.Exec(
 		query,
 		iso.ID,
 		iso.Name,
@@ -180,19 +387,54 @@ func (db *DB) CreateISO(iso *models.ISO) error {
 		iso.ChecksumType,
 		iso.DownloadURL,
 		iso.ChecksumURL,
+		iso.IPFamily,
 		iso.Status,
 		iso.Progress,
 		iso.ErrorMessage,
+		iso.ErrorReason,
 		iso.CreatedAt,
 		iso.CompletedAt,
 		iso.DownloadCount,
+		iso.UpstreamETag,
+		iso.UpstreamLastModified,
+		iso.UpstreamChanged,
+		iso.UpstreamCheckedAt,
+		iso.SHA256,
+		iso.SHA512,
+		iso.MD5,
+		iso.IntegrityHash,
+		iso.FileInode,
+		iso.FileModTime,
+		iso.Credential,
+		iso.Preset,
+		iso.Pinned,
+		iso.BytesServed,
+		iso.SignatureURL,
+		iso.SignatureSigner,
+		iso.VerifiedAt,
+		iso.SourceURL,
+		sourceHeaders,
+		iso.DownloadStartedAt,
+		iso.Redownload,
+		iso.ChecksumPending,
 	)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("%w (name=%s, version=%s, arch=%s, edition=%s, file_type=%s)",
+			ErrISOExists, iso.Name, iso.Version, iso.Arch, iso.Edition, iso.FileType)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to insert ISO record (id=%s): %w", iso.ID, err)
 	}
 	return nil
 }
 
+// isUniqueViolation reports whether err is SQLite rejecting a row that
+// duplicates a UNIQUE constraint.
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlitedriver.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
+
 // GetISO retrieves a single ISO by ID.
 func (db *DB) GetISO(id string) (*models.ISO, error) {
 	query := fmt.Sprintf("SELECT %s FROM isos WHERE id = ?", isoSelectFields)
@@ -200,7 +442,7 @@ func (db *DB) GetISO(id string) (*models.ISO, error) {
 
 	iso, err := scanISO(row)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("ISO not found (id=%s)", id)
+		return nil, fmt.Errorf("%w (id=%s)", ErrISONotFound, id)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan ISO record (id=%s): %w", id, err)
@@ -214,38 +456,39 @@ type ListISOsParams struct {
 	PageSize int    // Number of items per page
 	SortBy   string // Column to sort by
 	SortDir  string // Sort direction: "asc" or "desc"
+	Cursor   string // NextCursor of the previous page; Page and sorting are then ignored
 }
 
-// ListISOsResult contains the result of listing ISOs with pagination.
+// ListISOsResult contains the result of listing ISOs with pagination. A
+// cursor page doesn't count the catalog, so Total, Page, and TotalPages are
+// zero.
 type ListISOsResult struct {
 	ISOs       []models.ISO
+	NextCursor string // Continues after this page; empty on the last page or unless sorted by created_at
 	Total      int
 	Page       int
 	PageSize   int
 	TotalPages int
 }
 
-// allowedSortColumns defines which columns can be sorted.
-var allowedSortColumns = map[string]bool{
-	"name":       true,
-	"version":    true,
-	"size_bytes": true,
-	"created_at": true,
-	"status":     true,
+// isoSortKeys maps the sort keys ListISOsPaginated accepts to the SQL they
+// order by.
+var isoSortKeys = map[string]string{
+	"name":           "name",
+	"version":        "version",
+	"size":           "size_bytes",
+	"size_bytes":     "size_bytes",
+	"created_at":     "created_at",
+	"status":         "status",
+	"download_count": "download_count",
+	// Latest download event; ISOs never downloaded, or whose events were
+	// pruned or reset, sort last when descending
+	"last_downloaded_at": "(SELECT MAX(downloaded_at) FROM download_events WHERE iso_id = isos.id)",
 }
 
-// ListISOs retrieves all ISOs ordered by created_at DESC (for backwards compatibility).
+// ListISOs retrieves all ISOs, pinned first, then by created_at DESC.
 func (db *DB) ListISOs() ([]models.ISO, error) {
-	result, err := db.ListISOsPaginated(ListISOsParams{
-		Page:     1,
-		PageSize: 10000, // Large number to get all
-		SortBy:   "created_at",
-		SortDir:  "desc",
-	})
-	if err != nil {
-		return nil, err
-	}
-	return result.ISOs, nil
+	return db.queryISOs(fmt.Sprintf("SELECT %s FROM isos ORDER BY pinned DESC, created_at DESC, id DESC", isoSelectFields))
 }
 
 // ListISOsPaginated retrieves ISOs with pagination and sorting.
@@ -260,10 +503,13 @@ func (db *DB) ListISOsPaginated(params ListISOsParams) (*ListISOsResult, error)
 	if params.PageSize > 100 {
 		params.PageSize = 100
 	}
+	if params.Cursor != "" {
+		return db.listISOsAfter(params)
+	}
 
-	// Validate sort column
+	// Validate sort key
 	sortBy := "created_at"
-	if params.SortBy != "" && allowedSortColumns[params.SortBy] {
+	if _, ok := isoSortKeys[params.SortBy]; ok {
 		sortBy = params.SortBy
 	}
 
@@ -283,9 +529,10 @@ func (db *DB) ListISOsPaginated(params ListISOsParams) (*ListISOsResult, error)
 	// Calculate offset
 	offset := (params.Page - 1) * params.PageSize
 
-	// Build query with sorting and pagination
-	query := fmt.Sprintf("SELECT %s FROM isos ORDER BY %s %s LIMIT ? OFFSET ?",
-		isoSelectFields, sortBy, sortDir)
+	// Build query with sorting and pagination; pinned ISOs come first, and
+	// the id breaks ties so pages and cursors agree on the order
+	query := fmt.Sprintf("SELECT %s FROM isos ORDER BY pinned DESC, %s %s, id %s LIMIT ? OFFSET ?",
+		isoSelectFields, isoSortKeys[sortBy], sortDir, sortDir)
 
 	rows, err := db.conn.Query(query, params.PageSize, offset) //nolint:sqlclosecheck // False positive: rows are closed via deferred closure below
 	if err != nil {
@@ -312,8 +559,17 @@ func (db *DB) ListISOsPaginated(params ListISOsParams) (*ListISOsResult, error)
 	// Calculate total pages
 	totalPages := (total + params.PageSize - 1) / params.PageSize
 
+	// Offer a cursor so deep pages needn't skip over everything before them
+	var nextCursor string
+	if sortBy == "created_at" && len(isos) > 0 && offset+len(isos) < total {
+		if nextCursor, err = db.newISOCursor(&isos[len(isos)-1], sortDir == "ASC"); err != nil {
+			return nil, err
+		}
+	}
+
 	return &ListISOsResult{
 		ISOs:       isos,
+		NextCursor: nextCursor,
 		Total:      total,
 		Page:       params.Page,
 		PageSize:   params.PageSize,
@@ -321,6 +577,50 @@ func (db *DB) ListISOsPaginated(params ListISOsParams) (*ListISOsResult, error)
 	}, nil
 }
 
+// listISOsAfter returns the page after a cursor. It seeks the
+// (pinned, created_at, id) index instead of counting and skipping rows, so
+// deep pages cost the same as the first.
+func (db *DB) listISOsAfter(params ListISOsParams) (*ListISOsResult, error) {
+	cursor, err := parseISOCursor(params.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	cmp, dir := "<", "DESC"
+	if cursor.Asc {
+		cmp, dir = ">", "ASC"
+	}
+	// One extra row tells whether there is a next page
+	limit := params.PageSize + 1
+
+	// The rest of the cursor's group (pinned or not), then the unpinned ISOs
+	// after the pinned ones. Each query is a single seek into the index.
+	query := fmt.Sprintf(`SELECT %s FROM isos WHERE pinned = ? AND (created_at, id) %s (?, ?)
+		ORDER BY created_at %s, id %s LIMIT ?`, isoSelectFields, cmp, dir, dir)
+	isos, err := db.queryISOs(query, cursor.Pinned, cursor.CreatedAt, cursor.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if cursor.Pinned && len(isos) < limit {
+		query = fmt.Sprintf(`SELECT %s FROM isos WHERE pinned = 0
+			ORDER BY created_at %s, id %s LIMIT ?`, isoSelectFields, dir, dir)
+		unpinned, err := db.queryISOs(query, limit-len(isos))
+		if err != nil {
+			return nil, err
+		}
+		isos = append(isos, unpinned...)
+	}
+
+	result := &ListISOsResult{ISOs: isos, PageSize: params.PageSize}
+	if len(isos) > params.PageSize {
+		result.ISOs = isos[:params.PageSize]
+		if result.NextCursor, err = db.newISOCursor(&result.ISOs[params.PageSize-1], cursor.Asc); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
 // UpdateISO updates an existing ISO record.
 func (db *DB) UpdateISO(iso *models.ISO) error {
 	query := `
@@ -328,11 +628,19 @@ func (db *DB) UpdateISO(iso *models.ISO) error {
 		name = ?, version = ?, arch = ?, edition = ?, file_type = ?,
 		filename = ?, file_path = ?, download_link = ?,
 		size_bytes = ?, checksum = ?, checksum_type = ?,
-		download_url = ?, checksum_url = ?, status = ?, progress = ?,
-		error_message = ?, completed_at = ?
+		download_url = ?, checksum_url = ?, ip_family = ?, status = ?, progress = ?,
+		error_message = ?, error_reason = ?, completed_at = ?,
+		upstream_etag = ?, upstream_last_modified = ?, upstream_changed = ?, upstream_checked_at = ?,
+		sha256 = ?, sha512 = ?, md5 = ?, integrity_hash = ?, file_inode = ?, file_mtime = ?,
+		credential = ?, signature_url = ?, signature_signer = ?, verified_at = ?,
+		source_url = ?, source_headers = ?, download_started_at = ?, redownload = ?, checksum_pending = ?
 	WHERE id = ?
 	`
-	_, err := db.conn.Exec(
+	sourceHeaders, err := encodeSourceHeaders(iso)
+	if err != nil {
+		return err
+	}
+	_, err = db.conn.Exec(
 		query,
 		iso.Name,
 		iso.Version,
@@ -347,10 +655,31 @@ func (db *DB) UpdateISO(iso *models.ISO) error {
 		iso.ChecksumType,
 		iso.DownloadURL,
 		iso.ChecksumURL,
+		iso.IPFamily,
 		iso.Status,
 		iso.Progress,
 		iso.ErrorMessage,
+		iso.ErrorReason,
 		iso.CompletedAt,
+		iso.UpstreamETag,
+		iso.UpstreamLastModified,
+		iso.UpstreamChanged,
+		iso.UpstreamCheckedAt,
+		iso.SHA256,
+		iso.SHA512,
+		iso.MD5,
+		iso.IntegrityHash,
+		iso.FileInode,
+		iso.FileModTime,
+		iso.Credential,
+		iso.SignatureURL,
+		iso.SignatureSigner,
+		iso.VerifiedAt,
+		iso.SourceURL,
+		sourceHeaders,
+		iso.DownloadStartedAt,
+		iso.Redownload,
+		iso.ChecksumPending,
 		iso.ID,
 	)
 	if err != nil {
@@ -359,19 +688,65 @@ func (db *DB) UpdateISO(iso *models.ISO) error {
 	return nil
 }
 
-// UpdateISOStatus updates the status and error message of an ISO.
+// UpdateISOStatus updates the status and error message of an ISO and clears any error reason.
 func (db *DB) UpdateISOStatus(id string, status models.ISOStatus, errorMsg string) error {
-	query := `UPDATE isos SET status = ?, error_message = ? WHERE id = ?`
-	if _, err := db.conn.Exec(query, status, errorMsg, id); err != nil {
+	query := `UPDATE isos SET status = ?, error_message = ?, error_reason = '', progress_at = ? WHERE id = ?`
+	if _, err := db.conn.Exec(query, status, errorMsg, lockTime(time.Now()), id); err != nil {
+		return fmt.Errorf("failed to update ISO status (id=%s, status=%s): %w", id, status, err)
+	}
+	return nil
+}
+
+// UpdateISOStatusAndProgress updates status, progress, and error message in a single statement
+// and clears any error reason.
+func (db *DB) UpdateISOStatusAndProgress(id string, status models.ISOStatus, progress int, errorMsg string) error {
+	query := `UPDATE isos SET status = ?, progress = ?, error_message = ?, error_reason = '', progress_at = ? WHERE id = ?`
+	if _, err := db.conn.Exec(query, status, progress, errorMsg, lockTime(time.Now()), id); err != nil {
 		return fmt.Errorf("failed to update ISO status (id=%s, status=%s): %w", id, status, err)
 	}
 	return nil
 }
 
+// UpdateISOFailure marks an ISO as failed with a classified error reason.
+func (db *DB) UpdateISOFailure(id string, progress int, reason models.ErrorReason, errorMsg string) error {
+	query := `UPDATE isos SET status = ?, progress = ?, error_message = ?, error_reason = ? WHERE id = ?`
+	if _, err := db.conn.Exec(query, models.StatusFailed, progress, errorMsg, reason, id); err != nil {
+		return fmt.Errorf("failed to update ISO failure (id=%s, reason=%s): %w", id, reason, err)
+	}
+	return nil
+}
+
+// UpdateISOUpstreamCheck records the result of an upstream change check.
+func (db *DB) UpdateISOUpstreamCheck(id string, changed bool, checkedAt time.Time) error {
+	query := `UPDATE isos SET upstream_changed = ?, upstream_checked_at = ? WHERE id = ?`
+	if _, err := db.conn.Exec(query, changed, checkedAt, id); err != nil {
+		return fmt.Errorf("failed to update ISO upstream check (id=%s): %w", id, err)
+	}
+	return nil
+}
+
+// UpdateISOPinned pins or unpins an ISO.
+func (db *DB) UpdateISOPinned(id string, pinned bool) error {
+	query := `UPDATE isos SET pinned = ? WHERE id = ?`
+	if _, err := db.conn.Exec(query, pinned, id); err != nil {
+		return fmt.Errorf("failed to update ISO pin (id=%s): %w", id, err)
+	}
+	return nil
+}
+
+// UpdateISOIntegrityHash records the integrity hash of an ISO's file on disk.
+func (db *DB) UpdateISOIntegrityHash(id, integrityHash string) error {
+	query := `UPDATE isos SET integrity_hash = ? WHERE id = ?`
+	if _, err := db.conn.Exec(query, integrityHash, id); err != nil {
+		return fmt.Errorf("failed to update ISO integrity hash (id=%s): %w", id, err)
+	}
+	return nil
+}
+
 // UpdateISOProgress updates the progress of an ISO.
 func (db *DB) UpdateISOProgress(id string, progress int) error {
-	query := `UPDATE isos SET progress = ? WHERE id = ?`
-	if _, err := db.conn.Exec(query, progress, id); err != nil {
+	query := `UPDATE isos SET progress = ?, progress_at = ? WHERE id = ?`
+	if _, err := db.conn.Exec(query, progress, lockTime(time.Now()), id); err != nil {
 		return fmt.Errorf("failed to update ISO progress (id=%s, progress=%d): %w", id, progress, err)
 	}
 	return nil
@@ -395,13 +770,13 @@ func (db *DB) UpdateISOChecksum(id string, checksum string) error {
 	return nil
 }
 
-// DeleteISO deletes an ISO record from the database.
+// DeleteISO deletes an ISO record and its download log from the database.
 func (db *DB) DeleteISO(id string) error {
 	query := `DELETE FROM isos WHERE id = ?`
 	if _, err := db.conn.Exec(query, id); err != nil {
 		return fmt.Errorf("failed to delete ISO record (id=%s): %w", id, err)
 	}
-	return nil
+	return db.ClearDownloadLog(id)
 }
 
 // ISOExists checks if an ISO with the given combination already exists.
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -74,6 +75,40 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestNewWithoutAutoMigrate(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	cfg := config.Load().Database
+	cfg.AutoMigrate = false
+
+	if _, err := New(dbPath, &cfg); !errors.Is(err, ErrMigrationsPending) {
+		t.Fatalf("New() on an unmigrated database = %v, want ErrMigrationsPending", err)
+	}
+
+	// The explicit migrate step
+	db, err := Open(dbPath, &cfg)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	status, err := db.SchemaStatus()
+	if err != nil || !status.Pending() || status.Version != 0 || status.Latest == 0 {
+		t.Fatalf("SchemaStatus() before migrating = %+v, %v", status, err)
+	}
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("Migrate() failed: %v", err)
+	}
+	status, err = db.SchemaStatus()
+	if err != nil || status.Pending() || status.Version != status.Latest {
+		t.Errorf("SchemaStatus() after migrating = %+v, %v", status, err)
+	}
+	db.Close()
+
+	db, err = New(dbPath, &cfg)
+	if err != nil {
+		t.Fatalf("New() on a migrated database failed: %v", err)
+	}
+	db.Close()
+}
+
 func TestCreateISO(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -99,6 +134,63 @@ func TestCreateISO(t *testing.T) {
 	}
 }
 
+func TestCreateISO_Duplicate(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	iso := createTestISO()
+	if err := db.CreateISO(iso); err != nil {
+		t.Fatalf("CreateISO() failed: %v", err)
+	}
+
+	// Same composite key under a new ID
+	if err := db.CreateISO(createTestISO()); !errors.Is(err, ErrISOExists) {
+		t.Errorf("Expected ErrISOExists, got: %v", err)
+	}
+
+	// A reused ID is a different failure
+	reused := createTestISO()
+	reused.ID = iso.ID
+	reused.Version = "2.0"
+	if err := db.CreateISO(reused); err == nil || errors.Is(err, ErrISOExists) {
+		t.Errorf("Expected a plain insert error for a duplicate ID, got: %v", err)
+	}
+}
+
+func TestReplaceISO(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	old := createTestISO()
+	db.CreateISO(old)
+	db.RecordDownloadEvent(old.ID, time.Now())
+
+	// Only failed or canceled ISOs can be replaced
+	if err := db.ReplaceISO(old.ID, createTestISO()); !errors.Is(err, ErrISOExists) {
+		t.Errorf("Expected ErrISOExists for a pending ISO, got: %v", err)
+	}
+
+	db.UpdateISOStatus(old.ID, models.StatusFailed, "connection reset")
+	replacement := createTestISO()
+	if err := db.ReplaceISO(old.ID, replacement); err != nil {
+		t.Fatalf("ReplaceISO() failed: %v", err)
+	}
+	if _, err := db.GetISO(old.ID); err == nil {
+		t.Error("Expected the replaced ISO to be gone")
+	}
+	if got, err := db.GetISO(replacement.ID); err != nil || got.Status != models.StatusPending {
+		t.Errorf("Expected the replacement pending, got %+v, %v", got, err)
+	}
+	if events, _ := db.ListDownloadEvents(old.ID, 10); len(events) != 0 {
+		t.Errorf("Expected the replaced ISO's events deleted, got %d", len(events))
+	}
+
+	// The old ID is gone now
+	if err := db.ReplaceISO(old.ID, createTestISO()); !errors.Is(err, ErrISOExists) {
+		t.Errorf("Expected ErrISOExists for a missing ISO, got: %v", err)
+	}
+}
+
 func TestGetISO(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -287,6 +379,40 @@ func TestUpdateISOStatusWithError(t *testing.T) {
 	}
 }
 
+func TestUpdateISOFailure(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	iso := createTestISO()
+	if err := db.CreateISO(iso); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	if err := db.UpdateISOFailure(iso.ID, 0, models.ErrorReasonStalled, "download stalled"); err != nil {
+		t.Fatalf("UpdateISOFailure() failed: %v", err)
+	}
+
+	retrieved, err := db.GetISO(iso.ID)
+	if err != nil {
+		t.Fatalf("GetISO() failed: %v", err)
+	}
+	if retrieved.Status != models.StatusFailed {
+		t.Errorf("Expected Status %s, got %s", models.StatusFailed, retrieved.Status)
+	}
+	if retrieved.ErrorReason != models.ErrorReasonStalled {
+		t.Errorf("Expected ErrorReason %s, got '%s'", models.ErrorReasonStalled, retrieved.ErrorReason)
+	}
+
+	// A later status change clears the reason
+	if err := db.UpdateISOStatus(iso.ID, models.StatusQueued, ""); err != nil {
+		t.Fatalf("UpdateISOStatus() failed: %v", err)
+	}
+	retrieved, _ = db.GetISO(iso.ID)
+	if retrieved.ErrorReason != models.ErrorReasonNone {
+		t.Errorf("Expected ErrorReason to be cleared, got '%s'", retrieved.ErrorReason)
+	}
+}
+
 func TestUpdateISOProgress(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -688,6 +814,33 @@ func TestListISOsPaginated(t *testing.T) {
 		// All ISOs have same name, so just verify no error
 	})
 
+	t.Run("SortByPopularityAndRecency", func(t *testing.T) {
+		page, err := db.ListISOsPaginated(ListISOsParams{PageSize: 3})
+		if err != nil {
+			t.Fatalf("ListISOsPaginated() failed: %v", err)
+		}
+		popular, recent := page.ISOs[0].ID, page.ISOs[1].ID
+		for i := 0; i < 3; i++ {
+			db.IncrementDownloadCount(popular)
+			db.RecordDownloadEvent(popular, time.Now().Add(-48*time.Hour))
+		}
+		db.IncrementDownloadCount(recent)
+		db.RecordDownloadEvent(recent, time.Now())
+
+		for key, want := range map[string]string{"download_count": popular, "last_downloaded_at": recent} {
+			result, err := db.ListISOsPaginated(ListISOsParams{SortBy: key, SortDir: "desc", PageSize: 2})
+			if err != nil {
+				t.Fatalf("ListISOsPaginated(%s) failed: %v", key, err)
+			}
+			if result.ISOs[0].ID != want {
+				t.Errorf("Expected %s first by %s, got %s", want, key, result.ISOs[0].ID)
+			}
+			if result.NextCursor != "" {
+				t.Errorf("Expected no cursor sorting by %s, got %q", key, result.NextCursor)
+			}
+		}
+	})
+
 	t.Run("InvalidSortColumn", func(t *testing.T) {
 		result, err := db.ListISOsPaginated(ListISOsParams{
 			SortBy: "invalid_column",
@@ -701,6 +854,91 @@ func TestListISOsPaginated(t *testing.T) {
 			t.Error("Expected ISOs to be returned")
 		}
 	})
+
+	t.Run("PinnedFirst", func(t *testing.T) {
+		oldest, err := db.ListISOsPaginated(ListISOsParams{SortDir: "asc", PageSize: 1})
+		if err != nil {
+			t.Fatalf("ListISOsPaginated() failed: %v", err)
+		}
+		pinned := oldest.ISOs[0].ID
+		if err := db.UpdateISOPinned(pinned, true); err != nil {
+			t.Fatalf("UpdateISOPinned() failed: %v", err)
+		}
+
+		result, err := db.ListISOsPaginated(ListISOsParams{SortDir: "desc"})
+		if err != nil {
+			t.Fatalf("ListISOsPaginated() failed: %v", err)
+		}
+		if result.ISOs[0].ID != pinned || !result.ISOs[0].Pinned {
+			t.Errorf("Expected the pinned ISO first, got %+v", result.ISOs[0])
+		}
+	})
+}
+
+func TestListISOsCursor(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// Ties on created_at are broken by id, and pinned ISOs still come first
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < 23; i++ {
+		iso := createTestISO()
+		iso.Version = fmt.Sprintf("1.0.%d", i)
+		iso.Filename = fmt.Sprintf("test-iso-1.0.%d-x86_64.iso", i)
+		iso.CreatedAt = base.Add(time.Duration(i/3) * time.Minute)
+		iso.Pinned = i%7 == 0
+		if err := db.CreateISO(iso); err != nil {
+			t.Fatalf("CreateISO() failed: %v", err)
+		}
+	}
+
+	for _, dir := range []string{"desc", "asc"} {
+		t.Run(dir, func(t *testing.T) {
+			// The offset listing in one page is the order the cursor must follow
+			all, err := db.ListISOsPaginated(ListISOsParams{PageSize: 100, SortDir: dir})
+			if err != nil {
+				t.Fatalf("ListISOsPaginated() failed: %v", err)
+			}
+			if all.NextCursor != "" {
+				t.Errorf("Expected no cursor after the last page, got %q", all.NextCursor)
+			}
+
+			first, err := db.ListISOsPaginated(ListISOsParams{PageSize: 5, SortDir: dir})
+			if err != nil {
+				t.Fatalf("ListISOsPaginated() failed: %v", err)
+			}
+			walked := first.ISOs
+			cursor := first.NextCursor
+			for pages := 1; cursor != ""; pages++ {
+				if pages > 10 {
+					t.Fatal("Cursor walk did not end")
+				}
+				// Sorting is carried by the cursor, not the request
+				page, err := db.ListISOsPaginated(ListISOsParams{PageSize: 5, SortBy: "name", Cursor: cursor})
+				if err != nil {
+					t.Fatalf("ListISOsPaginated() with a cursor failed: %v", err)
+				}
+				if page.Total != 0 {
+					t.Errorf("Expected a cursor page not to count, got total %d", page.Total)
+				}
+				walked = append(walked, page.ISOs...)
+				cursor = page.NextCursor
+			}
+
+			if len(walked) != len(all.ISOs) {
+				t.Fatalf("Expected %d ISOs walking the cursor, got %d", len(all.ISOs), len(walked))
+			}
+			for i := range walked {
+				if walked[i].ID != all.ISOs[i].ID {
+					t.Fatalf("ISO %d: expected %s, got %s", i, all.ISOs[i].ID, walked[i].ID)
+				}
+			}
+		})
+	}
+
+	if _, err := db.ListISOsPaginated(ListISOsParams{Cursor: "not-a-cursor"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
 }
 
 func TestConcurrentOperations(t *testing.T) {
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/aloks98/isoman/backend/internal/models"
@@ -18,24 +19,136 @@ func (db *DB) IncrementDownloadCount(id string) error {
 	return nil
 }
 
+// AddBytesServed adds n to the bytes served for an ISO.
+func (db *DB) AddBytesServed(id string, n int64) error {
+	query := `UPDATE isos SET bytes_served = bytes_served + ? WHERE id = ?`
+	if _, err := db.conn.Exec(query, n, id); err != nil {
+		return fmt.Errorf("failed to add bytes served (id=%s): %w", id, err)
+	}
+	return nil
+}
+
+// AdjustDownloadCount adds delta to the download count for an ISO, never going below zero.
+// Download events are left alone, so trends keep showing what was actually served.
+func (db *DB) AdjustDownloadCount(id string, delta int64) error {
+	query := `UPDATE isos SET download_count = MAX(download_count + ?, 0) WHERE id = ?`
+	if _, err := db.conn.Exec(query, delta, id); err != nil {
+		return fmt.Errorf("failed to adjust download count (id=%s): %w", id, err)
+	}
+	return nil
+}
+
+// ResetDownloadStats clears the download count, bytes served, and download events for an ISO.
+func (db *DB) ResetDownloadStats(id string) error {
+	return db.inTx("download stats reset", func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`UPDATE isos SET download_count = 0, bytes_served = 0 WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("failed to reset download count (id=%s): %w", id, err)
+		}
+		if _, err := tx.Exec(`DELETE FROM download_events WHERE iso_id = ?`, id); err != nil {
+			return fmt.Errorf("failed to delete download events (id=%s): %w", id, err)
+		}
+		return nil
+	})
+}
+
 // RecordDownloadEvent records a download event for time-based tracking.
 func (db *DB) RecordDownloadEvent(isoID string, downloadedAt time.Time) error {
-	query := `INSERT INTO download_events (iso_id, downloaded_at) VALUES (?, ?)`
+	return db.CreateDownloadEvent(&models.DownloadEvent{ISOID: isoID, DownloadedAt: downloadedAt})
+}
+
+// CreateDownloadEvent records a download event with whatever is known about
+// the client; the client fields may be empty.
+func (db *DB) CreateDownloadEvent(event *models.DownloadEvent) error {
+	query := `INSERT INTO download_events (iso_id, downloaded_at, country, site, client_ip, user_agent) VALUES (?, ?, ?, ?, ?, ?)`
 	// Format as RFC3339 for consistent SQLite timestamp handling
-	_, err := db.conn.Exec(query, isoID, downloadedAt.Format(time.RFC3339))
+	result, err := db.conn.Exec(query, event.ISOID, event.DownloadedAt.Format(time.RFC3339),
+		event.Country, event.Site, event.ClientIP, event.UserAgent)
 	if err != nil {
 		return fmt.Errorf("failed to record download event: %w", err)
 	}
+	if event.ID, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("failed to get download event id: %w", err)
+	}
 	return nil
 }
 
-// GetStats retrieves aggregated statistics.
+// ListDownloadEvents retrieves an ISO's most recent download events, newest first.
+func (db *DB) ListDownloadEvents(isoID string, limit int) ([]models.DownloadEvent, error) {
+	query := `SELECT id, iso_id, downloaded_at, country, site, client_ip, user_agent
+		FROM download_events WHERE iso_id = ? ORDER BY downloaded_at DESC, id DESC LIMIT ?`
+	rows, err := db.conn.Query(query, isoID, limit) //nolint:sqlclosecheck
+	if err != nil {
+		return nil, fmt.Errorf("failed to query download events (iso_id=%s): %w", isoID, err)
+	}
+	defer closeRows(rows)
+
+	events := make([]models.DownloadEvent, 0)
+	for rows.Next() {
+		var e models.DownloadEvent
+		var country, site, clientIP, userAgent sql.NullString
+		if err := rows.Scan(&e.ID, &e.ISOID, &e.DownloadedAt, &country, &site, &clientIP, &userAgent); err != nil {
+			return nil, fmt.Errorf("failed to scan download event: %w", err)
+		}
+		e.Country, e.Site, e.ClientIP, e.UserAgent = country.String, site.String, clientIP.String, userAgent.String
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// DeleteDownloadEventsBefore removes download events recorded before cutoff
+// and returns how many were removed. Download counts are left as they are.
+func (db *DB) DeleteDownloadEventsBefore(cutoff time.Time) (int64, error) {
+	result, err := db.conn.Exec("DELETE FROM download_events WHERE downloaded_at < ?", cutoff.Format(time.RFC3339))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old download events: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// StatsParams contains parameters for the top-downloaded list and grouped breakdown.
+type StatsParams struct {
+	Top     int    // Number of top downloaded ISOs (default 10, max 100)
+	GroupBy string // Optional breakdown column: name, arch, edition, file_type
+	Status  string // Status the top list and groups are limited to; top defaults to complete
+}
+
+// allowedStatsGroupColumns defines which columns stats can be grouped by.
+var allowedStatsGroupColumns = map[string]bool{
+	"name":      true,
+	"arch":      true,
+	"edition":   true,
+	"file_type": true,
+}
+
+// IsValidStatsGroupBy reports whether stats can be grouped by column.
+func IsValidStatsGroupBy(column string) bool {
+	return allowedStatsGroupColumns[column]
+}
+
+// GetStats retrieves aggregated statistics with the default top 10 (for backwards compatibility).
 func (db *DB) GetStats() (*models.Stats, error) {
+	return db.GetStatsWithParams(StatsParams{})
+}
+
+// GetStatsWithParams retrieves aggregated statistics. Totals always cover every
+// ISO; params only shape the top-downloaded list and the grouped breakdown.
+func (db *DB) GetStatsWithParams(params StatsParams) (*models.Stats, error) {
+	// Set defaults
+	if params.Top < 1 {
+		params.Top = 10
+	}
+	if params.Top > 100 {
+		params.Top = 100
+	}
+
 	stats := &models.Stats{
-		ISOsByArch:    make(map[string]int64),
-		ISOsByEdition: make(map[string]int64),
-		ISOsByStatus:  make(map[string]int64),
-		TopDownloaded: make([]models.ISODownloadStat, 0),
+		ISOsByArch:         make(map[string]int64),
+		ISOsByEdition:      make(map[string]int64),
+		ISOsByStatus:       make(map[string]int64),
+		ISOsByVerification: make(map[string]int64),
+		TopDownloaded:      make([]models.ISODownloadStat, 0),
+		DownloadsByCountry: make(map[string]int64),
+		DownloadsBySite:    make(map[string]int64),
 	}
 
 	// Get total counts
@@ -48,6 +161,9 @@ func (db *DB) GetStats() (*models.Stats, error) {
 	if err := db.getStatsByStatus(stats); err != nil {
 		return nil, err
 	}
+	if err := db.getStatsByVerification(stats); err != nil {
+		return nil, err
+	}
 
 	// Get total storage used (only complete ISOs)
 	row = db.conn.QueryRow(`SELECT COALESCE(SUM(size_bytes), 0) FROM isos WHERE status = 'complete'`)
@@ -61,6 +177,12 @@ func (db *DB) GetStats() (*models.Stats, error) {
 		return nil, fmt.Errorf("failed to get total downloads: %w", err)
 	}
 
+	// Get total bytes actually served, to check bandwidth saved against
+	row = db.conn.QueryRow(`SELECT COALESCE(SUM(bytes_served), 0) FROM isos`)
+	if err := row.Scan(&stats.TotalBytesServed); err != nil {
+		return nil, fmt.Errorf("failed to get total bytes served: %w", err)
+	}
+
 	// Calculate bandwidth saved: Σ (download_count - 1) × size_bytes for downloads > 1
 	row = db.conn.QueryRow(`SELECT COALESCE(SUM((download_count - 1) * size_bytes), 0) FROM isos WHERE download_count > 1 AND status = 'complete'`)
 	if err := row.Scan(&stats.BandwidthSaved); err != nil {
@@ -77,11 +199,26 @@ func (db *DB) GetStats() (*models.Stats, error) {
 		return nil, err
 	}
 
-	// Get top 10 downloaded ISOs
-	if err := db.getTopDownloaded(stats); err != nil {
+	// Get top downloaded ISOs
+	if err := db.getTopDownloaded(stats, params); err != nil {
 		return nil, err
 	}
 
+	// Get downloads by client location
+	if err := db.getDownloadsByLocation(stats.DownloadsByCountry, "country"); err != nil {
+		return nil, err
+	}
+	if err := db.getDownloadsByLocation(stats.DownloadsBySite, "site"); err != nil {
+		return nil, err
+	}
+
+	// Get the optional breakdown
+	if params.GroupBy != "" && allowedStatsGroupColumns[params.GroupBy] {
+		if err := db.getStatsGroups(stats, params); err != nil {
+			return nil, err
+		}
+	}
+
 	return stats, nil
 }
 
@@ -104,13 +241,52 @@ func (db *DB) getStatsByStatus(stats *models.Stats) error {
 			stats.CompletedISOs = count
 		case "failed":
 			stats.FailedISOs = count
-		case "pending", "downloading", "verifying":
+		case "canceled":
+			stats.CanceledISOs = count
+		case "pending", "queued", "downloading", "verifying":
 			stats.PendingISOs += count
 		}
 	}
 	return rows.Err()
 }
 
+// getStatsByVerification counts ISOs by verification state, grouping on the
+// columns it is derived from so the classification stays in one place. The
+// unverified size, like the total size, only counts complete ISOs.
+func (db *DB) getStatsByVerification(stats *models.Stats) error {
+	rows, err := db.conn.Query(`SELECT status, error_reason, checksum_url != '' OR checksum != '' OR signature_url != '', checksum_pending,
+		COUNT(*), COALESCE(SUM(size_bytes), 0)
+		FROM isos GROUP BY 1, 2, 3, 4`) //nolint:sqlclosecheck
+	if err != nil {
+		return fmt.Errorf("failed to get ISOs by verification: %w", err)
+	}
+	defer closeRows(rows)
+
+	for rows.Next() {
+		var status models.ISOStatus
+		var reason models.ErrorReason
+		var checked, pending bool
+		var count, size int64
+		if err := rows.Scan(&status, &reason, &checked, &pending, &count, &size); err != nil {
+			return err
+		}
+		state := models.ClassifyVerification(status, reason, checked, pending)
+		stats.ISOsByVerification[string(state)] += count
+		switch state {
+		case models.VerificationStateVerified:
+			stats.VerifiedISOs += count
+		case models.VerificationStateFailed:
+			stats.VerificationFailedISOs += count
+		default:
+			stats.UnverifiedISOs += count
+			if status == models.StatusComplete {
+				stats.UnverifiedSizeBytes += size
+			}
+		}
+	}
+	return rows.Err()
+}
+
 func (db *DB) getStatsByArch(stats *models.Stats) error {
 	rows, err := db.conn.Query(`SELECT arch, COUNT(*) FROM isos GROUP BY arch`) //nolint:sqlclosecheck
 	if err != nil {
@@ -147,15 +323,20 @@ func (db *DB) getStatsByEdition(stats *models.Stats) error {
 	return rows.Err()
 }
 
-func (db *DB) getTopDownloaded(stats *models.Stats) error {
+func (db *DB) getTopDownloaded(stats *models.Stats, params StatsParams) error {
+	status := params.Status
+	if status == "" {
+		status = string(models.StatusComplete)
+	}
+
 	//nolint:sqlclosecheck
 	rows, err := db.conn.Query(`
-		SELECT id, name, version, arch, download_count, size_bytes
+		SELECT id, name, version, arch, download_count, size_bytes, bytes_served
 		FROM isos
-		WHERE status = 'complete' AND download_count > 0
+		WHERE status = ? AND download_count > 0
 		ORDER BY download_count DESC
-		LIMIT 10
-	`)
+		LIMIT ?
+	`, status, params.Top)
 	if err != nil {
 		return fmt.Errorf("failed to get top downloaded ISOs: %w", err)
 	}
@@ -163,7 +344,7 @@ func (db *DB) getTopDownloaded(stats *models.Stats) error {
 
 	for rows.Next() {
 		var stat models.ISODownloadStat
-		if err := rows.Scan(&stat.ID, &stat.Name, &stat.Version, &stat.Arch, &stat.DownloadCount, &stat.SizeBytes); err != nil {
+		if err := rows.Scan(&stat.ID, &stat.Name, &stat.Version, &stat.Arch, &stat.DownloadCount, &stat.SizeBytes, &stat.BytesServed); err != nil {
 			return err
 		}
 		stats.TopDownloaded = append(stats.TopDownloaded, stat)
@@ -171,6 +352,56 @@ func (db *DB) getTopDownloaded(stats *models.Stats) error {
 	return rows.Err()
 }
 
+// getDownloadsByLocation counts download events per value of column, which is
+// either country or site. Events without a value are left out.
+func (db *DB) getDownloadsByLocation(counts map[string]int64, column string) error {
+	query := fmt.Sprintf(`SELECT %[1]s, COUNT(*) FROM download_events WHERE %[1]s != '' GROUP BY %[1]s`, column)
+	rows, err := db.conn.Query(query) //nolint:sqlclosecheck
+	if err != nil {
+		return fmt.Errorf("failed to get downloads by %s: %w", column, err)
+	}
+	defer closeRows(rows)
+
+	for rows.Next() {
+		var key string
+		var count int64
+		if err := rows.Scan(&key, &count); err != nil {
+			return err
+		}
+		counts[key] = count
+	}
+	return rows.Err()
+}
+
+func (db *DB) getStatsGroups(stats *models.Stats, params StatsParams) error {
+	// The column comes from allowedStatsGroupColumns, so it is safe to interpolate
+	query := fmt.Sprintf(`
+		SELECT %[1]s, COUNT(*), COALESCE(SUM(download_count), 0),
+			COALESCE(SUM(CASE WHEN status = 'complete' THEN size_bytes ELSE 0 END), 0),
+			COALESCE(SUM(bytes_served), 0)
+		FROM isos
+		WHERE (? = '' OR status = ?)
+		GROUP BY %[1]s
+		ORDER BY SUM(download_count) DESC, %[1]s ASC
+	`, params.GroupBy)
+	rows, err := db.conn.Query(query, params.Status, params.Status) //nolint:sqlclosecheck
+	if err != nil {
+		return fmt.Errorf("failed to get ISOs grouped by %s: %w", params.GroupBy, err)
+	}
+	defer closeRows(rows)
+
+	stats.GroupBy = params.GroupBy
+	stats.Groups = make([]models.StatsGroup, 0)
+	for rows.Next() {
+		var group models.StatsGroup
+		if err := rows.Scan(&group.Key, &group.ISOCount, &group.DownloadCount, &group.SizeBytes, &group.BytesServed); err != nil {
+			return err
+		}
+		stats.Groups = append(stats.Groups, group)
+	}
+	return rows.Err()
+}
+
 // GetDownloadTrends retrieves download trends for a period.
 func (db *DB) GetDownloadTrends(period string, days int) (*models.DownloadTrend, error) {
 	trend := &models.DownloadTrend{
@@ -216,6 +447,29 @@ func (db *DB) GetDownloadTrends(period string, days int) (*models.DownloadTrend,
 	return trend, nil
 }
 
+// GetSyncSummary returns the number of complete ISOs and when the most recent
+// one finished downloading, or nil when none has.
+func (db *DB) GetSyncSummary() (int64, *time.Time, error) {
+	var complete int64
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM isos WHERE status = 'complete'`).Scan(&complete); err != nil {
+		return 0, nil, fmt.Errorf("failed to count complete ISOs: %w", err)
+	}
+
+	var lastSync time.Time
+	err := db.conn.QueryRow(`
+		SELECT completed_at FROM isos
+		WHERE status = 'complete' AND completed_at IS NOT NULL
+		ORDER BY completed_at DESC LIMIT 1
+	`).Scan(&lastSync)
+	if err == sql.ErrNoRows {
+		return complete, nil, nil
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to get last sync time: %w", err)
+	}
+	return complete, &lastSync, nil
+}
+
 // GetISOByFilePath retrieves an ISO by its file path (for download tracking).
 func (db *DB) GetISOByFilePath(filePath string) (*models.ISO, error) {
 	query := fmt.Sprintf("SELECT %s FROM isos WHERE file_path = ?", isoSelectFields)
@@ -231,6 +485,41 @@ func (db *DB) GetISOByFilePath(filePath string) (*models.ISO, error) {
 	return iso, nil
 }
 
+// GetISOsByFilePaths retrieves the ISOs stored at any of the given file paths,
+// keyed by file path (for catalog columns in directory listings).
+func (db *DB) GetISOsByFilePaths(filePaths []string) (map[string]*models.ISO, error) {
+	result := make(map[string]*models.ISO, len(filePaths))
+	if len(filePaths) == 0 {
+		return result, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(filePaths)), ",")
+	args := make([]any, len(filePaths))
+	for i, p := range filePaths {
+		args[i] = p
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM isos WHERE file_path IN (%s)", isoSelectFields, placeholders)
+	rows, err := db.conn.Query(query, args...) //nolint:sqlclosecheck
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ISOs by file path: %w", err)
+	}
+	defer closeRows(rows)
+
+	for rows.Next() {
+		iso, err := scanISO(rows)
+		if err != nil {
+			return nil, err
+		}
+		result[iso.FilePath] = iso
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating ISO rows: %w", err)
+	}
+
+	return result, nil
+}
+
 // closeRows is a helper to safely close rows with error logging.
 func closeRows(rows *sql.Rows) {
 	if err := rows.Close(); err != nil {
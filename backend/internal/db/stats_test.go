@@ -1,6 +1,7 @@
 package db
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -84,6 +85,47 @@ func TestRecordDownloadEvent(t *testing.T) {
 	}
 }
 
+func TestListAndPruneDownloadEvents(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	iso := createTestISO()
+	iso.Status = models.StatusComplete
+	db.CreateISO(iso)
+	db.IncrementDownloadCount(iso.ID)
+
+	now := time.Now()
+	old := &models.DownloadEvent{ISOID: iso.ID, DownloadedAt: now.Add(-48 * time.Hour), ClientIP: "192.0.2.0"}
+	recent := &models.DownloadEvent{ISOID: iso.ID, DownloadedAt: now, ClientIP: "198.51.100.0", UserAgent: "curl/8.5.0", Country: "DE"}
+	for _, event := range []*models.DownloadEvent{old, recent} {
+		if err := db.CreateDownloadEvent(event); err != nil {
+			t.Fatalf("CreateDownloadEvent() failed: %v", err)
+		}
+	}
+
+	events, err := db.ListDownloadEvents(iso.ID, 10)
+	if err != nil {
+		t.Fatalf("ListDownloadEvents() failed: %v", err)
+	}
+	if len(events) != 2 || events[0].ID != recent.ID || events[0].UserAgent != "curl/8.5.0" || events[0].Country != "DE" {
+		t.Fatalf("Expected the recent event first with its client details, got %+v", events)
+	}
+	if events, _ := db.ListDownloadEvents(iso.ID, 1); len(events) != 1 {
+		t.Errorf("Expected the limit applied, got %d events", len(events))
+	}
+
+	n, err := db.DeleteDownloadEventsBefore(now.Add(-24 * time.Hour))
+	if err != nil || n != 1 {
+		t.Fatalf("DeleteDownloadEventsBefore() = %d, %v; want 1", n, err)
+	}
+	if events, _ := db.ListDownloadEvents(iso.ID, 10); len(events) != 1 || events[0].ID != recent.ID {
+		t.Errorf("Expected only the recent event left, got %+v", events)
+	}
+	if retrieved, _ := db.GetISO(iso.ID); retrieved.DownloadCount != 1 {
+		t.Errorf("Expected the download count kept, got %d", retrieved.DownloadCount)
+	}
+}
+
 func TestGetStats_EmptyDatabase(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -205,6 +247,81 @@ func TestGetStats_WithData(t *testing.T) {
 	if stats.ISOsByStatus["pending"] != 1 {
 		t.Errorf("Expected 1 pending ISO, got %d", stats.ISOsByStatus["pending"])
 	}
+
+	// Both complete ISOs have a checksum; the failure wasn't a verification one
+	if stats.ISOsByVerification["verified"] != 2 {
+		t.Errorf("Expected 2 verified ISOs, got %d", stats.ISOsByVerification["verified"])
+	}
+	if stats.ISOsByVerification["unverified"] != 2 {
+		t.Errorf("Expected 2 unverified ISOs, got %d", stats.ISOsByVerification["unverified"])
+	}
+}
+
+// TestGetStats_ByVerification tests that ISOs are counted by verification
+// state rather than by whether a checksum happens to be set.
+func TestGetStats_ByVerification(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	add := func(name string, status models.ISOStatus, reason models.ErrorReason, checked, pending bool) {
+		iso := createTestISO()
+		iso.Name = name
+		iso.Filename = name + ".iso"
+		iso.Status = status
+		iso.ErrorReason = reason
+		iso.ChecksumPending = pending
+		if !checked {
+			iso.Checksum, iso.ChecksumURL = "", ""
+		}
+		if err := db.CreateISO(iso); err != nil {
+			t.Fatalf("CreateISO() failed: %v", err)
+		}
+	}
+	add("verified", models.StatusComplete, models.ErrorReasonNone, true, false)
+	add("quarantined", models.StatusQuarantined, models.ErrorReasonNone, true, false)
+	add("unchecked", models.StatusComplete, models.ErrorReasonNone, false, false)
+	add("pending-checksum", models.StatusComplete, models.ErrorReasonNone, true, true)
+	add("mismatch", models.StatusFailed, models.ErrorReasonVerification, true, false)
+	add("stalled", models.StatusFailed, models.ErrorReasonStalled, true, false)
+
+	stats, err := db.GetStats()
+	if err != nil {
+		t.Fatalf("GetStats() failed: %v", err)
+	}
+
+	want := map[string]int64{"verified": 2, "unverified": 3, "failed": 1}
+	for state, count := range want {
+		if stats.ISOsByVerification[state] != count {
+			t.Errorf("ISOsByVerification[%q] = %d, want %d", state, stats.ISOsByVerification[state], count)
+		}
+	}
+	if stats.VerifiedISOs != 2 || stats.UnverifiedISOs != 3 || stats.VerificationFailedISOs != 1 {
+		t.Errorf("Expected 2 verified, 3 unverified, and 1 failed, got %d, %d, and %d",
+			stats.VerifiedISOs, stats.UnverifiedISOs, stats.VerificationFailedISOs)
+	}
+	// The two complete unverified ISOs; the stalled one has no file
+	if stats.UnverifiedSizeBytes != 2*1024 {
+		t.Errorf("Expected UnverifiedSizeBytes %d, got %d", 2*1024, stats.UnverifiedSizeBytes)
+	}
+
+	// Listed ISOs carry the same state
+	isos, err := db.ListISOs()
+	if err != nil {
+		t.Fatalf("ListISOs() failed: %v", err)
+	}
+	wantISO := map[string]models.VerificationState{
+		"verified":         models.VerificationStateVerified,
+		"quarantined":      models.VerificationStateVerified,
+		"unchecked":        models.VerificationStateUnverified,
+		"pending-checksum": models.VerificationStateUnverified,
+		"mismatch":         models.VerificationStateFailed,
+		"stalled":          models.VerificationStateUnverified,
+	}
+	for _, iso := range isos {
+		if iso.Verification != wantISO[iso.Name] {
+			t.Errorf("%s: Verification = %q, want %q", iso.Name, iso.Verification, wantISO[iso.Name])
+		}
+	}
 }
 
 func TestGetStats_BandwidthSaved(t *testing.T) {
@@ -237,6 +354,43 @@ func TestGetStats_BandwidthSaved(t *testing.T) {
 	}
 }
 
+func TestAddBytesServed(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	iso := createTestISO()
+	iso.Status = models.StatusComplete
+	iso.SizeBytes = 1000
+	db.CreateISO(iso)
+
+	// One full transfer and one interrupted halfway
+	for _, n := range []int64{1000, 500} {
+		if err := db.AddBytesServed(iso.ID, n); err != nil {
+			t.Fatalf("AddBytesServed() failed: %v", err)
+		}
+	}
+	db.IncrementDownloadCount(iso.ID)
+
+	retrieved, _ := db.GetISO(iso.ID)
+	if retrieved.BytesServed != 1500 {
+		t.Errorf("Expected BytesServed 1500, got %d", retrieved.BytesServed)
+	}
+
+	stats, err := db.GetStatsWithParams(StatsParams{GroupBy: "arch"})
+	if err != nil {
+		t.Fatalf("GetStatsWithParams() failed: %v", err)
+	}
+	if stats.TotalBytesServed != 1500 {
+		t.Errorf("Expected TotalBytesServed 1500, got %d", stats.TotalBytesServed)
+	}
+	if len(stats.TopDownloaded) != 1 || stats.TopDownloaded[0].BytesServed != 1500 {
+		t.Errorf("Expected bytes served in top downloaded, got %+v", stats.TopDownloaded)
+	}
+	if len(stats.Groups) != 1 || stats.Groups[0].BytesServed != 1500 {
+		t.Errorf("Expected bytes served in groups, got %+v", stats.Groups)
+	}
+}
+
 func TestGetStats_TopDownloaded(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -382,6 +536,191 @@ func TestGetISOByFilePath(t *testing.T) {
 	})
 }
 
+func TestGetStatsWithParams(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	for i, spec := range []struct {
+		name, arch string
+		status     models.ISOStatus
+		downloads  int
+	}{
+		{"alpine", "x86_64", models.StatusComplete, 5},
+		{"alpine", "aarch64", models.StatusComplete, 3},
+		{"ubuntu", "x86_64", models.StatusComplete, 1},
+		{"debian", "x86_64", models.StatusFailed, 2},
+	} {
+		iso := createTestISO()
+		iso.ID = fmt.Sprintf("iso-%d", i)
+		iso.Name = spec.name
+		iso.Arch = spec.arch
+		iso.Status = spec.status
+		iso.Filename = fmt.Sprintf("%s-%s.iso", spec.name, spec.arch)
+		if err := db.CreateISO(iso); err != nil {
+			t.Fatalf("Setup failed: %v", err)
+		}
+		for j := 0; j < spec.downloads; j++ {
+			db.IncrementDownloadCount(iso.ID)
+		}
+	}
+
+	t.Run("Top", func(t *testing.T) {
+		stats, err := db.GetStatsWithParams(StatsParams{Top: 2})
+		if err != nil {
+			t.Fatalf("GetStatsWithParams() failed: %v", err)
+		}
+		if len(stats.TopDownloaded) != 2 || stats.TopDownloaded[0].DownloadCount != 5 {
+			t.Errorf("Expected top 2 complete ISOs, got %+v", stats.TopDownloaded)
+		}
+		if stats.Groups != nil {
+			t.Error("Groups should be omitted without group_by")
+		}
+	})
+
+	t.Run("StatusFilter", func(t *testing.T) {
+		stats, err := db.GetStatsWithParams(StatsParams{Status: string(models.StatusFailed)})
+		if err != nil {
+			t.Fatalf("GetStatsWithParams() failed: %v", err)
+		}
+		if len(stats.TopDownloaded) != 1 || stats.TopDownloaded[0].Name != "debian" {
+			t.Errorf("Expected only the failed ISO, got %+v", stats.TopDownloaded)
+		}
+		if stats.TotalISOs != 4 {
+			t.Errorf("Totals should ignore the filter, got TotalISOs %d", stats.TotalISOs)
+		}
+	})
+
+	t.Run("GroupBy", func(t *testing.T) {
+		stats, err := db.GetStatsWithParams(StatsParams{GroupBy: "name"})
+		if err != nil {
+			t.Fatalf("GetStatsWithParams() failed: %v", err)
+		}
+		if stats.GroupBy != "name" || len(stats.Groups) != 3 {
+			t.Fatalf("Expected 3 name groups, got %+v", stats.Groups)
+		}
+		first := stats.Groups[0]
+		if first.Key != "alpine" || first.ISOCount != 2 || first.DownloadCount != 8 {
+			t.Errorf("Expected alpine first with 2 ISOs and 8 downloads, got %+v", first)
+		}
+
+		stats, err = db.GetStatsWithParams(StatsParams{GroupBy: "arch", Status: string(models.StatusComplete)})
+		if err != nil {
+			t.Fatalf("GetStatsWithParams() failed: %v", err)
+		}
+		if len(stats.Groups) != 2 || stats.Groups[0].Key != "x86_64" || stats.Groups[0].DownloadCount != 6 {
+			t.Errorf("Expected complete ISOs grouped by arch, got %+v", stats.Groups)
+		}
+	})
+}
+
+func TestAdjustDownloadCount(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	iso := createTestISO()
+	if err := db.CreateISO(iso); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	if err := db.AdjustDownloadCount(iso.ID, 5); err != nil {
+		t.Fatalf("AdjustDownloadCount() failed: %v", err)
+	}
+	retrieved, _ := db.GetISO(iso.ID)
+	if retrieved.DownloadCount != 5 {
+		t.Errorf("Expected download count 5, got %d", retrieved.DownloadCount)
+	}
+
+	// Never goes below zero
+	if err := db.AdjustDownloadCount(iso.ID, -10); err != nil {
+		t.Fatalf("AdjustDownloadCount() failed: %v", err)
+	}
+	retrieved, _ = db.GetISO(iso.ID)
+	if retrieved.DownloadCount != 0 {
+		t.Errorf("Expected download count clamped to 0, got %d", retrieved.DownloadCount)
+	}
+}
+
+func TestResetDownloadStats(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	iso := createTestISO()
+	if err := db.CreateISO(iso); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	other := createTestISO()
+	other.Name = "other"
+	other.Filename = "other.iso"
+	other.FilePath = "other/other.iso"
+	if err := db.CreateISO(other); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	for _, id := range []string{iso.ID, iso.ID, other.ID} {
+		db.IncrementDownloadCount(id)
+		db.AddBytesServed(id, 100)
+		db.RecordDownloadEvent(id, time.Now())
+	}
+
+	if err := db.ResetDownloadStats(iso.ID); err != nil {
+		t.Fatalf("ResetDownloadStats() failed: %v", err)
+	}
+
+	retrieved, _ := db.GetISO(iso.ID)
+	if retrieved.DownloadCount != 0 || retrieved.BytesServed != 0 {
+		t.Errorf("Expected download count and bytes served 0, got %d and %d", retrieved.DownloadCount, retrieved.BytesServed)
+	}
+
+	var events int
+	db.conn.QueryRow(`SELECT COUNT(*) FROM download_events WHERE iso_id = ?`, iso.ID).Scan(&events)
+	if events != 0 {
+		t.Errorf("Expected download events to be cleared, got %d", events)
+	}
+
+	// Other ISOs are untouched
+	retrieved, _ = db.GetISO(other.ID)
+	if retrieved.DownloadCount != 1 || retrieved.BytesServed != 100 {
+		t.Errorf("Expected other ISO to keep its count and bytes served, got %d and %d", retrieved.DownloadCount, retrieved.BytesServed)
+	}
+	db.conn.QueryRow(`SELECT COUNT(*) FROM download_events WHERE iso_id = ?`, other.ID).Scan(&events)
+	if events != 1 {
+		t.Errorf("Expected other ISO to keep its events, got %d", events)
+	}
+}
+
+func TestGetISOsByFilePaths(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	alpine := createTestISO()
+	alpine.FilePath = "alpine/3.19.1/x86_64/alpine.iso"
+	if err := db.CreateISO(alpine); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	ubuntu := createTestISO()
+	ubuntu.Name = "ubuntu"
+	ubuntu.Filename = "ubuntu.iso"
+	ubuntu.FilePath = "ubuntu/24.04/x86_64/ubuntu.iso"
+	if err := db.CreateISO(ubuntu); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	isos, err := db.GetISOsByFilePaths([]string{alpine.FilePath, ubuntu.FilePath, "missing/file.iso"})
+	if err != nil {
+		t.Fatalf("GetISOsByFilePaths() failed: %v", err)
+	}
+	if len(isos) != 2 {
+		t.Fatalf("Expected 2 ISOs, got %d", len(isos))
+	}
+	if isos[alpine.FilePath].ID != alpine.ID || isos[ubuntu.FilePath].ID != ubuntu.ID {
+		t.Error("ISOs should be keyed by file path")
+	}
+
+	isos, err = db.GetISOsByFilePaths(nil)
+	if err != nil || len(isos) != 0 {
+		t.Errorf("Expected empty result for no paths, got %d (err: %v)", len(isos), err)
+	}
+}
+
 func TestGetStats_DownloadingAndVerifyingStatus(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -417,3 +756,70 @@ func TestGetStats_DownloadingAndVerifyingStatus(t *testing.T) {
 		t.Errorf("Expected 1 verifying ISO, got %d", stats.ISOsByStatus["verifying"])
 	}
 }
+
+func TestGetStats_QueuedAndCanceledStatus(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	queuedISO := createTestISO()
+	queuedISO.Name = "queued"
+	queuedISO.Filename = "queued.iso"
+	queuedISO.Status = models.StatusQueued
+	db.CreateISO(queuedISO)
+
+	canceledISO := createTestISO()
+	canceledISO.Name = "canceled"
+	canceledISO.Filename = "canceled.iso"
+	canceledISO.Status = models.StatusCanceled
+	db.CreateISO(canceledISO)
+
+	stats, err := db.GetStats()
+	if err != nil {
+		t.Fatalf("GetStats() failed: %v", err)
+	}
+
+	// Queued counts as pending, canceled is tracked separately from failed
+	if stats.PendingISOs != 1 {
+		t.Errorf("Expected PendingISOs 1, got %d", stats.PendingISOs)
+	}
+	if stats.CanceledISOs != 1 {
+		t.Errorf("Expected CanceledISOs 1, got %d", stats.CanceledISOs)
+	}
+	if stats.FailedISOs != 0 {
+		t.Errorf("Expected FailedISOs 0, got %d", stats.FailedISOs)
+	}
+}
+
+func TestGetSyncSummary(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	complete, lastSync, err := db.GetSyncSummary()
+	if err != nil || complete != 0 || lastSync != nil {
+		t.Errorf("GetSyncSummary() on an empty database = %d, %v, %v", complete, lastSync, err)
+	}
+
+	newest := time.Now().Truncate(time.Second)
+	for i, completedAt := range []time.Time{newest.Add(-time.Hour), newest} {
+		iso := createTestISO()
+		iso.Version = fmt.Sprintf("%d", i)
+		iso.Status = models.StatusComplete
+		iso.CompletedAt = &completedAt
+		if err := db.CreateISO(iso); err != nil {
+			t.Fatalf("CreateISO() failed: %v", err)
+		}
+	}
+	pending := createTestISO()
+	pending.Version = "pending"
+	if err := db.CreateISO(pending); err != nil {
+		t.Fatalf("CreateISO() failed: %v", err)
+	}
+
+	complete, lastSync, err = db.GetSyncSummary()
+	if err != nil {
+		t.Fatalf("GetSyncSummary() failed: %v", err)
+	}
+	if complete != 2 || lastSync == nil || !lastSync.Equal(newest) {
+		t.Errorf("GetSyncSummary() = %d, %v; want 2, %v", complete, lastSync, newest)
+	}
+}
@@ -0,0 +1,257 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+var (
+	// ErrUserNotFound is returned when no user has the requested ID or username.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrSessionNotFound is returned when a session doesn't exist or has expired.
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+const userSelectFields = `id, username, password_hash, created_at, updated_at, last_login_at, totp_secret, totp_enabled, totp_last_step`
+
+func scanUser(s scanner) (*models.User, error) {
+	user := &models.User{}
+	if err := s.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt,
+		&user.TOTPSecret, &user.TOTPEnabled, &user.TOTPLastStep); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// CreateUser inserts a new user.
+func (db *DB) CreateUser(user *models.User) error {
+	query := `INSERT INTO users (id, username, password_hash, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`
+	if _, err := db.conn.Exec(query, user.ID, user.Username, user.PasswordHash, user.CreatedAt, user.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to insert user (username=%s): %w", user.Username, err)
+	}
+	return nil
+}
+
+// GetUser retrieves a user by ID.
+func (db *DB) GetUser(id string) (*models.User, error) {
+	query := fmt.Sprintf("SELECT %s FROM users WHERE id = ?", userSelectFields)
+	user, err := scanUser(db.conn.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w (id=%s)", ErrUserNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan user (id=%s): %w", id, err)
+	}
+	return user, nil
+}
+
+// GetUserByUsername retrieves a user by username, ignoring case.
+func (db *DB) GetUserByUsername(username string) (*models.User, error) {
+	query := fmt.Sprintf("SELECT %s FROM users WHERE username = ?", userSelectFields)
+	user, err := scanUser(db.conn.QueryRow(query, username))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w (username=%s)", ErrUserNotFound, username)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan user (username=%s): %w", username, err)
+	}
+	return user, nil
+}
+
+// CountUsers returns the number of users.
+func (db *DB) CountUsers() (int, error) {
+	var count int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count, nil
+}
+
+// UpdateUserPassword replaces a user's password hash.
+func (db *DB) UpdateUserPassword(id, passwordHash string) error {
+	result, err := db.conn.Exec("UPDATE users SET password_hash = ?, updated_at = ? WHERE id = ?", passwordHash, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update password (id=%s): %w", id, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w (id=%s)", ErrUserNotFound, id)
+	}
+	return nil
+}
+
+// RecordUserLogin sets a user's last login time.
+func (db *DB) RecordUserLogin(id string, at time.Time) error {
+	if _, err := db.conn.Exec("UPDATE users SET last_login_at = ? WHERE id = ?", at, id); err != nil {
+		return fmt.Errorf("failed to record login (id=%s): %w", id, err)
+	}
+	return nil
+}
+
+// SetUserTOTP stores a user's TOTP secret and whether it is enforced. An
+// empty secret turns two-factor off. The replay guard is reset either way.
+func (db *DB) SetUserTOTP(id, secret string, enabled bool) error {
+	query := `UPDATE users SET totp_secret = ?, totp_enabled = ?, totp_last_step = 0, updated_at = ? WHERE id = ?`
+	result, err := db.conn.Exec(query, secret, enabled, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update TOTP (id=%s): %w", id, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w (id=%s)", ErrUserNotFound, id)
+	}
+	return nil
+}
+
+// UseTOTPStep records step as the last accepted TOTP step and reports whether
+// it was newer than the previous one. A false result means the code was
+// already used.
+func (db *DB) UseTOTPStep(id string, step int64) (bool, error) {
+	result, err := db.conn.Exec("UPDATE users SET totp_last_step = ? WHERE id = ? AND totp_last_step < ?", step, id, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to record TOTP step (id=%s): %w", id, err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// ReplaceRecoveryCodes discards a user's recovery codes and stores new ones.
+func (db *DB) ReplaceRecoveryCodes(userID string, codeHashes []string) error {
+	return db.inTx("recovery code replacement", func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = ?", userID); err != nil {
+			return fmt.Errorf("failed to delete recovery codes (user_id=%s): %w", userID, err)
+		}
+		for _, hash := range codeHashes {
+			if _, err := tx.Exec("INSERT INTO recovery_codes (user_id, code_hash) VALUES (?, ?)", userID, hash); err != nil {
+				return fmt.Errorf("failed to insert recovery code (user_id=%s): %w", userID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// UseRecoveryCode marks an unused recovery code as used and reports whether
+// there was one.
+func (db *DB) UseRecoveryCode(userID, codeHash string) (bool, error) {
+	result, err := db.conn.Exec("UPDATE recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL",
+		time.Now(), userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code (user_id=%s): %w", userID, err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// CountRecoveryCodes returns how many unused recovery codes a user has left.
+func (db *DB) CountRecoveryCodes(userID string) (int, error) {
+	var count int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM recovery_codes WHERE user_id = ? AND used_at IS NULL", userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes (user_id=%s): %w", userID, err)
+	}
+	return count, nil
+}
+
+const sessionSelectFields = `id, user_id, ip, user_agent, created_at, expires_at`
+
+func scanSession(s scanner) (*models.Session, error) {
+	session := &models.Session{}
+	if err := s.Scan(&session.ID, &session.UserID, &session.IP, &session.UserAgent, &session.CreatedAt, &session.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// CreateSession inserts a new session.
+func (db *DB) CreateSession(session *models.Session) error {
+	query := `INSERT INTO sessions (id, user_id, ip, user_agent, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)`
+	if _, err := db.conn.Exec(query, session.ID, session.UserID, session.IP, session.UserAgent, session.CreatedAt, session.ExpiresAt); err != nil {
+		return fmt.Errorf("failed to insert session (user_id=%s): %w", session.UserID, err)
+	}
+	return nil
+}
+
+// GetSession retrieves a session that hasn't expired by now.
+func (db *DB) GetSession(id string, now time.Time) (*models.Session, error) {
+	query := fmt.Sprintf("SELECT %s FROM sessions WHERE id = ? AND expires_at > ?", sessionSelectFields)
+	session, err := scanSession(db.conn.QueryRow(query, id, now))
+	if err == sql.ErrNoRows {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan session: %w", err)
+	}
+	return session, nil
+}
+
+// DeleteSession removes a session. Deleting a missing session is not an error.
+func (db *DB) DeleteSession(id string) error {
+	if _, err := db.conn.Exec("DELETE FROM sessions WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// ListSessions retrieves the sessions that haven't expired by now, newest
+// first, with the username of each.
+func (db *DB) ListSessions(now time.Time) ([]models.Session, error) {
+	query := `SELECT s.id, s.user_id, s.ip, s.user_agent, s.created_at, s.expires_at, COALESCE(u.username, '')
+		FROM sessions s LEFT JOIN users u ON u.id = s.user_id
+		WHERE s.expires_at > ? ORDER BY s.created_at DESC`
+	rows, err := db.conn.Query(query, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var session models.Session
+		if err := rows.Scan(&session.ID, &session.UserID, &session.IP, &session.UserAgent,
+			&session.CreatedAt, &session.ExpiresAt, &session.Username); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeSession removes a session, returning ErrSessionNotFound when there is none.
+func (db *DB) RevokeSession(id string) error {
+	result, err := db.conn.Exec("DELETE FROM sessions WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeSessions removes every session of userID, or of every user when
+// userID is empty, except the session exceptID, and returns how many were removed.
+func (db *DB) RevokeSessions(userID, exceptID string) (int64, error) {
+	query := "DELETE FROM sessions WHERE id != ?"
+	args := []any{exceptID}
+	if userID != "" {
+		query += " AND user_id = ?"
+		args = append(args, userID)
+	}
+
+	result, err := db.conn.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// DeleteExpiredSessions removes sessions that expired before now and returns
+// how many were removed.
+func (db *DB) DeleteExpiredSessions(now time.Time) (int64, error) {
+	result, err := db.conn.Exec("DELETE FROM sessions WHERE expires_at <= ?", now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	return result.RowsAffected()
+}
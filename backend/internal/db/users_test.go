@@ -0,0 +1,167 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+func TestUsers(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	user := &models.User{ID: "user-1", Username: "Admin", PasswordHash: "hash", CreatedAt: now, UpdatedAt: now}
+	if err := db.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser() failed: %v", err)
+	}
+	if err := db.CreateUser(&models.User{ID: "user-2", Username: "admin", PasswordHash: "hash", CreatedAt: now, UpdatedAt: now}); err == nil {
+		t.Error("CreateUser() should reject a username differing only in case")
+	}
+
+	got, err := db.GetUserByUsername("ADMIN")
+	if err != nil {
+		t.Fatalf("GetUserByUsername() failed: %v", err)
+	}
+	if got.ID != user.ID || got.LastLoginAt != nil {
+		t.Errorf("GetUserByUsername() = %+v, want user-1 without a login", got)
+	}
+	if _, err := db.GetUserByUsername("nobody"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got: %v", err)
+	}
+
+	if err := db.RecordUserLogin(user.ID, now); err != nil {
+		t.Fatalf("RecordUserLogin() failed: %v", err)
+	}
+	if err := db.UpdateUserPassword(user.ID, "new-hash"); err != nil {
+		t.Fatalf("UpdateUserPassword() failed: %v", err)
+	}
+	got, err = db.GetUser(user.ID)
+	if err != nil {
+		t.Fatalf("GetUser() failed: %v", err)
+	}
+	if got.LastLoginAt == nil || got.PasswordHash != "new-hash" {
+		t.Errorf("Expected login time and new hash, got %+v", got)
+	}
+
+	if count, err := db.CountUsers(); err != nil || count != 1 {
+		t.Errorf("CountUsers() = %d, %v; want 1", count, err)
+	}
+}
+
+func TestUserTOTP(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	user := &models.User{ID: "user-1", Username: "admin", PasswordHash: "hash", CreatedAt: now, UpdatedAt: now}
+	if err := db.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser() failed: %v", err)
+	}
+
+	if err := db.SetUserTOTP(user.ID, "SECRET", true); err != nil {
+		t.Fatalf("SetUserTOTP() failed: %v", err)
+	}
+	if fresh, err := db.UseTOTPStep(user.ID, 10); err != nil || !fresh {
+		t.Errorf("UseTOTPStep(10) = %v, %v; want true", fresh, err)
+	}
+	if fresh, _ := db.UseTOTPStep(user.ID, 10); fresh {
+		t.Error("UseTOTPStep() accepted the same step twice")
+	}
+	got, _ := db.GetUser(user.ID)
+	if !got.TOTPEnabled || got.TOTPSecret != "SECRET" || got.TOTPLastStep != 10 {
+		t.Errorf("Expected TOTP state to round-trip, got %+v", got)
+	}
+
+	if err := db.ReplaceRecoveryCodes(user.ID, []string{"a", "b"}); err != nil {
+		t.Fatalf("ReplaceRecoveryCodes() failed: %v", err)
+	}
+	if used, err := db.UseRecoveryCode(user.ID, "a"); err != nil || !used {
+		t.Errorf("UseRecoveryCode(a) = %v, %v; want true", used, err)
+	}
+	if used, _ := db.UseRecoveryCode(user.ID, "a"); used {
+		t.Error("UseRecoveryCode() accepted a used code")
+	}
+	if count, _ := db.CountRecoveryCodes(user.ID); count != 1 {
+		t.Errorf("CountRecoveryCodes() = %d, want 1", count)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	live := &models.Session{ID: "live", UserID: "user-1", IP: "192.0.2.1", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	expired := &models.Session{ID: "expired", UserID: "user-1", CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour)}
+	for _, session := range []*models.Session{live, expired} {
+		if err := db.CreateSession(session); err != nil {
+			t.Fatalf("CreateSession() failed: %v", err)
+		}
+	}
+
+	got, err := db.GetSession("live", now)
+	if err != nil {
+		t.Fatalf("GetSession() failed: %v", err)
+	}
+	if got.UserID != "user-1" || got.IP != "192.0.2.1" {
+		t.Errorf("GetSession() = %+v", got)
+	}
+	if _, err := db.GetSession("expired", now); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound for an expired session, got: %v", err)
+	}
+
+	if n, err := db.DeleteExpiredSessions(now); err != nil || n != 1 {
+		t.Errorf("DeleteExpiredSessions() = %d, %v; want 1", n, err)
+	}
+	if err := db.DeleteSession("live"); err != nil {
+		t.Fatalf("DeleteSession() failed: %v", err)
+	}
+	if _, err := db.GetSession("live", now); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound after delete, got: %v", err)
+	}
+}
+
+func TestRevokeSessions(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	if err := db.CreateUser(&models.User{ID: "user-1", Username: "admin", PasswordHash: "hash", CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatalf("CreateUser() failed: %v", err)
+	}
+	for i, id := range []string{"a", "b", "c"} {
+		userID := "user-1"
+		if id == "c" {
+			userID = "user-2"
+		}
+		session := &models.Session{ID: id, UserID: userID, CreatedAt: now.Add(time.Duration(i) * time.Minute), ExpiresAt: now.Add(time.Hour)}
+		if err := db.CreateSession(session); err != nil {
+			t.Fatalf("CreateSession() failed: %v", err)
+		}
+	}
+
+	sessions, err := db.ListSessions(now)
+	if err != nil {
+		t.Fatalf("ListSessions() failed: %v", err)
+	}
+	if len(sessions) != 3 || sessions[0].ID != "c" || sessions[2].Username != "admin" {
+		t.Errorf("ListSessions() = %+v, want newest first with usernames", sessions)
+	}
+
+	if err := db.RevokeSession("c"); err != nil {
+		t.Fatalf("RevokeSession() failed: %v", err)
+	}
+	if err := db.RevokeSession("c"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound, got: %v", err)
+	}
+
+	if n, err := db.RevokeSessions("user-1", "a"); err != nil || n != 1 {
+		t.Errorf("RevokeSessions() = %d, %v; want 1", n, err)
+	}
+	if _, err := db.GetSession("a", now); err != nil {
+		t.Errorf("Expected the excepted session to survive, got: %v", err)
+	}
+}
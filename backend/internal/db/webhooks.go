@@ -0,0 +1,102 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// ErrWebhookNotFound is returned when no webhook has the requested name.
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+const webhookSelectFields = `name, template, filter, secret, created_at, updated_at`
+
+func scanWebhook(s scanner) (*models.Webhook, error) {
+	hook := &models.Webhook{}
+	var template string
+	if err := s.Scan(&hook.Name, &template, &hook.Filter, &hook.Secret, &hook.CreatedAt, &hook.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(template), &hook.Template); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook template (name=%s): %w", hook.Name, err)
+	}
+	return hook, nil
+}
+
+// CreateWebhook inserts a new webhook.
+func (db *DB) CreateWebhook(hook *models.Webhook) error {
+	template, err := json.Marshal(hook.Template)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook template (name=%s): %w", hook.Name, err)
+	}
+	query := `INSERT INTO webhooks (name, template, filter, secret, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`
+	if _, err := db.conn.Exec(query, hook.Name, string(template), hook.Filter, hook.Secret, hook.CreatedAt, hook.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to insert webhook (name=%s): %w", hook.Name, err)
+	}
+	return nil
+}
+
+// GetWebhook retrieves a webhook by name.
+func (db *DB) GetWebhook(name string) (*models.Webhook, error) {
+	query := fmt.Sprintf("SELECT %s FROM webhooks WHERE name = ?", webhookSelectFields)
+	hook, err := scanWebhook(db.conn.QueryRow(query, name))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w (name=%s)", ErrWebhookNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan webhook (name=%s): %w", name, err)
+	}
+	return hook, nil
+}
+
+// ListWebhooks retrieves all webhooks ordered by name.
+func (db *DB) ListWebhooks() ([]models.Webhook, error) {
+	query := fmt.Sprintf("SELECT %s FROM webhooks ORDER BY name", webhookSelectFields)
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := []models.Webhook{}
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		hooks = append(hooks, *hook)
+	}
+	return hooks, rows.Err()
+}
+
+// UpdateWebhook replaces the template, filter, and secret of a webhook.
+func (db *DB) UpdateWebhook(hook *models.Webhook) error {
+	template, err := json.Marshal(hook.Template)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook template (name=%s): %w", hook.Name, err)
+	}
+	query := `UPDATE webhooks SET template = ?, filter = ?, secret = ?, updated_at = ? WHERE name = ?`
+	result, err := db.conn.Exec(query, string(template), hook.Filter, hook.Secret, hook.UpdatedAt, hook.Name)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook (name=%s): %w", hook.Name, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w (name=%s)", ErrWebhookNotFound, hook.Name)
+	}
+	return nil
+}
+
+// DeleteWebhook removes a webhook.
+func (db *DB) DeleteWebhook(name string) error {
+	result, err := db.conn.Exec("DELETE FROM webhooks WHERE name = ?", name)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook (name=%s): %w", name, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w (name=%s)", ErrWebhookNotFound, name)
+	}
+	return nil
+}
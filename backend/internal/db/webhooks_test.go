@@ -0,0 +1,61 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+func TestWebhooks(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	hook := &models.Webhook{
+		Name:      "internal-builds",
+		Filter:    `{{ eq .action "published" }}`,
+		Secret:    "v1:a",
+		Template:  models.WebhookTemplate{Name: "internal", Version: "{{ .release.tag_name }}", Arch: "x86_64", DownloadURL: "https://builds.example.com/{{ .release.tag_name }}.iso"},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := db.CreateWebhook(hook); err != nil {
+		t.Fatalf("CreateWebhook() failed: %v", err)
+	}
+	if err := db.CreateWebhook(hook); err == nil {
+		t.Error("CreateWebhook() should reject a duplicate name")
+	}
+
+	got, err := db.GetWebhook("internal-builds")
+	if err != nil {
+		t.Fatalf("GetWebhook() failed: %v", err)
+	}
+	if got.Template != hook.Template || got.Filter != hook.Filter || got.Secret != "v1:a" {
+		t.Errorf("Expected webhook to round-trip, got %+v", got)
+	}
+
+	hook.Template.Arch = "aarch64"
+	hook.Secret = "v1:b"
+	if err := db.UpdateWebhook(hook); err != nil {
+		t.Fatalf("UpdateWebhook() failed: %v", err)
+	}
+	hooks, err := db.ListWebhooks()
+	if err != nil {
+		t.Fatalf("ListWebhooks() failed: %v", err)
+	}
+	if len(hooks) != 1 || hooks[0].Template.Arch != "aarch64" || hooks[0].Secret != "v1:b" {
+		t.Errorf("Expected the updated webhook, got %+v", hooks)
+	}
+
+	if err := db.DeleteWebhook("internal-builds"); err != nil {
+		t.Fatalf("DeleteWebhook() failed: %v", err)
+	}
+	if _, err := db.GetWebhook("internal-builds"); !errors.Is(err, ErrWebhookNotFound) {
+		t.Errorf("Expected ErrWebhookNotFound after delete, got: %v", err)
+	}
+	if err := db.DeleteWebhook("internal-builds"); !errors.Is(err, ErrWebhookNotFound) {
+		t.Errorf("Expected ErrWebhookNotFound deleting twice, got: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"errors"
+	"sync"
+)
+
+// errWriterClosed is returned for writes submitted after the database closed.
+var errWriterClosed = errors.New("database is closed")
+
+// writer runs writes one at a time on its own goroutine, so writes made by
+// this process never contend for SQLite's write lock, however many download
+// workers report progress at once. Reads don't go through it and stay
+// concurrent.
+type writer struct {
+	jobs     chan func()
+	quit     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// newWriter starts the writer goroutine.
+func newWriter() *writer {
+	w := &writer{
+		jobs: make(chan func()),
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *writer) run() {
+	defer close(w.done)
+	for {
+		select {
+		case job := <-w.jobs:
+			job()
+		case <-w.quit:
+			return
+		}
+	}
+}
+
+// do runs op on the writer goroutine and returns its error.
+func (w *writer) do(op func() error) error {
+	errc := make(chan error, 1)
+	select {
+	case w.jobs <- func() { errc <- op() }:
+		return <-errc
+	case <-w.quit:
+		return errWriterClosed
+	}
+}
+
+// stop waits for the write in progress, if any, and stops the goroutine.
+func (w *writer) stop() {
+	w.stopOnce.Do(func() { close(w.quit) })
+	<-w.done
+}
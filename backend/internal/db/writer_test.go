@@ -0,0 +1,56 @@
+package db
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/config"
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+func TestSingleWriter(t *testing.T) {
+	cfg := config.Load().Database
+	cfg.BusyTimeout = 0 // Fail at once on contention, so only serializing can succeed
+	cfg.BusyRetries = 0
+	cfg.SingleWriter = true
+	db, err := New(filepath.Join(t.TempDir(), "test.db"), &cfg)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	iso := createTestISO()
+	if err := db.CreateISO(iso); err != nil {
+		t.Fatalf("CreateISO() failed: %v", err)
+	}
+
+	// Progress updates from many workers, with readers alongside
+	var wg sync.WaitGroup
+	errs := make(chan error, 200)
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				if err := db.UpdateISOStatusAndProgress(iso.ID, models.StatusDownloading, i*4, ""); err != nil {
+					errs <- err
+				}
+				if _, err := db.GetISO(iso.ID); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("Concurrent access failed: %v", err)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if err := db.SetSetting("key", "value"); err == nil {
+		t.Error("Expected writes after Close() to fail")
+	}
+}
@@ -0,0 +1,159 @@
+// Package diag captures runtime diagnostics of the running server: goroutines
+// grouped by where they are blocked, and memory statistics.
+package diag
+
+import (
+	"bufio"
+	"bytes"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// started approximates the process start time.
+var started = time.Now()
+
+// Snapshot captures the runtime's goroutines and memory statistics.
+func Snapshot() *models.RuntimeSnapshot {
+	stacks := goroutineStacks()
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	heap := models.HeapStats{
+		AllocBytes:    mem.HeapAlloc,
+		InuseBytes:    mem.HeapInuse,
+		IdleBytes:     mem.HeapIdle,
+		ReleasedBytes: mem.HeapReleased,
+		SysBytes:      mem.Sys,
+		NextGCBytes:   mem.NextGC,
+		Objects:       mem.HeapObjects,
+		NumGC:         mem.NumGC,
+		PauseTotalMS:  float64(mem.PauseTotalNs) / float64(time.Millisecond),
+	}
+	if mem.LastGC > 0 {
+		lastGC := time.Unix(0, int64(mem.LastGC))
+		heap.LastGC = &lastGC
+	}
+
+	groups, total := GroupGoroutines(stacks)
+	return &models.RuntimeSnapshot{
+		CapturedAt:      time.Now(),
+		StartedAt:       started,
+		GoVersion:       runtime.Version(),
+		GOMAXPROCS:      runtime.GOMAXPROCS(0),
+		NumCPU:          runtime.NumCPU(),
+		Goroutines:      total,
+		GoroutineGroups: groups,
+		Heap:            heap,
+	}
+}
+
+// goroutineStacks returns the stack traces of all goroutines, growing the
+// buffer until they fit.
+func goroutineStacks() []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// GroupGoroutines groups the goroutines of a runtime.Stack dump by state and
+// innermost non-runtime function, most goroutines first, and returns the
+// groups and the number of goroutines.
+func GroupGoroutines(stacks []byte) ([]models.GoroutineGroup, int) {
+	type key struct{ function, state string }
+	groups := make(map[key]*models.GoroutineGroup)
+	total := 0
+
+	var state, function string
+	var wait int
+	collecting := false
+	flush := func() {
+		if state == "" {
+			return
+		}
+		total++
+		k := key{function, state}
+		g, ok := groups[k]
+		if !ok {
+			g = &models.GoroutineGroup{Function: function, State: state}
+			groups[k] = g
+		}
+		g.Count++
+		g.MaxWaitMinutes = max(g.MaxWaitMinutes, wait)
+		state, function, wait = "", "", 0
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(stacks))
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "goroutine "):
+			flush()
+			state, wait = parseHeader(line)
+			collecting = true
+		case line == "" || strings.HasPrefix(line, "\t"):
+		case strings.HasPrefix(line, "created by "):
+			collecting = false
+		case collecting:
+			// Frames run innermost first; take the first outside the runtime
+			name := frameFunction(line)
+			if function == "" || !strings.HasPrefix(name, "runtime.") {
+				function = name
+			}
+			collecting = strings.HasPrefix(function, "runtime.")
+		}
+	}
+	flush()
+
+	result := make([]models.GoroutineGroup, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		if result[i].Function != result[j].Function {
+			return result[i].Function < result[j].Function
+		}
+		return result[i].State < result[j].State
+	})
+	return result, total
+}
+
+// parseHeader parses "goroutine 18 [chan receive, 3 minutes]:" into its
+// state and wait in minutes.
+func parseHeader(line string) (string, int) {
+	open, end := strings.Index(line, "["), strings.LastIndex(line, "]")
+	if open < 0 || end < open {
+		return "unknown", 0
+	}
+	parts := strings.Split(line[open+1:end], ", ")
+	wait := 0
+	for _, part := range parts[1:] {
+		if minutes, ok := strings.CutSuffix(part, " minutes"); ok {
+			wait, _ = strconv.Atoi(minutes)
+		}
+	}
+	return parts[0], wait
+}
+
+// frameFunction strips the arguments from a stack frame's function line.
+func frameFunction(line string) string {
+	if strings.HasSuffix(line, ")") {
+		if i := strings.LastIndex(line, "("); i > 0 {
+			return line[:i]
+		}
+	}
+	return line
+}
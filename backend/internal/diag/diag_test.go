@@ -0,0 +1,83 @@
+package diag
+
+import (
+	"strings"
+	"testing"
+)
+
+const testStacks = `goroutine 1 [running]:
+main.main()
+	/src/main.go:10 +0x1d
+
+goroutine 18 [chan receive, 3 minutes]:
+github.com/aloks98/isoman/backend/internal/download.(*Manager).worker(0xc000123000, 0x0)
+	/src/download/manager.go:300 +0x85
+created by github.com/aloks98/isoman/backend/internal/download.(*Manager).Start in goroutine 1
+	/src/download/manager.go:120 +0x45
+
+goroutine 19 [chan receive, 12 minutes]:
+github.com/aloks98/isoman/backend/internal/download.(*Manager).worker(0xc000123000, 0x1)
+	/src/download/manager.go:300 +0x85
+created by github.com/aloks98/isoman/backend/internal/download.(*Manager).Start in goroutine 1
+	/src/download/manager.go:120 +0x45
+
+goroutine 20 [select]:
+runtime.selectgo(0xc000055f28, 0xc000055ef0, 0x0, 0x0, 0x2, 0x1)
+	/go/src/runtime/select.go:327 +0x7be
+github.com/aloks98/isoman/backend/internal/ws.(*Hub).Run(0xc0000a2000)
+	/src/ws/hub.go:50 +0x10c
+created by main.main in goroutine 1
+	/src/main.go:40 +0x3a
+
+goroutine 21 [chan receive]:
+github.com/aloks98/isoman/backend/internal/download.(*Manager).worker(0xc000123000, 0x2)
+	/src/download/manager.go:300 +0x85
+created by github.com/aloks98/isoman/backend/internal/download.(*Manager).Start in goroutine 1
+	/src/download/manager.go:120 +0x45
+`
+
+func TestGroupGoroutines(t *testing.T) {
+	groups, total := GroupGoroutines([]byte(testStacks))
+	if total != 5 {
+		t.Errorf("total = %d, want 5", total)
+	}
+	if len(groups) != 3 {
+		t.Fatalf("got %d groups, want 3: %+v", len(groups), groups)
+	}
+
+	worker := groups[0]
+	if worker.Function != "github.com/aloks98/isoman/backend/internal/download.(*Manager).worker" ||
+		worker.State != "chan receive" || worker.Count != 3 || worker.MaxWaitMinutes != 12 {
+		t.Errorf("groups[0] = %+v, want 3 workers in chan receive, waiting up to 12 minutes", worker)
+	}
+	// Runtime frames are skipped for the first frame of the server's own code
+	if groups[1].Function != "github.com/aloks98/isoman/backend/internal/ws.(*Hub).Run" || groups[1].State != "select" {
+		t.Errorf("groups[1] = %+v, want the hub in select", groups[1])
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	for range 3 {
+		go func() { <-done }()
+	}
+
+	snapshot := Snapshot()
+	if snapshot.Goroutines < 4 {
+		t.Errorf("Goroutines = %d, want at least 4", snapshot.Goroutines)
+	}
+	// They may not all have reached the receive yet, so count every state
+	count := 0
+	for _, g := range snapshot.GoroutineGroups {
+		if strings.HasSuffix(g.Function, "diag.TestSnapshot.func1") {
+			count += g.Count
+		}
+	}
+	if count < 3 {
+		t.Errorf("Expected the 3 test goroutines, got: %+v", snapshot.GoroutineGroups)
+	}
+	if snapshot.Heap.SysBytes == 0 || snapshot.GoVersion == "" {
+		t.Errorf("Expected memory statistics and the Go version, got: %+v", snapshot)
+	}
+}
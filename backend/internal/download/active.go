@@ -0,0 +1,109 @@
+package download
+
+import (
+	"context"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// activeDownload is an ISO a download worker took from the queue, from then
+// until it is finalized or canceled. The byte counters are written by the
+// transfer and read by ActiveDownloads without holding the manager's lock.
+type activeDownload struct {
+	cancel    context.CancelFunc
+	info      models.ActiveDownload // The fields that don't change while it runs
+	verifying atomic.Bool
+
+	bytes         atomic.Int64 // Received by the current attempt
+	total         atomic.Int64 // Zero when upstream sent no length
+	attemptedAtNs atomic.Int64 // Start of the current attempt, in Unix nanoseconds
+}
+
+// newActiveDownload tracks iso from the moment workerID on node takes it from the queue.
+func newActiveDownload(iso *models.ISO, node string, workerID int, cancel context.CancelFunc) *activeDownload {
+	a := &activeDownload{
+		cancel: cancel,
+		info: models.ActiveDownload{
+			ISOID:     iso.ID,
+			Name:      iso.Name,
+			Version:   iso.Version,
+			Arch:      iso.Arch,
+			Edition:   iso.Edition,
+			Node:      node,
+			WorkerID:  workerID,
+			StartedAt: time.Now(),
+		},
+	}
+	a.attemptedAtNs.Store(a.info.StartedAt.UnixNano())
+	return a
+}
+
+// restart resets the byte counters for a new attempt, which rewrites the
+// file from the start. A nil activeDownload ignores updates.
+func (a *activeDownload) restart() {
+	if a == nil {
+		return
+	}
+	a.bytes.Store(0)
+	a.attemptedAtNs.Store(time.Now().UnixNano())
+}
+
+// transferred records the progress of the current attempt.
+func (a *activeDownload) transferred(downloaded, total int64) {
+	if a == nil {
+		return
+	}
+	a.bytes.Store(downloaded)
+	a.total.Store(total)
+}
+
+// snapshot returns the download as reported by the API at now.
+func (a *activeDownload) snapshot(now time.Time) models.ActiveDownload {
+	d := a.info
+	d.Status = models.StatusDownloading
+	d.BytesDownloaded = a.bytes.Load()
+	d.BytesTotal = a.total.Load()
+	if a.verifying.Load() {
+		d.Status = models.StatusVerifying
+		return d
+	}
+	if elapsed := now.Sub(time.Unix(0, a.attemptedAtNs.Load())); elapsed > 0 {
+		d.BytesPerSec = int64(float64(d.BytesDownloaded) / elapsed.Seconds())
+	}
+	return d
+}
+
+type activeDownloadKey struct{}
+
+// withActiveDownload returns a context the transfer reports its progress through.
+func withActiveDownload(ctx context.Context, a *activeDownload) context.Context {
+	return context.WithValue(ctx, activeDownloadKey{}, a)
+}
+
+// activeDownloadFrom returns the download registered on ctx, or nil.
+func activeDownloadFrom(ctx context.Context) *activeDownload {
+	a, _ := ctx.Value(activeDownloadKey{}).(*activeDownload)
+	return a
+}
+
+// ActiveDownloads returns the downloads workers are currently running or
+// verifying, oldest first, including those other nodes sharing the database
+// hold locks on. Queued downloads aren't included.
+func (m *Manager) ActiveDownloads() []models.ActiveDownload {
+	now := time.Now()
+	m.mu.RLock()
+	downloads := make([]models.ActiveDownload, 0, len(m.activeDownloads))
+	for _, a := range m.activeDownloads {
+		downloads = append(downloads, a.snapshot(now))
+	}
+	m.mu.RUnlock()
+	downloads = append(downloads, m.remoteDownloads()...)
+
+	sort.Slice(downloads, func(i, j int) bool {
+		return downloads[i].StartedAt.Before(downloads[j].StartedAt)
+	})
+	return downloads
+}
@@ -1,6 +1,11 @@
 package download
 
-import "log/slog"
+import (
+	"log/slog"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
 
 // CancelDownload cancels an ongoing download by ISO ID
 // Returns true if a download was canceled, false if no download was active
@@ -8,9 +13,9 @@ func (m *Manager) CancelDownload(isoID string) bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if cancel, exists := m.activeDownloads[isoID]; exists {
+	if active, exists := m.activeDownloads[isoID]; exists {
 		slog.Info("canceling download", slog.String("iso_id", isoID))
-		cancel()
+		active.cancel()
 		delete(m.activeDownloads, isoID)
 		return true
 	}
@@ -18,6 +23,61 @@ func (m *Manager) CancelDownload(isoID string) bool {
 	return false
 }
 
+// CancelQueued cancels an ISO that is waiting in a queue for a worker, marking
+// it canceled so it can be retried at once. It reports false when the ISO
+// isn't waiting here, e.g. because a worker has taken it already.
+func (m *Manager) CancelQueued(isoID string) bool {
+	m.mu.Lock()
+	iso, waiting := m.waiting[isoID]
+	if waiting {
+		// The entry stays in the queue until a worker reaches and skips it
+		delete(m.waiting, isoID)
+		delete(m.inFlight, isoID)
+		m.dropped[iso] = true
+	}
+	m.mu.Unlock()
+	if !waiting {
+		return false
+	}
+
+	slog.Info("canceling queued download", slog.String("iso_id", isoID))
+	if err := m.db.UpdateISOStatus(isoID, models.StatusCanceled, "Download canceled"); err != nil {
+		slog.Warn("failed to mark queued ISO as canceled", slog.String("iso_id", isoID), slog.Any("error", err))
+	}
+	m.notifyProgress(isoID, 0, models.StatusCanceled)
+	m.emit(newQueueEvent(models.QueueEventFailed, iso, models.StatusCanceled))
+	return true
+}
+
+// CancelAndWait cancels an ISO's download and waits up to CANCELLATION_WAIT_MS
+// for its worker to stop and release it, so its files and record can be
+// removed without the worker writing them again. It reports whether the
+// worker stopped in time, which it has when the ISO wasn't running here. A
+// download canceled earlier that is still stopping is waited for too.
+func (m *Manager) CancelAndWait(isoID string) bool {
+	m.CancelDownload(isoID)
+
+	m.mu.RLock()
+	stopped, running := m.stopped[isoID]
+	m.mu.RUnlock()
+	if !running {
+		return true
+	}
+
+	timer := time.NewTimer(m.cfg.CancellationWait)
+	defer timer.Stop()
+	select {
+	case <-stopped:
+		return true
+	case <-timer.C:
+		slog.Warn("download still stopping after cancellation",
+			slog.String("iso_id", isoID),
+			slog.Duration("waited", m.cfg.CancellationWait),
+		)
+		return false
+	}
+}
+
 // IsDownloading checks if an ISO is currently being downloaded
 func (m *Manager) IsDownloading(isoID string) bool {
 	m.mu.RLock()
@@ -2,12 +2,17 @@ package download
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/aloks98/isoman/backend/internal/models"
 	"github.com/aloks98/isoman/backend/internal/testutil"
+
+	"github.com/google/uuid"
 )
 
 func TestCancelDownload(t *testing.T) {
@@ -39,7 +44,7 @@ func TestCancelDownload(t *testing.T) {
 
 		// Manually register an active download
 		manager.mu.Lock()
-		manager.activeDownloads[iso.ID] = downloadCancel
+		manager.activeDownloads[iso.ID] = &activeDownload{cancel: downloadCancel}
 		manager.mu.Unlock()
 
 		// Verify download is active
@@ -78,7 +83,7 @@ func TestCancelDownload(t *testing.T) {
 
 		// Register download
 		manager.mu.Lock()
-		manager.activeDownloads[iso.ID] = downloadCancel
+		manager.activeDownloads[iso.ID] = &activeDownload{cancel: downloadCancel}
 		manager.mu.Unlock()
 
 		// First cancellation should succeed
@@ -116,7 +121,7 @@ func TestIsDownloading(t *testing.T) {
 
 		// Register download
 		manager.mu.Lock()
-		manager.activeDownloads[iso.ID] = downloadCancel
+		manager.activeDownloads[iso.ID] = &activeDownload{cancel: downloadCancel}
 		manager.mu.Unlock()
 
 		if !manager.IsDownloading(iso.ID) {
@@ -149,7 +154,7 @@ func TestCancelDownloadConcurrency(t *testing.T) {
 		_, cancel := context.WithCancel(context.Background())
 
 		manager.mu.Lock()
-		manager.activeDownloads[iso.ID] = cancel
+		manager.activeDownloads[iso.ID] = &activeDownload{cancel: cancel}
 		manager.mu.Unlock()
 	}
 
@@ -198,7 +203,7 @@ func TestIsDownloadingConcurrency(t *testing.T) {
 
 	// Register download
 	manager.mu.Lock()
-	manager.activeDownloads[iso.ID] = cancel
+	manager.activeDownloads[iso.ID] = &activeDownload{cancel: cancel}
 	manager.mu.Unlock()
 
 	// Check IsDownloading concurrently from multiple goroutines
@@ -240,7 +245,7 @@ func TestCancelDownloadRemovesFromMap(t *testing.T) {
 
 	// Register download
 	manager.mu.Lock()
-	manager.activeDownloads[iso.ID] = cancel
+	manager.activeDownloads[iso.ID] = &activeDownload{cancel: cancel}
 	initialCount := len(manager.activeDownloads)
 	manager.mu.Unlock()
 
@@ -260,3 +265,179 @@ func TestCancelDownloadRemovesFromMap(t *testing.T) {
 		t.Errorf("Expected 0 active downloads after cancellation, got %d", finalCount)
 	}
 }
+
+func TestCancelAndWait(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	cfg := DefaultConfig()
+	cfg.CancellationWait = 50 * time.Millisecond
+	manager := NewManagerWithConfig(env.DB, env.ISODir, cfg)
+	defer manager.Stop()
+
+	t.Run("nothing running returns at once", func(t *testing.T) {
+		if !manager.CancelAndWait("non-existent-id") {
+			t.Error("Expected CancelAndWait to return true when nothing is running")
+		}
+	})
+
+	register := func(id string) context.Context {
+		ctx, cancel := context.WithCancel(context.Background())
+		manager.mu.Lock()
+		manager.activeDownloads[id] = &activeDownload{cancel: cancel}
+		manager.stopped[id] = make(chan struct{})
+		manager.mu.Unlock()
+		return ctx
+	}
+
+	t.Run("waits for the worker to release", func(t *testing.T) {
+		ctx := register("acknowledged")
+		var released atomic.Bool
+		go func() {
+			<-ctx.Done()
+			time.Sleep(10 * time.Millisecond) // The worker cleaning up
+			released.Store(true)
+			manager.release("acknowledged", func() {})
+		}()
+
+		if !manager.CancelAndWait("acknowledged") {
+			t.Fatal("Expected CancelAndWait to return true once released")
+		}
+		if !released.Load() {
+			t.Error("CancelAndWait returned before the worker released the download")
+		}
+	})
+
+	t.Run("times out and waits again", func(t *testing.T) {
+		register("stuck")
+		if manager.CancelAndWait("stuck") {
+			t.Fatal("Expected CancelAndWait to time out while the worker holds the download")
+		}
+
+		// Canceled already, so a second call only waits
+		go manager.release("stuck", func() {})
+		if !manager.CancelAndWait("stuck") {
+			t.Error("Expected CancelAndWait to return true once the stuck worker released")
+		}
+	})
+}
+
+func TestCancelAndWaitStopsRunningDownload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1048576")
+		w.Write(make([]byte, 1024))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done() // Never finishes on its own
+	}))
+	defer server.Close()
+
+	manager, database, _, cleanup := setupTestManager(t, 1)
+	defer cleanup()
+	manager.Start()
+
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "slow",
+		Version:     "1.0",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: server.URL + "/slow.iso",
+		Status:      models.StatusPending,
+		CreatedAt:   time.Now(),
+	}
+	iso.ComputeFields()
+	if err := database.CreateISO(iso); err != nil {
+		t.Fatalf("CreateISO() failed: %v", err)
+	}
+	if err := manager.QueueDownload(iso); err != nil {
+		t.Fatalf("QueueDownload() failed: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !manager.IsDownloading(iso.ID) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if !manager.CancelAndWait(iso.ID) {
+		t.Fatal("Expected the worker to stop within the cancellation wait")
+	}
+
+	// The worker is done with the ISO: its status is final and it can be queued again
+	got, err := database.GetISO(iso.ID)
+	if err != nil {
+		t.Fatalf("GetISO() failed: %v", err)
+	}
+	if got.Status != models.StatusCanceled && got.Status != models.StatusFailed {
+		t.Errorf("Expected the download to be canceled, got status %s", got.Status)
+	}
+	manager.mu.RLock()
+	_, inFlight := manager.inFlight[iso.ID]
+	manager.mu.RUnlock()
+	if inFlight {
+		t.Error("Expected the ISO to be released after CancelAndWait")
+	}
+}
+
+func TestCancelQueued(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte("queued ISO content"))
+	}))
+	defer server.Close()
+
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	manager := NewManagerWithConfig(env.DB, env.ISODir, DefaultConfig())
+	defer manager.Stop()
+
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "queued",
+		Version:     "1.0",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: server.URL + "/queued.iso",
+		Status:      models.StatusPending,
+		CreatedAt:   time.Now(),
+	}
+	iso.ComputeFields()
+	if err := env.DB.CreateISO(iso); err != nil {
+		t.Fatalf("CreateISO() failed: %v", err)
+	}
+
+	// Not started, so the ISO waits in the queue
+	if err := manager.QueueDownload(iso); err != nil {
+		t.Fatalf("QueueDownload() failed: %v", err)
+	}
+	if !manager.CancelQueued(iso.ID) {
+		t.Fatal("Expected CancelQueued to take the waiting ISO off the queue")
+	}
+	if manager.CancelQueued(iso.ID) {
+		t.Error("Expected a second CancelQueued to find nothing waiting")
+	}
+	if got, _ := env.DB.GetISO(iso.ID); got.Status != models.StatusCanceled {
+		t.Errorf("Status = %s, want canceled", got.Status)
+	}
+
+	// Retrying needn't wait for a worker to pass the canceled entry, which
+	// is skipped rather than downloaded twice
+	retry := *iso
+	if err := manager.QueueDownload(&retry); err != nil {
+		t.Fatalf("QueueDownload() after cancel failed: %v", err)
+	}
+	manager.Start()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if got, err := env.DB.GetISO(iso.ID); err == nil && got.Status == models.StatusComplete {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got, _ := env.DB.GetISO(iso.ID); got.Status != models.StatusComplete {
+		t.Fatalf("Status = %s (%s), want complete after the retry", got.Status, got.ErrorMessage)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("Expected one upstream request, got %d", n)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"crypto/md5"
 	"crypto/sha256"
 	"crypto/sha512"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"hash"
 	"io"
@@ -14,9 +16,16 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aloks98/isoman/backend/internal/constants"
 	"github.com/aloks98/isoman/backend/internal/httputil"
+
+	"golang.org/x/crypto/blake2b"
 )
 
+// ErrChecksumUnavailable is returned when a checksum file can't be fetched, as
+// opposed to one that doesn't list the file or doesn't match it.
+var ErrChecksumUnavailable = errors.New("failed to fetch checksum file")
+
 // Streams the file to avoid memory issues with large ISOs.
 func ComputeHash(filepath string, hashType string) (string, error) {
 	file, err := os.Open(filepath)
@@ -25,16 +34,9 @@ func ComputeHash(filepath string, hashType string) (string, error) {
 	}
 	defer file.Close()
 
-	var hasher hash.Hash
-	switch strings.ToLower(hashType) {
-	case "sha256":
-		hasher = sha256.New()
-	case "sha512":
-		hasher = sha512.New()
-	case "md5":
-		hasher = md5.New()
-	default:
-		return "", fmt.Errorf("unsupported hash type: %s", hashType)
+	hasher, err := newHasher(hashType)
+	if err != nil {
+		return "", err
 	}
 
 	// Stream file to hasher
@@ -45,15 +47,144 @@ func ComputeHash(filepath string, hashType string) (string, error) {
 	return fmt.Sprintf("%x", hasher.Sum(nil)), nil
 }
 
+// ComputeIntegrityHash hashes a file with the given integrity algorithm and
+// returns it in the "algorithm:hex" form stored on ISOs.
+func ComputeIntegrityHash(filepath string, algorithm string) (string, error) {
+	algorithm = strings.ToLower(algorithm)
+	if !constants.IsValidIntegrityHash(algorithm) {
+		return "", fmt.Errorf("unsupported integrity hash: %s", algorithm)
+	}
+
+	sum, err := ComputeHash(filepath, algorithm)
+	if err != nil {
+		return "", err
+	}
+	return algorithm + ":" + sum, nil
+}
+
+// NewIntegrityHasher returns a hash for the algorithm of an integrity hash in
+// "algorithm:hex" form, for checking data as it streams by rather than
+// re-reading the file.
+func NewIntegrityHasher(integrityHash string) (hash.Hash, error) {
+	algorithm, _, found := strings.Cut(integrityHash, ":")
+	if !found || !constants.IsValidIntegrityHash(algorithm) {
+		return nil, fmt.Errorf("unsupported integrity hash: %s", integrityHash)
+	}
+	return newHasher(algorithm)
+}
+
+// integrityHashOrDefault returns algorithm, or the default when it isn't a valid integrity hash.
+func integrityHashOrDefault(algorithm string) string {
+	algorithm = strings.ToLower(algorithm)
+	if !constants.IsValidIntegrityHash(algorithm) {
+		return constants.DefaultIntegrityHash
+	}
+	return algorithm
+}
+
+// Digests holds every digest of a file, computed in a single pass.
+type Digests struct {
+	SHA256 string
+	SHA512 string
+	MD5    string
+
+	// Integrity is the internal integrity hash in "algorithm:hex" form.
+	Integrity string
+}
+
+// Get returns the digest for the given checksum type.
+func (d Digests) Get(hashType string) (string, error) {
+	switch strings.ToLower(hashType) {
+	case "sha256":
+		return d.SHA256, nil
+	case "sha512":
+		return d.SHA512, nil
+	case "md5":
+		return d.MD5, nil
+	}
+	return "", fmt.Errorf("unsupported hash type: %s", hashType)
+}
+
+// multiHasher feeds written data to every supported hash at once.
+type multiHasher struct {
+	sha256        hash.Hash
+	sha512        hash.Hash
+	md5           hash.Hash
+	integrity     hash.Hash // nil when the integrity hash reuses sha256
+	integrityHash string
+	w             io.Writer
+}
+
+// newMultiHasher creates a multiHasher for sha256, sha512, md5, and the given integrity hash.
+func newMultiHasher(integrityHash string) *multiHasher {
+	m := &multiHasher{
+		sha256:        sha256.New(),
+		sha512:        sha512.New(),
+		md5:           md5.New(),
+		integrityHash: integrityHashOrDefault(integrityHash),
+	}
+	writers := []io.Writer{m.sha256, m.sha512, m.md5}
+	if m.integrityHash != constants.IntegrityHashSHA256 {
+		m.integrity, _ = newHasher(m.integrityHash)
+		writers = append(writers, m.integrity)
+	}
+	m.w = io.MultiWriter(writers...)
+	return m
+}
+
+func (m *multiHasher) Write(p []byte) (int, error) {
+	return m.w.Write(p)
+}
+
+// Reset discards everything written so far.
+func (m *multiHasher) Reset() {
+	m.sha256.Reset()
+	m.sha512.Reset()
+	m.md5.Reset()
+	if m.integrity != nil {
+		m.integrity.Reset()
+	}
+}
+
+// Digests returns the hex digests of everything written so far.
+func (m *multiHasher) Digests() Digests {
+	d := Digests{
+		SHA256: fmt.Sprintf("%x", m.sha256.Sum(nil)),
+		SHA512: fmt.Sprintf("%x", m.sha512.Sum(nil)),
+		MD5:    fmt.Sprintf("%x", m.md5.Sum(nil)),
+	}
+	if m.integrity != nil {
+		d.Integrity = fmt.Sprintf("%s:%x", m.integrityHash, m.integrity.Sum(nil))
+	} else {
+		d.Integrity = m.integrityHash + ":" + d.SHA256
+	}
+	return d
+}
+
+// newHasher returns a hash for the given checksum type.
+func newHasher(hashType string) (hash.Hash, error) {
+	switch strings.ToLower(hashType) {
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	case "md5":
+		return md5.New(), nil
+	case constants.IntegrityHashBLAKE2b:
+		return blake2b.New256(nil)
+	}
+	return nil, fmt.Errorf("unsupported hash type: %s", hashType)
+}
+
 // for the given filename.
-func FetchExpectedChecksum(checksumURL, filename string) (string, error) {
+func FetchExpectedChecksum(ctx context.Context, checksumURL, filename string) (string, error) {
 	// Use context with timeout for checksum download
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
 	data, err := httputil.FetchBytes(ctx, checksumURL)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch checksum file: %w", err)
+		return "", fmt.Errorf("%w: %w", ErrChecksumUnavailable, err)
 	}
 
 	checksum, err := ParseChecksumFile(bytes.NewReader(data), filename)
@@ -68,8 +199,11 @@ func FetchExpectedChecksum(checksumURL, filename string) (string, error) {
 // Supports two formats:
 // 1. Standard: "hash  filename" or "hash *filename"
 // 2. BSD: "SHA256 (filename) = hash" or "MD5 (filename) = hash"
+// A file holding nothing but a single hash, as some sources publish per file,
+// is taken to be the hash of filename.
 func ParseChecksumFile(reader io.Reader, filename string) (string, error) {
 	scanner := bufio.NewScanner(reader)
+	var lines []string
 
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -78,6 +212,7 @@ func ParseChecksumFile(reader io.Reader, filename string) (string, error) {
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
+		lines = append(lines, line)
 
 		// Try BSD format first: SHA256 (filename) = hash
 		if strings.Contains(line, "(") && strings.Contains(line, ")") && strings.Contains(line, "=") {
@@ -120,5 +255,12 @@ func ParseChecksumFile(reader io.Reader, filename string) (string, error) {
 		return "", fmt.Errorf("error reading checksum file: %w", err)
 	}
 
+	// Bare hash with no filename
+	if len(lines) == 1 && !strings.ContainsAny(lines[0], " \t") {
+		if _, err := hex.DecodeString(lines[0]); err == nil {
+			return strings.ToLower(lines[0]), nil
+		}
+	}
+
 	return "", fmt.Errorf("checksum not found for file: %s", filename)
 }
@@ -0,0 +1,122 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/fileutil"
+	"github.com/aloks98/isoman/backend/internal/httputil"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/pathutil"
+)
+
+// StartChecksumRetrier verifies ISOs that completed while their checksum file
+// couldn't be fetched, once per interval until ctx is canceled. A zero
+// interval disables it, leaving them unverified.
+func (m *Manager) StartChecksumRetrier(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := m.clock.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+			}
+			m.retryPendingChecksums(ctx)
+		}
+	}()
+}
+
+// retryPendingChecksums checks every ISO with a pending checksum against its
+// checksum file, or a known checksum for it, and returns how many verified.
+// An ISO whose file doesn't match is failed and its file removed, as a
+// mismatch at download time would have left it.
+func (m *Manager) retryPendingChecksums(ctx context.Context) int {
+	isos, err := m.db.ListChecksumPendingISOs()
+	if err != nil {
+		slog.Warn("failed to list ISOs with pending checksums", slog.Any("error", err))
+		return 0
+	}
+
+	worker := NewWorker(m.db, m.isoDir, m.cfg, m.notifyProgress)
+	worker.clock = m.clock
+
+	verified := 0
+	for i := range isos {
+		if ctx.Err() != nil {
+			break
+		}
+		iso := &isos[i]
+		m.mu.RLock()
+		_, running := m.inFlight[iso.ID]
+		m.mu.RUnlock()
+		if running {
+			continue // Being downloaded again, which verifies it
+		}
+		if m.retryChecksum(ctx, worker, iso) {
+			verified++
+		}
+	}
+	return verified
+}
+
+// retryChecksum verifies one ISO with a pending checksum against the digests
+// recorded while it downloaded, reporting whether it verified.
+func (m *Manager) retryChecksum(ctx context.Context, worker *Worker, iso *models.ISO) bool {
+	fetchCtx := httputil.WithIPFamily(ctx, iso.IPFamily)
+	if m.credentials != nil {
+		fetchCtx = httputil.WithAuthorizer(fetchCtx, m.credentials.AuthorizerFor(iso))
+	}
+
+	digests := Digests{SHA256: iso.SHA256, SHA512: iso.SHA512, MD5: iso.MD5}
+	err := worker.verifyChecksum(fetchCtx, iso, digests)
+	if errors.Is(err, ErrChecksumUnavailable) || ctx.Err() != nil {
+		slog.Debug("checksum still unavailable", slog.String("iso_id", iso.ID), slog.Any("error", err))
+		return false
+	}
+
+	filePath := pathutil.ConstructISOPath(m.isoDir, iso.FilePath)
+	iso.ChecksumPending = false
+	if err != nil {
+		m.logDownload(iso.ID, models.LogLevelError, "Checksum retry failed: %v; the file was removed", err)
+		if err := fileutil.CleanupWithExtensions(filePath, constants.SidecarExtensions...); err != nil {
+			slog.Warn("failed to remove ISO that failed its checksum", slog.String("iso_id", iso.ID), slog.Any("error", err))
+		}
+		iso.Status = models.StatusFailed
+		iso.ErrorMessage = err.Error()
+		iso.ErrorReason = models.ErrorReasonVerification
+		if err := m.db.UpdateISO(iso); err != nil {
+			slog.Warn("failed to fail ISO after checksum retry", slog.String("iso_id", iso.ID), slog.Any("error", err))
+			return false
+		}
+		m.notifyProgress(iso.ID, iso.Progress, models.StatusFailed)
+		slog.Warn("ISO failed its checksum on retry", slog.String("iso_id", iso.ID), slog.String("name", iso.Name))
+		return false
+	}
+
+	verifiedAt := m.clock.Now()
+	iso.VerifiedAt = &verifiedAt
+	if err := m.db.UpdateISO(iso); err != nil {
+		slog.Warn("failed to record verified checksum", slog.String("iso_id", iso.ID), slog.Any("error", err))
+		return false
+	}
+	if iso.ChecksumURL != "" {
+		checksumFile := pathutil.ConstructChecksumPath(filePath, iso.ChecksumType)
+		if err := worker.downloadChecksumFile(fetchCtx, iso.ChecksumURL, checksumFile); err != nil {
+			slog.Warn("failed to save checksum file", slog.String("iso_id", iso.ID), slog.Any("error", err))
+		}
+	}
+	m.logDownload(iso.ID, models.LogLevelInfo, "Checksum verified on retry")
+	m.notifyProgress(iso.ID, iso.Progress, models.StatusComplete)
+	slog.Info("verified pending checksum", slog.String("iso_id", iso.ID), slog.String("name", iso.Name))
+	return true
+}
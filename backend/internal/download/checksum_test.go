@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
 )
 
 func TestComputeHash(t *testing.T) {
@@ -53,6 +55,62 @@ func TestComputeHash(t *testing.T) {
 	}
 }
 
+func TestMultiHasherDigests(t *testing.T) {
+	m := newMultiHasher(constants.IntegrityHashBLAKE2b)
+	m.Write([]byte("partial attempt"))
+	m.Reset()
+	m.Write([]byte("Hello, "))
+	m.Write([]byte("World!"))
+
+	digests := m.Digests()
+	want := Digests{
+		SHA256: "dffd6021bb2bd5b0af676290809ec3a53191dd81c7f70a4b28688a362182986f",
+		SHA512: "374d794a95cdcfd8b35993185fef9ba368f160d8daf432d08ba9f1ed1e5abe6cc69291e0fa2fe0006a52570ef18c19def4e617c33ce52ef0a6e5fbe318cb0387",
+		MD5:    "65a8e27d8879283831b664bd8b7f0ad4",
+
+		Integrity: "blake2b:511bc81dde11180838c562c82bb35f3223f46061ebde4a955c27b3f489cf1e03",
+	}
+	if digests != want {
+		t.Errorf("Digests() = %+v, want %+v", digests, want)
+	}
+
+	if got, err := digests.Get("SHA512"); err != nil || got != want.SHA512 {
+		t.Errorf("Get(SHA512) = %q, %v", got, err)
+	}
+	if _, err := digests.Get("crc32"); err == nil {
+		t.Error("Expected error for unsupported hash type, got nil")
+	}
+}
+
+func TestMultiHasherIntegritySHA256(t *testing.T) {
+	m := newMultiHasher(constants.IntegrityHashSHA256)
+	m.Write([]byte("Hello, World!"))
+
+	digests := m.Digests()
+	if digests.Integrity != "sha256:"+digests.SHA256 {
+		t.Errorf("Integrity should reuse the sha256 digest, got: %s", digests.Integrity)
+	}
+}
+
+func TestComputeIntegrityHash(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test.txt")
+	if err := os.WriteFile(testFile, []byte("Hello, World!"), 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	got, err := ComputeIntegrityHash(testFile, "BLAKE2b")
+	if err != nil {
+		t.Fatalf("ComputeIntegrityHash() error = %v", err)
+	}
+	if want := "blake2b:511bc81dde11180838c562c82bb35f3223f46061ebde4a955c27b3f489cf1e03"; got != want {
+		t.Errorf("ComputeIntegrityHash() = %v, want %v", got, want)
+	}
+
+	if _, err := ComputeIntegrityHash(testFile, "md5"); err == nil {
+		t.Error("Expected error for non-integrity hash type, got nil")
+	}
+}
+
 func TestComputeHashUnsupportedType(t *testing.T) {
 	tmpDir := t.TempDir()
 	testFile := filepath.Join(tmpDir, "test.txt")
@@ -148,6 +206,36 @@ e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  test.iso
 			want:     "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
 			wantErr:  false,
 		},
+		{
+			name:     "Bare hash without filename",
+			content:  "E3B0C44298FC1C149AFBF4C8996FB92427AE41E4649B934CA495991B7852B855\n",
+			filename: "test.iso",
+			want:     "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			wantErr:  false,
+		},
+		{
+			name: "Bare hash with comments",
+			content: `# test.iso
+e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855`,
+			filename: "test.iso",
+			want:     "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			wantErr:  false,
+		},
+		{
+			name: "Several bare hashes are ambiguous",
+			content: `e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855
+18543988d9a1a5632d142c3dc288136dcc48ab71628f92ebcd40ada7f4ecd110`,
+			filename: "test.iso",
+			want:     "",
+			wantErr:  true,
+		},
+		{
+			name:     "Single token that isn't a hash",
+			content:  "<html>",
+			filename: "test.iso",
+			want:     "",
+			wantErr:  true,
+		},
 		{
 			name:     "BSD format - SHA256",
 			content:  `SHA256 (test.iso) = e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855`,
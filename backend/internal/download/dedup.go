@@ -0,0 +1,137 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/fileutil"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/pathutil"
+)
+
+// sharedFetch is a transfer in progress that other ISOs with the same
+// download URL wait for instead of fetching the file again.
+type sharedFetch struct {
+	leader    string                 // ID of the ISO whose worker fetches the file
+	followers map[string]*models.ISO // Waiting ISOs, by ID; nil once the leader has linked its file to them
+	done      chan struct{}          // Closed once job and links are set
+	job       *verifyJob             // The leader's fetched file; nil when the fetch failed
+	links     map[string]error       // Each follower's temp file link, by ISO ID
+}
+
+// fetchKey identifies downloads of the same file: the URL, fetched with the
+// same credential over the same IP family.
+func fetchKey(iso *models.ISO) string {
+	return iso.DownloadURL + "\x00" + iso.Credential + "\x00" + iso.IPFamily
+}
+
+// fetchShared fetches an ISO like Worker.fetch, unless another worker is
+// already fetching its URL. Then it waits for that transfer and links the
+// file to the ISO's temp file, saving upstream bandwidth. If the other
+// transfer fails, the ISO is fetched on its own.
+func (m *Manager) fetchShared(ctx context.Context, worker *Worker, iso *models.ISO) (*verifyJob, error) {
+	key := fetchKey(iso)
+
+	m.mu.Lock()
+	shared, following := m.fetches[key]
+	if following {
+		shared.followers[iso.ID] = iso
+	} else {
+		shared = &sharedFetch{leader: iso.ID, followers: make(map[string]*models.ISO), done: make(chan struct{})}
+		m.fetches[key] = shared
+	}
+	m.mu.Unlock()
+
+	if following {
+		job, err := m.follow(ctx, worker, iso, shared)
+		if job != nil || err != nil {
+			return job, err
+		}
+		return m.fetchShared(ctx, worker, iso)
+	}
+
+	job, err := worker.fetch(ctx, iso)
+
+	m.mu.Lock()
+	delete(m.fetches, key)
+	followers := shared.followers
+	shared.followers = nil
+	m.mu.Unlock()
+
+	shared.links = make(map[string]error, len(followers))
+	if err == nil {
+		shared.job = job
+		for id, follower := range followers {
+			tmpFile := pathutil.ConstructTempPath(worker.tmpDir, follower.Filename)
+			fileutil.DeleteFileSilently(tmpFile) // Left over from an earlier run
+			shared.links[id] = fileutil.LinkOrCopyFile(job.tmpFile, tmpFile)
+		}
+	}
+	close(shared.done)
+	return job, err
+}
+
+// follow waits for the leader of shared to fetch the file and returns the
+// ISO's job for it. It returns neither a job nor an error when the ISO has
+// to be fetched on its own.
+func (m *Manager) follow(ctx context.Context, worker *Worker, iso *models.ISO, shared *sharedFetch) (*verifyJob, error) {
+	tmpFile := pathutil.ConstructTempPath(worker.tmpDir, iso.Filename)
+	finalFile := pathutil.ConstructISOPath(worker.isoDir, iso.FilePath)
+
+	if err := worker.db.ClearDownloadLog(iso.ID); err != nil {
+		slog.WarnContext(ctx, "failed to clear download log", slog.String("iso_id", iso.ID), slog.Any("error", err))
+	}
+	worker.updateStatus(iso.ID, models.StatusDownloading, 0, "")
+	worker.logDownload(iso.ID, models.LogLevelInfo, "Waiting for ISO %s, which is already downloading %s", shared.leader, logURL(iso.DownloadURL))
+	startedAt := worker.clock.Now()
+
+	select {
+	case <-shared.done:
+	case <-ctx.Done():
+		m.mu.Lock()
+		linking := shared.followers == nil
+		delete(shared.followers, iso.ID)
+		m.mu.Unlock()
+		if linking {
+			// The leader is already linking the file to this ISO
+			<-shared.done
+			fileutil.DeleteFileSilently(tmpFile)
+		}
+		worker.updateStatus(iso.ID, models.StatusCanceled, 0, "Download canceled")
+		return nil, fmt.Errorf("download canceled: %w", ctx.Err())
+	}
+
+	if shared.job == nil {
+		worker.logDownload(iso.ID, models.LogLevelWarn, "The download by ISO %s failed; downloading it here instead", shared.leader)
+		return nil, nil
+	}
+	if err := shared.links[iso.ID]; err != nil {
+		worker.logDownload(iso.ID, models.LogLevelWarn, "Failed to reuse the download by ISO %s (%v); downloading it here instead", shared.leader, err)
+		return nil, nil
+	}
+	if err := fileutil.EnsureParentDirectory(finalFile); err != nil {
+		fileutil.DeleteFileSilently(tmpFile)
+		return nil, fmt.Errorf("failed to create final directory: %w", err)
+	}
+	worker.logDownload(iso.ID, models.LogLevelInfo, "Reused the file downloaded by ISO %s after %s", shared.leader, worker.clock.Since(startedAt).Round(time.Millisecond))
+
+	digests := shared.job.digests
+	iso.DownloadStartedAt = &startedAt
+	iso.SHA256 = digests.SHA256
+	iso.SHA512 = digests.SHA512
+	iso.MD5 = digests.MD5
+	iso.IntegrityHash = digests.Integrity
+	if iso.HasChecksum() || iso.SignatureURL != "" {
+		worker.updateStatus(iso.ID, models.StatusVerifying, 100, "")
+	}
+
+	return &verifyJob{
+		iso:        iso,
+		validators: shared.job.validators,
+		digests:    digests,
+		tmpFile:    tmpFile,
+		finalFile:  finalFile,
+	}, nil
+}
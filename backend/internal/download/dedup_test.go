@@ -0,0 +1,97 @@
+package download
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/pathutil"
+
+	"github.com/google/uuid"
+)
+
+func TestSharedFetch(t *testing.T) {
+	content := []byte("shared ISO content")
+	release := make(chan struct{})
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-release // Held until the second ISO is waiting
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	manager, database, isoDir, cleanup := setupTestManager(t, 2)
+	defer cleanup()
+	manager.Start()
+
+	var isos []*models.ISO
+	for _, name := range []string{"first", "second"} {
+		iso := &models.ISO{
+			ID:          uuid.New().String(),
+			Name:        name,
+			Version:     "1.0",
+			Arch:        "x86_64",
+			FileType:    "iso",
+			DownloadURL: server.URL + "/shared.iso",
+			Status:      models.StatusPending,
+			CreatedAt:   time.Now(),
+		}
+		iso.ComputeFields()
+		if err := database.CreateISO(iso); err != nil {
+			t.Fatalf("CreateISO() failed: %v", err)
+		}
+		isos = append(isos, iso)
+	}
+
+	if err := manager.QueueDownload(isos[0]); err != nil {
+		t.Fatalf("QueueDownload() failed: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for requests.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := manager.QueueDownload(isos[1]); err != nil {
+		t.Fatalf("QueueDownload() failed: %v", err)
+	}
+	following := func() bool {
+		manager.mu.RLock()
+		defer manager.mu.RUnlock()
+		shared := manager.fetches[fetchKey(isos[0])]
+		return shared != nil && shared.followers[isos[1].ID] != nil
+	}
+	for !following() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !following() {
+		t.Fatal("Expected the second ISO to wait for the first one's transfer")
+	}
+	close(release)
+
+	for _, iso := range isos {
+		for time.Now().Before(deadline) {
+			if got, err := database.GetISO(iso.ID); err == nil && got.Status == models.StatusComplete {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		got, err := database.GetISO(iso.ID)
+		if err != nil {
+			t.Fatalf("GetISO() failed: %v", err)
+		}
+		if got.Status != models.StatusComplete {
+			t.Fatalf("Expected %s to complete, got status %s (%s)", iso.Name, got.Status, got.ErrorMessage)
+		}
+		data, err := os.ReadFile(pathutil.ConstructISOPath(isoDir, got.FilePath))
+		if err != nil || string(data) != string(content) {
+			t.Errorf("Expected %s to hold the downloaded file, got %q (%v)", iso.Name, data, err)
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("Expected one upstream request for both ISOs, got %d", n)
+	}
+}
@@ -0,0 +1,137 @@
+package download
+
+import (
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// forecastHistory is how many recently completed downloads the throughput
+// behind queue forecasts is taken from.
+const forecastHistory = 20
+
+// QueueForecast estimates when each ISO queued in the database will start and
+// finish. Queued ISOs are handed, oldest first, to whichever
+// of this instance's workers is free first, fast lane workers taking only
+// small ones; running downloads keep their worker until their remaining bytes
+// arrive at their current rate. Durations come from the throughput of recent
+// downloads, or their average duration when a size is unknown. Without any
+// history the estimates are left empty.
+func (m *Manager) QueueForecast() (*models.QueueForecast, error) {
+	now := m.clock.Now()
+	recent, err := m.db.ListRecentlyCompletedISOs(forecastHistory)
+	if err != nil {
+		return nil, err
+	}
+	queued, err := m.db.ListQueuedISOs()
+	if err != nil {
+		return nil, err
+	}
+
+	rate, typical := downloadHistory(recent)
+	forecast := &models.QueueForecast{
+		GeneratedAt: now,
+		BytesPerSec: int64(rate),
+		Queue:       make([]models.QueueEstimate, 0, len(queued)),
+	}
+
+	// When each download worker is next free; fast lane workers come last
+	free := make([]time.Time, m.workerCount+m.fastCount)
+	for i := range free {
+		free[i] = now
+	}
+	m.mu.RLock()
+	for _, a := range m.activeDownloads {
+		if id := a.info.WorkerID; id >= 0 && id < len(free) {
+			free[id] = now.Add(a.remaining(rate, typical))
+		}
+	}
+	m.mu.RUnlock()
+
+	for i, iso := range queued {
+		estimate := models.QueueEstimate{
+			ISOID:     iso.ID,
+			Name:      iso.Name,
+			Version:   iso.Version,
+			Arch:      iso.Arch,
+			Edition:   iso.Edition,
+			Position:  i + 1,
+			SizeBytes: iso.SizeBytes,
+			FastLane:  m.fastLane(iso.SizeBytes),
+		}
+		if typical > 0 && len(free) > 0 {
+			// Regular workers take from both lanes, fast lane workers only from theirs
+			candidates := free[:m.workerCount]
+			if estimate.FastLane {
+				candidates = free
+			}
+			worker := earliest(candidates)
+			if worker < 0 {
+				worker = earliest(free)
+			}
+			start := free[worker]
+			duration := typical
+			if iso.SizeBytes > 0 && rate > 0 {
+				duration = time.Duration(float64(iso.SizeBytes) / rate * float64(time.Second))
+			}
+			completion := start.Add(duration)
+			free[worker] = completion
+			estimate.EstimatedStartAt = &start
+			estimate.EstimatedCompletionAt = &completion
+		}
+		forecast.Queue = append(forecast.Queue, estimate)
+	}
+	return forecast, nil
+}
+
+// downloadHistory returns the bytes per second of a single download and the
+// average duration of one, from download start to completion, over recent
+// downloads. Both are zero when none recorded a start time and size.
+func downloadHistory(recent []models.ISO) (float64, time.Duration) {
+	var bytes int64
+	var elapsed time.Duration
+	count := 0
+	for _, iso := range recent {
+		if iso.DownloadStartedAt == nil || iso.CompletedAt == nil || iso.SizeBytes <= 0 {
+			continue
+		}
+		if d := iso.CompletedAt.Sub(*iso.DownloadStartedAt); d > 0 {
+			bytes += iso.SizeBytes
+			elapsed += d
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, 0
+	}
+	return float64(bytes) / elapsed.Seconds(), elapsed / time.Duration(count)
+}
+
+// remaining estimates how much longer the download holds its worker: its
+// missing bytes at its current rate, or the history's, or what is left of a
+// typical duration when upstream sent no length. A download being verified
+// has already freed its worker. Like the transfer, it is timed by the real clock.
+func (a *activeDownload) remaining(rate float64, typical time.Duration) time.Duration {
+	d := a.snapshot(time.Now())
+	if d.Status == models.StatusVerifying {
+		return 0
+	}
+	if d.BytesPerSec > 0 {
+		rate = float64(d.BytesPerSec)
+	}
+	if d.BytesTotal > 0 && rate > 0 {
+		return time.Duration(float64(d.BytesTotal-d.BytesDownloaded) / rate * float64(time.Second))
+	}
+	return max(typical-time.Since(d.StartedAt), 0)
+}
+
+// earliest returns the index of the earliest time, or -1 when there is none.
+func earliest(times []time.Time) int {
+	index := -1
+	for i, t := range times {
+		if index < 0 || t.Before(times[index]) {
+			index = i
+		}
+	}
+	return index
+}
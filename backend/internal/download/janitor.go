@@ -0,0 +1,139 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/fileutil"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/pathutil"
+)
+
+// emptyDirMinAge keeps recently created directories that a download or move may be about to fill.
+const emptyDirMinAge = time.Hour
+
+// TempCleanupResult reports what a temp directory sweep removed.
+type TempCleanupResult struct {
+	FilesRemoved   int
+	BytesReclaimed int64
+}
+
+// CleanupTempFiles removes files in the download temp directory that are older
+// than maxAge and don't belong to a queued or running download, such as partial
+// files left behind by a crash. Removed files are recorded in a GC report.
+func (m *Manager) CleanupTempFiles(maxAge time.Duration) (*TempCleanupResult, error) {
+	tmpDir := pathutil.ResolveTempDir(m.isoDir, m.cfg.TempDir)
+	result := &TempCleanupResult{}
+
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+		return nil, fmt.Errorf("failed to read temp directory: %w", err)
+	}
+
+	active := make(map[string]bool)
+	m.mu.RLock()
+	for _, filename := range m.inFlight {
+		active[filename] = true
+	}
+	m.mu.RUnlock()
+
+	var removed []models.GCFile
+	cutoff := m.clock.Now().Add(-maxAge)
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || active[entry.Name()] {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+
+		path := filepath.Join(tmpDir, entry.Name())
+		if err := os.Remove(path); err != nil {
+			slog.Warn("failed to remove stale temp file", slog.String("path", path), slog.Any("error", err))
+			continue
+		}
+		result.FilesRemoved++
+		result.BytesReclaimed += info.Size()
+		removed = append(removed, models.GCFile{Path: path, SizeBytes: info.Size()})
+	}
+
+	recordGCReport(m.db, &models.GCReport{CreatedAt: m.clock.Now(), Reason: models.GCReasonTempExpired, Files: removed})
+	return result, nil
+}
+
+// recordGCReport stores a report of files garbage collected, when there are
+// any. A failure is logged: the files are gone either way.
+func recordGCReport(database *db.DB, report *models.GCReport) {
+	if len(report.Files) == 0 {
+		return
+	}
+	if err := database.CreateGCReport(report); err != nil {
+		slog.Warn("failed to record gc report", slog.String("reason", report.Reason), slog.Any("error", err))
+	}
+}
+
+// PruneEmptyDirs removes empty name/version/arch directories left behind in the
+// ISO directory by deletions and moves. It returns the number removed.
+func (m *Manager) PruneEmptyDirs() (int, error) {
+	return fileutil.PruneEmptyDirs(m.isoDir, emptyDirMinAge)
+}
+
+// StartTempJanitor sweeps stale temp files and empty directories once at startup
+// and then once per interval until ctx is canceled. A zero interval disables the janitor.
+func (m *Manager) StartTempJanitor(ctx context.Context, interval, maxAge time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := m.clock.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			m.sweepTempFiles(maxAge)
+			m.sweepEmptyDirs()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+			}
+		}
+	}()
+}
+
+// sweepTempFiles runs CleanupTempFiles and logs the reclaimed space.
+func (m *Manager) sweepTempFiles(maxAge time.Duration) {
+	result, err := m.CleanupTempFiles(maxAge)
+	if err != nil {
+		slog.Warn("temp cleanup failed", slog.Any("error", err))
+		return
+	}
+	if result.FilesRemoved > 0 {
+		slog.Info("removed stale temp files",
+			slog.Int("files", result.FilesRemoved),
+			slog.Int64("bytes_reclaimed", result.BytesReclaimed),
+		)
+	}
+}
+
+// sweepEmptyDirs runs PruneEmptyDirs and logs how many directories were removed.
+func (m *Manager) sweepEmptyDirs() {
+	removed, err := m.PruneEmptyDirs()
+	if err != nil {
+		slog.Warn("empty directory pruning failed", slog.Any("error", err))
+		return
+	}
+	if removed > 0 {
+		slog.Info("pruned empty directories", slog.Int("dirs", removed))
+	}
+}
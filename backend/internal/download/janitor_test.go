@@ -0,0 +1,165 @@
+package download
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/clock"
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/pathutil"
+
+	"github.com/google/uuid"
+)
+
+// TestManagerCleanupTempFiles tests that only old files of inactive downloads are removed.
+func TestManagerCleanupTempFiles(t *testing.T) {
+	manager, database, isoDir, cleanup := setupTestManager(t, 1)
+	defer cleanup()
+
+	tmpDir := pathutil.GetTempDir(isoDir)
+	os.MkdirAll(tmpDir, 0o755)
+
+	old := time.Now().Add(-48 * time.Hour)
+	writeTemp := func(name string, content string, modTime time.Time) string {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write temp file: %v", err)
+		}
+		os.Chtimes(path, modTime, modTime)
+		return path
+	}
+
+	orphan := writeTemp("orphan.iso", "orphaned", old)
+	recent := writeTemp("recent.iso", "recent", time.Now())
+
+	// A queued download's temp file is kept however old it is
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "active",
+		Version:     "1.0",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: "http://example.com/active.iso",
+		Status:      models.StatusPending,
+		CreatedAt:   time.Now(),
+	}
+	iso.ComputeFields()
+	database.CreateISO(iso)
+	if err := manager.QueueDownload(iso); err != nil {
+		t.Fatalf("QueueDownload failed: %v", err)
+	}
+	active := writeTemp(iso.Filename, "active", old)
+
+	result, err := manager.CleanupTempFiles(24 * time.Hour)
+	if err != nil {
+		t.Fatalf("CleanupTempFiles failed: %v", err)
+	}
+
+	if result.FilesRemoved != 1 || result.BytesReclaimed != int64(len("orphaned")) {
+		t.Errorf("Expected 1 file and %d bytes reclaimed, got: %+v", len("orphaned"), result)
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Error("Old orphaned temp file should be removed")
+	}
+	if _, err := os.Stat(recent); err != nil {
+		t.Error("Recent temp file should be kept")
+	}
+	if _, err := os.Stat(active); err != nil {
+		t.Error("Temp file of a queued download should be kept")
+	}
+
+	reports, err := database.ListGCReports(db.GCReportsParams{Limit: 10})
+	if err != nil {
+		t.Fatalf("ListGCReports failed: %v", err)
+	}
+	if len(reports) != 1 || reports[0].Reason != models.GCReasonTempExpired || len(reports[0].Files) != 1 || reports[0].Files[0].Path != orphan {
+		t.Fatalf("Expected one report of the orphaned file, got: %+v", reports)
+	}
+
+	// A sweep that removes nothing records no report
+	if _, err := manager.CleanupTempFiles(24 * time.Hour); err != nil {
+		t.Fatalf("CleanupTempFiles failed: %v", err)
+	}
+	if reports, _ := database.ListGCReports(db.GCReportsParams{Limit: 10}); len(reports) != 1 {
+		t.Errorf("Expected no report for an empty sweep, got %d reports", len(reports))
+	}
+}
+
+// TestManagerCleanupTempFilesClock tests that a file's age is measured against
+// the manager's clock.
+func TestManagerCleanupTempFilesClock(t *testing.T) {
+	manager, database, isoDir, cleanup := setupTestManager(t, 1)
+	defer cleanup()
+	fake := clock.NewFake(time.Now().UTC().Truncate(time.Second))
+	manager.SetClock(fake)
+
+	tmpDir := pathutil.GetTempDir(isoDir)
+	os.MkdirAll(tmpDir, 0o755)
+	path := filepath.Join(tmpDir, "orphan.iso")
+	if err := os.WriteFile(path, []byte("orphaned"), 0o644); err != nil {
+		t.Fatalf("Failed to write temp file: %v", err)
+	}
+	modTime := fake.Now().Add(-2 * time.Hour)
+	os.Chtimes(path, modTime, modTime)
+
+	if result, err := manager.CleanupTempFiles(24 * time.Hour); err != nil || result.FilesRemoved != 0 {
+		t.Fatalf("Expected a two hour old file kept, got %+v (%v)", result, err)
+	}
+	fake.Advance(24 * time.Hour)
+	if result, err := manager.CleanupTempFiles(24 * time.Hour); err != nil || result.FilesRemoved != 1 {
+		t.Fatalf("Expected the file removed once the clock moved a day, got %+v (%v)", result, err)
+	}
+
+	reports, err := database.ListGCReports(db.GCReportsParams{Limit: 10})
+	if err != nil {
+		t.Fatalf("ListGCReports failed: %v", err)
+	}
+	if len(reports) != 1 || !reports[0].CreatedAt.Equal(fake.Now()) {
+		t.Errorf("Expected one report stamped %v, got %+v", fake.Now(), reports)
+	}
+}
+
+// TestManagerPruneEmptyDirs tests that empty directories are pruned bottom-up.
+func TestManagerPruneEmptyDirs(t *testing.T) {
+	manager, _, isoDir, cleanup := setupTestManager(t, 1)
+	defer cleanup()
+
+	old := time.Now().Add(-2 * time.Hour)
+	mkdirOld := func(rel string) string {
+		path := filepath.Join(isoDir, filepath.FromSlash(rel))
+		os.MkdirAll(path, 0o755)
+		return path
+	}
+
+	mkdirOld("deleted/1.0/x86_64")
+	kept := mkdirOld("alpine/3.19.1/x86_64")
+	os.WriteFile(filepath.Join(kept, "alpine.iso"), []byte("alpine"), 0o644)
+	fresh := mkdirOld("fresh/1.0/x86_64")
+	hidden := mkdirOld(".tmp")
+
+	// Age every directory except the fresh leaf
+	for _, rel := range []string{"deleted/1.0/x86_64", "deleted/1.0", "deleted", "alpine/3.19.1/x86_64", "alpine/3.19.1", "alpine", "fresh/1.0", "fresh", ".tmp"} {
+		path := filepath.Join(isoDir, filepath.FromSlash(rel))
+		os.Chtimes(path, old, old)
+	}
+
+	removed, err := manager.PruneEmptyDirs()
+	if err != nil {
+		t.Fatalf("PruneEmptyDirs failed: %v", err)
+	}
+
+	if removed != 3 {
+		t.Errorf("Expected 3 directories removed, got: %d", removed)
+	}
+	if _, err := os.Stat(filepath.Join(isoDir, "deleted")); !os.IsNotExist(err) {
+		t.Error("Empty directory tree should be removed")
+	}
+	for _, path := range []string{kept, fresh, hidden} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Directory should be kept: %s", path)
+		}
+	}
+}
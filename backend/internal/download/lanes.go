@@ -0,0 +1,91 @@
+package download
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/httputil"
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// laneFor returns the queue for a download of size bytes: the fast lane for
+// small ones, so netboot kernels and small images don't wait behind DVD
+// downloads.
+func (m *Manager) laneFor(iso *models.ISO, size int64) chan *models.ISO {
+	if !m.fastLane(size) {
+		return m.queue
+	}
+	slog.Debug("download queued in the fast lane", slog.String("iso_id", iso.ID), slog.Int64("size_bytes", size))
+	return m.fastQueue
+}
+
+// fastLane reports whether a download of size bytes belongs in the fast lane:
+// it is enabled and the size is known and at most FastLaneMaxSize.
+func (m *Manager) fastLane(size int64) bool {
+	return m.fastQueue != nil && size > 0 && size <= m.cfg.FastLaneMaxSize
+}
+
+// relaneLater sizes iso in the background when it went in the regular queue
+// without a recorded size, so queuing never waits on the upstream. The caller
+// holds enqueueMu and has sent iso to queue.
+func (m *Manager) relaneLater(iso *models.ISO, queue chan *models.ISO) {
+	if m.fastQueue == nil || queue != m.queue || iso.SizeBytes > 0 {
+		return
+	}
+	go m.relane(iso)
+}
+
+// relane asks the upstream for the size of iso's download and, when it fits
+// the fast lane and iso is still waiting in the regular queue, moves it to
+// the fast lane. The regular queue's entry is dropped, as by CancelQueued, in
+// favor of a copy sent to the fast lane.
+func (m *Manager) relane(iso *models.ISO) {
+	size := m.probeSize(iso)
+	if !m.fastLane(size) {
+		return
+	}
+
+	m.enqueueMu.Lock()
+	defer m.enqueueMu.Unlock()
+	if len(m.fastQueue) >= cap(m.fastQueue) {
+		return
+	}
+	m.mu.Lock()
+	if m.waiting[iso.ID] != iso {
+		m.mu.Unlock()
+		return // Taken by a worker or canceled meanwhile
+	}
+	moved := *iso
+	m.dropped[iso] = true
+	m.waiting[iso.ID] = &moved
+	m.mu.Unlock()
+
+	slog.Debug("download moved to the fast lane", slog.String("iso_id", iso.ID), slog.Int64("size_bytes", size))
+	m.fastQueue <- &moved
+}
+
+// probeSize returns the size of iso's download from the Content-Length of a
+// HEAD request, or 0 when it can't be learned, e.g. from a mirror that sends
+// no Content-Length.
+func (m *Manager) probeSize(iso *models.ISO) int64 {
+	lower := strings.ToLower(iso.DownloadURL)
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+		return 0
+	}
+
+	ctx, cancel := context.WithTimeout(m.ctx, constants.FastLaneProbeTimeoutSec*time.Second)
+	defer cancel()
+	ctx = httputil.WithIPFamily(ctx, iso.IPFamily)
+	if m.credentials != nil {
+		ctx = httputil.WithAuthorizer(ctx, m.credentials.AuthorizerFor(iso))
+	}
+	validators, err := httputil.HeadValidators(ctx, iso.DownloadURL)
+	if err != nil {
+		slog.Debug("failed to size download for the fast lane", slog.String("iso_id", iso.ID), slog.Any("error", err))
+		return 0
+	}
+	return validators.ContentLength
+}
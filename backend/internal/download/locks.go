@@ -0,0 +1,101 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// Node returns the name this instance holds download locks under.
+func (m *Manager) Node() string {
+	return m.node
+}
+
+// claim takes the download lock on iso, so two instances sharing the
+// database never download the same ISO. It reports false when another node
+// holds the lock, or when the ISO is no longer queued because another node
+// downloaded it while it waited here. If the lock can't be written at all,
+// the download goes ahead unlocked rather than stalling the queue.
+func (m *Manager) claim(ctx context.Context, iso *models.ISO) bool {
+	err := m.db.AcquireDownloadLock(iso.ID, m.node, m.lockTTL)
+	if errors.Is(err, db.ErrDownloadLocked) {
+		slog.WarnContext(ctx, "skipping download locked by another node",
+			slog.String("iso_id", iso.ID),
+			slog.String("name", iso.Name),
+		)
+		return false
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "failed to lock download", slog.String("iso_id", iso.ID), slog.Any("error", err))
+		return true
+	}
+
+	if current, err := m.db.GetISO(iso.ID); err != nil || current.Status != models.StatusQueued {
+		slog.InfoContext(ctx, "skipping download no longer queued", slog.String("iso_id", iso.ID))
+		m.releaseLock(iso.ID)
+		return false
+	}
+	return true
+}
+
+// renewLock extends the download lock on isoID every third of its TTL until
+// ctx, the download's context, is done. A lost lock is only logged: the
+// other node owns the ISO's status now, so canceling here would clobber it.
+func (m *Manager) renewLock(ctx context.Context, isoID string) {
+	ticker := time.NewTicker(m.lockTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.db.RenewDownloadLock(isoID, m.node, m.lockTTL); err != nil {
+				slog.WarnContext(ctx, "failed to renew download lock", slog.String("iso_id", isoID), slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// releaseLock removes this node's download lock on isoID.
+func (m *Manager) releaseLock(isoID string) {
+	if err := m.db.ReleaseDownloadLock(isoID, m.node); err != nil {
+		slog.Warn("failed to release download lock", slog.String("iso_id", isoID), slog.Any("error", err))
+	}
+}
+
+// remoteDownloads returns the downloads other nodes hold locks on. Their
+// progress lives on the other node, so only the ISO's status is known.
+func (m *Manager) remoteDownloads() []models.ActiveDownload {
+	locks, err := m.db.ListDownloadLocks()
+	if err != nil {
+		slog.Warn("failed to list download locks", slog.Any("error", err))
+		return nil
+	}
+
+	var downloads []models.ActiveDownload
+	for _, lock := range locks {
+		if lock.Node == m.node {
+			continue
+		}
+		iso, err := m.db.GetISO(lock.ISOID)
+		if err != nil {
+			continue // Deleted since; its lock is left to expire
+		}
+		downloads = append(downloads, models.ActiveDownload{
+			ISOID:     iso.ID,
+			Name:      iso.Name,
+			Version:   iso.Version,
+			Arch:      iso.Arch,
+			Edition:   iso.Edition,
+			Node:      lock.Node,
+			WorkerID:  -1,
+			Status:    iso.Status,
+			StartedAt: lock.AcquiredAt,
+		})
+	}
+	return downloads
+}
@@ -2,56 +2,209 @@ package download
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"os"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/aloks98/isoman/backend/internal/clock"
+	"github.com/aloks98/isoman/backend/internal/config"
+	"github.com/aloks98/isoman/backend/internal/constants"
 	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/fileutil"
+	"github.com/aloks98/isoman/backend/internal/httputil"
+	"github.com/aloks98/isoman/backend/internal/logger"
 	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/pathutil"
+	"github.com/aloks98/isoman/backend/internal/throughput"
 )
 
-// Manager manages a pool of download workers.
+var (
+	// ErrAlreadyQueued is returned by QueueDownload when the ISO is already queued or in flight.
+	ErrAlreadyQueued = errors.New("download already queued or in progress")
+	// ErrQueueFull is returned by QueueDownload when the download queue is at capacity.
+	ErrQueueFull = errors.New("download queue is full")
+)
+
+// Manager manages a pool of download workers and a separate pool of verify
+// workers, so checksum verification never holds a download slot.
 type Manager struct {
-	ctx              context.Context
-	db               *db.DB
-	queue            chan *models.ISO
-	progressCallback ProgressCallback
-	shutdown         chan struct{}
-	cancel           context.CancelFunc
-	activeDownloads  map[string]context.CancelFunc
-	isoDir           string
-	wg               sync.WaitGroup
-	workerCount      int
-	mu               sync.RWMutex
-	stopOnce         sync.Once
-}
-
-// NewManager creates a new download manager.
+	ctx               context.Context
+	db                *db.DB
+	cfg               *config.DownloadConfig
+	queue             chan *models.ISO
+	fastQueue         chan *models.ISO // Small downloads, also taken by the fast lane workers; nil without them
+	verifyQueue       chan *verifyTask
+	progressObservers observers[ProgressCallback]
+	queueEventHooks   observers[QueueEventHook]
+	ingest            *throughput.Meter
+	credentials       CredentialResolver
+	mirrors           *mirrorRotation // nil downloads every ISO from its own URL
+	clock             clock.Clock     // Stamps queue events and ISO times, paces retries and periodic jobs
+	shutdown          chan struct{}
+	cancel            context.CancelFunc
+	activeDownloads   map[string]*activeDownload
+	stopped           map[string]chan struct{} // Closed when a running download is released, even after CancelDownload
+	panics            atomic.Int64             // Panics recovered by download and verify workers
+	inFlight          map[string]string        // ISO ID to temp filename, from QueueDownload until finalized
+	waiting           map[string]*models.ISO   // ISOs in a queue, by ID, until a worker takes them
+	dropped           map[*models.ISO]bool     // Queue entries canceled by CancelQueued, which workers skip
+	fetches           map[string]*sharedFetch  // Transfers in progress, by fetchKey
+	isoDir            string
+	node              string        // Names this instance in download locks
+	lockTTL           time.Duration // Lease on each in-flight download
+	wg                sync.WaitGroup
+	workerCount       int
+	fastCount         int // Workers that only take the fast queue
+	verifyCount       int
+	mu                sync.RWMutex
+	enqueueMu         sync.Mutex // Serializes producers so a free queue slot can't be taken before the send
+	stopOnce          sync.Once
+}
+
+// NewManager creates a new download manager with default download settings.
 func NewManager(database *db.DB, isoDir string, workerCount int) *Manager {
+	cfg := DefaultConfig()
+	cfg.WorkerCount = workerCount
+	return NewManagerWithConfig(database, isoDir, cfg)
+}
+
+// NewManagerWithConfig creates a new download manager using the given download settings.
+func NewManagerWithConfig(database *db.DB, isoDir string, cfg *config.DownloadConfig) *Manager {
+	queueBuffer := cfg.QueueBuffer
+	if queueBuffer < 1 {
+		queueBuffer = constants.DefaultQueueBuffer
+	}
+
+	verifyCount := cfg.VerifyWorkerCount
+	if verifyCount < 1 {
+		verifyCount = constants.DefaultVerifyWorkerCount
+	}
+
+	node := cfg.NodeID
+	if node == "" {
+		if hostname, err := os.Hostname(); err == nil {
+			node = hostname
+		} else {
+			node = "localhost"
+		}
+	}
+
+	lockTTL := cfg.DownloadLockTTL
+	if lockTTL <= 0 {
+		lockTTL = constants.DefaultDownloadLockTTLSec * time.Second
+	}
+
+	var fastQueue chan *models.ISO
+	if cfg.FastLaneWorkers > 0 {
+		fastQueue = make(chan *models.ISO, queueBuffer)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Manager{
 		db:              database,
+		cfg:             cfg,
 		isoDir:          isoDir,
-		queue:           make(chan *models.ISO, 100),
-		workerCount:     workerCount,
+		node:            node,
+		lockTTL:         lockTTL,
+		queue:           make(chan *models.ISO, queueBuffer),
+		fastQueue:       fastQueue,
+		verifyQueue:     make(chan *verifyTask, queueBuffer),
+		workerCount:     cfg.WorkerCount,
+		fastCount:       cfg.FastLaneWorkers,
+		verifyCount:     verifyCount,
 		shutdown:        make(chan struct{}),
 		ctx:             ctx,
 		cancel:          cancel,
-		activeDownloads: make(map[string]context.CancelFunc),
+		activeDownloads: make(map[string]*activeDownload),
+		stopped:         make(map[string]chan struct{}),
+		inFlight:        make(map[string]string),
+		waiting:         make(map[string]*models.ISO),
+		dropped:         make(map[*models.ISO]bool),
+		fetches:         make(map[string]*sharedFetch),
+		clock:           clock.Real(),
+	}
+}
+
+// DefaultConfig returns download settings populated with the package defaults.
+func DefaultConfig() *config.DownloadConfig {
+	return &config.DownloadConfig{
+		WorkerCount:              constants.DefaultWorkerCount,
+		VerifyWorkerCount:        constants.DefaultVerifyWorkerCount,
+		FastLaneMaxSize:          constants.DefaultFastLaneMaxMB * 1024 * 1024,
+		QueueBuffer:              constants.DefaultQueueBuffer,
+		MaxRetries:               constants.DefaultMaxRetries,
+		RetryDelay:               constants.DefaultRetryDelayMs * time.Millisecond,
+		BufferSize:               constants.DefaultDownloadBufferSize,
+		ProgressUpdateInterval:   time.Second,
+		ProgressPercentThreshold: constants.DefaultProgressPercentThreshold,
+		ProgressPersistInterval:  constants.DefaultProgressPersistIntervalSec * time.Second,
+		MaxDownloadDuration:      constants.DefaultMaxDownloadDurationMin * time.Minute,
+		StallTimeout:             constants.DefaultStallTimeoutSec * time.Second,
+		CancellationWait:         constants.DefaultCancellationWaitMs * time.Millisecond,
+		KeepVersions:             constants.DefaultKeepVersions,
+		IntegrityHash:            constants.DefaultIntegrityHash,
+		TempCleanupInterval:      constants.DefaultTempCleanupIntervalMin * time.Minute,
+		TempMaxAge:               constants.DefaultTempMaxAgeHours * time.Hour,
+		DownloadLockTTL:          constants.DefaultDownloadLockTTLSec * time.Second,
 	}
 }
 
-// SetProgressCallback sets the callback function for progress updates.
-func (m *Manager) SetProgressCallback(callback ProgressCallback) {
-	m.progressCallback = callback
+// SetIngestMeter sets the meter that download workers add received bytes to.
+// Call it before Start.
+func (m *Manager) SetIngestMeter(meter *throughput.Meter) {
+	m.ingest = meter
+}
+
+// SetClock replaces the clock that stamps queue events, download logs, and the
+// completion, verification, and download start times recorded on ISOs, and
+// that times transfers, from stall detection and the download deadline to
+// progress updates, e.g. with a clock.Fake in tests. It also paces retries,
+// the queue poller, the watchdog, and the temp janitor, and sets their
+// cutoffs. Download lock leases keep using the real clock, since other
+// instances read them, as do the live stats of active downloads and the wait
+// for a canceled one to stop. Call it before Start.
+func (m *Manager) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
+// CredentialResolver supplies the authorizer for an ISO's upstream requests.
+type CredentialResolver interface {
+	AuthorizerFor(iso *models.ISO) httputil.Authorizer
+}
+
+// SetCredentialResolver sets where workers get upstream credentials from.
+// Call it before Start.
+func (m *Manager) SetCredentialResolver(resolver CredentialResolver) {
+	m.credentials = resolver
+}
+
+// IntegrityHash returns the algorithm workers use for the internal integrity hash.
+func (m *Manager) IntegrityHash() string {
+	return integrityHashOrDefault(m.cfg.IntegrityHash)
 }
 
 // Start launches the worker goroutines.
 func (m *Manager) Start() {
 	for i := 0; i < m.workerCount; i++ {
 		m.wg.Add(1)
-		go m.worker(i)
+		go m.worker(i, m.queue)
+	}
+	for i := 0; i < m.fastCount; i++ {
+		m.wg.Add(1)
+		go m.worker(m.workerCount+i, nil)
+	}
+	for i := 0; i < m.verifyCount; i++ {
+		m.wg.Add(1)
+		go m.verifyWorker(i)
 	}
-	slog.Debug("download manager workers started", slog.Int("worker_count", m.workerCount))
+	slog.Debug("download manager workers started",
+		slog.Int("worker_count", m.workerCount),
+		slog.Int("fast_lane_worker_count", m.fastCount),
+		slog.Int("verify_worker_count", m.verifyCount),
+	)
 }
 
 // Stop gracefully shuts down the manager (safe to call multiple times).
@@ -65,55 +218,200 @@ func (m *Manager) Stop() {
 	})
 }
 
-// QueueDownload adds an ISO to the download queue.
-func (m *Manager) QueueDownload(iso *models.ISO) {
-	m.queue <- iso
+// QueueDownload marks an ISO as queued and adds it to the download queue, or
+// the fast lane if its recorded size is small. An ISO of unknown size is
+// sized in the background and moved to the fast lane if it fits. It returns
+// ErrAlreadyQueued if the ISO is still queued, downloading, or verifying,
+// and ErrQueueFull instead of blocking when its queue is at capacity.
+func (m *Manager) QueueDownload(iso *models.ISO) error {
+	queue := m.laneFor(iso, iso.SizeBytes)
+
+	m.enqueueMu.Lock()
+	defer m.enqueueMu.Unlock()
+
+	if err := m.reserve(iso, queue); err != nil {
+		return err
+	}
+
+	iso.Status = models.StatusQueued
+	iso.Progress = 0
+	if err := m.db.UpdateISOStatus(iso.ID, models.StatusQueued, iso.ErrorMessage); err != nil {
+		slog.Warn("failed to mark ISO as queued", slog.String("iso_id", iso.ID), slog.Any("error", err))
+	}
+	m.notifyProgress(iso.ID, 0, models.StatusQueued)
+	m.emit(newQueueEvent(models.QueueEventQueued, iso, models.StatusQueued))
+
+	queue <- iso
+	m.relaneLater(iso, queue)
+	return nil
+}
+
+// reserve registers iso as in flight if queue has room for it. The caller
+// holds enqueueMu and sends iso to queue.
+func (m *Manager) reserve(iso *models.ISO, queue chan *models.ISO) error {
+	if len(queue) >= cap(queue) {
+		return ErrQueueFull
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.inFlight[iso.ID]; exists {
+		return ErrAlreadyQueued
+	}
+	m.inFlight[iso.ID] = iso.Filename
+	m.waiting[iso.ID] = iso
+	return nil
+}
+
+// take reports whether a worker should run iso, just taken from a queue, and
+// stops it from being canceled as queued. Entries canceled while waiting are
+// skipped; their ISO may have been queued again since under a new entry.
+func (m *Manager) take(iso *models.ISO) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.dropped[iso] {
+		delete(m.dropped, iso)
+		return false
+	}
+	delete(m.waiting, iso.ID)
+	return true
+}
+
+// QueueDepth returns the number of downloads waiting for a free worker, in
+// either lane.
+func (m *Manager) QueueDepth() int {
+	return len(m.queue) + len(m.fastQueue)
+}
+
+// WorkerPanics returns how many panics workers recovered from since startup.
+func (m *Manager) WorkerPanics() int64 {
+	return m.panics.Load()
 }
 
-// worker is the main worker goroutine.
-func (m *Manager) worker(id int) {
+// QueueCapacity returns the maximum number of downloads that can wait in the
+// queue and the fast lane.
+func (m *Manager) QueueCapacity() int {
+	return cap(m.queue) + cap(m.fastQueue)
+}
+
+// worker is the main worker goroutine. It takes downloads from queue and the
+// fast lane; fast lane workers pass a nil queue, so they only take small ones.
+func (m *Manager) worker(id int, queue chan *models.ISO) {
 	defer m.wg.Done()
 
-	worker := NewWorker(m.db, m.isoDir, m.progressCallback)
+	worker := NewWorker(m.db, m.isoDir, m.cfg, m.notifyProgress)
+	worker.clock = m.clock
+	worker.ingest = m.ingest
+	worker.panics = &m.panics
+	worker.mirrors = m.mirrors
 
 	for {
+		var iso *models.ISO
 		select {
 		case <-m.shutdown:
 			slog.Debug("worker shutting down", slog.Int("worker_id", id))
 			return
+		case iso = <-queue:
+		case iso = <-m.fastQueue:
+		}
+		if !m.take(iso) {
+			continue
+		}
 
-		case iso := <-m.queue:
-			slog.Info("worker starting download",
+		// Create a child context that can be canceled independently. It
+		// carries the queuing request's ID so worker logs can be traced to it.
+		downloadCtx, cancelDownload := context.WithCancel(logger.WithRequestID(m.ctx, iso.RequestID))
+		if m.credentials != nil {
+			// Also covers the checksum fetch, which runs on the same context
+			downloadCtx = httputil.WithAuthorizer(downloadCtx, m.credentials.AuthorizerFor(iso))
+		}
+
+		// Another instance sharing the database may have claimed it already
+		if !m.claim(downloadCtx, iso) {
+			m.mu.Lock()
+			delete(m.inFlight, iso.ID)
+			m.mu.Unlock()
+			cancelDownload()
+			continue
+		}
+		go m.renewLock(downloadCtx, iso.ID)
+
+		slog.InfoContext(downloadCtx, "worker starting download",
+			slog.Int("worker_id", id),
+			slog.String("name", iso.Name),
+			slog.String("iso_id", iso.ID),
+		)
+
+		// Register the cancel function and where the transfer reports progress
+		active := newActiveDownload(iso, m.node, id, cancelDownload)
+		downloadCtx = withActiveDownload(downloadCtx, active)
+		m.mu.Lock()
+		m.activeDownloads[iso.ID] = active
+		m.stopped[iso.ID] = make(chan struct{})
+		m.mu.Unlock()
+		m.emit(newQueueEvent(models.QueueEventStarted, iso, models.StatusDownloading))
+
+		// Download the file, or share another worker's transfer of the same
+		// URL, then hand it to the verify pool
+		job, err := m.fetchShared(downloadCtx, worker, iso)
+		if err != nil {
+			m.release(iso.ID, cancelDownload)
+			m.emitResult(downloadCtx, iso, err, m.keepPreviousFile(iso, err))
+			slog.ErrorContext(downloadCtx, "worker download failed",
 				slog.Int("worker_id", id),
 				slog.String("name", iso.Name),
-				slog.String("iso_id", iso.ID),
+				slog.Any("error", err),
 			)
+			continue
+		}
 
-			// Create a child context that can be canceled independently
-			downloadCtx, cancelDownload := context.WithCancel(m.ctx)
+		active.verifying.Store(true)
+		select {
+		case m.verifyQueue <- &verifyTask{job: job, ctx: downloadCtx, cancel: cancelDownload}:
+		case <-m.shutdown:
+			fileutil.DeleteFileSilently(job.tmpFile)
+			m.release(iso.ID, cancelDownload)
+			return
+		}
+	}
+}
 
-			// Register the cancel function
-			m.mu.Lock()
-			m.activeDownloads[iso.ID] = cancelDownload
-			m.mu.Unlock()
+// verifyTask is a fetched download queued for the verify pool. It carries the
+// download's context so cancellation keeps working until the ISO is complete.
+type verifyTask struct {
+	job    *verifyJob
+	ctx    context.Context
+	cancel context.CancelFunc
+}
 
-			// Process the download
-			err := worker.Process(downloadCtx, iso)
+// verifyWorker verifies and finalizes downloads handed off by the download workers.
+func (m *Manager) verifyWorker(id int) {
+	defer m.wg.Done()
 
-			// Clean up the cancel function
-			m.mu.Lock()
-			delete(m.activeDownloads, iso.ID)
-			m.mu.Unlock()
-			cancelDownload() // Clean up context resources
+	worker := NewWorker(m.db, m.isoDir, m.cfg, m.notifyProgress)
+	worker.clock = m.clock
+	worker.panics = &m.panics
+
+	for {
+		select {
+		case <-m.shutdown:
+			slog.Debug("verify worker shutting down", slog.Int("worker_id", id))
+			return
+
+		case task := <-m.verifyQueue:
+			iso := task.job.iso
+			err := worker.finalize(task.ctx, task.job)
+			m.release(iso.ID, task.cancel)
+			m.emitResult(task.ctx, iso, err, err != nil && m.keepPreviousFile(iso, err))
 
 			if err != nil {
-				slog.Error("worker download failed",
+				slog.ErrorContext(task.ctx, "worker download failed",
 					slog.Int("worker_id", id),
 					slog.String("name", iso.Name),
 					slog.Any("error", err),
 				)
 			} else {
-				slog.Info("worker download completed",
+				slog.InfoContext(task.ctx, "worker download completed",
 					slog.Int("worker_id", id),
 					slog.String("name", iso.Name),
 				)
@@ -121,3 +419,87 @@ func (m *Manager) worker(id int) {
 		}
 	}
 }
+
+// newQueueEvent returns an event of eventType for iso, now in status.
+func newQueueEvent(eventType string, iso *models.ISO, status models.ISOStatus) models.QueueEvent {
+	return models.QueueEvent{
+		Type:      eventType,
+		ISOID:     iso.ID,
+		Name:      iso.Name,
+		Filename:  iso.Filename,
+		Status:    status,
+		RequestID: iso.RequestID,
+	}
+}
+
+// emitResult emits the finished or failed event of a download that ended with
+// err. kept is set when a failed redownload went back to the previous file.
+func (m *Manager) emitResult(ctx context.Context, iso *models.ISO, err error, kept bool) {
+	event := newQueueEvent(models.QueueEventFinished, iso, models.StatusComplete)
+	if err != nil {
+		event.Type = models.QueueEventFailed
+		event.Error = err.Error()
+		switch {
+		case kept:
+			event.Status = models.StatusComplete
+		case errors.Is(err, ErrQuarantined):
+			event.Status = models.StatusQuarantined
+		case errors.Is(err, context.Canceled):
+			event.Status = models.StatusCanceled
+		default:
+			event.Status = models.StatusFailed
+		}
+	}
+	if active := activeDownloadFrom(ctx); active != nil {
+		event.DurationMs = time.Since(active.info.StartedAt).Milliseconds()
+	}
+	m.emit(event)
+}
+
+// keepPreviousFile puts a redownloaded ISO whose download failed or was
+// canceled with err back to complete, serving the file it had before, and
+// reports whether it did. A quarantined download stays quarantined for review,
+// and an ISO whose file is gone stays failed.
+func (m *Manager) keepPreviousFile(iso *models.ISO, err error) bool {
+	if !iso.Redownload || errors.Is(err, ErrQuarantined) {
+		return false
+	}
+	fi, statErr := os.Stat(pathutil.ConstructISOPath(m.isoDir, iso.FilePath))
+	if statErr != nil {
+		return false
+	}
+	current, getErr := m.db.GetISO(iso.ID)
+	if getErr != nil {
+		return false // Deleted while it downloaded
+	}
+
+	current.Status = models.StatusComplete
+	current.Progress = 100
+	current.SizeBytes = fi.Size()
+	current.ErrorMessage = "redownload failed, previous file kept: " + err.Error()
+	current.Redownload = false
+	if updateErr := m.db.UpdateISO(current); updateErr != nil {
+		slog.Warn("failed to restore redownloaded ISO", slog.String("iso_id", iso.ID), slog.Any("error", updateErr))
+		return false
+	}
+	m.logDownload(iso.ID, models.LogLevelWarn, "Redownload failed; the previous file is still served")
+	m.notifyProgress(iso.ID, 100, models.StatusComplete)
+	slog.Info("kept previous file of failed redownload", slog.String("iso_id", iso.ID), slog.String("name", iso.Name))
+	return true
+}
+
+// release unregisters an ISO's cancel function and unlocks its download once
+// it is no longer in flight, allowing it to be queued again. It wakes
+// CancelAndWait: the worker is done with the ISO's files and record.
+func (m *Manager) release(isoID string, cancel context.CancelFunc) {
+	m.releaseLock(isoID)
+	m.mu.Lock()
+	delete(m.activeDownloads, isoID)
+	delete(m.inFlight, isoID)
+	if stopped, ok := m.stopped[isoID]; ok {
+		close(stopped)
+		delete(m.stopped, isoID)
+	}
+	m.mu.Unlock()
+	cancel() // Clean up context resources
+}
@@ -1,18 +1,27 @@
 package download
 
 import (
+	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/aloks98/isoman/backend/internal/clock"
 	"github.com/aloks98/isoman/backend/internal/config"
 	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/fileutil"
+	"github.com/aloks98/isoman/backend/internal/httputil"
 	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/testutil"
 
 	"github.com/google/uuid"
 )
@@ -210,7 +219,7 @@ func TestManagerProgressCallback(t *testing.T) {
 	var mu sync.Mutex
 	progressCalls := 0
 	var lastStatus models.ISOStatus
-	manager.SetProgressCallback(func(isoID string, progress int, status models.ISOStatus) {
+	manager.AddProgressObserver(func(isoID string, progress int, status models.ISOStatus) {
 		mu.Lock()
 		defer mu.Unlock()
 		progressCalls++
@@ -264,6 +273,124 @@ func TestManagerProgressCallback(t *testing.T) {
 	}
 }
 
+// TestManagerProgressObservers tests that every registered observer gets each
+// update, in registration order, until it is removed.
+func TestManagerProgressObservers(t *testing.T) {
+	manager, _, _, cleanup := setupTestManager(t, 1)
+	defer cleanup()
+
+	var calls []string
+	manager.AddProgressObserver(func(isoID string, progress int, status models.ISOStatus) {
+		calls = append(calls, "first:"+string(status))
+	})
+	remove := manager.AddProgressObserver(func(isoID string, progress int, status models.ISOStatus) {
+		calls = append(calls, "second:"+string(status))
+	})
+
+	manager.notifyProgress("iso-1", 0, models.StatusQueued)
+	remove()
+	remove()
+	manager.notifyProgress("iso-1", 100, models.StatusComplete)
+
+	want := []string{"first:queued", "second:queued", "first:complete"}
+	if strings.Join(calls, ",") != strings.Join(want, ",") {
+		t.Errorf("Observer calls = %v, want %v", calls, want)
+	}
+}
+
+// TestManagerQueueEvents tests that hooks see each download queued, started,
+// and then finished or failed, and that a panicking hook is skipped.
+func TestManagerQueueEvents(t *testing.T) {
+	manager, database, _, cleanup := setupTestManager(t, 1)
+	defer cleanup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.iso" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("test iso content"))
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	events := make(map[string][]models.QueueEvent)
+	done := make(chan struct{}, 2)
+	manager.AddQueueEventHook(func(event models.QueueEvent) {
+		panic("broken hook")
+	})
+	manager.AddQueueEventHook(func(event models.QueueEvent) {
+		mu.Lock()
+		events[event.ISOID] = append(events[event.ISOID], event)
+		mu.Unlock()
+		if event.Type == models.QueueEventFinished || event.Type == models.QueueEventFailed {
+			done <- struct{}{}
+		}
+	})
+
+	var isos []*models.ISO
+	for _, name := range []string{"good", "missing"} {
+		iso := &models.ISO{
+			ID:          uuid.New().String(),
+			Name:        name,
+			Version:     "1.0",
+			Arch:        "x86_64",
+			FileType:    "iso",
+			DownloadURL: server.URL + "/" + name + ".iso",
+			Status:      models.StatusPending,
+			CreatedAt:   time.Now(),
+		}
+		iso.ComputeFields()
+		database.CreateISO(iso)
+		isos = append(isos, iso)
+	}
+
+	manager.Start()
+	for _, iso := range isos {
+		if err := manager.QueueDownload(iso); err != nil {
+			t.Fatalf("QueueDownload() failed: %v", err)
+		}
+	}
+	for range isos {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for downloads to end")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	tests := []struct {
+		iso    *models.ISO
+		last   string
+		status models.ISOStatus
+	}{
+		{isos[0], models.QueueEventFinished, models.StatusComplete},
+		{isos[1], models.QueueEventFailed, models.StatusFailed},
+	}
+	for _, tt := range tests {
+		got := events[tt.iso.ID]
+		if len(got) != 3 {
+			t.Fatalf("%s: expected 3 events, got %+v", tt.iso.Name, got)
+		}
+		types := []string{got[0].Type, got[1].Type, got[2].Type}
+		want := []string{models.QueueEventQueued, models.QueueEventStarted, tt.last}
+		if strings.Join(types, ",") != strings.Join(want, ",") {
+			t.Errorf("%s: event types = %v, want %v", tt.iso.Name, types, want)
+		}
+		if got[2].Status != tt.status {
+			t.Errorf("%s: final status = %s, want %s", tt.iso.Name, got[2].Status, tt.status)
+		}
+		if got[2].Filename != tt.iso.Filename || got[2].Node != manager.Node() || got[2].At.IsZero() {
+			t.Errorf("%s: expected the event to describe the download, got %+v", tt.iso.Name, got[2])
+		}
+		if (tt.status == models.StatusFailed) != (got[2].Error != "") {
+			t.Errorf("%s: unexpected error %q", tt.iso.Name, got[2].Error)
+		}
+	}
+}
+
 // TestManagerGracefulShutdown tests that manager stops gracefully.
 func TestManagerGracefulShutdown(t *testing.T) {
 	manager, database, _, cleanup := setupTestManager(t, 2)
@@ -314,8 +441,8 @@ func TestManagerGracefulShutdown(t *testing.T) {
 		t.Fatalf("Failed to get updated ISO: %v", err)
 	}
 
-	if updatedISO.Status != models.StatusFailed {
-		t.Errorf("Expected status 'failed' after cancellation, got: %s", updatedISO.Status)
+	if updatedISO.Status != models.StatusCanceled {
+		t.Errorf("Expected status 'canceled' after cancellation, got: %s", updatedISO.Status)
 	}
 
 	if updatedISO.ErrorMessage != "Download canceled" {
@@ -345,21 +472,718 @@ func TestManagerQueueCapacity(t *testing.T) {
 		iso.ComputeFields()
 		database.CreateISO(iso)
 
-		// Try to queue (should not block for first 100)
-		done := make(chan bool, 1)
+		// Try to queue (should never block)
+		done := make(chan error, 1)
 		go func() {
-			manager.QueueDownload(iso)
-			done <- true
+			done <- manager.QueueDownload(iso)
 		}()
 
-		// Should complete quickly for first 100
-		if i < 100 {
-			select {
-			case <-done:
-				// OK
-			case <-time.After(100 * time.Millisecond):
-				t.Fatalf("Queueing item %d blocked unexpectedly", i)
+		select {
+		case err := <-done:
+			// The first 100 fit; the rest are rejected instead of blocking
+			if i < 100 && err != nil {
+				t.Fatalf("Queueing item %d failed: %v", i, err)
+			}
+			if i >= 100 && !errors.Is(err, ErrQueueFull) {
+				t.Fatalf("Expected ErrQueueFull for item %d, got: %v", i, err)
 			}
+		case <-time.After(100 * time.Millisecond):
+			t.Fatalf("Queueing item %d blocked unexpectedly", i)
 		}
 	}
+
+	if manager.QueueDepth() != 100 || manager.QueueCapacity() != 100 {
+		t.Errorf("Expected queue depth and capacity of 100, got: %d/%d", manager.QueueDepth(), manager.QueueCapacity())
+	}
+}
+
+// TestManagerQueueDownloadDedupe tests that an ISO can't be queued twice while in flight.
+func TestManagerQueueDownloadDedupe(t *testing.T) {
+	manager, database, _, cleanup := setupTestManager(t, 1)
+	defer cleanup()
+
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "test",
+		Version:     "1.0",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: "http://example.com/test.iso",
+		Status:      models.StatusPending,
+		CreatedAt:   time.Now(),
+	}
+	iso.ComputeFields()
+	database.CreateISO(iso)
+
+	if err := manager.QueueDownload(iso); err != nil {
+		t.Fatalf("QueueDownload failed: %v", err)
+	}
+	if err := manager.QueueDownload(iso); !errors.Is(err, ErrAlreadyQueued) {
+		t.Errorf("Expected ErrAlreadyQueued for a duplicate, got: %v", err)
+	}
+
+	// Once released, the ISO can be queued again
+	manager.release(iso.ID, func() {})
+	if err := manager.QueueDownload(iso); err != nil {
+		t.Errorf("QueueDownload after release failed: %v", err)
+	}
+}
+
+// TestManagerVerifyPoolFreesDownloadSlot tests that a slow verification doesn't block the next download.
+func TestManagerVerifyPoolFreesDownloadSlot(t *testing.T) {
+	manager, database, _, cleanup := setupTestManager(t, 1)
+	defer cleanup()
+
+	testContent := []byte("test content")
+	sum := sha256.Sum256(testContent)
+	release := make(chan struct{})
+	var releaseOnce sync.Once
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a.iso.sha256":
+			<-release // Hold the first verification until the second download is done
+			fmt.Fprintf(w, "%x  a.iso\n", sum)
+		case "/b.iso.sha256":
+			fmt.Fprintf(w, "%x  b.iso\n", sum)
+		default:
+			w.Write(testContent)
+		}
+	}))
+	defer server.Close()
+	defer releaseOnce.Do(func() { close(release) })
+
+	isos := make([]*models.ISO, 0, 2)
+	for _, name := range []string{"a", "b"} {
+		iso := &models.ISO{
+			ID:           uuid.New().String(),
+			Name:         name,
+			Version:      "1.0",
+			Arch:         "x86_64",
+			FileType:     "iso",
+			DownloadURL:  server.URL + "/" + name + ".iso",
+			ChecksumURL:  server.URL + "/" + name + ".iso.sha256",
+			ChecksumType: "sha256",
+			Status:       models.StatusPending,
+			CreatedAt:    time.Now(),
+		}
+		iso.ComputeFields()
+		database.CreateISO(iso)
+		isos = append(isos, iso)
+	}
+
+	manager.Start()
+	for _, iso := range isos {
+		manager.QueueDownload(iso)
+	}
+
+	waitForStatus := func(id string, want models.ISOStatus) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if iso, err := database.GetISO(id); err == nil && iso.Status == want {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatalf("ISO %s did not reach status %q", id, want)
+	}
+
+	// The second download finishes while the first is still being verified
+	waitForStatus(isos[1].ID, models.StatusVerifying)
+	if iso, _ := database.GetISO(isos[0].ID); iso.Status != models.StatusVerifying {
+		t.Errorf("First ISO should still be verifying, got: %s", iso.Status)
+	}
+
+	releaseOnce.Do(func() { close(release) })
+	waitForStatus(isos[0].ID, models.StatusComplete)
+	waitForStatus(isos[1].ID, models.StatusComplete)
+}
+
+func TestManagerActiveDownloads(t *testing.T) {
+	manager, database, _, cleanup := setupTestManager(t, 1)
+	defer cleanup()
+
+	release := make(chan struct{})
+	var releaseOnce sync.Once
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write(make([]byte, 40))
+		w.(http.Flusher).Flush()
+		<-release // Hold the transfer so it can be observed
+		w.Write(make([]byte, 60))
+	}))
+	defer server.Close()
+	defer releaseOnce.Do(func() { close(release) })
+
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "alpine",
+		Version:     "3.19.1",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: server.URL + "/alpine.iso",
+		Status:      models.StatusPending,
+		CreatedAt:   time.Now(),
+	}
+	iso.ComputeFields()
+	database.CreateISO(iso)
+
+	if active := manager.ActiveDownloads(); len(active) != 0 {
+		t.Fatalf("Expected no active downloads before starting, got %+v", active)
+	}
+	manager.Start()
+	manager.QueueDownload(iso)
+
+	waitFor := func(done func([]models.ActiveDownload) bool) []models.ActiveDownload {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if active := manager.ActiveDownloads(); done(active) {
+				return active
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("Active downloads never reached the expected state: %+v", manager.ActiveDownloads())
+		return nil
+	}
+
+	active := waitFor(func(active []models.ActiveDownload) bool {
+		return len(active) == 1 && active[0].BytesDownloaded == 40
+	})
+	got := active[0]
+	if got.ISOID != iso.ID || got.Name != "alpine" || got.Node != manager.Node() || got.WorkerID != 0 || got.Status != models.StatusDownloading || got.BytesTotal != 100 || got.StartedAt.IsZero() {
+		t.Errorf("Unexpected active download: %+v", got)
+	}
+
+	releaseOnce.Do(func() { close(release) })
+	waitFor(func(active []models.ActiveDownload) bool { return len(active) == 0 })
+}
+
+// TestManagerState tests that the state dump shows a busy worker, the
+// download it runs, and one waiting behind it in the queue.
+func TestManagerState(t *testing.T) {
+	manager, database, _, cleanup := setupTestManager(t, 1)
+	defer cleanup()
+
+	release := make(chan struct{})
+	var releaseOnce sync.Once
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write(make([]byte, 40))
+		w.(http.Flusher).Flush()
+		<-release // Hold the transfer so the worker stays busy
+		w.Write(make([]byte, 60))
+	}))
+	defer server.Close()
+	defer releaseOnce.Do(func() { close(release) })
+
+	create := func(name string) *models.ISO {
+		t.Helper()
+		iso := &models.ISO{
+			ID:          uuid.New().String(),
+			Name:        name,
+			Version:     "1.0",
+			Arch:        "x86_64",
+			FileType:    "iso",
+			DownloadURL: server.URL + "/" + name + ".iso",
+			Status:      models.StatusPending,
+			CreatedAt:   time.Now(),
+		}
+		iso.ComputeFields()
+		if err := database.CreateISO(iso); err != nil {
+			t.Fatalf("CreateISO() failed: %v", err)
+		}
+		return iso
+	}
+
+	state, err := manager.State()
+	if err != nil {
+		t.Fatalf("State() failed: %v", err)
+	}
+	if len(state.Workers) != 1 || state.Workers[0].State != models.WorkerStateIdle || len(state.InFlight) != 0 || state.Node != manager.Node() {
+		t.Fatalf("Expected one idle worker and nothing in flight, got %+v", state)
+	}
+
+	manager.Start()
+	running := create("running")
+	waiting := create("waiting")
+	manager.QueueDownload(running)
+	deadline := time.Now().Add(5 * time.Second)
+	for !manager.IsDownloading(running.ID) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	manager.QueueDownload(waiting)
+
+	state, err = manager.State()
+	if err != nil {
+		t.Fatalf("State() failed: %v", err)
+	}
+	if w := state.Workers[0]; w.State != models.WorkerStateDownloading || w.ISOID != running.ID || w.Lane != models.WorkerLaneRegular {
+		t.Errorf("Expected the worker downloading %s, got %+v", running.ID, w)
+	}
+	if state.Queues.Queued != 1 || len(state.Active) != 1 || len(state.Locks) != 1 || state.Stopped {
+		t.Errorf("Expected one queued, one active, and one lock, got %+v", state)
+	}
+	statuses := map[string]models.ISOStatus{}
+	for _, d := range state.InFlight {
+		statuses[d.ISOID] = d.Status
+	}
+	if statuses[running.ID] != models.StatusDownloading || statuses[waiting.ID] != models.StatusQueued {
+		t.Errorf("Unexpected in-flight downloads: %+v", state.InFlight)
+	}
+}
+
+// TestManagerRetryPendingChecksums tests that ISOs completed without their
+// checksum file are verified once it is back, and failed on a mismatch.
+func TestManagerRetryPendingChecksums(t *testing.T) {
+	manager, database, isoDir, cleanup := setupTestManager(t, 1)
+	defer cleanup()
+
+	content := []byte("test iso content")
+	var available atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, "%x  good.iso\n%064d  bad.iso\n", sha256.Sum256(content), 0)
+	}))
+	defer server.Close()
+
+	create := func(name string) *models.ISO {
+		t.Helper()
+		iso := &models.ISO{
+			ID:              uuid.New().String(),
+			Name:            name,
+			Version:         "1.0",
+			Arch:            "x86_64",
+			FileType:        "iso",
+			DownloadURL:     "https://example.com/" + name + ".iso",
+			ChecksumURL:     server.URL + "/SHA256SUMS",
+			ChecksumType:    "sha256",
+			SHA256:          fmt.Sprintf("%x", sha256.Sum256(content)),
+			Status:          models.StatusComplete,
+			Progress:        100,
+			ChecksumPending: true,
+			CreatedAt:       time.Now(),
+		}
+		iso.ComputeFields()
+		path := filepath.Join(isoDir, iso.FilePath)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		os.WriteFile(path, content, 0o644)
+		if err := database.CreateISO(iso); err != nil {
+			t.Fatalf("CreateISO() failed: %v", err)
+		}
+		return iso
+	}
+	good := create("good")
+	bad := create("bad")
+
+	if verified := manager.retryPendingChecksums(context.Background()); verified != 0 {
+		t.Fatalf("Expected nothing verified while the checksum file is unavailable, got %d", verified)
+	}
+	if iso, _ := database.GetISO(good.ID); !iso.ChecksumPending || iso.Status != models.StatusComplete {
+		t.Fatalf("Expected the ISO to stay pending, got %s (pending=%t)", iso.Status, iso.ChecksumPending)
+	}
+
+	available.Store(true)
+	if verified := manager.retryPendingChecksums(context.Background()); verified != 1 {
+		t.Fatalf("Expected one ISO verified, got %d", verified)
+	}
+	iso, _ := database.GetISO(good.ID)
+	if iso.ChecksumPending || iso.VerifiedAt == nil || iso.Status != models.StatusComplete {
+		t.Errorf("Expected the good ISO verified, got %s (pending=%t, verified_at=%v)", iso.Status, iso.ChecksumPending, iso.VerifiedAt)
+	}
+	if !fileutil.FileExists(filepath.Join(isoDir, iso.FilePath+".sha256")) {
+		t.Error("Expected the checksum file saved beside the ISO")
+	}
+
+	iso, _ = database.GetISO(bad.ID)
+	if iso.Status != models.StatusFailed || iso.ChecksumPending || !strings.Contains(iso.ErrorMessage, "checksum mismatch") {
+		t.Errorf("Expected the bad ISO failed on its checksum, got %s (pending=%t): %s", iso.Status, iso.ChecksumPending, iso.ErrorMessage)
+	}
+	if fileutil.FileExists(filepath.Join(isoDir, iso.FilePath)) {
+		t.Error("Expected the mismatched file removed")
+	}
+}
+
+// TestManagerQueueForecast tests that queued ISOs are estimated back to back
+// on the free worker from the throughput of completed downloads.
+func TestManagerQueueForecast(t *testing.T) {
+	manager, database, _, cleanup := setupTestManager(t, 1)
+	defer cleanup()
+	now := time.Date(2024, 1, 26, 14, 45, 0, 0, time.UTC)
+	manager.SetClock(clock.NewFake(now))
+
+	create := func(name string, status models.ISOStatus, size int64, createdAt time.Time) *models.ISO {
+		t.Helper()
+		iso := &models.ISO{
+			ID:          uuid.New().String(),
+			Name:        name,
+			Version:     "1.0",
+			Arch:        "x86_64",
+			FileType:    "iso",
+			DownloadURL: "https://example.com/" + name + ".iso",
+			Status:      status,
+			SizeBytes:   size,
+			CreatedAt:   createdAt,
+		}
+		iso.ComputeFields()
+		if err := database.CreateISO(iso); err != nil {
+			t.Fatalf("CreateISO() failed: %v", err)
+		}
+		return iso
+	}
+
+	// Nothing completed yet: the queue is listed without estimates
+	small := create("small", models.StatusQueued, 10<<20, now.Add(-2*time.Minute))
+	unknown := create("unknown", models.StatusQueued, 0, now.Add(-time.Minute))
+	forecast, err := manager.QueueForecast()
+	if err != nil {
+		t.Fatalf("QueueForecast() failed: %v", err)
+	}
+	if len(forecast.Queue) != 2 || forecast.Queue[0].EstimatedStartAt != nil || forecast.BytesPerSec != 0 {
+		t.Fatalf("Expected two queued ISOs without estimates, got %+v", forecast)
+	}
+
+	// 100 MiB in 100s and 300 MiB in 300s: 1 MiB/s and a typical 200s
+	for i, size := range []int64{100 << 20, 300 << 20} {
+		iso := create(fmt.Sprintf("done-%d", i), models.StatusComplete, size, now.Add(-time.Hour))
+		started := now.Add(-time.Hour)
+		completed := started.Add(time.Duration(size>>20) * time.Second)
+		iso.DownloadStartedAt = &started
+		iso.CompletedAt = &completed
+		if err := database.UpdateISO(iso); err != nil {
+			t.Fatalf("UpdateISO() failed: %v", err)
+		}
+	}
+
+	forecast, err = manager.QueueForecast()
+	if err != nil {
+		t.Fatalf("QueueForecast() failed: %v", err)
+	}
+	if forecast.BytesPerSec != 1<<20 || !forecast.GeneratedAt.Equal(now) || len(forecast.Queue) != 2 {
+		t.Fatalf("Unexpected forecast: %+v", forecast)
+	}
+	expected := []struct {
+		id                string
+		start, completion time.Duration
+	}{
+		{small.ID, 0, 10 * time.Second},
+		{unknown.ID, 10 * time.Second, 210 * time.Second},
+	}
+	for i, want := range expected {
+		got := forecast.Queue[i]
+		if got.ISOID != want.id || got.Position != i+1 || got.EstimatedStartAt == nil || got.EstimatedCompletionAt == nil {
+			t.Fatalf("Queue[%d] = %+v, expected %s with estimates", i, got, want.id)
+		}
+		if !got.EstimatedStartAt.Equal(now.Add(want.start)) || !got.EstimatedCompletionAt.Equal(now.Add(want.completion)) {
+			t.Errorf("Queue[%d] runs %v to %v, expected +%v to +%v", i, got.EstimatedStartAt, got.EstimatedCompletionAt, want.start, want.completion)
+		}
+	}
+}
+
+// TestManagerDownloadLock tests that an ISO locked by another node isn't
+// downloaded, and that a finished download releases its lock.
+func TestManagerDownloadLock(t *testing.T) {
+	manager, database, _, cleanup := setupTestManager(t, 1)
+	defer cleanup()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte("test content"))
+	}))
+	defer server.Close()
+
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "alpine",
+		Version:     "3.19.1",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: server.URL + "/alpine.iso",
+		Status:      models.StatusPending,
+		CreatedAt:   time.Now(),
+	}
+	iso.ComputeFields()
+	database.CreateISO(iso)
+	if err := database.AcquireDownloadLock(iso.ID, "other-node", time.Minute); err != nil {
+		t.Fatalf("AcquireDownloadLock() failed: %v", err)
+	}
+
+	manager.Start()
+	manager.QueueDownload(iso)
+	deadline := time.Now().Add(5 * time.Second)
+	for manager.QueueDownload(iso) == ErrAlreadyQueued && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if requests.Load() != 0 {
+		t.Errorf("Expected no request for a locked ISO, got %d", requests.Load())
+	}
+
+	active := manager.ActiveDownloads()
+	if len(active) != 1 || active[0].Node != "other-node" || active[0].WorkerID != -1 {
+		t.Errorf("Expected the other node's download, got %+v", active)
+	}
+
+	// Once the other node lets go, the next attempt downloads it
+	database.ReleaseDownloadLock(iso.ID, "other-node")
+	manager.QueueDownload(iso)
+	for time.Now().Before(deadline) {
+		if got, _ := database.GetISO(iso.ID); got.Status == models.StatusComplete {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got, _ := database.GetISO(iso.ID); got.Status != models.StatusComplete {
+		t.Fatalf("Expected the download to complete, got %s", got.Status)
+	}
+	if locks, _ := database.ListDownloadLocks(); len(locks) != 0 {
+		t.Errorf("Expected the lock released, got %+v", locks)
+	}
+}
+
+// TestManagerPollQueue tests that idle workers pick up ISOs another instance
+// queued, but not ones another node has locked.
+func TestManagerPollQueue(t *testing.T) {
+	manager, database, _, cleanup := setupTestManager(t, 2)
+	defer cleanup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("test content"))
+	}))
+	defer server.Close()
+
+	isos := make([]*models.ISO, 0, 2)
+	for _, name := range []string{"free", "locked"} {
+		iso := &models.ISO{
+			ID:          uuid.New().String(),
+			Name:        name,
+			Version:     "1.0",
+			Arch:        "x86_64",
+			FileType:    "iso",
+			DownloadURL: server.URL + "/" + name + ".iso",
+			Status:      models.StatusQueued, // Queued elsewhere, never sent to this manager
+			CreatedAt:   time.Now(),
+		}
+		iso.ComputeFields()
+		database.CreateISO(iso)
+		isos = append(isos, iso)
+	}
+	database.AcquireDownloadLock(isos[1].ID, "other-node", time.Minute)
+
+	manager.Start()
+	if queued := manager.pollQueue(); queued != 1 {
+		t.Fatalf("Expected 1 ISO picked up, got %d", queued)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if got, _ := database.GetISO(isos[0].ID); got.Status == models.StatusComplete {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got, _ := database.GetISO(isos[0].ID); got.Status != models.StatusComplete {
+		t.Errorf("Expected the unlocked ISO to complete, got %s", got.Status)
+	}
+	if got, _ := database.GetISO(isos[1].ID); got.Status != models.StatusQueued {
+		t.Errorf("Expected the locked ISO left queued, got %s", got.Status)
+	}
+	if queued := manager.pollQueue(); queued != 0 {
+		t.Errorf("Expected nothing left to pick up, got %d", queued)
+	}
+}
+
+// TestManagerWorkerPanic tests that a panic fails the ISO without killing the
+// only worker.
+func TestManagerWorkerPanic(t *testing.T) {
+	manager, database, _, cleanup := setupTestManager(t, 1)
+	defer cleanup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("test content"))
+	}))
+	defer server.Close()
+
+	isos := make([]*models.ISO, 0, 2)
+	for _, name := range []string{"bad", "good"} {
+		iso := &models.ISO{
+			ID:          uuid.New().String(),
+			Name:        name,
+			Version:     "1.0",
+			Arch:        "x86_64",
+			FileType:    "iso",
+			DownloadURL: server.URL + "/" + name + ".iso",
+			Status:      models.StatusPending,
+			CreatedAt:   time.Now(),
+		}
+		iso.ComputeFields()
+		database.CreateISO(iso)
+		isos = append(isos, iso)
+	}
+	manager.AddProgressObserver(func(isoID string, progress int, status models.ISOStatus) {
+		if isoID == isos[0].ID && status == models.StatusDownloading {
+			panic("malformed checksum file")
+		}
+	})
+
+	manager.Start()
+	for _, iso := range isos {
+		manager.QueueDownload(iso)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if iso, _ := database.GetISO(isos[1].ID); iso.Status == models.StatusComplete {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	bad, _ := database.GetISO(isos[0].ID)
+	if bad.Status != models.StatusFailed || bad.ErrorReason != models.ErrorReasonPanic || !strings.Contains(bad.ErrorMessage, "malformed checksum file") {
+		t.Errorf("Expected the ISO failed with the panic, got %s/%s: %q", bad.Status, bad.ErrorReason, bad.ErrorMessage)
+	}
+	if good, _ := database.GetISO(isos[1].ID); good.Status != models.StatusComplete {
+		t.Errorf("Expected the worker to survive and complete the next ISO, got %s", good.Status)
+	}
+	if got := manager.WorkerPanics(); got != 1 {
+		t.Errorf("WorkerPanics() = %d, want 1", got)
+	}
+	if manager.IsDownloading(isos[0].ID) {
+		t.Error("Expected the panicked download released")
+	}
+}
+
+// credentialResolverFunc adapts a function to CredentialResolver.
+type credentialResolverFunc func(iso *models.ISO) httputil.Authorizer
+
+func (f credentialResolverFunc) AuthorizerFor(iso *models.ISO) httputil.Authorizer {
+	return f(iso)
+}
+
+// TestManagerCredentialResolver tests that the download and checksum fetch
+// carry the ISO's credentials.
+func TestManagerCredentialResolver(t *testing.T) {
+	manager, database, _, cleanup := setupTestManager(t, 1)
+	defer cleanup()
+
+	testContent := []byte("private content")
+	checksum := fmt.Sprintf("%x  private.iso\n", sha256.Sum256(testContent))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/private.iso.sha256" {
+			w.Write([]byte(checksum))
+			return
+		}
+		w.Write(testContent)
+	}))
+	defer server.Close()
+
+	iso := &models.ISO{
+		ID:           uuid.New().String(),
+		Name:         "private",
+		Version:      "1.0",
+		Arch:         "x86_64",
+		FileType:     "iso",
+		DownloadURL:  server.URL + "/private.iso",
+		ChecksumURL:  server.URL + "/private.iso.sha256",
+		ChecksumType: "sha256",
+		Credential:   "mirror",
+		Status:       models.StatusPending,
+		CreatedAt:    time.Now(),
+	}
+	iso.ComputeFields()
+	database.CreateISO(iso)
+
+	manager.SetCredentialResolver(credentialResolverFunc(func(iso *models.ISO) httputil.Authorizer {
+		if iso.Credential != "mirror" {
+			return nil
+		}
+		return httputil.BearerToken("s3cret")
+	}))
+	manager.Start()
+	manager.QueueDownload(iso)
+
+	var updated *models.ISO
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		updated, _ = database.GetISO(iso.ID)
+		if updated.Status == models.StatusComplete || updated.Status == models.StatusFailed {
+			break
+		}
+	}
+	if updated.Status != models.StatusComplete {
+		t.Errorf("Expected status 'complete', got: %s (%s)", updated.Status, updated.ErrorMessage)
+	}
+}
+
+// TestManagerQueueDownloadSizesInBackground tests that queuing an ISO of
+// unknown size doesn't wait on the upstream, and that it moves to the fast
+// lane once sized.
+func TestManagerQueueDownloadSizesInBackground(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Content-Length", "512")
+	}))
+	defer server.Close()
+	defer close(release)
+
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	cfg := DefaultConfig()
+	cfg.FastLaneWorkers = 1
+	cfg.FastLaneMaxSize = 1024
+	manager := NewManagerWithConfig(env.DB, env.ISODir, cfg)
+	defer manager.Stop()
+
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "netboot",
+		Version:     "1.0",
+		Arch:        "x86_64",
+		FileType:    "img",
+		DownloadURL: server.URL + "/mini.img",
+		Status:      models.StatusPending,
+		CreatedAt:   time.Now(),
+	}
+	iso.ComputeFields()
+	if err := env.DB.CreateISO(iso); err != nil {
+		t.Fatalf("CreateISO() failed: %v", err)
+	}
+
+	// Not started, so the ISO waits in a queue; the upstream hasn't answered yet
+	queued := make(chan error, 1)
+	go func() { queued <- manager.QueueDownload(iso) }()
+	select {
+	case err := <-queued:
+		if err != nil {
+			t.Fatalf("QueueDownload() failed: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected QueueDownload to return without waiting on the upstream")
+	}
+	if len(manager.queue) != 1 || len(manager.fastQueue) != 0 {
+		t.Fatalf("Expected the unsized ISO in the regular queue, got %d regular and %d fast", len(manager.queue), len(manager.fastQueue))
+	}
+
+	release <- struct{}{}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(manager.fastQueue) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the ISO moved to the fast lane once sized")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if manager.take(<-manager.queue) {
+		t.Error("Expected the regular queue's entry skipped after the move")
+	}
+	if moved := <-manager.fastQueue; moved.ID != iso.ID || !manager.take(moved) {
+		t.Errorf("Expected the fast lane's entry to run, got %s", moved.ID)
+	}
 }
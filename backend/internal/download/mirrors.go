@@ -0,0 +1,140 @@
+package download
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// MirrorGroup is a set of base URLs that serve the same tree, e.g. the
+// mirrors of a distribution.
+type MirrorGroup struct {
+	Name  string
+	Bases []string // Each ends with '/'
+}
+
+// ParseMirrorGroups parses "name=base URL" entries; a name repeats for each
+// mirror of its group, and a group needs at least two.
+func ParseMirrorGroups(entries []string) ([]MirrorGroup, error) {
+	var groups []MirrorGroup
+	index := make(map[string]int)
+	for _, entry := range entries {
+		name, base, found := strings.Cut(entry, "=")
+		name, base = strings.TrimSpace(name), strings.TrimSpace(base)
+		if !found || name == "" {
+			return nil, fmt.Errorf("mirror %q must be name=base URL", entry)
+		}
+		u, err := url.Parse(base)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("mirror group %q has an invalid base URL %q", name, base)
+		}
+		if !strings.HasSuffix(base, "/") {
+			base += "/"
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(groups)
+			index[name] = i
+			groups = append(groups, MirrorGroup{Name: name})
+		}
+		groups[i].Bases = append(groups[i].Bases, base)
+	}
+	for _, group := range groups {
+		if len(group.Bases) < 2 {
+			return nil, fmt.Errorf("mirror group %q needs at least two mirrors", group.Name)
+		}
+	}
+	return groups, nil
+}
+
+// mirrorRotation sends downloads whose URL lies under a mirror of a group to
+// one of the group's mirrors, as its policy picks.
+type mirrorRotation struct {
+	policy string
+	groups []MirrorGroup
+	next   []atomic.Uint64 // Round-robin position, by group
+	health func(host string) (*models.MirrorHealth, error)
+}
+
+// SetMirrors sets the mirror groups downloads rotate among and the policy
+// that picks one. Call it before Start.
+func (m *Manager) SetMirrors(groups []MirrorGroup, policy string) {
+	policy = strings.ToLower(policy)
+	if len(groups) == 0 || policy == "" || policy == constants.MirrorRotationNone {
+		m.mirrors = nil
+		return
+	}
+	m.mirrors = &mirrorRotation{
+		policy: policy,
+		groups: groups,
+		next:   make([]atomic.Uint64, len(groups)),
+		health: m.db.GetMirrorHealth,
+	}
+}
+
+// pick returns the URL to download rawURL from. URLs outside every group,
+// and every URL without a rotation, are returned unchanged.
+func (r *mirrorRotation) pick(rawURL string) string {
+	if r == nil {
+		return rawURL
+	}
+
+	// The longest base wins, so nested trees can form their own groups
+	group, own := -1, ""
+	for i, g := range r.groups {
+		for _, base := range g.Bases {
+			if strings.HasPrefix(rawURL, base) && len(base) > len(own) {
+				group, own = i, base
+			}
+		}
+	}
+	if group < 0 {
+		return rawURL
+	}
+	path := rawURL[len(own):]
+	bases := r.groups[group].Bases
+
+	switch r.policy {
+	case constants.MirrorRotationRoundRobin:
+		n := r.next[group].Add(1) - 1
+		return bases[n%uint64(len(bases))] + path
+	case constants.MirrorRotationHealthiest:
+		best, bestHealth := own, r.hostHealth(own)
+		for _, base := range bases {
+			if h := r.hostHealth(base); healthier(h, bestHealth) {
+				best, bestHealth = base, h
+			}
+		}
+		return best + path
+	}
+	return rawURL
+}
+
+// hostHealth returns the recorded health of base's host. A host without
+// downloads yet counts as fully healthy, so new mirrors get tried.
+func (r *mirrorRotation) hostHealth(base string) models.MirrorHealth {
+	u, err := url.Parse(base)
+	if err != nil {
+		return models.MirrorHealth{}
+	}
+	h, err := r.health(strings.ToLower(u.Hostname()))
+	if err != nil {
+		slog.Debug("no mirror health recorded", slog.String("host", u.Hostname()), slog.Any("error", err))
+		return models.MirrorHealth{SuccessRate: 1}
+	}
+	return *h
+}
+
+// healthier reports whether a has a better success rate than b, or the same
+// rate and a lower average latency.
+func healthier(a, b models.MirrorHealth) bool {
+	if a.SuccessRate != b.SuccessRate {
+		return a.SuccessRate > b.SuccessRate
+	}
+	return a.AvgLatencyMs < b.AvgLatencyMs
+}
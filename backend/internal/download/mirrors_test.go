@@ -0,0 +1,90 @@
+package download
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+func TestParseMirrorGroups(t *testing.T) {
+	groups, err := ParseMirrorGroups([]string{
+		"fedora=https://dl.fedoraproject.org/pub/fedora",
+		"fedora=https://mirror.example.edu/fedora/",
+	})
+	if err != nil {
+		t.Fatalf("ParseMirrorGroups() failed: %v", err)
+	}
+	if len(groups) != 1 || len(groups[0].Bases) != 2 || groups[0].Bases[0] != "https://dl.fedoraproject.org/pub/fedora/" {
+		t.Errorf("Unexpected groups: %+v", groups)
+	}
+
+	for _, entries := range [][]string{
+		{"https://mirror.example.edu/fedora/"},
+		{"fedora=ftp://mirror.example.edu/fedora/", "fedora=https://dl.fedoraproject.org/"},
+		{"fedora=https://mirror.example.edu/fedora/"},
+	} {
+		if _, err := ParseMirrorGroups(entries); err == nil {
+			t.Errorf("Expected ParseMirrorGroups(%q) to fail", entries)
+		}
+	}
+}
+
+func TestMirrorRotationPick(t *testing.T) {
+	groups, err := ParseMirrorGroups([]string{
+		"alpine=https://dl-cdn.alpinelinux.org/alpine/",
+		"alpine=https://mirror.example.edu/alpine/",
+		"alpine=https://mirror.example.org/pub/alpine/",
+	})
+	if err != nil {
+		t.Fatalf("ParseMirrorGroups() failed: %v", err)
+	}
+	const path = "v3.19/releases/x86_64/alpine-standard-3.19.1-x86_64.iso"
+	own := "https://dl-cdn.alpinelinux.org/alpine/" + path
+
+	manager, _, _, cleanup := setupTestManager(t, 1)
+	defer cleanup()
+
+	t.Run("round-robin", func(t *testing.T) {
+		manager.SetMirrors(groups, constants.MirrorRotationRoundRobin)
+		var got []string
+		for i := 0; i < 4; i++ {
+			got = append(got, manager.mirrors.pick(own))
+		}
+		want := []string{own, "https://mirror.example.edu/alpine/" + path, "https://mirror.example.org/pub/alpine/" + path, own}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("Pick %d = %s, want %s", i, got[i], want[i])
+			}
+		}
+		if other := "https://example.com/other.iso"; manager.mirrors.pick(other) != other {
+			t.Error("Expected a URL outside every group to be left alone")
+		}
+	})
+
+	t.Run("healthiest", func(t *testing.T) {
+		manager.SetMirrors(groups, constants.MirrorRotationHealthiest)
+		health := map[string]models.MirrorHealth{
+			"dl-cdn.alpinelinux.org": {SuccessRate: 0.5},
+			"mirror.example.edu":     {SuccessRate: 1, AvgLatencyMs: 300},
+			"mirror.example.org":     {SuccessRate: 1, AvgLatencyMs: 80},
+		}
+		manager.mirrors.health = func(host string) (*models.MirrorHealth, error) {
+			if h, ok := health[host]; ok {
+				return &h, nil
+			}
+			return nil, errors.New("not found")
+		}
+		if got := manager.mirrors.pick(own); got != "https://mirror.example.org/pub/alpine/"+path {
+			t.Errorf("Expected the fastest healthy mirror, got %s", got)
+		}
+	})
+
+	t.Run("none", func(t *testing.T) {
+		manager.SetMirrors(groups, constants.MirrorRotationNone)
+		if got := manager.mirrors.pick(own); got != own {
+			t.Errorf("Expected no rotation, got %s", got)
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package download
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// QueueEventHook is called for each step of a download's life in the queue.
+// Hooks run synchronously on the worker that emitted the event, so they
+// must not block.
+type QueueEventHook func(event models.QueueEvent)
+
+// observers is a registry of callbacks that may be added and removed while
+// downloads run. Callbacks are called in registration order.
+type observers[F any] struct {
+	mu      sync.RWMutex
+	entries []*observer[F] // Replaced, never modified, so callers can iterate without the lock
+}
+
+// observer wraps a callback so it can be told apart for removal.
+type observer[F any] struct {
+	callback F
+}
+
+// add registers callback and returns a function that unregisters it.
+func (o *observers[F]) add(callback F) func() {
+	entry := &observer[F]{callback: callback}
+
+	o.mu.Lock()
+	entries := make([]*observer[F], len(o.entries), len(o.entries)+1)
+	copy(entries, o.entries)
+	o.entries = append(entries, entry)
+	o.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { o.remove(entry) })
+	}
+}
+
+// remove unregisters entry.
+func (o *observers[F]) remove(entry *observer[F]) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	entries := make([]*observer[F], 0, len(o.entries))
+	for _, registered := range o.entries {
+		if registered != entry {
+			entries = append(entries, registered)
+		}
+	}
+	o.entries = entries
+}
+
+// each calls fn with every callback registered when it was called.
+func (o *observers[F]) each(fn func(callback F)) {
+	o.mu.RLock()
+	entries := o.entries
+	o.mu.RUnlock()
+	for _, entry := range entries {
+		fn(entry.callback)
+	}
+}
+
+// AddProgressObserver registers callback for progress and status updates of
+// every download, alongside any other observers, and returns a function that
+// unregisters it. It is safe to call while downloads run.
+func (m *Manager) AddProgressObserver(callback ProgressCallback) (remove func()) {
+	return m.progressObservers.add(callback)
+}
+
+// AddQueueEventHook registers hook for the queued, started, finished, and
+// failed events of every download and returns a function that unregisters it.
+// It is safe to call while downloads run.
+func (m *Manager) AddQueueEventHook(hook QueueEventHook) (remove func()) {
+	return m.queueEventHooks.add(hook)
+}
+
+// notifyProgress sends a progress update to every progress observer.
+func (m *Manager) notifyProgress(isoID string, progress int, status models.ISOStatus) {
+	m.progressObservers.each(func(callback ProgressCallback) {
+		callback(isoID, progress, status)
+	})
+}
+
+// emit stamps event and sends it to every queue event hook. A hook that
+// panics is logged and skipped, so it can't take a worker down with it.
+func (m *Manager) emit(event models.QueueEvent) {
+	event.At = m.clock.Now()
+	event.Node = m.node
+	m.queueEventHooks.each(func(hook QueueEventHook) {
+		defer func() {
+			if r := recover(); r != nil {
+				slog.Error("queue event hook panicked",
+					slog.String("type", event.Type),
+					slog.String("iso_id", event.ISOID),
+					slog.Any("panic", r),
+				)
+			}
+		}()
+		hook(event)
+	})
+}
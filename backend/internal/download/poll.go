@@ -0,0 +1,78 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// StartQueuePoller hands queued ISOs from the database to idle workers once
+// per interval until ctx is canceled. Instances sharing the database drain
+// one queue this way, whichever of them queued an ISO, and ISOs left queued
+// by an instance that stopped are picked up. A zero interval disables polling.
+func (m *Manager) StartQueuePoller(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := m.clock.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				m.pollQueue()
+			}
+		}
+	}()
+}
+
+// pollQueue queues up to one unlocked, queued ISO per idle download worker,
+// oldest first, and returns how many it queued. Workers claim them like any
+// other download, so an ISO two instances poll at once is fetched only once.
+// ISOs whose size is already recorded may go in the fast lane; others are
+// sized in the background, as by QueueDownload.
+func (m *Manager) pollQueue() int {
+	m.enqueueMu.Lock()
+	defer m.enqueueMu.Unlock()
+
+	idle := m.workerCount + m.fastCount - m.QueueDepth()
+	m.mu.RLock()
+	for _, a := range m.activeDownloads {
+		if !a.verifying.Load() {
+			idle--
+		}
+	}
+	m.mu.RUnlock()
+	if idle <= 0 {
+		return 0
+	}
+
+	isos, err := m.db.ListUnlockedQueuedISOs(idle)
+	if err != nil {
+		slog.Warn("failed to poll the download queue", slog.Any("error", err))
+		return 0
+	}
+
+	queued := 0
+	for i := range isos {
+		iso := &isos[i]
+		queue := m.laneFor(iso, iso.SizeBytes)
+		if err := m.reserve(iso, queue); errors.Is(err, ErrQueueFull) {
+			break
+		} else if err != nil {
+			continue // Already queued or running here
+		}
+		queue <- iso
+		m.relaneLater(iso, queue)
+		queued++
+	}
+	if queued > 0 {
+		slog.Info("picked up queued downloads", slog.Int("count", queued))
+	}
+	return queued
+}
@@ -0,0 +1,100 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/fileutil"
+	"github.com/aloks98/isoman/backend/internal/httputil"
+
+	"golang.org/x/crypto/openpgp" //nolint:staticcheck // Frozen upstream, but detached signature checks need nothing newer
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+// ErrNoKeyring is returned when an ISO has a signature URL but GPG_KEYRING is unset.
+var ErrNoKeyring = errors.New("no signing keys configured (GPG_KEYRING)")
+
+// FetchSignature downloads a detached signature file.
+func FetchSignature(ctx context.Context, signatureURL string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	data, err := httputil.FetchBytes(ctx, signatureURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch signature file: %w", err)
+	}
+	return data, nil
+}
+
+// VerifyDetachedSignature checks that signature, binary or ASCII-armored,
+// signs the file at filePath with one of the public keys in keyringPath, and
+// returns one of the signing key's identities, or its fingerprint if it has none.
+func VerifyDetachedSignature(filePath string, signature []byte, keyringPath string) (string, error) {
+	if keyringPath == "" {
+		return "", ErrNoKeyring
+	}
+	keyring, err := readKeyring(keyringPath)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	var signer *openpgp.Entity
+	if IsArmoredSignature(signature) {
+		signer, err = openpgp.CheckArmoredDetachedSignature(keyring, file, bytes.NewReader(signature))
+	} else {
+		signer, err = openpgp.CheckDetachedSignature(keyring, file, bytes.NewReader(signature))
+	}
+	if err != nil {
+		return "", fmt.Errorf("signature verification failed: %w", err)
+	}
+
+	for name := range signer.Identities {
+		return name, nil
+	}
+	return fmt.Sprintf("%X", signer.PrimaryKey.Fingerprint), nil
+}
+
+// IsArmoredSignature reports whether signature is ASCII-armored rather than binary.
+func IsArmoredSignature(signature []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(signature), []byte("-----BEGIN "))
+}
+
+// readKeyring reads the public keys in path, either ASCII-armored or binary.
+func readKeyring(path string) (openpgp.EntityList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %w", err)
+	}
+
+	var keyring openpgp.EntityList
+	if block, _ := armor.Decode(bytes.NewReader(data)); block != nil {
+		keyring, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	} else {
+		keyring, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse keyring %s: %w", path, err)
+	}
+	return keyring, nil
+}
+
+// saveSignature writes signature next to isoPath as .asc when armored and .sig
+// otherwise, removing a stale copy in the other format.
+func saveSignature(isoPath string, signature []byte) error {
+	ext, stale := ".sig", ".asc"
+	if IsArmoredSignature(signature) {
+		ext, stale = stale, ext
+	}
+	fileutil.DeleteFileSilently(isoPath + stale)
+	return os.WriteFile(isoPath+ext, signature, 0o644)
+}
@@ -0,0 +1,167 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/openpgp" //nolint:staticcheck // Matches signature.go
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+// newSigningKey generates a key and writes its public half to an armored
+// keyring in dir.
+func newSigningKey(t *testing.T, dir string) (*openpgp.Entity, string) {
+	t.Helper()
+	entity, err := openpgp.NewEntity("Release Signing", "", "release@example.com", nil)
+	if err != nil {
+		t.Fatalf("NewEntity() failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatalf("armor.Encode() failed: %v", err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatalf("Serialize() failed: %v", err)
+	}
+	w.Close()
+
+	keyring := filepath.Join(dir, "keyring.asc")
+	if err := os.WriteFile(keyring, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+	return entity, keyring
+}
+
+func TestVerifyDetachedSignature(t *testing.T) {
+	dir := t.TempDir()
+	signer, keyring := newSigningKey(t, dir)
+	other, _ := newSigningKey(t, t.TempDir())
+
+	content := []byte("test iso content")
+	file := filepath.Join(dir, "test.iso")
+	os.WriteFile(file, content, 0o644)
+
+	sign := func(entity *openpgp.Entity, armored bool) []byte {
+		var buf bytes.Buffer
+		var err error
+		if armored {
+			err = openpgp.ArmoredDetachSign(&buf, entity, bytes.NewReader(content), nil)
+		} else {
+			err = openpgp.DetachSign(&buf, entity, bytes.NewReader(content), nil)
+		}
+		if err != nil {
+			t.Fatalf("signing failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name      string
+		signature []byte
+		keyring   string
+		wantErr   bool
+	}{
+		{"binary", sign(signer, false), keyring, false},
+		{"armored", sign(signer, true), keyring, false},
+		{"unknown key", sign(other, true), keyring, true},
+		{"no keyring", sign(signer, true), "", true},
+		{"garbage", []byte("not a signature"), keyring, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifyDetachedSignature(file, tt.signature, tt.keyring)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyDetachedSignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != "Release Signing <release@example.com>" {
+				t.Errorf("Unexpected signer %q", got)
+			}
+		})
+	}
+
+	if _, err := VerifyDetachedSignature(file, sign(signer, true), ""); !errors.Is(err, ErrNoKeyring) {
+		t.Errorf("Expected ErrNoKeyring, got %v", err)
+	}
+
+	// A file changed after signing no longer verifies
+	os.WriteFile(file, []byte("tampered"), 0o644)
+	if _, err := VerifyDetachedSignature(file, sign(signer, false), keyring); err == nil {
+		t.Error("Expected a tampered file to fail verification")
+	}
+}
+
+// TestWorkerSignature tests that an ISO with a signature URL completes only
+// when the signature verifies, and that the signature is kept beside it.
+func TestWorkerSignature(t *testing.T) {
+	worker, database, isoDir, cleanup := setupTestWorker(t)
+	defer cleanup()
+	signer, keyring := newSigningKey(t, t.TempDir())
+	worker.signatureKeyring = keyring
+
+	testContent := []byte("test iso content")
+	var signature bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&signature, signer, bytes.NewReader(testContent), nil); err != nil {
+		t.Fatalf("ArmoredDetachSign() failed: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/test.iso.asc":
+			w.Write(signature.Bytes())
+		case "/bad.asc":
+			w.Write([]byte("-----BEGIN PGP SIGNATURE-----\n\n-----END PGP SIGNATURE-----\n"))
+		default:
+			w.Write(testContent)
+		}
+	}))
+	defer server.Close()
+
+	for _, tc := range []struct {
+		version      string
+		signatureURL string
+		want         models.ISOStatus
+	}{
+		{"1.0", server.URL + "/test.iso.asc", models.StatusComplete},
+		{"2.0", server.URL + "/bad.asc", models.StatusFailed},
+	} {
+		iso := &models.ISO{
+			ID:           uuid.New().String(),
+			Name:         "test",
+			Version:      tc.version,
+			Arch:         "x86_64",
+			FileType:     "iso",
+			DownloadURL:  server.URL + "/test.iso",
+			SignatureURL: tc.signatureURL,
+			Status:       models.StatusPending,
+			CreatedAt:    time.Now(),
+		}
+		iso.ComputeFields()
+		database.CreateISO(iso)
+
+		worker.Process(context.Background(), iso)
+		updated, _ := database.GetISO(iso.ID)
+		if updated.Status != tc.want {
+			t.Errorf("Expected %s for %s, got %s: %s", tc.want, tc.signatureURL, updated.Status, updated.ErrorMessage)
+		}
+
+		if tc.want == models.StatusComplete && (updated.SignatureSigner != "Release Signing <release@example.com>" || updated.VerifiedAt == nil) {
+			t.Errorf("Expected the signer and verification time recorded, got %q at %v", updated.SignatureSigner, updated.VerifiedAt)
+		}
+
+		sigFile := filepath.Join(isoDir, iso.FilePath) + ".asc"
+		if _, err := os.Stat(sigFile); (err == nil) != (tc.want == models.StatusComplete) {
+			t.Errorf("Expected the signature kept only for a verified ISO, stat %s: %v", sigFile, err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package download
+
+import (
+	"sort"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// State dumps the manager's queues, workers, and in-flight downloads, with
+// the download locks of every node sharing the database. The local parts are
+// read under one lock, so they agree with each other.
+func (m *Manager) State() (*models.ManagerState, error) {
+	locks, err := m.db.ListDownloadLocks()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	state := &models.ManagerState{
+		CapturedAt: now,
+		Node:       m.node,
+		Queues: models.ManagerQueues{
+			Queued:         len(m.queue),
+			Capacity:       cap(m.queue),
+			FastQueued:     len(m.fastQueue),
+			FastCapacity:   cap(m.fastQueue),
+			VerifyQueued:   len(m.verifyQueue),
+			VerifyCapacity: cap(m.verifyQueue),
+		},
+		Workers:       make([]models.WorkerState, m.workerCount+m.fastCount),
+		VerifyWorkers: m.verifyCount,
+		Locks:         locks,
+		WorkerPanics:  m.panics.Load(),
+	}
+	select {
+	case <-m.shutdown:
+		state.Stopped = true
+	default:
+	}
+
+	for i := range state.Workers {
+		state.Workers[i] = models.WorkerState{ID: i, Lane: models.WorkerLaneRegular, State: models.WorkerStateIdle}
+		if i >= m.workerCount {
+			state.Workers[i].Lane = models.WorkerLaneFast
+		}
+	}
+
+	m.mu.RLock()
+	state.Active = make([]models.ActiveDownload, 0, len(m.activeDownloads))
+	for _, a := range m.activeDownloads {
+		d := a.snapshot(now)
+		state.Active = append(state.Active, d)
+		if d.Status == models.StatusDownloading && d.WorkerID >= 0 && d.WorkerID < len(state.Workers) {
+			state.Workers[d.WorkerID].State = models.WorkerStateDownloading
+			state.Workers[d.WorkerID].ISOID = d.ISOID
+		}
+	}
+	state.InFlight = make([]models.InFlightDownload, 0, len(m.inFlight))
+	for id, tempFile := range m.inFlight {
+		status := models.StatusQueued
+		if a, ok := m.activeDownloads[id]; ok {
+			status = models.StatusDownloading
+			if a.verifying.Load() {
+				status = models.StatusVerifying
+			}
+		}
+		state.InFlight = append(state.InFlight, models.InFlightDownload{ISOID: id, TempFile: tempFile, Status: status})
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(state.Active, func(i, j int) bool {
+		return state.Active[i].StartedAt.Before(state.Active[j].StartedAt)
+	})
+	sort.Slice(state.InFlight, func(i, j int) bool {
+		return state.InFlight[i].ISOID < state.InFlight[j].ISOID
+	})
+	return state, nil
+}
@@ -0,0 +1,104 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// StartWatchdog recovers stale downloads once at startup and then once a
+// minute until ctx is canceled. A zero timeout disables the watchdog.
+func (m *Manager) StartWatchdog(ctx context.Context, timeout time.Duration, requeue bool) {
+	if timeout <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := m.clock.NewTicker(constants.StaleDownloadCheckIntervalSec * time.Second)
+		defer ticker.Stop()
+
+		for {
+			m.recoverStaleDownloads(timeout, requeue)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+			}
+		}
+	}()
+}
+
+// recoverStaleDownloads finds ISOs left downloading or verifying with no
+// worker behind them, e.g. by an instance that crashed or was killed, which
+// would otherwise show as running forever. An ISO counts as stale when it
+// isn't running here, no node holds its download lock, and its progress
+// hasn't moved for timeout. Stale ISOs are failed so they can be retried, or
+// queued again when requeue is set. It returns how many it recovered.
+func (m *Manager) recoverStaleDownloads(timeout time.Duration, requeue bool) int {
+	isos, err := m.db.ListStaleDownloads(m.clock.Now().Add(-timeout))
+	if err != nil {
+		slog.Warn("failed to list stale downloads", slog.Any("error", err))
+		return 0
+	}
+
+	recovered := 0
+	for i := range isos {
+		iso := &isos[i]
+		m.mu.RLock()
+		_, running := m.inFlight[iso.ID]
+		m.mu.RUnlock()
+		if running {
+			continue // Running here without a lock, e.g. after failing to write it
+		}
+
+		msg := fmt.Sprintf("download interrupted: no progress for %s", timeout)
+		m.logDownload(iso.ID, models.LogLevelWarn, "Interrupted: no progress for %s and no instance running it", timeout)
+		if requeue {
+			if err := m.QueueDownload(iso); err == nil {
+				slog.Info("queued stale download again", slog.String("iso_id", iso.ID), slog.String("name", iso.Name))
+				recovered++
+				continue
+			}
+			// The queue is full; failing it leaves a retry to the user
+		}
+
+		if m.keepPreviousFile(iso, errors.New(msg)) {
+			event := newQueueEvent(models.QueueEventFailed, iso, models.StatusComplete)
+			event.Error = msg
+			m.emit(event)
+			recovered++
+			continue
+		}
+
+		if err := m.db.UpdateISOFailure(iso.ID, iso.Progress, models.ErrorReasonInterrupted, msg); err != nil {
+			slog.Warn("failed to mark stale download failed", slog.String("iso_id", iso.ID), slog.Any("error", err))
+			continue
+		}
+		m.notifyProgress(iso.ID, iso.Progress, models.StatusFailed)
+		event := newQueueEvent(models.QueueEventFailed, iso, models.StatusFailed)
+		event.Error = msg
+		m.emit(event)
+		slog.Warn("failed stale download", slog.String("iso_id", iso.ID), slog.String("name", iso.Name))
+		recovered++
+	}
+	return recovered
+}
+
+// logDownload adds an entry to an ISO's download log.
+func (m *Manager) logDownload(isoID, level, format string, args ...any) {
+	entry := &models.DownloadLogEntry{
+		ISOID:    isoID,
+		LoggedAt: m.clock.Now(),
+		Level:    level,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if err := m.db.AppendDownloadLog(entry); err != nil {
+		slog.Warn("failed to append download log", slog.String("iso_id", isoID), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,122 @@
+package download
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/clock"
+	"github.com/aloks98/isoman/backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// TestManagerRecoverStaleDownloads tests that only running ISOs nobody is
+// working on are recovered.
+func TestManagerRecoverStaleDownloads(t *testing.T) {
+	manager, database, _, cleanup := setupTestManager(t, 1)
+	defer cleanup()
+
+	createRunning := func(name string, status models.ISOStatus) *models.ISO {
+		t.Helper()
+		iso := &models.ISO{
+			ID:          uuid.New().String(),
+			Name:        name,
+			Version:     "1.0",
+			Arch:        "x86_64",
+			FileType:    "iso",
+			DownloadURL: "http://example.com/" + name + ".iso",
+			Status:      status,
+			Progress:    40,
+			CreatedAt:   time.Now().UTC().Add(-2 * time.Hour),
+		}
+		iso.ComputeFields()
+		if err := database.CreateISO(iso); err != nil {
+			t.Fatalf("CreateISO failed: %v", err)
+		}
+		return iso
+	}
+
+	crashed := createRunning("crashed", models.StatusDownloading)
+	verifying := createRunning("verifying", models.StatusVerifying)
+	progressing := createRunning("progressing", models.StatusDownloading)
+	if err := database.UpdateISOProgress(progressing.ID, 50); err != nil {
+		t.Fatalf("UpdateISOProgress failed: %v", err)
+	}
+	locked := createRunning("locked", models.StatusDownloading)
+	if err := database.AcquireDownloadLock(locked.ID, "node-b", time.Minute); err != nil {
+		t.Fatalf("AcquireDownloadLock failed: %v", err)
+	}
+	createRunning("complete", models.StatusComplete)
+
+	if got := manager.recoverStaleDownloads(time.Hour, false); got != 2 {
+		t.Fatalf("Expected 2 stale downloads recovered, got %d", got)
+	}
+	for _, iso := range []*models.ISO{crashed, verifying} {
+		got, _ := database.GetISO(iso.ID)
+		if got.Status != models.StatusFailed || got.ErrorReason != models.ErrorReasonInterrupted || got.Progress != 40 {
+			t.Errorf("Expected %s failed as interrupted, got status %s, reason %q, progress %d",
+				iso.Name, got.Status, got.ErrorReason, got.Progress)
+		}
+	}
+	for _, iso := range []*models.ISO{progressing, locked} {
+		if got, _ := database.GetISO(iso.ID); got.Status != models.StatusDownloading {
+			t.Errorf("Expected %s left downloading, got %s", iso.Name, got.Status)
+		}
+	}
+
+	// With requeue set, a stale download is queued again instead
+	requeued := createRunning("requeued", models.StatusDownloading)
+	if got := manager.recoverStaleDownloads(time.Hour, true); got != 1 {
+		t.Fatalf("Expected 1 stale download recovered, got %d", got)
+	}
+	if got, _ := database.GetISO(requeued.ID); got.Status != models.StatusQueued || got.ErrorReason != models.ErrorReasonNone {
+		t.Errorf("Expected the stale download queued, got status %s, reason %q", got.Status, got.ErrorReason)
+	}
+	if manager.QueueDepth() != 1 {
+		t.Errorf("Expected 1 queued download, got %d", manager.QueueDepth())
+	}
+}
+
+// TestManagerRecoverStaleDownloadsClock tests that the watchdog measures
+// staleness and stamps its log with the manager's clock.
+func TestManagerRecoverStaleDownloadsClock(t *testing.T) {
+	manager, database, _, cleanup := setupTestManager(t, 1)
+	defer cleanup()
+	fake := clock.NewFake(time.Now().UTC().Truncate(time.Second))
+	manager.SetClock(fake)
+
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "crashed",
+		Version:     "1.0",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: "http://example.com/crashed.iso",
+		Status:      models.StatusDownloading,
+		CreatedAt:   fake.Now(),
+	}
+	iso.ComputeFields()
+	if err := database.CreateISO(iso); err != nil {
+		t.Fatalf("CreateISO failed: %v", err)
+	}
+	if err := database.UpdateISOProgress(iso.ID, 40); err != nil {
+		t.Fatalf("UpdateISOProgress failed: %v", err)
+	}
+
+	fake.Advance(30 * time.Minute)
+	if got := manager.recoverStaleDownloads(time.Hour, false); got != 0 {
+		t.Fatalf("Expected nothing stale half an hour in, got %d recovered", got)
+	}
+	fake.Advance(time.Hour)
+	if got := manager.recoverStaleDownloads(time.Hour, false); got != 1 {
+		t.Fatalf("Expected the download stale once the clock moved an hour, got %d recovered", got)
+	}
+
+	entries, err := database.ListDownloadLog(iso.ID)
+	if err != nil {
+		t.Fatalf("ListDownloadLog failed: %v", err)
+	}
+	if len(entries) != 1 || !entries[0].LoggedAt.Equal(fake.Now()) {
+		t.Errorf("Expected one log entry stamped %v, got %+v", fake.Now(), entries)
+	}
+}
@@ -2,109 +2,393 @@ package download
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/aloks98/isoman/backend/internal/clamav"
+	"github.com/aloks98/isoman/backend/internal/clock"
+	"github.com/aloks98/isoman/backend/internal/config"
+	"github.com/aloks98/isoman/backend/internal/constants"
 	"github.com/aloks98/isoman/backend/internal/db"
 	"github.com/aloks98/isoman/backend/internal/fileutil"
 	"github.com/aloks98/isoman/backend/internal/httputil"
 	"github.com/aloks98/isoman/backend/internal/models"
 	"github.com/aloks98/isoman/backend/internal/pathutil"
+	"github.com/aloks98/isoman/backend/internal/throughput"
 )
 
+// ErrStalled is returned when a transfer receives no data for longer than the stall timeout.
+var ErrStalled = errors.New("download stalled")
+
+// ErrTimedOut is returned when a transfer runs past the maximum download duration.
+var ErrTimedOut = errors.New("download timed out")
+
+// ErrQuarantined is returned when the antivirus scan flags a downloaded file.
+var ErrQuarantined = errors.New("download quarantined")
+
+// ErrPanicked is returned when handling a download panicked.
+var ErrPanicked = errors.New("worker panicked")
+
 // ProgressCallback is called when download progress updates.
 type ProgressCallback func(isoID string, progress int, status models.ISOStatus)
 
 // Worker handles the download and verification of a single ISO.
 type Worker struct {
-	db               *db.DB
-	progressCallback ProgressCallback
-	isoDir           string
-	tmpDir           string
+	db                *db.DB
+	progressCallback  ProgressCallback
+	isoDir            string
+	tmpDir            string
+	bufferSize        int
+	progressInterval  time.Duration
+	progressThreshold int
+	persistInterval   time.Duration
+	maxDuration       time.Duration
+	stallTimeout      time.Duration
+	maxRetries        int
+	retryDelay        time.Duration
+	keepVersions      int
+	integrityHash     string
+	signatureKeyring  string            // Public keys for signature_url checks; empty fails them
+	scanner           *clamav.Scanner   // nil when scanning is disabled
+	ingest            *throughput.Meter // nil leaves downloaded bytes unmetered
+	panics            *atomic.Int64     // Counts recovered panics; nil leaves them uncounted
+	mirrors           *mirrorRotation   // nil downloads every ISO from its own URL
+	clock             clock.Clock       // Stamps the ISO and its log, times transfers, and paces retries
 }
 
 // NewWorker creates a new download worker.
-func NewWorker(database *db.DB, isoDir string, callback ProgressCallback) *Worker {
-	tmpDir := filepath.Join(isoDir, ".tmp")
+// A nil cfg falls back to DefaultConfig.
+func NewWorker(database *db.DB, isoDir string, cfg *config.DownloadConfig, callback ProgressCallback) *Worker {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+
+	bufferSize := cfg.BufferSize
+	if bufferSize < 1 {
+		bufferSize = constants.DefaultDownloadBufferSize
+	}
+	progressThreshold := cfg.ProgressPercentThreshold
+	if progressThreshold < 1 {
+		progressThreshold = constants.DefaultProgressPercentThreshold
+	}
+	progressInterval := cfg.ProgressUpdateInterval
+	if progressInterval <= 0 {
+		progressInterval = time.Second
+	}
+	// A zero persist interval writes every progress tick through to the database
+	persistInterval := cfg.ProgressPersistInterval
+	if persistInterval < 0 {
+		persistInterval = 0
+	}
+	// A zero max duration lets transfers run until they finish or are canceled
+	maxDuration := cfg.MaxDownloadDuration
+	if maxDuration < 0 {
+		maxDuration = 0
+	}
+	// A zero stall timeout disables stall detection
+	stallTimeout := cfg.StallTimeout
+	if stallTimeout < 0 {
+		stallTimeout = 0
+	}
+	maxRetries := cfg.MaxRetries
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	retryDelay := cfg.RetryDelay
+	if retryDelay < 0 {
+		retryDelay = 0
+	}
+	// Zero keeps no previous versions; refreshed files are simply replaced
+	keepVersions := cfg.KeepVersions
+	if keepVersions < 0 {
+		keepVersions = 0
+	}
+
+	var scanner *clamav.Scanner
+	if cfg.ClamAVAddress != "" {
+		scanner = clamav.New(cfg.ClamAVAddress, cfg.ClamAVTimeout)
+	}
+
+	tmpDir := pathutil.ResolveTempDir(isoDir, cfg.TempDir)
 	return &Worker{
-		db:               database,
-		isoDir:           isoDir,
-		tmpDir:           tmpDir,
-		progressCallback: callback,
+		db:                database,
+		isoDir:            isoDir,
+		tmpDir:            tmpDir,
+		progressCallback:  callback,
+		bufferSize:        bufferSize,
+		progressInterval:  progressInterval,
+		progressThreshold: progressThreshold,
+		persistInterval:   persistInterval,
+		maxDuration:       maxDuration,
+		stallTimeout:      stallTimeout,
+		maxRetries:        maxRetries,
+		retryDelay:        retryDelay,
+		keepVersions:      keepVersions,
+		integrityHash:     integrityHashOrDefault(cfg.IntegrityHash),
+		signatureKeyring:  cfg.SignatureKeyring,
+		scanner:           scanner,
+		clock:             clock.Real(),
 	}
 }
 
-// Process downloads and verifies an ISO.
+// verifyJob is a downloaded file waiting to be verified and moved into place.
+type verifyJob struct {
+	iso        *models.ISO
+	validators *httputil.Validators
+	digests    Digests
+	tmpFile    string
+	finalFile  string
+}
+
+// Process downloads and verifies an ISO. A panic in either step fails the
+// ISO and is returned as ErrPanicked.
 func (w *Worker) Process(ctx context.Context, iso *models.ISO) error {
+	job, err := w.fetch(ctx, iso)
+	if err != nil {
+		return err
+	}
+	return w.finalize(ctx, job)
+}
+
+// fetch downloads an ISO to its temp file, hashing it on the way. The returned
+// job is finished by finalize, possibly on a different worker.
+func (w *Worker) fetch(ctx context.Context, iso *models.ISO) (_ *verifyJob, err error) {
+	defer w.recoverPanic(ctx, iso, 0, &err)
+
+	// Each run starts a fresh download log
+	if err := w.db.ClearDownloadLog(iso.ID); err != nil {
+		slog.WarnContext(ctx, "failed to clear download log", slog.String("iso_id", iso.ID), slog.Any("error", err))
+	}
+
 	// Ensure tmp directory exists
 	if err := fileutil.EnsureDirectory(w.tmpDir); err != nil {
-		return fmt.Errorf("failed to create temp directory: %w", err)
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
 	}
 
 	// Use the computed FilePath for nested directory structure
 	// FilePath example: "alpine/3.19.1/x86_64/alpine-3.19.1-x86_64.iso"
-	tmpFile := pathutil.ConstructTempPath(w.isoDir, iso.Filename)
+	tmpFile := pathutil.ConstructTempPath(w.tmpDir, iso.Filename)
 	finalFile := pathutil.ConstructISOPath(w.isoDir, iso.FilePath)
 
 	// Create the nested directory structure for the final file
 	if err := fileutil.EnsureParentDirectory(finalFile); err != nil {
-		return fmt.Errorf("failed to create final directory: %w", err)
+		return nil, fmt.Errorf("failed to create final directory: %w", err)
 	}
 
-	// Clean up temp file on error
+	// Clean up temp file unless it is handed off for verification
+	handedOff := false
 	defer func() {
-		fileutil.DeleteFileSilently(tmpFile)
+		if !handedOff {
+			fileutil.DeleteFileSilently(tmpFile)
+		}
 	}()
 
+	// Pin upstream fetches to the ISO's IP family (empty uses the server default)
+	ctx = httputil.WithIPFamily(ctx, iso.IPFamily)
+
+	// Redirectors hand out mirrors, so note where each redirect leads
+	ctx = httputil.WithRedirectHook(ctx, func(from, to *url.URL) {
+		w.logDownload(iso.ID, models.LogLevelInfo, "Redirected from %s to %s", logURL(from.String()), logURL(to.String()))
+	})
+
 	// Update status to downloading
 	w.updateStatus(iso.ID, models.StatusDownloading, 0, "")
+	if iso.IPFamily != "" {
+		w.logDownload(iso.ID, models.LogLevelInfo, "Downloading %s over %s", logURL(iso.DownloadURL), iso.IPFamily)
+	} else {
+		w.logDownload(iso.ID, models.LogLevelInfo, "Downloading %s", logURL(iso.DownloadURL))
+	}
+
+	// Hash while streaming so verification doesn't have to re-read the file.
+	// All digests are computed in the same pass and stored for clients.
+	hasher := newMultiHasher(w.integrityHash)
 
-	// Download the file
-	if err := w.download(ctx, iso, tmpFile); err != nil {
+	// Download the file, restarting stalled and timed out transfers up to
+	// maxRetries times; each attempt may pick another mirror
+	startedAt := w.clock.Now()
+	validators, err := w.download(ctx, iso, tmpFile, hasher)
+	for attempt := 1; (errors.Is(err, ErrStalled) || errors.Is(err, ErrTimedOut)) && attempt <= w.maxRetries; attempt++ {
+		slog.WarnContext(ctx, "download stalled or timed out, retrying",
+			slog.String("iso_id", iso.ID),
+			slog.Int("attempt", attempt),
+			slog.Int("max_retries", w.maxRetries),
+			slog.Any("error", err),
+		)
+		if errors.Is(err, ErrTimedOut) {
+			w.logDownload(iso.ID, models.LogLevelWarn, "Attempt %d ran past the maximum duration of %s, retrying (%d of %d retries)",
+				attempt, w.maxDuration, attempt, w.maxRetries)
+		} else {
+			w.logDownload(iso.ID, models.LogLevelWarn, "Attempt %d stalled with no data for %s, retrying (%d of %d retries)",
+				attempt, w.stallTimeout, attempt, w.maxRetries)
+		}
+		select {
+		case <-ctx.Done():
+		case <-w.clock.After(w.retryDelay):
+		}
+		validators, err = w.download(ctx, iso, tmpFile, hasher)
+	}
+	if err != nil {
 		// Check if it was canceled
 		if ctx.Err() == context.Canceled {
-			w.updateStatus(iso.ID, models.StatusFailed, 0, "Download canceled")
-			return fmt.Errorf("download canceled: %w", ctx.Err())
+			w.updateStatus(iso.ID, models.StatusCanceled, 0, "Download canceled")
+			return nil, fmt.Errorf("download canceled: %w", ctx.Err())
+		}
+		// Check if every attempt ran past the configured deadline
+		if errors.Is(err, ErrTimedOut) {
+			w.fail(iso.ID, 0, models.ErrorReasonTimeout, err.Error())
+			return nil, err
+		}
+		// Check if the mirror stopped sending data
+		if errors.Is(err, ErrStalled) {
+			w.fail(iso.ID, 0, models.ErrorReasonStalled, err.Error())
+			return nil, err
 		}
 		w.updateStatus(iso.ID, models.StatusFailed, 0, err.Error())
-		return err
+		return nil, err
 	}
 
-	// Verify checksum if provided
-	if iso.ChecksumURL != "" {
+	if fi, err := os.Stat(tmpFile); err == nil {
+		w.logDownload(iso.ID, models.LogLevelInfo, "Downloaded %d bytes in %s", fi.Size(), w.clock.Since(startedAt).Round(time.Millisecond))
+	}
+
+	iso.DownloadStartedAt = &startedAt
+	digests := hasher.Digests()
+	iso.SHA256 = digests.SHA256
+	iso.SHA512 = digests.SHA512
+	iso.MD5 = digests.MD5
+	iso.IntegrityHash = digests.Integrity
+
+	// Waiting for a verify worker counts as verifying, so the download slot shows as free
+	if iso.HasChecksum() || iso.SignatureURL != "" {
 		w.updateStatus(iso.ID, models.StatusVerifying, 100, "")
+	}
 
-		if err := w.verifyChecksum(iso, tmpFile); err != nil {
-			w.updateStatus(iso.ID, models.StatusFailed, 100, err.Error())
+	handedOff = true
+	return &verifyJob{
+		iso:        iso,
+		validators: validators,
+		digests:    digests,
+		tmpFile:    tmpFile,
+		finalFile:  finalFile,
+	}, nil
+}
+
+// finalize verifies a fetched file against its upstream checksum and
+// signature, moves it into place, and marks the ISO complete. The temp file is
+// always removed.
+func (w *Worker) finalize(ctx context.Context, job *verifyJob) (err error) {
+	defer w.recoverPanic(ctx, job.iso, 100, &err)
+
+	iso, tmpFile, finalFile := job.iso, job.tmpFile, job.finalFile
+	defer fileutil.DeleteFileSilently(tmpFile)
+
+	// Pin the checksum fetch to the ISO's IP family, as for the download
+	ctx = httputil.WithIPFamily(ctx, iso.IPFamily)
+
+	// Verify checksum if provided. A checksum file that can't be fetched
+	// doesn't fail the download: the ISO completes unverified and the
+	// checksum retrier checks it once the file is back.
+	iso.ChecksumPending = false
+	if iso.HasChecksum() {
+		if iso.ChecksumURL != "" {
+			w.logDownload(iso.ID, models.LogLevelInfo, "Verifying %s checksum from %s", iso.ChecksumType, logURL(iso.ChecksumURL))
+		} else {
+			w.logDownload(iso.ID, models.LogLevelInfo, "Verifying %s checksum %s", iso.ChecksumType, iso.Checksum)
+		}
+		err := w.verifyChecksum(ctx, iso, job.digests)
+		switch {
+		case err == nil:
+			w.logDownload(iso.ID, models.LogLevelInfo, "Checksum verified")
+		case ctx.Err() == context.Canceled:
+			w.updateStatus(iso.ID, models.StatusCanceled, 0, "Download canceled")
+			return fmt.Errorf("download canceled: %w", ctx.Err())
+		case errors.Is(err, ErrChecksumUnavailable):
+			w.logDownload(iso.ID, models.LogLevelWarn, "Checksum unavailable (%v); completing unverified, the checksum will be retried", err)
+			iso.ChecksumPending = true
+		default:
+			w.fail(iso.ID, 100, models.ErrorReasonVerification, err.Error())
 			return err
 		}
 	}
 
-	// Move temp file to final location
-	if err := os.Rename(tmpFile, finalFile); err != nil {
+	// Verify a detached signature over the image itself
+	var signature []byte
+	iso.SignatureSigner = ""
+	if iso.SignatureURL != "" {
+		w.logDownload(iso.ID, models.LogLevelInfo, "Verifying signature from %s", logURL(iso.SignatureURL))
+		signer, sig, err := w.verifySignature(ctx, iso, tmpFile)
+		if err != nil {
+			if ctx.Err() == context.Canceled {
+				w.updateStatus(iso.ID, models.StatusCanceled, 0, "Download canceled")
+				return fmt.Errorf("download canceled: %w", ctx.Err())
+			}
+			w.fail(iso.ID, 100, models.ErrorReasonVerification, err.Error())
+			return err
+		}
+		w.logDownload(iso.ID, models.LogLevelInfo, "Signature verified: signed by %s", signer)
+		signature = sig
+		iso.SignatureSigner = signer
+	}
+	iso.VerifiedAt = nil
+	if (iso.HasChecksum() || iso.SignatureURL != "") && !iso.ChecksumPending {
+		verifiedAt := w.clock.Now()
+		iso.VerifiedAt = &verifiedAt
+	}
+
+	// Scan before the file can be served under /images
+	if w.scanner != nil {
+		if err := w.scanFile(ctx, job); err != nil {
+			return err
+		}
+	}
+
+	// A refresh replaces an existing file; keep the old one per the retention policy
+	if fileutil.FileExists(finalFile) {
+		w.archiveVersion(iso, finalFile)
+	}
+
+	// Move temp file to final location, copying when TMP_DIR is on another filesystem
+	if err := fileutil.RenameOrCopy(tmpFile, finalFile); err != nil {
 		errMsg := fmt.Sprintf("failed to move file to final location: %v", err)
 		w.updateStatus(iso.ID, models.StatusFailed, 100, errMsg)
 		return fmt.Errorf("failed to move file to final location: %w", err)
 	}
 
-	// Update size_bytes from actual file size if not set (e.g., server didn't send Content-Length)
-	if iso.SizeBytes == 0 {
-		if fi, err := os.Stat(finalFile); err == nil {
+	// Persist the rename before the ISO is marked complete
+	if err := fileutil.SyncDir(filepath.Dir(finalFile)); err != nil {
+		slog.WarnContext(ctx, "failed to sync ISO directory", slog.String("iso_id", iso.ID), slog.Any("error", err))
+	}
+
+	if fi, err := os.Stat(finalFile); err == nil {
+		// Update size_bytes from actual file size if not set (e.g., server didn't send
+		// Content-Length) or left over from the file a redownload replaced
+		if iso.SizeBytes != fi.Size() {
 			iso.SizeBytes = fi.Size()
 			if err := w.db.UpdateISOSize(iso.ID, iso.SizeBytes); err != nil {
-				slog.Warn("failed to update ISO size from file", slog.Any("error", err))
+				slog.WarnContext(ctx, "failed to update ISO size from file", slog.Any("error", err))
 			}
 		}
+
+		// Remember the file's identity so startup can spot files changed behind our back
+		modTime := fi.ModTime()
+		iso.FileInode = fileutil.FileInode(fi)
+		iso.FileModTime = &modTime
 	}
 
 	// Download and save checksum file alongside ISO (after file is moved)
-	if iso.ChecksumURL != "" {
+	if iso.ChecksumURL != "" && !iso.ChecksumPending {
 		checksumFile := pathutil.ConstructChecksumPath(finalFile, iso.ChecksumType)
-		if err := w.downloadChecksumFile(iso.ChecksumURL, checksumFile); err != nil {
-			slog.Warn("failed to save checksum file",
+		if err := w.downloadChecksumFile(ctx, iso.ChecksumURL, checksumFile); err != nil {
+			slog.WarnContext(ctx, "failed to save checksum file",
 				slog.String("iso_id", iso.ID),
 				slog.Any("error", err),
 			)
@@ -112,51 +396,202 @@ func (w *Worker) Process(ctx context.Context, iso *models.ISO) error {
 		}
 	}
 
+	// Keep the verified signature alongside the ISO, replacing one in the other format
+	if signature != nil {
+		if err := saveSignature(finalFile, signature); err != nil {
+			slog.WarnContext(ctx, "failed to save signature file",
+				slog.String("iso_id", iso.ID),
+				slog.Any("error", err),
+			)
+		}
+	}
+
 	// Mark as complete
 	w.updateStatus(iso.ID, models.StatusComplete, 100, "")
-	now := time.Now()
+	now := w.clock.Now()
 	iso.CompletedAt = &now
 	iso.Status = models.StatusComplete
 	iso.Progress = 100
 	iso.ErrorMessage = ""
+	iso.Redownload = false
+
+	// Remember upstream validators so later checks can spot in-place republishing
+	iso.UpstreamETag = job.validators.ETag
+	iso.UpstreamLastModified = job.validators.LastModified
+	recordSource(iso, job.validators)
+	iso.UpstreamChanged = false
+	iso.UpstreamCheckedAt = &now
+
+	// Mark as complete; the db layer retries while the database is busy
+	if err := w.db.UpdateISO(iso); err != nil {
+		slog.ErrorContext(ctx, "failed to update ISO to complete status",
+			slog.String("iso_id", iso.ID),
+			slog.Any("error", err),
+		)
+		// Don't return error since download itself succeeded
+	}
 
-	// Update database to mark as complete (with retry for database busy errors)
-	maxRetries := 5
-	var lastErr error
-	for i := 0; i < maxRetries; i++ {
-		lastErr = w.db.UpdateISO(iso)
-		if lastErr == nil {
-			break // Success
-		}
+	return nil
+}
+
+// scanFile runs the antivirus scan on a verified file. A flagged file is moved
+// to the quarantine directory and the ISO is marked quarantined. Scan errors
+// fail the download, so files are never exposed unscanned.
+func (w *Worker) scanFile(ctx context.Context, job *verifyJob) error {
+	iso := job.iso
 
-		if i < maxRetries-1 {
-			time.Sleep(100 * time.Millisecond)
+	result, err := w.scanner.ScanFile(ctx, job.tmpFile)
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			w.updateStatus(iso.ID, models.StatusCanceled, 0, "Download canceled")
+			return fmt.Errorf("download canceled: %w", ctx.Err())
 		}
+		errMsg := fmt.Sprintf("antivirus scan failed: %v", err)
+		w.updateStatus(iso.ID, models.StatusFailed, 100, errMsg)
+		return fmt.Errorf("antivirus scan failed: %w", err)
+	}
+	if !result.Infected {
+		w.logDownload(iso.ID, models.LogLevelInfo, "Antivirus scan found nothing")
+		return nil
 	}
 
-	if lastErr != nil {
-		slog.Error("failed to update ISO to complete status",
-			slog.String("iso_id", iso.ID),
-			slog.Int("retries", maxRetries),
-			slog.Any("error", lastErr),
-		)
-		// Don't return error since download itself succeeded
+	quarantinePath := pathutil.ConstructQuarantinePath(w.isoDir, iso.FilePath)
+	if err := fileutil.EnsureParentDirectory(quarantinePath); err != nil {
+		w.updateStatus(iso.ID, models.StatusFailed, 100, err.Error())
+		return err
+	}
+	if err := fileutil.RenameOrCopy(job.tmpFile, quarantinePath); err != nil {
+		errMsg := fmt.Sprintf("failed to quarantine file: %v", err)
+		w.updateStatus(iso.ID, models.StatusFailed, 100, errMsg)
+		return fmt.Errorf("failed to quarantine file: %w", err)
 	}
 
-	return nil
+	slog.WarnContext(ctx, "download quarantined by antivirus scan",
+		slog.String("iso_id", iso.ID),
+		slog.String("name", iso.Name),
+		slog.String("signature", result.Signature),
+	)
+
+	w.logDownload(iso.ID, models.LogLevelWarn, "Quarantined: antivirus scan found %s", result.Signature)
+
+	// Keep digests and validators so a release can complete the ISO as-is
+	iso.Status = models.StatusQuarantined
+	iso.Progress = 100
+	iso.ErrorMessage = "antivirus scan found " + result.Signature
+	iso.Redownload = false
+	iso.UpstreamETag = job.validators.ETag
+	iso.UpstreamLastModified = job.validators.LastModified
+	recordSource(iso, job.validators)
+	if err := w.db.UpdateISO(iso); err != nil {
+		slog.ErrorContext(ctx, "failed to update ISO to quarantined status", slog.String("iso_id", iso.ID), slog.Any("error", err))
+	}
+	if w.progressCallback != nil {
+		w.progressCallback(iso.ID, 100, models.StatusQuarantined)
+	}
+
+	return fmt.Errorf("%w: %s", ErrQuarantined, result.Signature)
 }
 
-// download downloads the ISO file with progress tracking.
-func (w *Worker) download(ctx context.Context, iso *models.ISO, destPath string) error {
+// archiveVersion keeps a copy of the file about to be replaced and prunes versions
+// beyond keepVersions, except for pinned ISOs, recording them in a GC report.
+// Failures are logged; they never fail the download.
+func (w *Worker) archiveVersion(iso *models.ISO, finalFile string) {
+	if w.keepVersions == 0 {
+		return
+	}
+
+	versionPath := pathutil.ConstructVersionPath(w.isoDir, iso.FilePath, w.clock.Now())
+	if err := fileutil.EnsureParentDirectory(versionPath); err != nil {
+		slog.Warn("failed to create versions directory", slog.String("iso_id", iso.ID), slog.Any("error", err))
+		return
+	}
+	// Link rather than move so the old file keeps being served until the rename
+	if err := fileutil.LinkOrCopyFile(finalFile, versionPath); err != nil {
+		slog.Warn("failed to keep previous version", slog.String("iso_id", iso.ID), slog.Any("error", err))
+		return
+	}
+
+	if iso.Pinned {
+		return
+	}
+	versions, err := filepath.Glob(pathutil.VersionGlob(w.isoDir, iso.FilePath))
+	if err != nil {
+		return
+	}
+	// Timestamps sort lexically, so the oldest versions come first
+	sort.Strings(versions)
+	var removed []models.GCFile
+	for len(versions) > w.keepVersions {
+		oldest := versions[0]
+		versions = versions[1:]
+		info, err := os.Stat(oldest)
+		if err != nil {
+			continue
+		}
+		if err := os.Remove(oldest); err != nil {
+			slog.Warn("failed to remove old version", slog.String("path", oldest), slog.Any("error", err))
+			continue
+		}
+		removed = append(removed, models.GCFile{Path: oldest, SizeBytes: info.Size()})
+	}
+	recordGCReport(w.db, &models.GCReport{CreatedAt: w.clock.Now(), Reason: models.GCReasonVersionRetention, ISOID: iso.ID, Files: removed})
+}
+
+// download downloads the ISO file with progress tracking, feeding every chunk to hasher.
+// On success it returns the upstream validators of the response. Every timing
+// in it, from stall detection and the deadline to first-byte latency and
+// progress pacing, reads the worker's clock.
+func (w *Worker) download(ctx context.Context, iso *models.ISO, destPath string, hasher *multiHasher) (*httputil.Validators, error) {
+	// Each attempt rewrites the file from the start
+	hasher.Reset()
+	active := activeDownloadFrom(ctx)
+	active.restart()
+
+	// Cancel the transfer if no data arrives within the stall timeout
+	transferCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var lastActivity atomic.Int64
+	lastActivity.Store(w.clock.Now().UnixNano())
+	if w.stallTimeout > 0 {
+		go w.watchStall(transferCtx, cancel, &lastActivity)
+	}
+
+	// Bound the attempt so a mirror that trickles bytes can't hold the worker forever
+	if w.maxDuration > 0 {
+		deadline := w.clock.AfterFunc(w.maxDuration, func() { cancel(ErrTimedOut) })
+		defer deadline.Stop()
+	}
+
 	// Use httputil to download with progress tracking
+	start := w.clock.Now()
+	var firstByte time.Duration
+	gotFirstByte := false
 	lastProgress := -1
-	lastUpdate := time.Now()
+	lastUpdate := start
+	lastPersist := time.Time{}
+	var lastDownloaded int64
+
+	// Each attempt may go to a different mirror of the URL's group
+	downloadURL := w.mirrors.pick(iso.DownloadURL)
+	if downloadURL != iso.DownloadURL {
+		w.logDownload(iso.ID, models.LogLevelInfo, "Using mirror %s", logURL(downloadURL))
+	}
+
+	validators, err := httputil.DownloadFileWithProgress(transferCtx, downloadURL, destPath, w.bufferSize, hasher, func(downloaded, total int64) {
+		lastActivity.Store(w.clock.Now().UnixNano())
+		active.transferred(downloaded, total)
+		w.ingest.Add(downloaded - lastDownloaded)
+		lastDownloaded = downloaded
+		if !gotFirstByte {
+			firstByte = w.clock.Since(start)
+			gotFirstByte = true
+		}
 
-	err := httputil.DownloadFileWithProgress(ctx, iso.DownloadURL, destPath, 32*1024, func(downloaded, total int64) {
 		// Update database with total size on first callback
 		if iso.SizeBytes == 0 && total > 0 {
 			if err := w.db.UpdateISOSize(iso.ID, total); err != nil {
-				slog.Warn("failed to update ISO size", slog.Any("error", err))
+				slog.WarnContext(ctx, "failed to update ISO size", slog.Any("error", err))
 			}
 			iso.SizeBytes = total
 		}
@@ -167,37 +602,103 @@ func (w *Worker) download(ctx context.Context, iso *models.ISO, destPath string)
 			progress = int((downloaded * 100) / total)
 		}
 
-		// Update progress every 1% or every second
-		now := time.Now()
-		if progress != lastProgress && (progress-lastProgress >= 1 || now.Sub(lastUpdate) >= time.Second) {
-			w.updateStatus(iso.ID, models.StatusDownloading, progress, "")
+		// Update progress when the configured threshold or interval is reached
+		now := w.clock.Now()
+		if progress != lastProgress && (progress-lastProgress >= w.progressThreshold || now.Sub(lastUpdate) >= w.progressInterval) {
+			// Broadcast every tick, but only write through to SQLite once per persist interval
+			persist := now.Sub(lastPersist) >= w.persistInterval
+			w.updateProgress(iso.ID, progress, persist)
+			if persist {
+				lastPersist = now
+			}
 			lastProgress = progress
 			lastUpdate = now
 		}
 	})
-	if err != nil {
-		return err
+	if err != nil && errors.Is(context.Cause(transferCtx), ErrStalled) {
+		err = fmt.Errorf("%w: no data received for %s", ErrStalled, w.stallTimeout)
+	}
+	if err != nil && errors.Is(context.Cause(transferCtx), ErrTimedOut) {
+		err = fmt.Errorf("%w: exceeded maximum duration of %s", ErrTimedOut, w.maxDuration)
 	}
 
-	return nil
+	// User cancellation and shutdown say nothing about the mirror's health
+	if ctx.Err() != context.Canceled {
+		w.recordMirrorHealth(downloadURL, firstByte, err)
+	}
+
+	// Mirrors don't share ETags, so upstream checks of the ISO's own URL
+	// compare Last-Modified and size instead
+	if validators != nil && downloadURL != iso.DownloadURL {
+		validators.ETag = ""
+	}
+
+	return validators, err
 }
 
-// verifyChecksum verifies the downloaded file's checksum.
-func (w *Worker) verifyChecksum(iso *models.ISO, filepath string) error {
-	// Fetch expected checksum using the original filename from the download URL
-	// Checksum files reference the original filename, not our computed filename
-	originalFilename := iso.GetOriginalFilename()
-	expectedChecksum, err := FetchExpectedChecksum(iso.ChecksumURL, originalFilename)
+// recordMirrorHealth records the outcome of a transfer against the upstream host.
+func (w *Worker) recordMirrorHealth(downloadURL string, latency time.Duration, downloadErr error) {
+	u, err := url.Parse(downloadURL)
+	if err != nil || u.Hostname() == "" {
+		return
+	}
+	host := strings.ToLower(u.Hostname())
+
+	now := w.clock.Now()
+	if downloadErr == nil {
+		err = w.db.RecordMirrorSuccess(host, latency, now)
+	} else {
+		err = w.db.RecordMirrorFailure(host, downloadErr.Error(), now)
+	}
 	if err != nil {
-		return err
+		slog.Warn("failed to record mirror health", slog.String("host", host), slog.Any("error", err))
 	}
+}
+
+// watchStall cancels the transfer with ErrStalled once no data has arrived for the stall timeout.
+func (w *Worker) watchStall(ctx context.Context, cancel context.CancelCauseFunc, lastActivity *atomic.Int64) {
+	interval := w.stallTimeout / 4
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	ticker := w.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			if w.clock.Since(time.Unix(0, lastActivity.Load())) >= w.stallTimeout {
+				// Stop ticking before the transfer unwinds, so a retry delay
+				// is all that's left waiting on the clock
+				ticker.Stop()
+				cancel(ErrStalled)
+				return
+			}
+		}
+	}
+}
 
-	// Compute actual checksum
-	actualChecksum, err := ComputeHash(filepath, iso.ChecksumType)
+// verifyChecksum compares the digests computed during the download with the expected checksum.
+func (w *Worker) verifyChecksum(ctx context.Context, iso *models.ISO, digests Digests) error {
+	actualChecksum, err := digests.Get(iso.ChecksumType)
 	if err != nil {
 		return fmt.Errorf("failed to compute checksum: %w", err)
 	}
 
+	// Without a checksum file, compare against the digest given for the ISO
+	expectedChecksum := strings.ToLower(iso.Checksum)
+	if iso.ChecksumURL != "" {
+		// Fetch expected checksum using the original filename from the download URL
+		// Checksum files reference the original filename, not our computed filename
+		originalFilename := iso.GetOriginalFilename()
+		expectedChecksum, err = w.expectedChecksum(ctx, iso, originalFilename)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Compare checksums (case-insensitive)
 	if actualChecksum != expectedChecksum {
 		return fmt.Errorf("checksum mismatch: expected %s, got %s", expectedChecksum, actualChecksum)
@@ -205,33 +706,172 @@ func (w *Worker) verifyChecksum(iso *models.ISO, filepath string) error {
 
 	// Update database with verified checksum
 	if err := w.db.UpdateISOChecksum(iso.ID, actualChecksum); err != nil {
-		slog.Warn("failed to update ISO checksum", slog.Any("error", err))
+		slog.WarnContext(ctx, "failed to update ISO checksum", slog.Any("error", err))
 	}
 	iso.Checksum = actualChecksum
 
 	return nil
 }
 
+// expectedChecksum fetches the checksum published for filename in the ISO's
+// checksum file and keeps it in the known checksums. When the file can't be
+// fetched or no longer lists filename, a known checksum stands in for it.
+func (w *Worker) expectedChecksum(ctx context.Context, iso *models.ISO, filename string) (string, error) {
+	checksum, err := FetchExpectedChecksum(ctx, iso.ChecksumURL, filename)
+	if err == nil {
+		known := models.KnownChecksum{
+			ChecksumURL: iso.ChecksumURL,
+			Filename:    filename,
+			Checksum:    checksum,
+			Source:      models.KnownChecksumSourceUpstream,
+			UpdatedAt:   w.clock.Now(),
+		}
+		if err := w.db.SaveKnownChecksums([]models.KnownChecksum{known}); err != nil {
+			slog.WarnContext(ctx, "failed to save known checksum", slog.Any("error", err))
+		}
+		return checksum, nil
+	}
+	if ctx.Err() != nil {
+		return "", err
+	}
+
+	known, lookupErr := w.db.GetKnownChecksum(iso.ChecksumURL, filename)
+	if lookupErr != nil {
+		slog.WarnContext(ctx, "failed to look up known checksum", slog.Any("error", lookupErr))
+	}
+	if known == nil {
+		return "", err
+	}
+	w.logDownload(iso.ID, models.LogLevelWarn, "Checksum file unavailable (%v), using the known %s checksum", err, known.Source)
+	return known.Checksum, nil
+}
+
+// verifySignature fetches the ISO's detached signature and checks it against
+// the downloaded file. It returns the signer and the signature bytes.
+func (w *Worker) verifySignature(ctx context.Context, iso *models.ISO, filePath string) (string, []byte, error) {
+	signature, err := FetchSignature(ctx, iso.SignatureURL)
+	if err != nil {
+		return "", nil, err
+	}
+	signer, err := VerifyDetachedSignature(filePath, signature, w.signatureKeyring)
+	if err != nil {
+		return "", nil, err
+	}
+	return signer, signature, nil
+}
+
 // updateStatus updates the ISO status and triggers progress callback.
 func (w *Worker) updateStatus(isoID string, status models.ISOStatus, progress int, errorMsg string) {
-	if err := w.db.UpdateISOStatus(isoID, status, errorMsg); err != nil {
-		slog.Warn("failed to update ISO status", slog.Any("error", err))
+	switch status {
+	case models.StatusFailed:
+		w.logDownload(isoID, models.LogLevelError, "Failed: %s", errorMsg)
+	case models.StatusCanceled:
+		w.logDownload(isoID, models.LogLevelWarn, "Canceled")
+	case models.StatusComplete:
+		w.logDownload(isoID, models.LogLevelInfo, "Complete")
 	}
+
 	if progress >= 0 {
+		if err := w.db.UpdateISOStatusAndProgress(isoID, status, progress, errorMsg); err != nil {
+			slog.Warn("failed to update ISO status", slog.Any("error", err))
+		}
+	} else if err := w.db.UpdateISOStatus(isoID, status, errorMsg); err != nil {
+		slog.Warn("failed to update ISO status", slog.Any("error", err))
+	}
+
+	if w.progressCallback != nil {
+		w.progressCallback(isoID, progress, status)
+	}
+}
+
+// fail marks the ISO as failed with a classified error reason and triggers progress callback.
+func (w *Worker) fail(isoID string, progress int, reason models.ErrorReason, errorMsg string) {
+	w.logDownload(isoID, models.LogLevelError, "Failed (%s): %s", reason, errorMsg)
+	if err := w.db.UpdateISOFailure(isoID, progress, reason, errorMsg); err != nil {
+		slog.Warn("failed to update ISO status", slog.Any("error", err))
+	}
+
+	if w.progressCallback != nil {
+		w.progressCallback(isoID, progress, models.StatusFailed)
+	}
+}
+
+// recoverPanic is deferred by fetch and finalize. A panic while handling iso,
+// e.g. on a malformed checksum file, fails the ISO with the panic value and
+// is returned as ErrPanicked, so the worker goroutine lives on to take the
+// next download.
+func (w *Worker) recoverPanic(ctx context.Context, iso *models.ISO, progress int, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	slog.ErrorContext(ctx, "worker panicked",
+		slog.String("iso_id", iso.ID),
+		slog.Any("panic", r),
+		slog.String("stack", string(debug.Stack())),
+	)
+	if w.panics != nil {
+		w.panics.Add(1)
+	}
+	w.fail(iso.ID, progress, models.ErrorReasonPanic, fmt.Sprintf("internal error: %v", r))
+	*err = fmt.Errorf("%w: %v", ErrPanicked, r)
+}
+
+// logDownload adds an entry to the ISO's download log. Failing to write it
+// never fails the download.
+func (w *Worker) logDownload(isoID, level, format string, args ...any) {
+	entry := &models.DownloadLogEntry{
+		ISOID:    isoID,
+		LoggedAt: w.clock.Now(),
+		Level:    level,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if err := w.db.AppendDownloadLog(entry); err != nil {
+		slog.Warn("failed to append download log", slog.String("iso_id", isoID), slog.Any("error", err))
+	}
+}
+
+// recordSource stores where the file was served from: the URL that answered
+// after redirects, without credentials or query, and the server's identifying
+// response headers.
+func recordSource(iso *models.ISO, validators *httputil.Validators) {
+	iso.SourceURL = ""
+	if validators.FinalURL != "" {
+		iso.SourceURL = logURL(validators.FinalURL)
+	}
+	iso.SourceHeaders = validators.Headers
+}
+
+// logURL returns a URL for the download log without its query string or
+// user info, which may carry signatures or credentials.
+func logURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "(invalid URL)"
+	}
+	u.User, u.RawQuery, u.Fragment = nil, "", ""
+	return u.String()
+}
+
+// updateProgress reports download progress while the status stays "downloading".
+// The progress callback fires on every call; the database is only written when persist is set,
+// since status transitions (verifying, complete, failed) always persist the final progress.
+func (w *Worker) updateProgress(isoID string, progress int, persist bool) {
+	if persist {
 		if err := w.db.UpdateISOProgress(isoID, progress); err != nil {
 			slog.Warn("failed to update ISO progress", slog.Any("error", err))
 		}
 	}
 
 	if w.progressCallback != nil {
-		w.progressCallback(isoID, progress, status)
+		w.progressCallback(isoID, progress, models.StatusDownloading)
 	}
 }
 
 // downloadChecksumFile downloads the checksum file and saves it.
-func (w *Worker) downloadChecksumFile(checksumURL, destPath string) error {
+func (w *Worker) downloadChecksumFile(ctx context.Context, checksumURL, destPath string) error {
 	// Use context with timeout for checksum download
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
 	return httputil.DownloadFile(ctx, checksumURL, destPath)
@@ -1,19 +1,31 @@
 package download
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/aloks98/isoman/backend/internal/config"
+	"github.com/aloks98/isoman/backend/internal/constants"
 	"github.com/aloks98/isoman/backend/internal/db"
 	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/pathutil"
+	"github.com/aloks98/isoman/backend/internal/throughput"
 
 	"github.com/google/uuid"
 )
@@ -37,7 +49,7 @@ func setupTestWorker(t *testing.T) (*Worker, *db.DB, string, func()) {
 	}
 
 	// Create worker
-	worker := NewWorker(database, isoDir, nil)
+	worker := NewWorker(database, isoDir, &cfg.Download, nil)
 
 	cleanup := func() {
 		database.Close()
@@ -56,6 +68,7 @@ func TestWorkerDownloadSuccess(t *testing.T) {
 	testContent := []byte("test file content")
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(testContent)))
+		w.Header().Set("ETag", `"abc123"`)
 		w.WriteHeader(http.StatusOK)
 		w.Write(testContent)
 	}))
@@ -77,11 +90,16 @@ func TestWorkerDownloadSuccess(t *testing.T) {
 	database.CreateISO(iso)
 
 	// Process download
+	ingest := &throughput.Meter{}
+	worker.ingest = ingest
 	ctx := context.Background()
 	err := worker.Process(ctx, iso)
 	if err != nil {
 		t.Fatalf("Process failed: %v", err)
 	}
+	if ingest.Total() != int64(len(testContent)) {
+		t.Errorf("Ingest meter should count %d bytes, got %d", len(testContent), ingest.Total())
+	}
 
 	// Verify file was downloaded
 	finalPath := filepath.Join(isoDir, iso.FilePath)
@@ -119,6 +137,146 @@ func TestWorkerDownloadSuccess(t *testing.T) {
 	if updatedISO.SizeBytes != int64(len(testContent)) {
 		t.Errorf("Size should be %d, got: %d", len(testContent), updatedISO.SizeBytes)
 	}
+
+	wantSHA256 := fmt.Sprintf("%x", sha256.Sum256(testContent))
+	if updatedISO.SHA256 != wantSHA256 || updatedISO.SHA512 == "" || updatedISO.MD5 == "" {
+		t.Errorf("All digests should be stored, got sha256=%q sha512=%q md5=%q", updatedISO.SHA256, updatedISO.SHA512, updatedISO.MD5)
+	}
+	if !strings.HasPrefix(updatedISO.IntegrityHash, constants.IntegrityHashBLAKE2b+":") {
+		t.Errorf("IntegrityHash should default to blake2b, got: %q", updatedISO.IntegrityHash)
+	}
+
+	// The finalized file's identity is recorded for startup checks
+	fi, err := os.Stat(finalPath)
+	if err != nil {
+		t.Fatalf("Failed to stat final file: %v", err)
+	}
+	if updatedISO.FileModTime == nil || !updatedISO.FileModTime.Equal(fi.ModTime()) {
+		t.Errorf("FileModTime should match the final file, got: %v want: %v", updatedISO.FileModTime, fi.ModTime())
+	}
+	if runtime.GOOS != "windows" && updatedISO.FileInode == 0 {
+		t.Error("FileInode should be recorded")
+	}
+
+	if updatedISO.UpstreamETag != `"abc123"` {
+		t.Errorf("UpstreamETag should be recorded, got: %q", updatedISO.UpstreamETag)
+	}
+
+	// Verify the transfer was recorded against the mirror
+	mirror, err := database.GetMirrorHealth("127.0.0.1")
+	if err != nil {
+		t.Fatalf("Failed to get mirror health: %v", err)
+	}
+	if mirror.SuccessCount != 1 || mirror.FailureCount != 0 {
+		t.Errorf("Expected 1 success and 0 failures, got: %d/%d", mirror.SuccessCount, mirror.FailureCount)
+	}
+}
+
+// TestWorkerRefreshKeepsVersions tests that replacing an existing file keeps the
+// previous copies up to the configured limit.
+func TestWorkerRefreshKeepsVersions(t *testing.T) {
+	worker, database, isoDir, cleanup := setupTestWorker(t)
+	defer cleanup()
+	worker.keepVersions = 1
+
+	var body atomic.Value
+	body.Store("v1")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body.Load().(string)))
+	}))
+	defer server.Close()
+
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "nightly",
+		Version:     "latest",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: server.URL,
+		Status:      models.StatusPending,
+		CreatedAt:   time.Now(),
+	}
+	iso.ComputeFields()
+	database.CreateISO(iso)
+
+	for i, content := range []string{"v1", "v2", "v3"} {
+		if i > 0 {
+			// Version names have second resolution
+			time.Sleep(1100 * time.Millisecond)
+		}
+		body.Store(content)
+		if err := worker.Process(context.Background(), iso); err != nil {
+			t.Fatalf("Process failed: %v", err)
+		}
+	}
+
+	content, _ := os.ReadFile(filepath.Join(isoDir, iso.FilePath))
+	if string(content) != "v3" {
+		t.Errorf("Final file should hold the latest content, got: %q", content)
+	}
+
+	versions, _ := filepath.Glob(pathutil.VersionGlob(isoDir, iso.FilePath))
+	if len(versions) != 1 {
+		t.Fatalf("Expected 1 kept version, got: %v", versions)
+	}
+	kept, _ := os.ReadFile(versions[0])
+	if string(kept) != "v2" {
+		t.Errorf("Kept version should be the previous file, got: %q", kept)
+	}
+
+	// Pruning v1 is reported against the ISO
+	reports, err := database.ListGCReports(db.GCReportsParams{Reason: models.GCReasonVersionRetention, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListGCReports failed: %v", err)
+	}
+	if len(reports) != 1 || reports[0].ISOID != iso.ID || reports[0].FileCount != 1 || reports[0].BytesReclaimed != int64(len("v1")) {
+		t.Fatalf("Expected one report of the pruned version, got: %+v", reports)
+	}
+	if path := reports[0].Files[0].Path; !strings.HasPrefix(path, pathutil.GetVersionsDir(isoDir)) || path == versions[0] {
+		t.Errorf("Expected the oldest version reported, got: %s", path)
+	}
+}
+
+func TestWorkerRefreshPinnedKeepsAllVersions(t *testing.T) {
+	worker, database, isoDir, cleanup := setupTestWorker(t)
+	defer cleanup()
+	worker.keepVersions = 1
+
+	var body atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body.Load().(string)))
+	}))
+	defer server.Close()
+
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "nightly",
+		Version:     "latest",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: server.URL,
+		Status:      models.StatusPending,
+		CreatedAt:   time.Now(),
+		Pinned:      true,
+	}
+	iso.ComputeFields()
+	database.CreateISO(iso)
+
+	for i, content := range []string{"v1", "v2", "v3"} {
+		if i > 0 {
+			// Version names have second resolution
+			time.Sleep(1100 * time.Millisecond)
+		}
+		body.Store(content)
+		if err := worker.Process(context.Background(), iso); err != nil {
+			t.Fatalf("Process failed: %v", err)
+		}
+	}
+
+	versions, _ := filepath.Glob(pathutil.VersionGlob(isoDir, iso.FilePath))
+	if len(versions) != 2 {
+		t.Errorf("Expected both previous files kept for a pinned ISO, got: %v", versions)
+	}
 }
 
 // TestWorkerDownloadWithChecksum tests download with checksum verification.
@@ -297,14 +455,14 @@ func TestWorkerDownloadCancellation(t *testing.T) {
 		t.Fatal("Expected download to be canceled, but it succeeded")
 	}
 
-	// Verify status is failed with cancellation message
+	// Verify status is canceled with cancellation message
 	updatedISO, err := database.GetISO(iso.ID)
 	if err != nil {
 		t.Fatalf("Failed to get updated ISO: %v", err)
 	}
 
-	if updatedISO.Status != models.StatusFailed {
-		t.Errorf("Status should be 'failed', got: %s", updatedISO.Status)
+	if updatedISO.Status != models.StatusCanceled {
+		t.Errorf("Status should be 'canceled', got: %s", updatedISO.Status)
 	}
 
 	if updatedISO.ErrorMessage != "Download canceled" {
@@ -312,6 +470,190 @@ func TestWorkerDownloadCancellation(t *testing.T) {
 	}
 }
 
+// TestWorkerDownloadMaxDuration tests that a trickling transfer is retried and
+// then failed once every attempt passes the deadline.
+func TestWorkerDownloadMaxDuration(t *testing.T) {
+	worker, database, _, cleanup := setupTestWorker(t)
+	defer cleanup()
+	worker.maxDuration = 100 * time.Millisecond
+	worker.maxRetries = 1
+	worker.retryDelay = 0
+
+	// Create test HTTP server that trickles bytes
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Length", "1000000")
+		w.WriteHeader(http.StatusOK)
+		for i := 0; i < 100; i++ {
+			select {
+			case <-r.Context().Done():
+				return
+			default:
+			}
+			w.Write(make([]byte, 10))
+			w.(http.Flusher).Flush()
+			time.Sleep(10 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "test",
+		Version:     "1.0",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: server.URL,
+		Status:      models.StatusPending,
+		CreatedAt:   time.Now(),
+	}
+	iso.ComputeFields()
+	database.CreateISO(iso)
+
+	if err := worker.Process(context.Background(), iso); !errors.Is(err, ErrTimedOut) {
+		t.Fatalf("Expected ErrTimedOut, got: %v", err)
+	}
+
+	if got := requests.Load(); got != 2 {
+		t.Errorf("Expected 2 attempts (1 retry), got: %d", got)
+	}
+
+	updatedISO, err := database.GetISO(iso.ID)
+	if err != nil {
+		t.Fatalf("Failed to get updated ISO: %v", err)
+	}
+
+	if updatedISO.Status != models.StatusFailed {
+		t.Errorf("Status should be 'failed', got: %s", updatedISO.Status)
+	}
+
+	if !strings.Contains(updatedISO.ErrorMessage, "maximum duration") {
+		t.Errorf("ErrorMessage should mention the maximum duration, got: %s", updatedISO.ErrorMessage)
+	}
+
+	if updatedISO.ErrorReason != models.ErrorReasonTimeout {
+		t.Errorf("ErrorReason should be 'timeout', got: %s", updatedISO.ErrorReason)
+	}
+}
+
+// TestWorkerDownloadTimeoutRetriesMirror tests that an attempt past the
+// deadline is retried on another mirror, with a deadline of its own.
+func TestWorkerDownloadTimeoutRetriesMirror(t *testing.T) {
+	worker, database, _, cleanup := setupTestWorker(t)
+	defer cleanup()
+	worker.maxDuration = 200 * time.Millisecond
+	worker.maxRetries = 1
+	worker.retryDelay = 0
+
+	content := []byte("complete ISO content")
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000000")
+		w.WriteHeader(http.StatusOK)
+		w.Write(make([]byte, 10))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Starts late, so it only finishes if the retry got a fresh deadline
+		time.Sleep(150 * time.Millisecond)
+		w.Write(content)
+	}))
+	defer fast.Close()
+
+	groups, err := ParseMirrorGroups([]string{"test=" + slow.URL + "/pub/", "test=" + fast.URL + "/pub/"})
+	if err != nil {
+		t.Fatalf("ParseMirrorGroups() failed: %v", err)
+	}
+	worker.mirrors = &mirrorRotation{
+		policy: constants.MirrorRotationRoundRobin,
+		groups: groups,
+		next:   make([]atomic.Uint64, len(groups)),
+	}
+
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "test",
+		Version:     "1.0",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: slow.URL + "/pub/test.iso",
+		Status:      models.StatusPending,
+		CreatedAt:   time.Now(),
+	}
+	iso.ComputeFields()
+	database.CreateISO(iso)
+
+	if err := worker.Process(context.Background(), iso); err != nil {
+		t.Fatalf("Expected the retry on the other mirror to succeed, got: %v", err)
+	}
+
+	updatedISO, err := database.GetISO(iso.ID)
+	if err != nil {
+		t.Fatalf("Failed to get updated ISO: %v", err)
+	}
+	if updatedISO.Status != models.StatusComplete {
+		t.Errorf("Status should be 'complete', got: %s (%s)", updatedISO.Status, updatedISO.ErrorMessage)
+	}
+}
+
+// TestWorkerDownloadStalled tests that a transfer with no data is retried and then failed as stalled.
+func TestWorkerDownloadStalled(t *testing.T) {
+	worker, database, _, cleanup := setupTestWorker(t)
+	defer cleanup()
+	worker.stallTimeout = 100 * time.Millisecond
+	worker.maxRetries = 1
+	worker.retryDelay = 0
+
+	// Create test HTTP server that sends headers and then goes silent
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Length", "1000000")
+		w.WriteHeader(http.StatusOK)
+		w.Write(make([]byte, 100))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "test",
+		Version:     "1.0",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: server.URL,
+		Status:      models.StatusPending,
+		CreatedAt:   time.Now(),
+	}
+	iso.ComputeFields()
+	database.CreateISO(iso)
+
+	err := worker.Process(context.Background(), iso)
+	if !errors.Is(err, ErrStalled) {
+		t.Fatalf("Expected ErrStalled, got: %v", err)
+	}
+
+	if got := requests.Load(); got != 2 {
+		t.Errorf("Expected 2 attempts (1 retry), got: %d", got)
+	}
+
+	updatedISO, err := database.GetISO(iso.ID)
+	if err != nil {
+		t.Fatalf("Failed to get updated ISO: %v", err)
+	}
+
+	if updatedISO.Status != models.StatusFailed {
+		t.Errorf("Status should be 'failed', got: %s", updatedISO.Status)
+	}
+
+	if updatedISO.ErrorReason != models.ErrorReasonStalled {
+		t.Errorf("ErrorReason should be 'stalled', got: %s", updatedISO.ErrorReason)
+	}
+}
+
 // TestWorkerProgressCallback tests progress callback.
 func TestWorkerProgressCallback(t *testing.T) {
 	worker, database, _, cleanup := setupTestWorker(t)
@@ -373,6 +715,151 @@ func TestWorkerProgressCallback(t *testing.T) {
 	}
 }
 
+// TestWorkerCustomTempDir tests downloading through a configured TMP_DIR.
+func TestWorkerCustomTempDir(t *testing.T) {
+	_, database, isoDir, cleanup := setupTestWorker(t)
+	defer cleanup()
+
+	cfg := DefaultConfig()
+	cfg.TempDir = filepath.Join(t.TempDir(), "scratch")
+	worker := NewWorker(database, isoDir, cfg, nil)
+
+	// The partial file lives in the configured temp dir while downloading
+	var tmpSeen bool
+	worker.progressCallback = func(isoID string, progress int, status models.ISOStatus) {
+		if status == models.StatusDownloading && progress > 0 {
+			entries, _ := os.ReadDir(cfg.TempDir)
+			tmpSeen = len(entries) == 1
+		}
+	}
+
+	testContent := []byte("custom temp dir")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(testContent)))
+		w.Write(testContent)
+	}))
+	defer server.Close()
+
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "test",
+		Version:     "1.0",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: server.URL,
+		Status:      models.StatusPending,
+		CreatedAt:   time.Now(),
+	}
+	iso.ComputeFields()
+	database.CreateISO(iso)
+
+	if err := worker.Process(context.Background(), iso); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	if !tmpSeen {
+		t.Error("Expected the download to be written to the configured temp dir")
+	}
+	content, err := os.ReadFile(pathutil.ConstructISOPath(isoDir, iso.FilePath))
+	if err != nil || !bytes.Equal(content, testContent) {
+		t.Errorf("Final file should hold the downloaded content, got %q (%v)", content, err)
+	}
+	if _, err := os.Stat(pathutil.GetTempDir(isoDir)); !os.IsNotExist(err) {
+		t.Error("Default temp dir should not be used when TMP_DIR is set")
+	}
+}
+
+// TestWorkerProgressThreshold tests that progress updates honor the configured threshold.
+func TestWorkerProgressThreshold(t *testing.T) {
+	_, database, isoDir, cleanup := setupTestWorker(t)
+	defer cleanup()
+
+	cfg := DefaultConfig()
+	cfg.BufferSize = 100
+	cfg.ProgressPercentThreshold = 25
+	cfg.ProgressUpdateInterval = time.Hour
+	worker := NewWorker(database, isoDir, cfg, nil)
+
+	var downloadingUpdates []int
+	worker.progressCallback = func(isoID string, progress int, status models.ISOStatus) {
+		if status == models.StatusDownloading && progress > 0 {
+			downloadingUpdates = append(downloadingUpdates, progress)
+		}
+	}
+
+	// 10000 bytes read 100 at a time would produce 100 updates at a 1% threshold
+	testContent := make([]byte, 10000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(testContent)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(testContent)
+	}))
+	defer server.Close()
+
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "test",
+		Version:     "1.0",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: server.URL,
+		Status:      models.StatusPending,
+		CreatedAt:   time.Now(),
+	}
+	iso.ComputeFields()
+	database.CreateISO(iso)
+
+	if err := worker.Process(context.Background(), iso); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	if len(downloadingUpdates) == 0 || len(downloadingUpdates) > 4 {
+		t.Errorf("Expected between 1 and 4 progress updates with a 25%% threshold, got %d: %v", len(downloadingUpdates), downloadingUpdates)
+	}
+}
+
+// TestWorkerProgressWriteBehind tests that progress is broadcast without always being persisted.
+func TestWorkerProgressWriteBehind(t *testing.T) {
+	worker, database, _, cleanup := setupTestWorker(t)
+	defer cleanup()
+
+	var broadcast []int
+	worker.progressCallback = func(isoID string, progress int, status models.ISOStatus) {
+		broadcast = append(broadcast, progress)
+	}
+
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "test",
+		Version:     "1.0",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: "http://example.com/test.iso",
+		Status:      models.StatusDownloading,
+		CreatedAt:   time.Now(),
+	}
+	iso.ComputeFields()
+	database.CreateISO(iso)
+
+	// Unpersisted tick: broadcast only
+	worker.updateProgress(iso.ID, 42, false)
+	stored, _ := database.GetISO(iso.ID)
+	if stored.Progress != 0 {
+		t.Errorf("Progress should not be persisted yet, got: %d", stored.Progress)
+	}
+
+	// Persisted tick: written through
+	worker.updateProgress(iso.ID, 50, true)
+	stored, _ = database.GetISO(iso.ID)
+	if stored.Progress != 50 {
+		t.Errorf("Progress should be persisted as 50, got: %d", stored.Progress)
+	}
+
+	if len(broadcast) != 2 || broadcast[0] != 42 || broadcast[1] != 50 {
+		t.Errorf("Expected both ticks to be broadcast, got: %v", broadcast)
+	}
+}
+
 // TestWorkerChecksumMismatch tests checksum verification failure.
 func TestWorkerChecksumMismatch(t *testing.T) {
 	worker, database, _, cleanup := setupTestWorker(t)
@@ -436,6 +923,244 @@ func TestWorkerChecksumMismatch(t *testing.T) {
 	}
 }
 
+// TestWorkerExpectedChecksum tests verification against a checksum given
+// for the ISO instead of a checksum file.
+func TestWorkerExpectedChecksum(t *testing.T) {
+	worker, database, _, cleanup := setupTestWorker(t)
+	defer cleanup()
+
+	testContent := []byte("test iso content")
+	isoServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(testContent)
+	}))
+	defer isoServer.Close()
+
+	for _, tc := range []struct {
+		version  string
+		checksum string
+		want     models.ISOStatus
+	}{
+		{"1.0", fmt.Sprintf("%X", sha256.Sum256(testContent)), models.StatusComplete},
+		{"2.0", strings.Repeat("0", 64), models.StatusFailed},
+	} {
+		iso := &models.ISO{
+			ID:           uuid.New().String(),
+			Name:         "test",
+			Version:      tc.version,
+			Arch:         "x86_64",
+			FileType:     "iso",
+			DownloadURL:  isoServer.URL + "/test.iso",
+			Checksum:     tc.checksum,
+			ChecksumType: "sha256",
+			Status:       models.StatusPending,
+			CreatedAt:    time.Now(),
+		}
+		iso.ComputeFields()
+		database.CreateISO(iso)
+
+		worker.Process(context.Background(), iso)
+		updated, _ := database.GetISO(iso.ID)
+		if updated.Status != tc.want {
+			t.Errorf("Expected %s for checksum %s, got %s: %s", tc.want, tc.checksum, updated.Status, updated.ErrorMessage)
+		}
+	}
+}
+
+// TestWorkerKnownChecksumFallback tests that a checksum read from a checksum
+// file is kept, and verifies a later download once the file is gone.
+func TestWorkerKnownChecksumFallback(t *testing.T) {
+	worker, database, _, cleanup := setupTestWorker(t)
+	defer cleanup()
+
+	testContent := []byte("test iso content")
+	var checksumGone atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/SHA256SUMS", "/OTHERSUMS":
+			if checksumGone.Load() {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprintf(w, "%x  test.iso\n", sha256.Sum256(testContent))
+		default:
+			w.Write(testContent)
+		}
+	}))
+	defer server.Close()
+
+	process := func(version, checksumURL string) *models.ISO {
+		t.Helper()
+		iso := &models.ISO{
+			ID:           uuid.New().String(),
+			Name:         "test",
+			Version:      version,
+			Arch:         "x86_64",
+			FileType:     "iso",
+			DownloadURL:  server.URL + "/test.iso",
+			ChecksumURL:  server.URL + checksumURL,
+			ChecksumType: "sha256",
+			Status:       models.StatusPending,
+			CreatedAt:    time.Now(),
+		}
+		iso.ComputeFields()
+		database.CreateISO(iso)
+		worker.Process(context.Background(), iso)
+		updated, _ := database.GetISO(iso.ID)
+		return updated
+	}
+
+	if iso := process("1.0", "/SHA256SUMS"); iso.Status != models.StatusComplete {
+		t.Fatalf("Expected complete, got %s: %s", iso.Status, iso.ErrorMessage)
+	}
+	known, err := database.GetKnownChecksum(server.URL+"/SHA256SUMS", "test.iso")
+	if err != nil || known == nil || known.Source != models.KnownChecksumSourceUpstream {
+		t.Fatalf("Expected the fetched checksum to be kept, got %+v (%v)", known, err)
+	}
+
+	checksumGone.Store(true)
+	if iso := process("2.0", "/SHA256SUMS"); iso.Status != models.StatusComplete {
+		t.Errorf("Expected the known checksum to verify, got %s: %s", iso.Status, iso.ErrorMessage)
+	}
+	// Without a known checksum the ISO completes unverified, to be retried
+	if iso := process("3.0", "/OTHERSUMS"); iso.Status != models.StatusComplete || !iso.ChecksumPending || iso.VerifiedAt != nil {
+		t.Errorf("Expected complete with a pending checksum, got %s (pending=%t): %s", iso.Status, iso.ChecksumPending, iso.ErrorMessage)
+	}
+}
+
+// TestWorkerDownloadLog tests that a run records its steps in the ISO's
+// download log, without query strings, and that the next run starts afresh.
+func TestWorkerDownloadLog(t *testing.T) {
+	worker, database, _, cleanup := setupTestWorker(t)
+	defer cleanup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/test.iso":
+			http.Redirect(w, r, "/mirror/test.iso?token=secret", http.StatusFound)
+		case "/test.iso.sha256":
+			fmt.Fprintf(w, "%064d  test.iso\n", 0)
+		default:
+			w.Write([]byte("test iso content"))
+		}
+	}))
+	defer server.Close()
+
+	iso := &models.ISO{
+		ID:           uuid.New().String(),
+		Name:         "test",
+		Version:      "1.0",
+		Arch:         "x86_64",
+		FileType:     "iso",
+		DownloadURL:  server.URL + "/test.iso",
+		ChecksumURL:  server.URL + "/test.iso.sha256",
+		ChecksumType: "sha256",
+		Status:       models.StatusPending,
+		CreatedAt:    time.Now(),
+	}
+	iso.ComputeFields()
+	database.CreateISO(iso)
+
+	for run := 0; run < 2; run++ {
+		if err := worker.Process(context.Background(), iso); err == nil {
+			t.Fatal("Expected checksum verification to fail")
+		}
+
+		entries, err := database.ListDownloadLog(iso.ID)
+		if err != nil {
+			t.Fatalf("ListDownloadLog() failed: %v", err)
+		}
+		var messages []string
+		for _, entry := range entries {
+			messages = append(messages, entry.Level+": "+entry.Message)
+		}
+		log := strings.Join(messages, "\n")
+
+		want := []string{
+			"info: Downloading " + server.URL + "/test.iso",
+			"info: Redirected from " + server.URL + "/test.iso to " + server.URL + "/mirror/test.iso",
+			"info: Downloaded 16 bytes",
+			"info: Verifying sha256 checksum from " + server.URL + "/test.iso.sha256",
+			"error: Failed (verification): checksum mismatch",
+		}
+		if len(entries) != len(want) {
+			t.Fatalf("Run %d: expected %d entries, got:\n%s", run, len(want), log)
+		}
+		for i, prefix := range want {
+			if !strings.HasPrefix(messages[i], prefix) {
+				t.Errorf("Run %d: entry %d = %q, want prefix %q", run, i, messages[i], prefix)
+			}
+		}
+		if strings.Contains(log, "secret") {
+			t.Errorf("Expected query strings left out of the log, got:\n%s", log)
+		}
+	}
+
+	failed, err := database.GetISO(iso.ID)
+	if err != nil {
+		t.Fatalf("GetISO() failed: %v", err)
+	}
+	if failed.ErrorReason != models.ErrorReasonVerification || failed.Verification != models.VerificationStateFailed {
+		t.Errorf("Expected a failed verification, got reason %q and verification %q", failed.ErrorReason, failed.Verification)
+	}
+}
+
+// TestWorkerDownloadSource tests that a completed download records the URL
+// that served it after redirects, its identifying headers, and when it began.
+func TestWorkerDownloadSource(t *testing.T) {
+	worker, database, _, cleanup := setupTestWorker(t)
+	defer cleanup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/test.iso" {
+			http.Redirect(w, r, "/mirror/test.iso?token=secret", http.StatusFound)
+			return
+		}
+		w.Header().Set("Server", "test-mirror")
+		w.Header().Set("X-Cache", "HIT")
+		w.Header().Set("X-Unrelated", "ignored")
+		w.Write([]byte("test iso content"))
+	}))
+	defer server.Close()
+
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "test",
+		Version:     "1.0",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: server.URL + "/test.iso",
+		Status:      models.StatusPending,
+		CreatedAt:   time.Now(),
+	}
+	iso.ComputeFields()
+	database.CreateISO(iso)
+
+	before := time.Now()
+	if err := worker.Process(context.Background(), iso); err != nil {
+		t.Fatalf("Process() failed: %v", err)
+	}
+
+	updated, err := database.GetISO(iso.ID)
+	if err != nil {
+		t.Fatalf("GetISO() failed: %v", err)
+	}
+	if want := server.URL + "/mirror/test.iso"; updated.SourceURL != want {
+		t.Errorf("SourceURL = %q, want %q", updated.SourceURL, want)
+	}
+	if updated.SourceHeaders["Server"] != "test-mirror" || updated.SourceHeaders["X-Cache"] != "HIT" {
+		t.Errorf("Expected Server and X-Cache headers recorded, got %v", updated.SourceHeaders)
+	}
+	if _, ok := updated.SourceHeaders["X-Unrelated"]; ok {
+		t.Errorf("Expected unlisted headers left out, got %v", updated.SourceHeaders)
+	}
+	if updated.DownloadStartedAt == nil || updated.DownloadStartedAt.Before(before.Add(-time.Second)) {
+		t.Errorf("Expected download start recorded, got %v", updated.DownloadStartedAt)
+	}
+	if updated.CompletedAt == nil || updated.CompletedAt.Before(*updated.DownloadStartedAt) {
+		t.Errorf("Expected completion after the download began, got %v", updated.CompletedAt)
+	}
+}
+
 // TestWorkerNestedDirectoryCreation tests that nested directories are created.
 func TestWorkerNestedDirectoryCreation(t *testing.T) {
 	worker, database, isoDir, cleanup := setupTestWorker(t)
@@ -598,3 +1323,78 @@ func TestWorkerDownloadNoContentLength(t *testing.T) {
 		t.Errorf("SizeBytes should be %d (content length), got: %d", len(testContent), updatedISO.SizeBytes)
 	}
 }
+
+// TestWorkerQuarantine tests that a file flagged by clamd is quarantined instead of served.
+func TestWorkerQuarantine(t *testing.T) {
+	_, database, isoDir, cleanup := setupTestWorker(t)
+	defer cleanup()
+
+	// Minimal clamd that flags every stream
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			r := bufio.NewReader(conn)
+			if _, err := r.ReadString(0); err == nil {
+				for {
+					var size uint32
+					if binary.Read(r, binary.BigEndian, &size) != nil || size == 0 {
+						break
+					}
+					io.CopyN(io.Discard, r, int64(size))
+				}
+				conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+			}
+			conn.Close()
+		}
+	}()
+
+	cfg := DefaultConfig()
+	cfg.ClamAVAddress = "tcp://" + ln.Addr().String()
+	cfg.ClamAVTimeout = 5 * time.Second
+	worker := NewWorker(database, isoDir, cfg, nil)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("flagged content"))
+	}))
+	defer server.Close()
+
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        "test",
+		Version:     "1.0",
+		Arch:        "x86_64",
+		FileType:    "iso",
+		DownloadURL: server.URL,
+		Status:      models.StatusPending,
+		CreatedAt:   time.Now(),
+	}
+	iso.ComputeFields()
+	database.CreateISO(iso)
+
+	err = worker.Process(context.Background(), iso)
+	if !errors.Is(err, ErrQuarantined) {
+		t.Fatalf("Expected ErrQuarantined, got: %v", err)
+	}
+
+	updated, _ := database.GetISO(iso.ID)
+	if updated.Status != models.StatusQuarantined {
+		t.Errorf("Status should be 'quarantined', got: %s", updated.Status)
+	}
+	if !strings.Contains(updated.ErrorMessage, "Eicar-Test-Signature") {
+		t.Errorf("Error message should name the signature, got: %s", updated.ErrorMessage)
+	}
+	if _, err := os.Stat(pathutil.ConstructISOPath(isoDir, iso.FilePath)); !os.IsNotExist(err) {
+		t.Error("Flagged file should not be placed in the ISO directory")
+	}
+	if _, err := os.Stat(pathutil.ConstructQuarantinePath(isoDir, iso.FilePath)); err != nil {
+		t.Errorf("Flagged file should be in the quarantine directory: %v", err)
+	}
+}
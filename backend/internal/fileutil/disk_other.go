@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package fileutil
+
+import "errors"
+
+// FreeSpace returns errors.ErrUnsupported; free space isn't queried on this platform.
+func FreeSpace(path string) (int64, error) {
+	return 0, errors.ErrUnsupported
+}
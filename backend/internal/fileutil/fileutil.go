@@ -1,10 +1,16 @@
 package fileutil
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 )
 
 // Returns nil if the file doesn't exist.
@@ -152,3 +158,145 @@ func CleanupEmptyParentDirs(filePath, root string) {
 		dir = filepath.Dir(dir)
 	}
 }
+
+// PruneEmptyDirs removes empty directories under root, deepest first, and returns
+// how many were removed. Root itself and hidden directories (e.g. .tmp) are kept,
+// as are directories modified within minAge, which may be about to receive a file.
+func PruneEmptyDirs(root string, minAge time.Duration) (int, error) {
+	type dirInfo struct {
+		path    string
+		modTime time.Time
+	}
+	var dirs []dirInfo
+
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if !d.IsDir() || p == root {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		// Record mtimes before removing anything, since removing a child touches its parent
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		dirs = append(dirs, dirInfo{path: p, modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to walk %s: %w", root, err)
+	}
+
+	cutoff := time.Now().Add(-minAge)
+	removed := 0
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if dirs[i].modTime.After(cutoff) {
+			continue
+		}
+		// os.Remove fails on non-empty directories, which is what we want
+		if err := os.Remove(dirs[i].path); err == nil {
+			slog.Debug("removed empty directory", slog.String("dir", dirs[i].path))
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+// CopyFile copies the contents of srcPath to a new file at dstPath.
+// Creates parent directories for dstPath if needed.
+func CopyFile(srcPath, dstPath string) error {
+	if err := EnsureParentDirectory(dstPath); err != nil {
+		return err
+	}
+
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to open source file %s: %w", srcPath, err)
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", dstPath, err)
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(dstPath)
+		return fmt.Errorf("failed to copy file from %s to %s: %w", srcPath, dstPath, err)
+	}
+
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		os.Remove(dstPath)
+		return fmt.Errorf("failed to sync file %s: %w", dstPath, err)
+	}
+
+	if err := dst.Close(); err != nil {
+		os.Remove(dstPath)
+		return fmt.Errorf("failed to close file %s: %w", dstPath, err)
+	}
+
+	return nil
+}
+
+// LinkOrCopyFile hard-links srcPath to dstPath, falling back to a copy when
+// the paths are on different filesystems or hard links are unsupported.
+func LinkOrCopyFile(srcPath, dstPath string) error {
+	if err := EnsureParentDirectory(dstPath); err != nil {
+		return err
+	}
+
+	if err := os.Link(srcPath, dstPath); err == nil {
+		return nil
+	}
+
+	return CopyFile(srcPath, dstPath)
+}
+
+// RenameOrCopy renames oldPath to newPath. When the paths are on different
+// filesystems, it copies into a hidden file next to newPath, syncs it, and
+// renames that into place so newPath never holds a partial file.
+func RenameOrCopy(oldPath, newPath string) error {
+	err := os.Rename(oldPath, newPath)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	slog.Debug("rename crosses filesystems, copying instead",
+		slog.String("from", oldPath),
+		slog.String("to", newPath),
+	)
+
+	partial := filepath.Join(filepath.Dir(newPath), "."+filepath.Base(newPath)+".partial")
+	DeleteFileSilently(partial) // Left over from an interrupted copy
+	if err := CopyFile(oldPath, partial); err != nil {
+		return err
+	}
+	if err := os.Rename(partial, newPath); err != nil {
+		os.Remove(partial)
+		return fmt.Errorf("failed to move file from %s to %s: %w", partial, newPath, err)
+	}
+
+	DeleteFileSilently(oldPath)
+	return nil
+}
+
+// SyncDir flushes a directory's entries to disk, so a rename into it survives a crash.
+func SyncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open directory %s: %w", dir, err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync directory %s: %w", dir, err)
+	}
+	return nil
+}
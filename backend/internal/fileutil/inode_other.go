@@ -0,0 +1,10 @@
+//go:build !unix
+
+package fileutil
+
+import "os"
+
+// FileInode returns 0; inode numbers aren't available on this platform.
+func FileInode(fi os.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package fileutil
+
+import (
+	"os"
+	"syscall"
+)
+
+// FileInode returns the inode number of the file described by fi.
+func FileInode(fi os.FileInfo) uint64 {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino) //nolint:unconvert // Ino is uint32 on some platforms
+	}
+	return 0
+}
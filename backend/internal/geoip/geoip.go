@@ -0,0 +1,389 @@
+// Package geoip resolves client IPs to a country from a local MaxMind DB
+// (MMDB) file, such as GeoLite2-Country, and to a site from configured
+// networks.
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// metadataMarker precedes the metadata map at the end of an MMDB file.
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// dataSectionSeparator is the size of the zero gap between the search tree
+// and the data section.
+const dataSectionSeparator = 16
+
+// MMDB data section field types.
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// Reader looks up IPs in an MMDB file held in memory.
+type Reader struct {
+	buf        []byte
+	data       []byte // Data section
+	nodeCount  uint
+	recordSize uint // Bits per record: 24, 28, or 32
+	ipVersion  uint
+	ipv4Start  uint // Node that IPv4 lookups start from in an IPv6 tree
+}
+
+// Open reads an MMDB file into memory.
+func Open(path string) (*Reader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GeoIP database: %w", err)
+	}
+	r, err := newReader(buf)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GeoIP database %s: %w", path, err)
+	}
+	return r, nil
+}
+
+func newReader(buf []byte) (*Reader, error) {
+	start := bytes.LastIndex(buf, metadataMarker)
+	if start < 0 {
+		return nil, errors.New("metadata not found")
+	}
+	meta, _, err := (&decoder{buf: buf[start+len(metadataMarker):]}).decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode metadata: %w", err)
+	}
+	fields, ok := meta.(map[string]any)
+	if !ok {
+		return nil, errors.New("metadata is not a map")
+	}
+
+	r := &Reader{buf: buf}
+	for key, dst := range map[string]*uint{"node_count": &r.nodeCount, "record_size": &r.recordSize, "ip_version": &r.ipVersion} {
+		v, ok := fields[key].(uint64)
+		if !ok {
+			return nil, fmt.Errorf("metadata has no %s", key)
+		}
+		*dst = uint(v)
+	}
+	switch r.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("unsupported record size %d", r.recordSize)
+	}
+	if r.ipVersion != 4 && r.ipVersion != 6 {
+		return nil, fmt.Errorf("unsupported IP version %d", r.ipVersion)
+	}
+
+	treeSize := r.nodeCount * r.recordSize / 4
+	if treeSize+dataSectionSeparator > uint(start) {
+		return nil, errors.New("search tree is larger than the file")
+	}
+	r.data = buf[treeSize+dataSectionSeparator : start]
+
+	// IPv4 addresses live under ::/96 in IPv6 trees
+	if r.ipVersion == 6 {
+		for i := 0; i < 96 && r.ipv4Start < r.nodeCount; i++ {
+			r.ipv4Start = r.record(r.ipv4Start, 0)
+		}
+	}
+	return r, nil
+}
+
+// Lookup returns the record for ip, or nil when the database has none.
+func (r *Reader) Lookup(ip netip.Addr) (any, error) {
+	ip = ip.Unmap()
+	node, bits := uint(0), ip.AsSlice()
+	if ip.Is4() && r.ipVersion == 6 {
+		node = r.ipv4Start
+	} else if ip.Is6() && r.ipVersion == 4 {
+		return nil, nil
+	}
+
+	for i := 0; i < len(bits)*8 && node < r.nodeCount; i++ {
+		bit := uint(bits[i/8]>>(7-i%8)) & 1
+		node = r.record(node, bit)
+	}
+	if node <= r.nodeCount {
+		return nil, nil
+	}
+	if node < r.nodeCount+dataSectionSeparator {
+		return nil, fmt.Errorf("invalid record %d in search tree", node)
+	}
+
+	offset := node - r.nodeCount - dataSectionSeparator
+	value, _, err := (&decoder{buf: r.data}).decode(offset)
+	return value, err
+}
+
+// Country returns the ISO 3166 code of the country ip is in, falling back to
+// the country it is registered to, or "" when the database doesn't know.
+func (r *Reader) Country(ip netip.Addr) (string, error) {
+	record, err := r.Lookup(ip)
+	if err != nil {
+		return "", err
+	}
+	fields, _ := record.(map[string]any)
+	for _, key := range []string{"country", "registered_country"} {
+		country, _ := fields[key].(map[string]any)
+		if code, _ := country["iso_code"].(string); code != "" {
+			return code, nil
+		}
+	}
+	return "", nil
+}
+
+// record returns the left (bit 0) or right (bit 1) record of a search tree node.
+func (r *Reader) record(node, bit uint) uint {
+	switch r.recordSize {
+	case 24:
+		b := r.buf[node*6+bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		b := r.buf[node*7:]
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(r.buf[node*8+bit*4:]))
+	}
+}
+
+// decoder decodes values of an MMDB data section. Pointers are offsets into buf.
+type decoder struct {
+	buf []byte
+}
+
+// decode returns the value at offset and the offset right after it.
+func (d *decoder) decode(offset uint) (any, uint, error) {
+	kind, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch kind {
+	case typePointer:
+		value, _, err := d.decode(size)
+		return value, offset, err
+	case typeBool:
+		return size != 0, offset, nil
+	case typeMap:
+		m := make(map[string]any, size)
+		for range size {
+			key, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, 0, errors.New("map key is not a string")
+			}
+			if m[name], offset, err = d.decode(next); err != nil {
+				return nil, 0, err
+			}
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]any, 0, size)
+		for range size {
+			var value any
+			if value, offset, err = d.decode(offset); err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+		}
+		return a, offset, nil
+	}
+
+	// Everything else is size bytes of payload
+	end := offset + size
+	if end > uint(len(d.buf)) {
+		return nil, 0, errors.New("value runs past the end of the data section")
+	}
+	b := d.buf[offset:end]
+	switch kind {
+	case typeString:
+		return string(b), end, nil
+	case typeBytes:
+		return append([]byte(nil), b...), end, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("invalid double size %d", size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), end, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("invalid float size %d", size)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), end, nil
+	case typeUint16, typeUint32, typeUint64, typeUint128:
+		var v uint64
+		for _, c := range b {
+			v = v<<8 | uint64(c) // uint128 values keep only their low 64 bits
+		}
+		return v, end, nil
+	case typeInt32:
+		var v uint32
+		for _, c := range b {
+			v = v<<8 | uint32(c)
+		}
+		return int64(int32(v)), end, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported data type %d", kind)
+}
+
+// control reads the control byte(s) at offset and returns the field type, its
+// size (or pointer target), and the offset of the field's payload.
+func (d *decoder) control(offset uint) (int, uint, uint, error) {
+	next := func(n uint) ([]byte, error) {
+		if offset+n > uint(len(d.buf)) {
+			return nil, errors.New("unexpected end of data section")
+		}
+		b := d.buf[offset : offset+n]
+		offset += n
+		return b, nil
+	}
+
+	b, err := next(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	ctrl := b[0]
+	kind := int(ctrl >> 5)
+
+	if kind == typePointer {
+		n := uint(ctrl>>3&0x3) + 1
+		b, err := next(n)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		var p uint
+		if n < 4 {
+			p = uint(ctrl & 0x7)
+		}
+		for _, c := range b {
+			p = p<<8 | uint(c)
+		}
+		p += [...]uint{0, 2048, 526336, 0}[n-1]
+		return kind, p, offset, nil
+	}
+
+	if kind == typeExtended {
+		b, err := next(1)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		kind = 7 + int(b[0])
+	}
+
+	size := uint(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		b, err := next(n)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		var extra uint
+		for _, c := range b {
+			extra = extra<<8 | uint(c)
+		}
+		size = [...]uint{29, 285, 65821}[n-1] + extra
+	}
+	return kind, size, offset, nil
+}
+
+// Site is a named group of networks, e.g. an office sharing the mirror.
+type Site struct {
+	Name     string
+	Prefixes []netip.Prefix
+}
+
+// ParseSites parses "name=cidr" entries; a name may repeat to cover several networks.
+func ParseSites(entries []string) ([]Site, error) {
+	var sites []Site
+	index := make(map[string]int)
+	for _, entry := range entries {
+		name, cidr, found := strings.Cut(entry, "=")
+		name, cidr = strings.TrimSpace(name), strings.TrimSpace(cidr)
+		if !found || name == "" {
+			return nil, fmt.Errorf("site %q must be name=cidr", entry)
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("site %q has an invalid network: %w", name, err)
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(sites)
+			index[name] = i
+			sites = append(sites, Site{Name: name})
+		}
+		sites[i].Prefixes = append(sites[i].Prefixes, prefix.Masked())
+	}
+	return sites, nil
+}
+
+// Location is where a client is, as far as the configured sources know.
+type Location struct {
+	Country string // ISO 3166 code; empty when unknown
+	Site    string // Empty when the client is in no configured site
+}
+
+// Locator resolves client IPs to a Location. Either source may be absent.
+type Locator struct {
+	reader *Reader
+	sites  []Site
+}
+
+// NewLocator creates a Locator from an optional MMDB reader and sites.
+func NewLocator(reader *Reader, sites []Site) *Locator {
+	return &Locator{reader: reader, sites: sites}
+}
+
+// Locate resolves a client IP. Sites are matched in order, so the first site
+// containing the IP wins. Unparsable IPs resolve to an empty Location.
+func (l *Locator) Locate(clientIP string) Location {
+	var loc Location
+	ip, err := netip.ParseAddr(clientIP)
+	if err != nil {
+		return loc
+	}
+	ip = ip.Unmap()
+
+	for _, site := range l.sites {
+		for _, prefix := range site.Prefixes {
+			if prefix.Contains(ip) {
+				loc.Site = site.Name
+				break
+			}
+		}
+		if loc.Site != "" {
+			break
+		}
+	}
+	if l.reader != nil {
+		loc.Country, _ = l.reader.Country(ip)
+	}
+	return loc
+}
@@ -0,0 +1,162 @@
+package geoip
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// encode writes a value in the MMDB data section format. Only the types the
+// tests need are supported, all of which fit a one-byte control field.
+func encode(v any) []byte {
+	control := func(kind, size int) []byte {
+		return []byte{byte(kind<<5 | size)}
+	}
+	switch v := v.(type) {
+	case string:
+		return append(control(typeString, len(v)), v...)
+	case uint32:
+		return append(control(typeUint32, 4), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	case uint16:
+		return append(control(typeUint16, 2), byte(v>>8), byte(v))
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		out := control(typeMap, len(v))
+		for _, key := range keys {
+			out = append(out, encode(key)...)
+			out = append(out, encode(v[key])...)
+		}
+		return out
+	}
+	panic("unsupported type")
+}
+
+// buildDatabase writes an IPv4 MMDB with 24-bit records where prefix maps to
+// record and everything else is unknown.
+func buildDatabase(t *testing.T, prefix netip.Prefix, record map[string]any) string {
+	t.Helper()
+	nodeCount := prefix.Bits()
+	bits := prefix.Addr().As4()
+
+	var tree []byte
+	for i := range nodeCount {
+		match := uint32(i + 1)
+		if i == nodeCount-1 {
+			match = uint32(nodeCount + dataSectionSeparator) // Data at offset 0
+		}
+		left, right := match, uint32(nodeCount)
+		if bits[i/8]>>(7-i%8)&1 == 1 {
+			left, right = right, left
+		}
+		tree = append(tree, byte(left>>16), byte(left>>8), byte(left), byte(right>>16), byte(right>>8), byte(right))
+	}
+
+	buf := append(tree, make([]byte, dataSectionSeparator)...)
+	buf = append(buf, encode(record)...)
+	buf = append(buf, metadataMarker...)
+	buf = append(buf, encode(map[string]any{
+		"node_count":    uint32(nodeCount),
+		"record_size":   uint16(24),
+		"ip_version":    uint16(4),
+		"database_type": "Test-Country",
+	})...)
+
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	if err := os.WriteFile(path, buf, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReaderCountry(t *testing.T) {
+	path := buildDatabase(t, netip.MustParsePrefix("192.0.2.0/24"), map[string]any{
+		"country": map[string]any{"iso_code": "DE"},
+	})
+	reader, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"192.0.2.1", "DE"},
+		{"192.0.2.255", "DE"},
+		{"::ffff:192.0.2.7", "DE"},
+		{"192.0.3.1", ""},
+		{"10.0.0.1", ""},
+		{"2001:db8::1", ""}, // IPv6 isn't in an IPv4 database
+	}
+	for _, tt := range tests {
+		got, err := reader.Country(netip.MustParseAddr(tt.ip))
+		if err != nil || got != tt.want {
+			t.Errorf("Country(%s) = %q, %v; want %q", tt.ip, got, err, tt.want)
+		}
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bogus.mmdb")
+	os.WriteFile(path, []byte("not a database"), 0o644)
+	if _, err := Open(path); err == nil {
+		t.Error("Expected an error for a file without metadata")
+	}
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.mmdb")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestParseSites(t *testing.T) {
+	sites, err := ParseSites([]string{"hq=10.0.0.0/16", "lab=10.0.5.0/24", "hq=2001:db8::/32"})
+	if err != nil {
+		t.Fatalf("ParseSites() failed: %v", err)
+	}
+	if len(sites) != 2 || sites[0].Name != "hq" || len(sites[0].Prefixes) != 2 {
+		t.Errorf("Expected hq with 2 networks and lab, got %+v", sites)
+	}
+
+	for _, entry := range []string{"10.0.0.0/8", "=10.0.0.0/8", "hq=10.0.0.300/8"} {
+		if _, err := ParseSites([]string{entry}); err == nil {
+			t.Errorf("ParseSites(%q) should fail", entry)
+		}
+	}
+}
+
+func TestLocatorLocate(t *testing.T) {
+	reader, err := Open(buildDatabase(t, netip.MustParsePrefix("192.0.2.0/24"), map[string]any{
+		"registered_country": map[string]any{"iso_code": "FR"},
+	}))
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	sites, _ := ParseSites([]string{"hq=10.0.0.0/16", "campus=10.0.0.0/8", "partner=192.0.2.128/25"})
+	locator := NewLocator(reader, sites)
+
+	tests := []struct {
+		ip   string
+		want Location
+	}{
+		{"10.0.1.1", Location{Site: "hq"}},     // First matching site wins
+		{"10.9.0.1", Location{Site: "campus"}}, // Private IPs have no country
+		{"192.0.2.200", Location{Country: "FR", Site: "partner"}},
+		{"192.0.2.1", Location{Country: "FR"}},
+		{"203.0.113.1", Location{}},
+		{"not-an-ip", Location{}},
+	}
+	for _, tt := range tests {
+		if got := locator.Locate(tt.ip); got != tt.want {
+			t.Errorf("Locate(%s) = %+v, want %+v", tt.ip, got, tt.want)
+		}
+	}
+
+	if got := NewLocator(nil, sites).Locate("192.0.2.1"); got != (Location{}) {
+		t.Errorf("Expected no country without a database, got %+v", got)
+	}
+}
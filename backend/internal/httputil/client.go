@@ -0,0 +1,355 @@
+package httputil
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/config"
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/validation"
+)
+
+// ErrBlockedAddress is returned when a request would connect to a loopback,
+// private, link-local, or reserved address that isn't explicitly allowed.
+var ErrBlockedAddress = errors.New("connection to private or reserved address blocked")
+
+// ClientConfig holds tuning options for the shared upstream HTTP client.
+type ClientConfig struct {
+	ConnectTimeout        time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	IdleConnTimeout       time.Duration
+	KeepAlive             time.Duration
+	DNSCacheTTL           time.Duration // 0 disables the DNS cache
+	MaxIdleConns          int
+	MaxIdleConnsPerHost   int
+	EnableHTTP2           bool
+	IPFamily              string // any, ipv4, ipv6
+	BlockPrivateNetworks  bool   // Refuse to dial non-public addresses
+	AllowedNetworks       []netip.Prefix
+	MinHostInterval       time.Duration // Minimum time between requests to the same host; 0 disables
+	RetryAfterMax         time.Duration // Longest Retry-After waited for on 429 and 503; 0 disables
+
+	// Transport, when set, carries every request instead of a dialing
+	// transport built from the settings above, e.g. to route upstream
+	// requests to a fake mirror in tests. Redirect handling still applies.
+	Transport http.RoundTripper
+}
+
+// DefaultClientConfig returns the client settings used when none are configured.
+// It doesn't block private networks; NewClientConfig does unless disabled.
+func DefaultClientConfig() ClientConfig {
+	return ClientConfig{
+		ConnectTimeout:        constants.DefaultHTTPConnectTimeoutSec * time.Second,
+		TLSHandshakeTimeout:   constants.DefaultHTTPTLSHandshakeTimeoutSec * time.Second,
+		ResponseHeaderTimeout: constants.DefaultHTTPResponseHeaderTimeoutSec * time.Second,
+		IdleConnTimeout:       constants.DefaultHTTPIdleConnTimeoutSec * time.Second,
+		KeepAlive:             30 * time.Second,
+		MaxIdleConns:          constants.DefaultHTTPMaxIdleConns,
+		MaxIdleConnsPerHost:   constants.DefaultHTTPMaxIdleConnsPerHost,
+		EnableHTTP2:           true,
+		IPFamily:              constants.IPFamilyAny,
+		RetryAfterMax:         constants.DefaultHTTPRetryAfterMaxSec * time.Second,
+	}
+}
+
+// NewClientConfig builds client settings from the download configuration.
+func NewClientConfig(cfg *config.DownloadConfig) ClientConfig {
+	allowed, err := validation.ParseNetworks(cfg.HTTPAllowedNetworks)
+	if err != nil {
+		slog.Warn("ignoring invalid HTTP_ALLOWED_NETWORKS entries", slog.Any("error", err))
+	}
+
+	return ClientConfig{
+		ConnectTimeout:        cfg.HTTPConnectTimeout,
+		TLSHandshakeTimeout:   cfg.HTTPTLSHandshakeTimeout,
+		ResponseHeaderTimeout: cfg.HTTPResponseHeaderTimeout,
+		IdleConnTimeout:       cfg.HTTPIdleConnTimeout,
+		KeepAlive:             30 * time.Second,
+		DNSCacheTTL:           cfg.DNSCacheTTL,
+		MaxIdleConns:          cfg.HTTPMaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.HTTPMaxIdleConnsPerHost,
+		EnableHTTP2:           cfg.HTTPEnableHTTP2,
+		IPFamily:              cfg.HTTPIPFamily,
+		BlockPrivateNetworks:  cfg.HTTPBlockPrivateNetworks,
+		AllowedNetworks:       allowed,
+		MinHostInterval:       cfg.HTTPMinHostInterval,
+		RetryAfterMax:         cfg.HTTPRetryAfterMax,
+	}
+}
+
+var (
+	clientMu      sync.RWMutex
+	sharedClients = newClientSet(DefaultClientConfig())
+)
+
+// Configure replaces the shared clients used for all upstream requests.
+// Idle connections held by the previous clients are closed.
+func Configure(cfg ClientConfig) {
+	clients := newClientSet(cfg)
+
+	clientMu.Lock()
+	previous := sharedClients
+	sharedClients = clients
+	clientMu.Unlock()
+
+	previous.closeIdleConnections()
+}
+
+// Client returns the shared HTTP client for the configured default IP family.
+func Client() *http.Client {
+	return ClientFor("")
+}
+
+// ClientFor returns the shared HTTP client restricted to the given IP family.
+// An empty or unknown family selects the configured default.
+func ClientFor(family string) *http.Client {
+	clientMu.RLock()
+	defer clientMu.RUnlock()
+	return sharedClients.client(family)
+}
+
+type ipFamilyKey struct{}
+
+// WithIPFamily returns a context that pins upstream requests made with it to the given IP family.
+func WithIPFamily(ctx context.Context, family string) context.Context {
+	return context.WithValue(ctx, ipFamilyKey{}, family)
+}
+
+// clientFromContext returns the shared client for the IP family carried by ctx.
+func clientFromContext(ctx context.Context) *http.Client {
+	family, _ := ctx.Value(ipFamilyKey{}).(string)
+	return ClientFor(family)
+}
+
+// clientSet holds one pooled client per IP family so that connections dialed
+// for one family are never reused for a request pinned to another. The
+// clients share one pacer, so a host is paced whichever family reaches it.
+type clientSet struct {
+	byFamily      map[string]*http.Client
+	defaultFamily string
+}
+
+func newClientSet(cfg ClientConfig) *clientSet {
+	set := &clientSet{
+		byFamily:      make(map[string]*http.Client, len(constants.IPFamilies)),
+		defaultFamily: strings.ToLower(cfg.IPFamily),
+	}
+	pacer := newHostPacer(cfg.MinHostInterval, cfg.RetryAfterMax)
+	for _, family := range constants.IPFamilies {
+		familyCfg := cfg
+		familyCfg.IPFamily = family
+		set.byFamily[family] = newClient(familyCfg, pacer)
+	}
+	if _, ok := set.byFamily[set.defaultFamily]; !ok {
+		set.defaultFamily = constants.IPFamilyAny
+	}
+	return set
+}
+
+func (s *clientSet) client(family string) *http.Client {
+	if client, ok := s.byFamily[strings.ToLower(family)]; ok {
+		return client
+	}
+	return s.byFamily[s.defaultFamily]
+}
+
+func (s *clientSet) closeIdleConnections() {
+	for _, client := range s.byFamily {
+		client.CloseIdleConnections()
+	}
+}
+
+// NewClient creates an HTTP client with a pooled, tuned transport.
+// No overall client timeout is set because ISO transfers can run for a long time;
+// callers bound requests with their context instead.
+func NewClient(cfg ClientConfig) *http.Client {
+	return newClient(cfg, newHostPacer(cfg.MinHostInterval, cfg.RetryAfterMax))
+}
+
+// newClient creates a client whose requests pacer paces; nil leaves them unpaced.
+func newClient(cfg ClientConfig, pacer *hostPacer) *http.Client {
+	if cfg.Transport != nil {
+		return &http.Client{Transport: pacer.wrap(cfg.Transport), CheckRedirect: checkRedirect}
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   cfg.ConnectTimeout,
+		KeepAlive: cfg.KeepAlive,
+	}
+	if cfg.BlockPrivateNetworks {
+		// Control sees the address actually being dialed, after DNS resolution,
+		// so neither redirects nor rebinding can reach an internal service
+		dialer.Control = blockPrivateAddresses(cfg.AllowedNetworks)
+	}
+
+	dialContext := dialer.DialContext
+	if cfg.DNSCacheTTL > 0 {
+		dialContext = newDNSCache(cfg.DNSCacheTTL, net.DefaultResolver).wrapDialer(dialer.DialContext)
+	}
+	if network := familyNetwork(cfg.IPFamily); network != "" {
+		// Pin every dial to one address family instead of letting happy eyeballs pick
+		dial := dialContext
+		dialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return dial(ctx, network, addr)
+		}
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialContext,
+		ForceAttemptHTTP2:     cfg.EnableHTTP2,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+	if !cfg.EnableHTTP2 {
+		// A non-nil empty map disables the transport's automatic HTTP/2 upgrade
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return &http.Client{Transport: pacer.wrap(transport), CheckRedirect: checkRedirect}
+}
+
+// maxRedirects matches the default policy of net/http.
+const maxRedirects = 10
+
+type redirectHookKey struct{}
+
+// WithRedirectHook returns a context whose upstream requests call hook with
+// each redirect they follow, e.g. to note which mirror a redirector picked.
+func WithRedirectHook(ctx context.Context, hook func(from, to *url.URL)) context.Context {
+	return context.WithValue(ctx, redirectHookKey{}, hook)
+}
+
+// checkRedirect follows up to maxRedirects redirects, reporting each to the
+// hook carried by the request's context, if any.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if hook, ok := req.Context().Value(redirectHookKey{}).(func(from, to *url.URL)); ok && hook != nil {
+		hook(via[len(via)-1].URL, req.URL)
+	}
+	return nil
+}
+
+// blockPrivateAddresses returns a dialer control function that rejects
+// addresses validation.IsBlockedIP flags unless they fall inside allowed.
+func blockPrivateAddresses(allowed []netip.Prefix) func(network, address string, c syscall.RawConn) error {
+	return func(_, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+		}
+		if validation.IsBlockedIP(ip) && !validation.InNetworks(ip, allowed) {
+			return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+		}
+		return nil
+	}
+}
+
+// familyNetwork maps an IP family preference to a dial network, or "" for no restriction.
+func familyNetwork(family string) string {
+	switch strings.ToLower(family) {
+	case constants.IPFamilyIPv4:
+		return "tcp4"
+	case constants.IPFamilyIPv6:
+		return "tcp6"
+	}
+	return ""
+}
+
+// dnsCache caches resolved host addresses for a fixed TTL.
+type dnsCache struct {
+	resolver *net.Resolver
+	entries  map[string]dnsCacheEntry
+	ttl      time.Duration
+	mu       sync.Mutex
+}
+
+type dnsCacheEntry struct {
+	expires time.Time
+	addrs   []string
+}
+
+func newDNSCache(ttl time.Duration, resolver *net.Resolver) *dnsCache {
+	return &dnsCache{
+		resolver: resolver,
+		entries:  make(map[string]dnsCacheEntry),
+		ttl:      ttl,
+	}
+}
+
+// lookup returns cached addresses for host, resolving them when missing or
+// expired. Storing a result sweeps out every expired entry, so hosts that are
+// never looked up again don't pile up.
+func (c *dnsCache) lookup(ctx context.Context, host string) ([]string, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	addrs, err := c.resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	for cached, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, cached)
+		}
+	}
+	c.entries[host] = dnsCacheEntry{addrs: addrs, expires: now.Add(c.ttl)}
+	c.mu.Unlock()
+
+	return addrs, nil
+}
+
+// wrapDialer returns a dial function that resolves hosts through the cache
+// and tries each cached address in order.
+func (c *dnsCache) wrapDialer(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+
+		addrs, err := c.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		var lastErr error
+		for _, ip := range addrs {
+			conn, err := dial(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
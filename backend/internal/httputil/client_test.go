@@ -0,0 +1,219 @@
+package httputil
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDNSCacheReusesEntries(t *testing.T) {
+	cache := newDNSCache(time.Minute, net.DefaultResolver)
+	cache.entries["mirror.example"] = dnsCacheEntry{
+		addrs:   []string{"192.0.2.10"},
+		expires: time.Now().Add(time.Minute),
+	}
+
+	var dialed []string
+	dial := cache.wrapDialer(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		return nil, &net.OpError{Op: "dial", Net: network, Err: net.UnknownNetworkError("test")}
+	})
+
+	_, _ = dial(context.Background(), "tcp", "mirror.example:443")
+
+	if len(dialed) != 1 || dialed[0] != "192.0.2.10:443" {
+		t.Errorf("Expected cached address to be dialed, got: %v", dialed)
+	}
+}
+
+func TestDNSCachePrunesExpiredEntries(t *testing.T) {
+	cache := newDNSCache(time.Minute, net.DefaultResolver)
+	cache.entries["gone.example"] = dnsCacheEntry{
+		addrs:   []string{"192.0.2.10"},
+		expires: time.Now().Add(-time.Second),
+	}
+	cache.entries["mirror.example"] = dnsCacheEntry{
+		addrs:   []string{"192.0.2.20"},
+		expires: time.Now().Add(time.Minute),
+	}
+
+	if _, err := cache.lookup(context.Background(), "localhost"); err != nil {
+		t.Fatalf("Failed to resolve localhost: %v", err)
+	}
+
+	if _, ok := cache.entries["gone.example"]; ok {
+		t.Error("Expected the expired entry to be pruned")
+	}
+	if _, ok := cache.entries["mirror.example"]; !ok {
+		t.Error("Expected the live entry to be kept")
+	}
+	if _, ok := cache.entries["localhost"]; !ok {
+		t.Error("Expected the resolved host to be cached")
+	}
+}
+
+func TestDNSCacheSkipsLiteralIPs(t *testing.T) {
+	cache := newDNSCache(time.Minute, net.DefaultResolver)
+
+	var dialed string
+	dial := cache.wrapDialer(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = addr
+		return nil, net.UnknownNetworkError("test")
+	})
+
+	_, _ = dial(context.Background(), "tcp", "127.0.0.1:8080")
+
+	if dialed != "127.0.0.1:8080" {
+		t.Errorf("Expected literal address to be dialed unchanged, got: %s", dialed)
+	}
+	if len(cache.entries) != 0 {
+		t.Errorf("Expected no cache entries for literal IPs, got: %d", len(cache.entries))
+	}
+}
+
+func TestConfigureReplacesSharedClient(t *testing.T) {
+	original := Client()
+	defer Configure(DefaultClientConfig())
+
+	cfg := DefaultClientConfig()
+	cfg.EnableHTTP2 = false
+	Configure(cfg)
+
+	if Client() == original {
+		t.Error("Expected Configure to replace the shared client")
+	}
+}
+
+func TestConfigureTransport(t *testing.T) {
+	defer Configure(DefaultClientConfig())
+
+	var requested string
+	cfg := DefaultClientConfig()
+	cfg.BlockPrivateNetworks = true
+	cfg.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requested = req.URL.String()
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+	Configure(cfg)
+
+	if _, err := FetchBytes(context.Background(), "https://mirror.example/test.iso.sha256"); err != nil {
+		t.Fatalf("FetchBytes() failed: %v", err)
+	}
+	if requested != "https://mirror.example/test.iso.sha256" {
+		t.Errorf("Expected the request to go through the transport, got %q", requested)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestFamilyNetwork(t *testing.T) {
+	tests := map[string]string{
+		"any":  "",
+		"":     "",
+		"ipv4": "tcp4",
+		"IPv6": "tcp6",
+	}
+	for family, want := range tests {
+		if got := familyNetwork(family); got != want {
+			t.Errorf("familyNetwork(%q) = %q, want %q", family, got, want)
+		}
+	}
+}
+
+func TestClientForFamily(t *testing.T) {
+	defer Configure(DefaultClientConfig())
+
+	cfg := DefaultClientConfig()
+	cfg.IPFamily = "ipv4"
+	Configure(cfg)
+
+	if Client() != ClientFor("ipv4") {
+		t.Error("Expected default client to use the configured IP family")
+	}
+	if ClientFor("ipv6") == ClientFor("ipv4") {
+		t.Error("Expected separate clients per IP family")
+	}
+	if clientFromContext(WithIPFamily(context.Background(), "ipv6")) != ClientFor("ipv6") {
+		t.Error("Expected context IP family to select the matching client")
+	}
+	if clientFromContext(context.Background()) != Client() {
+		t.Error("Expected context without IP family to use the default client")
+	}
+}
+
+func TestRedirectHook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hops, _ := strconv.Atoi(r.URL.Query().Get("hops"))
+		if hops > 0 {
+			http.Redirect(w, r, "/?hops="+strconv.Itoa(hops-1), http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	var redirects []string
+	ctx := WithRedirectHook(context.Background(), func(from, to *url.URL) {
+		redirects = append(redirects, from.RawQuery+">"+to.RawQuery)
+	})
+	if _, err := FetchBytes(ctx, server.URL+"/?hops=2"); err != nil {
+		t.Fatalf("FetchBytes() failed: %v", err)
+	}
+	if len(redirects) != 2 || redirects[0] != "hops=2>hops=1" || redirects[1] != "hops=1>hops=0" {
+		t.Errorf("Expected both redirects reported, got %v", redirects)
+	}
+
+	if _, err := FetchBytes(context.Background(), server.URL+"/?hops=11"); err == nil {
+		t.Error("Expected more than 10 redirects to fail")
+	}
+}
+
+func TestBlockPrivateNetworks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := DefaultClientConfig()
+	cfg.BlockPrivateNetworks = true
+
+	_, err := NewClient(cfg).Get(server.URL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("Expected loopback dial to be blocked, got: %v", err)
+	}
+
+	cfg.AllowedNetworks = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+	resp, err := NewClient(cfg).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected allowed network to be dialed, got: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestBlockPrivateAddresses(t *testing.T) {
+	control := blockPrivateAddresses([]netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")})
+
+	tests := map[string]bool{
+		"93.184.216.34:443":     false,
+		"169.254.169.254:80":    true,
+		"[fd00::1]:443":         true,
+		"10.1.2.3:8080":         false,
+		"10.2.0.1:8080":         true,
+		"[::ffff:127.0.0.1]:80": true,
+	}
+	for address, blocked := range tests {
+		err := control("tcp", address, nil)
+		if got := errors.Is(err, ErrBlockedAddress); got != blocked {
+			t.Errorf("control(%q) blocked = %v, want %v (err: %v)", address, got, blocked, err)
+		}
+	}
+}
@@ -6,16 +6,18 @@ import (
 	"io"
 	"net/http"
 	"os"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
 )
 
 // FetchContent fetches content from a URL and returns it as a reader.
 func FetchContent(ctx context.Context, url string) (io.ReadCloser, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, http.NoBody)
+	req, err := newRequest(ctx, http.MethodGet, url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := clientFromContext(ctx).Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
@@ -47,13 +49,13 @@ func FetchBytes(ctx context.Context, url string) ([]byte, error) {
 // DownloadFile downloads a file from a URL to a destination path.
 func DownloadFile(ctx context.Context, url, destPath string) error {
 	// Create request
-	req, err := http.NewRequestWithContext(ctx, "GET", url, http.NoBody)
+	req, err := newRequest(ctx, http.MethodGet, url)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return err
 	}
 
 	// Perform request
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := clientFromContext(ctx).Do(req)
 	if err != nil {
 		return fmt.Errorf("request failed: %w", err)
 	}
@@ -79,33 +81,104 @@ func DownloadFile(ctx context.Context, url, destPath string) error {
 	return nil
 }
 
-// The progress callback is called with (bytesDownloaded, totalBytes).
+// Validators are the upstream response headers used to detect whether a file
+// has been republished in place since it was downloaded, along with where the
+// response came from.
+type Validators struct {
+	ETag          string
+	LastModified  string
+	FinalURL      string            // URL that answered, after redirects
+	Headers       map[string]string // constants.ProvenanceHeaders the response carried
+	ContentLength int64             // -1 when unknown
+}
+
+// Differs reports whether the upstream file appears to have changed compared to
+// the stored validators. The strongest validator both sides have wins: ETag,
+// then Last-Modified, then size. Without a common validator it reports false.
+func (v *Validators) Differs(etag, lastModified string, size int64) bool {
+	switch {
+	case v.ETag != "" && etag != "":
+		return v.ETag != etag
+	case v.LastModified != "" && lastModified != "":
+		return v.LastModified != lastModified
+	case v.ContentLength > 0 && size > 0:
+		return v.ContentLength != size
+	}
+	return false
+}
+
+// validatorsFromResponse extracts the change-detection and provenance headers
+// from a response.
+func validatorsFromResponse(resp *http.Response) *Validators {
+	v := &Validators{
+		ETag:          resp.Header.Get("ETag"),
+		LastModified:  resp.Header.Get("Last-Modified"),
+		ContentLength: resp.ContentLength,
+	}
+	if resp.Request != nil && resp.Request.URL != nil {
+		v.FinalURL = resp.Request.URL.String()
+	}
+	for _, name := range constants.ProvenanceHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			if v.Headers == nil {
+				v.Headers = make(map[string]string)
+			}
+			v.Headers[name] = value
+		}
+	}
+	return v
+}
+
+// HeadValidators issues a HEAD request and returns the upstream validators.
+func HeadValidators(ctx context.Context, url string) (*Validators, error) {
+	req, err := newRequest(ctx, http.MethodHead, url)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := clientFromContext(ctx).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %s", resp.Status)
+	}
+
+	return validatorsFromResponse(resp), nil
+}
+
+// The progress callback is called with (bytesDownloaded, totalBytes) after every chunk.
+// totalBytes is -1 when the server did not send a Content-Length.
 type ProgressCallback func(downloaded, total int64)
 
 // DownloadFileWithProgress downloads a file and reports progress.
-func DownloadFileWithProgress(ctx context.Context, url, destPath string, bufferSize int, onProgress ProgressCallback) error {
+// Every chunk is also written to tee when it is non-nil, e.g. to hash the file while streaming.
+// On success it returns the response's validators for later change detection.
+func DownloadFileWithProgress(ctx context.Context, url, destPath string, bufferSize int, tee io.Writer, onProgress ProgressCallback) (*Validators, error) {
 	// Create request
-	req, err := http.NewRequestWithContext(ctx, "GET", url, http.NoBody)
+	req, err := newRequest(ctx, http.MethodGet, url)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
 	// Perform request
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := clientFromContext(ctx).Do(req)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return nil, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Check status
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("server returned %s", resp.Status)
+		return nil, fmt.Errorf("server returned %s", resp.Status)
 	}
 
 	// Create destination file
 	file, err := os.Create(destPath)
 	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+		return nil, fmt.Errorf("failed to create file: %w", err)
 	}
 	defer file.Close()
 
@@ -121,7 +194,7 @@ func DownloadFileWithProgress(ctx context.Context, url, destPath string, bufferS
 		// Check for cancellation
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			return nil, ctx.Err()
 		default:
 		}
 
@@ -130,12 +203,17 @@ func DownloadFileWithProgress(ctx context.Context, url, destPath string, bufferS
 		if n > 0 {
 			// Write to file
 			if _, writeErr := file.Write(buf[:n]); writeErr != nil {
-				return fmt.Errorf("failed to write to file: %w", writeErr)
+				return nil, fmt.Errorf("failed to write to file: %w", writeErr)
+			}
+			if tee != nil {
+				if _, writeErr := tee.Write(buf[:n]); writeErr != nil {
+					return nil, fmt.Errorf("failed to write to tee: %w", writeErr)
+				}
 			}
 
 			// Update progress
 			downloaded += int64(n)
-			if onProgress != nil && totalSize > 0 {
+			if onProgress != nil {
 				onProgress(downloaded, totalSize)
 			}
 		}
@@ -144,9 +222,14 @@ func DownloadFileWithProgress(ctx context.Context, url, destPath string, bufferS
 			break
 		}
 		if err != nil {
-			return fmt.Errorf("failed to read response: %w", err)
+			return nil, fmt.Errorf("failed to read response: %w", err)
 		}
 	}
 
-	return nil
+	// Flush to disk so a crash after the file is finalized can't leave it truncated
+	if err := file.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to sync file: %w", err)
+	}
+
+	return validatorsFromResponse(resp), nil
 }
@@ -0,0 +1,79 @@
+package httputil
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidatorsDiffers(t *testing.T) {
+	tests := []struct {
+		name         string
+		v            Validators
+		etag         string
+		lastModified string
+		size         int64
+		want         bool
+	}{
+		{"same etag", Validators{ETag: `"a"`, LastModified: "Mon", ContentLength: 10}, `"a"`, "Tue", 20, false},
+		{"different etag", Validators{ETag: `"b"`}, `"a"`, "", 0, true},
+		{"last modified fallback", Validators{LastModified: "Tue"}, "", "Mon", 0, true},
+		{"size fallback", Validators{ContentLength: 20}, "", "", 10, true},
+		{"unknown size", Validators{ContentLength: -1}, "", "", 10, false},
+		{"nothing stored", Validators{ETag: `"a"`, ContentLength: 10}, "", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.v.Differs(tt.etag, tt.lastModified, tt.size); got != tt.want {
+				t.Errorf("Differs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeadValidators(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("Expected HEAD request, got: %s", r.Method)
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 01 Jan 2024 00:00:00 GMT")
+		w.Header().Set("Content-Length", "1234")
+	}))
+	defer server.Close()
+
+	v, err := HeadValidators(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("HeadValidators() failed: %v", err)
+	}
+	if v.ETag != `"v1"` || v.LastModified != "Mon, 01 Jan 2024 00:00:00 GMT" || v.ContentLength != 1234 {
+		t.Errorf("Unexpected validators: %+v", v)
+	}
+}
+
+func TestDownloadFileWithProgressTee(t *testing.T) {
+	content := bytes.Repeat([]byte("isoman"), 1000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	dest := filepath.Join(t.TempDir(), "file.iso")
+	var tee bytes.Buffer
+	if _, err := DownloadFileWithProgress(context.Background(), server.URL, dest, 512, &tee, nil); err != nil {
+		t.Fatalf("DownloadFileWithProgress() failed: %v", err)
+	}
+
+	written, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatalf("Failed to read downloaded file: %v", err)
+	}
+	if !bytes.Equal(written, content) || !bytes.Equal(tee.Bytes(), content) {
+		t.Errorf("File and tee should both receive the full body (file=%d, tee=%d bytes)", len(written), tee.Len())
+	}
+}
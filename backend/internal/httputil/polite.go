@@ -0,0 +1,149 @@
+package httputil
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+)
+
+// hostPacer spaces out requests to the same host and holds them back while
+// a host has asked for a pause with Retry-After, so public mirrors aren't
+// hammered by a burst of downloads, checksum fetches, and upstream checks.
+type hostPacer struct {
+	interval      time.Duration        // Minimum time between requests to a host
+	retryAfterMax time.Duration        // Longest Retry-After honored; zero returns 429 and 503 responses at once
+	next          map[string]time.Time // Earliest time of the next request, by host
+	mu            sync.Mutex
+}
+
+// newHostPacer returns a pacer, or nil when neither setting is enabled.
+func newHostPacer(interval, retryAfterMax time.Duration) *hostPacer {
+	if interval <= 0 && retryAfterMax <= 0 {
+		return nil
+	}
+	return &hostPacer{
+		interval:      max(interval, 0),
+		retryAfterMax: max(retryAfterMax, 0),
+		next:          make(map[string]time.Time),
+	}
+}
+
+// wrap returns a transport that paces the requests next carries. A nil pacer
+// returns next unchanged.
+func (p *hostPacer) wrap(next http.RoundTripper) http.RoundTripper {
+	if p == nil {
+		return next
+	}
+	return &politeTransport{next: next, pacer: p}
+}
+
+// wait blocks until a request to host may be sent and takes that slot.
+func (p *hostPacer) wait(ctx context.Context, host string) error {
+	p.mu.Lock()
+	now := time.Now()
+	at := p.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	p.next[host] = at.Add(p.interval)
+	p.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// pause holds back requests to host for d.
+func (p *hostPacer) pause(host string, d time.Duration) {
+	until := time.Now().Add(d)
+	p.mu.Lock()
+	if until.After(p.next[host]) {
+		p.next[host] = until
+	}
+	p.mu.Unlock()
+}
+
+// politeTransport paces requests per host and retries requests without a
+// body that a host answered with 429 or 503 and a Retry-After it can wait for.
+type politeTransport struct {
+	next  http.RoundTripper
+	pacer *hostPacer
+}
+
+func (t *politeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := strings.ToLower(req.URL.Host)
+	replayable := req.Body == nil || req.Body == http.NoBody
+
+	for attempt := 0; ; attempt++ {
+		if err := t.pacer.wait(req.Context(), host); err != nil {
+			return nil, err
+		}
+		resp, err := t.next.RoundTrip(req)
+		if err != nil || t.pacer.retryAfterMax <= 0 {
+			return resp, err
+		}
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+			return resp, nil
+		}
+		delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if !ok {
+			return resp, nil
+		}
+
+		// Requests to the host wait either way; this one only if it can be sent again
+		t.pacer.pause(host, min(delay, t.pacer.retryAfterMax))
+		if delay > t.pacer.retryAfterMax || attempt >= constants.HTTPRetryAfterRetries || !replayable {
+			return resp, nil
+		}
+		slog.InfoContext(req.Context(), "upstream asked to retry later",
+			slog.String("host", host),
+			slog.Int("status", resp.StatusCode),
+			slog.Duration("retry_after", delay),
+		)
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096)) //nolint:errcheck // Only drained so the connection can be reused
+		resp.Body.Close()
+	}
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport.
+func (t *politeTransport) CloseIdleConnections() {
+	if closer, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// parseRetryAfter parses a Retry-After value in seconds or as an HTTP date.
+// Dates in the past give a zero delay.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(at.Sub(now), 0), true
+}
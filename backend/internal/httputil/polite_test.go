@@ -0,0 +1,111 @@
+package httputil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{"0", 0, true},
+		{"Wed, 01 May 2024 12:00:30 GMT", 30 * time.Second, true},
+		{"Wed, 01 May 2024 11:00:00 GMT", 0, true},
+		{"", 0, false},
+		{"-5", 0, false},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %s, %v; want %s, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestHostPacerSpacesRequests(t *testing.T) {
+	var sent []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = append(sent, time.Now())
+	}))
+	defer server.Close()
+
+	cfg := DefaultClientConfig()
+	cfg.MinHostInterval = 50 * time.Millisecond
+	client := NewClient(cfg)
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Get() failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	for i := 1; i < len(sent); i++ {
+		if gap := sent[i].Sub(sent[i-1]); gap < 45*time.Millisecond {
+			t.Errorf("Request %d followed the previous one after %s, want at least 50ms", i, gap)
+		}
+	}
+}
+
+func TestHostPacerHonorsRetryAfter(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	t.Run("waits and retries", func(t *testing.T) {
+		requests.Store(0)
+		client := NewClient(DefaultClientConfig())
+		start := time.Now()
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Get() failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || requests.Load() != 2 {
+			t.Errorf("Expected 200 after one retry, got %d after %d requests", resp.StatusCode, requests.Load())
+		}
+		if waited := time.Since(start); waited < 900*time.Millisecond {
+			t.Errorf("Expected the retry to wait out Retry-After, waited %s", waited)
+		}
+	})
+
+	t.Run("longer than the maximum returns the response", func(t *testing.T) {
+		requests.Store(0)
+		cfg := DefaultClientConfig()
+		cfg.RetryAfterMax = 500 * time.Millisecond
+		client := NewClient(cfg)
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Get() failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusTooManyRequests || requests.Load() != 1 {
+			t.Errorf("Expected the 429 without a retry, got %d after %d requests", resp.StatusCode, requests.Load())
+		}
+
+		// The host still gets its pause, up to the maximum
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, http.NoBody)
+		if _, err := client.Do(req); err == nil {
+			t.Error("Expected the next request to wait for the paused host")
+		}
+	})
+}
@@ -0,0 +1,223 @@
+// Package i18n translates user-facing text, such as directory listings and
+// notification emails, from message catalogs. Catalogs for a few languages
+// are built in; more, or changes to the built-in ones, are loaded from JSON
+// files named after their language, e.g. nl.json or pt-br.json.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SourceLanguage is the language messages are written in. Its catalog has
+// every key, so it is the last fallback for a key missing from another.
+const SourceLanguage = "en"
+
+//go:embed locales/*.json
+var builtinLocales embed.FS
+
+// Catalog holds the messages of every available language.
+type Catalog struct {
+	defaultLang string
+	messages    map[string]map[string]string // language -> key -> message
+}
+
+// Builtin returns a catalog of the built-in languages that defaults to
+// SourceLanguage.
+func Builtin() *Catalog {
+	c, err := Load(SourceLanguage, "")
+	if err != nil {
+		panic(err) // The embedded catalogs are checked by the tests
+	}
+	return c
+}
+
+// Load builds a catalog from the built-in languages and the *.json files in
+// dir, if set. A file for a built-in language replaces only the messages it
+// has. defaultLang is used when a request prefers no available language and
+// must be one of them.
+func Load(defaultLang, dir string) (*Catalog, error) {
+	c := &Catalog{messages: make(map[string]map[string]string)}
+	if err := c.loadFS(builtinLocales, "locales"); err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if err := c.loadFS(os.DirFS(dir), "."); err != nil {
+			return nil, err
+		}
+	}
+
+	c.defaultLang = normalize(defaultLang)
+	if c.defaultLang == "" {
+		c.defaultLang = SourceLanguage
+	}
+	if _, ok := c.messages[c.defaultLang]; !ok {
+		return nil, fmt.Errorf("no messages for locale %q; available: %s", defaultLang, strings.Join(c.Languages(), ", "))
+	}
+	return c, nil
+}
+
+// loadFS merges the *.json catalogs in dir of fsys into c.
+func (c *Catalog) loadFS(fsys fs.FS, dir string) error {
+	names, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return fmt.Errorf("failed to read messages %s: %w", name, err)
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return fmt.Errorf("invalid messages %s: %w", name, err)
+		}
+		lang := normalize(strings.TrimSuffix(path.Base(name), ".json"))
+		if c.messages[lang] == nil {
+			c.messages[lang] = make(map[string]string, len(messages))
+		}
+		for key, message := range messages {
+			c.messages[lang][key] = message
+		}
+	}
+	return nil
+}
+
+// Languages lists the available languages, sorted.
+func (c *Catalog) Languages() []string {
+	langs := make([]string, 0, len(c.messages))
+	for lang := range c.messages {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Localizer returns a localizer for lang, or for its base language when only
+// that is available (de for de-at). Other languages get the default.
+func (c *Catalog) Localizer(lang string) *Localizer {
+	if match, ok := c.match(normalize(lang)); ok {
+		return c.localizer(match)
+	}
+	return c.localizer(c.defaultLang)
+}
+
+// Negotiate returns a localizer for the available language an Accept-Language
+// header prefers most, or for the default language when it prefers none.
+func (c *Catalog) Negotiate(acceptLanguage string) *Localizer {
+	for _, lang := range parseAcceptLanguage(acceptLanguage) {
+		if lang == "*" {
+			break
+		}
+		if match, ok := c.match(lang); ok {
+			return c.localizer(match)
+		}
+	}
+	return c.localizer(c.defaultLang)
+}
+
+// match finds the available language for a normalized tag.
+func (c *Catalog) match(lang string) (string, bool) {
+	if _, ok := c.messages[lang]; ok {
+		return lang, true
+	}
+	if base, _, found := strings.Cut(lang, "-"); found {
+		if _, ok := c.messages[base]; ok {
+			return base, true
+		}
+	}
+	return "", false
+}
+
+func (c *Catalog) localizer(lang string) *Localizer {
+	l := &Localizer{lang: lang}
+	for _, fallback := range []string{lang, c.defaultLang, SourceLanguage} {
+		if messages, ok := c.messages[fallback]; ok {
+			l.chain = append(l.chain, messages)
+		}
+	}
+	return l
+}
+
+// Localizer translates messages into one language.
+type Localizer struct {
+	lang  string
+	chain []map[string]string // The language, then the default, then the source language
+}
+
+// Lang returns the language messages are translated into.
+func (l *Localizer) Lang() string {
+	return l.lang
+}
+
+// Lookup returns the message for key, falling back to the default and then
+// the source language, and whether any of them has it.
+func (l *Localizer) Lookup(key string) (string, bool) {
+	for _, messages := range l.chain {
+		if message, ok := messages[key]; ok {
+			return message, true
+		}
+	}
+	return "", false
+}
+
+// T returns the message for key formatted with args, as by fmt.Sprintf. A
+// key no catalog has is returned as is, so a gap shows instead of vanishing.
+func (l *Localizer) T(key string, args ...any) string {
+	message, ok := l.Lookup(key)
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// normalize lowercases a language tag and uses hyphens as separators.
+func normalize(lang string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(lang), "_", "-"))
+}
+
+// parseAcceptLanguage returns the normalized tags of an Accept-Language
+// header, most preferred first. Tags with q=0 are left out.
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		lang string
+		q    float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		lang, params, _ := strings.Cut(part, ";")
+		lang = normalize(lang)
+		if lang == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if name == "q" {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > 0 {
+			tags = append(tags, weighted{lang, q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	langs := make([]string, len(tags))
+	for i, tag := range tags {
+		langs[i] = tag.lang
+	}
+	return langs
+}
@@ -0,0 +1,123 @@
+package i18n
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestBuiltinCatalogsComplete tests that every built-in language translates
+// exactly the keys of the source language and keeps their format verbs.
+func TestBuiltinCatalogsComplete(t *testing.T) {
+	c := Builtin()
+	source := c.messages[SourceLanguage]
+	for _, lang := range c.Languages() {
+		messages := c.messages[lang]
+		for key, message := range source {
+			translated, ok := messages[key]
+			if !ok {
+				t.Errorf("%s: missing %q", lang, key)
+				continue
+			}
+			if got, want := verbs(translated), verbs(message); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: %q has verbs %q, want %q", lang, key, got, want)
+			}
+		}
+		for key := range messages {
+			if _, ok := source[key]; !ok {
+				t.Errorf("%s: %q is not a source message", lang, key)
+			}
+		}
+	}
+}
+
+// verbs returns the format verbs of a message in order.
+func verbs(message string) []string {
+	var found []string
+	for i := 0; i < len(message)-1; i++ {
+		if message[i] == '%' {
+			found = append(found, message[i:i+2])
+			i++
+		}
+	}
+	return found
+}
+
+func TestNegotiate(t *testing.T) {
+	c := Builtin()
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"de", "de"},
+		{"de-AT,en;q=0.5", "de"},
+		{"nl,fr;q=0.8,de;q=0.9", "de"},
+		{"es;q=0, fr_CA", "fr"},
+		{"nl", "en"},
+		{"*", "en"},
+		{"en-GB;q=0.7, de;q=0.7", "en"},
+	}
+	for _, tt := range tests {
+		if got := c.Negotiate(tt.header).Lang(); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	// A new language with one message, and a change to a built-in one
+	if err := os.WriteFile(filepath.Join(dir, "nl.json"), []byte(`{"listing.empty": "Deze map is leeg"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "de.json"), []byte(`{"listing.heading": "ISO-Archiv"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Load("de", dir)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if got := c.Localizer("de").T("listing.heading"); got != "ISO-Archiv" {
+		t.Errorf("Expected the override, got %q", got)
+	}
+	if got := c.Localizer("de").T("listing.empty"); got != "Dieses Verzeichnis ist leer" {
+		t.Errorf("Expected the built-in message where the override has none, got %q", got)
+	}
+
+	nl := c.Negotiate("nl-BE")
+	if nl.Lang() != "nl" || nl.T("listing.empty") != "Deze map is leeg" {
+		t.Errorf("Expected the added language, got %s: %q", nl.Lang(), nl.T("listing.empty"))
+	}
+	// Messages it lacks come from the default language
+	if got := nl.T("listing.verified"); got != "Verifiziert" {
+		t.Errorf("Expected the default language's message, got %q", got)
+	}
+	// Unknown languages get the default
+	if got := c.Negotiate("ja").Lang(); got != "de" {
+		t.Errorf("Expected the default language, got %q", got)
+	}
+
+	if got := c.Localizer("en").T("listing.title", "alpine"); got != "Index of /images/alpine" {
+		t.Errorf("Expected a formatted message, got %q", got)
+	}
+	if got := c.Localizer("en").T("no.such.key"); got != "no.such.key" {
+		t.Errorf("Expected an unknown key back, got %q", got)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	if _, err := Load("ja", ""); err == nil {
+		t.Error("Expected an error for a default language without messages")
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nl.json"), []byte(`{"listing.empty": 1}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load("en", dir); err == nil {
+		t.Error("Expected an error for invalid messages")
+	}
+}
@@ -0,0 +1,505 @@
+// Package integration drives the API end to end against a fake upstream
+// mirror: ISOs are created over HTTP, downloaded by a running manager,
+// verified, and served back from /images.
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aloks98/isoman/backend/internal/api"
+	"github.com/aloks98/isoman/backend/internal/clock"
+	"github.com/aloks98/isoman/backend/internal/config"
+	"github.com/aloks98/isoman/backend/internal/download"
+	"github.com/aloks98/isoman/backend/internal/httputil"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/service"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+	"github.com/aloks98/isoman/backend/internal/ws"
+)
+
+// startTime is when the manager's fake clock starts. Unless a test advances
+// it, it is the time stamped on every ISO and event.
+var startTime = time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+// harness is a running server whose upstream requests all go to mirror.
+type harness struct {
+	t       *testing.T
+	mirror  *testutil.FakeMirror
+	manager *download.Manager
+	clock   *clock.Fake
+	router  *gin.Engine
+}
+
+// newHarness starts a server and mirror. configure adjusts the download
+// settings before the manager starts.
+func newHarness(t *testing.T, options testutil.MirrorOptions, configure func(cfg *config.DownloadConfig)) *harness {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	env := testutil.SetupTestEnvironment(t)
+	t.Cleanup(env.Cleanup)
+
+	mirror := testutil.NewFakeMirror(t, options)
+	clientCfg := httputil.DefaultClientConfig()
+	clientCfg.Transport = mirror.Transport()
+	httputil.Configure(clientCfg)
+	t.Cleanup(func() { httputil.Configure(httputil.DefaultClientConfig()) })
+
+	cfg := download.DefaultConfig()
+	cfg.RetryDelay = 0
+	if configure != nil {
+		configure(cfg)
+	}
+	manager := download.NewManagerWithConfig(env.DB, env.ISODir, cfg)
+	fake := clock.NewFake(startTime)
+	manager.SetClock(fake)
+	manager.Start()
+	t.Cleanup(manager.Stop)
+
+	isoService := service.NewISOService(env.DB, manager, env.ISODir)
+	router := api.SetupRoutes(isoService, service.NewStatsService(env.DB), service.NewAuthService(env.DB, env.Config.Auth.SessionTTL), env.DB, env.ISODir, ws.NewHub(), env.Config)
+	return &harness{t: t, mirror: mirror, manager: manager, clock: fake, router: router}
+}
+
+// do sends a request to the server and decodes the data of a JSON response into out.
+func (h *harness) do(method, path string, body any, out any) *httptest.ResponseRecorder {
+	h.t.Helper()
+	var reader bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reader).Encode(body); err != nil {
+			h.t.Fatalf("Failed to encode request: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.router.ServeHTTP(w, req)
+
+	if out != nil && w.Code < 300 {
+		envelope := struct {
+			Data json.RawMessage `json:"data"`
+		}{}
+		if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
+			h.t.Fatalf("%s %s: failed to decode response: %v", method, path, err)
+		}
+		if err := json.Unmarshal(envelope.Data, out); err != nil {
+			h.t.Fatalf("%s %s: failed to decode data: %v", method, path, err)
+		}
+	}
+	return w
+}
+
+// create queues an ISO over the API.
+func (h *harness) create(req map[string]string) *models.ISO {
+	h.t.Helper()
+	var iso models.ISO
+	if w := h.do(http.MethodPost, "/api/isos", req, &iso); w.Code != http.StatusCreated {
+		h.t.Fatalf("POST /api/isos = %d: %s", w.Code, w.Body.String())
+	}
+	return &iso
+}
+
+// waitFor polls the ISO until it leaves the active statuses.
+func (h *harness) waitFor(id string) *models.ISO {
+	h.t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		var iso models.ISO
+		if w := h.do(http.MethodGet, "/api/isos/"+id, nil, &iso); w.Code != http.StatusOK {
+			h.t.Fatalf("GET /api/isos/%s = %d: %s", id, w.Code, w.Body.String())
+		}
+		if !iso.Status.IsActive() {
+			return &iso
+		}
+		if time.Now().After(deadline) {
+			h.t.Fatalf("ISO %s still %s", id, iso.Status)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// stall advances the fake clock a stall timeout at a time until the worker
+// logs that it gave up on the ISO's stalled transfer. Stall detection runs on
+// the manager's clock, so a transfer that stops sending data waits for this.
+func (h *harness) stall(id string, timeout time.Duration) {
+	h.t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		h.clock.Advance(timeout)
+		// Give the worker time to notice before moving the clock again, or the
+		// restarted transfer could be taken for another stall
+		for wait := time.Now().Add(500 * time.Millisecond); time.Now().Before(wait); {
+			var entries []models.DownloadLogEntry
+			h.do(http.MethodGet, "/api/isos/"+id+"/log", nil, &entries)
+			for _, entry := range entries {
+				if strings.Contains(entry.Message, "stalled") {
+					return
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		if time.Now().After(deadline) {
+			h.t.Fatalf("ISO %s never stalled", id)
+		}
+	}
+}
+
+// content returns n bytes of a recognizable test image.
+func content(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte('A' + i%26)
+	}
+	return data
+}
+
+func TestDownloadVerifiesEveryChecksumFormat(t *testing.T) {
+	h := newHarness(t, testutil.MirrorOptions{}, nil)
+	image := content(64 * 1024)
+	h.mirror.AddFile("/alpine/v3.19/alpine-standard-x86_64.iso", image)
+	h.mirror.AddFile("/alpine/v3.19/alpine-virt-x86_64.iso", content(1024))
+
+	tests := []struct {
+		name         string
+		checksumType string
+		format       testutil.ChecksumFormat
+	}{
+		{"gnu", "sha256", testutil.ChecksumGNU},
+		{"gnu-sha512", "sha512", testutil.ChecksumGNU},
+		{"sums", "sha256", testutil.ChecksumSums},
+		{"bsd", "sha256", testutil.ChecksumBSD},
+		{"bare", "md5", testutil.ChecksumBare},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := "https://mirror.example/alpine/v3.19/alpine-standard-x86_64.iso"
+			iso := h.create(map[string]string{
+				"name":          "alpine",
+				"version":       fmt.Sprintf("3.19.%d", i),
+				"arch":          "x86_64",
+				"download_url":  upstream,
+				"checksum_url":  h.mirror.ChecksumURL("/alpine/v3.19/alpine-standard-x86_64.iso", tt.checksumType, tt.format),
+				"checksum_type": tt.checksumType,
+			})
+
+			iso = h.waitFor(iso.ID)
+			if iso.Status != models.StatusComplete {
+				t.Fatalf("Status = %s (%s), want complete", iso.Status, iso.ErrorMessage)
+			}
+			if iso.SourceURL != upstream || iso.SourceHeaders["Server"] != "fake-mirror" {
+				t.Errorf("Expected the upstream recorded, got %q %v", iso.SourceURL, iso.SourceHeaders)
+			}
+			if iso.CompletedAt == nil || !iso.CompletedAt.Equal(startTime) {
+				t.Errorf("CompletedAt = %v, want the injected clock %v", iso.CompletedAt, startTime)
+			}
+
+			w := h.do(http.MethodGet, iso.DownloadLink, nil, nil)
+			if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), image) {
+				t.Errorf("GET %s = %d with %d bytes, want the mirrored image", iso.DownloadLink, w.Code, w.Body.Len())
+			}
+
+			var report models.VerificationReport
+			h.do(http.MethodGet, "/api/isos/"+iso.ID+"/verification", nil, &report)
+			if !report.Verified || report.Checksum.Status != models.VerificationVerified {
+				t.Errorf("Expected a verified report, got %+v", report.Checksum)
+			}
+		})
+	}
+}
+
+func TestDownloadRestartsStalledTransfer(t *testing.T) {
+	h := newHarness(t, testutil.MirrorOptions{FlakyResponses: 1, FailAfterBytes: 4096, Stall: true}, func(cfg *config.DownloadConfig) {
+		cfg.StallTimeout = 200 * time.Millisecond
+		cfg.MaxRetries = 1
+	})
+	h.mirror.AddFile("/debian/debian-12-amd64.iso", content(32*1024))
+
+	iso := h.create(map[string]string{
+		"name":          "debian",
+		"version":       "12",
+		"arch":          "amd64",
+		"download_url":  "https://mirror.example/debian/debian-12-amd64.iso",
+		"checksum_url":  "https://mirror.example/debian/SHA256SUMS",
+		"checksum_type": "sha256",
+	})
+
+	h.stall(iso.ID, 200*time.Millisecond)
+	iso = h.waitFor(iso.ID)
+	if iso.Status != models.StatusComplete {
+		t.Fatalf("Status = %s (%s), want complete after a restart", iso.Status, iso.ErrorMessage)
+	}
+	if got := h.mirror.RequestCount("/debian/debian-12-amd64.iso"); got != 2 {
+		t.Errorf("Expected the stalled transfer to be restarted once, got %d requests", got)
+	}
+}
+
+func TestStalledTransferWaitsOutRetryDelay(t *testing.T) {
+	h := newHarness(t, testutil.MirrorOptions{FlakyResponses: 1, FailAfterBytes: 4096, Stall: true}, func(cfg *config.DownloadConfig) {
+		cfg.StallTimeout = 200 * time.Millisecond
+		cfg.MaxRetries = 1
+		cfg.RetryDelay = time.Hour
+	})
+	h.mirror.AddFile("/debian/debian-12-amd64.iso", content(32*1024))
+
+	iso := h.create(map[string]string{
+		"name":          "debian",
+		"version":       "12",
+		"arch":          "amd64",
+		"download_url":  "https://mirror.example/debian/debian-12-amd64.iso",
+		"checksum_url":  "https://mirror.example/debian/SHA256SUMS",
+		"checksum_type": "sha256",
+	})
+
+	// The worker waits on the fake clock, not the wall clock, before restarting
+	h.stall(iso.ID, 200*time.Millisecond)
+	h.clock.BlockUntil(1)
+	if got := h.mirror.RequestCount("/debian/debian-12-amd64.iso"); got != 1 {
+		t.Fatalf("Expected the restart to wait for the retry delay, got %d requests", got)
+	}
+	h.clock.Advance(time.Hour)
+
+	iso = h.waitFor(iso.ID)
+	if iso.Status != models.StatusComplete {
+		t.Fatalf("Status = %s (%s), want complete after the retry delay", iso.Status, iso.ErrorMessage)
+	}
+	if iso.CompletedAt == nil || iso.CompletedAt.Before(startTime.Add(time.Hour)) {
+		t.Errorf("CompletedAt = %v, want at least an hour after the start %v", iso.CompletedAt, startTime)
+	}
+}
+
+func TestRetryAfterMirrorDropsConnection(t *testing.T) {
+	h := newHarness(t, testutil.MirrorOptions{FlakyResponses: 1, FailAfterBytes: 4096}, nil)
+	image := content(32 * 1024)
+	h.mirror.AddFile("/fedora/Fedora-40-x86_64.iso", image)
+
+	var failures []models.QueueEvent
+	var mu sync.Mutex
+	h.manager.AddQueueEventHook(func(event models.QueueEvent) {
+		if event.Type == models.QueueEventFailed {
+			mu.Lock()
+			failures = append(failures, event)
+			mu.Unlock()
+		}
+	})
+
+	iso := h.create(map[string]string{
+		"name":          "fedora",
+		"version":       "40",
+		"arch":          "x86_64",
+		"download_url":  "https://mirror.example/fedora/Fedora-40-x86_64.iso",
+		"checksum_url":  "https://mirror.example/fedora/CHECKSUM",
+		"checksum_type": "sha256",
+	})
+	if iso = h.waitFor(iso.ID); iso.Status != models.StatusFailed {
+		t.Fatalf("Status = %s, want failed after the connection dropped", iso.Status)
+	}
+
+	if w := h.do(http.MethodPost, "/api/isos/"+iso.ID+"/retry", nil, nil); w.Code != http.StatusOK {
+		t.Fatalf("POST retry = %d: %s", w.Code, w.Body.String())
+	}
+	if iso = h.waitFor(iso.ID); iso.Status != models.StatusComplete {
+		t.Fatalf("Status = %s (%s), want complete after a retry", iso.Status, iso.ErrorMessage)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(failures) != 1 || failures[0].Status != models.StatusFailed || !failures[0].At.Equal(startTime) {
+		t.Errorf("Expected one failed event stamped by the injected clock, got %+v", failures)
+	}
+}
+
+func TestThrottledMirrorReportsProgress(t *testing.T) {
+	h := newHarness(t, testutil.MirrorOptions{Latency: 50 * time.Millisecond, BytesPerSecond: 64 * 1024}, func(cfg *config.DownloadConfig) {
+		cfg.ProgressUpdateInterval = 10 * time.Millisecond
+		cfg.BufferSize = 1024
+	})
+	h.mirror.AddFile("/arch/archlinux-x86_64.iso", content(32*1024))
+
+	var mu sync.Mutex
+	var partial []int
+	h.manager.AddProgressObserver(func(_ string, progress int, status models.ISOStatus) {
+		if status == models.StatusDownloading && progress > 0 && progress < 100 {
+			mu.Lock()
+			partial = append(partial, progress)
+			mu.Unlock()
+		}
+	})
+
+	iso := h.create(map[string]string{
+		"name":         "arch",
+		"version":      "2026.10.01",
+		"arch":         "x86_64",
+		"download_url": "https://mirror.example/arch/archlinux-x86_64.iso",
+	})
+	if iso = h.waitFor(iso.ID); iso.Status != models.StatusComplete {
+		t.Fatalf("Status = %s (%s), want complete", iso.Status, iso.ErrorMessage)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(partial) < 2 {
+		t.Errorf("Expected progress updates while throttled, got %v", partial)
+	}
+}
+
+func TestRedownloadKeepsPreviousFileUntilVerified(t *testing.T) {
+	h := newHarness(t, testutil.MirrorOptions{}, nil)
+	original := content(16 * 1024)
+	h.mirror.AddFile("/rocky/Rocky-9-x86_64.iso", original)
+
+	ended := make(chan models.QueueEvent, 4)
+	h.manager.AddQueueEventHook(func(event models.QueueEvent) {
+		if event.Type == models.QueueEventFinished || event.Type == models.QueueEventFailed {
+			ended <- event
+		}
+	})
+
+	iso := h.create(map[string]string{
+		"name":          "rocky",
+		"version":       "9",
+		"arch":          "x86_64",
+		"download_url":  "https://mirror.example/rocky/Rocky-9-x86_64.iso",
+		"checksum_url":  "https://mirror.example/rocky/CHECKSUM",
+		"checksum_type": "sha256",
+	})
+	<-ended
+	if iso = h.waitFor(iso.ID); iso.Status != models.StatusComplete {
+		t.Fatalf("Status = %s (%s), want complete", iso.Status, iso.ErrorMessage)
+	}
+
+	// The first redownload breaks off; the ISO goes back to the original file
+	h.mirror.SetOptions(testutil.MirrorOptions{FlakyResponses: 1, FailAfterBytes: 4096})
+	replacement := content(24 * 1024)
+	replacement[0] = 'Z'
+	h.mirror.AddFile("/rocky/Rocky-9-x86_64.iso", replacement)
+
+	var queued models.ISO
+	if w := h.do(http.MethodPost, "/api/isos/"+iso.ID+"/redownload", nil, &queued); w.Code != http.StatusOK {
+		t.Fatalf("POST redownload = %d: %s", w.Code, w.Body.String())
+	}
+	if !queued.Redownload || queued.SizeBytes != int64(len(original)) {
+		t.Errorf("Expected a redownload still describing the original file, got size=%d redownload=%v", queued.SizeBytes, queued.Redownload)
+	}
+	if event := <-ended; event.Type != models.QueueEventFailed || event.Status != models.StatusComplete {
+		t.Errorf("Expected a failed event keeping the ISO complete, got %+v", event)
+	}
+	iso = h.waitFor(iso.ID)
+	if iso.Status != models.StatusComplete || iso.Redownload || iso.SizeBytes != int64(len(original)) || iso.ErrorMessage == "" {
+		t.Errorf("Expected complete with the original file and an error, got %s size=%d redownload=%v %q",
+			iso.Status, iso.SizeBytes, iso.Redownload, iso.ErrorMessage)
+	}
+	if w := h.do(http.MethodGet, iso.DownloadLink, nil, nil); !bytes.Equal(w.Body.Bytes(), original) {
+		t.Errorf("GET %s = %d bytes, want the original file", iso.DownloadLink, w.Body.Len())
+	}
+
+	// The second one verifies and replaces it
+	if w := h.do(http.MethodPost, "/api/isos/"+iso.ID+"/redownload", nil, nil); w.Code != http.StatusOK {
+		t.Fatalf("POST redownload = %d: %s", w.Code, w.Body.String())
+	}
+	if event := <-ended; event.Type != models.QueueEventFinished {
+		t.Errorf("Expected the redownload to finish, got %+v", event)
+	}
+	iso = h.waitFor(iso.ID)
+	if iso.Status != models.StatusComplete || iso.Redownload || iso.SizeBytes != int64(len(replacement)) {
+		t.Errorf("Expected complete with the new file, got %s size=%d redownload=%v", iso.Status, iso.SizeBytes, iso.Redownload)
+	}
+	if w := h.do(http.MethodGet, iso.DownloadLink, nil, nil); !bytes.Equal(w.Body.Bytes(), replacement) {
+		t.Errorf("GET %s = %d bytes, want the new file", iso.DownloadLink, w.Body.Len())
+	}
+
+	if w := h.do(http.MethodPost, "/api/isos/does-not-exist/redownload", nil, nil); w.Code != http.StatusNotFound {
+		t.Errorf("POST redownload of a missing ISO = %d, want 404", w.Code)
+	}
+}
+
+func TestSmallDownloadTakesFastLane(t *testing.T) {
+	h := newHarness(t, testutil.MirrorOptions{BytesPerSecond: 64 * 1024}, func(cfg *config.DownloadConfig) {
+		cfg.WorkerCount = 1
+		cfg.FastLaneWorkers = 1
+		cfg.FastLaneMaxSize = 16 * 1024
+	})
+	h.mirror.AddFile("/debian/debian-12-amd64-DVD-1.iso", content(1024*1024))
+	h.mirror.AddFile("/debian/netboot/mini.img", content(4*1024))
+
+	dvd := h.create(map[string]string{
+		"name":         "debian-dvd",
+		"version":      "12",
+		"arch":         "amd64",
+		"download_url": "https://mirror.example/debian/debian-12-amd64-DVD-1.iso",
+	})
+	netboot := h.create(map[string]string{
+		"name":         "debian-netboot",
+		"version":      "12",
+		"arch":         "amd64",
+		"download_url": "https://mirror.example/debian/netboot/mini.img",
+	})
+
+	// The only regular worker is busy with the DVD for 16 seconds
+	if iso := h.waitFor(netboot.ID); iso.Status != models.StatusComplete {
+		t.Fatalf("Status = %s (%s), want complete", iso.Status, iso.ErrorMessage)
+	}
+	var iso models.ISO
+	h.do(http.MethodGet, "/api/isos/"+dvd.ID, nil, &iso)
+	if iso.Status != models.StatusDownloading {
+		t.Errorf("Expected the DVD still downloading, got %s", iso.Status)
+	}
+}
+
+func TestWaitEndpointReturnsOnceSettled(t *testing.T) {
+	h := newHarness(t, testutil.MirrorOptions{BytesPerSecond: 64 * 1024}, func(cfg *config.DownloadConfig) {
+		cfg.BufferSize = 1024
+	})
+	h.mirror.AddFile("/alpine/alpine-virt-3.22.0-x86_64.iso", content(96*1024))
+
+	iso := h.create(map[string]string{
+		"name":         "alpine",
+		"version":      "3.22.0",
+		"arch":         "x86_64",
+		"download_url": "https://mirror.example/alpine/alpine-virt-3.22.0-x86_64.iso",
+	})
+
+	// Throttled to about 1.5 seconds, so a 1 second wait runs out first
+	var waited models.ISO
+	if w := h.do(http.MethodGet, "/api/isos/"+iso.ID+"/wait?timeout=1", nil, &waited); w.Code != http.StatusAccepted {
+		t.Fatalf("GET wait?timeout=1 = %d, want 202: %s", w.Code, w.Body.String())
+	}
+	if !waited.Status.IsActive() {
+		t.Errorf("Expected the ISO still active after a short wait, got %s", waited.Status)
+	}
+
+	// Woken by the finished event, well before the poll interval
+	start := time.Now()
+	if w := h.do(http.MethodGet, "/api/isos/"+iso.ID+"/wait?timeout=30", nil, &waited); w.Code != http.StatusOK {
+		t.Fatalf("GET wait = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	if waited.Status != models.StatusComplete {
+		t.Errorf("Status = %s (%s), want complete", waited.Status, waited.ErrorMessage)
+	}
+	if elapsed := time.Since(start); elapsed > 4*time.Second {
+		t.Errorf("Wait took %s, expected it to return when the download finished", elapsed)
+	}
+
+	// A settled ISO answers at once
+	if w := h.do(http.MethodGet, "/api/isos/"+iso.ID+"/wait", nil, nil); w.Code != http.StatusOK {
+		t.Errorf("GET wait on a complete ISO = %d, want 200", w.Code)
+	}
+	for path, want := range map[string]int{
+		"/api/isos/" + iso.ID + "/wait?timeout=0":    http.StatusBadRequest,
+		"/api/isos/" + iso.ID + "/wait?timeout=9999": http.StatusBadRequest,
+		"/api/isos/missing/wait":                     http.StatusNotFound,
+	} {
+		if w := h.do(http.MethodGet, path, nil, nil); w.Code != want {
+			t.Errorf("GET %s = %d, want %d", path, w.Code, want)
+		}
+	}
+}
@@ -1,40 +1,224 @@
 package logger
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Log formats.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
 )
 
 // New creates a new structured logger based on configuration.
 func New(level, format string) *slog.Logger {
-	// Parse log level
-	var logLevel slog.Level
-	switch strings.ToLower(level) {
+	return NewTo(os.Stdout, level, format)
+}
+
+// NewTo creates a structured logger like New that writes to w.
+func NewTo(w io.Writer, level, format string) *slog.Logger {
+	// Unknown levels log at info
+	logLevel, err := ParseLevel(level)
+	if err != nil {
+		logLevel = slog.LevelInfo
+	}
+
+	return slog.New(&contextHandler{Handler: newHandler(w, &slog.HandlerOptions{Level: logLevel}, format)})
+}
+
+// newHandler creates a handler writing format, text unless it is json.
+func newHandler(w io.Writer, opts *slog.HandlerOptions, format string) slog.Handler {
+	if strings.EqualFold(format, FormatJSON) {
+		return slog.NewJSONHandler(w, opts)
+	}
+	return slog.NewTextHandler(w, opts)
+}
+
+// ParseLevel parses a level name: debug, info, warn (or warning), or error.
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
 	case "debug":
-		logLevel = slog.LevelDebug
+		return slog.LevelDebug, nil
 	case "info":
-		logLevel = slog.LevelInfo
+		return slog.LevelInfo, nil
 	case "warn", "warning":
-		logLevel = slog.LevelWarn
+		return slog.LevelWarn, nil
 	case "error":
-		logLevel = slog.LevelError
-	default:
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("invalid log level %q: must be debug, info, warn, or error", level)
+}
+
+// LevelName returns the name ParseLevel accepts for level.
+func LevelName(level slog.Level) string {
+	switch {
+	case level <= slog.LevelDebug:
+		return "debug"
+	case level <= slog.LevelInfo:
+		return "info"
+	case level <= slog.LevelWarn:
+		return "warn"
+	}
+	return "error"
+}
+
+// Controller is a logger whose level and format can be changed while the
+// server runs, e.g. to turn on debug logging during an incident without a
+// restart.
+type Controller struct {
+	w       io.Writer
+	level   slog.LevelVar
+	mu      sync.Mutex // Serializes Set
+	format  atomic.Value
+	handler atomic.Pointer[slog.Handler] // Writes in the current format
+	logger  *slog.Logger
+}
+
+// NewController creates a controlled logger writing to w. Unknown levels log
+// at info, and unknown formats as text, like New.
+func NewController(w io.Writer, level, format string) *Controller {
+	c := &Controller{w: w}
+	logLevel, err := ParseLevel(level)
+	if err != nil {
 		logLevel = slog.LevelInfo
 	}
+	c.level.Set(logLevel)
+	if !strings.EqualFold(format, FormatJSON) {
+		format = FormatText
+	}
+	c.setFormat(strings.ToLower(format))
+	c.logger = slog.New(&contextHandler{Handler: &switchHandler{c: c}})
+	return c
+}
+
+// Logger returns the controlled logger.
+func (c *Controller) Logger() *slog.Logger {
+	return c.logger
+}
+
+// Level returns the name of the current level.
+func (c *Controller) Level() string {
+	return LevelName(c.level.Level())
+}
+
+// Format returns the current format, text or json.
+func (c *Controller) Format() string {
+	return c.format.Load().(string)
+}
+
+// Set changes the level and format; an empty one is left as it is. Neither
+// changes unless both are valid.
+func (c *Controller) Set(level, format string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	// Create handler options
-	opts := &slog.HandlerOptions{
-		Level: logLevel,
+	logLevel := c.level.Level()
+	if level != "" {
+		parsed, err := ParseLevel(level)
+		if err != nil {
+			return err
+		}
+		logLevel = parsed
+	}
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format != "" && format != FormatText && format != FormatJSON {
+		return fmt.Errorf("invalid log format %q: must be text or json", format)
 	}
 
-	// Create handler based on format
-	var handler slog.Handler
-	if strings.EqualFold(format, "json") {
-		handler = slog.NewJSONHandler(os.Stdout, opts)
-	} else {
-		handler = slog.NewTextHandler(os.Stdout, opts)
+	c.level.Set(logLevel)
+	if format != "" && format != c.Format() {
+		c.setFormat(format)
 	}
+	return nil
+}
+
+func (c *Controller) setFormat(format string) {
+	h := newHandler(c.w, &slog.HandlerOptions{Level: &c.level}, format)
+	c.handler.Store(&h)
+	c.format.Store(format)
+}
+
+// switchHandler sends records to the Controller's current handler. Attributes
+// and groups added with WithAttrs and WithGroup are kept as a list and
+// replayed on each record, so they survive a format change.
+type switchHandler struct {
+	c   *Controller
+	ops []func(slog.Handler) slog.Handler
+}
+
+func (h *switchHandler) current() slog.Handler {
+	handler := *h.c.handler.Load()
+	for _, op := range h.ops {
+		handler = op(handler)
+	}
+	return handler
+}
+
+func (h *switchHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.c.level.Level()
+}
+
+func (h *switchHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.current().Handle(ctx, r)
+}
+
+func (h *switchHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithAttrs(attrs) })
+}
+
+func (h *switchHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithGroup(name) })
+}
+
+func (h *switchHandler) with(op func(slog.Handler) slog.Handler) slog.Handler {
+	ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &switchHandler{c: h.c, ops: append(ops, op)}
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the ID of the request being served.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID stored in ctx, or an empty string.
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHandler adds a request_id attribute to records logged with a context
+// carrying one (slog.InfoContext and friends).
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
 
-	return slog.New(handler)
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
 }
@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestContextHandlerAddsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(&contextHandler{Handler: slog.NewTextHandler(&buf, nil)})
+
+	ctx := WithRequestID(context.Background(), "req-123")
+	log.InfoContext(ctx, "with id")
+	if !strings.Contains(buf.String(), "request_id=req-123") {
+		t.Errorf("Expected request_id in log line, got: %q", buf.String())
+	}
+
+	buf.Reset()
+	log.With(slog.String("component", "test")).InfoContext(context.Background(), "without id")
+	if strings.Contains(buf.String(), "request_id") {
+		t.Errorf("Expected no request_id without one in the context, got: %q", buf.String())
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	if got := RequestID(context.Background()); got != "" {
+		t.Errorf("RequestID() = %q, want empty", got)
+	}
+	if got := RequestID(WithRequestID(context.Background(), "abc")); got != "abc" {
+		t.Errorf("RequestID() = %q, want abc", got)
+	}
+	if ctx := WithRequestID(context.Background(), ""); RequestID(ctx) != "" {
+		t.Error("WithRequestID() should ignore an empty ID")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		input string
+		want  slog.Level
+		name  string
+	}{
+		{"debug", slog.LevelDebug, "debug"},
+		{"INFO", slog.LevelInfo, "info"},
+		{"warning", slog.LevelWarn, "warn"},
+		{" error ", slog.LevelError, "error"},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v", tt.input, got, err, tt.want)
+		}
+		if LevelName(got) != tt.name {
+			t.Errorf("LevelName(%v) = %q, want %q", got, LevelName(got), tt.name)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("ParseLevel(verbose) should fail")
+	}
+}
+
+func TestController(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewController(&buf, "info", "text")
+	log := c.Logger()
+	child := log.With(slog.String("component", "worker"))
+
+	log.Debug("hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("Expected debug to be dropped at info, got: %q", buf.String())
+	}
+
+	if err := c.Set("debug", ""); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if c.Level() != "debug" || c.Format() != FormatText {
+		t.Errorf("Level(), Format() = %q, %q, want debug, text", c.Level(), c.Format())
+	}
+	log.Debug("shown")
+	if !strings.Contains(buf.String(), "msg=shown") {
+		t.Errorf("Expected debug line after switching level, got: %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := c.Set("", "JSON"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	child.InfoContext(WithRequestID(context.Background(), "req-1"), "switched")
+	line := buf.String()
+	for _, want := range []string{`"msg":"switched"`, `"component":"worker"`, `"request_id":"req-1"`} {
+		if !strings.Contains(line, want) {
+			t.Errorf("Expected %s in JSON line, got: %q", want, line)
+		}
+	}
+
+	// Neither setting changes when one is invalid
+	if err := c.Set("error", "xml"); err == nil {
+		t.Error("Set() with an invalid format should fail")
+	}
+	if err := c.Set("loud", "text"); err == nil {
+		t.Error("Set() with an invalid level should fail")
+	}
+	if c.Level() != "debug" || c.Format() != FormatJSON {
+		t.Errorf("Level(), Format() = %q, %q after invalid Set, want debug, json", c.Level(), c.Format())
+	}
+}
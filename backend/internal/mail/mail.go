@@ -0,0 +1,154 @@
+// Package mail sends plain-text email through an SMTP relay.
+package mail
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// implicitTLSPort is the SMTPS port, where TLS starts before the SMTP greeting
+// instead of through STARTTLS.
+const implicitTLSPort = 465
+
+// Sender delivers messages to an SMTP server.
+type Sender struct {
+	host     string
+	port     int
+	username string // Empty skips authentication
+	password string
+	from     string
+	timeout  time.Duration
+}
+
+// New creates a Sender for the SMTP server at host:port. STARTTLS is used
+// whenever the server offers it, and port 465 connects with TLS straight away.
+// timeout bounds the whole conversation with the server.
+func New(host string, port int, username, password, from string, timeout time.Duration) *Sender {
+	return &Sender{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     from,
+		timeout:  timeout,
+	}
+}
+
+// Send delivers a plain-text message to every recipient.
+func (s *Sender) Send(to []string, subject, body string) error {
+	if len(to) == 0 {
+		return errors.New("no recipients")
+	}
+	from, err := mail.ParseAddress(s.from)
+	if err != nil {
+		return fmt.Errorf("invalid sender address %q: %w", s.from, err)
+	}
+	recipients := make([]*mail.Address, len(to))
+	for i, addr := range to {
+		if recipients[i], err = mail.ParseAddress(addr); err != nil {
+			return fmt.Errorf("invalid recipient address %q: %w", addr, err)
+		}
+	}
+
+	client, err := s.dial()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && s.port != implicitTLSPort {
+		if err := client.StartTLS(&tls.Config{ServerName: s.host, MinVersion: tls.VersionTLS12}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if s.username != "" {
+		// PlainAuth refuses to send the password over an unencrypted connection
+		// to anything but localhost
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("sender rejected: %w", err)
+	}
+	for _, rcpt := range recipients {
+		if err := client.Rcpt(rcpt.Address); err != nil {
+			return fmt.Errorf("recipient %s rejected: %w", rcpt.Address, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start message: %w", err)
+	}
+	if _, err := w.Write(buildMessage(from, recipients, subject, body, time.Now())); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("message rejected: %w", err)
+	}
+	return client.Quit()
+}
+
+// dial connects to the server and applies the timeout as a deadline on the
+// connection.
+func (s *Sender) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.host, strconv.Itoa(s.port))
+	dialer := &net.Dialer{Timeout: s.timeout}
+
+	var conn net.Conn
+	var err error
+	if s.port == implicitTLSPort {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: s.host, MinVersion: tls.VersionTLS12})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	if s.timeout > 0 {
+		if err := conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to greet %s: %w", addr, err)
+	}
+	return client, nil
+}
+
+// buildMessage formats the headers and body of a UTF-8 plain-text message.
+func buildMessage(from *mail.Address, to []*mail.Address, subject, body string, date time.Time) []byte {
+	addrs := make([]string, len(to))
+	for i, addr := range to {
+		addrs[i] = addr.String()
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(addrs, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	buf.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return buf.Bytes()
+}
@@ -0,0 +1,131 @@
+package mail
+
+import (
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// receivedMessage is what the fake server saw in one SMTP transaction.
+type receivedMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+// startFakeSMTP accepts one message per connection without authentication or
+// TLS and hands it to the returned channel.
+func startFakeSMTP(t *testing.T) (string, int, <-chan receivedMessage) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	messages := make(chan receivedMessage, 1)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				tp := textproto.NewConn(conn)
+				tp.PrintfLine("220 fake ESMTP")
+
+				var msg receivedMessage
+				for {
+					line, err := tp.ReadLine()
+					if err != nil {
+						return
+					}
+					verb, arg, _ := strings.Cut(line, " ")
+					switch strings.ToUpper(verb) {
+					case "EHLO", "HELO":
+						tp.PrintfLine("250 fake")
+					case "MAIL":
+						msg.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+						tp.PrintfLine("250 ok")
+					case "RCPT":
+						msg.to = append(msg.to, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+						tp.PrintfLine("250 ok")
+					case "DATA":
+						tp.PrintfLine("354 go ahead")
+						data, err := tp.ReadDotBytes()
+						if err != nil {
+							return
+						}
+						msg.data = string(data)
+						tp.PrintfLine("250 queued")
+						messages <- msg
+					case "QUIT":
+						tp.PrintfLine("221 bye")
+						return
+					default:
+						tp.PrintfLine("502 unsupported")
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	portNum, _ := strconv.Atoi(port)
+	return host, portNum, messages
+}
+
+func TestSend(t *testing.T) {
+	host, port, messages := startFakeSMTP(t)
+	sender := New(host, port, "", "", "ISOMan <isoman@example.com>", 5*time.Second)
+
+	if err := sender.Send([]string{"ops@example.com", "Admin <admin@example.com>"}, "Weekly report", "line one\nline two"); err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+
+	select {
+	case msg := <-messages:
+		if msg.from != "isoman@example.com" {
+			t.Errorf("MAIL FROM = %q", msg.from)
+		}
+		if len(msg.to) != 2 || msg.to[1] != "admin@example.com" {
+			t.Errorf("RCPT TO = %v", msg.to)
+		}
+		if !strings.Contains(msg.data, "Subject: Weekly report\n") || !strings.Contains(msg.data, "\nline one\nline two") {
+			t.Errorf("Unexpected message:\n%s", msg.data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the message")
+	}
+}
+
+func TestSendInvalidAddresses(t *testing.T) {
+	sender := New("127.0.0.1", 25, "", "", "not an address", time.Second)
+	if err := sender.Send([]string{"ops@example.com"}, "s", "b"); err == nil {
+		t.Error("Send() should reject an invalid sender")
+	}
+
+	sender = New("127.0.0.1", 25, "", "", "isoman@example.com", time.Second)
+	if err := sender.Send(nil, "s", "b"); err == nil {
+		t.Error("Send() should reject an empty recipient list")
+	}
+	if err := sender.Send([]string{"nope"}, "s", "b"); err == nil {
+		t.Error("Send() should reject an invalid recipient")
+	}
+}
+
+func TestBuildMessageEncodesSubject(t *testing.T) {
+	from, _ := mail.ParseAddress("isoman@example.com")
+	to, _ := mail.ParseAddress("ops@example.com")
+
+	msg := string(buildMessage(from, []*mail.Address{to}, "Report\r\nBcc: evil@example.com", "body", time.Now()))
+	if strings.Contains(msg, "\r\nBcc:") {
+		t.Errorf("Subject newline leaked into the headers:\n%s", msg)
+	}
+}
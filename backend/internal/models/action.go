@@ -0,0 +1,55 @@
+package models
+
+// Actions on an ISO, as listed by GET /api/actions.
+const (
+	ActionRetry  = "retry"
+	ActionCancel = "cancel"
+	ActionVerify = "verify"
+	ActionShare  = "share"
+	ActionDelete = "delete"
+)
+
+// Caller roles of GET /api/actions.
+const (
+	ActionRoleOperator = "operator" // Signed in, or authentication is off
+	ActionRoleGuest    = "guest"    // Reading through a public scope without a session
+)
+
+// ActionRoute is the request that performs an action. {id} and {file_path}
+// stand for the ISO's fields.
+type ActionRoute struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Body   string `json:"body,omitempty"` // JSON body template, if the request needs one
+}
+
+// ISOActions lists the actions open to the caller on one ISO.
+type ISOActions struct {
+	ID      string    `json:"id"`
+	Status  ISOStatus `json:"status"`
+	Actions []string  `json:"actions"`
+}
+
+// ActionSet is the response of GET /api/actions.
+type ActionSet struct {
+	Role     string                 `json:"role"`
+	ReadOnly bool                   `json:"read_only"` // A replica, which refuses catalog changes
+	Routes   map[string]ActionRoute `json:"routes"`    // By action, for every action listed
+	ISOs     []ISOActions           `json:"isos"`
+}
+
+// Actions returns the actions an ISO in this status allows, in display
+// order, before the caller's role is considered.
+func (s ISOStatus) Actions() []string {
+	var actions []string
+	if s.IsRetryable() {
+		actions = append(actions, ActionRetry)
+	}
+	if s.IsCancelable() {
+		actions = append(actions, ActionCancel)
+	}
+	if s == StatusComplete {
+		actions = append(actions, ActionVerify, ActionShare)
+	}
+	return append(actions, ActionDelete)
+}
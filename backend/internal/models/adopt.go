@@ -0,0 +1,42 @@
+package models
+
+// Adopt modes control how files from a source tree are placed into the ISO directory.
+const (
+	AdoptModeLink = "link" // Hard link, falling back to copy across filesystems
+	AdoptModeCopy = "copy"
+	AdoptModeMove = "move"
+)
+
+// AdoptRule maps a file path (relative to the source directory, using forward slashes)
+// onto ISO metadata. Pattern must define the named groups name, version, and arch;
+// edition is optional.
+type AdoptRule struct {
+	Pattern string `json:"pattern" binding:"required"`
+}
+
+// AdoptDirectoryRequest represents a request to register files from an existing mirror tree.
+type AdoptDirectoryRequest struct {
+	SourceDir string      `json:"source_dir" binding:"required"`
+	Mode      string      `json:"mode" binding:"omitempty,oneof=link copy move"`
+	Rules     []AdoptRule `json:"rules"`
+	DryRun    bool        `json:"dry_run"`
+}
+
+// AdoptedFile describes a file registered as a complete ISO.
+type AdoptedFile struct {
+	ISO        *ISO   `json:"iso"`
+	SourcePath string `json:"source_path"`
+}
+
+// AdoptSkippedFile describes a file that was not adopted and why.
+type AdoptSkippedFile struct {
+	SourcePath string `json:"source_path"`
+	Reason     string `json:"reason"`
+}
+
+// AdoptResult summarizes an adopt directory run.
+type AdoptResult struct {
+	Adopted []AdoptedFile      `json:"adopted"`
+	Skipped []AdoptSkippedFile `json:"skipped"`
+	DryRun  bool               `json:"dry_run"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Audit actions.
+const (
+	AuditActionStatsReset   = "stats.reset"   // Download count and events cleared
+	AuditActionStatsAdjust  = "stats.adjust"  // Download count changed by hand
+	AuditActionAuthThrottle = "auth.throttle" // Repeated auth failures from an IP started delaying it
+	AuditActionAuthLockout  = "auth.lockout"  // An IP reached the failure limit and was locked out
+	AuditActionWebhook      = "webhook"       // A webhook delivery queued an ISO
+	AuditActionLogLevel     = "log.level"     // The log level or format was changed at runtime
+)
+
+// AuditEvent records an administrative change.
+type AuditEvent struct {
+	CreatedAt time.Time `json:"created_at"`
+	Action    string    `json:"action"`
+	TargetID  string    `json:"target_id"` // e.g. the ISO ID
+	Details   string    `json:"details"`
+	Reason    string    `json:"reason"`
+	ClientIP  string    `json:"client_ip"` // Real client address behind trusted proxies
+	ID        int64     `json:"id"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// ListingBranding customizes the /images directory listing, so a public
+// mirror can carry its organization's name and colors. Empty fields keep the
+// built-in look.
+type ListingBranding struct {
+	UpdatedAt       *time.Time `json:"updated_at,omitempty"`
+	Title           string     `json:"title"`            // Heading of the root listing and prefix of page titles
+	LogoURL         string     `json:"logo_url"`         // Image shown in place of the folder icon
+	FooterText      string     `json:"footer_text"`      // Shown above "Powered by ISOMan"
+	PrimaryColor    string     `json:"primary_color"`    // Header, path, and link hover color
+	BackgroundColor string     `json:"background_color"` // Page background, replacing the gradient
+}
+
+// ListingColumns is the set of columns the /images directory listing shows,
+// besides the name. They appear in a fixed order whatever order they are given in.
+type ListingColumns struct {
+	Columns []string `json:"columns"`
+}
+
+// UpdateListingBrandingRequest represents the branding fields to change. An
+// empty string restores a field's default.
+type UpdateListingBrandingRequest struct {
+	Title           *string `json:"title"`
+	LogoURL         *string `json:"logo_url"`
+	FooterText      *string `json:"footer_text"`
+	PrimaryColor    *string `json:"primary_color"`
+	BackgroundColor *string `json:"background_color"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// KnownChecksumSourceUpstream is the source of checksums recorded from
+// checksum files fetched during verification.
+const KnownChecksumSourceUpstream = "upstream"
+
+// KnownChecksum is a published checksum kept locally: the hash a checksum
+// file lists for a filename. Verification falls back to it when the checksum
+// file can no longer be fetched.
+type KnownChecksum struct {
+	UpdatedAt   time.Time `json:"updated_at"`
+	ChecksumURL string    `json:"checksum_url"`
+	Filename    string    `json:"filename"` // As named in the checksum file
+	Checksum    string    `json:"checksum"`
+	Source      string    `json:"source"` // "upstream", or the source named by an import
+}
+
+// ChecksumImportRequest is a batch of published checksums to keep locally. It
+// is also the format of the file named by CHECKSUM_DB_FILE.
+type ChecksumImportRequest struct {
+	Source    string          `json:"source"` // Recorded on entries that don't name their own
+	Checksums []KnownChecksum `json:"checksums" binding:"required"`
+}
+
+// ChecksumImportResult reports how many checksums an import stored.
+type ChecksumImportResult struct {
+	Imported int `json:"imported"`
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Credential types.
+const (
+	CredentialTypeBasic = "basic" // HTTP basic auth
+	CredentialTypeToken = "token" // Bearer token
+	CredentialTypeS3    = "s3"    // AWS Signature Version 4 with an access key
+)
+
+// IsValidCredentialType reports whether t is a known credential type.
+func IsValidCredentialType(t string) bool {
+	switch t {
+	case CredentialTypeBasic, CredentialTypeToken, CredentialTypeS3:
+		return true
+	}
+	return false
+}
+
+var credentialNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// IsValidCredentialName reports whether name can be used to reference a credential.
+func IsValidCredentialName(name string) bool {
+	return credentialNamePattern.MatchString(name)
+}
+
+// Credential is a named set of upstream credentials. The secret material is
+// stored sealed and never returned by the API.
+type Credential struct {
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Host      string    `json:"host"` // Applies to ISOs without a credential whose URLs use this host; empty only applies by name
+	Secret    string    `json:"-"`    // Sealed CredentialSecret
+}
+
+// CredentialSecret holds the secret material of a credential. Which fields
+// are used depends on the credential type.
+type CredentialSecret struct {
+	Username        string `json:"username,omitempty"`
+	Password        string `json:"password,omitempty"`
+	Token           string `json:"token,omitempty"`
+	AccessKeyID     string `json:"access_key_id,omitempty"`
+	SecretAccessKey string `json:"secret_access_key,omitempty"`
+	SessionToken    string `json:"session_token,omitempty"`
+	Region          string `json:"region,omitempty"` // Defaults to us-east-1
+}
+
+// Validate checks that the fields required by credentialType are set.
+func (s *CredentialSecret) Validate(credentialType string) error {
+	switch credentialType {
+	case CredentialTypeBasic:
+		if s.Username == "" {
+			return fmt.Errorf("basic credentials require a username")
+		}
+	case CredentialTypeToken:
+		if s.Token == "" {
+			return fmt.Errorf("token credentials require a token")
+		}
+	case CredentialTypeS3:
+		if s.AccessKeyID == "" || s.SecretAccessKey == "" {
+			return fmt.Errorf("s3 credentials require access_key_id and secret_access_key")
+		}
+	default:
+		return fmt.Errorf("unknown credential type: %s", credentialType)
+	}
+	return nil
+}
+
+// CreateCredentialRequest represents the request to store a new credential.
+type CreateCredentialRequest struct {
+	Name   string           `json:"name" binding:"required"`
+	Type   string           `json:"type" binding:"required,oneof=basic token s3"`
+	Host   string           `json:"host"`
+	Secret CredentialSecret `json:"secret"`
+}
+
+// UpdateCredentialRequest represents the allowed fields for updating a
+// credential. A new secret replaces the stored one entirely; changing the
+// type requires a new secret.
+type UpdateCredentialRequest struct {
+	Type   *string           `json:"type" binding:"omitempty,oneof=basic token s3"`
+	Host   *string           `json:"host"`
+	Secret *CredentialSecret `json:"secret"`
+}
@@ -0,0 +1,38 @@
+package models
+
+// Authentication modes listed in the discovery document.
+const (
+	AuthModeSession       = "session"        // isoman_session cookie from POST /api/auth/login
+	AuthModeBearer        = "bearer"         // The session token in an "Authorization: Bearer" header
+	AuthModeDownloadToken = "download_token" // ?token= of a download link, for /images only
+)
+
+// Discovery is the document served at /.well-known/isoman.json, from which
+// client tools configure themselves against an instance.
+type Discovery struct {
+	Name       string         `json:"name"` // Always "isoman"
+	Version    string         `json:"version"`
+	APIBase    string         `json:"api_base"`
+	WebSocket  string         `json:"websocket"`
+	Auth       DiscoveryAuth  `json:"auth"`
+	Links      DiscoveryLinks `json:"links"`
+	ReadOnly   bool           `json:"read_only"`             // A replica whose catalog follows PrimaryURL
+	PrimaryURL string         `json:"primary_url,omitempty"` // Set on replicas
+}
+
+// DiscoveryAuth describes how clients authenticate.
+type DiscoveryAuth struct {
+	Enabled      bool     `json:"enabled"`
+	Modes        []string `json:"modes"` // Empty when authentication is disabled
+	LoginURL     string   `json:"login_url,omitempty"`
+	PublicScopes []string `json:"public_scopes"` // Endpoint scopes served without a session
+}
+
+// DiscoveryLinks are the absolute URLs of an instance's catalog and feeds.
+type DiscoveryLinks struct {
+	Images       string `json:"images"`
+	Feed         string `json:"feed"`
+	Status       string `json:"status"`
+	Health       string `json:"health"`
+	RepoMetadata string `json:"repo_metadata,omitempty"` // Set when REPO_SIGNING_KEY is configured
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// DownloadLink lets anyone holding its token download one file under /images
+// until it expires, without a session. A single-use link is burned by the
+// first complete download.
+type DownloadLink struct {
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	ID        string     `json:"id"`   // SHA-256 of the token, so it is safe to show
+	Path      string     `json:"path"` // Relative to the ISO directory
+	CreatedBy string     `json:"created_by"`
+	URL       string     `json:"url,omitempty"` // Only set on creation; it holds the token
+	SingleUse bool       `json:"single_use"`
+}
+
+// CreateDownloadLinkRequest is the request body for creating a download link.
+type CreateDownloadLinkRequest struct {
+	Path           string `json:"path" binding:"required"`
+	ExpiresInHours int    `json:"expires_in_hours"` // 0 uses the default
+	SingleUse      bool   `json:"single_use"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// DownloadLock is a node's lease on an in-flight download. The node renews
+// it while the download runs; an expired lease may be taken over.
+type DownloadLock struct {
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	ISOID      string    `json:"iso_id"`
+	Node       string    `json:"node"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Download log levels.
+const (
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+// DownloadLogEntry is one step of an ISO's latest download run: an attempt,
+// a redirect, a retry, the verification, or the outcome.
+type DownloadLogEntry struct {
+	ID       int64     `json:"id"`
+	ISOID    string    `json:"iso_id"`
+	LoggedAt time.Time `json:"logged_at"`
+	Level    string    `json:"level"` // info, warn, or error
+	Message  string    `json:"message"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// System event types. Most come in pairs so the log shows when a problem
+// started and when it cleared.
+const (
+	EventServerStarted     = "server.started"
+	EventServerStopping    = "server.stopping"
+	EventStorageLow        = "storage.low"       // Free space in the ISO directory fell below the threshold
+	EventStorageRecovered  = "storage.recovered" // Free space is back above the threshold
+	EventDatabaseError     = "database.error"    // Health check query failed
+	EventDatabaseRecovered = "database.recovered"
+	EventQueueFull         = "queue.full" // New downloads are rejected with 429
+	EventQueueDrained      = "queue.drained"
+	EventIntegrityMismatch = "integrity.mismatch" // A file served with SERVE_VERIFY didn't match its integrity hash
+)
+
+// System event severities.
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// IsValidSeverity reports whether s is a known system event severity.
+func IsValidSeverity(s string) bool {
+	switch s {
+	case SeverityInfo, SeverityWarning, SeverityError:
+		return true
+	}
+	return false
+}
+
+// SystemEvent records a change in the server's health.
+type SystemEvent struct {
+	CreatedAt time.Time `json:"created_at"`
+	Type      string    `json:"type"`
+	Severity  string    `json:"severity"`
+	Message   string    `json:"message"`
+	ID        int64     `json:"id"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Reasons files are deleted without anyone asking for it.
+const (
+	GCReasonTempExpired      = "temp_expired"      // Temp file of no queued or running download, older than TEMP_MAX_AGE_HOURS
+	GCReasonVersionRetention = "version_retention" // Archived version beyond the newest REFRESH_KEEP_VERSIONS of an ISO
+)
+
+// IsValidGCReason reports whether s is a known garbage collection reason.
+func IsValidGCReason(s string) bool {
+	switch s {
+	case GCReasonTempExpired, GCReasonVersionRetention:
+		return true
+	}
+	return false
+}
+
+// GCReport records one automated deletion pass: which files it removed, how
+// much space they took, and why.
+type GCReport struct {
+	CreatedAt      time.Time `json:"created_at"`
+	Reason         string    `json:"reason"`
+	ISOID          string    `json:"iso_id,omitempty"` // ISO whose files were removed, when they belong to one
+	Files          []GCFile  `json:"files"`
+	ID             int64     `json:"id"`
+	FileCount      int       `json:"file_count"`
+	BytesReclaimed int64     `json:"bytes_reclaimed"`
+}
+
+// GCFile is a file removed by garbage collection.
+type GCFile struct {
+	Path      string `json:"path"` // Absolute path on the server
+	SizeBytes int64  `json:"size_bytes"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// IntegrityCheckResult is the outcome of re-hashing an ISO's file on disk
+// against its stored integrity hash.
+type IntegrityCheckResult struct {
+	ISO       *ISO   `json:"iso"`
+	Algorithm string `json:"algorithm"`
+	Expected  string `json:"expected"` // Empty when no hash was recorded before
+	Actual    string `json:"actual"`
+	Valid     bool   `json:"valid"`
+	Baseline  bool   `json:"baseline"` // The actual hash was recorded as the new baseline
+}
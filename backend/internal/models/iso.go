@@ -15,57 +15,196 @@ type ISOStatus string
 
 const (
 	StatusPending     ISOStatus = "pending"
+	StatusQueued      ISOStatus = "queued"
 	StatusDownloading ISOStatus = "downloading"
 	StatusVerifying   ISOStatus = "verifying"
 	StatusComplete    ISOStatus = "complete"
 	StatusFailed      ISOStatus = "failed"
+	StatusCanceled    ISOStatus = "canceled"
+	StatusQuarantined ISOStatus = "quarantined" // Flagged by the antivirus scan; not served until released
+)
+
+// IsValid reports whether s is a known status.
+func (s ISOStatus) IsValid() bool {
+	switch s {
+	case StatusPending, StatusQueued, StatusDownloading, StatusVerifying,
+		StatusComplete, StatusFailed, StatusCanceled, StatusQuarantined:
+		return true
+	}
+	return false
+}
+
+// IsActive reports whether the ISO is waiting for or being processed by a worker.
+func (s ISOStatus) IsActive() bool {
+	switch s {
+	case StatusPending, StatusQueued, StatusDownloading, StatusVerifying:
+		return true
+	}
+	return false
+}
+
+// IsRetryable reports whether a download in this status can be retried.
+func (s ISOStatus) IsRetryable() bool {
+	return s == StatusFailed || s == StatusCanceled
+}
+
+// IsCancelable reports whether the ISO's download is waiting for or run by a
+// worker in this status, so it can be canceled.
+func (s ISOStatus) IsCancelable() bool {
+	return s == StatusQueued || s == StatusDownloading || s == StatusVerifying
+}
+
+// ErrorReason classifies why a download failed, so clients can tell a stalled
+// mirror apart from a hard timeout without parsing the error message.
+type ErrorReason string
+
+const (
+	ErrorReasonNone         ErrorReason = ""
+	ErrorReasonStalled      ErrorReason = "stalled"
+	ErrorReasonTimeout      ErrorReason = "timeout"
+	ErrorReasonPanic        ErrorReason = "panic"        // A bug in the worker; error_message has the panic value
+	ErrorReasonInterrupted  ErrorReason = "interrupted"  // The instance running it stopped without finishing, e.g. on a crash
+	ErrorReasonVerification ErrorReason = "verification" // The file failed its checksum or signature check
+)
+
+// VerificationState says whether an ISO's file can be trusted, so clients
+// needn't infer it from which checksum fields happen to be set.
+type VerificationState string
+
+const (
+	VerificationStateVerified   VerificationState = "verified"   // Every configured checksum and signature check passed
+	VerificationStateUnverified VerificationState = "unverified" // Nothing vouches for the file (yet): no checks configured, not complete, or checksum pending
+	VerificationStateFailed     VerificationState = "failed"     // The last download failed its checksum or signature check
 )
 
 // ISO represents an ISO file record in the database.
 type ISO struct {
-	CreatedAt     time.Time  `json:"created_at"`
-	CompletedAt   *time.Time `json:"completed_at"`
-	DownloadLink  string     `json:"download_link"`
-	ChecksumType  string     `json:"checksum_type"`
-	Edition       string     `json:"edition"`
-	FileType      string     `json:"file_type"`
-	Filename      string     `json:"filename"`
-	FilePath      string     `json:"file_path"`
-	ID            string     `json:"id"`
-	Name          string     `json:"name"`
-	Checksum      string     `json:"checksum"`
-	Arch          string     `json:"arch"`
-	DownloadURL   string     `json:"download_url"`
-	ChecksumURL   string     `json:"checksum_url"`
-	Status        ISOStatus  `json:"status"`
-	Version       string     `json:"version"`
-	ErrorMessage  string     `json:"error_message"`
-	Progress      int        `json:"progress"`
-	SizeBytes     int64      `json:"size_bytes"`
-	DownloadCount int64      `json:"download_count"`
+	CreatedAt            time.Time         `json:"created_at"`
+	CompletedAt          *time.Time        `json:"completed_at"`
+	FileModTime          *time.Time        `json:"file_mtime"` // Recorded when the file was finalized
+	UpstreamCheckedAt    *time.Time        `json:"upstream_checked_at"`
+	VerifiedAt           *time.Time        `json:"verified_at"` // When the checksum or signature last verified; nil if neither was checked
+	DownloadStartedAt    *time.Time        `json:"download_started_at"`
+	DownloadLink         string            `json:"download_link"`
+	ChecksumType         string            `json:"checksum_type"`
+	Edition              string            `json:"edition"`
+	FileType             string            `json:"file_type"`
+	Filename             string            `json:"filename"`
+	FilePath             string            `json:"file_path"`
+	ID                   string            `json:"id"`
+	Name                 string            `json:"name"`
+	Checksum             string            `json:"checksum"`
+	Arch                 string            `json:"arch"`
+	DownloadURL          string            `json:"download_url"`
+	ChecksumURL          string            `json:"checksum_url"`
+	SignatureURL         string            `json:"signature_url"`    // Detached OpenPGP signature over the file itself
+	SignatureSigner      string            `json:"signature_signer"` // Identity of the key that made the verified signature
+	IPFamily             string            `json:"ip_family"`
+	Status               ISOStatus         `json:"status"`
+	Version              string            `json:"version"`
+	ErrorMessage         string            `json:"error_message"`
+	ErrorReason          ErrorReason       `json:"error_reason"`
+	UpstreamETag         string            `json:"upstream_etag"`
+	UpstreamLastModified string            `json:"upstream_last_modified"`
+	SourceURL            string            `json:"source_url"` // URL the file was served from, after redirects
+	SHA256               string            `json:"sha256"`
+	SHA512               string            `json:"sha512"`
+	MD5                  string            `json:"md5"`
+	IntegrityHash        string            `json:"integrity_hash"` // "algorithm:hex", used to scrub the file on disk
+	Credential           string            `json:"credential"`     // Name of the credential used for upstream requests; empty picks one by host
+	Preset               string            `json:"preset"`         // Preset the ISO was created from, if any
+	SourceHeaders        map[string]string `json:"source_headers"` // Upstream response headers naming the server, mirror, or CDN node
+	RequestID            string            `json:"-"`              // Request that queued the current download, for logs; not persisted
+	Progress             int               `json:"progress"`
+	SizeBytes            int64             `json:"size_bytes"`
+	DownloadCount        int64             `json:"download_count"`
+	BytesServed          int64             `json:"bytes_served"` // Bytes of the file sent to clients, including partial transfers
+	FileInode            uint64            `json:"file_inode"`   // 0 when unknown or unsupported by the platform
+	UpstreamChanged      bool              `json:"upstream_changed"`
+	Pinned               bool              `json:"pinned"`           // Listed first; refreshes never prune its archived versions
+	Redownload           bool              `json:"redownload"`       // Downloading again while the previous file stays served
+	ChecksumPending      bool              `json:"checksum_pending"` // Complete but unverified: the checksum file couldn't be fetched and is retried
+	Verification         VerificationState `json:"verification"`     // Derived from the fields above; not persisted
 }
 
 // CreateISORequest represents the request to create a new ISO download.
 type CreateISORequest struct {
-	Name         string `json:"name" binding:"required"`
-	Version      string `json:"version" binding:"required"`
-	Arch         string `json:"arch" binding:"required"`
+	Name             string `json:"name" binding:"required"`
+	Version          string `json:"version" binding:"required"`
+	Arch             string `json:"arch" binding:"required"`
+	Edition          string `json:"edition"`
+	DownloadURL      string `json:"download_url" binding:"required,url"`
+	ChecksumURL      string `json:"checksum_url" binding:"omitempty,url"`
+	ExpectedChecksum string `json:"expected_checksum"` // Hex digest, for sources that publish one instead of a checksum file
+	SignatureURL     string `json:"signature_url" binding:"omitempty,url"`
+	ChecksumType     string `json:"checksum_type" binding:"omitempty,oneof=sha256 sha512 md5"`
+	IPFamily         string `json:"ip_family" binding:"omitempty,oneof=any ipv4 ipv6"`
+	Credential       string `json:"credential"`
+}
+
+// ISOPreview shows the normalized name and the locations an ISO would get if
+// it were created, without creating it.
+type ISOPreview struct {
+	Name         string `json:"name"` // Normalized
+	Version      string `json:"version"`
+	Arch         string `json:"arch"`
 	Edition      string `json:"edition"`
-	DownloadURL  string `json:"download_url" binding:"required,url"`
-	ChecksumURL  string `json:"checksum_url" binding:"omitempty,url"`
-	ChecksumType string `json:"checksum_type" binding:"omitempty,oneof=sha256 sha512 md5"`
+	FileType     string `json:"file_type"`
+	Filename     string `json:"filename"`
+	FilePath     string `json:"file_path"`
+	DownloadLink string `json:"download_link"`
+	ExistingID   string `json:"existing_id,omitempty"` // ISO already stored under this name, version, arch, edition, and file type
 }
 
 // UpdateISORequest represents the allowed fields for updating an ISO.
 // Which fields are actually editable depends on the ISO's current status.
 type UpdateISORequest struct {
-	Name         *string `json:"name"`
-	Version      *string `json:"version"`
-	Arch         *string `json:"arch"`
-	Edition      *string `json:"edition"`
-	DownloadURL  *string `json:"download_url" binding:"omitempty,url"`
-	ChecksumURL  *string `json:"checksum_url" binding:"omitempty,url"`
-	ChecksumType *string `json:"checksum_type" binding:"omitempty,oneof=sha256 sha512 md5"`
+	Name             *string `json:"name"`
+	Version          *string `json:"version"`
+	Arch             *string `json:"arch"`
+	Edition          *string `json:"edition"`
+	DownloadURL      *string `json:"download_url" binding:"omitempty,url"`
+	ChecksumURL      *string `json:"checksum_url" binding:"omitempty,url"`
+	ExpectedChecksum *string `json:"expected_checksum"` // Hex digest; empty string clears it
+	SignatureURL     *string `json:"signature_url" binding:"omitempty,url"`
+	ChecksumType     *string `json:"checksum_type" binding:"omitempty,oneof=sha256 sha512 md5"`
+	IPFamily         *string `json:"ip_family" binding:"omitempty,oneof=any ipv4 ipv6"`
+	Credential       *string `json:"credential"` // Empty string clears the reference
+}
+
+// VersionBumpRequest represents the request to queue a new version of
+// several ISOs at once.
+type VersionBumpRequest struct {
+	IDs     []string `json:"ids" binding:"required,min=1"`
+	Version string   `json:"version" binding:"required"`
+}
+
+// VersionBumpResult reports what a version bump queued and what it couldn't.
+type VersionBumpResult struct {
+	Queued []*ISO               `json:"queued"`
+	Failed []VersionBumpFailure `json:"failed"`
+}
+
+// VersionBumpFailure is an ISO a version bump couldn't queue a new version of.
+type VersionBumpFailure struct {
+	ID    string `json:"id"` // Source ISO
+	Error string `json:"error"`
+}
+
+// Ways to resolve a create that hit an existing ISO.
+const (
+	ConflictActionRetry     = "retry"     // Retry the existing failed or canceled download
+	ConflictActionOverwrite = "overwrite" // Replace the existing failed or canceled ISO
+	ConflictActionRefresh   = "refresh"   // Re-download the existing complete ISO if upstream changed
+	ConflictActionEdition   = "edition"   // Create it alongside under a different edition
+)
+
+// ConflictOption is a request that resolves a create conflict.
+type ConflictOption struct {
+	Action      string `json:"action"`
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	Description string `json:"description"`
 }
 
 // "Ubuntu Server" -> "ubuntu-server".
@@ -140,10 +279,40 @@ func (iso *ISO) GetOriginalFilename() string {
 	return ExtractFilenameFromURL(iso.DownloadURL)
 }
 
+// HasChecksum reports whether downloads of the ISO are verified, against its
+// checksum file or, without one, the expected checksum it was given.
+func (iso *ISO) HasChecksum() bool {
+	return iso.ChecksumURL != "" || iso.Checksum != ""
+}
+
+// VerificationState classifies the ISO's file; see ClassifyVerification.
+func (iso *ISO) VerificationState() VerificationState {
+	return ClassifyVerification(iso.Status, iso.ErrorReason, iso.HasChecksum() || iso.SignatureURL != "", iso.ChecksumPending)
+}
+
+// ClassifyVerification classifies a file by its ISO's status and error reason,
+// whether a checksum or signature is configured, and whether the checksum is
+// still pending. A file counts as verified once it completed with a check
+// configured, since a failed check fails the download; quarantine comes after
+// verification, so a quarantined file has passed it too.
+func ClassifyVerification(status ISOStatus, reason ErrorReason, checked, checksumPending bool) VerificationState {
+	switch {
+	case status == StatusFailed && reason == ErrorReasonVerification:
+		return VerificationStateFailed
+	case status != StatusComplete && status != StatusQuarantined:
+		return VerificationStateUnverified
+	case !checked || checksumPending:
+		return VerificationStateUnverified
+	default:
+		return VerificationStateVerified
+	}
+}
+
 // ComputeFields computes all derived fields for an ISO.
 func (iso *ISO) ComputeFields() {
 	iso.Name = NormalizeName(iso.Name)
 	iso.Filename = GenerateFilename(iso.Name, iso.Version, iso.Edition, iso.Arch, iso.FileType)
 	iso.FilePath = GenerateFilePath(iso.Name, iso.Version, iso.Arch, iso.Filename)
 	iso.DownloadLink = GenerateDownloadLink(iso.FilePath)
+	iso.Verification = iso.VerificationState()
 }
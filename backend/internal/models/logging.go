@@ -0,0 +1,15 @@
+package models
+
+// LogSettings is the running server's log level and format.
+type LogSettings struct {
+	Level  string `json:"level"`  // debug, info, warn, error
+	Format string `json:"format"` // text, json
+}
+
+// LogSettingsRequest changes the log level, the format, or both; an omitted
+// one is left as it is.
+type LogSettingsRequest struct {
+	Level  string `json:"level"`
+	Format string `json:"format"`
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Download worker lanes and states reported in a ManagerState.
+const (
+	WorkerLaneRegular = "regular" // Takes from the queue and the fast lane
+	WorkerLaneFast    = "fast"    // Only takes small downloads from the fast lane
+
+	WorkerStateIdle        = "idle"
+	WorkerStateDownloading = "downloading"
+)
+
+// ManagerState is a point-in-time dump of this instance's download manager,
+// for debugging stuck pipelines.
+type ManagerState struct {
+	CapturedAt    time.Time          `json:"captured_at"`
+	Node          string             `json:"node"`
+	Stopped       bool               `json:"stopped"` // Shutting down; workers take nothing more
+	Queues        ManagerQueues      `json:"queues"`
+	Workers       []WorkerState      `json:"workers"`
+	VerifyWorkers int                `json:"verify_workers"`
+	InFlight      []InFlightDownload `json:"in_flight"`     // From queuing until finalized, by ISO ID
+	Active        []ActiveDownload   `json:"active"`        // Running or verifying on this node, oldest first
+	Locks         []DownloadLock     `json:"locks"`         // Held by every node sharing the database
+	WorkerPanics  int64              `json:"worker_panics"` // Recovered since startup
+}
+
+// ManagerQueues are the lengths and capacities of the manager's queues.
+type ManagerQueues struct {
+	Queued         int `json:"queued"`
+	Capacity       int `json:"capacity"`
+	FastQueued     int `json:"fast_queued"`
+	FastCapacity   int `json:"fast_capacity"` // Zero without fast lane workers
+	VerifyQueued   int `json:"verify_queued"` // Fetched and waiting for a verify worker
+	VerifyCapacity int `json:"verify_capacity"`
+}
+
+// WorkerState is what a download worker is doing. A worker that handed its
+// download to the verify pool is idle again.
+type WorkerState struct {
+	ID    int    `json:"id"`
+	Lane  string `json:"lane"`
+	State string `json:"state"`
+	ISOID string `json:"iso_id,omitempty"` // Set while downloading
+}
+
+// InFlightDownload is a download the manager holds, queued or running.
+type InFlightDownload struct {
+	ISOID    string    `json:"iso_id"`
+	TempFile string    `json:"temp_file"` // Filename the download is written to under the temp dir
+	Status   ISOStatus `json:"status"`    // queued, downloading, or verifying
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// ManifestFormatVersion is the current data dir manifest format.
+const ManifestFormatVersion = 1
+
+// Manifest lists every file in the ISO directory with its size and SHA-256,
+// so the tree can be copied to another instance and verified there.
+type Manifest struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	Entries     []ManifestEntry `json:"entries"`
+	Version     int             `json:"version"`
+}
+
+// ManifestEntry describes one file in the manifest. Image files carry their
+// ISO metadata; checksum and signature sidecar files do not.
+type ManifestEntry struct {
+	ISO       *ISO   `json:"iso,omitempty"`
+	Path      string `json:"path"` // Relative to the ISO directory, forward slashes
+	SHA256    string `json:"sha256"`
+	SizeBytes int64  `json:"size_bytes"`
+}
+
+// ManifestImportRequest represents a request to verify and import a copied data dir.
+type ManifestImportRequest struct {
+	Manifest  *Manifest `json:"manifest" binding:"required"`
+	SourceDir string    `json:"source_dir" binding:"required"`
+	Mode      string    `json:"mode" binding:"omitempty,oneof=link copy move"`
+	DryRun    bool      `json:"dry_run"`
+}
+
+// BundleExportRequest selects the ISOs to package into a bundle.
+type BundleExportRequest struct {
+	IDs []string `json:"ids" binding:"required,min=1"`
+}
+
+// ManifestImportResult summarizes a manifest import. Invalid lists files that
+// failed verification; Skipped lists files that were already present.
+type ManifestImportResult struct {
+	Imported []*ISO             `json:"imported"`
+	Skipped  []AdoptSkippedFile `json:"skipped"`
+	Invalid  []AdoptSkippedFile `json:"invalid"`
+	DryRun   bool               `json:"dry_run"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// MirrorHealth summarizes download outcomes for a single upstream host.
+type MirrorHealth struct {
+	LastSuccessAt  *time.Time `json:"last_success_at"`
+	LastFailureAt  *time.Time `json:"last_failure_at"`
+	Host           string     `json:"host"`
+	LastError      string     `json:"last_error"`
+	SuccessCount   int64      `json:"success_count"`
+	FailureCount   int64      `json:"failure_count"`
+	AvgLatencyMs   int64      `json:"avg_latency_ms"`
+	LastLatencyMs  int64      `json:"last_latency_ms"`
+	SuccessRate    float64    `json:"success_rate"`
+	TotalLatencyMs int64      `json:"-"`
+}
+
+// ComputeFields derives the success rate and average latency from the raw counters.
+func (m *MirrorHealth) ComputeFields() {
+	total := m.SuccessCount + m.FailureCount
+	if total > 0 {
+		m.SuccessRate = float64(m.SuccessCount) / float64(total)
+	}
+	if m.SuccessCount > 0 {
+		m.AvgLatencyMs = m.TotalLatencyMs / m.SuccessCount
+	}
+}
@@ -0,0 +1,94 @@
+package models
+
+import "time"
+
+// Placeholders that preset URLs may contain.
+const (
+	PresetPlaceholderVersion = "{version}"
+	PresetPlaceholderMajor   = "{major}" // Version up to the first '.'
+	PresetPlaceholderArch    = "{arch}"
+	PresetPlaceholderEdition = "{edition}"
+)
+
+// IsValidPresetName reports whether name can be used to reference a preset.
+func IsValidPresetName(name string) bool {
+	return credentialNamePattern.MatchString(name)
+}
+
+// Preset is a named recipe for creating ISOs of one distribution, so that
+// only the version (and maybe the arch) changes between releases.
+type Preset struct {
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Name         string    `json:"name"`
+	ISOName      string    `json:"iso_name"`
+	Arch         string    `json:"arch"`    // Default when a request doesn't name one
+	Edition      string    `json:"edition"` // Default when a request doesn't name one
+	DownloadURL  string    `json:"download_url"`
+	ChecksumURL  string    `json:"checksum_url"`
+	ChecksumType string    `json:"checksum_type"`
+}
+
+// CreatePresetRequest represents the request to create a preset. It is also
+// an entry of a preset pack.
+type CreatePresetRequest struct {
+	Name         string `json:"name" yaml:"name" binding:"required"`
+	ISOName      string `json:"iso_name" yaml:"iso_name" binding:"required"`
+	Arch         string `json:"arch" yaml:"arch,omitempty"`
+	Edition      string `json:"edition" yaml:"edition,omitempty"`
+	DownloadURL  string `json:"download_url" yaml:"download_url" binding:"required"`
+	ChecksumURL  string `json:"checksum_url" yaml:"checksum_url,omitempty"`
+	ChecksumType string `json:"checksum_type" yaml:"checksum_type,omitempty"`
+}
+
+// UpdatePresetRequest represents the allowed fields for updating a preset.
+type UpdatePresetRequest struct {
+	ISOName      *string `json:"iso_name"`
+	Arch         *string `json:"arch"`
+	Edition      *string `json:"edition"`
+	DownloadURL  *string `json:"download_url"`
+	ChecksumURL  *string `json:"checksum_url"`
+	ChecksumType *string `json:"checksum_type"`
+}
+
+// ApplyPresetRequest represents the request to create an ISO from a preset.
+// Empty fields use the preset's defaults.
+type ApplyPresetRequest struct {
+	Version    string `json:"version" binding:"required"`
+	Arch       string `json:"arch"`
+	Edition    string `json:"edition"`
+	IPFamily   string `json:"ip_family"`
+	Credential string `json:"credential"`
+}
+
+// PresetPackVersion is the preset pack format this build writes and reads.
+const PresetPackVersion = 1
+
+// PresetPack is a shareable set of presets, exported as YAML so one instance's
+// curated presets can be imported on others.
+type PresetPack struct {
+	Version int                   `yaml:"version"`
+	Presets []CreatePresetRequest `yaml:"presets"`
+}
+
+// What a preset pack import does with presets whose name is taken.
+const (
+	PresetImportSkip    = "skip"    // Keep the existing preset
+	PresetImportReplace = "replace" // Overwrite it with the pack's
+)
+
+// PresetImportResult lists the presets a pack import created, replaced, and
+// skipped, by name.
+type PresetImportResult struct {
+	Created  []string `json:"created"`
+	Replaced []string `json:"replaced"`
+	Skipped  []string `json:"skipped"`
+}
+
+// CatalogDownloadRequest is the optional body of a catalog download, whose
+// preset, version, and arch come from the path.
+type CatalogDownloadRequest struct {
+	Edition    string `json:"edition"`
+	IPFamily   string `json:"ip_family"`
+	Credential string `json:"credential"`
+}
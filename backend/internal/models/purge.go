@@ -0,0 +1,13 @@
+package models
+
+// Purge events say why /images/ paths are purged.
+const (
+	PurgeEventReplaced = "file.replaced" // A file was written at the path by a download, refresh, rename, or release
+	PurgeEventDeleted  = "file.deleted"
+)
+
+// PurgeRequest is the body POSTed to the CDN purge URL.
+type PurgeRequest struct {
+	Event string   `json:"event"`
+	Paths []string `json:"paths"` // /images/ URL paths: the file, its checksum files, and the listings above it
+}
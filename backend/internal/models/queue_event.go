@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Queue event types, in the order a download passes through them. Every
+// download that starts ends with one finished or failed event.
+const (
+	QueueEventQueued   = "download.queued"
+	QueueEventStarted  = "download.started"
+	QueueEventFinished = "download.finished"
+	QueueEventFailed   = "download.failed" // Status tells a failure apart from a cancellation or quarantine
+)
+
+// QueueEvent is a step in a download's life in the queue, for features that
+// react to downloads without reaching into the workers.
+type QueueEvent struct {
+	At         time.Time `json:"at"`
+	Type       string    `json:"type"`
+	ISOID      string    `json:"iso_id"`
+	Name       string    `json:"name"`
+	Filename   string    `json:"filename"`
+	Status     ISOStatus `json:"status"`      // Of the ISO after the step
+	Error      string    `json:"error"`       // Why a failed event failed
+	Node       string    `json:"node"`        // Instance that emitted the event
+	RequestID  string    `json:"request_id"`  // Request that queued the download, if known
+	DurationMs int64     `json:"duration_ms"` // Since the download started, for finished and failed events
+}
@@ -0,0 +1,32 @@
+package models
+
+// ReconcileRequest asks for ISOs whose stored paths and links don't match
+// their metadata to be fixed, or with DryRun only reported.
+type ReconcileRequest struct {
+	DryRun bool `json:"dry_run"`
+}
+
+// ReconcileChange is an ISO whose derived fields were recomputed.
+type ReconcileChange struct {
+	ISOID       string   `json:"iso_id"`
+	Fields      []string `json:"fields"` // Derived fields that changed: name, filename, file_path, download_link
+	OldFilePath string   `json:"old_file_path"`
+	NewFilePath string   `json:"new_file_path"`
+	FileMoved   bool     `json:"file_moved"`   // The file or its sidecars were moved to the new path
+	FileMissing bool     `json:"file_missing"` // A complete ISO's file is at neither path
+}
+
+// ReconcileSkipped is an ISO that needs fixing but was left alone.
+type ReconcileSkipped struct {
+	ISOID  string `json:"iso_id"`
+	Reason string `json:"reason"`
+}
+
+// ReconcileResult summarizes a reconciliation run. On a dry run, Fixed lists
+// the changes that would be made.
+type ReconcileResult struct {
+	Checked int                `json:"checked"`
+	Fixed   []ReconcileChange  `json:"fixed"`
+	Skipped []ReconcileSkipped `json:"skipped"`
+	DryRun  bool               `json:"dry_run"`
+}
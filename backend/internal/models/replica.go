@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// ReplicaStatus reports how a read-only replica's catalog compares with its
+// primary's as of the last sync.
+type ReplicaStatus struct {
+	LastSyncedAt *time.Time `json:"last_synced_at"` // Last successful sync; nil before the first
+	PrimaryURL   string     `json:"primary_url"`
+	LastError    string     `json:"last_error"` // Empty when the last sync succeeded
+	ISOs         int        `json:"isos"`       // Primary ISOs listed here
+	Waiting      int        `json:"waiting"`    // Primary ISOs whose files haven't arrived yet
+	Enabled      bool       `json:"enabled"`    // False when this instance isn't a replica
+}
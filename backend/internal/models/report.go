@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Report summarizes one period of activity for the scheduled report email.
+type Report struct {
+	PeriodStart  time.Time         `json:"period_start"`
+	PeriodEnd    time.Time         `json:"period_end"`
+	NewISOs      []ISO             `json:"new_isos"`      // Created during the period
+	FailedISOs   []ISO             `json:"failed_isos"`   // Failed now, whenever the failure happened
+	TopDownloads []ISODownloadStat `json:"top_downloads"` // DownloadCount covers the period only
+	StorageBytes int64             `json:"storage_bytes"` // Size of every complete ISO
+	StorageDelta int64             `json:"storage_delta"` // Change since the previous report
+	HasPrevious  bool              `json:"has_previous"`  // False on the first report, when StorageDelta is zero
+}
+
+// ReportRun records a report that was sent, so the next one can compare
+// against it.
+type ReportRun struct {
+	PeriodStart  time.Time `json:"period_start"`
+	PeriodEnd    time.Time `json:"period_end"`
+	CreatedAt    time.Time `json:"created_at"`
+	ID           int64     `json:"id"`
+	StorageBytes int64     `json:"storage_bytes"`
+}
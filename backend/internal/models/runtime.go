@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// RuntimeSnapshot is a point-in-time dump of the Go runtime, for diagnosing
+// goroutine leaks and memory growth in production.
+type RuntimeSnapshot struct {
+	CapturedAt      time.Time        `json:"captured_at"`
+	StartedAt       time.Time        `json:"started_at"`
+	GoVersion       string           `json:"go_version"`
+	GOMAXPROCS      int              `json:"gomaxprocs"`
+	NumCPU          int              `json:"num_cpu"`
+	Goroutines      int              `json:"goroutines"`
+	GoroutineGroups []GoroutineGroup `json:"goroutine_groups"` // Most goroutines first
+	Heap            HeapStats        `json:"heap"`
+}
+
+// GoroutineGroup counts goroutines in the same state at the same function.
+// Many goroutines, or a long wait, in one group points at a leak or a stuck
+// worker.
+type GoroutineGroup struct {
+	Function       string `json:"function"` // Innermost non-runtime frame, e.g. github.com/.../download.(*Manager).worker
+	State          string `json:"state"`    // e.g. running, chan receive, select, IO wait
+	Count          int    `json:"count"`
+	MaxWaitMinutes int    `json:"max_wait_minutes"` // Longest any of them has been blocked; 0 under a minute
+}
+
+// HeapStats are the memory statistics of a RuntimeSnapshot.
+type HeapStats struct {
+	LastGC        *time.Time `json:"last_gc"` // Nil before the first collection
+	AllocBytes    uint64     `json:"alloc_bytes"`
+	InuseBytes    uint64     `json:"inuse_bytes"`
+	IdleBytes     uint64     `json:"idle_bytes"`
+	ReleasedBytes uint64     `json:"released_bytes"`
+	SysBytes      uint64     `json:"sys_bytes"` // Obtained from the OS for everything, not just the heap
+	NextGCBytes   uint64     `json:"next_gc_bytes"`
+	Objects       uint64     `json:"objects"`
+	NumGC         uint32     `json:"num_gc"`
+	PauseTotalMS  float64    `json:"pause_total_ms"`
+}
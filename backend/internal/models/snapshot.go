@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// CatalogSnapshot records the complete ISOs as they were at one moment, the
+// baseline the catalog changelog compares against.
+type CatalogSnapshot struct {
+	TakenAt  time.Time      `json:"taken_at"`
+	Entries  []CatalogEntry `json:"entries"`
+	ID       int64          `json:"id"`
+	ISOCount int            `json:"iso_count"`
+}
+
+// CatalogEntry is what a snapshot keeps of one complete ISO.
+type CatalogEntry struct {
+	CompletedAt *time.Time `json:"completed_at"`
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Version     string     `json:"version"`
+	Arch        string     `json:"arch"`
+	Edition     string     `json:"edition"`
+	FileType    string     `json:"file_type"`
+	FilePath    string     `json:"file_path"`
+	SHA256      string     `json:"sha256"`
+	SizeBytes   int64      `json:"size_bytes"`
+}
+
+// NewCatalogEntry returns what a snapshot keeps of iso.
+func NewCatalogEntry(iso *ISO) CatalogEntry {
+	return CatalogEntry{
+		CompletedAt: iso.CompletedAt,
+		ID:          iso.ID,
+		Name:        iso.Name,
+		Version:     iso.Version,
+		Arch:        iso.Arch,
+		Edition:     iso.Edition,
+		FileType:    iso.FileType,
+		FilePath:    iso.FilePath,
+		SHA256:      iso.SHA256,
+		SizeBytes:   iso.SizeBytes,
+	}
+}
+
+// CatalogChanges lists how the complete ISOs changed between a snapshot and now.
+type CatalogChanges struct {
+	Since   time.Time       `json:"since"` // As requested
+	From    time.Time       `json:"from"`  // When the snapshot compared against was taken; after Since when none is older
+	To      time.Time       `json:"to"`
+	Added   []CatalogEntry  `json:"added"`
+	Removed []CatalogEntry  `json:"removed"`
+	Updated []CatalogUpdate `json:"updated"`
+}
+
+// CatalogUpdate is an ISO in both the snapshot and the catalog whose file changed.
+type CatalogUpdate struct {
+	Fields []string     `json:"fields"` // JSON names of the entry fields that differ
+	Before CatalogEntry `json:"before"`
+	After  CatalogEntry `json:"after"`
+}
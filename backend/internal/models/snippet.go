@@ -0,0 +1,24 @@
+package models
+
+// Snippet types served by GET /api/isos/:id/snippets.
+const (
+	SnippetWget    = "wget"    // Shell script fetching the ISO with wget and checking its SHA-256
+	SnippetCurl    = "curl"    // The same with curl
+	SnippetProxmox = "proxmox" // storage.cfg stanza and the pvesh command that downloads into it
+	SnippetIPXE    = "ipxe"    // iPXE script booting the image over HTTP
+)
+
+// Snippet is a ready-to-use piece of client configuration for one ISO.
+type Snippet struct {
+	Type     string `json:"type"`
+	Filename string `json:"filename"` // Suggested name to save the snippet as
+	Content  string `json:"content"`
+}
+
+// ISOSnippets is the client configuration generated for an ISO.
+type ISOSnippets struct {
+	ISOID        string    `json:"iso_id"`
+	URL          string    `json:"url"`           // Absolute download URL the snippets use
+	RequiresAuth bool      `json:"requires_auth"` // /images needs a session or download link token
+	Snippets     []Snippet `json:"snippets"`
+}
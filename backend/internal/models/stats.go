@@ -4,17 +4,40 @@ import "time"
 
 // Stats represents aggregated statistics for the dashboard.
 type Stats struct {
-	TotalISOs      int64             `json:"total_isos"`
-	CompletedISOs  int64             `json:"completed_isos"`
-	FailedISOs     int64             `json:"failed_isos"`
-	PendingISOs    int64             `json:"pending_isos"`
-	TotalSizeBytes int64             `json:"total_size_bytes"`
-	TotalDownloads int64             `json:"total_downloads"`
-	BandwidthSaved int64             `json:"bandwidth_saved"`
-	ISOsByArch     map[string]int64  `json:"isos_by_arch"`
-	ISOsByEdition  map[string]int64  `json:"isos_by_edition"`
-	ISOsByStatus   map[string]int64  `json:"isos_by_status"`
-	TopDownloaded  []ISODownloadStat `json:"top_downloaded"`
+	TotalISOs              int64             `json:"total_isos"`
+	CompletedISOs          int64             `json:"completed_isos"`
+	FailedISOs             int64             `json:"failed_isos"`
+	CanceledISOs           int64             `json:"canceled_isos"`
+	PendingISOs            int64             `json:"pending_isos"`
+	VerifiedISOs           int64             `json:"verified_isos"` // Verification coverage; these three add up to total_isos
+	UnverifiedISOs         int64             `json:"unverified_isos"`
+	VerificationFailedISOs int64             `json:"verification_failed_isos"`
+	UnverifiedSizeBytes    int64             `json:"unverified_size_bytes"` // Complete ISOs served without a passed checksum or signature
+	TotalSizeBytes         int64             `json:"total_size_bytes"`
+	TotalDownloads         int64             `json:"total_downloads"`
+	BandwidthSaved         int64             `json:"bandwidth_saved"`
+	TotalBytesServed       int64             `json:"total_bytes_served"` // Bytes actually sent from /images, to check bandwidth_saved against
+	ISOsByArch             map[string]int64  `json:"isos_by_arch"`
+	ISOsByEdition          map[string]int64  `json:"isos_by_edition"`
+	ISOsByStatus           map[string]int64  `json:"isos_by_status"`
+	ISOsByVerification     map[string]int64  `json:"isos_by_verification"` // Keyed by VerificationState
+	TopDownloaded          []ISODownloadStat `json:"top_downloaded"`
+	DownloadsByCountry     map[string]int64  `json:"downloads_by_country"` // From download events with a resolved country
+	DownloadsBySite        map[string]int64  `json:"downloads_by_site"`    // From download events inside a GEOIP_SITES network
+	QueueDepth             int               `json:"queue_depth"`          // Downloads waiting for a free worker
+	QueueCapacity          int               `json:"queue_capacity"`       // QUEUE_BUFFER; new downloads are rejected when full
+	WorkerPanics           int64             `json:"worker_panics"`        // Panics download workers recovered from since startup
+	GroupBy                string            `json:"group_by,omitempty"`
+	Groups                 []StatsGroup      `json:"groups,omitempty"` // Set when group_by is requested
+}
+
+// StatsGroup aggregates the ISOs sharing one value of the group_by column.
+type StatsGroup struct {
+	Key           string `json:"key"`
+	ISOCount      int64  `json:"iso_count"`
+	DownloadCount int64  `json:"download_count"`
+	SizeBytes     int64  `json:"size_bytes"` // Complete ISOs only
+	BytesServed   int64  `json:"bytes_served"`
 }
 
 // ISODownloadStat represents download statistics for a single ISO.
@@ -25,6 +48,7 @@ type ISODownloadStat struct {
 	Arch          string `json:"arch"`
 	DownloadCount int64  `json:"download_count"`
 	SizeBytes     int64  `json:"size_bytes"`
+	BytesServed   int64  `json:"bytes_served"`
 }
 
 // DownloadTrend represents download trends over time.
@@ -44,4 +68,81 @@ type DownloadEvent struct {
 	ID           int64     `json:"id"`
 	ISOID        string    `json:"iso_id"`
 	DownloadedAt time.Time `json:"downloaded_at"`
+	Country      string    `json:"country"`    // ISO 3166 code from GEOIP_DB; empty when unknown
+	Site         string    `json:"site"`       // Matching GEOIP_SITES entry; empty when none
+	ClientIP     string    `json:"client_ip"`  // As kept under ANALYTICS_CLIENT_IP; empty when none
+	UserAgent    string    `json:"user_agent"` // Empty unless ANALYTICS_USER_AGENT is set
+}
+
+// StatsResetRequest resets an ISO's download statistics.
+type StatsResetRequest struct {
+	Reason string `json:"reason"`
+}
+
+// StatsAdjustRequest adds delta (which may be negative) to an ISO's download count.
+type StatsAdjustRequest struct {
+	Delta  int64  `json:"delta" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+// LiveThroughput is a sample of aggregate transfer rates.
+type LiveThroughput struct {
+	SampledAt         time.Time `json:"sampled_at"`
+	IngestBytesPerSec int64     `json:"ingest_bytes_per_sec"` // Downloads from upstream
+	EgressBytesPerSec int64     `json:"egress_bytes_per_sec"` // Files served under /images
+	IngestBytesTotal  int64     `json:"ingest_bytes_total"`   // Since startup
+	EgressBytesTotal  int64     `json:"egress_bytes_total"`   // Since startup
+}
+
+// ActiveDownload is a download a worker is running or verifying.
+type ActiveDownload struct {
+	ISOID           string    `json:"iso_id"`
+	Name            string    `json:"name"`
+	Version         string    `json:"version"`
+	Arch            string    `json:"arch"`
+	Edition         string    `json:"edition"`
+	Node            string    `json:"node"`             // Instance holding the download lock
+	WorkerID        int       `json:"worker_id"`        // Download worker that fetched it; -1 on another node
+	Status          ISOStatus `json:"status"`           // downloading or verifying
+	BytesDownloaded int64     `json:"bytes_downloaded"` // By the current attempt; a stall retry starts over
+	BytesTotal      int64     `json:"bytes_total"`      // Zero when upstream sent no length
+	BytesPerSec     int64     `json:"bytes_per_sec"`    // Average over the current attempt; zero while verifying
+	StartedAt       time.Time `json:"started_at"`
+}
+
+// QueueForecast estimates when each queued download will start and finish,
+// from the downloads ahead of it and the throughput of recent downloads.
+type QueueForecast struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	BytesPerSec int64           `json:"bytes_per_sec"` // Per download, over recent completed downloads; zero without history
+	Queue       []QueueEstimate `json:"queue"`
+}
+
+// QueueEstimate is the forecast for one queued download.
+type QueueEstimate struct {
+	ISOID                 string     `json:"iso_id"`
+	Name                  string     `json:"name"`
+	Version               string     `json:"version"`
+	Arch                  string     `json:"arch"`
+	Edition               string     `json:"edition"`
+	Position              int        `json:"position"`   // 1 is next in line
+	SizeBytes             int64      `json:"size_bytes"` // Zero when unknown; a typical duration is assumed
+	FastLane              bool       `json:"fast_lane"`
+	EstimatedStartAt      *time.Time `json:"estimated_start_at"` // Nil without download history to go by
+	EstimatedCompletionAt *time.Time `json:"estimated_completion_at"`
+}
+
+// Instance status values reported by GET /status.
+const (
+	InstanceStatusOK       = "ok"
+	InstanceStatusDegraded = "degraded" // Serving, but the health monitor found a problem
+	InstanceStatusDown     = "down"     // The database can't be queried
+)
+
+// InstanceStatus is the public summary served by GET /status.
+type InstanceStatus struct {
+	LastSyncAt   *time.Time `json:"last_sync_at"` // When the most recent download completed
+	Status       string     `json:"status"`
+	Problems     []string   `json:"problems"`
+	CompleteISOs int64      `json:"complete_isos"`
 }
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// RelocationState is the state of a storage relocation.
+type RelocationState string
+
+const (
+	RelocationRunning  RelocationState = "running"
+	RelocationComplete RelocationState = "complete"
+	RelocationFailed   RelocationState = "failed"
+)
+
+// RelocateStorageRequest represents a request to copy the ISO directory to a
+// new location ahead of switching ISO_DIR to it.
+type RelocateStorageRequest struct {
+	Target string `json:"target" binding:"required"`
+}
+
+// Relocation reports the progress of copying the ISO directory to a new
+// location. Files already in the target with the same size and modification
+// time are skipped, so a relocation can be repeated to pick up changes.
+type Relocation struct {
+	StartedAt    time.Time       `json:"started_at"`
+	FinishedAt   *time.Time      `json:"finished_at"`
+	Source       string          `json:"source"`
+	Target       string          `json:"target"`
+	State        RelocationState `json:"state"`
+	Error        string          `json:"error,omitempty"`
+	FilesTotal   int             `json:"files_total"`
+	FilesCopied  int             `json:"files_copied"`
+	FilesSkipped int             `json:"files_skipped"`
+	BytesTotal   int64           `json:"bytes_total"`
+	BytesCopied  int64           `json:"bytes_copied"`
+}
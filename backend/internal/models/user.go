@@ -0,0 +1,84 @@
+package models
+
+import (
+	"regexp"
+	"time"
+)
+
+// usernamePattern restricts usernames to characters that are safe in logs and URLs.
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._@-]{0,63}$`)
+
+// IsValidUsername checks that a username is 1-64 letters, digits, '.', '_', '@' or '-'.
+func IsValidUsername(username string) bool {
+	return usernamePattern.MatchString(username)
+}
+
+// User is a local account that can sign in to the management API.
+type User struct {
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	LastLoginAt  *time.Time `json:"last_login_at"`
+	ID           string     `json:"id"`
+	Username     string     `json:"username"`
+	PasswordHash string     `json:"-"` // bcrypt
+	TOTPSecret   string     `json:"-"` // Base32; set while enrolling or enrolled
+	TOTPLastStep int64      `json:"-"` // Last accepted time step, so codes can't be replayed
+	TOTPEnabled  bool       `json:"totp_enabled"`
+}
+
+// Session is a signed-in browser or API client. ID is the SHA-256 of the
+// session token handed to the client; the token itself is never stored, so
+// the ID can be shown without exposing the session.
+type Session struct {
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username,omitempty"` // Filled in by ListSessions
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Current   bool      `json:"current"` // The session making the request
+}
+
+// LoginRequest represents the request to sign in with a username and password.
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code"` // TOTP or recovery code; required once two-factor is enabled
+}
+
+// LoginResponse is returned after a successful sign-in. Token is the session
+// token, also set as a cookie; send it as a Bearer token from non-browser
+// clients. Clients using the cookie instead send CSRFToken in the
+// X-CSRF-Token header on requests that change anything.
+type LoginResponse struct {
+	User      *User     `json:"user"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Token     string    `json:"token"`
+	CSRFToken string    `json:"csrf_token"`
+}
+
+// TOTPCodeRequest carries a TOTP code, e.g. to confirm enrollment.
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableTOTPRequest represents the request to turn off two-factor
+// authentication. Code may be a TOTP or a recovery code.
+type DisableTOTPRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// TOTPEnrollment is returned when enrollment starts. The secret is shown
+// once; scan URL as a QR code or type the secret into an authenticator app.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URL    string `json:"url"`
+}
+
+// RecoveryCodes are single-use codes that replace a TOTP code when the
+// authenticator is lost. They are shown once.
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// Verification states of each check in a VerificationReport.
+const (
+	VerificationVerified   = "verified"   // Passed for the current file
+	VerificationUnverified = "unverified" // Configured, but no download has passed it yet
+	VerificationNone       = "none"       // Not configured for the ISO
+)
+
+// Checksum sources of a ChecksumVerification.
+const (
+	ChecksumMethodFile     = "checksum_file"     // Fetched from checksum_url
+	ChecksumMethodExpected = "expected_checksum" // Given on create or update
+)
+
+// VerificationReport traces how an ISO's file was verified, from where it
+// was fetched to the hashes recorded for it, so its provenance can be audited.
+type VerificationReport struct {
+	GeneratedAt  time.Time              `json:"generated_at"`
+	ISOID        string                 `json:"iso_id"`
+	Filename     string                 `json:"filename"`
+	DownloadLink string                 `json:"download_link"`
+	Status       ISOStatus              `json:"status"`
+	Source       VerificationSource     `json:"source"`
+	Checksum     ChecksumVerification   `json:"checksum"`
+	Signature    SignatureVerification  `json:"signature"`
+	Hashes       VerificationHashes     `json:"hashes"`
+	Timestamps   VerificationTimestamps `json:"timestamps"`
+	Verified     bool                   `json:"verified"` // At least one check is configured and all of them passed
+}
+
+// VerificationSource is where the file came from.
+type VerificationSource struct {
+	DownloadURL          string            `json:"download_url"`
+	SourceURL            string            `json:"source_url"`     // URL that served the file, after redirects
+	SourceHeaders        map[string]string `json:"source_headers"` // Server, CDN, and mirror headers of that response
+	UpstreamETag         string            `json:"upstream_etag"`
+	UpstreamLastModified string            `json:"upstream_last_modified"`
+	UpstreamChanged      bool              `json:"upstream_changed"` // Republished upstream since the download
+}
+
+// ChecksumVerification is the upstream checksum the file was compared with.
+type ChecksumVerification struct {
+	Status      string `json:"status"`
+	Method      string `json:"method"` // ChecksumMethodFile or ChecksumMethodExpected; empty when none
+	URL         string `json:"url"`
+	Type        string `json:"type"`
+	Expected    string `json:"expected"`
+	SidecarLink string `json:"sidecar_link"` // Saved copy of the checksum file; empty when there is none
+}
+
+// SignatureVerification is the detached signature the file was checked against.
+type SignatureVerification struct {
+	Status      string `json:"status"`
+	URL         string `json:"url"`
+	Signer      string `json:"signer"`
+	SidecarLink string `json:"sidecar_link"` // Saved copy of the signature; empty when there is none
+}
+
+// VerificationHashes are the digests computed while downloading.
+type VerificationHashes struct {
+	SHA256        string `json:"sha256"`
+	SHA512        string `json:"sha512"`
+	MD5           string `json:"md5"`
+	IntegrityHash string `json:"integrity_hash"`
+	SizeBytes     int64  `json:"size_bytes"`
+}
+
+// VerificationTimestamps are the points in the ISO's life relevant to an audit.
+type VerificationTimestamps struct {
+	CreatedAt         time.Time  `json:"created_at"`
+	DownloadStartedAt *time.Time `json:"download_started_at"`
+	CompletedAt       *time.Time `json:"completed_at"`
+	VerifiedAt        *time.Time `json:"verified_at"`
+	FileModTime       *time.Time `json:"file_mtime"`
+	UpstreamCheckedAt *time.Time `json:"upstream_checked_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+// SchemaInfo reports the database schema version against the migrations this
+// build ships, so operators can spot replicas running a different release than
+// the one that last migrated a shared database.
+type SchemaInfo struct {
+	Version    uint `json:"version"`    // Last applied migration; 0 for an empty database
+	Latest     uint `json:"latest"`     // Last migration shipped with this build
+	Dirty      bool `json:"dirty"`      // A migration failed partway and needs fixing by hand
+	Pending    uint `json:"pending"`    // Migrations this build has that the database lacks
+	Compatible bool `json:"compatible"` // The database is at exactly this build's version
+}
+
+// VersionInfo is the response of GET /api/version.
+type VersionInfo struct {
+	Version string     `json:"version"`
+	Schema  SchemaInfo `json:"schema"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// IsValidWebhookName reports whether name can be used in a webhook URL.
+func IsValidWebhookName(name string) bool {
+	return credentialNamePattern.MatchString(name)
+}
+
+// Webhook turns inbound release announcements into ISO downloads. Each field
+// of its template is a Go text/template rendered against the JSON payload.
+type Webhook struct {
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Template  WebhookTemplate `json:"template"`
+	Name      string          `json:"name"`
+	Filter    string          `json:"filter"`           // Template that must render "true" for a delivery to act; empty accepts all
+	Secret    string          `json:"-"`                // Sealed HMAC secret
+	NewSecret string          `json:"secret,omitempty"` // Only set on creation or rotation
+}
+
+// WebhookTemplate holds one template per CreateISORequest field. Empty
+// optional templates leave the field empty.
+type WebhookTemplate struct {
+	Name         string `json:"name"`
+	Version      string `json:"version"`
+	Arch         string `json:"arch"`
+	Edition      string `json:"edition"`
+	DownloadURL  string `json:"download_url"`
+	ChecksumURL  string `json:"checksum_url"`
+	ChecksumType string `json:"checksum_type"`
+	IPFamily     string `json:"ip_family"`
+	Credential   string `json:"credential"`
+}
+
+// CreateWebhookRequest represents the request to create a webhook. The
+// signing secret is generated and returned once.
+type CreateWebhookRequest struct {
+	Name     string          `json:"name" binding:"required"`
+	Filter   string          `json:"filter"`
+	Template WebhookTemplate `json:"template"`
+}
+
+// UpdateWebhookRequest represents the allowed fields for updating a webhook.
+// A new template replaces the stored one entirely.
+type UpdateWebhookRequest struct {
+	Template     *WebhookTemplate `json:"template"`
+	Filter       *string          `json:"filter"`
+	RotateSecret bool             `json:"rotate_secret"`
+}
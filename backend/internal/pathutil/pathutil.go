@@ -2,6 +2,8 @@ package pathutil
 
 import (
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 // Returns: "/data/isos/alpine/3.19.1/x86_64/alpine-3.19.1-x86_64.iso".
@@ -10,8 +12,8 @@ func ConstructISOPath(isoDir, filePath string) string {
 }
 
 // Returns: "/data/isos/.tmp/alpine-3.19.1-x86_64.iso".
-func ConstructTempPath(isoDir, filename string) string {
-	return filepath.Join(isoDir, ".tmp", filename)
+func ConstructTempPath(tmpDir, filename string) string {
+	return filepath.Join(tmpDir, filename)
 }
 
 // Returns: "/data/isos/file.iso.sha256".
@@ -24,9 +26,37 @@ func GetTempDir(isoDir string) string {
 	return filepath.Join(isoDir, ".tmp")
 }
 
-// GetDBDir returns the database directory path.
-func GetDBDir(dataDir string) string {
-	return filepath.Join(dataDir, "db")
+// ResolveTempDir returns the configured download temp directory, or the default under isoDir when unset.
+func ResolveTempDir(isoDir, configured string) string {
+	if configured == "" {
+		return GetTempDir(isoDir)
+	}
+	return configured
+}
+
+// GetQuarantineDir returns the directory holding files flagged by the antivirus scan.
+func GetQuarantineDir(isoDir string) string {
+	return filepath.Join(isoDir, ".quarantine")
+}
+
+// Returns: "/data/isos/.quarantine/alpine/3.19.1/x86_64/alpine-3.19.1-x86_64.iso".
+func ConstructQuarantinePath(isoDir, filePath string) string {
+	return filepath.Join(GetQuarantineDir(isoDir), filePath)
+}
+
+// GetVersionsDir returns the directory holding files replaced by a refresh.
+func GetVersionsDir(isoDir string) string {
+	return filepath.Join(isoDir, ".versions")
+}
+
+// Returns: "/data/isos/.versions/alpine/3.19.1/x86_64/alpine-3.19.1-x86_64.iso.20240101T000000Z".
+func ConstructVersionPath(isoDir, filePath string, replacedAt time.Time) string {
+	return filepath.Join(GetVersionsDir(isoDir), filePath) + "." + replacedAt.UTC().Format("20060102T150405Z")
+}
+
+// VersionGlob returns a glob pattern matching every kept version of filePath.
+func VersionGlob(isoDir, filePath string) string {
+	return filepath.Join(GetVersionsDir(isoDir), filePath) + ".*"
 }
 
 // GetISODir returns the ISO storage directory path.
@@ -34,7 +64,37 @@ func GetISODir(dataDir string) string {
 	return filepath.Join(dataDir, "isos")
 }
 
+// ResolveISODir returns the configured ISO storage directory, or the default under dataDir when unset.
+func ResolveISODir(dataDir, configured string) string {
+	if configured == "" {
+		return GetISODir(dataDir)
+	}
+	return configured
+}
+
 // GetDBPath returns the full database file path.
 func GetDBPath(dataDir string) string {
 	return filepath.Join(dataDir, "db", "isos.db")
 }
+
+// ResolveDBPath returns the configured database file path, or the default under dataDir when unset.
+func ResolveDBPath(dataDir, configured string) string {
+	if configured == "" {
+		return GetDBPath(dataDir)
+	}
+	return configured
+}
+
+// IsWithin reports whether path is root or lies inside it, comparing absolute paths.
+func IsWithin(root, path string) bool {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return false
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absRoot, absPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,250 @@
+// Package repometa signs repository metadata in the style of The Update
+// Framework (TUF): a root role naming the trusted key, and a targets role
+// listing every published file with its length and hashes. Both roles are
+// signed with one Ed25519 key, so a consumer that pins the key (or root.json)
+// can verify the whole mirror rather than one file at a time.
+package repometa
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// SpecVersion is the TUF specification version the metadata follows.
+const SpecVersion = "1.0.31"
+
+// Role names.
+const (
+	RoleRoot    = "root"
+	RoleTargets = "targets"
+)
+
+// Key types and signature schemes.
+const (
+	KeyTypeEd25519 = "ed25519"
+	SchemeEd25519  = "ed25519"
+)
+
+// ErrBadSignature is returned by Verify when metadata isn't signed by the key.
+var ErrBadSignature = errors.New("metadata is not signed by the key")
+
+// Key is a public key as listed in root metadata.
+type Key struct {
+	KeyType string `json:"keytype"`
+	Scheme  string `json:"scheme"`
+	KeyVal  KeyVal `json:"keyval"`
+}
+
+// KeyVal holds the hex-encoded public key.
+type KeyVal struct {
+	Public string `json:"public"`
+}
+
+// Role lists the keys trusted for a role and how many must sign.
+type Role struct {
+	KeyIDs    []string `json:"keyids"`
+	Threshold int      `json:"threshold"`
+}
+
+// Root is the signed part of root.json.
+type Root struct {
+	Type               string          `json:"_type"`
+	SpecVersion        string          `json:"spec_version"`
+	Version            int64           `json:"version"`
+	Expires            string          `json:"expires"`
+	Keys               map[string]Key  `json:"keys"`
+	Roles              map[string]Role `json:"roles"`
+	ConsistentSnapshot bool            `json:"consistent_snapshot"`
+}
+
+// Targets is the signed part of targets.json.
+type Targets struct {
+	Type        string                `json:"_type"`
+	SpecVersion string                `json:"spec_version"`
+	Version     int64                 `json:"version"`
+	Expires     string                `json:"expires"`
+	Targets     map[string]TargetFile `json:"targets"`
+}
+
+// TargetFile describes one published file.
+type TargetFile struct {
+	Length int64             `json:"length"`
+	Hashes map[string]string `json:"hashes"`
+}
+
+// Signed is a metadata file: the signed role and its signatures.
+type Signed struct {
+	Signatures []Signature      `json:"signatures"`
+	Signed     *json.RawMessage `json:"signed"`
+}
+
+// Signature is one key's signature over the canonical form of Signed.
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// Signer signs metadata with an Ed25519 repository key.
+type Signer struct {
+	key    ed25519.PrivateKey
+	public Key
+	keyID  string
+}
+
+// LoadSigner reads a PEM-encoded PKCS #8 Ed25519 private key, as written by
+// `openssl genpkey -algorithm ed25519`.
+func LoadSigner(path string) (*Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("repository key %s is not PEM encoded", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse repository key: %w", err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("repository key must be Ed25519, got %T", parsed)
+	}
+	return NewSigner(key)
+}
+
+// NewSigner creates a signer for key.
+func NewSigner(key ed25519.PrivateKey) (*Signer, error) {
+	public := Key{
+		KeyType: KeyTypeEd25519,
+		Scheme:  SchemeEd25519,
+		KeyVal:  KeyVal{Public: hex.EncodeToString(key.Public().(ed25519.PublicKey))},
+	}
+	keyID, err := KeyID(public)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{key: key, public: public, keyID: keyID}, nil
+}
+
+// KeyID returns a key's ID: the SHA-256 of its canonical JSON form.
+func KeyID(key Key) (string, error) {
+	data, err := CanonicalJSON(key)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// KeyID returns the ID of the signer's public key.
+func (s *Signer) KeyID() string {
+	return s.keyID
+}
+
+// PublicKey returns the signer's public key as listed in root metadata.
+func (s *Signer) PublicKey() Key {
+	return s.public
+}
+
+// PublicKeyPEM returns the signer's public key as a PEM-encoded PKIX key, for
+// checking SignBytes signatures with standard tools.
+func (s *Signer) PublicKeyPEM() ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(s.key.Public())
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// Root returns root metadata that trusts the signer's key for both roles.
+func (s *Signer) Root(version int64, expires time.Time) *Root {
+	role := Role{KeyIDs: []string{s.keyID}, Threshold: 1}
+	return &Root{
+		Type:        RoleRoot,
+		SpecVersion: SpecVersion,
+		Version:     version,
+		Expires:     FormatExpires(expires),
+		Keys:        map[string]Key{s.keyID: s.public},
+		Roles:       map[string]Role{RoleRoot: role, RoleTargets: role},
+	}
+}
+
+// Sign signs role and returns the metadata file.
+func (s *Signer) Sign(role any) ([]byte, error) {
+	canonical, err := CanonicalJSON(role)
+	if err != nil {
+		return nil, err
+	}
+	raw := json.RawMessage(canonical)
+	signed := Signed{
+		Signatures: []Signature{{KeyID: s.keyID, Sig: hex.EncodeToString(ed25519.Sign(s.key, canonical))}},
+		Signed:     &raw,
+	}
+	return json.MarshalIndent(signed, "", "  ")
+}
+
+// SignBytes returns a raw Ed25519 signature over data, e.g. a SHA256SUMS file.
+func (s *Signer) SignBytes(data []byte) []byte {
+	return ed25519.Sign(s.key, data)
+}
+
+// Verify checks that a metadata file is signed by key and decodes the signed
+// role into role.
+func Verify(data []byte, key Key, role any) error {
+	var signed Signed
+	if err := json.Unmarshal(data, &signed); err != nil {
+		return fmt.Errorf("failed to decode metadata: %w", err)
+	}
+	if signed.Signed == nil {
+		return errors.New("metadata has no signed role")
+	}
+	var decoded any
+	if err := unmarshalNumbers(*signed.Signed, &decoded); err != nil {
+		return fmt.Errorf("failed to decode signed role: %w", err)
+	}
+	canonical, err := encodeCanonical(decoded)
+	if err != nil {
+		return err
+	}
+
+	public, err := hex.DecodeString(key.KeyVal.Public)
+	if err != nil || len(public) != ed25519.PublicKeySize {
+		return errors.New("invalid public key")
+	}
+	keyID, err := KeyID(key)
+	if err != nil {
+		return err
+	}
+	for _, sig := range signed.Signatures {
+		raw, err := hex.DecodeString(sig.Sig)
+		if err != nil || sig.KeyID != keyID {
+			continue
+		}
+		if ed25519.Verify(public, canonical, raw) {
+			return json.Unmarshal(*signed.Signed, role)
+		}
+	}
+	return ErrBadSignature
+}
+
+// FormatExpires formats an expiry time as TUF metadata expects.
+func FormatExpires(t time.Time) string {
+	return t.UTC().Truncate(time.Second).Format(time.RFC3339)
+}
+
+// unmarshalNumbers decodes JSON keeping numbers exact.
+func unmarshalNumbers(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
@@ -0,0 +1,130 @@
+package repometa
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSigner(t *testing.T) *Signer {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() failed: %v", err)
+	}
+	signer, err := NewSigner(key)
+	if err != nil {
+		t.Fatalf("NewSigner() failed: %v", err)
+	}
+	return signer
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	got, err := CanonicalJSON(map[string]any{
+		"b": []any{1, "x<y>&\"z\\"},
+		"a": map[string]any{"d": true, "c": nil},
+	})
+	if err != nil {
+		t.Fatalf("CanonicalJSON() failed: %v", err)
+	}
+	want := `{"a":{"c":null,"d":true},"b":[1,"x<y>&\"z\\"]}`
+	if string(got) != want {
+		t.Errorf("CanonicalJSON() = %s, want %s", got, want)
+	}
+
+	if _, err := CanonicalJSON(map[string]any{"f": 1.5}); err == nil {
+		t.Error("Expected floats to be rejected")
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	signer := newTestSigner(t)
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 6, time.UTC)
+
+	data, err := signer.Sign(&Targets{
+		Type:        RoleTargets,
+		SpecVersion: SpecVersion,
+		Version:     7,
+		Expires:     FormatExpires(expires),
+		Targets: map[string]TargetFile{
+			"alpine/3.19.1/x86_64/alpine-3.19.1-x86_64.iso": {Length: 4, Hashes: map[string]string{"sha256": "abc"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Sign() failed: %v", err)
+	}
+
+	var targets Targets
+	if err := Verify(data, signer.PublicKey(), &targets); err != nil {
+		t.Fatalf("Verify() failed: %v", err)
+	}
+	if targets.Version != 7 || targets.Expires != "2030-01-02T03:04:05Z" || len(targets.Targets) != 1 {
+		t.Errorf("Unexpected targets: %+v", targets)
+	}
+
+	// Another key, or a change to the signed role, fails verification
+	if err := Verify(data, newTestSigner(t).PublicKey(), &targets); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("Expected ErrBadSignature for another key, got %v", err)
+	}
+	tampered := strings.Replace(string(data), `"length": 4`, `"length": 5`, 1)
+	if tampered == string(data) {
+		t.Fatal("Expected to tamper with the length")
+	}
+	if err := Verify([]byte(tampered), signer.PublicKey(), &targets); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("Expected ErrBadSignature for a tampered role, got %v", err)
+	}
+}
+
+func TestRoot(t *testing.T) {
+	signer := newTestSigner(t)
+	data, err := signer.Sign(signer.Root(1, time.Now().Add(time.Hour)))
+	if err != nil {
+		t.Fatalf("Sign() failed: %v", err)
+	}
+
+	var root Root
+	if err := Verify(data, signer.PublicKey(), &root); err != nil {
+		t.Fatalf("Verify() failed: %v", err)
+	}
+	if root.Type != RoleRoot || root.Keys[signer.KeyID()].KeyVal.Public != signer.PublicKey().KeyVal.Public {
+		t.Errorf("Expected the signer's key in root, got %+v", root)
+	}
+	for _, name := range []string{RoleRoot, RoleTargets} {
+		if role := root.Roles[name]; len(role.KeyIDs) != 1 || role.KeyIDs[0] != signer.KeyID() || role.Threshold != 1 {
+			t.Errorf("Unexpected %s role: %+v", name, role)
+		}
+	}
+}
+
+func TestLoadSigner(t *testing.T) {
+	dir := t.TempDir()
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey() failed: %v", err)
+	}
+	path := filepath.Join(dir, "repo.pem")
+	os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)
+
+	signer, err := LoadSigner(path)
+	if err != nil {
+		t.Fatalf("LoadSigner() failed: %v", err)
+	}
+	sig := signer.SignBytes([]byte("SHA256SUMS"))
+	if !ed25519.Verify(key.Public().(ed25519.PublicKey), []byte("SHA256SUMS"), sig) {
+		t.Error("Expected SignBytes() to sign with the loaded key")
+	}
+
+	notPEM := filepath.Join(dir, "key.txt")
+	os.WriteFile(notPEM, []byte("not a key"), 0o600)
+	if _, err := LoadSigner(notPEM); err == nil {
+		t.Error("Expected an error for a file that isn't PEM")
+	}
+}
@@ -0,0 +1,98 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testKey(fill byte) []byte {
+	return bytes.Repeat([]byte{fill}, KeySize)
+}
+
+func TestBoxRoundTrip(t *testing.T) {
+	box, err := NewBox(testKey(1))
+	if err != nil {
+		t.Fatalf("NewBox() error: %v", err)
+	}
+
+	sealed, err := box.Seal([]byte("hunter2"))
+	if err != nil {
+		t.Fatalf("Seal() error: %v", err)
+	}
+	if strings.Contains(sealed, "hunter2") {
+		t.Fatal("Sealed value contains the plaintext")
+	}
+
+	again, _ := box.Seal([]byte("hunter2"))
+	if again == sealed {
+		t.Error("Expected a fresh nonce for every seal")
+	}
+
+	plaintext, err := box.Open(sealed)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if string(plaintext) != "hunter2" {
+		t.Errorf("Open() = %q, want %q", plaintext, "hunter2")
+	}
+
+	other, _ := NewBox(testKey(2))
+	if _, err := other.Open(sealed); err == nil {
+		t.Error("Expected Open() with a different key to fail")
+	}
+}
+
+func TestNilBox(t *testing.T) {
+	var box *Box
+	if _, err := box.Seal([]byte("x")); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Seal() error = %v, want ErrNoKey", err)
+	}
+	if _, err := box.Open("v1:AAAA"); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Open() error = %v, want ErrNoKey", err)
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	key := testKey(7)
+	encoded := base64.StdEncoding.EncodeToString(key)
+	dir := t.TempDir()
+
+	encodedFile := filepath.Join(dir, "key.b64")
+	os.WriteFile(encodedFile, []byte(encoded+"\n"), 0o600)
+	rawFile := filepath.Join(dir, "key.bin")
+	os.WriteFile(rawFile, key, 0o600)
+
+	tests := []struct {
+		name    string
+		value   string
+		file    string
+		want    []byte
+		wantErr bool
+	}{
+		{"Unset", "", "", nil, false},
+		{"Value", encoded, "", key, false},
+		{"ValueWinsOverFile", encoded, filepath.Join(dir, "missing"), key, false},
+		{"EncodedFile", "", encodedFile, key, false},
+		{"RawFile", "", rawFile, key, false},
+		{"MissingFile", "", filepath.Join(dir, "missing"), nil, true},
+		{"NotBase64", "not base64!", "", nil, true},
+		{"WrongLength", base64.StdEncoding.EncodeToString([]byte("short")), "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadKey(tt.value, tt.file)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("LoadKey() = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+// Package selftest checks that the server can start with its configuration,
+// without starting it, so deployment pipelines can gate a rollout on the
+// result of `server --self-test`.
+package selftest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/config"
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/cron"
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/download"
+	"github.com/aloks98/isoman/backend/internal/geoip"
+	"github.com/aloks98/isoman/backend/internal/httputil"
+	"github.com/aloks98/isoman/backend/internal/i18n"
+	"github.com/aloks98/isoman/backend/internal/pathutil"
+	"github.com/aloks98/isoman/backend/internal/repometa"
+	"github.com/aloks98/isoman/backend/internal/secrets"
+	"github.com/aloks98/isoman/backend/internal/validation"
+)
+
+// Check names, in the order they run.
+const (
+	CheckConfig       = "config"
+	CheckDataDirs     = "data_dirs"
+	CheckDatabase     = "database"
+	CheckMigrations   = "migrations"
+	CheckConnectivity = "connectivity"
+)
+
+// Check statuses. Only a failed check fails the self-test.
+const (
+	StatusPass = "pass"
+	StatusWarn = "warn" // The server starts, but without something that was configured
+	StatusFail = "fail" // The server would refuse to start, or fail once running
+	StatusSkip = "skip" // Nothing to check, e.g. no probe URL
+)
+
+// Check is the outcome of one check.
+type Check struct {
+	Name       string   `json:"name"`
+	Status     string   `json:"status"`
+	Message    string   `json:"message"`
+	Problems   []string `json:"problems,omitempty"` // Each thing wrong, when there are several
+	DurationMS int64    `json:"duration_ms"`
+}
+
+// Result is the outcome of a self-test.
+type Result struct {
+	Version string  `json:"version"`
+	Checks  []Check `json:"checks"`
+	OK      bool    `json:"ok"` // No check failed
+}
+
+// Run checks the configuration, the data directories, the database and its
+// migrations, and outbound connectivity. It creates nothing: directories and
+// a database that don't exist yet pass when the server could create them.
+func Run(ctx context.Context, cfg *config.Config) *Result {
+	isoDir := pathutil.ResolveISODir(cfg.Download.DataDir, cfg.Download.ISODir)
+	dbPath := pathutil.ResolveDBPath(cfg.Download.DataDir, cfg.Database.Path)
+	tmpDir := pathutil.ResolveTempDir(isoDir, cfg.Download.TempDir)
+
+	result := &Result{Version: cfg.Version, OK: true}
+	run := func(name string, check func() Check) {
+		start := time.Now()
+		c := check()
+		c.Name = name
+		c.DurationMS = time.Since(start).Milliseconds()
+		if c.Status == StatusFail {
+			result.OK = false
+		}
+		result.Checks = append(result.Checks, c)
+	}
+
+	run(CheckConfig, func() Check { return checkConfig(cfg, isoDir, dbPath) })
+	run(CheckDataDirs, func() Check { return checkDataDirs(isoDir, filepath.Dir(dbPath), tmpDir) })
+
+	_, err := os.Stat(dbPath)
+	missing := errors.Is(err, fs.ErrNotExist)
+	var schema *db.SchemaStatus
+	run(CheckDatabase, func() Check {
+		if missing {
+			return Check{Status: StatusPass, Message: fmt.Sprintf("%s doesn't exist yet; it is created at startup", dbPath)}
+		}
+		c, status := checkDatabase(dbPath, &cfg.Database)
+		schema = status
+		return c
+	})
+	run(CheckMigrations, func() Check { return checkMigrations(schema, missing, cfg.Database.AutoMigrate) })
+	run(CheckConnectivity, func() Check { return checkConnectivity(ctx, cfg) })
+
+	return result
+}
+
+// checkConfig repeats the validation the server does at startup.
+func checkConfig(cfg *config.Config, isoDir, dbPath string) Check {
+	var problems, warnings []string
+	fail := func(format string, args ...any) { problems = append(problems, fmt.Sprintf(format, args...)) }
+
+	if pathutil.IsWithin(isoDir, dbPath) {
+		fail("database %s must not be inside the ISO directory %s", dbPath, isoDir)
+	}
+	if key, err := secrets.LoadKey(cfg.Download.CredentialsKey, cfg.Download.CredentialsKeyFile); err != nil {
+		fail("CREDENTIALS_KEY: %v", err)
+	} else if key != nil {
+		if _, err := secrets.NewBox(key); err != nil {
+			fail("CREDENTIALS_KEY: %v", err)
+		}
+	}
+	if _, err := i18n.Load(cfg.Server.Locale, cfg.Server.LocaleDir); err != nil {
+		fail("LOCALE: %v", err)
+	}
+	if cfg.Repo.SigningKey != "" {
+		if _, err := repometa.LoadSigner(cfg.Repo.SigningKey); err != nil {
+			fail("REPO_SIGNING_KEY: %v", err)
+		}
+	}
+	if _, err := geoip.ParseSites(cfg.Server.GeoIPSites); err != nil {
+		fail("GEOIP_SITES: %v", err)
+	}
+	if cfg.Server.GeoIPDB != "" {
+		if _, err := geoip.Open(cfg.Server.GeoIPDB); err != nil {
+			fail("GEOIP_DB: %v", err)
+		}
+	}
+	if mode := strings.ToLower(cfg.Server.AnalyticsClientIP); mode != "" && !constants.IsValidAnalyticsClientIPMode(mode) {
+		fail("ANALYTICS_CLIENT_IP: invalid mode %q", mode)
+	}
+	if _, err := download.ParseMirrorGroups(cfg.Download.MirrorGroups); err != nil {
+		fail("MIRROR_GROUPS: %v", err)
+	}
+	if policy := cfg.Download.MirrorRotation; policy != "" && !constants.IsValidMirrorRotation(policy) {
+		fail("MIRROR_ROTATION: invalid policy %q", policy)
+	}
+
+	// The server logs these and carries on without the feature
+	if rc := cfg.Report; rc.SMTPHost != "" && len(rc.Recipients) > 0 && rc.Schedule != "" {
+		if _, err := cron.Parse(rc.Schedule); err != nil {
+			warnings = append(warnings, fmt.Sprintf("REPORT_SCHEDULE: %v; reports are disabled", err))
+		}
+	}
+	if cfg.Snapshot.Schedule != "" {
+		if _, err := cron.Parse(cfg.Snapshot.Schedule); err != nil {
+			warnings = append(warnings, fmt.Sprintf("CATALOG_SNAPSHOT_SCHEDULE: %v; catalog snapshots are disabled", err))
+		}
+	}
+	if _, err := validation.ParseNetworks(cfg.Download.HTTPAllowedNetworks); err != nil {
+		warnings = append(warnings, fmt.Sprintf("HTTP_ALLOWED_NETWORKS: %v; invalid entries are ignored", err))
+	}
+
+	switch {
+	case len(problems) > 0:
+		return Check{Status: StatusFail, Message: fmt.Sprintf("%d invalid settings", len(problems)), Problems: append(problems, warnings...)}
+	case len(warnings) > 0:
+		return Check{Status: StatusWarn, Message: fmt.Sprintf("%d settings are ignored", len(warnings)), Problems: warnings}
+	}
+	return Check{Status: StatusPass, Message: "configuration is valid"}
+}
+
+// checkDataDirs checks that files can be created in each directory, or in the
+// nearest existing parent of one that doesn't exist yet.
+func checkDataDirs(dirs ...string) Check {
+	var problems []string
+	for _, dir := range dirs {
+		if err := checkWritable(dir); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		return Check{Status: StatusFail, Message: fmt.Sprintf("%d directories are not writable", len(problems)), Problems: problems}
+	}
+	return Check{Status: StatusPass, Message: fmt.Sprintf("%d directories are writable", len(dirs))}
+}
+
+func checkWritable(dir string) error {
+	target := dir
+	for {
+		info, err := os.Stat(target)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s: %s is not a directory", dir, target)
+			}
+			break
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%s: %w", dir, err)
+		}
+		parent := filepath.Dir(target)
+		if parent == target {
+			return fmt.Errorf("%s: no existing parent directory", dir)
+		}
+		target = parent
+	}
+
+	f, err := os.CreateTemp(target, ".isoman-self-test-*")
+	if err != nil {
+		if target != dir {
+			return fmt.Errorf("%s: cannot be created: %w", dir, err)
+		}
+		return fmt.Errorf("%s: %w", dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// checkDatabase opens the database and reads its schema version. It returns
+// the schema status for checkMigrations, or nil when it couldn't be read.
+func checkDatabase(dbPath string, cfg *config.DatabaseConfig) (Check, *db.SchemaStatus) {
+	f, err := os.OpenFile(dbPath, os.O_RDWR, 0)
+	if err != nil {
+		return Check{Status: StatusFail, Message: fmt.Sprintf("%s is not writable: %v", dbPath, err)}, nil
+	}
+	f.Close()
+
+	database, err := db.Open(dbPath, cfg)
+	if err != nil {
+		return Check{Status: StatusFail, Message: err.Error()}, nil
+	}
+	defer database.Close()
+
+	status, err := database.SchemaStatus()
+	if err != nil {
+		return Check{Status: StatusFail, Message: err.Error()}, nil
+	}
+	if status.Version > 0 && !status.Dirty {
+		if err := database.Ping(); err != nil {
+			return Check{Status: StatusFail, Message: err.Error()}, status
+		}
+	}
+	return Check{Status: StatusPass, Message: fmt.Sprintf("%s is readable", dbPath)}, status
+}
+
+// checkMigrations reports whether the schema is current, or will be made so
+// at startup. A nil status means the database couldn't be read.
+func checkMigrations(status *db.SchemaStatus, missing, autoMigrate bool) Check {
+	switch {
+	case missing && autoMigrate:
+		return Check{Status: StatusPass, Message: "migrations are applied to the new database at startup"}
+	case missing:
+		return Check{Status: StatusFail, Message: "the new database needs migrating; run `server migrate` or set DB_AUTO_MIGRATE=true"}
+	case status == nil:
+		return Check{Status: StatusSkip, Message: "schema version unknown"}
+	case status.Dirty:
+		return Check{Status: StatusFail, Message: fmt.Sprintf("migration %d failed partway and needs fixing by hand", status.Version)}
+	case status.Version > status.Latest:
+		return Check{Status: StatusFail, Message: fmt.Sprintf("schema version %d is newer than this build's %d", status.Version, status.Latest)}
+	case status.Version == status.Latest:
+		return Check{Status: StatusPass, Message: fmt.Sprintf("schema is at version %d", status.Version)}
+	case autoMigrate:
+		return Check{Status: StatusPass, Message: fmt.Sprintf("schema version %d is migrated to %d at startup", status.Version, status.Latest)}
+	}
+	return Check{Status: StatusFail, Message: fmt.Sprintf("schema version %d is behind %d; run `server migrate` or set DB_AUTO_MIGRATE=true", status.Version, status.Latest)}
+}
+
+// checkConnectivity fetches the probe URL with the upstream HTTP client. Any
+// HTTP response passes: the check is about reaching the network, not about
+// the probe's content.
+func checkConnectivity(ctx context.Context, cfg *config.Config) Check {
+	if cfg.SelfTest.ProbeURL == "" {
+		return Check{Status: StatusSkip, Message: "SELF_TEST_PROBE_URL is not set"}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.SelfTest.ProbeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, cfg.SelfTest.ProbeURL, http.NoBody)
+	if err != nil {
+		return Check{Status: StatusFail, Message: fmt.Sprintf("invalid SELF_TEST_PROBE_URL: %v", err)}
+	}
+	resp, err := httputil.Client().Do(req)
+	if err != nil {
+		return Check{Status: StatusFail, Message: err.Error()}
+	}
+	resp.Body.Close()
+	return Check{Status: StatusPass, Message: fmt.Sprintf("%s answered %s", cfg.SelfTest.ProbeURL, resp.Status)}
+}
@@ -0,0 +1,144 @@
+package selftest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/config"
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/pathutil"
+)
+
+// testConfig returns the default configuration with its data under a temp directory.
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg := config.Load()
+	cfg.Download.DataDir = filepath.Join(t.TempDir(), "data")
+	cfg.Download.ISODir = ""
+	cfg.Download.TempDir = ""
+	cfg.Database.Path = ""
+	cfg.SelfTest.ProbeURL = ""
+	return cfg
+}
+
+func statuses(result *Result) map[string]string {
+	m := make(map[string]string)
+	for _, c := range result.Checks {
+		m[c.Name] = c.Status
+	}
+	return m
+}
+
+func TestRunFreshInstall(t *testing.T) {
+	cfg := testConfig(t)
+	result := Run(context.Background(), cfg)
+
+	if !result.OK {
+		t.Fatalf("Expected a fresh install to pass, got %+v", result.Checks)
+	}
+	want := map[string]string{
+		CheckConfig:       StatusPass,
+		CheckDataDirs:     StatusPass,
+		CheckDatabase:     StatusPass,
+		CheckMigrations:   StatusPass,
+		CheckConnectivity: StatusSkip,
+	}
+	got := statuses(result)
+	for name, status := range want {
+		if got[name] != status {
+			t.Errorf("%s = %s, want %s", name, got[name], status)
+		}
+	}
+
+	// Nothing is created
+	if _, err := os.Stat(cfg.Download.DataDir); !os.IsNotExist(err) {
+		t.Errorf("Expected the data directory left uncreated, got %v", err)
+	}
+
+	cfg.Database.AutoMigrate = false
+	if result := Run(context.Background(), cfg); result.OK || statuses(result)[CheckMigrations] != StatusFail {
+		t.Errorf("Expected a new database without DB_AUTO_MIGRATE to fail, got %+v", result.Checks)
+	}
+}
+
+func TestRunMigratedDatabase(t *testing.T) {
+	cfg := testConfig(t)
+	dbPath := pathutil.ResolveDBPath(cfg.Download.DataDir, cfg.Database.Path)
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	database, err := db.New(dbPath, &cfg.Database)
+	if err != nil {
+		t.Fatalf("db.New() failed: %v", err)
+	}
+	database.Close()
+
+	cfg.Database.AutoMigrate = false
+	result := Run(context.Background(), cfg)
+	if !result.OK {
+		t.Fatalf("Expected a migrated database to pass, got %+v", result.Checks)
+	}
+	if got := statuses(result); got[CheckDatabase] != StatusPass || got[CheckMigrations] != StatusPass {
+		t.Errorf("Expected database and migrations to pass, got %v", got)
+	}
+}
+
+func TestRunFailures(t *testing.T) {
+	cfg := testConfig(t)
+
+	// A file where the ISO directory should be
+	if err := os.MkdirAll(cfg.Download.DataDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pathutil.ResolveISODir(cfg.Download.DataDir, ""), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Download.CredentialsKey = "not base64"
+	cfg.Snapshot.Schedule = "daily"
+
+	result := Run(context.Background(), cfg)
+	if result.OK {
+		t.Fatal("Expected the self-test to fail")
+	}
+	for _, c := range result.Checks {
+		switch c.Name {
+		case CheckConfig:
+			if c.Status != StatusFail || len(c.Problems) != 2 {
+				t.Errorf("Expected the key to fail and the schedule to be reported, got %+v", c)
+			}
+		case CheckDataDirs:
+			if c.Status != StatusFail || len(c.Problems) != 2 {
+				t.Errorf("Expected the ISO and temp directories to fail, got %+v", c)
+			}
+		}
+	}
+
+	// An ignored setting only warns
+	cfg = testConfig(t)
+	cfg.Snapshot.Schedule = "daily"
+	if result := Run(context.Background(), cfg); !result.OK || statuses(result)[CheckConfig] != StatusWarn {
+		t.Errorf("Expected an invalid schedule to warn, got %+v", result.Checks)
+	}
+}
+
+func TestRunConnectivity(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	cfg := testConfig(t)
+
+	// Any response shows the network is reachable
+	cfg.SelfTest.ProbeURL = ts.URL
+	if got := statuses(Run(context.Background(), cfg))[CheckConnectivity]; got != StatusPass {
+		t.Errorf("connectivity = %s, want pass", got)
+	}
+
+	ts.Close()
+	if result := Run(context.Background(), cfg); result.OK || statuses(result)[CheckConnectivity] != StatusFail {
+		t.Errorf("Expected an unreachable probe to fail, got %+v", result.Checks)
+	}
+}
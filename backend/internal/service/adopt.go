@@ -0,0 +1,236 @@
+package service
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/fileutil"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/pathutil"
+
+	"github.com/google/uuid"
+)
+
+// DefaultAdoptRules match isoman's own layout: {name}/{version}/{arch}/{file}.
+var DefaultAdoptRules = []models.AdoptRule{
+	{Pattern: `^(?P<name>[^/]+)/(?P<version>[^/]+)/(?P<arch>[^/]+)/[^/]+$`},
+}
+
+// requiredAdoptGroups are the named groups every adopt rule must define.
+var requiredAdoptGroups = []string{"name", "version", "arch"}
+
+// InvalidAdoptRequestError indicates that an adopt request cannot be processed.
+type InvalidAdoptRequestError struct {
+	Message string
+}
+
+func (e *InvalidAdoptRequestError) Error() string {
+	return e.Message
+}
+
+// AdoptDirectory walks an existing mirror tree and registers every image file whose
+// path matches one of the rules as a complete ISO, without re-downloading it.
+// Files are placed at their computed location in the ISO directory according to the mode.
+func (s *ISOService) AdoptDirectory(req models.AdoptDirectoryRequest) (*models.AdoptResult, error) {
+	sourceDir, err := filepath.Abs(req.SourceDir)
+	if err != nil {
+		return nil, &InvalidAdoptRequestError{Message: fmt.Sprintf("invalid source_dir: %v", err)}
+	}
+	if fi, err := os.Stat(sourceDir); err != nil || !fi.IsDir() {
+		return nil, &InvalidAdoptRequestError{Message: fmt.Sprintf("source_dir is not a directory: %s", req.SourceDir)}
+	}
+
+	mode := req.Mode
+	if mode == "" {
+		mode = models.AdoptModeLink
+	}
+
+	ruleSet := req.Rules
+	if len(ruleSet) == 0 {
+		ruleSet = DefaultAdoptRules
+	}
+	rules, err := compileAdoptRules(ruleSet)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.AdoptResult{
+		Adopted: make([]models.AdoptedFile, 0),
+		Skipped: make([]models.AdoptSkippedFile, 0),
+		DryRun:  req.DryRun,
+	}
+
+	err = filepath.WalkDir(sourceDir, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		// Skip hidden files and directories (e.g. our own .tmp)
+		if path != sourceDir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		if !constants.IsSupportedFileType(ext) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(sourceDir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		iso, reason := s.adoptFile(path, rel, ext, rules, mode, req.DryRun)
+		if iso == nil {
+			result.Skipped = append(result.Skipped, models.AdoptSkippedFile{SourcePath: rel, Reason: reason})
+			return nil
+		}
+		result.Adopted = append(result.Adopted, models.AdoptedFile{SourcePath: rel, ISO: iso})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk source directory: %w", err)
+	}
+
+	slog.Info("adopt directory finished",
+		slog.String("source_dir", sourceDir),
+		slog.Int("adopted", len(result.Adopted)),
+		slog.Int("skipped", len(result.Skipped)),
+		slog.Bool("dry_run", req.DryRun),
+	)
+
+	return result, nil
+}
+
+// adoptFile registers a single file, returning the new ISO or the reason it was skipped.
+func (s *ISOService) adoptFile(path, rel, fileType string, rules []*regexp.Regexp, mode string, dryRun bool) (*models.ISO, string) {
+	fields := matchAdoptRules(rules, rel)
+	if fields == nil {
+		return nil, "no rule matched"
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Sprintf("failed to stat file: %v", err)
+	}
+
+	now := time.Now()
+	iso := &models.ISO{
+		ID:          uuid.New().String(),
+		Name:        fields["name"],
+		Version:     fields["version"],
+		Arch:        fields["arch"],
+		Edition:     fields["edition"],
+		FileType:    fileType,
+		DownloadURL: "file://" + filepath.ToSlash(path),
+		SizeBytes:   fi.Size(),
+		Status:      models.StatusComplete,
+		Progress:    100,
+		CreatedAt:   now,
+		CompletedAt: &now,
+	}
+	ComputeFields(iso)
+
+	if iso.Name == "" {
+		return nil, "name is empty after normalization"
+	}
+
+	exists, err := s.db.ISOExists(iso.Name, iso.Version, iso.Arch, iso.Edition, iso.FileType)
+	if err != nil {
+		return nil, fmt.Sprintf("failed to check for duplicate: %v", err)
+	}
+	if exists {
+		return nil, "ISO already exists"
+	}
+
+	destPath := pathutil.ConstructISOPath(s.isoDir, iso.FilePath)
+	if fileutil.FileExists(destPath) {
+		return nil, "destination file already exists"
+	}
+
+	if dryRun {
+		return iso, ""
+	}
+
+	if err := placeAdoptedFile(path, destPath, mode); err != nil {
+		return nil, err.Error()
+	}
+
+	if err := s.db.CreateISO(iso); err != nil {
+		// Undo the placement so the tree is left as we found it
+		if mode == models.AdoptModeMove {
+			if moveErr := fileutil.MoveFile(destPath, path); moveErr != nil {
+				slog.Warn("failed to restore adopted file", slog.String("path", path), slog.Any("error", moveErr))
+			}
+		} else {
+			fileutil.DeleteFileSilently(destPath)
+		}
+		return nil, fmt.Sprintf("failed to create ISO: %v", err)
+	}
+
+	return iso, ""
+}
+
+// placeAdoptedFile puts the source file at its destination according to mode.
+func placeAdoptedFile(srcPath, destPath, mode string) error {
+	switch mode {
+	case models.AdoptModeMove:
+		return fileutil.MoveFile(srcPath, destPath)
+	case models.AdoptModeCopy:
+		return fileutil.CopyFile(srcPath, destPath)
+	default:
+		return fileutil.LinkOrCopyFile(srcPath, destPath)
+	}
+}
+
+// compileAdoptRules compiles rule patterns and checks that each defines the required groups.
+func compileAdoptRules(rules []models.AdoptRule) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(rules))
+	for i, rule := range rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, &InvalidAdoptRequestError{Message: fmt.Sprintf("rules[%d]: invalid pattern: %v", i, err)}
+		}
+		for _, group := range requiredAdoptGroups {
+			if re.SubexpIndex(group) < 0 {
+				return nil, &InvalidAdoptRequestError{Message: fmt.Sprintf("rules[%d]: pattern must define named group %q", i, group)}
+			}
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// matchAdoptRules returns the named groups of the first rule matching rel, or nil.
+func matchAdoptRules(rules []*regexp.Regexp, rel string) map[string]string {
+	for _, re := range rules {
+		match := re.FindStringSubmatch(rel)
+		if match == nil {
+			continue
+		}
+		fields := make(map[string]string)
+		for i, name := range re.SubexpNames() {
+			if name != "" {
+				fields[name] = match[i]
+			}
+		}
+		if fields["version"] == "" || fields["arch"] == "" {
+			continue
+		}
+		return fields
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/pathutil"
+)
+
+// writeMirrorFile creates a file under root at the given slash-separated path.
+func writeMirrorFile(t *testing.T, root, rel, content string) string {
+	t.Helper()
+
+	path := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	return path
+}
+
+func TestISOService_AdoptDirectory(t *testing.T) {
+	t.Run("DefaultRules", func(t *testing.T) {
+		service, env := setupTestISOService(t)
+		defer env.Cleanup()
+
+		mirror := t.TempDir()
+		src := writeMirrorFile(t, mirror, "alpine/3.19.1/x86_64/alpine-standard.iso", "alpine")
+		writeMirrorFile(t, mirror, "alpine/3.19.1/x86_64/alpine-standard.iso.sha256", "abc")
+		writeMirrorFile(t, mirror, "README.txt", "readme")
+		writeMirrorFile(t, mirror, "stray.iso", "stray")
+
+		result, err := service.AdoptDirectory(models.AdoptDirectoryRequest{SourceDir: mirror})
+		if err != nil {
+			t.Fatalf("AdoptDirectory() failed: %v", err)
+		}
+
+		if len(result.Adopted) != 1 {
+			t.Fatalf("Expected 1 adopted file, got: %d", len(result.Adopted))
+		}
+		if len(result.Skipped) != 1 || result.Skipped[0].SourcePath != "stray.iso" {
+			t.Errorf("Expected stray.iso to be skipped, got: %+v", result.Skipped)
+		}
+
+		iso := result.Adopted[0].ISO
+		if iso.Name != "alpine" || iso.Version != "3.19.1" || iso.Arch != "x86_64" {
+			t.Errorf("Unexpected metadata: %s %s %s", iso.Name, iso.Version, iso.Arch)
+		}
+		if iso.Status != models.StatusComplete {
+			t.Errorf("Status should be 'complete', got: %s", iso.Status)
+		}
+		if iso.SizeBytes != int64(len("alpine")) {
+			t.Errorf("SizeBytes should be %d, got: %d", len("alpine"), iso.SizeBytes)
+		}
+
+		// File is placed at its computed path and the source is left alone
+		destPath := pathutil.ConstructISOPath(env.ISODir, iso.FilePath)
+		if _, err := os.Stat(destPath); err != nil {
+			t.Errorf("Adopted file should exist at %s: %v", destPath, err)
+		}
+		if _, err := os.Stat(src); err != nil {
+			t.Errorf("Source file should remain in link mode: %v", err)
+		}
+
+		if _, err := service.GetISO(iso.ID); err != nil {
+			t.Errorf("Adopted ISO should be in the database: %v", err)
+		}
+
+		// Running again skips the already registered ISO
+		result, err = service.AdoptDirectory(models.AdoptDirectoryRequest{SourceDir: mirror})
+		if err != nil {
+			t.Fatalf("Second AdoptDirectory() failed: %v", err)
+		}
+		if len(result.Adopted) != 0 {
+			t.Errorf("Expected no files adopted on second run, got: %d", len(result.Adopted))
+		}
+	})
+
+	t.Run("CustomRulesAndMove", func(t *testing.T) {
+		service, env := setupTestISOService(t)
+		defer env.Cleanup()
+
+		mirror := t.TempDir()
+		src := writeMirrorFile(t, mirror, "releases/24.04/ubuntu-24.04-live-server-amd64.iso", "ubuntu")
+
+		result, err := service.AdoptDirectory(models.AdoptDirectoryRequest{
+			SourceDir: mirror,
+			Mode:      models.AdoptModeMove,
+			Rules: []models.AdoptRule{
+				{Pattern: `^releases/(?P<version>[^/]+)/(?P<name>ubuntu)-[^-]+-live-(?P<edition>[a-z]+)-(?P<arch>[a-z0-9_]+)\.iso$`},
+			},
+		})
+		if err != nil {
+			t.Fatalf("AdoptDirectory() failed: %v", err)
+		}
+
+		if len(result.Adopted) != 1 {
+			t.Fatalf("Expected 1 adopted file, got: %d (skipped: %+v)", len(result.Adopted), result.Skipped)
+		}
+		iso := result.Adopted[0].ISO
+		if iso.Edition != "server" || iso.Arch != "amd64" {
+			t.Errorf("Unexpected edition/arch: %s/%s", iso.Edition, iso.Arch)
+		}
+		if _, err := os.Stat(src); !os.IsNotExist(err) {
+			t.Error("Source file should be moved away in move mode")
+		}
+	})
+
+	t.Run("DryRun", func(t *testing.T) {
+		service, env := setupTestISOService(t)
+		defer env.Cleanup()
+
+		mirror := t.TempDir()
+		writeMirrorFile(t, mirror, "debian/12/amd64/debian.iso", "debian")
+
+		result, err := service.AdoptDirectory(models.AdoptDirectoryRequest{SourceDir: mirror, DryRun: true})
+		if err != nil {
+			t.Fatalf("AdoptDirectory() failed: %v", err)
+		}
+		if len(result.Adopted) != 1 {
+			t.Fatalf("Expected 1 file reported, got: %d", len(result.Adopted))
+		}
+
+		isos, _ := service.ListISOs()
+		if len(isos) != 0 {
+			t.Errorf("Dry run should not create records, got: %d", len(isos))
+		}
+	})
+
+	t.Run("InvalidRequests", func(t *testing.T) {
+		service, env := setupTestISOService(t)
+		defer env.Cleanup()
+
+		var invalidErr *InvalidAdoptRequestError
+
+		_, err := service.AdoptDirectory(models.AdoptDirectoryRequest{SourceDir: filepath.Join(t.TempDir(), "missing")})
+		if !errors.As(err, &invalidErr) {
+			t.Errorf("Expected InvalidAdoptRequestError for missing directory, got: %v", err)
+		}
+
+		_, err = service.AdoptDirectory(models.AdoptDirectoryRequest{
+			SourceDir: t.TempDir(),
+			Rules:     []models.AdoptRule{{Pattern: `^(?P<name>[^/]+)/(?P<version>[^/]+)$`}},
+		})
+		if !errors.As(err, &invalidErr) {
+			t.Errorf("Expected InvalidAdoptRequestError for rule without arch group, got: %v", err)
+		}
+	})
+}
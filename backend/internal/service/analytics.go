@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/db"
+)
+
+// retentionInterval is how often download events past their retention are deleted.
+const retentionInterval = time.Hour
+
+// AnalyticsPolicy decides what download events keep about clients and for how
+// long. The zero value keeps no client IP or User-Agent and never deletes events.
+type AnalyticsPolicy struct {
+	ClientIP  string        // full, truncate, hash, none; empty keeps nothing
+	UserAgent bool          // Keep the client's User-Agent
+	Retention time.Duration // Age after which download events are deleted; zero keeps them
+}
+
+// SetAnalyticsPolicy sets what download events keep about clients. Hashing
+// uses a key generated on first use and stored in the database, so a client
+// hashes the same across restarts but can't be recovered from the hash.
+func (s *StatsService) SetAnalyticsPolicy(policy AnalyticsPolicy) error {
+	policy.ClientIP = strings.ToLower(policy.ClientIP)
+	if policy.ClientIP != "" && !constants.IsValidAnalyticsClientIPMode(policy.ClientIP) {
+		return fmt.Errorf("invalid client IP mode %q: must be one of full, truncate, hash, none", policy.ClientIP)
+	}
+	if policy.ClientIP == constants.AnalyticsClientIPHash {
+		salt, err := analyticsSalt(s.db, s.random)
+		if err != nil {
+			return err
+		}
+		s.salt = salt
+	}
+	s.policy = policy
+	return nil
+}
+
+// StartRetention deletes download events past the policy's retention once
+// now and then every hour until ctx is canceled. A zero retention disables it.
+func (s *StatsService) StartRetention(ctx context.Context) {
+	if s.policy.Retention <= 0 {
+		return
+	}
+	s.PruneDownloadEvents(s.clock.Now())
+
+	go func() {
+		ticker := s.clock.NewTicker(retentionInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C():
+				s.PruneDownloadEvents(now)
+			}
+		}
+	}()
+}
+
+// PruneDownloadEvents deletes download events older than the policy's
+// retention as of now and returns how many were deleted.
+func (s *StatsService) PruneDownloadEvents(now time.Time) int64 {
+	if s.policy.Retention <= 0 {
+		return 0
+	}
+	n, err := s.db.DeleteDownloadEventsBefore(now.Add(-s.policy.Retention))
+	if err != nil {
+		slog.Warn("failed to delete old download events", slog.Any("error", err))
+		return 0
+	}
+	if n > 0 {
+		slog.Debug("deleted old download events", slog.Int64("count", n), slog.Duration("retention", s.policy.Retention))
+	}
+	return n
+}
+
+// anonymizeClientIP returns what the policy keeps of clientIP.
+func (s *StatsService) anonymizeClientIP(clientIP string) string {
+	ip, err := netip.ParseAddr(clientIP)
+	if err != nil {
+		return ""
+	}
+	ip = ip.Unmap()
+
+	switch s.policy.ClientIP {
+	case constants.AnalyticsClientIPFull:
+		return ip.String()
+	case constants.AnalyticsClientIPTruncate:
+		bits := 24
+		if ip.Is6() {
+			bits = 48
+		}
+		prefix, _ := ip.Prefix(bits)
+		return prefix.Addr().String()
+	case constants.AnalyticsClientIPHash:
+		mac := hmac.New(sha256.New, s.salt)
+		mac.Write(ip.AsSlice())
+		return hex.EncodeToString(mac.Sum(nil))[:16]
+	}
+	return ""
+}
+
+// analyticsSalt returns the key client IPs are hashed with, generating and
+// storing one read from random the first time.
+func analyticsSalt(database *db.DB, random io.Reader) ([]byte, error) {
+	value, err := database.GetSetting(db.SettingAnalyticsSalt)
+	if err == nil {
+		return hex.DecodeString(value)
+	}
+	if !errors.Is(err, db.ErrSettingNotFound) {
+		return nil, err
+	}
+
+	salt := make([]byte, 32)
+	if _, err := io.ReadFull(random, salt); err != nil {
+		return nil, fmt.Errorf("failed to generate analytics salt: %w", err)
+	}
+	if err := database.SetSetting(db.SettingAnalyticsSalt, hex.EncodeToString(salt)); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/geoip"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+)
+
+func TestStatsService_AnalyticsPolicy(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	iso := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{
+		Name:   "alpine",
+		Status: models.StatusComplete,
+	})
+	service := NewStatsService(env.DB)
+	sites, _ := geoip.ParseSites([]string{"hq=192.0.2.128/25", "hq=2001:db8:1:2::/64"})
+	service.SetLocator(geoip.NewLocator(nil, sites))
+
+	if err := service.SetAnalyticsPolicy(AnalyticsPolicy{ClientIP: "partial"}); err == nil {
+		t.Error("Expected an error for an unknown client IP mode")
+	}
+
+	tests := []struct {
+		policy    AnalyticsPolicy
+		clientIP  string
+		wantIP    string
+		wantAgent string
+	}{
+		{AnalyticsPolicy{}, "192.0.2.200", "", ""}, // Nothing kept without a policy
+		{AnalyticsPolicy{ClientIP: constants.AnalyticsClientIPFull, UserAgent: true}, "::ffff:192.0.2.200", "192.0.2.200", "curl/8.5.0"},
+		{AnalyticsPolicy{ClientIP: constants.AnalyticsClientIPTruncate}, "192.0.2.200", "192.0.2.0", ""},
+		{AnalyticsPolicy{ClientIP: constants.AnalyticsClientIPTruncate}, "2001:db8:1:2::1", "2001:db8:1::", ""},
+		{AnalyticsPolicy{ClientIP: constants.AnalyticsClientIPNone, UserAgent: true}, "192.0.2.200", "", "curl/8.5.0"},
+	}
+	for _, tt := range tests {
+		if err := service.SetAnalyticsPolicy(tt.policy); err != nil {
+			t.Fatalf("SetAnalyticsPolicy(%+v) failed: %v", tt.policy, err)
+		}
+		if err := service.RecordDownloadFrom(iso.ID, tt.clientIP, "curl/8.5.0"); err != nil {
+			t.Fatalf("RecordDownloadFrom() failed: %v", err)
+		}
+		events, _ := service.ListDownloadEvents(iso.ID, 1)
+		got := events[0]
+		if got.ClientIP != tt.wantIP || got.UserAgent != tt.wantAgent {
+			t.Errorf("%+v kept %q, %q; want %q, %q", tt.policy, got.ClientIP, got.UserAgent, tt.wantIP, tt.wantAgent)
+		}
+		// Sites are matched on the full IP, not on what is kept
+		if got.Site != "hq" {
+			t.Errorf("%+v resolved site %q, want hq", tt.policy, got.Site)
+		}
+	}
+
+	if _, err := service.ListDownloadEvents("missing", 10); err == nil {
+		t.Error("Expected an error for an unknown ISO")
+	}
+}
+
+func TestStatsService_AnalyticsHash(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	hashOf := func(service *StatsService, ip string) string {
+		t.Helper()
+		if err := service.SetAnalyticsPolicy(AnalyticsPolicy{ClientIP: constants.AnalyticsClientIPHash}); err != nil {
+			t.Fatalf("SetAnalyticsPolicy() failed: %v", err)
+		}
+		return service.anonymizeClientIP(ip)
+	}
+
+	first := hashOf(NewStatsService(env.DB), "192.0.2.1")
+	if len(first) != 16 || first == "192.0.2.1" {
+		t.Fatalf("Expected a 16-character hash, got %q", first)
+	}
+	// The key is stored, so hashes are stable across restarts
+	if again := hashOf(NewStatsService(env.DB), "192.0.2.1"); again != first {
+		t.Errorf("Expected the same hash after a restart, got %q and %q", first, again)
+	}
+	if other := hashOf(NewStatsService(env.DB), "192.0.2.2"); other == first {
+		t.Error("Expected different clients to hash differently")
+	}
+	if got := hashOf(NewStatsService(env.DB), "not-an-ip"); got != "" {
+		t.Errorf("Expected nothing kept for an unparsable IP, got %q", got)
+	}
+}
+
+func TestStatsService_StartRetention(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	iso := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{
+		Name:   "alpine",
+		Status: models.StatusComplete,
+	})
+	now := time.Now()
+	for _, age := range []time.Duration{0, 10 * 24 * time.Hour, 40 * 24 * time.Hour} {
+		env.DB.CreateDownloadEvent(&models.DownloadEvent{ISOID: iso.ID, DownloadedAt: now.Add(-age)})
+	}
+
+	service := NewStatsService(env.DB)
+	if n := service.PruneDownloadEvents(now); n != 0 {
+		t.Errorf("Expected nothing deleted without a retention, got %d", n)
+	}
+
+	service.SetAnalyticsPolicy(AnalyticsPolicy{Retention: 30 * 24 * time.Hour})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service.StartRetention(ctx) // Prunes once right away
+
+	if events, _ := service.ListDownloadEvents(iso.ID, 10); len(events) != 2 {
+		t.Errorf("Expected 2 events within 30 days left, got %d", len(events))
+	}
+}
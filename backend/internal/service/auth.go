@@ -0,0 +1,306 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrInvalidLogin is returned for an unknown username or a wrong password.
+	// The two are deliberately indistinguishable.
+	ErrInvalidLogin = errors.New("invalid username or password")
+	// ErrUnauthenticated is returned when a session token is missing, unknown, or expired.
+	ErrUnauthenticated = errors.New("authentication required")
+)
+
+// AuthService manages local user accounts and their sessions.
+type AuthService struct {
+	db         *db.DB
+	cache      *sessionCache
+	throttle   *authThrottle // Failed logins
+	tokens     *authThrottle // Invalid session tokens, which Login doesn't check
+	now        func() time.Time
+	random     io.Reader // Source of session tokens and recovery codes
+	dummyHash  []byte    // Compared against for unknown users so timing doesn't reveal them
+	dummyOnce  sync.Once
+	sessionTTL time.Duration
+	bcryptCost int
+}
+
+// NewAuthService creates an auth service issuing sessions valid for sessionTTL.
+func NewAuthService(database *db.DB, sessionTTL time.Duration) *AuthService {
+	if sessionTTL <= 0 {
+		sessionTTL = time.Duration(constants.DefaultSessionTTLHours) * time.Hour
+	}
+	return &AuthService{
+		db:         database,
+		cache:      newSessionCache(sessionCacheTTL),
+		throttle:   newAuthThrottle(constants.DefaultAuthMaxFailures, time.Duration(constants.DefaultAuthLockoutMin)*time.Minute),
+		tokens:     newAuthThrottle(constants.DefaultAuthMaxFailures, time.Duration(constants.DefaultAuthLockoutMin)*time.Minute),
+		now:        time.Now,
+		random:     rand.Reader,
+		sessionTTL: sessionTTL,
+		bcryptCost: bcrypt.DefaultCost,
+	}
+}
+
+// SessionTTL returns how long new sessions stay valid.
+func (s *AuthService) SessionTTL() time.Duration {
+	return s.sessionTTL
+}
+
+// CreateUser adds a local user with a bcrypt-hashed password.
+func (s *AuthService) CreateUser(username, password string) (*models.User, error) {
+	if !models.IsValidUsername(username) {
+		return nil, &InvalidUserError{Message: "username must be 1-64 letters, digits, '.', '_', '@' or '-'"}
+	}
+	hash, err := s.hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.db.GetUserByUsername(username); err == nil {
+		return nil, &UserExistsError{Username: username}
+	} else if !errors.Is(err, db.ErrUserNotFound) {
+		return nil, err
+	}
+
+	now := s.now()
+	user := &models.User{
+		ID:           uuid.New().String(),
+		Username:     username,
+		PasswordHash: string(hash),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.db.CreateUser(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// EnsureAdmin creates the first user from the given credentials when no user
+// exists yet, and reports whether it did. Existing users are never touched.
+func (s *AuthService) EnsureAdmin(username, password string) (bool, error) {
+	count, err := s.db.CountUsers()
+	if err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+	if username == "" || password == "" {
+		return false, nil
+	}
+	if _, err := s.CreateUser(username, password); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Login checks a username, password, and, for users with two-factor
+// authentication, a TOTP or recovery code, then opens a session. The returned
+// token is shown to the client once; only its hash is stored. Repeated
+// failures from ip are throttled with a *ThrottledError. Invalid session
+// tokens from ip don't count, so a browser still sending a revoked session
+// can't lock its user out of signing in again.
+func (s *AuthService) Login(username, password, code, ip, userAgent string) (*models.LoginResponse, error) {
+	if err := s.throttle.check(ip, s.now()); err != nil {
+		return nil, err
+	}
+	user, err := s.checkLogin(username, password, code)
+	if errors.Is(err, ErrInvalidLogin) {
+		s.recordFailure(ip, false)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.throttle.reset(ip)
+	s.tokens.reset(ip)
+
+	token, err := newSessionToken(s.random)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	session := &models.Session{
+		ID:        hashSessionToken(token),
+		UserID:    user.ID,
+		IP:        ip,
+		UserAgent: userAgent,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.sessionTTL),
+	}
+	if err := s.db.CreateSession(session); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.RecordUserLogin(user.ID, now); err != nil {
+		slog.Warn("failed to record login", slog.String("user", user.Username), slog.Any("error", err))
+	} else {
+		user.LastLoginAt = &now
+	}
+	if n, err := s.db.DeleteExpiredSessions(now); err != nil {
+		slog.Warn("failed to delete expired sessions", slog.Any("error", err))
+	} else if n > 0 {
+		slog.Debug("deleted expired sessions", slog.Int64("count", n))
+	}
+
+	return &models.LoginResponse{User: user, Token: token, CSRFToken: CSRFToken(token), ExpiresAt: session.ExpiresAt}, nil
+}
+
+// checkLogin verifies a user's password and, with two-factor authentication,
+// code.
+func (s *AuthService) checkLogin(username, password, code string) (*models.User, error) {
+	user, err := s.db.GetUserByUsername(username)
+	if errors.Is(err, db.ErrUserNotFound) {
+		s.dummyOnce.Do(func() {
+			s.dummyHash, _ = bcrypt.GenerateFromPassword([]byte("isoman"), s.bcryptCost)
+		})
+		_ = bcrypt.CompareHashAndPassword(s.dummyHash, []byte(password))
+		return nil, ErrInvalidLogin
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return nil, ErrInvalidLogin
+	}
+	if user.TOTPEnabled {
+		if code == "" {
+			return nil, ErrTOTPRequired
+		}
+		if err := s.verifySecondFactor(user, code); errors.Is(err, ErrInvalidTOTPCode) {
+			return nil, ErrInvalidLogin
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// Authenticate resolves a session token to its user. Sessions are cached
+// briefly; revoking one through the service takes effect immediately.
+func (s *AuthService) Authenticate(token string) (*models.User, *models.Session, error) {
+	return s.AuthenticateFrom(token, "")
+}
+
+// AuthenticateFrom is Authenticate for a request from ip. Invalid tokens count
+// towards ip's own token failure limit, apart from failed logins, and while ip
+// is throttled for either every token is refused with a *ThrottledError. A
+// missing token doesn't count as a failure.
+func (s *AuthService) AuthenticateFrom(token, ip string) (*models.User, *models.Session, error) {
+	if token == "" {
+		return nil, nil, ErrUnauthenticated
+	}
+	id, now := hashSessionToken(token), s.now()
+	if err := s.throttle.check(ip, now); err != nil {
+		return nil, nil, err
+	}
+	if err := s.tokens.check(ip, now); err != nil {
+		return nil, nil, err
+	}
+	if user, session, ok := s.cache.get(id, now); ok {
+		return user, session, nil
+	}
+
+	session, err := s.db.GetSession(id, now)
+	if errors.Is(err, db.ErrSessionNotFound) {
+		s.recordFailure(ip, true)
+		return nil, nil, ErrUnauthenticated
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.db.GetUser(session.UserID)
+	if errors.Is(err, db.ErrUserNotFound) {
+		return nil, nil, ErrUnauthenticated
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	s.cache.put(id, user, session, now)
+	return user, session, nil
+}
+
+// Logout ends the session identified by token.
+func (s *AuthService) Logout(token string) error {
+	if token == "" {
+		return nil
+	}
+	id := hashSessionToken(token)
+	s.cache.evict(id)
+	return s.db.DeleteSession(id)
+}
+
+// hashPassword validates a password's length and hashes it with bcrypt.
+func (s *AuthService) hashPassword(password string) ([]byte, error) {
+	if len(password) < constants.MinPasswordLength {
+		return nil, &InvalidUserError{Message: fmt.Sprintf("password must be at least %d characters", constants.MinPasswordLength)}
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.bcryptCost)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return nil, &InvalidUserError{Message: "password must be at most 72 bytes"}
+	}
+	return hash, err
+}
+
+// newSessionToken returns 32 bytes read from random, base64url-encoded.
+func newSessionToken(random io.Reader) (string, error) {
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(random, b); err != nil {
+		return "", fmt.Errorf("failed to generate session token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CSRFToken derives the CSRF token of a session token. It can't be computed
+// without the session token, which browsers keep in an HttpOnly cookie, so a
+// matching header proves the request came from the UI rather than a foreign page.
+func CSRFToken(sessionToken string) string {
+	mac := hmac.New(sha256.New, []byte(sessionToken))
+	mac.Write([]byte("isoman-csrf"))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// hashSessionToken derives the stored session ID from a token.
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// InvalidUserError indicates that a username or password is unacceptable.
+type InvalidUserError struct {
+	Message string
+}
+
+func (e *InvalidUserError) Error() string {
+	return e.Message
+}
+
+// UserExistsError indicates that a username is taken.
+type UserExistsError struct {
+	Username string
+}
+
+func (e *UserExistsError) Error() string {
+	return fmt.Sprintf("user %q already exists", e.Username)
+}
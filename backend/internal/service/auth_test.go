@@ -0,0 +1,214 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/testutil"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestAuthService(env *testutil.TestEnv) *AuthService {
+	svc := NewAuthService(env.DB, time.Hour)
+	svc.bcryptCost = bcrypt.MinCost
+	return svc
+}
+
+func TestAuthService_LoginAndAuthenticate(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := newTestAuthService(env)
+
+	user, err := svc.CreateUser("admin", "correct horse")
+	if err != nil {
+		t.Fatalf("CreateUser() failed: %v", err)
+	}
+	if !strings.HasPrefix(user.PasswordHash, "$2") {
+		t.Errorf("Expected a bcrypt hash, got %q", user.PasswordHash)
+	}
+
+	if _, err := svc.Login("admin", "wrong password", "", "", ""); !errors.Is(err, ErrInvalidLogin) {
+		t.Errorf("Expected ErrInvalidLogin for a wrong password, got: %v", err)
+	}
+	if _, err := svc.Login("nobody", "correct horse", "", "", ""); !errors.Is(err, ErrInvalidLogin) {
+		t.Errorf("Expected ErrInvalidLogin for an unknown user, got: %v", err)
+	}
+
+	resp, err := svc.Login("ADMIN", "correct horse", "", "192.0.2.1", "test")
+	if err != nil {
+		t.Fatalf("Login() failed: %v", err)
+	}
+	if resp.Token == "" || resp.User.LastLoginAt == nil {
+		t.Errorf("Expected a token and login time, got %+v", resp)
+	}
+
+	got, session, err := svc.Authenticate(resp.Token)
+	if err != nil {
+		t.Fatalf("Authenticate() failed: %v", err)
+	}
+	if got.ID != user.ID || session.IP != "192.0.2.1" {
+		t.Errorf("Authenticate() = %+v, %+v", got, session)
+	}
+	if session.ID == resp.Token {
+		t.Error("Session token is stored in plaintext")
+	}
+
+	if err := svc.Logout(resp.Token); err != nil {
+		t.Fatalf("Logout() failed: %v", err)
+	}
+	if _, _, err := svc.Authenticate(resp.Token); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected ErrUnauthenticated after logout, got: %v", err)
+	}
+}
+
+func TestAuthService_TokensFromRandomSource(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := newTestAuthService(env)
+	svc.random = bytes.NewReader(bytes.Repeat([]byte{0xff}, 32))
+
+	if _, err := svc.CreateUser("admin", "correct horse"); err != nil {
+		t.Fatalf("CreateUser() failed: %v", err)
+	}
+	resp, err := svc.Login("admin", "correct horse", "", "", "")
+	if err != nil {
+		t.Fatalf("Login() failed: %v", err)
+	}
+	if want := strings.Repeat("_", 42) + "8"; resp.Token != want {
+		t.Errorf("Token = %q, expected %q from the injected source", resp.Token, want)
+	}
+
+	// An exhausted source fails the login rather than issuing a weak token
+	if _, err := svc.Login("admin", "correct horse", "", "", ""); err == nil {
+		t.Error("Login() should fail when the random source runs dry")
+	}
+}
+
+func TestAuthService_SessionExpiry(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := newTestAuthService(env)
+
+	if _, err := svc.CreateUser("admin", "correct horse"); err != nil {
+		t.Fatalf("CreateUser() failed: %v", err)
+	}
+	resp, err := svc.Login("admin", "correct horse", "", "", "")
+	if err != nil {
+		t.Fatalf("Login() failed: %v", err)
+	}
+
+	svc.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, _, err := svc.Authenticate(resp.Token); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected ErrUnauthenticated for an expired session, got: %v", err)
+	}
+}
+
+func TestAuthService_CreateUserValidation(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := newTestAuthService(env)
+
+	var invalid *InvalidUserError
+	if _, err := svc.CreateUser("admin", "short"); !errors.As(err, &invalid) {
+		t.Errorf("Expected InvalidUserError for a short password, got: %v", err)
+	}
+	if _, err := svc.CreateUser("bad name", "correct horse"); !errors.As(err, &invalid) {
+		t.Errorf("Expected InvalidUserError for a bad username, got: %v", err)
+	}
+	if _, err := svc.CreateUser("admin", strings.Repeat("x", 73)); !errors.As(err, &invalid) {
+		t.Errorf("Expected InvalidUserError for a password over 72 bytes, got: %v", err)
+	}
+
+	if _, err := svc.CreateUser("admin", "correct horse"); err != nil {
+		t.Fatalf("CreateUser() failed: %v", err)
+	}
+	var exists *UserExistsError
+	if _, err := svc.CreateUser("Admin", "correct horse"); !errors.As(err, &exists) {
+		t.Errorf("Expected UserExistsError, got: %v", err)
+	}
+}
+
+func TestAuthService_EnsureAdmin(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := newTestAuthService(env)
+
+	if created, err := svc.EnsureAdmin("", ""); err != nil || created {
+		t.Errorf("EnsureAdmin() without credentials = %v, %v; want false, nil", created, err)
+	}
+	if created, err := svc.EnsureAdmin("admin", "correct horse"); err != nil || !created {
+		t.Errorf("EnsureAdmin() = %v, %v; want true, nil", created, err)
+	}
+	if created, err := svc.EnsureAdmin("other", "correct horse"); err != nil || created {
+		t.Errorf("EnsureAdmin() with an existing user = %v, %v; want false, nil", created, err)
+	}
+}
+
+func TestAuthService_RevokeSession(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := newTestAuthService(env)
+
+	if _, err := svc.CreateUser("admin", "correct horse"); err != nil {
+		t.Fatalf("CreateUser() failed: %v", err)
+	}
+	current, err := svc.Login("admin", "correct horse", "", "", "")
+	if err != nil {
+		t.Fatalf("Login() failed: %v", err)
+	}
+	leaked, err := svc.Login("admin", "correct horse", "", "", "")
+	if err != nil {
+		t.Fatalf("Login() failed: %v", err)
+	}
+
+	// Warm the cache so revocation has to evict it.
+	_, leakedSession, err := svc.Authenticate(leaked.Token)
+	if err != nil {
+		t.Fatalf("Authenticate() failed: %v", err)
+	}
+
+	sessions, err := svc.ListSessions(current.Token)
+	if err != nil {
+		t.Fatalf("ListSessions() failed: %v", err)
+	}
+	var marked int
+	for _, session := range sessions {
+		if session.Current {
+			marked++
+			if session.ID == leakedSession.ID {
+				t.Error("ListSessions() marked the wrong session current")
+			}
+		}
+	}
+	if len(sessions) != 2 || marked != 1 {
+		t.Errorf("ListSessions() = %+v, want 2 sessions with one current", sessions)
+	}
+
+	if err := svc.RevokeSession(leakedSession.ID); err != nil {
+		t.Fatalf("RevokeSession() failed: %v", err)
+	}
+	if _, _, err := svc.Authenticate(leaked.Token); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected ErrUnauthenticated after revoke, got: %v", err)
+	}
+
+	other, err := svc.Login("admin", "correct horse", "", "", "")
+	if err != nil {
+		t.Fatalf("Login() failed: %v", err)
+	}
+	if _, _, err := svc.Authenticate(other.Token); err != nil {
+		t.Fatalf("Authenticate() failed: %v", err)
+	}
+	if n, err := svc.RevokeSessions("", current.Token); err != nil || n != 1 {
+		t.Errorf("RevokeSessions() = %d, %v; want 1", n, err)
+	}
+	if _, _, err := svc.Authenticate(other.Token); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected ErrUnauthenticated after bulk revoke, got: %v", err)
+	}
+	if _, _, err := svc.Authenticate(current.Token); err != nil {
+		t.Errorf("Expected the caller's session to survive, got: %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+const (
+	// throttleFreeFailures is how many failures an IP gets before it has to wait.
+	throttleFreeFailures = 3
+	// throttleBaseDelay is the wait after the first throttled failure; it
+	// doubles with each further failure up to throttleMaxDelay.
+	throttleBaseDelay = time.Second
+	throttleMaxDelay  = 30 * time.Second
+	// maxThrottledIPs bounds the failure table; forgotten entries are pruned first.
+	maxThrottledIPs = 10000
+)
+
+// authThrottle counts failed logins or invalid tokens per client IP. Past
+// throttleFreeFailures each failure makes the IP wait longer before trying
+// again, and maxFailures locks it out for lockout.
+type authThrottle struct {
+	mu          sync.Mutex
+	maxFailures int // 0 disables throttling
+	lockout     time.Duration
+	clients     map[string]*failureRecord
+}
+
+type failureRecord struct {
+	lastFailure time.Time
+	retryAt     time.Time
+	logins      int
+	tokens      int
+}
+
+func (r *failureRecord) failures() int {
+	return r.logins + r.tokens
+}
+
+func newAuthThrottle(maxFailures int, lockout time.Duration) *authThrottle {
+	return &authThrottle{
+		maxFailures: maxFailures,
+		lockout:     lockout,
+		clients:     make(map[string]*failureRecord),
+	}
+}
+
+// check returns a *ThrottledError while ip has to wait.
+func (t *authThrottle) check(ip string, now time.Time) error {
+	if t.maxFailures <= 0 || ip == "" {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	record, ok := t.clients[ip]
+	if !ok {
+		return nil
+	}
+	if now.Before(record.retryAt) {
+		return &ThrottledError{RetryAfter: record.retryAt.Sub(now), Locked: record.failures() >= t.maxFailures}
+	}
+	return nil
+}
+
+// fail records a failure by ip and returns the audit event to record when it
+// started a delay or a lockout.
+func (t *authThrottle) fail(ip string, token bool, now time.Time) *models.AuditEvent {
+	if t.maxFailures <= 0 {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	record, ok := t.clients[ip]
+	if ok && now.Sub(record.lastFailure) > t.lockout && !now.Before(record.retryAt) {
+		ok = false // Failures are forgotten after a quiet lockout period
+	}
+	if !ok {
+		if len(t.clients) >= maxThrottledIPs {
+			t.prune(now)
+		}
+		record = &failureRecord{}
+		t.clients[ip] = record
+	}
+	if token {
+		record.tokens++
+	} else {
+		record.logins++
+	}
+	record.lastFailure = now
+
+	failures := record.failures()
+	switch {
+	case failures >= t.maxFailures:
+		record.retryAt = now.Add(t.lockout)
+		if failures > t.maxFailures {
+			return nil // Already audited
+		}
+		return &models.AuditEvent{
+			Action:    models.AuditActionAuthLockout,
+			TargetID:  ip,
+			Details:   fmt.Sprintf("%d failed logins, %d invalid tokens; locked out for %s", record.logins, record.tokens, t.lockout),
+			ClientIP:  ip,
+			CreatedAt: now,
+		}
+	case failures > throttleFreeFailures:
+		delay := throttleBaseDelay << (failures - throttleFreeFailures - 1)
+		if delay > throttleMaxDelay || delay <= 0 {
+			delay = throttleMaxDelay
+		}
+		record.retryAt = now.Add(delay)
+		if failures > throttleFreeFailures+1 {
+			return nil
+		}
+		return &models.AuditEvent{
+			Action:    models.AuditActionAuthThrottle,
+			TargetID:  ip,
+			Details:   fmt.Sprintf("%d failed logins, %d invalid tokens; delaying further attempts", record.logins, record.tokens),
+			ClientIP:  ip,
+			CreatedAt: now,
+		}
+	}
+	return nil
+}
+
+// reset forgets ip's failures, e.g. after it signed in.
+func (t *authThrottle) reset(ip string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.clients, ip)
+}
+
+// prune drops entries whose failures have been forgotten, or everything when
+// that frees nothing. The caller holds t.mu.
+func (t *authThrottle) prune(now time.Time) {
+	for ip, record := range t.clients {
+		if now.Sub(record.lastFailure) > t.lockout && !now.Before(record.retryAt) {
+			delete(t.clients, ip)
+		}
+	}
+	if len(t.clients) >= maxThrottledIPs {
+		slog.Warn("auth failure table full, forgetting all failures", slog.Int("entries", len(t.clients)))
+		t.clients = make(map[string]*failureRecord)
+	}
+}
+
+// SetLockout configures brute-force protection: an IP is locked out for
+// lockout after maxFailures failed logins, or separately maxFailures invalid
+// tokens. Before that, each failure past the third makes it wait longer.
+// maxFailures <= 0 disables it.
+func (s *AuthService) SetLockout(maxFailures int, lockout time.Duration) {
+	s.throttle = newAuthThrottle(maxFailures, lockout)
+	s.tokens = newAuthThrottle(maxFailures, lockout)
+}
+
+// recordFailure counts a failure by ip and audits the start of a delay or
+// lockout.
+func (s *AuthService) recordFailure(ip string, token bool) {
+	if ip == "" {
+		return
+	}
+	throttle := s.throttle
+	if token {
+		throttle = s.tokens
+	}
+	event := throttle.fail(ip, token, s.now())
+	if event == nil {
+		return
+	}
+	slog.Warn("repeated authentication failures", slog.String("ip", ip), slog.String("action", event.Action), slog.String("details", event.Details))
+	if err := s.db.RecordAuditEvent(event); err != nil {
+		slog.Warn("failed to record audit event", slog.String("action", event.Action), slog.Any("error", err))
+	}
+}
+
+// ThrottledError indicates that a client failed to authenticate too often and
+// must wait before trying again.
+type ThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool // The failure limit was reached, not just a delay
+}
+
+func (e *ThrottledError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("too many failed attempts, locked out for %s", e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("too many failed attempts, retry in %s", e.RetryAfter.Round(time.Second))
+}
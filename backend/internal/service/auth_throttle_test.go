@@ -0,0 +1,135 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+)
+
+func TestAuthService_LoginThrottle(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := newTestAuthService(env)
+	svc.SetLockout(6, time.Hour)
+
+	if _, err := svc.CreateUser("admin", "correct horse"); err != nil {
+		t.Fatalf("CreateUser() failed: %v", err)
+	}
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+
+	for i := 0; i < throttleFreeFailures; i++ {
+		if _, err := svc.Login("admin", "wrong password", "", "192.0.2.1", ""); !errors.Is(err, ErrInvalidLogin) {
+			t.Fatalf("attempt %d: expected ErrInvalidLogin, got: %v", i+1, err)
+		}
+	}
+	// The next failure starts the delay.
+	if _, err := svc.Login("admin", "wrong password", "", "192.0.2.1", ""); !errors.Is(err, ErrInvalidLogin) {
+		t.Fatalf("Expected ErrInvalidLogin, got: %v", err)
+	}
+	var throttled *ThrottledError
+	if _, err := svc.Login("admin", "correct horse", "", "192.0.2.1", ""); !errors.As(err, &throttled) || throttled.Locked {
+		t.Fatalf("Expected a delay, got: %v", err)
+	}
+	if _, err := svc.Login("admin", "correct horse", "", "198.51.100.1", ""); err != nil {
+		t.Errorf("Expected other IPs to be unaffected, got: %v", err)
+	}
+
+	// Waiting out each delay still reaches the lockout.
+	for i := 0; i < 2; i++ {
+		now = now.Add(throttleMaxDelay)
+		if _, err := svc.Login("admin", "wrong password", "", "192.0.2.1", ""); !errors.Is(err, ErrInvalidLogin) {
+			t.Fatalf("Expected ErrInvalidLogin, got: %v", err)
+		}
+	}
+	now = now.Add(throttleMaxDelay)
+	if _, err := svc.Login("admin", "correct horse", "", "192.0.2.1", ""); !errors.As(err, &throttled) || !throttled.Locked {
+		t.Fatalf("Expected a lockout, got: %v", err)
+	}
+
+	events, err := env.DB.ListAuditEvents(10)
+	if err != nil {
+		t.Fatalf("ListAuditEvents() failed: %v", err)
+	}
+	if len(events) != 2 || events[0].Action != models.AuditActionAuthLockout || events[1].Action != models.AuditActionAuthThrottle || events[0].TargetID != "192.0.2.1" {
+		t.Errorf("Expected throttle and lockout audit events, got %+v", events)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := svc.Login("admin", "correct horse", "", "192.0.2.1", ""); err != nil {
+		t.Errorf("Expected the lockout to expire, got: %v", err)
+	}
+}
+
+func TestAuthService_TokenThrottle(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := newTestAuthService(env)
+	svc.SetLockout(throttleFreeFailures+1, time.Hour)
+
+	for i := 0; i <= throttleFreeFailures; i++ {
+		if _, _, err := svc.AuthenticateFrom("forged", "192.0.2.1"); !errors.Is(err, ErrUnauthenticated) {
+			t.Fatalf("Expected ErrUnauthenticated, got: %v", err)
+		}
+	}
+	if _, _, err := svc.AuthenticateFrom("", "192.0.2.1"); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected a missing token to stay a plain 401, got: %v", err)
+	}
+	var throttled *ThrottledError
+	if _, _, err := svc.AuthenticateFrom("forged", "192.0.2.1"); !errors.As(err, &throttled) || !throttled.Locked {
+		t.Errorf("Expected a lockout after invalid tokens, got: %v", err)
+	}
+}
+
+func TestAuthService_RevokedTokenDoesNotBlockLogin(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := newTestAuthService(env)
+	svc.SetLockout(throttleFreeFailures+1, time.Hour)
+
+	if _, err := svc.CreateUser("admin", "correct horse"); err != nil {
+		t.Fatalf("CreateUser() failed: %v", err)
+	}
+	login, err := svc.Login("admin", "correct horse", "", "192.0.2.1", "")
+	if err != nil {
+		t.Fatalf("Login() failed: %v", err)
+	}
+	if err := svc.RevokeSession(hashSessionToken(login.Token)); err != nil {
+		t.Fatalf("RevokeSession() failed: %v", err)
+	}
+
+	// A browser keeps polling with the revoked session until it signs in again
+	var throttled *ThrottledError
+	for i := 0; i < 10; i++ {
+		if _, _, err := svc.AuthenticateFrom(login.Token, "192.0.2.1"); !errors.Is(err, ErrUnauthenticated) && !errors.As(err, &throttled) {
+			t.Fatalf("Expected the revoked token to be refused, got: %v", err)
+		}
+	}
+	if throttled == nil || !throttled.Locked {
+		t.Errorf("Expected the dead token to be locked out, got: %v", throttled)
+	}
+
+	relogin, err := svc.Login("admin", "correct horse", "", "192.0.2.1", "")
+	if err != nil {
+		t.Fatalf("Expected to sign in again despite the dead token, got: %v", err)
+	}
+	if _, _, err := svc.AuthenticateFrom(relogin.Token, "192.0.2.1"); err != nil {
+		t.Errorf("Expected the new session to be accepted, got: %v", err)
+	}
+}
+
+func TestAuthService_ThrottleDisabled(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := newTestAuthService(env)
+	svc.SetLockout(0, time.Hour)
+
+	for i := 0; i < 20; i++ {
+		if _, _, err := svc.AuthenticateFrom("forged", "192.0.2.1"); !errors.Is(err, ErrUnauthenticated) {
+			t.Fatalf("attempt %d: expected ErrUnauthenticated, got: %v", i+1, err)
+		}
+	}
+}
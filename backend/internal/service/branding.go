@@ -0,0 +1,186 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// Longest branding texts, in characters.
+const (
+	maxBrandingTitle   = 100
+	maxBrandingFooter  = 500
+	maxBrandingLogoURL = 2048
+)
+
+var brandingColorPattern = regexp.MustCompile(`^#([0-9a-f]{3}|[0-9a-f]{6})$`)
+
+// BrandingService stores how the /images directory listing looks: its
+// branding and its columns. Both live in the settings table, so every
+// instance sharing the database lists with them.
+type BrandingService struct {
+	db *db.DB
+}
+
+// NewBrandingService creates a branding service.
+func NewBrandingService(database *db.DB) *BrandingService {
+	return &BrandingService{db: database}
+}
+
+// ListingBranding returns the stored branding, or the zero value (the
+// built-in look) when none has been set.
+func (s *BrandingService) ListingBranding() (*models.ListingBranding, error) {
+	value, err := s.db.GetSetting(db.SettingListingBranding)
+	if errors.Is(err, db.ErrSettingNotFound) {
+		return &models.ListingBranding{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var branding models.ListingBranding
+	if err := json.Unmarshal([]byte(value), &branding); err != nil {
+		return nil, fmt.Errorf("invalid stored listing branding: %w", err)
+	}
+	return &branding, nil
+}
+
+// UpdateListingBranding changes the fields of the branding that req sets.
+func (s *BrandingService) UpdateListingBranding(req models.UpdateListingBrandingRequest) (*models.ListingBranding, error) {
+	branding, err := s.ListingBranding()
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Title != nil {
+		branding.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.LogoURL != nil {
+		branding.LogoURL = strings.TrimSpace(*req.LogoURL)
+	}
+	if req.FooterText != nil {
+		branding.FooterText = strings.TrimSpace(*req.FooterText)
+	}
+	if req.PrimaryColor != nil {
+		branding.PrimaryColor = strings.ToLower(strings.TrimSpace(*req.PrimaryColor))
+	}
+	if req.BackgroundColor != nil {
+		branding.BackgroundColor = strings.ToLower(strings.TrimSpace(*req.BackgroundColor))
+	}
+	if err := validateBranding(branding); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	branding.UpdatedAt = &now
+	value, err := json.Marshal(branding)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.SetSetting(db.SettingListingBranding, string(value)); err != nil {
+		return nil, err
+	}
+	return branding, nil
+}
+
+// ListingColumns returns the columns the listing shows, in display order, or
+// the defaults when none have been chosen.
+func (s *BrandingService) ListingColumns() ([]string, error) {
+	value, err := s.db.GetSetting(db.SettingListingColumns)
+	if errors.Is(err, db.ErrSettingNotFound) {
+		return append([]string{}, constants.DefaultListingColumns...), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []string
+	if err := json.Unmarshal([]byte(value), &columns); err != nil {
+		return nil, fmt.Errorf("invalid stored listing columns: %w", err)
+	}
+	return columns, nil
+}
+
+// SetListingColumns chooses the columns the listing shows. An empty set lists
+// names only. The columns are returned in display order.
+func (s *BrandingService) SetListingColumns(columns []string) ([]string, error) {
+	if columns == nil {
+		return nil, &InvalidBrandingError{Message: "columns is required"}
+	}
+	chosen := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if !constants.IsValidListingColumn(column) {
+			return nil, &InvalidBrandingError{Message: fmt.Sprintf("unknown column %q; must be one of: %s", column, strings.Join(constants.ListingColumns, ", "))}
+		}
+		chosen[column] = true
+	}
+
+	ordered := make([]string, 0, len(chosen))
+	for _, column := range constants.ListingColumns {
+		if chosen[column] {
+			ordered = append(ordered, column)
+		}
+	}
+	value, err := json.Marshal(ordered)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.SetSetting(db.SettingListingColumns, string(value)); err != nil {
+		return nil, err
+	}
+	return ordered, nil
+}
+
+func validateBranding(branding *models.ListingBranding) error {
+	if utf8.RuneCountInString(branding.Title) > maxBrandingTitle {
+		return &InvalidBrandingError{Message: fmt.Sprintf("title must be at most %d characters", maxBrandingTitle)}
+	}
+	if utf8.RuneCountInString(branding.FooterText) > maxBrandingFooter {
+		return &InvalidBrandingError{Message: fmt.Sprintf("footer_text must be at most %d characters", maxBrandingFooter)}
+	}
+	if branding.LogoURL != "" && !isValidLogoURL(branding.LogoURL) {
+		return &InvalidBrandingError{Message: fmt.Sprintf("logo_url must be an http(s) URL or a path starting with '/', at most %d characters", maxBrandingLogoURL)}
+	}
+	if branding.PrimaryColor != "" && !brandingColorPattern.MatchString(branding.PrimaryColor) {
+		return &InvalidBrandingError{Message: "primary_color must be a hex color like #1d4ed8"}
+	}
+	if branding.BackgroundColor != "" && !brandingColorPattern.MatchString(branding.BackgroundColor) {
+		return &InvalidBrandingError{Message: "background_color must be a hex color like #f8fafc"}
+	}
+	return nil
+}
+
+// isValidLogoURL reports whether a logo can be loaded from raw: an absolute
+// http(s) URL, or a path on this server such as one under /images.
+func isValidLogoURL(raw string) bool {
+	if len(raw) > maxBrandingLogoURL {
+		return false
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" {
+		return u.Host == "" && strings.HasPrefix(u.Path, "/")
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// InvalidBrandingError indicates that a branding or column update is malformed.
+type InvalidBrandingError struct {
+	Message string
+}
+
+func (e *InvalidBrandingError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+)
+
+func TestBrandingService(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := NewBrandingService(env.DB)
+
+	branding, err := svc.ListingBranding()
+	if err != nil {
+		t.Fatalf("ListingBranding() failed: %v", err)
+	}
+	if *branding != (models.ListingBranding{}) {
+		t.Errorf("Expected no branding by default, got %+v", branding)
+	}
+
+	title, logo, color := "  Example Corp Mirror ", "/images/branding/logo.svg", "#1D4ED8"
+	if _, err := svc.UpdateListingBranding(models.UpdateListingBrandingRequest{Title: &title, LogoURL: &logo, PrimaryColor: &color}); err != nil {
+		t.Fatalf("UpdateListingBranding() failed: %v", err)
+	}
+	footer := "Operated by Example Corp IT"
+	if _, err := svc.UpdateListingBranding(models.UpdateListingBrandingRequest{FooterText: &footer}); err != nil {
+		t.Fatalf("UpdateListingBranding() failed: %v", err)
+	}
+
+	branding, err = svc.ListingBranding()
+	if err != nil {
+		t.Fatalf("ListingBranding() failed: %v", err)
+	}
+	if branding.Title != "Example Corp Mirror" || branding.LogoURL != logo || branding.FooterText != footer || branding.PrimaryColor != "#1d4ed8" {
+		t.Errorf("Expected both updates kept, normalized, got %+v", branding)
+	}
+	if branding.UpdatedAt == nil {
+		t.Error("Expected the update time recorded")
+	}
+
+	// An empty string restores the default
+	empty := ""
+	if branding, err = svc.UpdateListingBranding(models.UpdateListingBrandingRequest{LogoURL: &empty}); err != nil || branding.LogoURL != "" || branding.Title == "" {
+		t.Errorf("Expected only the logo reset, got %+v, %v", branding, err)
+	}
+}
+
+func TestBrandingService_ListingColumns(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := NewBrandingService(env.DB)
+
+	columns, err := svc.ListingColumns()
+	if err != nil {
+		t.Fatalf("ListingColumns() failed: %v", err)
+	}
+	if !reflect.DeepEqual(columns, constants.DefaultListingColumns) {
+		t.Errorf("Expected the default columns, got %v", columns)
+	}
+
+	// Stored in display order, without duplicates
+	if columns, err = svc.SetListingColumns([]string{"Modified", "checksum", "size", "checksum"}); err != nil {
+		t.Fatalf("SetListingColumns() failed: %v", err)
+	}
+	want := []string{"checksum", "size", "modified"}
+	if !reflect.DeepEqual(columns, want) {
+		t.Errorf("SetListingColumns() = %v, want %v", columns, want)
+	}
+	if columns, _ = svc.ListingColumns(); !reflect.DeepEqual(columns, want) {
+		t.Errorf("ListingColumns() = %v, want %v", columns, want)
+	}
+
+	if columns, err = svc.SetListingColumns([]string{}); err != nil || len(columns) != 0 {
+		t.Errorf("Expected no columns, got %v, %v", columns, err)
+	}
+
+	var invalidErr *InvalidBrandingError
+	if _, err := svc.SetListingColumns([]string{"size", "owner"}); !errors.As(err, &invalidErr) {
+		t.Errorf("Expected InvalidBrandingError for an unknown column, got %v", err)
+	}
+	if _, err := svc.SetListingColumns(nil); !errors.As(err, &invalidErr) {
+		t.Errorf("Expected InvalidBrandingError without columns, got %v", err)
+	}
+}
+
+func TestBrandingService_Invalid(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := NewBrandingService(env.DB)
+
+	str := func(s string) *string { return &s }
+	tests := []struct {
+		name string
+		req  models.UpdateListingBrandingRequest
+	}{
+		{"named color", models.UpdateListingBrandingRequest{PrimaryColor: str("blue")}},
+		{"color without hash", models.UpdateListingBrandingRequest{BackgroundColor: str("ffffff")}},
+		{"css in color", models.UpdateListingBrandingRequest{PrimaryColor: str("#fff;background:url(x)")}},
+		{"javascript logo", models.UpdateListingBrandingRequest{LogoURL: str("javascript:alert(1)")}},
+		{"relative logo", models.UpdateListingBrandingRequest{LogoURL: str("logo.png")}},
+		{"protocol-relative logo", models.UpdateListingBrandingRequest{LogoURL: str("//evil.example.com/logo.png")}},
+		{"long title", models.UpdateListingBrandingRequest{Title: str(string(make([]byte, maxBrandingTitle+1)))}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var invalidErr *InvalidBrandingError
+			if _, err := svc.UpdateListingBranding(tt.req); !errors.As(err, &invalidErr) {
+				t.Errorf("Expected InvalidBrandingError, got %v", err)
+			}
+		})
+	}
+
+	if branding, _ := svc.ListingBranding(); *branding != (models.ListingBranding{}) {
+		t.Errorf("Expected rejected updates not stored, got %+v", branding)
+	}
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/fileutil"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/pathutil"
+
+	"github.com/google/uuid"
+)
+
+// BundleManifestName is the name of the manifest entry at the start of every bundle.
+const BundleManifestName = "manifest.json"
+
+// PrepareBundle builds the manifest for a bundle of the given complete ISOs and
+// their checksum files. It is separate from WriteBundle so callers can report
+// errors before streaming begins.
+func (s *ISOService) PrepareBundle(ids []string) (*models.Manifest, error) {
+	manifest := &models.Manifest{
+		Version:     models.ManifestFormatVersion,
+		GeneratedAt: time.Now().UTC(),
+		Entries:     make([]models.ManifestEntry, 0, len(ids)),
+	}
+
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		iso, err := s.db.GetISO(id)
+		if err != nil {
+			return nil, err
+		}
+		if iso.Status != models.StatusComplete {
+			return nil, &InvalidStateError{
+				CurrentStatus: string(iso.Status),
+				Message:       fmt.Sprintf("Only complete ISOs can be bundled (id=%s)", id),
+			}
+		}
+
+		rel := filepath.ToSlash(iso.FilePath)
+		absPath := pathutil.ConstructISOPath(s.isoDir, iso.FilePath)
+		entry, err := newManifestEntry(absPath, rel, iso)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ISO file (id=%s): %w", id, err)
+		}
+		manifest.Entries = append(manifest.Entries, entry)
+
+		for _, ext := range s.sidecars {
+			if !fileutil.FileExists(absPath + ext) {
+				continue
+			}
+			sidecar, err := newManifestEntry(absPath+ext, rel+ext, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read sidecar file (id=%s): %w", id, err)
+			}
+			manifest.Entries = append(manifest.Entries, sidecar)
+		}
+	}
+
+	return manifest, nil
+}
+
+// WriteBundle writes a tar archive containing the manifest followed by every file it lists.
+func (s *ISOService) WriteBundle(w io.Writer, manifest *models.Manifest) error {
+	tw := tar.NewWriter(w)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     BundleManifestName,
+		Mode:     0o644,
+		Size:     int64(len(data)),
+		ModTime:  manifest.GeneratedAt,
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return fmt.Errorf("failed to write manifest header: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	for _, entry := range manifest.Entries {
+		if err := writeBundleFile(tw, pathutil.ConstructISOPath(s.isoDir, filepath.FromSlash(entry.Path)), entry); err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+// writeBundleFile appends a single file to the archive.
+func writeBundleFile(tw *tar.Writer, absPath string, entry models.ManifestEntry) error {
+	f, err := os.Open(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", entry.Path, err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", entry.Path, err)
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     entry.Path,
+		Mode:     0o644,
+		Size:     entry.SizeBytes,
+		ModTime:  fi.ModTime(),
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return fmt.Errorf("failed to write header for %s: %w", entry.Path, err)
+	}
+	if _, err := io.CopyN(tw, f, entry.SizeBytes); err != nil {
+		return fmt.Errorf("failed to write %s: %w", entry.Path, err)
+	}
+	return nil
+}
+
+// ImportBundle ingests a bundle produced by WriteBundle. Files are staged in the
+// temp directory, then verified and registered exactly like a manifest import.
+func (s *ISOService) ImportBundle(r io.Reader, dryRun bool) (*models.ManifestImportResult, error) {
+	tr := tar.NewReader(r)
+
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != BundleManifestName {
+		return nil, &InvalidAdoptRequestError{Message: "bundle must start with " + BundleManifestName}
+	}
+	var manifest models.Manifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, &InvalidAdoptRequestError{Message: fmt.Sprintf("invalid bundle manifest: %v", err)}
+	}
+
+	listed := make(map[string]bool, len(manifest.Entries))
+	for _, entry := range manifest.Entries {
+		listed[path.Clean(entry.Path)] = true
+	}
+
+	stageDir := filepath.Join(pathutil.GetTempDir(s.isoDir), "bundle-"+uuid.New().String())
+	if err := os.MkdirAll(stageDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(stageDir)
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, &InvalidAdoptRequestError{Message: fmt.Sprintf("invalid bundle: %v", err)}
+		}
+
+		// Only extract regular files the manifest lists; verification happens on import
+		name := path.Clean(hdr.Name)
+		if hdr.Typeflag != tar.TypeReg || !listed[name] || strings.HasPrefix(name, "../") || path.IsAbs(name) {
+			continue
+		}
+		if err := extractBundleFile(tr, filepath.Join(stageDir, filepath.FromSlash(name))); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.ImportManifest(models.ManifestImportRequest{
+		Manifest:  &manifest,
+		SourceDir: stageDir,
+		Mode:      models.AdoptModeMove,
+		DryRun:    dryRun,
+	})
+}
+
+// extractBundleFile writes the current archive entry to dest.
+func extractBundleFile(r io.Reader, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	f, err := os.Create(dest)
+	if err != nil {
+		return fmt.Errorf("failed to create staged file: %w", err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to extract %s: %w", filepath.Base(dest), err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/pathutil"
+)
+
+func TestISOService_Bundle(t *testing.T) {
+	source, srcEnv := setupTestISOService(t)
+	defer srcEnv.Cleanup()
+
+	mirror := t.TempDir()
+	writeMirrorFile(t, mirror, "alpine/3.19.1/x86_64/alpine.iso", "alpine")
+	writeMirrorFile(t, mirror, "debian/12/amd64/debian.iso", "debian")
+	adopted, err := source.AdoptDirectory(models.AdoptDirectoryRequest{SourceDir: mirror})
+	if err != nil || len(adopted.Adopted) != 2 {
+		t.Fatalf("AdoptDirectory() failed: %v", err)
+	}
+
+	var alpine *models.ISO
+	for _, f := range adopted.Adopted {
+		if f.ISO.Name == "alpine" {
+			alpine = f.ISO
+		}
+	}
+	writeMirrorFile(t, srcEnv.ISODir, alpine.FilePath+".sha256", "sum")
+	writeMirrorFile(t, srcEnv.ISODir, alpine.FilePath+".torrent", "torrent")
+	source.SetSidecarExtensions([]string{".torrent", ".sha256"})
+
+	manifest, err := source.PrepareBundle([]string{alpine.ID})
+	if err != nil {
+		t.Fatalf("PrepareBundle() failed: %v", err)
+	}
+	if len(manifest.Entries) != 3 {
+		t.Fatalf("Expected ISO and sidecar entries, got: %+v", manifest.Entries)
+	}
+
+	var buf bytes.Buffer
+	if err := source.WriteBundle(&buf, manifest); err != nil {
+		t.Fatalf("WriteBundle() failed: %v", err)
+	}
+	bundle := buf.Bytes()
+
+	t.Run("Import", func(t *testing.T) {
+		target, env := setupTestISOService(t)
+		defer env.Cleanup()
+
+		result, err := target.ImportBundle(bytes.NewReader(bundle), false)
+		if err != nil {
+			t.Fatalf("ImportBundle() failed: %v", err)
+		}
+		if len(result.Imported) != 1 || len(result.Invalid) != 0 {
+			t.Fatalf("Expected 1 imported ISO, got: %+v", result)
+		}
+
+		iso := result.Imported[0]
+		if iso.Name != "alpine" {
+			t.Errorf("Expected alpine, got: %s", iso.Name)
+		}
+		isoPath := pathutil.ConstructISOPath(env.ISODir, iso.FilePath)
+		if _, err := os.Stat(isoPath); err != nil {
+			t.Errorf("Imported file should exist: %v", err)
+		}
+		for _, ext := range []string{".sha256", ".torrent"} {
+			if _, err := os.Stat(isoPath + ext); err != nil {
+				t.Errorf("Sidecar file should exist: %v", err)
+			}
+		}
+
+		// Staging directory is cleaned up
+		entries, _ := os.ReadDir(pathutil.GetTempDir(env.ISODir))
+		if len(entries) != 0 {
+			t.Errorf("Temp directory should be empty, got %d entries", len(entries))
+		}
+	})
+
+	t.Run("NotComplete", func(t *testing.T) {
+		iso, err := source.CreateISO(context.Background(), CreateISORequest{
+			Name:        "ubuntu",
+			Version:     "24.04",
+			Arch:        "x86_64",
+			DownloadURL: "https://example.com/ubuntu.iso",
+		})
+		if err != nil {
+			t.Fatalf("CreateISO() failed: %v", err)
+		}
+
+		_, err = source.PrepareBundle([]string{iso.ID})
+		var stateErr *InvalidStateError
+		if !errors.As(err, &stateErr) {
+			t.Errorf("Expected InvalidStateError, got: %v", err)
+		}
+	})
+
+	t.Run("MissingManifest", func(t *testing.T) {
+		target, env := setupTestISOService(t)
+		defer env.Cleanup()
+
+		var empty bytes.Buffer
+		tw := tar.NewWriter(&empty)
+		tw.Close()
+
+		_, err := target.ImportBundle(&empty, false)
+		var invalidErr *InvalidAdoptRequestError
+		if !errors.As(err, &invalidErr) {
+			t.Errorf("Expected InvalidAdoptRequestError, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// ChecksumService manages the published checksums kept locally for offline
+// verification.
+type ChecksumService struct {
+	db *db.DB
+}
+
+// NewChecksumService creates a checksum service.
+func NewChecksumService(database *db.DB) *ChecksumService {
+	return &ChecksumService{db: database}
+}
+
+// ListKnownChecksums retrieves the known checksums, all of them or, when
+// checksumURL is set, those of one checksum file.
+func (s *ChecksumService) ListKnownChecksums(checksumURL string) ([]models.KnownChecksum, error) {
+	return s.db.ListKnownChecksums(checksumURL)
+}
+
+// ImportChecksums validates and stores a batch of published checksums,
+// replacing any already known for the same checksum file and filename. The
+// batch is stored whole or not at all.
+func (s *ChecksumService) ImportChecksums(req models.ChecksumImportRequest) (*models.ChecksumImportResult, error) {
+	now := time.Now()
+	checksums := make([]models.KnownChecksum, 0, len(req.Checksums))
+	for i, known := range req.Checksums {
+		known.ChecksumURL = strings.TrimSpace(known.ChecksumURL)
+		known.Filename = strings.TrimSpace(known.Filename)
+		known.Checksum = strings.ToLower(strings.TrimSpace(known.Checksum))
+		switch {
+		case known.ChecksumURL == "":
+			return nil, &InvalidChecksumImportError{Message: fmt.Sprintf("checksums[%d]: checksum_url is required", i)}
+		case known.Filename == "":
+			return nil, &InvalidChecksumImportError{Message: fmt.Sprintf("checksums[%d]: filename is required", i)}
+		case !isHexDigest(known.Checksum):
+			return nil, &InvalidChecksumImportError{Message: fmt.Sprintf("checksums[%d]: checksum must be an MD5, SHA-256, or SHA-512 hex digest", i)}
+		}
+		if known.Source == "" {
+			known.Source = req.Source
+		}
+		known.UpdatedAt = now
+		checksums = append(checksums, known)
+	}
+
+	if err := s.db.SaveKnownChecksums(checksums); err != nil {
+		return nil, err
+	}
+	return &models.ChecksumImportResult{Imported: len(checksums)}, nil
+}
+
+// ImportChecksumFile imports the checksums in a JSON file laid out like an
+// import request, such as one bundled with a deployment.
+func (s *ChecksumService) ImportChecksumFile(path string) (*models.ChecksumImportResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checksum file: %w", err)
+	}
+	var req models.ChecksumImportRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("failed to parse checksum file %s: %w", path, err)
+	}
+	return s.ImportChecksums(req)
+}
+
+// isHexDigest reports whether s is an MD5, SHA-256, or SHA-512 hex digest.
+func isHexDigest(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil && constants.ChecksumTypeForDigest(s) != ""
+}
+
+// InvalidChecksumImportError indicates that a checksum import is malformed.
+type InvalidChecksumImportError struct {
+	Message string
+}
+
+func (e *InvalidChecksumImportError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+)
+
+func TestImportChecksums(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := NewChecksumService(env.DB)
+
+	const sumsURL = "https://releases.example.com/24.04/SHA256SUMS"
+	result, err := svc.ImportChecksums(models.ChecksumImportRequest{
+		Source: "bundle-2026.10",
+		Checksums: []models.KnownChecksum{
+			{ChecksumURL: sumsURL, Filename: "desktop.iso", Checksum: " " + strings.Repeat("AB", 32) + " "},
+			{ChecksumURL: sumsURL, Filename: "server.iso", Checksum: strings.Repeat("cd", 32), Source: "mirror"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ImportChecksums() failed: %v", err)
+	}
+	if result.Imported != 2 {
+		t.Errorf("Imported = %d, expected 2", result.Imported)
+	}
+
+	known, err := env.DB.GetKnownChecksum(sumsURL, "desktop.iso")
+	if err != nil || known == nil {
+		t.Fatalf("GetKnownChecksum() = %v, %v", known, err)
+	}
+	if known.Checksum != strings.Repeat("ab", 32) || known.Source != "bundle-2026.10" {
+		t.Errorf("Unexpected known checksum: %+v", known)
+	}
+	if listed, _ := svc.ListKnownChecksums(sumsURL); len(listed) != 2 || listed[1].Source != "mirror" {
+		t.Errorf("Unexpected checksums of %s: %+v", sumsURL, listed)
+	}
+	if listed, _ := svc.ListKnownChecksums("https://other.example.com/SUMS"); len(listed) != 0 {
+		t.Errorf("Expected no checksums of another file, got %+v", listed)
+	}
+
+	// A bad entry rejects the whole batch
+	_, err = svc.ImportChecksums(models.ChecksumImportRequest{Checksums: []models.KnownChecksum{
+		{ChecksumURL: sumsURL, Filename: "desktop.iso", Checksum: strings.Repeat("ef", 32)},
+		{ChecksumURL: sumsURL, Filename: "netboot.iso", Checksum: "not-a-digest"},
+	}})
+	var invalidErr *InvalidChecksumImportError
+	if !errors.As(err, &invalidErr) {
+		t.Fatalf("Expected InvalidChecksumImportError, got %v", err)
+	}
+	if known, _ := env.DB.GetKnownChecksum(sumsURL, "desktop.iso"); known.Checksum != strings.Repeat("ab", 32) {
+		t.Errorf("Rejected import replaced a checksum: %+v", known)
+	}
+}
+
+func TestImportChecksumFile(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := NewChecksumService(env.DB)
+
+	path := filepath.Join(t.TempDir(), "checksums.json")
+	data := `{"source": "bundle", "checksums": [{"checksum_url": "https://example.com/MD5SUMS", "filename": "mini.iso", "checksum": "` + strings.Repeat("0", 32) + `"}]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := svc.ImportChecksumFile(path); err != nil || result.Imported != 1 {
+		t.Fatalf("ImportChecksumFile() = %+v, %v", result, err)
+	}
+	if _, err := svc.ImportChecksumFile(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/httputil"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/secrets"
+)
+
+// ErrCredentialsDisabled is returned when credentials are stored or used
+// without a master key.
+var ErrCredentialsDisabled = errors.New("credentials are disabled: set CREDENTIALS_KEY or CREDENTIALS_KEY_FILE")
+
+// CredentialService stores upstream credentials sealed with the master key
+// and applies them to upstream requests.
+type CredentialService struct {
+	db  *db.DB
+	box *secrets.Box // nil when no master key is configured
+}
+
+// NewCredentialService creates a credential service. A nil box disables
+// storing and using credentials; listing and deleting still work.
+func NewCredentialService(database *db.DB, box *secrets.Box) *CredentialService {
+	return &CredentialService{
+		db:  database,
+		box: box,
+	}
+}
+
+// Enabled reports whether a master key is configured.
+func (s *CredentialService) Enabled() bool {
+	return s.box != nil
+}
+
+// ListCredentials retrieves all credentials without their secrets.
+func (s *CredentialService) ListCredentials() ([]models.Credential, error) {
+	return s.db.ListCredentials()
+}
+
+// GetCredential retrieves a credential by name without its secret.
+func (s *CredentialService) GetCredential(name string) (*models.Credential, error) {
+	return s.db.GetCredential(name)
+}
+
+// CreateCredential seals and stores a new credential.
+func (s *CredentialService) CreateCredential(req models.CreateCredentialRequest) (*models.Credential, error) {
+	if s.box == nil {
+		return nil, ErrCredentialsDisabled
+	}
+	if !models.IsValidCredentialName(req.Name) {
+		return nil, &InvalidCredentialError{Message: "name must be 1-64 lowercase letters, digits, '.', '_' or '-'"}
+	}
+	if err := req.Secret.Validate(req.Type); err != nil {
+		return nil, &InvalidCredentialError{Message: err.Error()}
+	}
+
+	if _, err := s.db.GetCredential(req.Name); err == nil {
+		return nil, &CredentialExistsError{Name: req.Name}
+	} else if !errors.Is(err, db.ErrCredentialNotFound) {
+		return nil, err
+	}
+
+	sealed, err := s.seal(&req.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	cred := &models.Credential{
+		Name:      req.Name,
+		Type:      req.Type,
+		Host:      normalizeHost(req.Host),
+		Secret:    sealed,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.db.CreateCredential(cred); err != nil {
+		return nil, err
+	}
+	return cred, nil
+}
+
+// UpdateCredential changes the host of a credential or replaces its secret.
+func (s *CredentialService) UpdateCredential(name string, req models.UpdateCredentialRequest) (*models.Credential, error) {
+	cred, err := s.db.GetCredential(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Type != nil && *req.Type != cred.Type {
+		if req.Secret == nil {
+			return nil, &InvalidCredentialError{Message: "changing the type requires a new secret"}
+		}
+		cred.Type = *req.Type
+	}
+	if req.Host != nil {
+		cred.Host = normalizeHost(*req.Host)
+	}
+	if req.Secret != nil {
+		if s.box == nil {
+			return nil, ErrCredentialsDisabled
+		}
+		if err := req.Secret.Validate(cred.Type); err != nil {
+			return nil, &InvalidCredentialError{Message: err.Error()}
+		}
+		if cred.Secret, err = s.seal(req.Secret); err != nil {
+			return nil, err
+		}
+	}
+
+	cred.UpdatedAt = time.Now()
+	if err := s.db.UpdateCredential(cred); err != nil {
+		return nil, err
+	}
+	return cred, nil
+}
+
+// DeleteCredential removes a credential that no ISO references.
+func (s *CredentialService) DeleteCredential(name string) error {
+	if _, err := s.db.GetCredential(name); err != nil {
+		return err
+	}
+
+	count, err := s.db.CountISOsUsingCredential(name)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return &CredentialInUseError{Name: name, ISOCount: count}
+	}
+
+	return s.db.DeleteCredential(name)
+}
+
+// AuthorizerFor returns an authorizer for the upstream requests of iso. A
+// credential the ISO references by name applies to every request when it has
+// no host, or to requests for its host otherwise; other requests use the
+// credential bound to their host, if any.
+func (s *CredentialService) AuthorizerFor(iso *models.ISO) httputil.Authorizer {
+	name := iso.Credential
+	return httputil.AuthorizerFunc(func(req *http.Request) error {
+		host := strings.ToLower(req.URL.Hostname())
+
+		var cred *models.Credential
+		if name != "" {
+			named, err := s.db.GetCredential(name)
+			if err != nil {
+				return fmt.Errorf("credential %q: %w", name, err)
+			}
+			if named.Host == "" || named.Host == host {
+				cred = named
+			}
+		}
+		if cred == nil {
+			byHost, err := s.db.GetCredentialForHost(host)
+			if err != nil {
+				return err
+			}
+			cred = byHost
+		}
+		if cred == nil {
+			return nil
+		}
+
+		auth, err := s.authorizer(cred)
+		if err != nil {
+			return fmt.Errorf("credential %q: %w", cred.Name, err)
+		}
+		return auth.Authorize(req)
+	})
+}
+
+// WithCredentials returns a context whose upstream requests carry the
+// credentials of iso. A nil service returns ctx unchanged.
+func (s *CredentialService) WithCredentials(ctx context.Context, iso *models.ISO) context.Context {
+	if s == nil {
+		return ctx
+	}
+	return httputil.WithAuthorizer(ctx, s.AuthorizerFor(iso))
+}
+
+// authorizer opens the secret of cred and builds the matching authorizer.
+func (s *CredentialService) authorizer(cred *models.Credential) (httputil.Authorizer, error) {
+	if s.box == nil {
+		return nil, ErrCredentialsDisabled
+	}
+	plaintext, err := s.box.Open(cred.Secret)
+	if err != nil {
+		return nil, err
+	}
+	var secret models.CredentialSecret
+	if err := json.Unmarshal(plaintext, &secret); err != nil {
+		return nil, fmt.Errorf("failed to decode secret: %w", err)
+	}
+
+	switch cred.Type {
+	case models.CredentialTypeBasic:
+		return httputil.BasicAuth{Username: secret.Username, Password: secret.Password}, nil
+	case models.CredentialTypeToken:
+		return httputil.BearerToken(secret.Token), nil
+	case models.CredentialTypeS3:
+		return httputil.SigV4Signer{
+			AccessKeyID:     secret.AccessKeyID,
+			SecretAccessKey: secret.SecretAccessKey,
+			SessionToken:    secret.SessionToken,
+			Region:          secret.Region,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown credential type: %s", cred.Type)
+}
+
+func (s *CredentialService) seal(secret *models.CredentialSecret) (string, error) {
+	plaintext, err := json.Marshal(secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode secret: %w", err)
+	}
+	return s.box.Seal(plaintext)
+}
+
+// normalizeHost lowercases a host and drops any port, so it compares equal to
+// the hostname of request URLs.
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.Trim(host, "[]")
+}
+
+// InvalidCredentialError indicates that a credential request is malformed.
+type InvalidCredentialError struct {
+	Message string
+}
+
+func (e *InvalidCredentialError) Error() string {
+	return e.Message
+}
+
+// CredentialExistsError indicates that a credential name is taken.
+type CredentialExistsError struct {
+	Name string
+}
+
+func (e *CredentialExistsError) Error() string {
+	return fmt.Sprintf("credential %q already exists", e.Name)
+}
+
+// CredentialInUseError indicates that ISOs still reference a credential.
+type CredentialInUseError struct {
+	Name     string
+	ISOCount int
+}
+
+func (e *CredentialInUseError) Error() string {
+	return fmt.Sprintf("credential %q is used by %d ISO(s)", e.Name, e.ISOCount)
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/secrets"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+)
+
+func newTestCredentialService(t *testing.T, env *testutil.TestEnv) *CredentialService {
+	t.Helper()
+	box, err := secrets.NewBox(bytes.Repeat([]byte{1}, secrets.KeySize))
+	if err != nil {
+		t.Fatalf("NewBox() failed: %v", err)
+	}
+	return NewCredentialService(env.DB, box)
+}
+
+func TestCredentialService_CreateAndSeal(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := newTestCredentialService(t, env)
+
+	cred, err := svc.CreateCredential(models.CreateCredentialRequest{
+		Name:   "mirror",
+		Type:   models.CredentialTypeBasic,
+		Host:   "Mirror.Example.com:8443",
+		Secret: models.CredentialSecret{Username: "user", Password: "hunter2"},
+	})
+	if err != nil {
+		t.Fatalf("CreateCredential() failed: %v", err)
+	}
+	if cred.Host != "mirror.example.com" {
+		t.Errorf("Expected host to be normalized, got %q", cred.Host)
+	}
+
+	stored, err := env.DB.GetCredential("mirror")
+	if err != nil {
+		t.Fatalf("GetCredential() failed: %v", err)
+	}
+	if strings.Contains(stored.Secret, "hunter2") {
+		t.Error("Secret is stored in plaintext")
+	}
+
+	tests := []struct {
+		name string
+		req  models.CreateCredentialRequest
+		want any
+	}{
+		{"Duplicate", models.CreateCredentialRequest{Name: "mirror", Type: models.CredentialTypeToken, Secret: models.CredentialSecret{Token: "t"}}, &CredentialExistsError{}},
+		{"BadName", models.CreateCredentialRequest{Name: "Bad Name", Type: models.CredentialTypeToken, Secret: models.CredentialSecret{Token: "t"}}, &InvalidCredentialError{}},
+		{"MissingSecret", models.CreateCredentialRequest{Name: "s3", Type: models.CredentialTypeS3}, &InvalidCredentialError{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.CreateCredential(tt.req)
+			switch tt.want.(type) {
+			case *CredentialExistsError:
+				var target *CredentialExistsError
+				if !errors.As(err, &target) {
+					t.Errorf("Expected CredentialExistsError, got %v", err)
+				}
+			case *InvalidCredentialError:
+				var target *InvalidCredentialError
+				if !errors.As(err, &target) {
+					t.Errorf("Expected InvalidCredentialError, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestCredentialService_Disabled(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := NewCredentialService(env.DB, nil)
+
+	_, err := svc.CreateCredential(models.CreateCredentialRequest{
+		Name:   "token",
+		Type:   models.CredentialTypeToken,
+		Secret: models.CredentialSecret{Token: "t"},
+	})
+	if !errors.Is(err, ErrCredentialsDisabled) {
+		t.Errorf("Expected ErrCredentialsDisabled, got %v", err)
+	}
+	if creds, err := svc.ListCredentials(); err != nil || len(creds) != 0 {
+		t.Errorf("ListCredentials() = %v, %v; want empty", creds, err)
+	}
+}
+
+func TestCredentialService_UpdateAndDelete(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := newTestCredentialService(t, env)
+
+	if _, err := svc.CreateCredential(models.CreateCredentialRequest{
+		Name:   "mirror",
+		Type:   models.CredentialTypeBasic,
+		Secret: models.CredentialSecret{Username: "user"},
+	}); err != nil {
+		t.Fatalf("CreateCredential() failed: %v", err)
+	}
+
+	token := models.CredentialTypeToken
+	var invalidErr *InvalidCredentialError
+	if _, err := svc.UpdateCredential("mirror", models.UpdateCredentialRequest{Type: &token}); !errors.As(err, &invalidErr) {
+		t.Errorf("Expected a type change without a secret to be rejected, got %v", err)
+	}
+
+	cred, err := svc.UpdateCredential("mirror", models.UpdateCredentialRequest{Type: &token, Secret: &models.CredentialSecret{Token: "t"}})
+	if err != nil {
+		t.Fatalf("UpdateCredential() failed: %v", err)
+	}
+	if cred.Type != models.CredentialTypeToken {
+		t.Errorf("Expected type to change, got %q", cred.Type)
+	}
+
+	iso := testutil.CreateTestISO(nil)
+	iso.Credential = "mirror"
+	if err := env.DB.CreateISO(iso); err != nil {
+		t.Fatalf("CreateISO() failed: %v", err)
+	}
+	var inUseErr *CredentialInUseError
+	if err := svc.DeleteCredential("mirror"); !errors.As(err, &inUseErr) || inUseErr.ISOCount != 1 {
+		t.Errorf("Expected CredentialInUseError for 1 ISO, got %v", err)
+	}
+
+	env.DB.DeleteISO(iso.ID)
+	if err := svc.DeleteCredential("mirror"); err != nil {
+		t.Errorf("DeleteCredential() failed: %v", err)
+	}
+}
+
+func TestCredentialService_AuthorizerFor(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := newTestCredentialService(t, env)
+
+	for _, req := range []models.CreateCredentialRequest{
+		{Name: "named", Type: models.CredentialTypeToken, Secret: models.CredentialSecret{Token: "named-token"}},
+		{Name: "scoped", Type: models.CredentialTypeToken, Host: "a.example.com", Secret: models.CredentialSecret{Token: "scoped-token"}},
+		{Name: "by-host", Type: models.CredentialTypeBasic, Host: "b.example.com", Secret: models.CredentialSecret{Username: "b", Password: "pw"}},
+	} {
+		if _, err := svc.CreateCredential(req); err != nil {
+			t.Fatalf("CreateCredential(%s) failed: %v", req.Name, err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		credential string
+		url        string
+		want       string
+	}{
+		{"NamedAppliesEverywhere", "named", "https://c.example.com/a.iso", "Bearer named-token"},
+		{"ScopedMatchingHost", "scoped", "https://a.example.com/a.iso", "Bearer scoped-token"},
+		{"ScopedOtherHostFallsBackToHost", "scoped", "https://b.example.com/a.iso", "Basic Yjpwdw=="},
+		{"ByHost", "", "https://B.example.com:8443/a.iso", "Basic Yjpwdw=="},
+		{"NoMatch", "", "https://c.example.com/a.iso", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, tt.url, http.NoBody)
+			if err := svc.AuthorizerFor(&models.ISO{Credential: tt.credential}).Authorize(req); err != nil {
+				t.Fatalf("Authorize() failed: %v", err)
+			}
+			if got := req.Header.Get("Authorization"); got != tt.want {
+				t.Errorf("Authorization = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://c.example.com/a.iso", http.NoBody)
+	if err := svc.AuthorizerFor(&models.ISO{Credential: "missing"}).Authorize(req); err == nil {
+		t.Error("Expected a missing named credential to fail the request")
+	}
+
+	disabled := NewCredentialService(env.DB, nil)
+	req, _ = http.NewRequest(http.MethodGet, "https://b.example.com/a.iso", http.NoBody)
+	if err := disabled.AuthorizerFor(&models.ISO{}).Authorize(req); !errors.Is(err, ErrCredentialsDisabled) {
+		t.Errorf("Expected ErrCredentialsDisabled without a master key, got %v", err)
+	}
+}
+
+func TestCreateISOUnknownCredential(t *testing.T) {
+	svc, env := setupTestISOService(t)
+	defer env.Cleanup()
+
+	_, err := svc.CreateISO(context.Background(), CreateISORequest{
+		Name:        "alpine",
+		Version:     "3.19.1",
+		Arch:        "x86_64",
+		DownloadURL: "https://example.com/alpine.iso",
+		Credential:  "missing",
+	})
+	var credErr *InvalidCredentialError
+	if !errors.As(err, &credErr) {
+		t.Fatalf("Expected InvalidCredentialError, got %v", err)
+	}
+
+	isos, _ := env.DB.ListISOs()
+	if len(isos) != 0 {
+		t.Errorf("Expected no ISO to be created, got %d", len(isos))
+	}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// ErrDownloadLinkInvalid is returned for a download link token that is
+// unknown, expired, already used, or for another file.
+var ErrDownloadLinkInvalid = errors.New("download link is invalid, expired, or already used")
+
+// DownloadLinkService issues and checks download links for files under /images.
+type DownloadLinkService struct {
+	db     *db.DB
+	isoDir string
+	now    func() time.Time
+}
+
+// NewDownloadLinkService creates a download link service for files in isoDir.
+func NewDownloadLinkService(database *db.DB, isoDir string) *DownloadLinkService {
+	return &DownloadLinkService{
+		db:     database,
+		isoDir: isoDir,
+		now:    time.Now,
+	}
+}
+
+// CreateLink issues a link to the file at req.Path. The returned link's URL
+// carries the token; it can't be recovered later.
+func (s *DownloadLinkService) CreateLink(req models.CreateDownloadLinkRequest, createdBy string) (*models.DownloadLink, error) {
+	hours := req.ExpiresInHours
+	if hours == 0 {
+		hours = constants.DefaultDownloadLinkTTLHours
+	}
+	if hours < 0 || hours > constants.MaxDownloadLinkTTLHours {
+		return nil, &InvalidDownloadLinkError{Message: fmt.Sprintf("expires_in_hours must be between 1 and %d", constants.MaxDownloadLinkTTLHours)}
+	}
+
+	rel := strings.TrimPrefix(path.Clean("/"+strings.TrimPrefix(req.Path, "/images/")), "/")
+	if rel == "" || strings.ContainsRune(rel, 0) {
+		return nil, &InvalidDownloadLinkError{Message: "path must name a file"}
+	}
+	info, err := os.Stat(filepath.Join(s.isoDir, filepath.FromSlash(rel)))
+	if err != nil || !info.Mode().IsRegular() {
+		return nil, &InvalidDownloadLinkError{Message: fmt.Sprintf("no file at %q", rel)}
+	}
+
+	token, err := newSessionToken(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	link := &models.DownloadLink{
+		ID:        hashSessionToken(token),
+		Path:      rel,
+		SingleUse: req.SingleUse,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Duration(hours) * time.Hour),
+	}
+	if err := s.db.CreateDownloadLink(link); err != nil {
+		return nil, err
+	}
+	if n, err := s.db.DeleteExpiredDownloadLinks(now); err != nil {
+		slog.Warn("failed to delete expired download links", slog.Any("error", err))
+	} else if n > 0 {
+		slog.Debug("deleted expired download links", slog.Int64("count", n))
+	}
+
+	link.URL = (&url.URL{Path: "/images/" + rel, RawQuery: url.Values{"token": {token}}.Encode()}).String()
+	return link, nil
+}
+
+// Resolve returns the link for token if it grants access to the file at rel
+// right now.
+func (s *DownloadLinkService) Resolve(token, rel string) (*models.DownloadLink, error) {
+	link, err := s.db.GetDownloadLink(hashSessionToken(token))
+	if errors.Is(err, db.ErrDownloadLinkNotFound) {
+		return nil, ErrDownloadLinkInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if link.Path != rel || !s.now().Before(link.ExpiresAt) || link.UsedAt != nil {
+		return nil, ErrDownloadLinkInvalid
+	}
+	return link, nil
+}
+
+// Claim marks a single-use link used as a download through it starts, and
+// reports false when another download already claimed it.
+func (s *DownloadLinkService) Claim(id string) (bool, error) {
+	return s.db.BurnDownloadLink(id, s.now())
+}
+
+// Release makes a claimed link usable again after a download that didn't send
+// the whole file.
+func (s *DownloadLinkService) Release(id string) error {
+	return s.db.ReleaseDownloadLink(id)
+}
+
+// ListLinks retrieves the links that haven't expired, used ones included.
+func (s *DownloadLinkService) ListLinks() ([]models.DownloadLink, error) {
+	return s.db.ListDownloadLinks(s.now())
+}
+
+// RevokeLink deletes a link so it stops working.
+func (s *DownloadLinkService) RevokeLink(id string) error {
+	return s.db.DeleteDownloadLink(id)
+}
+
+// InvalidDownloadLinkError indicates that a download link request is unacceptable.
+type InvalidDownloadLinkError struct {
+	Message string
+}
+
+func (e *InvalidDownloadLinkError) Error() string {
+	return e.Message
+}
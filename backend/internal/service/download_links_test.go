@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+)
+
+func TestDownloadLinkService(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	if err := os.MkdirAll(filepath.Join(env.ISODir, "alpine"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(env.ISODir, "alpine", "alpine.iso"), []byte("iso"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	svc := NewDownloadLinkService(env.DB, env.ISODir)
+
+	var invalid *InvalidDownloadLinkError
+	for _, req := range []models.CreateDownloadLinkRequest{
+		{Path: "alpine/missing.iso"},
+		{Path: "alpine"},
+		{Path: "../etc/passwd"},
+		{Path: "alpine/alpine.iso", ExpiresInHours: -1},
+	} {
+		if _, err := svc.CreateLink(req, ""); !errors.As(err, &invalid) {
+			t.Errorf("CreateLink(%+v): expected InvalidDownloadLinkError, got: %v", req, err)
+		}
+	}
+
+	link, err := svc.CreateLink(models.CreateDownloadLinkRequest{Path: "/images/alpine/alpine.iso", SingleUse: true}, "admin")
+	if err != nil {
+		t.Fatalf("CreateLink() failed: %v", err)
+	}
+	u, err := url.Parse(link.URL)
+	if err != nil || u.Path != "/images/alpine/alpine.iso" {
+		t.Fatalf("URL = %q, want a link to /images/alpine/alpine.iso", link.URL)
+	}
+	token := u.Query().Get("token")
+	if token == "" || link.ID == token {
+		t.Fatalf("Expected a token distinct from the ID, got %q", link.URL)
+	}
+
+	if _, err := svc.Resolve(token, "alpine/other.iso"); !errors.Is(err, ErrDownloadLinkInvalid) {
+		t.Errorf("Expected ErrDownloadLinkInvalid for another file, got: %v", err)
+	}
+	if _, err := svc.Resolve(token, "alpine/alpine.iso"); err != nil {
+		t.Fatalf("Resolve() failed: %v", err)
+	}
+	if claimed, err := svc.Claim(link.ID); err != nil || !claimed {
+		t.Fatalf("Claim() = %v, %v; want true", claimed, err)
+	}
+	if claimed, _ := svc.Claim(link.ID); claimed {
+		t.Error("Expected a claimed link not to be claimed twice")
+	}
+	if _, err := svc.Resolve(token, "alpine/alpine.iso"); !errors.Is(err, ErrDownloadLinkInvalid) {
+		t.Errorf("Expected ErrDownloadLinkInvalid after claiming, got: %v", err)
+	}
+	if err := svc.Release(link.ID); err != nil {
+		t.Fatalf("Release() failed: %v", err)
+	}
+	if _, err := svc.Resolve(token, "alpine/alpine.iso"); err != nil {
+		t.Errorf("Expected a released link to work again, got: %v", err)
+	}
+
+	reusable, err := svc.CreateLink(models.CreateDownloadLinkRequest{Path: "alpine/alpine.iso", ExpiresInHours: 1}, "")
+	if err != nil {
+		t.Fatalf("CreateLink() failed: %v", err)
+	}
+	u, _ = url.Parse(reusable.URL)
+	svc.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, err := svc.Resolve(u.Query().Get("token"), "alpine/alpine.iso"); !errors.Is(err, ErrDownloadLinkInvalid) {
+		t.Errorf("Expected ErrDownloadLinkInvalid after expiry, got: %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/clock"
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/download"
+	"github.com/aloks98/isoman/backend/internal/fileutil"
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// HealthMonitor periodically checks storage, the database, and the download
+// queue, and records a system event whenever one of them changes state.
+type HealthMonitor struct {
+	db           *db.DB
+	clock        clock.Clock
+	manager      *download.Manager // nil skips the queue check
+	dbFailedAt   time.Time         // When the database first failed; zero while healthy
+	dbErr        error
+	isoDir       string
+	minFreeBytes int64 // Zero or less skips the storage check
+	mu           sync.Mutex
+	storageLow   bool
+	queueFull    bool
+	notifier     *Notifier // nil sends no notifications
+}
+
+// NewHealthMonitor creates a health monitor for the ISO directory's filesystem.
+func NewHealthMonitor(database *db.DB, manager *download.Manager, isoDir string, minFreeBytes int64) *HealthMonitor {
+	return &HealthMonitor{
+		db:           database,
+		clock:        clock.Real(),
+		manager:      manager,
+		isoDir:       isoDir,
+		minFreeBytes: minFreeBytes,
+	}
+}
+
+// SetNotifier emails warning and error events through notifier. Info events,
+// such as startup and recoveries, are only recorded.
+func (h *HealthMonitor) SetNotifier(notifier *Notifier) {
+	h.notifier = notifier
+}
+
+// Record appends an event to the system event log. Failures are logged, since
+// the event log must never take down the code path reporting the event.
+func (h *HealthMonitor) Record(eventType, severity, message string) {
+	h.record(eventType, severity, message, h.clock.Now())
+}
+
+func (h *HealthMonitor) record(eventType, severity, message string, at time.Time) {
+	event := &models.SystemEvent{Type: eventType, Severity: severity, Message: message, CreatedAt: at}
+	if err := h.db.RecordSystemEvent(event); err != nil {
+		slog.Warn("failed to record system event", slog.String("type", eventType), slog.Any("error", err))
+	}
+	if severity == models.SeverityWarning || severity == models.SeverityError {
+		h.notifier.Notify(fmt.Sprintf("%s: %s", eventType, message))
+	}
+}
+
+// Start runs Check once per interval until ctx is canceled. A zero interval
+// disables the monitor.
+func (h *HealthMonitor) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := h.clock.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				h.Check()
+			}
+		}
+	}()
+}
+
+// Check runs every health check once and records state transitions.
+func (h *HealthMonitor) Check() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// The database goes first: while it is down, nothing else can be recorded
+	if !h.checkDatabase() {
+		return
+	}
+	h.checkStorage()
+	h.checkQueue()
+}
+
+// Problems describes what the latest Check found wrong, or returns nil when
+// everything is healthy.
+func (h *HealthMonitor) Problems() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var problems []string
+	if !h.dbFailedAt.IsZero() {
+		problems = append(problems, "database unavailable")
+	}
+	if h.storageLow {
+		problems = append(problems, "storage low")
+	}
+	if h.queueFull {
+		problems = append(problems, "download queue full")
+	}
+	return problems
+}
+
+// checkDatabase reports whether the database is usable. A failure is held in
+// memory and written to the log, together with the recovery, once queries
+// succeed again.
+func (h *HealthMonitor) checkDatabase() bool {
+	if err := h.db.Ping(); err != nil {
+		if h.dbFailedAt.IsZero() {
+			h.dbFailedAt = h.clock.Now()
+			h.dbErr = err
+			slog.Error("database health check failed", slog.Any("error", err))
+		}
+		return false
+	}
+
+	if !h.dbFailedAt.IsZero() {
+		h.record(models.EventDatabaseError, models.SeverityError, h.dbErr.Error(), h.dbFailedAt)
+		h.Record(models.EventDatabaseRecovered, models.SeverityInfo,
+			fmt.Sprintf("database answering again after %s", h.clock.Since(h.dbFailedAt).Round(time.Second)))
+		slog.Info("database health check recovered")
+		h.dbFailedAt = time.Time{}
+		h.dbErr = nil
+	}
+	return true
+}
+
+func (h *HealthMonitor) checkStorage() {
+	if h.minFreeBytes <= 0 {
+		return
+	}
+
+	free, err := fileutil.FreeSpace(h.isoDir)
+	if err != nil {
+		if !errors.Is(err, errors.ErrUnsupported) {
+			slog.Warn("failed to check free space", slog.String("path", h.isoDir), slog.Any("error", err))
+		}
+		return
+	}
+
+	low := free < h.minFreeBytes
+	if low == h.storageLow {
+		return
+	}
+	h.storageLow = low
+
+	message := fmt.Sprintf("%d MB free in %s (threshold %d MB)", free/(1024*1024), h.isoDir, h.minFreeBytes/(1024*1024))
+	if low {
+		slog.Warn("storage low", slog.String("path", h.isoDir), slog.Int64("free_bytes", free))
+		h.Record(models.EventStorageLow, models.SeverityWarning, message)
+	} else {
+		h.Record(models.EventStorageRecovered, models.SeverityInfo, message)
+	}
+}
+
+func (h *HealthMonitor) checkQueue() {
+	if h.manager == nil {
+		return
+	}
+
+	depth, capacity := h.manager.QueueDepth(), h.manager.QueueCapacity()
+	full := depth >= capacity
+	if full == h.queueFull {
+		return
+	}
+	h.queueFull = full
+
+	message := fmt.Sprintf("%d of %d queue slots in use", depth, capacity)
+	if full {
+		h.Record(models.EventQueueFull, models.SeverityWarning, message)
+	} else {
+		h.Record(models.EventQueueDrained, models.SeverityInfo, message)
+	}
+}
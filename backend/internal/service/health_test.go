@@ -0,0 +1,99 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/download"
+	"github.com/aloks98/isoman/backend/internal/fileutil"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+)
+
+func systemEventTypes(t *testing.T, database *db.DB) []string {
+	t.Helper()
+	events, err := database.ListSystemEvents(db.SystemEventsParams{Limit: 100})
+	if err != nil {
+		t.Fatalf("ListSystemEvents() failed: %v", err)
+	}
+	types := make([]string, len(events))
+	for i, e := range events {
+		types[len(events)-1-i] = e.Type // Oldest first
+	}
+	return types
+}
+
+func TestHealthMonitor_Storage(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	if _, err := fileutil.FreeSpace(env.ISODir); err != nil {
+		t.Skipf("free space not available: %v", err)
+	}
+
+	monitor := NewHealthMonitor(env.DB, nil, env.ISODir, math.MaxInt64)
+	monitor.Check()
+	monitor.Check() // Still low, nothing new recorded
+
+	monitor.minFreeBytes = 1
+	monitor.Check()
+
+	types := systemEventTypes(t, env.DB)
+	if len(types) != 2 || types[0] != models.EventStorageLow || types[1] != models.EventStorageRecovered {
+		t.Errorf("Expected storage.low then storage.recovered, got %v", types)
+	}
+}
+
+func TestHealthMonitor_Queue(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	cfg := download.DefaultConfig()
+	cfg.QueueBuffer = 1
+	manager := download.NewManagerWithConfig(env.DB, env.ISODir, cfg) // Not started, so the queue never drains
+	defer manager.Stop()
+
+	monitor := NewHealthMonitor(env.DB, manager, env.ISODir, 0)
+	monitor.Check()
+
+	iso := testutil.CreateAndInsertTestISO(t, env.DB, nil)
+	if err := manager.QueueDownload(iso); err != nil {
+		t.Fatalf("QueueDownload() failed: %v", err)
+	}
+	monitor.Check()
+
+	types := systemEventTypes(t, env.DB)
+	if len(types) != 1 || types[0] != models.EventQueueFull {
+		t.Errorf("Expected a single queue.full event, got %v", types)
+	}
+}
+
+func TestHealthMonitor_DatabaseDown(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	monitor := NewHealthMonitor(env.DB, nil, env.ISODir, 0)
+	env.DB.Close()
+	monitor.Check()
+
+	if monitor.dbFailedAt.IsZero() || monitor.dbErr == nil {
+		t.Error("Expected the database failure to be held until it can be recorded")
+	}
+}
+
+func TestHealthMonitor_Record(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	monitor := NewHealthMonitor(env.DB, nil, env.ISODir, 0)
+	monitor.Record(models.EventServerStarted, models.SeverityInfo, "version dev")
+
+	events, err := env.DB.ListSystemEvents(db.SystemEventsParams{Limit: 10})
+	if err != nil {
+		t.Fatalf("ListSystemEvents() failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Message != "version dev" || events[0].Severity != models.SeverityInfo {
+		t.Errorf("Expected the recorded event, got %+v", events)
+	}
+}
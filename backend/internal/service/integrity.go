@@ -0,0 +1,211 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/download"
+	"github.com/aloks98/isoman/backend/internal/fileutil"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/pathutil"
+)
+
+// VerifyISO re-hashes a complete ISO's file with its stored integrity hash
+// algorithm and reports whether the file is intact. ISOs without an integrity
+// hash (e.g. adopted files) get one recorded with the configured algorithm.
+func (s *ISOService) VerifyISO(id string) (*models.IntegrityCheckResult, error) {
+	iso, err := s.db.GetISO(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if iso.Status != models.StatusComplete {
+		return nil, &InvalidStateError{
+			CurrentStatus: string(iso.Status),
+			Message:       "Only complete ISOs can be verified",
+		}
+	}
+
+	algorithm, _, found := strings.Cut(iso.IntegrityHash, ":")
+	if !found {
+		algorithm = s.manager.IntegrityHash()
+	}
+
+	filePath := pathutil.ConstructISOPath(s.isoDir, iso.FilePath)
+	actual, err := download.ComputeIntegrityHash(filePath, algorithm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash ISO file: %w", err)
+	}
+
+	result := &models.IntegrityCheckResult{
+		ISO:       iso,
+		Algorithm: algorithm,
+		Expected:  iso.IntegrityHash,
+		Actual:    actual,
+		Valid:     !found || actual == iso.IntegrityHash,
+		Baseline:  !found,
+	}
+
+	if !found {
+		if err := s.db.UpdateISOIntegrityHash(iso.ID, actual); err != nil {
+			return nil, err
+		}
+		iso.IntegrityHash = actual
+	} else if !result.Valid {
+		slog.Warn("ISO file failed integrity check",
+			slog.String("iso_id", iso.ID),
+			slog.String("expected", iso.IntegrityHash),
+			slog.String("actual", actual),
+		)
+	}
+
+	return result, nil
+}
+
+// ReleaseISO moves a quarantined ISO's file into place and marks it complete,
+// for files an administrator has confirmed are safe. A file already at the
+// final path (from before a refresh) is replaced.
+func (s *ISOService) ReleaseISO(id string) (*models.ISO, error) {
+	iso, err := s.db.GetISO(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if iso.Status != models.StatusQuarantined {
+		return nil, &InvalidStateError{
+			CurrentStatus: string(iso.Status),
+			Message:       "Only quarantined ISOs can be released",
+		}
+	}
+
+	quarantinePath := pathutil.ConstructQuarantinePath(s.isoDir, iso.FilePath)
+	finalPath := pathutil.ConstructISOPath(s.isoDir, iso.FilePath)
+	if !fileutil.FileExists(quarantinePath) {
+		return nil, fmt.Errorf("quarantined file not found: %s", quarantinePath)
+	}
+	if err := fileutil.EnsureParentDirectory(finalPath); err != nil {
+		return nil, err
+	}
+	if err := fileutil.RenameOrCopy(quarantinePath, finalPath); err != nil {
+		return nil, fmt.Errorf("failed to release file: %w", err)
+	}
+	fileutil.CleanupEmptyParentDirs(quarantinePath, pathutil.GetQuarantineDir(s.isoDir))
+
+	now := time.Now()
+	iso.Status = models.StatusComplete
+	iso.Progress = 100
+	iso.ErrorMessage = ""
+	iso.CompletedAt = &now
+	iso.UpstreamChanged = false
+	iso.UpstreamCheckedAt = &now
+	if fi, err := os.Stat(finalPath); err == nil {
+		modTime := fi.ModTime()
+		iso.FileInode = fileutil.FileInode(fi)
+		iso.FileModTime = &modTime
+	}
+	if err := s.db.UpdateISO(iso); err != nil {
+		return nil, fmt.Errorf("failed to update ISO: %w", err)
+	}
+	s.purger.PurgeISOFile(models.PurgeEventReplaced, iso.FilePath)
+
+	slog.Info("released ISO from quarantine", slog.String("iso_id", iso.ID), slog.String("name", iso.Name))
+	return iso, nil
+}
+
+// VerificationReport describes how an ISO was verified: its source, the
+// checksum and signature it was checked against, and the hashes recorded
+// for it. Checks count as passed once the ISO has completed, since a failed
+// check fails the download.
+func (s *ISOService) VerificationReport(id string) (*models.VerificationReport, error) {
+	iso, err := s.db.GetISO(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Quarantine comes after verification, so a quarantined file has passed it
+	passed := iso.Status == models.StatusComplete || iso.Status == models.StatusQuarantined
+	checkStatus := func(configured bool) string {
+		switch {
+		case !configured:
+			return models.VerificationNone
+		case passed:
+			return models.VerificationVerified
+		default:
+			return models.VerificationUnverified
+		}
+	}
+
+	isoPath := pathutil.ConstructISOPath(s.isoDir, iso.FilePath)
+	sidecarLink := func(exts ...string) string {
+		for _, ext := range exts {
+			if fileutil.FileExists(isoPath + ext) {
+				return iso.DownloadLink + ext
+			}
+		}
+		return ""
+	}
+
+	report := &models.VerificationReport{
+		GeneratedAt:  time.Now(),
+		ISOID:        iso.ID,
+		Filename:     iso.Filename,
+		DownloadLink: iso.DownloadLink,
+		Status:       iso.Status,
+		Source: models.VerificationSource{
+			DownloadURL:          iso.DownloadURL,
+			SourceURL:            iso.SourceURL,
+			SourceHeaders:        iso.SourceHeaders,
+			UpstreamETag:         iso.UpstreamETag,
+			UpstreamLastModified: iso.UpstreamLastModified,
+			UpstreamChanged:      iso.UpstreamChanged,
+		},
+		Checksum: models.ChecksumVerification{
+			Status:   checkStatus(iso.HasChecksum()),
+			URL:      iso.ChecksumURL,
+			Expected: iso.Checksum,
+		},
+		Signature: models.SignatureVerification{
+			Status: checkStatus(iso.SignatureURL != ""),
+			URL:    iso.SignatureURL,
+			Signer: iso.SignatureSigner,
+		},
+		Hashes: models.VerificationHashes{
+			SHA256:        iso.SHA256,
+			SHA512:        iso.SHA512,
+			MD5:           iso.MD5,
+			IntegrityHash: iso.IntegrityHash,
+			SizeBytes:     iso.SizeBytes,
+		},
+		Timestamps: models.VerificationTimestamps{
+			CreatedAt:         iso.CreatedAt,
+			DownloadStartedAt: iso.DownloadStartedAt,
+			CompletedAt:       iso.CompletedAt,
+			VerifiedAt:        iso.VerifiedAt,
+			FileModTime:       iso.FileModTime,
+			UpstreamCheckedAt: iso.UpstreamCheckedAt,
+		},
+		Verified: passed && (iso.HasChecksum() || iso.SignatureURL != "") && !iso.ChecksumPending,
+	}
+
+	if iso.ChecksumPending {
+		// Completed while the checksum file was unavailable; still retried
+		report.Checksum.Status = models.VerificationUnverified
+	}
+	if iso.HasChecksum() {
+		report.Checksum.Type = iso.ChecksumType
+		report.Checksum.Method = models.ChecksumMethodExpected
+		if iso.ChecksumURL != "" {
+			report.Checksum.Method = models.ChecksumMethodFile
+			report.Checksum.SidecarLink = sidecarLink("." + iso.ChecksumType)
+		}
+	}
+	if iso.SignatureURL != "" {
+		report.Signature.SidecarLink = sidecarLink(constants.SignatureExtensions...)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/pathutil"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+)
+
+func TestISOService_VerifyISO(t *testing.T) {
+	service, env := setupTestISOService(t)
+	defer env.Cleanup()
+
+	iso := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{
+		Name:   "alpine",
+		Status: models.StatusComplete,
+	})
+	writeMirrorFile(t, env.ISODir, filepath.ToSlash(iso.FilePath), "alpine")
+
+	// First run records a baseline with the configured algorithm
+	result, err := service.VerifyISO(iso.ID)
+	if err != nil {
+		t.Fatalf("VerifyISO() failed: %v", err)
+	}
+	if !result.Baseline || !result.Valid {
+		t.Errorf("Expected a valid baseline, got: %+v", result)
+	}
+	if !strings.HasPrefix(result.Actual, constants.IntegrityHashBLAKE2b+":") {
+		t.Errorf("Baseline should use blake2b, got: %s", result.Actual)
+	}
+
+	stored, _ := service.GetISO(iso.ID)
+	if stored.IntegrityHash != result.Actual {
+		t.Errorf("Baseline should be stored, got: %q", stored.IntegrityHash)
+	}
+
+	// Unchanged file passes
+	result, err = service.VerifyISO(iso.ID)
+	if err != nil {
+		t.Fatalf("VerifyISO() failed: %v", err)
+	}
+	if result.Baseline || !result.Valid {
+		t.Errorf("Expected a passing check, got: %+v", result)
+	}
+
+	// Corrupted file fails
+	writeMirrorFile(t, env.ISODir, filepath.ToSlash(iso.FilePath), "corrupted")
+	result, err = service.VerifyISO(iso.ID)
+	if err != nil {
+		t.Fatalf("VerifyISO() failed: %v", err)
+	}
+	if result.Valid {
+		t.Error("Expected corrupted file to fail the check")
+	}
+
+	// Only complete ISOs can be verified
+	pending := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{
+		Name:   "debian",
+		Status: models.StatusPending,
+	})
+	var invalidStateErr *InvalidStateError
+	if _, err := service.VerifyISO(pending.ID); !errors.As(err, &invalidStateErr) {
+		t.Errorf("Expected InvalidStateError, got: %v", err)
+	}
+}
+
+func TestISOService_ReleaseISO(t *testing.T) {
+	service, env := setupTestISOService(t)
+	defer env.Cleanup()
+
+	iso := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{
+		Name:   "alpine",
+		Status: models.StatusQuarantined,
+	})
+	quarantinePath := pathutil.ConstructQuarantinePath(env.ISODir, iso.FilePath)
+	rel, _ := filepath.Rel(env.ISODir, quarantinePath)
+	writeMirrorFile(t, env.ISODir, filepath.ToSlash(rel), "alpine")
+
+	released, err := service.ReleaseISO(iso.ID)
+	if err != nil {
+		t.Fatalf("ReleaseISO() failed: %v", err)
+	}
+	if released.Status != models.StatusComplete || released.ErrorMessage != "" {
+		t.Errorf("Released ISO should be complete without error, got: %s %q", released.Status, released.ErrorMessage)
+	}
+	if _, err := os.Stat(pathutil.ConstructISOPath(env.ISODir, iso.FilePath)); err != nil {
+		t.Errorf("Released file should be in place: %v", err)
+	}
+	if _, err := os.Stat(quarantinePath); !os.IsNotExist(err) {
+		t.Error("Quarantined file should be moved out of quarantine")
+	}
+
+	// Only quarantined ISOs can be released
+	_, err = service.ReleaseISO(iso.ID)
+	var invalidStateErr *InvalidStateError
+	if !errors.As(err, &invalidStateErr) {
+		t.Errorf("Expected InvalidStateError, got: %v", err)
+	}
+}
+
+func TestISOService_VerificationReport(t *testing.T) {
+	service, env := setupTestISOService(t)
+	defer env.Cleanup()
+
+	iso := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Status: models.StatusComplete})
+	iso.Checksum = "abc123"
+	iso.SignatureURL = "https://example.com/alpine.iso.asc"
+	iso.SignatureSigner = "Alpine Release <release@alpinelinux.org>"
+	if err := env.DB.UpdateISO(iso); err != nil {
+		t.Fatalf("UpdateISO() failed: %v", err)
+	}
+	writeMirrorFile(t, env.ISODir, filepath.ToSlash(iso.FilePath)+".sha256", "abc123")
+
+	report, err := service.VerificationReport(iso.ID)
+	if err != nil {
+		t.Fatalf("VerificationReport() failed: %v", err)
+	}
+	if !report.Verified || report.Checksum.Status != models.VerificationVerified || report.Signature.Status != models.VerificationVerified {
+		t.Errorf("Expected both checks verified, got %+v", report)
+	}
+	if report.Checksum.Method != models.ChecksumMethodFile || report.Checksum.Expected != "abc123" {
+		t.Errorf("Unexpected checksum: %+v", report.Checksum)
+	}
+	if report.Checksum.SidecarLink != iso.DownloadLink+".sha256" {
+		t.Errorf("Expected the saved checksum file linked, got %q", report.Checksum.SidecarLink)
+	}
+	if report.Signature.Signer != iso.SignatureSigner || report.Signature.SidecarLink != "" {
+		t.Errorf("Expected the signer and no missing signature file, got %+v", report.Signature)
+	}
+
+	// Until a download passes them, configured checks are unverified
+	failed := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Version: "3.20.0", Status: models.StatusFailed})
+	report, err = service.VerificationReport(failed.ID)
+	if err != nil {
+		t.Fatalf("VerificationReport() failed: %v", err)
+	}
+	if report.Verified || report.Checksum.Status != models.VerificationUnverified || report.Signature.Status != models.VerificationNone {
+		t.Errorf("Expected an unverified checksum and no signature, got %+v", report)
+	}
+
+	// A complete ISO whose checksum file was unavailable isn't verified yet
+	iso.ChecksumPending = true
+	if err := env.DB.UpdateISO(iso); err != nil {
+		t.Fatalf("UpdateISO() failed: %v", err)
+	}
+	report, err = service.VerificationReport(iso.ID)
+	if err != nil {
+		t.Fatalf("VerificationReport() failed: %v", err)
+	}
+	if report.Verified || report.Checksum.Status != models.VerificationUnverified || report.Signature.Status != models.VerificationVerified {
+		t.Errorf("Expected only the checksum unverified, got %+v", report)
+	}
+
+	if _, err := service.VerificationReport("missing"); err == nil {
+		t.Error("Expected an error for an unknown ISO")
+	}
+}
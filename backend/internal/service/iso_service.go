@@ -1,16 +1,22 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/aloks98/isoman/backend/internal/clock"
 	"github.com/aloks98/isoman/backend/internal/constants"
 	"github.com/aloks98/isoman/backend/internal/db"
 	"github.com/aloks98/isoman/backend/internal/download"
 	"github.com/aloks98/isoman/backend/internal/fileutil"
+	"github.com/aloks98/isoman/backend/internal/logger"
 	"github.com/aloks98/isoman/backend/internal/models"
 	"github.com/aloks98/isoman/backend/internal/pathutil"
 
@@ -19,33 +25,90 @@ import (
 
 // ISOService handles ISO-related business logic.
 type ISOService struct {
-	db      *db.DB
-	manager *download.Manager
-	isoDir  string
+	db          *db.DB
+	clock       clock.Clock // Paces and dates upstream checks
+	manager     *download.Manager
+	credentials *CredentialService   // nil sends upstream checks without credentials
+	purger      *Purger              // nil leaves CDN caches alone
+	repo        *RepoMetadataService // nil publishes no signed repository metadata
+	sidecars    []string             // Extensions of the files kept beside each ISO
+	isoDir      string
 }
 
 // NewISOService creates a new ISO service.
 func NewISOService(database *db.DB, manager *download.Manager, isoDir string) *ISOService {
 	return &ISOService{
-		db:      database,
-		manager: manager,
-		isoDir:  isoDir,
+		db:       database,
+		clock:    clock.Real(),
+		manager:  manager,
+		sidecars: constants.SidecarExtensions,
+		isoDir:   isoDir,
 	}
 }
 
+// SetCredentials sets the service that supplies credentials for upstream checks.
+func (s *ISOService) SetCredentials(credentials *CredentialService) {
+	s.credentials = credentials
+}
+
+// Credentials returns the credential service, or nil when none is set.
+func (s *ISOService) Credentials() *CredentialService {
+	return s.credentials
+}
+
+// SetPurger sets the purger told about files that are moved or deleted.
+func (s *ISOService) SetPurger(purger *Purger) {
+	s.purger = purger
+}
+
+// Purger returns the CDN purger, or nil when none is set.
+func (s *ISOService) Purger() *Purger {
+	return s.purger
+}
+
+// SetSidecarExtensions adds extra to the checksum and signature extensions of
+// the files kept beside each ISO, which are moved, deleted, and bundled with it.
+func (s *ISOService) SetSidecarExtensions(extra []string) {
+	s.sidecars = sidecarExtensions(extra)
+}
+
+// SidecarExtensions returns the extensions of the files kept beside each ISO.
+func (s *ISOService) SidecarExtensions() []string {
+	return s.sidecars
+}
+
+// SetRepoMetadata sets the service that signs repository metadata.
+func (s *ISOService) SetRepoMetadata(repo *RepoMetadataService) {
+	s.repo = repo
+}
+
+// RepoMetadata returns the repository metadata service, or nil when no
+// repository key is configured.
+func (s *ISOService) RepoMetadata() *RepoMetadataService {
+	return s.repo
+}
+
 // CreateISORequest represents the request to create a new ISO download.
 type CreateISORequest struct {
-	Name         string
-	Version      string
-	Arch         string
-	Edition      string
-	DownloadURL  string
-	ChecksumURL  string
-	ChecksumType string
+	Name             string
+	Version          string
+	Arch             string
+	Edition          string
+	DownloadURL      string
+	ChecksumURL      string
+	ExpectedChecksum string // Hex digest verified when there's no checksum URL
+	SignatureURL     string // Detached OpenPGP signature over the file
+	ChecksumType     string
+	IPFamily         string // Empty uses the server-wide HTTP_IP_FAMILY
+	Credential       string // Empty picks a credential by host, if any
+	Preset           string // Preset the request was expanded from, if any
+	Overwrite        bool   // Replace an existing failed or canceled ISO with the same combination
 }
 
-// CreateISO creates a new ISO download.
-func (s *ISOService) CreateISO(req CreateISORequest) (*models.ISO, error) {
+// CreateISO creates a new ISO download. With Overwrite, an existing failed
+// or canceled ISO with the same combination is replaced; any other existing
+// ISO is still a conflict.
+func (s *ISOService) CreateISO(ctx context.Context, req CreateISORequest) (*models.ISO, error) {
 	// Detect file type from download URL
 	fileType, err := DetectFileType(req.DownloadURL)
 	if err != nil {
@@ -55,10 +118,21 @@ func (s *ISOService) CreateISO(req CreateISORequest) (*models.ISO, error) {
 	// Normalize name
 	normalizedName := NormalizeName(req.Name)
 
-	// Default checksum type to sha256 if checksum URL is provided
+	// Default checksum type to sha256 if checksum URL is provided, or to the
+	// type of an expected checksum
 	checksumType := req.ChecksumType
-	if req.ChecksumURL != "" && checksumType == "" {
-		checksumType = "sha256"
+	checksum := strings.ToLower(strings.TrimSpace(req.ExpectedChecksum))
+	if checksumType == "" {
+		switch {
+		case req.ChecksumURL != "":
+			checksumType = "sha256"
+		case checksum != "":
+			checksumType = constants.ChecksumTypeForDigest(checksum)
+		}
+	}
+
+	if err := s.checkCredential(req.Credential); err != nil {
+		return nil, err
 	}
 
 	// Check if ISO already exists (based on unique constraint)
@@ -67,12 +141,19 @@ func (s *ISOService) CreateISO(req CreateISORequest) (*models.ISO, error) {
 		return nil, fmt.Errorf("failed to check for duplicate: %w", err)
 	}
 
+	var replaced *models.ISO
 	if exists {
+		if !req.Overwrite {
+			return nil, s.alreadyExists(normalizedName, req.Version, req.Arch, req.Edition, fileType)
+		}
 		existingISO, err := s.db.GetISOByComposite(normalizedName, req.Version, req.Arch, req.Edition, fileType)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get existing ISO: %w", err)
 		}
-		return nil, &ISOAlreadyExistsError{ExistingISO: existingISO}
+		if !existingISO.Status.IsRetryable() {
+			return nil, &ISOAlreadyExistsError{ExistingISO: existingISO}
+		}
+		replaced = existingISO
 	}
 
 	// Create ISO record
@@ -85,7 +166,12 @@ func (s *ISOService) CreateISO(req CreateISORequest) (*models.ISO, error) {
 		FileType:     fileType,
 		DownloadURL:  req.DownloadURL,
 		ChecksumURL:  req.ChecksumURL,
+		Checksum:     checksum,
+		SignatureURL: req.SignatureURL,
 		ChecksumType: checksumType,
+		IPFamily:     strings.ToLower(req.IPFamily),
+		Credential:   req.Credential,
+		Preset:       req.Preset,
 		Status:       models.StatusPending,
 		Progress:     0,
 		CreatedAt:    time.Now(),
@@ -94,22 +180,267 @@ func (s *ISOService) CreateISO(req CreateISORequest) (*models.ISO, error) {
 	// Compute derived fields (filename, file_path, download_link)
 	ComputeFields(iso)
 
-	// Save to database
-	if err := s.db.CreateISO(iso); err != nil {
+	// Reject before creating a record the queue can't take
+	if err := s.checkQueueCapacity(); err != nil {
+		return nil, err
+	}
+
+	// Save to database; an identical create may have won the race since the check above
+	if replaced != nil {
+		err = s.db.ReplaceISO(replaced.ID, iso)
+	} else {
+		err = s.db.CreateISO(iso)
+	}
+	if err != nil {
+		if errors.Is(err, db.ErrISOExists) {
+			return nil, s.alreadyExists(iso.Name, iso.Version, iso.Arch, iso.Edition, iso.FileType)
+		}
 		return nil, fmt.Errorf("failed to create ISO: %w", err)
 	}
 
 	// Queue download
-	s.manager.QueueDownload(iso)
+	if err := s.queueDownload(ctx, iso); err != nil {
+		return nil, err
+	}
 
 	return iso, nil
 }
 
+// PreviewISORequest represents the request to preview where an ISO would be
+// stored. FileType takes precedence over detecting it from DownloadURL.
+type PreviewISORequest struct {
+	Name        string
+	Version     string
+	Arch        string
+	Edition     string
+	DownloadURL string
+	FileType    string
+}
+
+// PreviewISO computes the normalized name, filename, path, and download link
+// CreateISO would give an ISO, and reports an existing ISO it would conflict
+// with. Nothing is created.
+func (s *ISOService) PreviewISO(req PreviewISORequest) (*models.ISOPreview, error) {
+	fileType := strings.ToLower(req.FileType)
+	if fileType == "" {
+		var err error
+		if fileType, err = DetectFileType(req.DownloadURL); err != nil {
+			return nil, fmt.Errorf("invalid file type: %w", err)
+		}
+	}
+
+	iso := &models.ISO{
+		Name:     req.Name,
+		Version:  req.Version,
+		Arch:     req.Arch,
+		Edition:  req.Edition,
+		FileType: fileType,
+	}
+	ComputeFields(iso)
+
+	preview := &models.ISOPreview{
+		Name:         iso.Name,
+		Version:      iso.Version,
+		Arch:         iso.Arch,
+		Edition:      iso.Edition,
+		FileType:     iso.FileType,
+		Filename:     iso.Filename,
+		FilePath:     iso.FilePath,
+		DownloadLink: iso.DownloadLink,
+	}
+	exists, err := s.db.ISOExists(iso.Name, iso.Version, iso.Arch, iso.Edition, iso.FileType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for duplicate: %w", err)
+	}
+	if exists {
+		existing, err := s.db.GetISOByComposite(iso.Name, iso.Version, iso.Arch, iso.Edition, iso.FileType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get existing ISO: %w", err)
+		}
+		preview.ExistingID = existing.ID
+	}
+	return preview, nil
+}
+
 // GetISO retrieves a single ISO by ID.
 func (s *ISOService) GetISO(id string) (*models.ISO, error) {
 	return s.db.GetISO(id)
 }
 
+// WaitForISO returns an ISO once its download has settled, that is it is no
+// longer pending, queued, downloading, or verifying. If ctx is done first, it
+// returns the ISO as it is then, with settled false.
+func (s *ISOService) WaitForISO(ctx context.Context, id string) (iso *models.ISO, settled bool, err error) {
+	// Wake on this instance's events; the poll catches downloads run by
+	// other instances sharing the database
+	wake := make(chan struct{}, 1)
+	if s.manager != nil {
+		remove := s.manager.AddQueueEventHook(func(event models.QueueEvent) {
+			if event.ISOID != id {
+				return
+			}
+			select {
+			case wake <- struct{}{}:
+			default:
+			}
+		})
+		defer remove()
+	}
+	ticker := time.NewTicker(constants.WaitPollIntervalSec * time.Second)
+	defer ticker.Stop()
+
+	for {
+		if iso, err = s.db.GetISO(id); err != nil {
+			return nil, false, err
+		}
+		if !iso.Status.IsActive() {
+			return iso, true, nil
+		}
+		select {
+		case <-ctx.Done():
+			return iso, false, nil
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// FindISOsBySHA256 retrieves the complete ISOs whose file has the given
+// SHA-256, so callers can tell whether an image is already mirrored.
+func (s *ISOService) FindISOsBySHA256(sum string) ([]models.ISO, error) {
+	return s.db.ListCompleteISOsBySHA256(strings.ToLower(sum))
+}
+
+// GetDownloadLog retrieves the log of an ISO's latest download run.
+func (s *ISOService) GetDownloadLog(id string) ([]models.DownloadLogEntry, error) {
+	if _, err := s.db.GetISO(id); err != nil {
+		return nil, err
+	}
+	return s.db.ListDownloadLog(id)
+}
+
+// CloneISORequest builds a create request from source, overridden by the
+// fields req sets. When the version changes, the old version is replaced
+// with the new one in URLs that req leaves unset, so bumping the version is
+// usually enough.
+func CloneISORequest(source *models.ISO, req models.UpdateISORequest) CreateISORequest {
+	clone := CreateISORequest{
+		Name:         source.Name,
+		Version:      source.Version,
+		Arch:         source.Arch,
+		Edition:      source.Edition,
+		DownloadURL:  source.DownloadURL,
+		ChecksumURL:  source.ChecksumURL,
+		SignatureURL: source.SignatureURL,
+		ChecksumType: source.ChecksumType,
+		IPFamily:     source.IPFamily,
+		Credential:   source.Credential,
+		Preset:       source.Preset,
+	}
+
+	// An expected checksum only matches the same file
+	if source.ChecksumURL == "" && req.Version == nil && req.DownloadURL == nil && req.ChecksumURL == nil {
+		clone.ExpectedChecksum = source.Checksum
+	}
+
+	if req.Version != nil {
+		clone.Version = *req.Version
+		clone.DownloadURL = replaceVersion(clone.DownloadURL, source.Version, clone.Version)
+		clone.ChecksumURL = replaceVersion(clone.ChecksumURL, source.Version, clone.Version)
+		clone.SignatureURL = replaceVersion(clone.SignatureURL, source.Version, clone.Version)
+	}
+	if req.Name != nil {
+		clone.Name = *req.Name
+	}
+	if req.Arch != nil {
+		clone.Arch = *req.Arch
+	}
+	if req.Edition != nil {
+		clone.Edition = *req.Edition
+	}
+	if req.DownloadURL != nil {
+		clone.DownloadURL = *req.DownloadURL
+	}
+	if req.ChecksumURL != nil {
+		clone.ChecksumURL = *req.ChecksumURL
+	}
+	if req.ExpectedChecksum != nil {
+		clone.ExpectedChecksum = *req.ExpectedChecksum
+	}
+	if req.SignatureURL != nil {
+		clone.SignatureURL = *req.SignatureURL
+	}
+	if req.ChecksumType != nil {
+		clone.ChecksumType = *req.ChecksumType
+	}
+	if req.IPFamily != nil {
+		clone.IPFamily = *req.IPFamily
+	}
+	if req.Credential != nil {
+		clone.Credential = *req.Credential
+	}
+	return clone
+}
+
+// VersionBumpRequest builds the create request for version of source. An
+// ISO created from a preset that still exists is expanded from it again, with
+// its arch and edition; any other ISO is cloned with the new version.
+func (s *ISOService) VersionBumpRequest(source *models.ISO, version string) (*CreateISORequest, error) {
+	if source.Preset != "" {
+		preset, err := s.db.GetPreset(source.Preset)
+		if err == nil {
+			return ExpandPreset(preset, models.ApplyPresetRequest{
+				Version:    version,
+				Arch:       source.Arch,
+				Edition:    source.Edition,
+				IPFamily:   source.IPFamily,
+				Credential: source.Credential,
+			})
+		}
+		if !errors.Is(err, db.ErrPresetNotFound) {
+			return nil, err
+		}
+	}
+
+	req := CloneISORequest(source, models.UpdateISORequest{Version: &version})
+	req.Preset = ""
+	return &req, nil
+}
+
+// replaceVersion replaces old with new in s wherever it isn't part of a
+// longer run of letters and digits, so "9.4" matches in "Rocky-9.4-x86_64"
+// but not in "19.4".
+func replaceVersion(s, old, new string) string {
+	if old == "" || old == new {
+		return s
+	}
+	isAlnum := func(i int) bool {
+		if i < 0 || i >= len(s) {
+			return false
+		}
+		c := s[i]
+		return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+	}
+
+	var b strings.Builder
+	last := 0
+	for offset := 0; ; {
+		i := strings.Index(s[offset:], old)
+		if i < 0 {
+			break
+		}
+		start, end := offset+i, offset+i+len(old)
+		if !isAlnum(start-1) && !isAlnum(end) {
+			b.WriteString(s[last:start])
+			b.WriteString(new)
+			last = end
+		}
+		offset = end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
 // ListISOs retrieves all ISOs.
 func (s *ISOService) ListISOs() ([]models.ISO, error) {
 	return s.db.ListISOs()
@@ -128,36 +459,94 @@ func (s *ISOService) DeleteISO(id string) error {
 		return err
 	}
 
-	// Cancel ongoing download if the ISO is being downloaded
+	// Cancel ongoing download if the ISO is being downloaded, and keep the
+	// record until its worker has let go of it
 	if iso.Status == models.StatusDownloading || iso.Status == models.StatusVerifying {
-		s.manager.CancelDownload(id)
-		time.Sleep(100 * time.Millisecond) // Wait for cancellation
+		if !s.manager.CancelAndWait(id) {
+			return ErrCancellationPending
+		}
 	}
 
 	// Delete database record
 	return s.db.DeleteISO(id)
 }
 
+// CancelISO takes a queued ISO off the queue, or stops its running download
+// or verification and waits for the worker. Either way the ISO ends up
+// canceled, so it can be retried.
+func (s *ISOService) CancelISO(id string) (*models.ISO, error) {
+	iso, err := s.db.GetISO(id)
+	if err != nil {
+		return nil, err
+	}
+	if !iso.Status.IsCancelable() {
+		return nil, &InvalidStateError{
+			CurrentStatus: string(iso.Status),
+			Message:       "Only queued, downloading, or verifying ISOs can be canceled",
+		}
+	}
+	if iso.Status == models.StatusQueued && s.manager.CancelQueued(id) {
+		return s.db.GetISO(id)
+	}
+	if !s.manager.CancelAndWait(id) {
+		return nil, ErrCancellationPending
+	}
+
+	// A worker that stopped has marked it canceled. Without one, e.g. for a
+	// row left downloading by a restart, the status is still ours to set
+	iso, err = s.db.GetISO(id)
+	if err != nil {
+		return nil, err
+	}
+	if iso.Status.IsCancelable() {
+		if err := s.db.UpdateISOStatus(id, models.StatusCanceled, "Download canceled"); err != nil {
+			return nil, fmt.Errorf("failed to update ISO: %w", err)
+		}
+		iso.Status = models.StatusCanceled
+		iso.ErrorMessage = "Download canceled"
+	}
+	return iso, nil
+}
+
+// SetPinned pins or unpins an ISO. Pinned ISOs are listed first and keep
+// every archived version when refreshed.
+func (s *ISOService) SetPinned(id string, pinned bool) (*models.ISO, error) {
+	iso, err := s.db.GetISO(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.UpdateISOPinned(id, pinned); err != nil {
+		return nil, err
+	}
+	iso.Pinned = pinned
+	return iso, nil
+}
+
 // RetryISO retries a failed download.
-func (s *ISOService) RetryISO(id string) (*models.ISO, error) {
+func (s *ISOService) RetryISO(ctx context.Context, id string) (*models.ISO, error) {
 	// Get ISO from database
 	iso, err := s.db.GetISO(id)
 	if err != nil {
 		return nil, err
 	}
 
-	// Verify status is failed
-	if iso.Status != models.StatusFailed {
+	// Verify status is failed or canceled
+	if !iso.Status.IsRetryable() {
 		return nil, &InvalidStateError{
 			CurrentStatus: string(iso.Status),
-			Message:       "Only failed downloads can be retried",
+			Message:       "Only failed or canceled downloads can be retried",
 		}
 	}
 
+	if err := s.checkQueueCapacity(); err != nil {
+		return nil, err
+	}
+
 	// Reset status, progress, and error message
 	iso.Status = models.StatusPending
 	iso.Progress = 0
 	iso.ErrorMessage = ""
+	iso.ErrorReason = models.ErrorReasonNone
 	iso.CompletedAt = nil
 
 	// Update database
@@ -166,15 +555,17 @@ func (s *ISOService) RetryISO(id string) (*models.ISO, error) {
 	}
 
 	// Re-queue download
-	s.manager.QueueDownload(iso)
+	if err := s.queueDownload(ctx, iso); err != nil {
+		return nil, err
+	}
 
 	return iso, nil
 }
 
 // UpdateISO updates an existing ISO.
-// For failed ISOs: can edit all fields, triggers re-download.
+// For failed or canceled ISOs: can edit all fields, triggers re-download.
 // For complete ISOs: can only edit metadata (name, version, arch, edition), moves files.
-func (s *ISOService) UpdateISO(id string, req models.UpdateISORequest) (*models.ISO, error) {
+func (s *ISOService) UpdateISO(ctx context.Context, id string, req models.UpdateISORequest) (*models.ISO, error) {
 	// Get existing ISO from database
 	iso, err := s.db.GetISO(id)
 	if err != nil {
@@ -185,6 +576,11 @@ func (s *ISOService) UpdateISO(id string, req models.UpdateISORequest) (*models.
 	if err := s.validateISOUpdate(iso, req); err != nil {
 		return nil, err
 	}
+	if req.Credential != nil {
+		if err := s.checkCredential(*req.Credential); err != nil {
+			return nil, err
+		}
+	}
 
 	// Store old path for file operations
 	oldFilePath := iso.FilePath
@@ -203,13 +599,13 @@ func (s *ISOService) UpdateISO(id string, req models.UpdateISORequest) (*models.
 	}
 
 	// Perform file operations and update database
-	return iso, s.finalizeISOUpdate(iso, oldFilePath, metadataChanged)
+	return iso, s.finalizeISOUpdate(ctx, iso, oldFilePath, metadataChanged)
 }
 
 // validateISOUpdate checks if the update is allowed based on ISO status.
 func (s *ISOService) validateISOUpdate(iso *models.ISO, req models.UpdateISORequest) error {
 	// Can't edit downloads in progress
-	if iso.Status == models.StatusPending || iso.Status == models.StatusDownloading || iso.Status == models.StatusVerifying {
+	if iso.Status.IsActive() {
 		return &InvalidStateError{
 			CurrentStatus: string(iso.Status),
 			Message:       "Cannot edit ISO while download is in progress",
@@ -218,10 +614,10 @@ func (s *ISOService) validateISOUpdate(iso *models.ISO, req models.UpdateISORequ
 
 	// For complete ISOs, only allow editing metadata
 	if iso.Status == models.StatusComplete {
-		if req.DownloadURL != nil || req.ChecksumURL != nil || req.ChecksumType != nil {
+		if req.DownloadURL != nil || req.ChecksumURL != nil || req.ExpectedChecksum != nil || req.SignatureURL != nil || req.ChecksumType != nil || req.IPFamily != nil {
 			return &InvalidStateError{
 				CurrentStatus: string(iso.Status),
-				Message:       "Cannot edit URLs for complete ISOs. Only metadata (name, version, arch, edition) can be changed",
+				Message:       "Cannot edit download settings for complete ISOs. Only metadata (name, version, arch, edition) can be changed",
 			}
 		}
 	}
@@ -251,8 +647,13 @@ func (s *ISOService) applyISOUpdates(iso *models.ISO, req models.UpdateISOReques
 		metadataChanged = true
 	}
 
-	// For failed ISOs, allow URL changes
-	if iso.Status == models.StatusFailed {
+	// The credential only affects future upstream requests, so it can always change
+	if req.Credential != nil {
+		iso.Credential = *req.Credential
+	}
+
+	// For failed or canceled ISOs, allow URL changes
+	if iso.Status.IsRetryable() {
 		if req.DownloadURL != nil {
 			if newFileType, err := DetectFileType(*req.DownloadURL); err == nil {
 				iso.DownloadURL = *req.DownloadURL
@@ -262,17 +663,56 @@ func (s *ISOService) applyISOUpdates(iso *models.ISO, req models.UpdateISOReques
 		}
 		if req.ChecksumURL != nil {
 			iso.ChecksumURL = *req.ChecksumURL
+			iso.Checksum = "" // Verified against the new checksum file instead
+		}
+		if req.ExpectedChecksum != nil {
+			iso.Checksum = strings.ToLower(strings.TrimSpace(*req.ExpectedChecksum))
+			if iso.Checksum != "" {
+				iso.ChecksumURL = "" // Verified against the expected checksum instead
+			}
+		}
+		if req.SignatureURL != nil {
+			iso.SignatureURL = *req.SignatureURL
 		}
 		if req.ChecksumType != nil {
 			iso.ChecksumType = *req.ChecksumType
+		} else if req.ExpectedChecksum != nil && iso.Checksum != "" {
+			iso.ChecksumType = constants.ChecksumTypeForDigest(iso.Checksum)
 		} else if req.ChecksumURL != nil && iso.ChecksumType == "" {
 			iso.ChecksumType = "sha256"
 		}
+		if req.IPFamily != nil {
+			iso.IPFamily = strings.ToLower(*req.IPFamily)
+		}
 	}
 
 	return metadataChanged
 }
 
+// checkCredential verifies that a referenced credential exists.
+func (s *ISOService) checkCredential(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, err := s.db.GetCredential(name); err != nil {
+		if errors.Is(err, db.ErrCredentialNotFound) {
+			return &InvalidCredentialError{Message: fmt.Sprintf("unknown credential: %s", name)}
+		}
+		return err
+	}
+	return nil
+}
+
+// alreadyExists returns an ISOAlreadyExistsError carrying the ISO stored
+// under the composite key.
+func (s *ISOService) alreadyExists(name, version, arch, edition, fileType string) error {
+	existingISO, err := s.db.GetISOByComposite(name, version, arch, edition, fileType)
+	if err != nil {
+		return fmt.Errorf("failed to get existing ISO: %w", err)
+	}
+	return &ISOAlreadyExistsError{ExistingISO: existingISO}
+}
+
 // checkUpdateConflict checks if the updated ISO conflicts with an existing ISO.
 func (s *ISOService) checkUpdateConflict(iso *models.ISO) error {
 	exists, err := s.db.ISOExists(iso.Name, iso.Version, iso.Arch, iso.Edition, iso.FileType)
@@ -295,26 +735,32 @@ func (s *ISOService) checkUpdateConflict(iso *models.ISO) error {
 }
 
 // finalizeISOUpdate performs file operations and database update based on ISO status.
-func (s *ISOService) finalizeISOUpdate(iso *models.ISO, oldFilePath string, metadataChanged bool) error {
-	if iso.Status == models.StatusFailed {
+func (s *ISOService) finalizeISOUpdate(ctx context.Context, iso *models.ISO, oldFilePath string, metadataChanged bool) error {
+	if iso.Status.IsRetryable() {
+		if err := s.checkQueueCapacity(); err != nil {
+			return err
+		}
+
 		// Reset and re-queue download
 		iso.Status = models.StatusPending
 		iso.Progress = 0
 		iso.ErrorMessage = ""
+		iso.ErrorReason = models.ErrorReasonNone
 		iso.CompletedAt = nil
 
 		if err := s.db.UpdateISO(iso); err != nil {
 			return fmt.Errorf("failed to update ISO: %w", err)
 		}
 
-		s.manager.QueueDownload(iso)
-		return nil
+		return s.queueDownload(ctx, iso)
 	}
 	if iso.Status == models.StatusComplete && metadataChanged {
 		// Move files for complete ISOs with metadata changes
 		if err := s.moveISOFiles(oldFilePath, iso.FilePath); err != nil {
 			return fmt.Errorf("failed to move ISO files: %w", err)
 		}
+		s.purger.PurgeISOFile(models.PurgeEventDeleted, oldFilePath)
+		s.purger.PurgeISOFile(models.PurgeEventReplaced, iso.FilePath)
 	}
 
 	// Update database
@@ -325,14 +771,14 @@ func (s *ISOService) finalizeISOUpdate(iso *models.ISO, oldFilePath string, meta
 	return nil
 }
 
-// moveISOFiles moves an ISO file and its checksum files from old path to new path.
+// moveISOFiles moves an ISO file and its sidecar files from old path to new path.
 func (s *ISOService) moveISOFiles(oldRelPath, newRelPath string) error {
 	// Convert relative paths to absolute paths
 	oldAbsPath := pathutil.ConstructISOPath(s.isoDir, oldRelPath)
 	newAbsPath := pathutil.ConstructISOPath(s.isoDir, newRelPath)
 
-	// Move the main ISO file and checksum files
-	if err := fileutil.MoveFileWithExtensions(oldAbsPath, newAbsPath, constants.ChecksumExtensions...); err != nil {
+	// Move the main ISO file and sidecar files
+	if err := fileutil.MoveFileWithExtensions(oldAbsPath, newAbsPath, s.sidecars...); err != nil {
 		return err
 	}
 
@@ -342,6 +788,18 @@ func (s *ISOService) moveISOFiles(oldRelPath, newRelPath string) error {
 	return nil
 }
 
+// sidecarExtensions returns the built-in sidecar extensions followed by the
+// extra ones they don't already include.
+func sidecarExtensions(extra []string) []string {
+	exts := append([]string{}, constants.SidecarExtensions...)
+	for _, ext := range extra {
+		if !slices.Contains(exts, ext) {
+			exts = append(exts, ext)
+		}
+	}
+	return exts
+}
+
 // Business logic functions (moved from models package)
 
 // "Ubuntu Server" -> "ubuntu-server".
@@ -415,6 +873,39 @@ func ComputeFields(iso *models.ISO) {
 	iso.Filename = GenerateFilename(iso.Name, iso.Version, iso.Edition, iso.Arch, iso.FileType)
 	iso.FilePath = GenerateFilePath(iso.Name, iso.Version, iso.Arch, iso.Filename)
 	iso.DownloadLink = GenerateDownloadLink(iso.FilePath)
+	iso.Verification = iso.VerificationState()
+}
+
+// checkQueueCapacity returns a QueueFullError when the download queue can't take another ISO.
+// Callers check it before changing any state so a rejected request leaves the ISO as it was.
+func (s *ISOService) checkQueueCapacity() error {
+	if s.manager.QueueDepth() >= s.manager.QueueCapacity() {
+		return &QueueFullError{Capacity: s.manager.QueueCapacity()}
+	}
+	return nil
+}
+
+// queueDownload hands an ISO to the download manager, reporting a duplicate as a
+// DownloadConflictError. If the queue filled up since checkQueueCapacity, the ISO
+// is marked failed so it can be retried later.
+func (s *ISOService) queueDownload(ctx context.Context, iso *models.ISO) error {
+	iso.RequestID = logger.RequestID(ctx)
+	err := s.manager.QueueDownload(iso)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, download.ErrAlreadyQueued):
+		return &DownloadConflictError{ISOID: iso.ID}
+	case errors.Is(err, download.ErrQueueFull):
+		iso.Status = models.StatusFailed
+		iso.ErrorMessage = "Download queue is full"
+		if updateErr := s.db.UpdateISOStatus(iso.ID, iso.Status, iso.ErrorMessage); updateErr != nil {
+			slog.Warn("failed to mark ISO as failed", slog.String("iso_id", iso.ID), slog.Any("error", updateErr))
+		}
+		return &QueueFullError{Capacity: s.manager.QueueCapacity()}
+	default:
+		return err
+	}
 }
 
 // Custom errors
@@ -428,6 +919,11 @@ func (e *ISOAlreadyExistsError) Error() string {
 	return "ISO already exists"
 }
 
+// ErrCancellationPending indicates that a canceled download's worker didn't
+// stop within CANCELLATION_WAIT_MS. The cancellation stands, so a retry
+// succeeds once it has.
+var ErrCancellationPending = errors.New("the download is still stopping; try again shortly")
+
 // InvalidStateError indicates an invalid state transition.
 type InvalidStateError struct {
 	CurrentStatus string
@@ -437,3 +933,21 @@ type InvalidStateError struct {
 func (e *InvalidStateError) Error() string {
 	return fmt.Sprintf("invalid state: %s (status: %s)", e.Message, e.CurrentStatus)
 }
+
+// DownloadConflictError indicates that the ISO is already queued or downloading.
+type DownloadConflictError struct {
+	ISOID string
+}
+
+func (e *DownloadConflictError) Error() string {
+	return "download is already queued or in progress"
+}
+
+// QueueFullError indicates that the download queue is at capacity.
+type QueueFullError struct {
+	Capacity int
+}
+
+func (e *QueueFullError) Error() string {
+	return fmt.Sprintf("download queue is full (%d waiting), try again later", e.Capacity)
+}
@@ -1,13 +1,17 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/aloks98/isoman/backend/internal/download"
+	"github.com/aloks98/isoman/backend/internal/logger"
 	"github.com/aloks98/isoman/backend/internal/models"
 	"github.com/aloks98/isoman/backend/internal/testutil"
 )
@@ -429,7 +433,7 @@ func TestISOService_CreateISO(t *testing.T) {
 			ChecksumType: "sha256",
 		}
 
-		iso, err := service.CreateISO(req)
+		iso, err := service.CreateISO(context.Background(), req)
 		if err != nil {
 			t.Fatalf("CreateISO() failed: %v", err)
 		}
@@ -437,14 +441,46 @@ func TestISOService_CreateISO(t *testing.T) {
 		if iso.Name != "alpine-linux" {
 			t.Errorf("Name should be normalized to 'alpine-linux', got: %s", iso.Name)
 		}
-		if iso.Status != models.StatusPending {
-			t.Errorf("Status should be 'pending', got: %s", iso.Status)
+		if iso.Status != models.StatusQueued {
+			t.Errorf("Status should be 'queued', got: %s", iso.Status)
 		}
 		if iso.FileType != "iso" {
 			t.Errorf("FileType should be 'iso', got: %s", iso.FileType)
 		}
 	})
 
+	t.Run("ExpectedChecksum", func(t *testing.T) {
+		iso, err := service.CreateISO(context.Background(), CreateISORequest{
+			Name:             "netboot",
+			Version:          "1.0",
+			Arch:             "x86_64",
+			DownloadURL:      "https://example.com/netboot.iso",
+			ExpectedChecksum: " D41D8CD98F00B204E9800998ECF8427E ",
+		})
+		if err != nil {
+			t.Fatalf("CreateISO() failed: %v", err)
+		}
+		if iso.Checksum != "d41d8cd98f00b204e9800998ecf8427e" || iso.ChecksumType != "md5" || !iso.HasChecksum() {
+			t.Errorf("Expected a normalized md5 checksum, got %q of type %q", iso.Checksum, iso.ChecksumType)
+		}
+	})
+
+	t.Run("CarriesRequestID", func(t *testing.T) {
+		ctx := logger.WithRequestID(context.Background(), "req-create")
+		iso, err := service.CreateISO(ctx, CreateISORequest{
+			Name:        "debian",
+			Version:     "12",
+			Arch:        "amd64",
+			DownloadURL: "https://example.com/debian.iso",
+		})
+		if err != nil {
+			t.Fatalf("CreateISO() failed: %v", err)
+		}
+		if iso.RequestID != "req-create" {
+			t.Errorf("Expected the queued ISO to carry the request ID, got: %q", iso.RequestID)
+		}
+	})
+
 	t.Run("DuplicateISO", func(t *testing.T) {
 		// Create first ISO
 		req := CreateISORequest{
@@ -454,13 +490,13 @@ func TestISOService_CreateISO(t *testing.T) {
 			DownloadURL: "https://example.com/ubuntu.iso",
 		}
 
-		_, err := service.CreateISO(req)
+		_, err := service.CreateISO(context.Background(), req)
 		if err != nil {
 			t.Fatalf("First CreateISO() failed: %v", err)
 		}
 
 		// Try to create duplicate
-		_, err = service.CreateISO(req)
+		_, err = service.CreateISO(context.Background(), req)
 		if err == nil {
 			t.Fatal("Expected error for duplicate ISO")
 		}
@@ -471,6 +507,83 @@ func TestISOService_CreateISO(t *testing.T) {
 		}
 	})
 
+	t.Run("ConcurrentDuplicates", func(t *testing.T) {
+		req := CreateISORequest{
+			Name:        "rocky",
+			Version:     "9.4",
+			Arch:        "x86_64",
+			DownloadURL: "https://example.com/rocky.iso",
+		}
+
+		// Identical creates can all pass the existence check before any inserts
+		const n = 8
+		errs := make(chan error, n)
+		var wg sync.WaitGroup
+		for range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := service.CreateISO(context.Background(), req)
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		created := 0
+		for err := range errs {
+			var existsErr *ISOAlreadyExistsError
+			switch {
+			case err == nil:
+				created++
+			case errors.As(err, &existsErr):
+				if existsErr.ExistingISO == nil || existsErr.ExistingISO.Name != "rocky" {
+					t.Errorf("Expected the existing ISO in the error, got: %+v", existsErr.ExistingISO)
+				}
+			default:
+				t.Errorf("Expected ISOAlreadyExistsError, got: %v", err)
+			}
+		}
+		if created != 1 {
+			t.Errorf("Expected exactly one create to succeed, got %d", created)
+		}
+	})
+
+	t.Run("Overwrite", func(t *testing.T) {
+		existing := func(status models.ISOStatus) *models.ISO {
+			iso := &models.ISO{ID: "kali-" + string(status), Name: "kali", Version: "2024.1", Arch: "x86_64", FileType: "iso", DownloadURL: "https://example.com/kali.iso", Status: status, CreatedAt: time.Now()}
+			iso.ComputeFields()
+			env.DB.CreateISO(iso)
+			return iso
+		}
+		req := CreateISORequest{
+			Name:        "kali",
+			Version:     "2024.1",
+			Arch:        "x86_64",
+			DownloadURL: "https://mirror.example.com/kali.iso",
+			Overwrite:   true,
+		}
+
+		complete := existing(models.StatusComplete)
+		var existsErr *ISOAlreadyExistsError
+		if _, err := service.CreateISO(context.Background(), req); !errors.As(err, &existsErr) || existsErr.ExistingISO.ID != complete.ID {
+			t.Fatalf("Expected ISOAlreadyExistsError for a complete ISO, got: %v", err)
+		}
+		env.DB.DeleteISO(complete.ID)
+
+		failed := existing(models.StatusFailed)
+		iso, err := service.CreateISO(context.Background(), req)
+		if err != nil {
+			t.Fatalf("CreateISO() failed: %v", err)
+		}
+		if iso.ID == failed.ID || iso.DownloadURL != req.DownloadURL {
+			t.Errorf("Expected a new ISO from the request, got %+v", iso)
+		}
+		if _, err := env.DB.GetISO(failed.ID); err == nil {
+			t.Error("Expected the failed ISO replaced")
+		}
+	})
+
 	t.Run("UnsupportedFileType", func(t *testing.T) {
 		req := CreateISORequest{
 			Name:        "Test",
@@ -479,7 +592,7 @@ func TestISOService_CreateISO(t *testing.T) {
 			DownloadURL: "https://example.com/file.txt",
 		}
 
-		_, err := service.CreateISO(req)
+		_, err := service.CreateISO(context.Background(), req)
 		if err == nil {
 			t.Fatal("Expected error for unsupported file type")
 		}
@@ -495,7 +608,7 @@ func TestISOService_CreateISO(t *testing.T) {
 			// ChecksumType not specified
 		}
 
-		iso, err := service.CreateISO(req)
+		iso, err := service.CreateISO(context.Background(), req)
 		if err != nil {
 			t.Fatalf("CreateISO() failed: %v", err)
 		}
@@ -504,6 +617,29 @@ func TestISOService_CreateISO(t *testing.T) {
 			t.Errorf("ChecksumType should default to 'sha256', got: %s", iso.ChecksumType)
 		}
 	})
+
+	t.Run("IPFamilyPersisted", func(t *testing.T) {
+		req := CreateISORequest{
+			Name:        "Fedora",
+			Version:     "40",
+			Arch:        "x86_64",
+			DownloadURL: "https://example.com/fedora.iso",
+			IPFamily:    "IPv4",
+		}
+
+		iso, err := service.CreateISO(context.Background(), req)
+		if err != nil {
+			t.Fatalf("CreateISO() failed: %v", err)
+		}
+
+		stored, err := service.GetISO(iso.ID)
+		if err != nil {
+			t.Fatalf("GetISO() failed: %v", err)
+		}
+		if stored.IPFamily != "ipv4" {
+			t.Errorf("IPFamily should be stored as 'ipv4', got: %s", stored.IPFamily)
+		}
+	})
 }
 
 func TestISOService_GetISO(t *testing.T) {
@@ -602,6 +738,38 @@ func TestISOService_DeleteISO(t *testing.T) {
 	})
 }
 
+func TestISOService_CreateISO_QueueFull(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	cfg := download.DefaultConfig()
+	cfg.QueueBuffer = 1
+	service := NewISOService(env.DB, download.NewManagerWithConfig(env.DB, env.ISODir, cfg), env.ISODir)
+
+	req := CreateISORequest{
+		Name:        "alpine",
+		Version:     "3.19.1",
+		Arch:        "x86_64",
+		DownloadURL: "https://example.com/alpine.iso",
+	}
+	if _, err := service.CreateISO(context.Background(), req); err != nil {
+		t.Fatalf("CreateISO() failed: %v", err)
+	}
+
+	// The manager isn't started, so the single queue slot stays taken
+	req.Version = "3.20.0"
+	_, err := service.CreateISO(context.Background(), req)
+	var queueFullErr *QueueFullError
+	if !errors.As(err, &queueFullErr) {
+		t.Fatalf("Expected QueueFullError, got: %v", err)
+	}
+
+	isos, _ := service.ListISOs()
+	if len(isos) != 1 {
+		t.Errorf("Rejected create should not leave a record, got: %d ISOs", len(isos))
+	}
+}
+
 func TestISOService_RetryISO(t *testing.T) {
 	service, env := setupTestISOService(t)
 	defer env.Cleanup()
@@ -612,13 +780,13 @@ func TestISOService_RetryISO(t *testing.T) {
 			Status: models.StatusFailed,
 		})
 
-		retried, err := service.RetryISO(iso.ID)
+		retried, err := service.RetryISO(context.Background(), iso.ID)
 		if err != nil {
 			t.Fatalf("RetryISO() failed: %v", err)
 		}
 
-		if retried.Status != models.StatusPending {
-			t.Errorf("Status should be 'pending', got: %s", retried.Status)
+		if retried.Status != models.StatusQueued {
+			t.Errorf("Status should be 'queued', got: %s", retried.Status)
 		}
 		if retried.Progress != 0 {
 			t.Errorf("Progress should be 0, got: %d", retried.Progress)
@@ -628,13 +796,61 @@ func TestISOService_RetryISO(t *testing.T) {
 		}
 	})
 
+	t.Run("CanceledISO", func(t *testing.T) {
+		iso := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{
+			Name:   "canceled-iso",
+			Status: models.StatusCanceled,
+		})
+
+		retried, err := service.RetryISO(context.Background(), iso.ID)
+		if err != nil {
+			t.Fatalf("RetryISO() failed: %v", err)
+		}
+
+		if retried.Status != models.StatusQueued {
+			t.Errorf("Status should be 'queued', got: %s", retried.Status)
+		}
+	})
+
+	t.Run("AlreadyInFlight_ShouldConflict", func(t *testing.T) {
+		iso := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{
+			Name:   "double-retry",
+			Status: models.StatusFailed,
+		})
+
+		if _, err := service.RetryISO(context.Background(), iso.ID); err != nil {
+			t.Fatalf("RetryISO() failed: %v", err)
+		}
+
+		// A second retry racing the first still sees the failed status
+		env.DB.UpdateISOStatus(iso.ID, models.StatusFailed, "")
+		_, err := service.RetryISO(context.Background(), iso.ID)
+		var conflictErr *DownloadConflictError
+		if !errors.As(err, &conflictErr) {
+			t.Errorf("Expected DownloadConflictError, got: %v", err)
+		}
+	})
+
+	t.Run("QueuedISO_ShouldFail", func(t *testing.T) {
+		iso := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{
+			Name:   "queued-iso",
+			Status: models.StatusQueued,
+		})
+
+		_, err := service.RetryISO(context.Background(), iso.ID)
+		var invalidStateErr *InvalidStateError
+		if !errors.As(err, &invalidStateErr) {
+			t.Errorf("Expected InvalidStateError, got: %v", err)
+		}
+	})
+
 	t.Run("CompleteISO_ShouldFail", func(t *testing.T) {
 		iso := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{
 			Name:   "complete-iso",
 			Status: models.StatusComplete,
 		})
 
-		_, err := service.RetryISO(iso.ID)
+		_, err := service.RetryISO(context.Background(), iso.ID)
 		if err == nil {
 			t.Fatal("Expected error when retrying complete ISO")
 		}
@@ -646,7 +862,7 @@ func TestISOService_RetryISO(t *testing.T) {
 	})
 
 	t.Run("NonExistentISO", func(t *testing.T) {
-		_, err := service.RetryISO("nonexistent-id")
+		_, err := service.RetryISO(context.Background(), "nonexistent-id")
 		if err == nil {
 			t.Fatal("Expected error for non-existent ISO")
 		}
@@ -670,7 +886,7 @@ func TestISOService_UpdateISO(t *testing.T) {
 			Version: &newVersion,
 		}
 
-		updated, err := service.UpdateISO(iso.ID, req)
+		updated, err := service.UpdateISO(context.Background(), iso.ID, req)
 		if err != nil {
 			t.Fatalf("UpdateISO() failed: %v", err)
 		}
@@ -681,9 +897,9 @@ func TestISOService_UpdateISO(t *testing.T) {
 		if updated.Version != "2.0" {
 			t.Errorf("Version should be '2.0', got: %s", updated.Version)
 		}
-		// Should reset to pending
-		if updated.Status != models.StatusPending {
-			t.Errorf("Status should be 'pending', got: %s", updated.Status)
+		// Should be re-queued
+		if updated.Status != models.StatusQueued {
+			t.Errorf("Status should be 'queued', got: %s", updated.Status)
 		}
 	})
 
@@ -704,7 +920,7 @@ func TestISOService_UpdateISO(t *testing.T) {
 			Edition: &newEdition,
 		}
 
-		updated, err := service.UpdateISO(iso.ID, req)
+		updated, err := service.UpdateISO(context.Background(), iso.ID, req)
 		if err != nil {
 			t.Fatalf("UpdateISO() failed: %v", err)
 		}
@@ -729,7 +945,7 @@ func TestISOService_UpdateISO(t *testing.T) {
 			Name: &newName,
 		}
 
-		_, err := service.UpdateISO(iso.ID, req)
+		_, err := service.UpdateISO(context.Background(), iso.ID, req)
 		if err == nil {
 			t.Fatal("Expected error when updating downloading ISO")
 		}
@@ -751,7 +967,7 @@ func TestISOService_UpdateISO(t *testing.T) {
 			DownloadURL: &newURL,
 		}
 
-		_, err := service.UpdateISO(iso.ID, req)
+		_, err := service.UpdateISO(context.Background(), iso.ID, req)
 		if err == nil {
 			t.Fatal("Expected error when changing URL of complete ISO")
 		}
@@ -762,13 +978,44 @@ func TestISOService_UpdateISO(t *testing.T) {
 		}
 	})
 
+	t.Run("SwitchBetweenChecksumURLAndExpected", func(t *testing.T) {
+		iso := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{
+			Name:   "checksum-switch",
+			Status: models.StatusFailed,
+		})
+
+		checksum := strings.Repeat("ab", 64)
+		updated, err := service.UpdateISO(context.Background(), iso.ID, models.UpdateISORequest{ExpectedChecksum: &checksum})
+		if err != nil {
+			t.Fatalf("UpdateISO() failed: %v", err)
+		}
+		if updated.ChecksumURL != "" || updated.Checksum != checksum || updated.ChecksumType != "sha512" {
+			t.Errorf("Expected the sha512 expected checksum to replace the URL, got %q, %q, %q",
+				updated.ChecksumURL, updated.Checksum, updated.ChecksumType)
+		}
+
+		other := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{
+			Name:   "checksum-switch-back",
+			Status: models.StatusFailed,
+		})
+		env.DB.UpdateISOChecksum(other.ID, checksum)
+		checksumURL := "https://example.com/SHA256SUMS"
+		updated, err = service.UpdateISO(context.Background(), other.ID, models.UpdateISORequest{ChecksumURL: &checksumURL})
+		if err != nil {
+			t.Fatalf("UpdateISO() failed: %v", err)
+		}
+		if updated.ChecksumURL != checksumURL || updated.Checksum != "" {
+			t.Errorf("Expected the checksum URL to replace the expected checksum, got %q, %q", updated.ChecksumURL, updated.Checksum)
+		}
+	})
+
 	t.Run("NonExistentISO", func(t *testing.T) {
 		newName := "test"
 		req := models.UpdateISORequest{
 			Name: &newName,
 		}
 
-		_, err := service.UpdateISO("nonexistent-id", req)
+		_, err := service.UpdateISO(context.Background(), "nonexistent-id", req)
 		if err == nil {
 			t.Fatal("Expected error for non-existent ISO")
 		}
@@ -799,3 +1046,65 @@ func TestInvalidStateError(t *testing.T) {
 		t.Errorf("Error() should contain message, got: %s", errStr)
 	}
 }
+
+func TestCloneISORequest(t *testing.T) {
+	source := &models.ISO{
+		Name:         "rocky",
+		Version:      "9.4",
+		Arch:         "x86_64",
+		Edition:      "minimal",
+		DownloadURL:  "https://mirror.example.com/rocky/9.4/isos/x86_64/Rocky-9.4-x86_64-minimal.iso",
+		ChecksumURL:  "https://mirror.example.com/rocky/9.4/isos/x86_64/CHECKSUM",
+		ChecksumType: "sha256",
+		Credential:   "mirror",
+	}
+
+	version := "9.5"
+	got := CloneISORequest(source, models.UpdateISORequest{Version: &version})
+	want := CreateISORequest{
+		Name:         "rocky",
+		Version:      "9.5",
+		Arch:         "x86_64",
+		Edition:      "minimal",
+		DownloadURL:  "https://mirror.example.com/rocky/9.5/isos/x86_64/Rocky-9.5-x86_64-minimal.iso",
+		ChecksumURL:  "https://mirror.example.com/rocky/9.5/isos/x86_64/CHECKSUM",
+		ChecksumType: "sha256",
+		Credential:   "mirror",
+	}
+	if got != want {
+		t.Errorf("CloneISORequest() = %+v, want %+v", got, want)
+	}
+
+	url := "https://other.example.com/rocky-9.5.iso"
+	got = CloneISORequest(source, models.UpdateISORequest{Version: &version, DownloadURL: &url})
+	if got.DownloadURL != url || got.ChecksumURL != want.ChecksumURL {
+		t.Errorf("Expected the explicit URL to win, got %+v", got)
+	}
+
+	// An expected checksum is kept for the same file only
+	literal := &models.ISO{Name: "netboot", Version: "1.0", DownloadURL: "https://example.com/netboot.iso", Checksum: "d41d8cd98f00b204e9800998ecf8427e", ChecksumType: "md5"}
+	edition := "efi"
+	if got := CloneISORequest(literal, models.UpdateISORequest{Edition: &edition}); got.ExpectedChecksum != literal.Checksum {
+		t.Errorf("Expected the checksum kept for the same file, got %q", got.ExpectedChecksum)
+	}
+	version = "2.0"
+	if got := CloneISORequest(literal, models.UpdateISORequest{Version: &version}); got.ExpectedChecksum != "" {
+		t.Errorf("Expected the checksum dropped for another version, got %q", got.ExpectedChecksum)
+	}
+}
+
+func TestReplaceVersion(t *testing.T) {
+	tests := []struct {
+		s, old, new, want string
+	}{
+		{"https://example.com/9.4/Rocky-9.4-x86_64.iso", "9.4", "9.5", "https://example.com/9.5/Rocky-9.5-x86_64.iso"},
+		{"https://example.com/19.4/a-9.40.iso", "9.4", "9.5", "https://example.com/19.4/a-9.40.iso"},
+		{"https://example.com/ubuntu_22.04_amd64.iso", "22.04", "24.04", "https://example.com/ubuntu_24.04_amd64.iso"},
+		{"https://example.com/latest.iso", "", "1", "https://example.com/latest.iso"},
+	}
+	for _, tt := range tests {
+		if got := replaceVersion(tt.s, tt.old, tt.new); got != tt.want {
+			t.Errorf("replaceVersion(%q, %q, %q) = %q, want %q", tt.s, tt.old, tt.new, got, tt.want)
+		}
+	}
+}
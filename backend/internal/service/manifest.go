@@ -0,0 +1,269 @@
+package service
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/download"
+	"github.com/aloks98/isoman/backend/internal/fileutil"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/pathutil"
+
+	"github.com/google/uuid"
+)
+
+// ExportManifest builds a manifest of every file in the ISO directory.
+// SHA-256 digests recorded at download time are reused; other files are hashed.
+func (s *ISOService) ExportManifest() (*models.Manifest, error) {
+	isos, err := s.db.ListISOs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ISOs: %w", err)
+	}
+	byPath := make(map[string]*models.ISO, len(isos))
+	for i := range isos {
+		if isos[i].Status == models.StatusComplete {
+			byPath[filepath.ToSlash(isos[i].FilePath)] = &isos[i]
+		}
+	}
+
+	manifest := &models.Manifest{
+		Version:     models.ManifestFormatVersion,
+		GeneratedAt: time.Now().UTC(),
+		Entries:     make([]models.ManifestEntry, 0),
+	}
+
+	err = filepath.WalkDir(s.isoDir, func(p string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		// Skip hidden directories such as .tmp and .versions
+		if d.IsDir() {
+			if p != s.isoDir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.isoDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		entry, err := newManifestEntry(p, rel, byPath[rel])
+		if err != nil {
+			return err
+		}
+		manifest.Entries = append(manifest.Entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// newManifestEntry describes the file at absPath. iso may be nil for sidecar files.
+func newManifestEntry(absPath, rel string, iso *models.ISO) (models.ManifestEntry, error) {
+	fi, err := os.Stat(absPath)
+	if err != nil {
+		return models.ManifestEntry{}, err
+	}
+
+	entry := models.ManifestEntry{Path: rel, SizeBytes: fi.Size(), ISO: iso}
+	if iso != nil {
+		if sum := knownSHA256(iso); sum != "" {
+			entry.SHA256 = sum
+			return entry, nil
+		}
+	}
+
+	sum, err := download.ComputeHash(absPath, "sha256")
+	if err != nil {
+		return models.ManifestEntry{}, err
+	}
+	entry.SHA256 = sum
+	return entry, nil
+}
+
+// ImportManifest verifies files copied from another instance against a manifest
+// and registers them. Image files become complete ISOs; sidecar files are placed
+// next to them. Files that fail verification are reported and not imported.
+func (s *ISOService) ImportManifest(req models.ManifestImportRequest) (*models.ManifestImportResult, error) {
+	if req.Manifest == nil || req.Manifest.Version != models.ManifestFormatVersion {
+		return nil, &InvalidAdoptRequestError{Message: fmt.Sprintf("unsupported manifest version (expected %d)", models.ManifestFormatVersion)}
+	}
+	sourceDir, err := filepath.Abs(req.SourceDir)
+	if err != nil {
+		return nil, &InvalidAdoptRequestError{Message: fmt.Sprintf("invalid source_dir: %v", err)}
+	}
+	if fi, err := os.Stat(sourceDir); err != nil || !fi.IsDir() {
+		return nil, &InvalidAdoptRequestError{Message: fmt.Sprintf("source_dir is not a directory: %s", req.SourceDir)}
+	}
+
+	mode := req.Mode
+	if mode == "" {
+		mode = models.AdoptModeLink
+	}
+
+	result := &models.ManifestImportResult{
+		Imported: make([]*models.ISO, 0),
+		Skipped:  make([]models.AdoptSkippedFile, 0),
+		Invalid:  make([]models.AdoptSkippedFile, 0),
+		DryRun:   req.DryRun,
+	}
+
+	// Import image files first so sidecars land next to registered ISOs
+	entries := make([]models.ManifestEntry, 0, len(req.Manifest.Entries))
+	for _, entry := range req.Manifest.Entries {
+		if entry.ISO != nil {
+			entries = append(entries, entry)
+		}
+	}
+	for _, entry := range req.Manifest.Entries {
+		if entry.ISO == nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	for _, entry := range entries {
+		srcPath, reason := verifyManifestEntry(sourceDir, entry)
+		if reason != "" {
+			result.Invalid = append(result.Invalid, models.AdoptSkippedFile{SourcePath: entry.Path, Reason: reason})
+			continue
+		}
+
+		if entry.ISO == nil {
+			destPath := pathutil.ConstructISOPath(s.isoDir, filepath.FromSlash(entry.Path))
+			if fileutil.FileExists(destPath) {
+				result.Skipped = append(result.Skipped, models.AdoptSkippedFile{SourcePath: entry.Path, Reason: "destination file already exists"})
+				continue
+			}
+			if !req.DryRun {
+				if err := placeAdoptedFile(srcPath, destPath, mode); err != nil {
+					result.Invalid = append(result.Invalid, models.AdoptSkippedFile{SourcePath: entry.Path, Reason: err.Error()})
+				}
+			}
+			continue
+		}
+
+		iso, reason := s.importManifestISO(srcPath, entry, mode, req.DryRun)
+		if iso == nil {
+			result.Skipped = append(result.Skipped, models.AdoptSkippedFile{SourcePath: entry.Path, Reason: reason})
+			continue
+		}
+		result.Imported = append(result.Imported, iso)
+	}
+
+	slog.Info("manifest import finished",
+		slog.String("source_dir", sourceDir),
+		slog.Int("imported", len(result.Imported)),
+		slog.Int("skipped", len(result.Skipped)),
+		slog.Int("invalid", len(result.Invalid)),
+		slog.Bool("dry_run", req.DryRun),
+	)
+
+	return result, nil
+}
+
+// importManifestISO registers a verified image file, returning the new ISO or the reason it was skipped.
+func (s *ISOService) importManifestISO(srcPath string, entry models.ManifestEntry, mode string, dryRun bool) (*models.ISO, string) {
+	now := time.Now()
+	iso := &models.ISO{
+		ID:            uuid.New().String(),
+		Name:          entry.ISO.Name,
+		Version:       entry.ISO.Version,
+		Arch:          entry.ISO.Arch,
+		Edition:       entry.ISO.Edition,
+		FileType:      entry.ISO.FileType,
+		DownloadURL:   entry.ISO.DownloadURL,
+		ChecksumURL:   entry.ISO.ChecksumURL,
+		ChecksumType:  entry.ISO.ChecksumType,
+		Checksum:      entry.ISO.Checksum,
+		SHA256:        strings.ToLower(entry.SHA256),
+		SHA512:        entry.ISO.SHA512,
+		MD5:           entry.ISO.MD5,
+		IntegrityHash: entry.ISO.IntegrityHash,
+		IPFamily:      entry.ISO.IPFamily,
+		SizeBytes:     entry.SizeBytes,
+		Status:        models.StatusComplete,
+		Progress:      100,
+		CreatedAt:     entry.ISO.CreatedAt,
+		CompletedAt:   &now,
+	}
+	if iso.CreatedAt.IsZero() {
+		iso.CreatedAt = now
+	}
+	ComputeFields(iso)
+
+	exists, err := s.db.ISOExists(iso.Name, iso.Version, iso.Arch, iso.Edition, iso.FileType)
+	if err != nil {
+		return nil, fmt.Sprintf("failed to check for duplicate: %v", err)
+	}
+	if exists {
+		return nil, "ISO already exists"
+	}
+
+	destPath := pathutil.ConstructISOPath(s.isoDir, iso.FilePath)
+	if fileutil.FileExists(destPath) {
+		return nil, "destination file already exists"
+	}
+
+	if dryRun {
+		return iso, ""
+	}
+
+	if err := placeAdoptedFile(srcPath, destPath, mode); err != nil {
+		return nil, err.Error()
+	}
+	if err := s.db.CreateISO(iso); err != nil {
+		if mode == models.AdoptModeMove {
+			if moveErr := fileutil.MoveFile(destPath, srcPath); moveErr != nil {
+				slog.Warn("failed to restore imported file", slog.String("path", srcPath), slog.Any("error", moveErr))
+			}
+		} else {
+			fileutil.DeleteFileSilently(destPath)
+		}
+		return nil, fmt.Sprintf("failed to create ISO: %v", err)
+	}
+
+	return iso, ""
+}
+
+// verifyManifestEntry checks that the entry's file exists under sourceDir with the
+// expected size and SHA-256. It returns the file's path, or a reason when verification fails.
+func verifyManifestEntry(sourceDir string, entry models.ManifestEntry) (string, string) {
+	clean := path.Clean(entry.Path)
+	if entry.Path == "" || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", "path escapes source directory"
+	}
+	srcPath := filepath.Join(sourceDir, filepath.FromSlash(clean))
+
+	fi, err := os.Stat(srcPath)
+	if err != nil {
+		return "", "file not found"
+	}
+	if fi.Size() != entry.SizeBytes {
+		return "", fmt.Sprintf("size mismatch: expected %d, got %d", entry.SizeBytes, fi.Size())
+	}
+
+	sum, err := download.ComputeHash(srcPath, "sha256")
+	if err != nil {
+		return "", err.Error()
+	}
+	if !strings.EqualFold(sum, entry.SHA256) {
+		return "", fmt.Sprintf("sha256 mismatch: expected %s, got %s", entry.SHA256, sum)
+	}
+
+	return srcPath, ""
+}
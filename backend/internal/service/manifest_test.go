@@ -0,0 +1,126 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/pathutil"
+)
+
+func TestISOService_ExportImportManifest(t *testing.T) {
+	source, srcEnv := setupTestISOService(t)
+	defer srcEnv.Cleanup()
+
+	// Populate the source instance through adopt so it has a registered ISO and a sidecar
+	mirror := t.TempDir()
+	writeMirrorFile(t, mirror, "alpine/3.19.1/x86_64/alpine.iso", "alpine")
+	if _, err := source.AdoptDirectory(models.AdoptDirectoryRequest{SourceDir: mirror}); err != nil {
+		t.Fatalf("AdoptDirectory() failed: %v", err)
+	}
+	writeMirrorFile(t, srcEnv.ISODir, "alpine/3.19.1/x86_64/alpine-3.19.1-x86_64.iso.sha256", "sum")
+
+	manifest, err := source.ExportManifest()
+	if err != nil {
+		t.Fatalf("ExportManifest() failed: %v", err)
+	}
+	if manifest.Version != models.ManifestFormatVersion {
+		t.Errorf("Version should be %d, got: %d", models.ManifestFormatVersion, manifest.Version)
+	}
+	if len(manifest.Entries) != 2 {
+		t.Fatalf("Expected 2 entries, got: %+v", manifest.Entries)
+	}
+	withISO := 0
+	for _, entry := range manifest.Entries {
+		if entry.SHA256 == "" {
+			t.Errorf("Entry %s has no sha256", entry.Path)
+		}
+		if entry.ISO != nil {
+			withISO++
+		}
+	}
+	if withISO != 1 {
+		t.Errorf("Expected 1 entry with ISO metadata, got: %d", withISO)
+	}
+
+	t.Run("Import", func(t *testing.T) {
+		target, env := setupTestISOService(t)
+		defer env.Cleanup()
+
+		result, err := target.ImportManifest(models.ManifestImportRequest{
+			Manifest:  manifest,
+			SourceDir: srcEnv.ISODir,
+			Mode:      models.AdoptModeCopy,
+		})
+		if err != nil {
+			t.Fatalf("ImportManifest() failed: %v", err)
+		}
+		if len(result.Imported) != 1 || len(result.Invalid) != 0 {
+			t.Fatalf("Expected 1 imported and no invalid, got: %+v", result)
+		}
+
+		iso := result.Imported[0]
+		if iso.Status != models.StatusComplete {
+			t.Errorf("Status should be 'complete', got: %s", iso.Status)
+		}
+		if _, err := os.Stat(pathutil.ConstructISOPath(env.ISODir, iso.FilePath)); err != nil {
+			t.Errorf("Imported file should exist: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(env.ISODir, "alpine", "3.19.1", "x86_64", "alpine-3.19.1-x86_64.iso.sha256")); err != nil {
+			t.Errorf("Sidecar file should be placed: %v", err)
+		}
+
+		// Importing again skips everything
+		result, err = target.ImportManifest(models.ManifestImportRequest{Manifest: manifest, SourceDir: srcEnv.ISODir})
+		if err != nil {
+			t.Fatalf("Second ImportManifest() failed: %v", err)
+		}
+		if len(result.Imported) != 0 || len(result.Skipped) != 2 {
+			t.Errorf("Expected 0 imported and 2 skipped, got: %+v", result)
+		}
+	})
+
+	t.Run("CorruptFile", func(t *testing.T) {
+		target, env := setupTestISOService(t)
+		defer env.Cleanup()
+
+		copyDir := t.TempDir()
+		for _, entry := range manifest.Entries {
+			writeMirrorFile(t, copyDir, entry.Path, "tampered")
+		}
+
+		result, err := target.ImportManifest(models.ManifestImportRequest{Manifest: manifest, SourceDir: copyDir, DryRun: true})
+		if err != nil {
+			t.Fatalf("ImportManifest() failed: %v", err)
+		}
+		if len(result.Imported) != 0 || len(result.Invalid) != 2 {
+			t.Errorf("Expected both files to fail verification, got: %+v", result)
+		}
+	})
+
+	t.Run("InvalidRequests", func(t *testing.T) {
+		target, env := setupTestISOService(t)
+		defer env.Cleanup()
+
+		var invalidErr *InvalidAdoptRequestError
+
+		_, err := target.ImportManifest(models.ManifestImportRequest{Manifest: &models.Manifest{Version: 99}, SourceDir: t.TempDir()})
+		if !errors.As(err, &invalidErr) {
+			t.Errorf("Expected InvalidAdoptRequestError for unknown version, got: %v", err)
+		}
+
+		escaping := &models.Manifest{
+			Version: models.ManifestFormatVersion,
+			Entries: []models.ManifestEntry{{Path: "../etc/passwd", SHA256: "x"}},
+		}
+		result, err := target.ImportManifest(models.ManifestImportRequest{Manifest: escaping, SourceDir: t.TempDir()})
+		if err != nil {
+			t.Fatalf("ImportManifest() failed: %v", err)
+		}
+		if len(result.Invalid) != 1 {
+			t.Errorf("Expected escaping path to be rejected, got: %+v", result)
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/clock"
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/i18n"
+)
+
+// Notifier emails download failures and health problems to one channel's
+// recipients. Notifications arriving within the digest window of the first
+// are batched into a single message, so a mirror outage that fails twenty
+// downloads sends one email instead of twenty.
+type Notifier struct {
+	db         *db.DB
+	mailer     Mailer
+	clock      clock.Clock
+	recipients []string
+	window     time.Duration // Zero sends without waiting for more
+	localizer  *i18n.Localizer
+
+	mu      sync.Mutex
+	pending []notification
+	timer   clock.Timer // Running while notifications are pending
+}
+
+type notification struct {
+	at      time.Time
+	summary string
+}
+
+// NewNotifier creates a notifier that mails recipients at most once per window.
+func NewNotifier(database *db.DB, mailer Mailer, recipients []string, window time.Duration) *Notifier {
+	return &Notifier{
+		db:         database,
+		mailer:     mailer,
+		recipients: recipients,
+		window:     max(window, 0),
+		clock:      clock.Real(),
+		localizer:  i18n.Builtin().Localizer(i18n.SourceLanguage),
+	}
+}
+
+// SetLocalizer writes notifications in l's language instead of English.
+func (n *Notifier) SetLocalizer(l *i18n.Localizer) {
+	n.localizer = l
+}
+
+// Notify queues a one-line summary for the next message. A nil Notifier
+// drops it, so callers needn't check whether notifications are configured.
+func (n *Notifier) Notify(summary string) {
+	if n == nil {
+		return
+	}
+	// One line per notification keeps the digest readable and the subject a
+	// single header line
+	summary = strings.Join(strings.Fields(summary), " ")
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.pending = append(n.pending, notification{at: n.clock.Now(), summary: summary})
+	if n.timer == nil {
+		n.timer = n.clock.AfterFunc(n.window, n.Flush)
+	}
+}
+
+// NotifyDownloadFailed queues a notification for a failed ISO.
+func (n *Notifier) NotifyDownloadFailed(isoID string) {
+	if n == nil {
+		return
+	}
+	iso, err := n.db.GetISO(isoID)
+	if err != nil {
+		slog.Warn("failed to load failed ISO for notification", slog.String("iso_id", isoID), slog.Any("error", err))
+		return
+	}
+	n.Notify(n.localizer.T("notify.download_failed", iso.Filename, iso.ErrorMessage))
+}
+
+// Flush mails the pending notifications now. It runs when the digest window
+// ends, and at shutdown so nothing queued is lost. A failed send is logged
+// and dropped rather than retried into the next digest.
+func (n *Notifier) Flush() {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	pending := n.pending
+	n.pending = nil
+	if n.timer != nil {
+		n.timer.Stop()
+		n.timer = nil
+	}
+	n.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+	subject, body := renderNotifications(n.localizer, pending)
+	if err := n.mailer.Send(n.recipients, subject, body); err != nil {
+		slog.Warn("failed to mail notifications", slog.Int("count", len(pending)), slog.Any("error", err))
+		return
+	}
+	slog.Info("notifications sent", slog.Int("count", len(pending)), slog.Int("recipients", len(n.recipients)))
+}
+
+// renderNotifications formats a lone notification as its own message and
+// several as a digest, oldest first, with the messages of l.
+func renderNotifications(l *i18n.Localizer, pending []notification) (subject, body string) {
+	if len(pending) == 1 {
+		p := pending[0]
+		return l.T("notify.subject", p.summary), fmt.Sprintf("%s\n\n%s\n", p.summary, l.T("notify.at", p.at.Format(time.RFC1123)))
+	}
+
+	var b strings.Builder
+	b.WriteString(l.T("notify.digest_header",
+		len(pending), pending[0].at.Format(time.RFC1123), pending[len(pending)-1].at.Format(time.RFC1123)))
+	b.WriteString("\n\n")
+	for _, p := range pending {
+		fmt.Fprintf(&b, "%s  %s\n", p.at.Format(time.TimeOnly), p.summary)
+	}
+	return l.T("notify.subject_digest", len(pending)), b.String()
+}
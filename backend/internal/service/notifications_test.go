@@ -0,0 +1,124 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/clock"
+	"github.com/aloks98/isoman/backend/internal/i18n"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+)
+
+func TestNotifier_Digest(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	mailer := &fakeMailer{}
+	notifier := NewNotifier(env.DB, mailer, []string{"ops@example.com"}, time.Hour)
+
+	// A lone notification is sent as itself
+	notifier.Notify("storage.low: 10 MB free\nin /data")
+	notifier.Flush()
+	if len(mailer.subjects) != 1 || mailer.subjects[0] != "ISOMan: storage.low: 10 MB free in /data" {
+		t.Fatalf("Expected a single-line subject, got %q", mailer.subjects)
+	}
+
+	// A burst of failures within the window shares one message
+	for _, version := range []string{"3.19.1", "3.20.0", "3.21.0"} {
+		iso := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Version: version, Status: models.StatusFailed})
+		if err := env.DB.UpdateISOStatus(iso.ID, models.StatusFailed, "mirror unreachable"); err != nil {
+			t.Fatalf("UpdateISOStatus() failed: %v", err)
+		}
+		notifier.NotifyDownloadFailed(iso.ID)
+	}
+	notifier.Flush()
+	if len(mailer.subjects) != 2 || mailer.subjects[1] != "ISOMan: 3 notifications" {
+		t.Fatalf("Expected one digest of 3, got %q", mailer.subjects)
+	}
+	if got := strings.Count(mailer.bodies[1], "Download failed: alpine-linux-"); got != 3 {
+		t.Errorf("Expected 3 failures in the digest, got %d:\n%s", got, mailer.bodies[1])
+	}
+	if !strings.Contains(mailer.bodies[1], "mirror unreachable") {
+		t.Errorf("Digest is missing the error message:\n%s", mailer.bodies[1])
+	}
+
+	// Nothing pending, nothing sent
+	notifier.Flush()
+	if len(mailer.subjects) != 2 {
+		t.Errorf("Expected no empty digest, got %q", mailer.subjects)
+	}
+}
+
+func TestNotifier_WindowEnds(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	mailer := &fakeMailer{}
+	notifier := NewNotifier(env.DB, mailer, []string{"ops@example.com"}, time.Hour)
+	fake := clock.NewFake(time.Date(2024, 1, 26, 14, 45, 0, 0, time.UTC))
+	notifier.clock = fake
+
+	notifier.Notify("storage.low: 10 MB free")
+	fake.Advance(30 * time.Minute)
+	notifier.Notify("queue.full: 50 queued")
+	if len(mailer.subjects) != 0 {
+		t.Fatalf("Sent before the window ended: %q", mailer.subjects)
+	}
+
+	// The window runs from the first notification, not the latest
+	fake.Advance(30 * time.Minute)
+	if len(mailer.subjects) != 1 || mailer.subjects[0] != "ISOMan: 2 notifications" {
+		t.Fatalf("Expected one digest of 2 when the window ended, got %q", mailer.subjects)
+	}
+	if !strings.Contains(mailer.bodies[0], "15:15") {
+		t.Errorf("Digest should be dated by the notifier's clock:\n%s", mailer.bodies[0])
+	}
+}
+
+func TestNotifier_Localized(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	mailer := &fakeMailer{}
+	notifier := NewNotifier(env.DB, mailer, []string{"ops@example.com"}, time.Hour)
+	notifier.SetLocalizer(i18n.Builtin().Localizer("de"))
+
+	iso := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Status: models.StatusFailed})
+	if err := env.DB.UpdateISOStatus(iso.ID, models.StatusFailed, "mirror unreachable"); err != nil {
+		t.Fatalf("UpdateISOStatus() failed: %v", err)
+	}
+	notifier.NotifyDownloadFailed(iso.ID)
+	notifier.Flush()
+	if want := "ISOMan: Download fehlgeschlagen: " + iso.Filename + ": mirror unreachable"; len(mailer.subjects) != 1 || mailer.subjects[0] != want {
+		t.Fatalf("Expected %q, got %q", want, mailer.subjects)
+	}
+
+	notifier.Notify("storage.low: 10 MB free")
+	notifier.Notify("queue.full: 50 queued")
+	notifier.Flush()
+	if len(mailer.subjects) != 2 || mailer.subjects[1] != "ISOMan: 2 Benachrichtigungen" {
+		t.Fatalf("Expected a German digest, got %q", mailer.subjects)
+	}
+	if !strings.HasPrefix(mailer.bodies[1], "2 Benachrichtigungen zwischen ") {
+		t.Errorf("Expected a German digest body:\n%s", mailer.bodies[1])
+	}
+}
+
+func TestHealthMonitor_Notify(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	mailer := &fakeMailer{}
+	notifier := NewNotifier(env.DB, mailer, []string{"ops@example.com"}, time.Hour)
+	monitor := NewHealthMonitor(env.DB, nil, env.ISODir, 0)
+	monitor.SetNotifier(notifier)
+
+	monitor.Record(models.EventServerStarted, models.SeverityInfo, "version test")
+	monitor.Record(models.EventQueueFull, models.SeverityWarning, "queue is full")
+	notifier.Flush()
+	if len(mailer.subjects) != 1 || mailer.subjects[0] != "ISOMan: queue.full: queue is full" {
+		t.Errorf("Expected only the warning to be mailed, got %q", mailer.subjects)
+	}
+}
@@ -0,0 +1,317 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
+
+	"go.yaml.in/yaml/v3"
+)
+
+var presetPlaceholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+
+// PresetService manages presets and expands them into ISO requests.
+type PresetService struct {
+	db *db.DB
+}
+
+// NewPresetService creates a preset service.
+func NewPresetService(database *db.DB) *PresetService {
+	return &PresetService{db: database}
+}
+
+// ListPresets retrieves all presets.
+func (s *PresetService) ListPresets() ([]models.Preset, error) {
+	return s.db.ListPresets()
+}
+
+// GetPreset retrieves a preset by name.
+func (s *PresetService) GetPreset(name string) (*models.Preset, error) {
+	return s.db.GetPreset(name)
+}
+
+// CreatePreset validates and stores a new preset.
+func (s *PresetService) CreatePreset(req models.CreatePresetRequest) (*models.Preset, error) {
+	preset, err := newPreset(req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.db.GetPreset(req.Name); err == nil {
+		return nil, &PresetExistsError{Name: req.Name}
+	} else if !errors.Is(err, db.ErrPresetNotFound) {
+		return nil, err
+	}
+
+	if err := s.db.CreatePreset(preset); err != nil {
+		return nil, err
+	}
+	return preset, nil
+}
+
+// UpdatePreset changes the fields of a preset that req sets.
+func (s *PresetService) UpdatePreset(name string, req models.UpdatePresetRequest) (*models.Preset, error) {
+	preset, err := s.db.GetPreset(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ISOName != nil {
+		preset.ISOName = strings.TrimSpace(*req.ISOName)
+	}
+	if req.Arch != nil {
+		preset.Arch = strings.TrimSpace(*req.Arch)
+	}
+	if req.Edition != nil {
+		preset.Edition = strings.TrimSpace(*req.Edition)
+	}
+	if req.DownloadURL != nil {
+		preset.DownloadURL = strings.TrimSpace(*req.DownloadURL)
+	}
+	if req.ChecksumURL != nil {
+		preset.ChecksumURL = strings.TrimSpace(*req.ChecksumURL)
+	}
+	if req.ChecksumType != nil {
+		preset.ChecksumType = strings.ToLower(strings.TrimSpace(*req.ChecksumType))
+	}
+	if err := validatePreset(preset); err != nil {
+		return nil, err
+	}
+
+	preset.UpdatedAt = time.Now()
+	if err := s.db.UpdatePreset(preset); err != nil {
+		return nil, err
+	}
+	return preset, nil
+}
+
+// ExportPresets returns the named presets, or all of them when names is
+// empty, as a pack.
+func (s *PresetService) ExportPresets(names []string) (*models.PresetPack, error) {
+	var presets []models.Preset
+	if len(names) == 0 {
+		var err error
+		if presets, err = s.db.ListPresets(); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range names {
+		preset, err := s.db.GetPreset(name)
+		if err != nil {
+			return nil, err
+		}
+		presets = append(presets, *preset)
+	}
+
+	pack := &models.PresetPack{Version: models.PresetPackVersion, Presets: []models.CreatePresetRequest{}}
+	for _, preset := range presets {
+		pack.Presets = append(pack.Presets, models.CreatePresetRequest{
+			Name:         preset.Name,
+			ISOName:      preset.ISOName,
+			Arch:         preset.Arch,
+			Edition:      preset.Edition,
+			DownloadURL:  preset.DownloadURL,
+			ChecksumURL:  preset.ChecksumURL,
+			ChecksumType: preset.ChecksumType,
+		})
+	}
+	return pack, nil
+}
+
+// ImportPresets stores the presets of a pack. Presets whose name is taken are
+// skipped, or overwritten when existing is models.PresetImportReplace. The
+// whole pack is validated first, so an invalid preset stores none of them.
+func (s *PresetService) ImportPresets(pack *models.PresetPack, existing string) (*models.PresetImportResult, error) {
+	if pack.Version != models.PresetPackVersion {
+		return nil, &InvalidPresetError{Message: fmt.Sprintf("unsupported preset pack version %d; this server reads version %d", pack.Version, models.PresetPackVersion)}
+	}
+	if existing == "" {
+		existing = models.PresetImportSkip
+	}
+	if existing != models.PresetImportSkip && existing != models.PresetImportReplace {
+		return nil, &InvalidPresetError{Message: fmt.Sprintf("existing must be %s or %s", models.PresetImportSkip, models.PresetImportReplace)}
+	}
+
+	now := time.Now()
+	presets := make([]*models.Preset, 0, len(pack.Presets))
+	seen := make(map[string]bool, len(pack.Presets))
+	for i, req := range pack.Presets {
+		preset, err := newPreset(req, now)
+		if err != nil {
+			return nil, &InvalidPresetError{Message: fmt.Sprintf("preset %d (%q): %v", i+1, req.Name, err)}
+		}
+		if seen[preset.Name] {
+			return nil, &InvalidPresetError{Message: fmt.Sprintf("preset %q appears more than once", preset.Name)}
+		}
+		seen[preset.Name] = true
+		presets = append(presets, preset)
+	}
+
+	result := &models.PresetImportResult{Created: []string{}, Replaced: []string{}, Skipped: []string{}}
+	for _, preset := range presets {
+		current, err := s.db.GetPreset(preset.Name)
+		switch {
+		case errors.Is(err, db.ErrPresetNotFound):
+			if err := s.db.CreatePreset(preset); err != nil {
+				return result, err
+			}
+			result.Created = append(result.Created, preset.Name)
+		case err != nil:
+			return result, err
+		case existing == models.PresetImportReplace:
+			preset.CreatedAt = current.CreatedAt
+			if err := s.db.UpdatePreset(preset); err != nil {
+				return result, err
+			}
+			result.Replaced = append(result.Replaced, preset.Name)
+		default:
+			result.Skipped = append(result.Skipped, preset.Name)
+		}
+	}
+	return result, nil
+}
+
+// DecodePresetPack parses a YAML preset pack. JSON, being YAML, parses too.
+// Unknown fields are rejected, so a misspelled one isn't silently dropped.
+func DecodePresetPack(data []byte) (*models.PresetPack, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var pack models.PresetPack
+	if err := decoder.Decode(&pack); err != nil {
+		return nil, &InvalidPresetError{Message: fmt.Sprintf("invalid preset pack: %v", err)}
+	}
+	return &pack, nil
+}
+
+// EncodePresetPack writes a preset pack as YAML.
+func EncodePresetPack(pack *models.PresetPack) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(pack); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DeletePreset removes a preset. ISOs created from it are unaffected.
+func (s *PresetService) DeletePreset(name string) error {
+	return s.db.DeletePreset(name)
+}
+
+// ExpandPreset fills the placeholders of a preset from req and returns the
+// ISO request it describes. Empty arch and edition fall back to the preset's.
+func ExpandPreset(preset *models.Preset, req models.ApplyPresetRequest) (*CreateISORequest, error) {
+	version := strings.TrimSpace(req.Version)
+	arch := strings.TrimSpace(req.Arch)
+	if arch == "" {
+		arch = preset.Arch
+	}
+	edition := strings.TrimSpace(req.Edition)
+	if edition == "" {
+		edition = preset.Edition
+	}
+	if version == "" {
+		return nil, &InvalidPresetError{Message: "version is required"}
+	}
+	if arch == "" {
+		return nil, &InvalidPresetError{Message: fmt.Sprintf("preset %q has no default arch, so arch is required", preset.Name)}
+	}
+
+	major, _, _ := strings.Cut(version, ".")
+	replacer := strings.NewReplacer(
+		models.PresetPlaceholderVersion, version,
+		models.PresetPlaceholderMajor, major,
+		models.PresetPlaceholderArch, arch,
+		models.PresetPlaceholderEdition, edition,
+	)
+	return &CreateISORequest{
+		Name:         preset.ISOName,
+		Version:      version,
+		Arch:         arch,
+		Edition:      edition,
+		DownloadURL:  replacer.Replace(preset.DownloadURL),
+		ChecksumURL:  replacer.Replace(preset.ChecksumURL),
+		ChecksumType: preset.ChecksumType,
+		IPFamily:     req.IPFamily,
+		Credential:   req.Credential,
+		Preset:       preset.Name,
+	}, nil
+}
+
+// newPreset builds a validated preset from req, stamped with now.
+func newPreset(req models.CreatePresetRequest, now time.Time) (*models.Preset, error) {
+	if !models.IsValidPresetName(req.Name) {
+		return nil, &InvalidPresetError{Message: "name must be 1-64 lowercase letters, digits, '.', '_' or '-'"}
+	}
+
+	preset := &models.Preset{
+		Name:         req.Name,
+		ISOName:      strings.TrimSpace(req.ISOName),
+		Arch:         strings.TrimSpace(req.Arch),
+		Edition:      strings.TrimSpace(req.Edition),
+		DownloadURL:  strings.TrimSpace(req.DownloadURL),
+		ChecksumURL:  strings.TrimSpace(req.ChecksumURL),
+		ChecksumType: strings.ToLower(strings.TrimSpace(req.ChecksumType)),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := validatePreset(preset); err != nil {
+		return nil, err
+	}
+	return preset, nil
+}
+
+// validatePreset checks the required fields, the checksum type, and that
+// the URLs only use known placeholders. The URLs themselves are validated
+// when the preset is applied, once they are complete.
+func validatePreset(preset *models.Preset) error {
+	if preset.ISOName == "" {
+		return &InvalidPresetError{Message: "iso_name is required"}
+	}
+	if preset.DownloadURL == "" {
+		return &InvalidPresetError{Message: "download_url is required"}
+	}
+	if preset.ChecksumType != "" && !constants.IsValidChecksumType(preset.ChecksumType) {
+		return &InvalidPresetError{Message: fmt.Sprintf("checksum_type must be one of: %s", strings.Join(constants.ChecksumTypes, ", "))}
+	}
+	for _, field := range []struct{ name, url string }{{"download_url", preset.DownloadURL}, {"checksum_url", preset.ChecksumURL}} {
+		for _, placeholder := range presetPlaceholderPattern.FindAllString(field.url, -1) {
+			switch placeholder {
+			case models.PresetPlaceholderVersion, models.PresetPlaceholderMajor, models.PresetPlaceholderArch, models.PresetPlaceholderEdition:
+			default:
+				return &InvalidPresetError{Message: fmt.Sprintf("%s has unknown placeholder %s; use {version}, {major}, {arch}, or {edition}", field.name, placeholder)}
+			}
+		}
+	}
+	return nil
+}
+
+// InvalidPresetError indicates that a preset or a request to apply one is
+// malformed.
+type InvalidPresetError struct {
+	Message string
+}
+
+func (e *InvalidPresetError) Error() string {
+	return e.Message
+}
+
+// PresetExistsError indicates that a preset name is taken.
+type PresetExistsError struct {
+	Name string
+}
+
+func (e *PresetExistsError) Error() string {
+	return fmt.Sprintf("preset %q already exists", e.Name)
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+)
+
+func testPresetRequest() models.CreatePresetRequest {
+	return models.CreatePresetRequest{
+		Name:         "rocky-minimal",
+		ISOName:      "rocky",
+		Arch:         "x86_64",
+		Edition:      "minimal",
+		DownloadURL:  "https://download.rockylinux.org/pub/rocky/{major}/isos/{arch}/Rocky-{version}-{arch}-{edition}.iso",
+		ChecksumURL:  "https://download.rockylinux.org/pub/rocky/{major}/isos/{arch}/CHECKSUM",
+		ChecksumType: "SHA256",
+	}
+}
+
+func TestExpandPreset(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := NewPresetService(env.DB)
+
+	preset, err := svc.CreatePreset(testPresetRequest())
+	if err != nil {
+		t.Fatalf("CreatePreset() failed: %v", err)
+	}
+	if preset.ChecksumType != "sha256" {
+		t.Errorf("Expected the checksum type lowercased, got %q", preset.ChecksumType)
+	}
+	if _, err := svc.CreatePreset(testPresetRequest()); err == nil {
+		t.Error("CreatePreset() should reject a duplicate name")
+	}
+
+	req, err := ExpandPreset(preset, models.ApplyPresetRequest{Version: "9.4"})
+	if err != nil {
+		t.Fatalf("Expand() failed: %v", err)
+	}
+	want := CreateISORequest{
+		Name:         "rocky",
+		Version:      "9.4",
+		Arch:         "x86_64",
+		Edition:      "minimal",
+		DownloadURL:  "https://download.rockylinux.org/pub/rocky/9/isos/x86_64/Rocky-9.4-x86_64-minimal.iso",
+		ChecksumURL:  "https://download.rockylinux.org/pub/rocky/9/isos/x86_64/CHECKSUM",
+		ChecksumType: "sha256",
+		Preset:       "rocky-minimal",
+	}
+	if *req != want {
+		t.Errorf("Expand() = %+v, want %+v", *req, want)
+	}
+
+	req, err = ExpandPreset(preset, models.ApplyPresetRequest{Version: "9.4", Arch: "aarch64", Edition: "dvd"})
+	if err != nil {
+		t.Fatalf("Expand() failed: %v", err)
+	}
+	if req.DownloadURL != "https://download.rockylinux.org/pub/rocky/9/isos/aarch64/Rocky-9.4-aarch64-dvd.iso" {
+		t.Errorf("Expected the request to override the defaults, got %s", req.DownloadURL)
+	}
+
+	preset.Arch = ""
+	var invalidErr *InvalidPresetError
+	if _, err := ExpandPreset(preset, models.ApplyPresetRequest{Version: "9.4"}); !errors.As(err, &invalidErr) {
+		t.Errorf("Expected InvalidPresetError without any arch, got: %v", err)
+	}
+}
+
+func TestPresetService_Validation(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := NewPresetService(env.DB)
+
+	tests := []struct {
+		name   string
+		modify func(*models.CreatePresetRequest)
+	}{
+		{"invalid name", func(r *models.CreatePresetRequest) { r.Name = "Rocky Minimal" }},
+		{"missing iso name", func(r *models.CreatePresetRequest) { r.ISOName = " " }},
+		{"unknown placeholder", func(r *models.CreatePresetRequest) { r.DownloadURL = "https://example.com/{release}.iso" }},
+		{"unknown checksum placeholder", func(r *models.CreatePresetRequest) { r.ChecksumURL = "https://example.com/{}/SUMS" }},
+		{"invalid checksum type", func(r *models.CreatePresetRequest) { r.ChecksumType = "crc32" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := testPresetRequest()
+			tt.modify(&req)
+			var invalidErr *InvalidPresetError
+			if _, err := svc.CreatePreset(req); !errors.As(err, &invalidErr) {
+				t.Errorf("Expected InvalidPresetError, got: %v", err)
+			}
+		})
+	}
+
+	if _, err := svc.CreatePreset(testPresetRequest()); err != nil {
+		t.Fatalf("CreatePreset() failed: %v", err)
+	}
+	bad := "https://example.com/{build}.iso"
+	var invalidErr *InvalidPresetError
+	if _, err := svc.UpdatePreset("rocky-minimal", models.UpdatePresetRequest{DownloadURL: &bad}); !errors.As(err, &invalidErr) {
+		t.Errorf("Expected UpdatePreset() to validate, got: %v", err)
+	}
+}
+
+func TestISOService_VersionBumpRequest(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := NewISOService(env.DB, nil, env.ISODir)
+
+	if _, err := NewPresetService(env.DB).CreatePreset(testPresetRequest()); err != nil {
+		t.Fatalf("CreatePreset() failed: %v", err)
+	}
+	fromPreset := testutil.CreateTestISO(&testutil.TestISO{
+		Name:        "rocky",
+		Version:     "9.3",
+		Arch:        "aarch64",
+		Edition:     "dvd",
+		DownloadURL: "https://download.rockylinux.org/pub/rocky/9/isos/aarch64/Rocky-9.3-aarch64-dvd.iso",
+	})
+	fromPreset.Preset = "rocky-minimal"
+	if err := env.DB.CreateISO(fromPreset); err != nil {
+		t.Fatalf("CreateISO() failed: %v", err)
+	}
+	stored, err := env.DB.GetISO(fromPreset.ID)
+	if err != nil || stored.Preset != "rocky-minimal" {
+		t.Fatalf("Expected the preset to round-trip, got %+v, %v", stored, err)
+	}
+
+	req, err := svc.VersionBumpRequest(stored, "9.4")
+	if err != nil {
+		t.Fatalf("VersionBumpRequest() failed: %v", err)
+	}
+	if req.DownloadURL != "https://download.rockylinux.org/pub/rocky/9/isos/aarch64/Rocky-9.4-aarch64-dvd.iso" || req.Preset != "rocky-minimal" {
+		t.Errorf("Expected the preset expanded with the ISO's arch and edition, got %+v", req)
+	}
+
+	// Without its preset, the ISO is cloned with the version substituted
+	if err := env.DB.DeletePreset("rocky-minimal"); err != nil {
+		t.Fatalf("DeletePreset() failed: %v", err)
+	}
+	req, err = svc.VersionBumpRequest(stored, "9.4")
+	if err != nil {
+		t.Fatalf("VersionBumpRequest() failed: %v", err)
+	}
+	if req.DownloadURL != "https://download.rockylinux.org/pub/rocky/9/isos/aarch64/Rocky-9.4-aarch64-dvd.iso" || req.ChecksumURL != stored.ChecksumURL || req.Preset != "" {
+		t.Errorf("Expected a clone with the new version, got %+v", req)
+	}
+}
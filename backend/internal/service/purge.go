@@ -0,0 +1,128 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// Purger asks a CDN or caching proxy to drop its copies of /images/ paths
+// whose files were replaced or deleted. Purges are sent in the background;
+// a failed one is logged, since a stale cache entry isn't worth failing the
+// change that caused it.
+type Purger struct {
+	db     *db.DB
+	client *http.Client
+	url    string
+	token  string   // Sent as a bearer token when set
+	exts   []string // Sidecar extensions purged with each file
+
+	wg sync.WaitGroup
+}
+
+// NewPurger creates a purger that POSTs to url.
+func NewPurger(database *db.DB, url, token string) *Purger {
+	return &Purger{
+		db:     database,
+		client: &http.Client{Timeout: constants.CDNPurgeTimeoutSec * time.Second},
+		url:    url,
+		token:  token,
+		exts:   constants.SidecarExtensions,
+	}
+}
+
+// SetSidecarExtensions adds extra to the checksum and signature extensions
+// purged with each file.
+func (p *Purger) SetSidecarExtensions(extra []string) {
+	p.exts = sidecarExtensions(extra)
+}
+
+// PurgeISOFile purges the file at an ISO's relative file path, its sidecar
+// files, and the directory listings above it. A nil Purger does nothing, so
+// callers needn't check whether purging is configured.
+func (p *Purger) PurgeISOFile(event, filePath string) {
+	if p == nil {
+		return
+	}
+	req := models.PurgeRequest{Event: event, Paths: purgePaths(filePath, p.exts)}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		if err := p.send(req); err != nil {
+			slog.Warn("failed to purge CDN cache", slog.String("event", event), slog.String("path", req.Paths[0]), slog.Any("error", err))
+		}
+	}()
+}
+
+// PurgeCompleted purges the file of an ISO whose download just completed,
+// replacing any copy or cached 404 from before.
+func (p *Purger) PurgeCompleted(isoID string) {
+	if p == nil {
+		return
+	}
+	iso, err := p.db.GetISO(isoID)
+	if err != nil {
+		slog.Warn("failed to load completed ISO for CDN purge", slog.String("iso_id", isoID), slog.Any("error", err))
+		return
+	}
+	p.PurgeISOFile(models.PurgeEventReplaced, iso.FilePath)
+}
+
+// Wait blocks until the purges sent so far have finished, e.g. at shutdown.
+func (p *Purger) Wait() {
+	if p == nil {
+		return
+	}
+	p.wg.Wait()
+}
+
+func (p *Purger) send(purge models.PurgeRequest) error {
+	body, err := json.Marshal(purge)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("purge URL returned %s", resp.Status)
+	}
+	slog.Debug("CDN cache purged", slog.String("event", purge.Event), slog.Int("paths", len(purge.Paths)))
+	return nil
+}
+
+// purgePaths lists the URL paths a change to filePath makes stale: the file,
+// its sidecar files, and every listing from its directory up to /images/.
+func purgePaths(filePath string, exts []string) []string {
+	link := GenerateDownloadLink(filePath)
+	paths := []string{link}
+	for _, ext := range exts {
+		paths = append(paths, link+ext)
+	}
+	for dir := path.Dir(link); strings.HasPrefix(dir, "/images"); dir = path.Dir(dir) {
+		paths = append(paths, dir+"/")
+	}
+	return paths
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+)
+
+func TestPurger(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	var mu sync.Mutex
+	var got []models.PurgeRequest
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Unexpected %s request with %q", r.Method, r.Header.Get("Authorization"))
+		}
+		var req models.PurgeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode purge request: %v", err)
+		}
+		mu.Lock()
+		got = append(got, req)
+		mu.Unlock()
+	}))
+	defer cdn.Close()
+
+	purger := NewPurger(env.DB, cdn.URL, "token")
+	purger.SetSidecarExtensions([]string{".zsync"})
+	iso := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Status: models.StatusComplete})
+	purger.PurgeCompleted(iso.ID)
+	purger.Wait()
+
+	if len(got) != 1 || got[0].Event != models.PurgeEventReplaced {
+		t.Fatalf("Expected one file.replaced purge, got %+v", got)
+	}
+	link := GenerateDownloadLink(iso.FilePath)
+	for _, want := range []string{link, link + ".sha256", link + ".zsync", "/images/" + filepath.ToSlash(filepath.Dir(iso.FilePath)) + "/", "/images/"} {
+		if !slices.Contains(got[0].Paths, want) {
+			t.Errorf("Expected %s purged, got %v", want, got[0].Paths)
+		}
+	}
+
+	// Without a purge URL nothing is sent
+	var none *Purger
+	none.PurgeISOFile(models.PurgeEventDeleted, iso.FilePath)
+	none.Wait()
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/aloks98/isoman/backend/internal/fileutil"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/pathutil"
+)
+
+// ReconcilePaths recomputes the derived fields of every ISO with
+// ComputeFields and fixes those that drifted from their metadata, e.g. after
+// a restore or a manual database edit. Files and sidecars are moved to the
+// new path; sidecars left behind by an earlier partial move follow the file.
+// ISOs with a download in flight, whose new identity collides with another
+// ISO, or with files at both paths are skipped.
+func (s *ISOService) ReconcilePaths(dryRun bool) (*models.ReconcileResult, error) {
+	isos, err := s.db.ListISOs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ISOs: %w", err)
+	}
+
+	result := &models.ReconcileResult{
+		Checked: len(isos),
+		Fixed:   make([]models.ReconcileChange, 0),
+		Skipped: make([]models.ReconcileSkipped, 0),
+		DryRun:  dryRun,
+	}
+	for i := range isos {
+		change, reason := s.reconcileISO(&isos[i], dryRun)
+		switch {
+		case reason != "":
+			result.Skipped = append(result.Skipped, models.ReconcileSkipped{ISOID: isos[i].ID, Reason: reason})
+		case change != nil:
+			result.Fixed = append(result.Fixed, *change)
+		}
+	}
+
+	slog.Info("path reconciliation finished",
+		slog.Int("checked", result.Checked),
+		slog.Int("fixed", len(result.Fixed)),
+		slog.Int("skipped", len(result.Skipped)),
+		slog.Bool("dry_run", dryRun),
+	)
+	return result, nil
+}
+
+// reconcileISO fixes one ISO, returning the change made, nil if it was
+// consistent, or the reason it was skipped.
+func (s *ISOService) reconcileISO(iso *models.ISO, dryRun bool) (*models.ReconcileChange, string) {
+	want := *iso
+	want.ComputeFields()
+
+	var fields []string
+	if want.Name != iso.Name {
+		fields = append(fields, "name")
+	}
+	if want.Filename != iso.Filename {
+		fields = append(fields, "filename")
+	}
+	if want.FilePath != iso.FilePath {
+		fields = append(fields, "file_path")
+	}
+	if want.DownloadLink != iso.DownloadLink {
+		fields = append(fields, "download_link")
+	}
+	if len(fields) == 0 {
+		return nil, ""
+	}
+
+	if iso.Status.IsActive() {
+		return nil, "download in progress"
+	}
+	if err := s.checkUpdateConflict(&want); err != nil {
+		var existsErr *ISOAlreadyExistsError
+		if errors.As(err, &existsErr) {
+			return nil, fmt.Sprintf("recomputed fields collide with ISO %s", existsErr.ExistingISO.ID)
+		}
+		return nil, err.Error()
+	}
+
+	change := &models.ReconcileChange{
+		ISOID:       iso.ID,
+		Fields:      fields,
+		OldFilePath: iso.FilePath,
+		NewFilePath: want.FilePath,
+	}
+
+	// Either the whole set moves, or the file already moved and only the
+	// sidecars left at the old path follow it
+	oldAbsPath := pathutil.ConstructISOPath(s.isoDir, iso.FilePath)
+	newAbsPath := pathutil.ConstructISOPath(s.isoDir, want.FilePath)
+	var move bool
+	var strays []string
+	if want.FilePath != iso.FilePath {
+		oldExists := fileutil.FileExists(oldAbsPath)
+		newExists := fileutil.FileExists(newAbsPath)
+		switch {
+		case oldExists && newExists:
+			return nil, "files exist at both the old and new path"
+		case oldExists:
+			move = true
+		case newExists:
+			strays = s.straySidecars(oldAbsPath, newAbsPath)
+		case iso.Status == models.StatusComplete:
+			change.FileMissing = true
+		}
+		change.FileMoved = move || len(strays) > 0
+	}
+	if dryRun {
+		return change, ""
+	}
+
+	if move {
+		if err := s.moveISOFiles(iso.FilePath, want.FilePath); err != nil {
+			return nil, fmt.Sprintf("failed to move files: %v", err)
+		}
+	}
+	for _, ext := range strays {
+		if err := fileutil.MoveFile(oldAbsPath+ext, newAbsPath+ext); err != nil {
+			slog.Warn("failed to move sidecar file", slog.String("iso_id", iso.ID), slog.String("ext", ext), slog.Any("error", err))
+		}
+	}
+	if len(strays) > 0 {
+		fileutil.CleanupEmptyParentDirs(oldAbsPath, s.isoDir)
+	}
+
+	if err := s.db.UpdateISO(&want); err != nil {
+		if move {
+			if moveErr := s.moveISOFiles(want.FilePath, iso.FilePath); moveErr != nil {
+				slog.Error("failed to move files back after reconcile failed", slog.String("iso_id", iso.ID), slog.Any("error", moveErr))
+			}
+		}
+		return nil, fmt.Sprintf("failed to update ISO: %v", err)
+	}
+	if change.FileMoved {
+		s.purger.PurgeISOFile(models.PurgeEventDeleted, iso.FilePath)
+		s.purger.PurgeISOFile(models.PurgeEventReplaced, want.FilePath)
+	}
+	return change, ""
+}
+
+// straySidecars returns the sidecar extensions present beside oldPath but not
+// beside newPath.
+func (s *ISOService) straySidecars(oldPath, newPath string) []string {
+	var strays []string
+	for _, ext := range s.sidecars {
+		if fileutil.FileExists(oldPath+ext) && !fileutil.FileExists(newPath+ext) {
+			strays = append(strays, ext)
+		}
+	}
+	return strays
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/fileutil"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestISOService_ReconcilePaths(t *testing.T) {
+	// createDrifted stores a complete ISO for alpine 3.19.1, then changes its
+	// version to 3.20.0 without recomputing the derived fields, as a manual
+	// database edit would
+	createDrifted := func(t *testing.T, service *ISOService, edition string, status models.ISOStatus) *models.ISO {
+		t.Helper()
+		iso := &models.ISO{
+			ID:          uuid.New().String(),
+			Name:        "alpine",
+			Version:     "3.19.1",
+			Arch:        "x86_64",
+			Edition:     edition,
+			FileType:    "iso",
+			DownloadURL: "https://example.com/alpine.iso",
+			Status:      status,
+			CreatedAt:   time.Now(),
+		}
+		iso.ComputeFields()
+		if err := service.db.CreateISO(iso); err != nil {
+			t.Fatalf("CreateISO() failed: %v", err)
+		}
+		iso.Version = "3.20.0"
+		if err := service.db.UpdateISO(iso); err != nil {
+			t.Fatalf("UpdateISO() failed: %v", err)
+		}
+		return iso
+	}
+	const newPath = "alpine/3.20.0/x86_64/alpine-3.20.0-x86_64.iso"
+
+	t.Run("MovesFiles", func(t *testing.T) {
+		service, env := setupTestISOService(t)
+		defer env.Cleanup()
+
+		iso := createDrifted(t, service, "", models.StatusComplete)
+		writeMirrorFile(t, env.ISODir, iso.FilePath, "alpine")
+		writeMirrorFile(t, env.ISODir, iso.FilePath+".sha256", "abc")
+
+		result, err := service.ReconcilePaths(true)
+		if err != nil {
+			t.Fatalf("ReconcilePaths() failed: %v", err)
+		}
+		if result.Checked != 1 || len(result.Fixed) != 1 || !result.DryRun {
+			t.Fatalf("Expected one fix on a dry run, got %+v", result)
+		}
+		change := result.Fixed[0]
+		if change.NewFilePath != newPath || !change.FileMoved || change.FileMissing || len(change.Fields) != 3 {
+			t.Errorf("Unexpected change: %+v", change)
+		}
+		if !fileutil.FileExists(filepath.Join(env.ISODir, iso.FilePath)) {
+			t.Fatal("A dry run should leave the file in place")
+		}
+
+		if _, err := service.ReconcilePaths(false); err != nil {
+			t.Fatalf("ReconcilePaths() failed: %v", err)
+		}
+		for _, rel := range []string{newPath, newPath + ".sha256"} {
+			if !fileutil.FileExists(filepath.Join(env.ISODir, rel)) {
+				t.Errorf("Expected %s to be moved into place", rel)
+			}
+		}
+		if fileutil.FileExists(filepath.Join(env.ISODir, "alpine", "3.19.1")) {
+			t.Error("Expected the emptied old directory to be removed")
+		}
+		stored, _ := service.db.GetISO(iso.ID)
+		if stored.FilePath != newPath || stored.DownloadLink != "/images/"+newPath {
+			t.Errorf("Expected the stored fields to be recomputed, got %s and %s", stored.FilePath, stored.DownloadLink)
+		}
+
+		// A consistent catalog has nothing left to fix
+		result, _ = service.ReconcilePaths(false)
+		if len(result.Fixed) != 0 || len(result.Skipped) != 0 {
+			t.Errorf("Expected nothing to fix, got %+v", result)
+		}
+	})
+
+	t.Run("StraySidecars", func(t *testing.T) {
+		service, env := setupTestISOService(t)
+		defer env.Cleanup()
+
+		// The file was moved by hand, its checksum was not
+		iso := createDrifted(t, service, "", models.StatusComplete)
+		writeMirrorFile(t, env.ISODir, newPath, "alpine")
+		writeMirrorFile(t, env.ISODir, iso.FilePath+".sha256", "abc")
+
+		result, err := service.ReconcilePaths(false)
+		if err != nil {
+			t.Fatalf("ReconcilePaths() failed: %v", err)
+		}
+		if len(result.Fixed) != 1 || !result.Fixed[0].FileMoved {
+			t.Fatalf("Expected the sidecar to be moved, got %+v", result)
+		}
+		if !fileutil.FileExists(filepath.Join(env.ISODir, newPath+".sha256")) {
+			t.Error("Expected the sidecar beside the file")
+		}
+	})
+
+	t.Run("SkipsAndMissing", func(t *testing.T) {
+		service, env := setupTestISOService(t)
+		defer env.Cleanup()
+
+		missing := createDrifted(t, service, "", models.StatusComplete)
+		queued := createDrifted(t, service, "standard", models.StatusQueued)
+
+		result, err := service.ReconcilePaths(false)
+		if err != nil {
+			t.Fatalf("ReconcilePaths() failed: %v", err)
+		}
+		if len(result.Fixed) != 1 || result.Fixed[0].ISOID != missing.ID || !result.Fixed[0].FileMissing || result.Fixed[0].FileMoved {
+			t.Errorf("Expected the missing file to be reported, got %+v", result.Fixed)
+		}
+		if len(result.Skipped) != 1 || result.Skipped[0].ISOID != queued.ID || result.Skipped[0].Reason != "download in progress" {
+			t.Errorf("Expected the queued ISO to be skipped, got %+v", result.Skipped)
+		}
+	})
+}
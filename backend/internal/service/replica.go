@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/clock"
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/fileutil"
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// ReplicaService keeps a read-only replica's catalog in step with its
+// primary. The primary's files are copied into the ISO directory by rsync or
+// similar; each sync fetches the primary's manifest and lists the ISOs whose
+// files have arrived, under the primary's IDs.
+type ReplicaService struct {
+	db         *db.DB
+	clock      clock.Clock
+	client     *http.Client
+	primaryURL string
+	token      string // Sent as a bearer token when the primary requires sign-in
+	isoDir     string
+
+	mu     sync.Mutex
+	status models.ReplicaStatus
+}
+
+// NewReplicaService creates a replica of the instance at primaryURL.
+func NewReplicaService(database *db.DB, isoDir, primaryURL, token string) *ReplicaService {
+	primaryURL = strings.TrimRight(primaryURL, "/")
+	return &ReplicaService{
+		db:         database,
+		clock:      clock.Real(),
+		client:     &http.Client{Timeout: constants.ReplicaFetchTimeoutSec * time.Second},
+		primaryURL: primaryURL,
+		token:      token,
+		isoDir:     isoDir,
+		status:     models.ReplicaStatus{Enabled: true, PrimaryURL: primaryURL},
+	}
+}
+
+// Start syncs once straight away and then once per interval until ctx is
+// canceled. A zero interval syncs only at startup.
+func (s *ReplicaService) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		for {
+			if err := s.Sync(ctx); err != nil && ctx.Err() == nil {
+				slog.Warn("replica sync failed", slog.String("primary", s.primaryURL), slog.Any("error", err))
+			}
+			if interval <= 0 {
+				return
+			}
+
+			timer := s.clock.NewTimer(interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C():
+			}
+		}
+	}()
+}
+
+// Status returns the result of the last sync.
+func (s *ReplicaService) Status() models.ReplicaStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// Sync fetches the primary's manifest and reconciles the catalog with it.
+// Primary ISOs whose file is present with the right size are listed as
+// complete; the rest wait for the next sync. ISOs the primary no longer has
+// are removed from the catalog, leaving their files to rsync.
+func (s *ReplicaService) Sync(ctx context.Context) error {
+	manifest, err := s.fetchManifest(ctx)
+	if err == nil {
+		err = s.reconcile(manifest)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.status.LastError = err.Error()
+		return err
+	}
+	now := s.clock.Now()
+	s.status.LastSyncedAt = &now
+	s.status.LastError = ""
+	return nil
+}
+
+// fetchManifest downloads GET /api/manifest from the primary.
+func (s *ReplicaService) fetchManifest(ctx context.Context) (*models.Manifest, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.primaryURL+"/api/manifest", http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("primary returned %s for the manifest", resp.Status)
+	}
+
+	var body struct {
+		Data *models.Manifest `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	if body.Data == nil || body.Data.Version != models.ManifestFormatVersion {
+		return nil, fmt.Errorf("unsupported manifest version (expected %d)", models.ManifestFormatVersion)
+	}
+	return body.Data, nil
+}
+
+// reconcile lists the manifest's ISOs whose files are present and removes
+// catalog entries the primary no longer has.
+func (s *ReplicaService) reconcile(manifest *models.Manifest) error {
+	local, err := s.db.ListISOs()
+	if err != nil {
+		return fmt.Errorf("failed to list ISOs: %w", err)
+	}
+	existing := make(map[string]bool, len(local))
+	for _, iso := range local {
+		existing[iso.ID] = true
+	}
+
+	listed, waiting := 0, 0
+	primary := make(map[string]bool, len(manifest.Entries))
+	for _, entry := range manifest.Entries {
+		if entry.ISO == nil {
+			continue // Sidecar files are served as they are
+		}
+		primary[entry.ISO.ID] = true
+
+		iso, ok := s.replicaISO(entry)
+		if !ok {
+			waiting++
+			continue
+		}
+		if existing[iso.ID] {
+			err = s.db.UpdateISO(iso)
+			if err == nil {
+				err = s.db.UpdateISOPinned(iso.ID, iso.Pinned)
+			}
+		} else {
+			err = s.db.CreateISO(iso)
+		}
+		if err != nil {
+			// e.g. a local ISO with the same name under another ID
+			slog.Warn("failed to sync replica ISO", slog.String("iso_id", iso.ID), slog.Any("error", err))
+			waiting++
+			continue
+		}
+		listed++
+	}
+
+	for _, iso := range local {
+		if primary[iso.ID] {
+			continue
+		}
+		if err := s.db.DeleteISO(iso.ID); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", iso.ID, err)
+		}
+		slog.Info("removed ISO the primary no longer has", slog.String("iso_id", iso.ID), slog.String("filename", iso.Filename))
+	}
+
+	s.mu.Lock()
+	s.status.ISOs = listed
+	s.status.Waiting = waiting
+	s.mu.Unlock()
+	return nil
+}
+
+// replicaISO returns the catalog record for a manifest entry, or false while
+// its file is missing or still being copied.
+func (s *ReplicaService) replicaISO(entry models.ManifestEntry) (*models.ISO, bool) {
+	clean := path.Clean(entry.Path)
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return nil, false
+	}
+	fi, err := os.Stat(filepath.Join(s.isoDir, filepath.FromSlash(clean)))
+	if err != nil || fi.Size() != entry.SizeBytes {
+		return nil, false
+	}
+
+	iso := *entry.ISO
+	iso.FilePath = filepath.FromSlash(clean)
+	iso.Status = models.StatusComplete
+	iso.Progress = 100
+	iso.SizeBytes = entry.SizeBytes
+	iso.ErrorMessage = ""
+	iso.ErrorReason = models.ErrorReasonNone
+	iso.DownloadCount, iso.BytesServed = 0, 0 // Each instance counts what it serves
+	iso.FileInode = fileutil.FileInode(fi)
+	modTime := fi.ModTime()
+	iso.FileModTime = &modTime
+	return &iso, true
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+)
+
+func TestReplicaService_Sync(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	// Two ISOs on the primary, of which only the first has been rsynced
+	synced := testutil.CreateTestISO(&testutil.TestISO{Status: models.StatusComplete})
+	pending := testutil.CreateTestISO(&testutil.TestISO{Version: "3.20.0", Status: models.StatusComplete})
+	manifest := &models.Manifest{Version: models.ManifestFormatVersion}
+	for _, iso := range []*models.ISO{synced, pending} {
+		iso.DownloadCount = 42 // The primary's count isn't copied
+		manifest.Entries = append(manifest.Entries, models.ManifestEntry{
+			ISO: iso, Path: filepath.ToSlash(iso.FilePath), SizeBytes: 4, SHA256: "unused",
+		})
+	}
+	writeFile := func(iso *models.ISO) {
+		t.Helper()
+		p := filepath.Join(env.ISODir, iso.FilePath)
+		os.MkdirAll(filepath.Dir(p), 0o755)
+		if err := os.WriteFile(p, []byte("data"), 0o644); err != nil {
+			t.Fatalf("WriteFile() failed: %v", err)
+		}
+	}
+	writeFile(synced)
+
+	var fail atomic.Bool
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/manifest" || r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Unexpected request %s with %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		if fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"success": true, "data": manifest})
+	}))
+	defer primary.Close()
+
+	// A local ISO the primary doesn't have is dropped from the catalog
+	stale := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Name: "stale", Status: models.StatusComplete})
+
+	replica := NewReplicaService(env.DB, env.ISODir, primary.URL+"/", "token")
+	if err := replica.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync() failed: %v", err)
+	}
+	status := replica.Status()
+	if !status.Enabled || status.ISOs != 1 || status.Waiting != 1 || status.LastSyncedAt == nil || status.PrimaryURL != primary.URL {
+		t.Errorf("Unexpected status after the first sync: %+v", status)
+	}
+	got, err := env.DB.GetISO(synced.ID)
+	if err != nil {
+		t.Fatalf("Expected the synced ISO under the primary's ID: %v", err)
+	}
+	if got.Status != models.StatusComplete || got.SizeBytes != 4 || got.DownloadCount != 0 {
+		t.Errorf("Unexpected synced ISO: status %s, size %d, downloads %d", got.Status, got.SizeBytes, got.DownloadCount)
+	}
+	if _, err := env.DB.GetISO(pending.ID); err == nil {
+		t.Error("Expected the ISO without a file to wait")
+	}
+	if _, err := env.DB.GetISO(stale.ID); err == nil {
+		t.Error("Expected the stale ISO removed")
+	}
+
+	// Once its file arrives, the next sync lists it too
+	writeFile(pending)
+	if err := replica.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync() failed: %v", err)
+	}
+	if status := replica.Status(); status.ISOs != 2 || status.Waiting != 0 {
+		t.Errorf("Expected both ISOs listed, got %+v", status)
+	}
+
+	// A failed sync degrades the public status
+	fail.Store(true)
+	if err := replica.Sync(context.Background()); err == nil {
+		t.Fatal("Expected Sync() to fail")
+	}
+	stats := NewStatsService(env.DB)
+	stats.SetReplica(replica)
+	instance, err := stats.Status()
+	if err != nil {
+		t.Fatalf("Status() failed: %v", err)
+	}
+	if instance.Status != models.InstanceStatusDegraded || len(instance.Problems) != 1 || !strings.Contains(instance.Problems[0], "replica sync failed") {
+		t.Errorf("Expected a degraded status, got %+v", instance)
+	}
+}
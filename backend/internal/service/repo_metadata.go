@@ -0,0 +1,157 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/repometa"
+)
+
+// RepoMetadataService publishes signed metadata for the whole mirror:
+// root.json naming the repository key, targets.json listing every complete
+// ISO with its length and hashes, and a SHA256SUMS file with a detached
+// signature. Target paths are relative to /images/.
+type RepoMetadataService struct {
+	db            *db.DB
+	signer        *repometa.Signer
+	targetsExpiry time.Duration
+
+	mu      sync.Mutex
+	root    []byte
+	targets []byte
+	sums    []byte
+	sumsSig []byte
+	digest  string    // Of the target list the cached files were built from
+	version int64     // Of the cached targets.json
+	renewAt time.Time // When the cached targets.json is re-signed to push its expiry out
+}
+
+// NewRepoMetadataService signs root.json once, valid for rootExpiry, and
+// signs targets.json on demand, each version valid for targetsExpiry.
+func NewRepoMetadataService(database *db.DB, signer *repometa.Signer, rootExpiry, targetsExpiry time.Duration) (*RepoMetadataService, error) {
+	if rootExpiry <= 0 {
+		rootExpiry = constants.DefaultRepoRootExpiryDays * 24 * time.Hour
+	}
+	if targetsExpiry <= 0 {
+		targetsExpiry = constants.DefaultRepoTargetsExpiryDays * 24 * time.Hour
+	}
+	root, err := signer.Sign(signer.Root(1, time.Now().Add(rootExpiry)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign root metadata: %w", err)
+	}
+	return &RepoMetadataService{
+		db:            database,
+		signer:        signer,
+		targetsExpiry: targetsExpiry,
+		root:          root,
+	}, nil
+}
+
+// Root returns the signed root.json.
+func (s *RepoMetadataService) Root() []byte {
+	return s.root
+}
+
+// PublicKeyPEM returns the repository's public key, for checking SHA256SUMS.sig.
+func (s *RepoMetadataService) PublicKeyPEM() ([]byte, error) {
+	return s.signer.PublicKeyPEM()
+}
+
+// Targets returns the signed targets.json for the current catalog.
+func (s *RepoMetadataService) Targets() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	return s.targets, nil
+}
+
+// Checksums returns SHA256SUMS for the current catalog and its raw Ed25519
+// signature.
+func (s *RepoMetadataService) Checksums() (sums, sig []byte, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return nil, nil, err
+	}
+	return s.sums, s.sumsSig, nil
+}
+
+// refresh re-signs the cached files when the catalog changed or targets.json
+// is past half its validity. Versions are Unix times, so they keep
+// increasing across restarts. Callers hold s.mu.
+func (s *RepoMetadataService) refresh() error {
+	targets, err := s.listTargets()
+	if err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(targets))
+	for path := range targets {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var sums bytes.Buffer
+	for _, path := range paths {
+		fmt.Fprintf(&sums, "%s  %s\n", targets[path].Hashes["sha256"], path)
+	}
+	digest := sha256.Sum256(sums.Bytes())
+
+	now := time.Now()
+	if s.targets != nil && hex.EncodeToString(digest[:]) == s.digest && now.Before(s.renewAt) {
+		return nil
+	}
+
+	version := max(now.Unix(), s.version+1)
+	signed, err := s.signer.Sign(&repometa.Targets{
+		Type:        repometa.RoleTargets,
+		SpecVersion: repometa.SpecVersion,
+		Version:     version,
+		Expires:     repometa.FormatExpires(now.Add(s.targetsExpiry)),
+		Targets:     targets,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to sign targets metadata: %w", err)
+	}
+
+	s.targets = signed
+	s.sums = sums.Bytes()
+	s.sumsSig = s.signer.SignBytes(s.sums)
+	s.digest = hex.EncodeToString(digest[:])
+	s.version = version
+	s.renewAt = now.Add(s.targetsExpiry / 2)
+	return nil
+}
+
+// listTargets returns the complete ISOs as targets. ISOs without a SHA-256,
+// e.g. adopted files not yet hashed, are left out.
+func (s *RepoMetadataService) listTargets() (map[string]repometa.TargetFile, error) {
+	isos, err := s.db.ListISOs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ISOs: %w", err)
+	}
+
+	targets := make(map[string]repometa.TargetFile, len(isos))
+	for i := range isos {
+		iso := &isos[i]
+		if iso.Status != models.StatusComplete || iso.SHA256 == "" {
+			continue
+		}
+		hashes := map[string]string{"sha256": iso.SHA256}
+		if iso.SHA512 != "" {
+			hashes["sha512"] = iso.SHA512
+		}
+		targets[filepath.ToSlash(iso.FilePath)] = repometa.TargetFile{Length: iso.SizeBytes, Hashes: hashes}
+	}
+	return targets, nil
+}
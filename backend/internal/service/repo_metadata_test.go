@@ -0,0 +1,92 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/repometa"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+)
+
+func TestRepoMetadataService(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	signer, err := repometa.NewSigner(key)
+	if err != nil {
+		t.Fatalf("NewSigner() failed: %v", err)
+	}
+	repo, err := NewRepoMetadataService(env.DB, signer, 0, time.Hour)
+	if err != nil {
+		t.Fatalf("NewRepoMetadataService() failed: %v", err)
+	}
+
+	var root repometa.Root
+	if err := repometa.Verify(repo.Root(), signer.PublicKey(), &root); err != nil {
+		t.Fatalf("Root() doesn't verify: %v", err)
+	}
+
+	// Only complete ISOs with a digest are targets
+	complete := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Status: models.StatusComplete})
+	complete.SHA256, complete.SHA512, complete.SizeBytes = strings.Repeat("a", 64), strings.Repeat("b", 128), 4
+	if err := env.DB.UpdateISO(complete); err != nil {
+		t.Fatalf("UpdateISO() failed: %v", err)
+	}
+	testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Version: "3.20.0", Status: models.StatusFailed})
+	testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Version: "3.21.0", Status: models.StatusComplete})
+
+	data, err := repo.Targets()
+	if err != nil {
+		t.Fatalf("Targets() failed: %v", err)
+	}
+	var targets repometa.Targets
+	if err := repometa.Verify(data, signer.PublicKey(), &targets); err != nil {
+		t.Fatalf("Targets() doesn't verify: %v", err)
+	}
+	path := filepath.ToSlash(complete.FilePath)
+	if len(targets.Targets) != 1 || targets.Targets[path].Length != 4 || targets.Targets[path].Hashes["sha512"] != complete.SHA512 {
+		t.Errorf("Expected only %s as a target, got %+v", path, targets.Targets)
+	}
+
+	sums, sig, err := repo.Checksums()
+	if err != nil {
+		t.Fatalf("Checksums() failed: %v", err)
+	}
+	if string(sums) != complete.SHA256+"  "+path+"\n" {
+		t.Errorf("Unexpected SHA256SUMS:\n%s", sums)
+	}
+	pub, err := repo.PublicKeyPEM()
+	if err != nil {
+		t.Fatalf("PublicKeyPEM() failed: %v", err)
+	}
+	block, _ := pem.Decode(pub)
+	public, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil || !ed25519.Verify(public.(ed25519.PublicKey), sums, sig) {
+		t.Errorf("Expected SHA256SUMS.sig to verify with key.pub: %v", err)
+	}
+
+	// An unchanged catalog keeps its version; a change signs a newer one
+	again, _ := repo.Targets()
+	if string(again) != string(data) {
+		t.Error("Expected the cached targets.json for an unchanged catalog")
+	}
+	if err := env.DB.DeleteISO(complete.ID); err != nil {
+		t.Fatalf("DeleteISO() failed: %v", err)
+	}
+	data, _ = repo.Targets()
+	var next repometa.Targets
+	if err := repometa.Verify(data, signer.PublicKey(), &next); err != nil {
+		t.Fatalf("Targets() doesn't verify: %v", err)
+	}
+	if next.Version <= targets.Version || len(next.Targets) != 0 {
+		t.Errorf("Expected a newer, empty version after %d, got %d with %d targets", targets.Version, next.Version, len(next.Targets))
+	}
+}
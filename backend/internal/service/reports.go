@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/clock"
+	"github.com/aloks98/isoman/backend/internal/cron"
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+const (
+	// defaultReportPeriod is covered by the first report, before there is a
+	// previous run to start from.
+	defaultReportPeriod = 7 * 24 * time.Hour
+	// reportTopDownloads is how many ISOs the top downloads section lists.
+	reportTopDownloads = 10
+)
+
+// Mailer delivers a plain-text message. *mail.Sender satisfies it.
+type Mailer interface {
+	Send(to []string, subject, body string) error
+}
+
+// ReportService builds the periodic summary report and emails it.
+type ReportService struct {
+	db         *db.DB
+	mailer     Mailer
+	clock      clock.Clock
+	recipients []string
+}
+
+// NewReportService creates a report service that mails reports to recipients.
+func NewReportService(database *db.DB, mailer Mailer, recipients []string) *ReportService {
+	return &ReportService{
+		db:         database,
+		mailer:     mailer,
+		recipients: recipients,
+		clock:      clock.Real(),
+	}
+}
+
+// Start sends a report every time schedule fires until ctx is canceled.
+func (s *ReportService) Start(ctx context.Context, schedule *cron.Schedule) {
+	go func() {
+		for {
+			next := schedule.Next(s.clock.Now())
+			if next.IsZero() {
+				slog.Warn("report schedule never fires, reports disabled", slog.String("schedule", schedule.String()))
+				return
+			}
+
+			timer := s.clock.NewTimer(s.clock.Until(next))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C():
+				if err := s.Send(); err != nil {
+					slog.Warn("failed to send report", slog.Any("error", err))
+				}
+			}
+		}
+	}()
+}
+
+// Send mails the report covering everything since the previous one, or the
+// last week when none was sent yet, and records the run.
+func (s *ReportService) Send() error {
+	to := s.clock.Now()
+	from := to.Add(-defaultReportPeriod)
+	previous, err := s.db.GetLastReportRun()
+	if err != nil && !errors.Is(err, db.ErrReportRunNotFound) {
+		return err
+	}
+	if previous != nil && previous.PeriodEnd.Before(to) {
+		from = previous.PeriodEnd
+	}
+
+	report, err := s.Generate(from, to)
+	if err != nil {
+		return err
+	}
+	if previous != nil {
+		report.HasPrevious = true
+		report.StorageDelta = report.StorageBytes - previous.StorageBytes
+	}
+
+	subject, body := RenderReport(report)
+	if err := s.mailer.Send(s.recipients, subject, body); err != nil {
+		return fmt.Errorf("failed to mail report: %w", err)
+	}
+	slog.Info("report sent", slog.Int("recipients", len(s.recipients)))
+
+	return s.db.RecordReportRun(&models.ReportRun{
+		PeriodStart:  from,
+		PeriodEnd:    to,
+		StorageBytes: report.StorageBytes,
+		CreatedAt:    s.clock.Now(),
+	})
+}
+
+// Generate builds the report for [from, to). StorageDelta is left for the
+// caller, which knows the previous run.
+func (s *ReportService) Generate(from, to time.Time) (*models.Report, error) {
+	newISOs, err := s.db.ListISOsCreatedBetween(from, to)
+	if err != nil {
+		return nil, err
+	}
+	failed, err := s.db.ListISOsByStatus(models.StatusFailed)
+	if err != nil {
+		return nil, err
+	}
+	top, err := s.db.GetTopDownloadsBetween(from, to, reportTopDownloads)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := s.db.GetStats()
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Report{
+		PeriodStart:  from,
+		PeriodEnd:    to,
+		NewISOs:      newISOs,
+		FailedISOs:   failed,
+		TopDownloads: top,
+		StorageBytes: stats.TotalSizeBytes,
+	}, nil
+}
+
+// RenderReport formats a report as an email subject and plain-text body.
+func RenderReport(report *models.Report) (subject, body string) {
+	const day = "2006-01-02"
+	subject = fmt.Sprintf("ISOMan report %s to %s", report.PeriodStart.Format(day), report.PeriodEnd.Format(day))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Activity from %s to %s.\n", report.PeriodStart.Format(time.RFC1123), report.PeriodEnd.Format(time.RFC1123))
+
+	fmt.Fprintf(&b, "\nNew ISOs (%d)\n", len(report.NewISOs))
+	for _, iso := range report.NewISOs {
+		fmt.Fprintf(&b, "  %s %s %s - %s\n", iso.Name, iso.Version, iso.Arch, iso.Status)
+	}
+
+	fmt.Fprintf(&b, "\nFailed ISOs (%d)\n", len(report.FailedISOs))
+	for _, iso := range report.FailedISOs {
+		fmt.Fprintf(&b, "  %s %s %s - %s\n", iso.Name, iso.Version, iso.Arch, iso.ErrorMessage)
+	}
+
+	fmt.Fprintf(&b, "\nTop downloads\n")
+	if len(report.TopDownloads) == 0 {
+		b.WriteString("  No downloads\n")
+	}
+	for i, stat := range report.TopDownloads {
+		fmt.Fprintf(&b, "  %d. %s %s %s - %d\n", i+1, stat.Name, stat.Version, stat.Arch, stat.DownloadCount)
+	}
+
+	fmt.Fprintf(&b, "\nStorage\n  %s in use", formatBytes(report.StorageBytes))
+	if report.HasPrevious {
+		sign := "+"
+		delta := report.StorageDelta
+		if delta < 0 {
+			sign, delta = "-", -delta
+		}
+		fmt.Fprintf(&b, " (%s%s since the last report)", sign, formatBytes(delta))
+	}
+	b.WriteString("\n")
+
+	return subject, b.String()
+}
+
+// formatBytes converts bytes to a human-readable size.
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
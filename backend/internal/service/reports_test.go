@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/clock"
+	"github.com/aloks98/isoman/backend/internal/cron"
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+)
+
+// fakeMailer records sent messages, or fails every send when err is set.
+type fakeMailer struct {
+	err      error
+	subjects []string
+	bodies   []string
+}
+
+func (m *fakeMailer) Send(to []string, subject, body string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.subjects = append(m.subjects, subject)
+	m.bodies = append(m.bodies, body)
+	return nil
+}
+
+func TestReportService_Send(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	complete := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Status: models.StatusComplete})
+	testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Version: "3.20.0", Status: models.StatusFailed})
+	if err := env.DB.RecordDownloadEvent(complete.ID, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("RecordDownloadEvent() failed: %v", err)
+	}
+
+	mailer := &fakeMailer{}
+	svc := NewReportService(env.DB, mailer, []string{"ops@example.com"})
+	fake := clock.NewFake(time.Now().Add(time.Minute))
+	svc.clock = fake
+
+	if err := svc.Send(); err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if len(mailer.bodies) != 1 {
+		t.Fatalf("Expected one message, got %d", len(mailer.bodies))
+	}
+	body := mailer.bodies[0]
+	for _, want := range []string{"New ISOs (2)", "Failed ISOs (1)", "1. alpine-linux 3.19.1 x86_64 - 1", "1.0 MB in use\n"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Report is missing %q:\n%s", want, body)
+		}
+	}
+
+	// The second report starts where the first ended and compares storage
+	testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Version: "3.21.0", Status: models.StatusComplete})
+	fake.Advance(time.Minute)
+	if err := svc.Send(); err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	body = mailer.bodies[1]
+	for _, want := range []string{"No downloads", "2.0 MB in use (+1.0 MB since the last report)"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Second report is missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestReportService_StartFollowsSchedule(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	schedule, err := cron.Parse("0 8 * * 1")
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	friday := time.Date(2024, 1, 26, 14, 45, 0, 0, time.UTC)
+	monday := time.Date(2024, 1, 29, 8, 0, 0, 0, time.UTC)
+
+	mailer := &fakeMailer{}
+	svc := NewReportService(env.DB, mailer, []string{"ops@example.com"})
+	fake := clock.NewFake(friday)
+	svc.clock = fake
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	svc.Start(ctx, schedule)
+
+	fake.BlockUntil(1)
+	fake.Advance(monday.Sub(friday) - time.Second)
+	if len(mailer.subjects) != 0 {
+		t.Fatalf("Report sent before its schedule: %q", mailer.subjects)
+	}
+
+	// Once sent, the service waits for the following Monday
+	fake.Advance(time.Second)
+	fake.BlockUntil(1)
+	if len(mailer.subjects) != 1 {
+		t.Fatalf("Expected one report on Monday morning, got %d", len(mailer.subjects))
+	}
+	run, err := env.DB.GetLastReportRun()
+	if err != nil {
+		t.Fatalf("GetLastReportRun() failed: %v", err)
+	}
+	if !run.PeriodEnd.Equal(monday) || !run.PeriodStart.Equal(monday.Add(-defaultReportPeriod)) {
+		t.Errorf("Report covers %v to %v, expected the week up to %v", run.PeriodStart, run.PeriodEnd, monday)
+	}
+}
+
+func TestReportService_SendFailureSkipsRun(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	svc := NewReportService(env.DB, &fakeMailer{err: errors.New("relay down")}, []string{"ops@example.com"})
+	if err := svc.Send(); err == nil {
+		t.Fatal("Send() should fail when the mailer does")
+	}
+	if _, err := env.DB.GetLastReportRun(); !errors.Is(err, db.ErrReportRunNotFound) {
+		t.Errorf("Expected no recorded run after a failed send, got: %v", err)
+	}
+}
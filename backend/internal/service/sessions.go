@@ -0,0 +1,124 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// sessionCacheTTL bounds how long Authenticate trusts a cached session.
+// Revocations through the AuthService evict entries at once, so this only
+// matters for sessions removed behind its back.
+const sessionCacheTTL = 30 * time.Second
+
+// maxCachedSessions bounds the session cache; it is simply emptied when full.
+const maxCachedSessions = 4096
+
+// sessionCache holds authenticated sessions keyed by session ID, so the auth
+// middleware doesn't query the database on every request.
+type sessionCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cachedSession
+}
+
+type cachedSession struct {
+	cachedAt time.Time
+	user     *models.User
+	session  *models.Session
+}
+
+func newSessionCache(ttl time.Duration) *sessionCache {
+	return &sessionCache{ttl: ttl, entries: make(map[string]cachedSession)}
+}
+
+// get returns the cached session id while it is fresh and unexpired at now.
+func (sc *sessionCache) get(id string, now time.Time) (*models.User, *models.Session, bool) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	entry, ok := sc.entries[id]
+	if !ok {
+		return nil, nil, false
+	}
+	if now.Sub(entry.cachedAt) > sc.ttl || !now.Before(entry.session.ExpiresAt) {
+		delete(sc.entries, id)
+		return nil, nil, false
+	}
+	return entry.user, entry.session, true
+}
+
+func (sc *sessionCache) put(id string, user *models.User, session *models.Session, now time.Time) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if len(sc.entries) >= maxCachedSessions {
+		sc.entries = make(map[string]cachedSession)
+	}
+	sc.entries[id] = cachedSession{cachedAt: now, user: user, session: session}
+}
+
+func (sc *sessionCache) evict(id string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	delete(sc.entries, id)
+}
+
+// evictUser drops every session of userID, e.g. after the user changed.
+func (sc *sessionCache) evictUser(userID string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	for id, entry := range sc.entries {
+		if entry.user.ID == userID {
+			delete(sc.entries, id)
+		}
+	}
+}
+
+func (sc *sessionCache) clear() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.entries = make(map[string]cachedSession)
+}
+
+// ListSessions returns every active session. The one identified by
+// currentToken, if any, is marked current.
+func (s *AuthService) ListSessions(currentToken string) ([]models.Session, error) {
+	sessions, err := s.db.ListSessions(s.now())
+	if err != nil {
+		return nil, err
+	}
+	if currentToken != "" {
+		currentID := hashSessionToken(currentToken)
+		for i := range sessions {
+			sessions[i].Current = sessions[i].ID == currentID
+		}
+	}
+	return sessions, nil
+}
+
+// RevokeSession ends a session by ID. It stops working immediately.
+func (s *AuthService) RevokeSession(id string) error {
+	if err := s.db.RevokeSession(id); err != nil {
+		return err
+	}
+	s.cache.evict(id)
+	return nil
+}
+
+// RevokeSessions ends every session of userID, or of every user when userID
+// is empty, except the one identified by currentToken. It returns how many
+// sessions were ended.
+func (s *AuthService) RevokeSessions(userID, currentToken string) (int64, error) {
+	exceptID := ""
+	if currentToken != "" {
+		exceptID = hashSessionToken(currentToken)
+	}
+	n, err := s.db.RevokeSessions(userID, exceptID)
+	if err != nil {
+		return 0, err
+	}
+	s.cache.clear()
+	return n, nil
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/clock"
+	"github.com/aloks98/isoman/backend/internal/cron"
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// snapshotMinInterval is how recent a snapshot must be for a scheduled one to
+// be skipped, so instances sharing a database don't each take one.
+const snapshotMinInterval = time.Hour
+
+// SnapshotService takes snapshots of the complete ISOs and compares the
+// catalog against them, for a changelog of the mirror.
+type SnapshotService struct {
+	db        *db.DB
+	clock     clock.Clock
+	retention time.Duration
+}
+
+// NewSnapshotService creates a snapshot service that deletes snapshots older
+// than retention; zero keeps them.
+func NewSnapshotService(database *db.DB, retention time.Duration) *SnapshotService {
+	return &SnapshotService{
+		db:        database,
+		clock:     clock.Real(),
+		retention: retention,
+	}
+}
+
+// Start takes a snapshot now when there is none yet, so changes can be listed
+// from the first day, then one every time schedule fires until ctx is canceled.
+func (s *SnapshotService) Start(ctx context.Context, schedule *cron.Schedule) {
+	if _, err := s.db.GetLatestCatalogSnapshot(); errors.Is(err, db.ErrCatalogSnapshotNotFound) {
+		if _, err := s.Take(); err != nil {
+			slog.Warn("failed to take catalog snapshot", slog.Any("error", err))
+		}
+	}
+
+	go func() {
+		for {
+			next := schedule.Next(s.clock.Now())
+			if next.IsZero() {
+				slog.Warn("catalog snapshot schedule never fires, snapshots disabled", slog.String("schedule", schedule.String()))
+				return
+			}
+
+			timer := s.clock.NewTimer(s.clock.Until(next))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C():
+				if latest, err := s.db.GetLatestCatalogSnapshot(); err == nil && s.clock.Since(latest.TakenAt) < snapshotMinInterval {
+					continue
+				}
+				if _, err := s.Take(); err != nil {
+					slog.Warn("failed to take catalog snapshot", slog.Any("error", err))
+				}
+			}
+		}
+	}()
+}
+
+// Take stores a snapshot of the complete ISOs, then deletes the snapshots
+// past retention.
+func (s *SnapshotService) Take() (*models.CatalogSnapshot, error) {
+	entries, err := s.catalog()
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &models.CatalogSnapshot{TakenAt: s.clock.Now(), Entries: entries}
+	if err := s.db.CreateCatalogSnapshot(snapshot); err != nil {
+		return nil, err
+	}
+	slog.Info("catalog snapshot taken", slog.Int("isos", snapshot.ISOCount))
+
+	if s.retention > 0 {
+		n, err := s.db.DeleteCatalogSnapshotsBefore(snapshot.TakenAt.Add(-s.retention))
+		if err != nil {
+			slog.Warn("failed to delete old catalog snapshots", slog.Any("error", err))
+		} else if n > 0 {
+			slog.Debug("deleted old catalog snapshots", slog.Int64("count", n), slog.Duration("retention", s.retention))
+		}
+	}
+	return snapshot, nil
+}
+
+// Changes compares the catalog against the snapshot taken at or before since,
+// or the earliest one when none is that old. It returns
+// db.ErrCatalogSnapshotNotFound when no snapshot has been taken.
+func (s *SnapshotService) Changes(since time.Time) (*models.CatalogChanges, error) {
+	snapshot, err := s.db.GetCatalogSnapshotAt(since)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.catalog()
+	if err != nil {
+		return nil, err
+	}
+
+	changes := &models.CatalogChanges{
+		Since:   since,
+		From:    snapshot.TakenAt,
+		To:      s.clock.Now(),
+		Added:   make([]models.CatalogEntry, 0),
+		Removed: make([]models.CatalogEntry, 0),
+		Updated: make([]models.CatalogUpdate, 0),
+	}
+	before := make(map[string]models.CatalogEntry, len(snapshot.Entries))
+	for _, entry := range snapshot.Entries {
+		before[entry.ID] = entry
+	}
+	for _, entry := range current {
+		old, ok := before[entry.ID]
+		if !ok {
+			changes.Added = append(changes.Added, entry)
+			continue
+		}
+		delete(before, entry.ID)
+		if fields := changedCatalogFields(old, entry); len(fields) > 0 {
+			changes.Updated = append(changes.Updated, models.CatalogUpdate{Fields: fields, Before: old, After: entry})
+		}
+	}
+	for _, entry := range snapshot.Entries {
+		if _, ok := before[entry.ID]; ok {
+			changes.Removed = append(changes.Removed, entry)
+		}
+	}
+	return changes, nil
+}
+
+// catalog returns the complete ISOs as snapshot entries, ordered by name,
+// version, and architecture.
+func (s *SnapshotService) catalog() ([]models.CatalogEntry, error) {
+	isos, err := s.db.ListISOsByStatus(models.StatusComplete)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]models.CatalogEntry, 0, len(isos))
+	for i := range isos {
+		entries = append(entries, models.NewCatalogEntry(&isos[i]))
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.Arch < b.Arch
+	})
+	return entries, nil
+}
+
+// changedCatalogFields returns the JSON names of the fields that differ
+// between two entries for the same ISO.
+func changedCatalogFields(before, after models.CatalogEntry) []string {
+	var fields []string
+	for _, f := range []struct {
+		name    string
+		changed bool
+	}{
+		{"name", before.Name != after.Name},
+		{"version", before.Version != after.Version},
+		{"arch", before.Arch != after.Arch},
+		{"edition", before.Edition != after.Edition},
+		{"file_type", before.FileType != after.FileType},
+		{"file_path", before.FilePath != after.FilePath},
+		{"sha256", before.SHA256 != after.SHA256},
+		{"size_bytes", before.SizeBytes != after.SizeBytes},
+		{"completed_at", !sameTime(before.CompletedAt, after.CompletedAt)},
+	} {
+		if f.changed {
+			fields = append(fields, f.name)
+		}
+	}
+	return fields
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
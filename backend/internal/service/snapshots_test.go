@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/clock"
+	"github.com/aloks98/isoman/backend/internal/cron"
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+)
+
+func TestSnapshotService_Changes(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	kept := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Status: models.StatusComplete})
+	updated := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Version: "3.20.0", Status: models.StatusComplete})
+	removed := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Version: "3.18.0", Status: models.StatusComplete})
+	testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Version: "3.21.0", Status: models.StatusFailed})
+
+	svc := NewSnapshotService(env.DB, 0)
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	svc.clock = fake
+
+	snapshot, err := svc.Take()
+	if err != nil {
+		t.Fatalf("Take() failed: %v", err)
+	}
+	if snapshot.ISOCount != 3 {
+		t.Errorf("Expected the 3 complete ISOs in the snapshot, got %d", snapshot.ISOCount)
+	}
+
+	if _, err := svc.Changes(start); err != nil {
+		t.Fatalf("Changes() failed: %v", err)
+	}
+
+	fake.Advance(24 * time.Hour)
+	added := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Version: "3.22.0", Status: models.StatusComplete})
+	if err := env.DB.DeleteISO(removed.ID); err != nil {
+		t.Fatalf("DeleteISO() failed: %v", err)
+	}
+	updated.SizeBytes *= 2
+	updated.SHA256 = "c0ffee"
+	if err := env.DB.UpdateISO(updated); err != nil {
+		t.Fatalf("UpdateISO() failed: %v", err)
+	}
+
+	changes, err := svc.Changes(start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Changes() failed: %v", err)
+	}
+	if !changes.From.Equal(start) || !changes.To.Equal(fake.Now()) {
+		t.Errorf("Expected changes from %v to %v, got %v to %v", start, fake.Now(), changes.From, changes.To)
+	}
+	if len(changes.Added) != 1 || changes.Added[0].ID != added.ID {
+		t.Errorf("Expected %s added, got %+v", added.ID, changes.Added)
+	}
+	if len(changes.Removed) != 1 || changes.Removed[0].ID != removed.ID {
+		t.Errorf("Expected %s removed, got %+v", removed.ID, changes.Removed)
+	}
+	if len(changes.Updated) != 1 || changes.Updated[0].After.ID != updated.ID {
+		t.Fatalf("Expected %s updated, got %+v", updated.ID, changes.Updated)
+	}
+	if fields := changes.Updated[0].Fields; !reflect.DeepEqual(fields, []string{"sha256", "size_bytes"}) {
+		t.Errorf("Expected sha256 and size_bytes changed, got %v", fields)
+	}
+	for _, update := range changes.Updated {
+		if update.After.ID == kept.ID {
+			t.Errorf("Expected %s unchanged", kept.ID)
+		}
+	}
+}
+
+func TestSnapshotService_NoSnapshot(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	if _, err := NewSnapshotService(env.DB, 0).Changes(time.Now()); !errors.Is(err, db.ErrCatalogSnapshotNotFound) {
+		t.Errorf("Expected ErrCatalogSnapshotNotFound, got %v", err)
+	}
+}
+
+func TestSnapshotService_StartFollowsSchedule(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	schedule, err := cron.Parse("0 0 * * *")
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	start := time.Date(2026, 10, 1, 18, 0, 0, 0, time.UTC)
+
+	svc := NewSnapshotService(env.DB, 48*time.Hour)
+	fake := clock.NewFake(start)
+	svc.clock = fake
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	svc.Start(ctx, schedule)
+
+	// The first snapshot is taken at once
+	latest, err := env.DB.GetLatestCatalogSnapshot()
+	if err != nil {
+		t.Fatalf("GetLatestCatalogSnapshot() failed: %v", err)
+	}
+	if !latest.TakenAt.Equal(start) {
+		t.Errorf("Expected a snapshot at start, got one at %v", latest.TakenAt)
+	}
+
+	// Then one every midnight, deleting those past retention
+	for day := 1; day <= 3; day++ {
+		fake.BlockUntil(1)
+		midnight := time.Date(2026, 10, 1+day, 0, 0, 0, 0, time.UTC)
+		fake.Advance(midnight.Sub(fake.Now()))
+		fake.BlockUntil(1)
+
+		if latest, err = env.DB.GetLatestCatalogSnapshot(); err != nil || !latest.TakenAt.Equal(midnight) {
+			t.Fatalf("Expected a snapshot at %v, got %+v, %v", midnight, latest, err)
+		}
+	}
+	if snapshot, err := env.DB.GetCatalogSnapshotAt(start); err != nil || !snapshot.TakenAt.Equal(time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected snapshots older than 48 hours deleted, earliest is %+v, %v", snapshot, err)
+	}
+}
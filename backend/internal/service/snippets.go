@@ -0,0 +1,72 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// snippetStorage is the Proxmox storage the Proxmox snippet defines.
+const snippetStorage = "isoman"
+
+// BuildSnippets generates client configuration that fetches or boots iso from
+// the instance at baseURL, e.g. "https://isos.example.com". Snippets that
+// don't apply to the ISO's file type are left out: Proxmox and iPXE only take
+// ISO and raw disk images.
+func BuildSnippets(iso *models.ISO, baseURL string) []models.Snippet {
+	url := strings.TrimRight(baseURL, "/") + iso.DownloadLink
+	sum := knownSHA256(iso)
+
+	var verify string
+	if sum != "" {
+		verify = fmt.Sprintf("echo '%s  %s' | sha256sum -c -\n", sum, iso.Filename)
+	}
+	script := func(fetch string) string {
+		return "#!/bin/sh\nset -e\n" + fetch + "\n" + verify
+	}
+
+	snippets := []models.Snippet{
+		{
+			Type:     models.SnippetWget,
+			Filename: "fetch-" + iso.Filename + ".sh",
+			Content:  script(fmt.Sprintf("wget -c -O '%s' '%s'", iso.Filename, url)),
+		},
+		{
+			Type:     models.SnippetCurl,
+			Filename: "fetch-" + iso.Filename + ".sh",
+			Content:  script(fmt.Sprintf("curl -fL -C - -o '%s' '%s'", iso.Filename, url)),
+		},
+	}
+	if iso.FileType != "iso" && iso.FileType != "img" {
+		return snippets
+	}
+
+	var proxmox strings.Builder
+	fmt.Fprintf(&proxmox, "# /etc/pve/storage.cfg\ndir: %s\n\tpath /var/lib/vz/%s\n\tcontent iso\n\n", snippetStorage, snippetStorage)
+	fmt.Fprintf(&proxmox, "# Download the image into it\npvesh create /nodes/$(hostname)/storage/%s/download-url --content iso --filename '%s' --url '%s'", snippetStorage, iso.Filename, url)
+	if sum != "" {
+		fmt.Fprintf(&proxmox, " --checksum %s --checksum-algorithm sha256", sum)
+	}
+	proxmox.WriteString("\n")
+
+	return append(snippets,
+		models.Snippet{Type: models.SnippetProxmox, Filename: "storage.cfg", Content: proxmox.String()},
+		models.Snippet{
+			Type:     models.SnippetIPXE,
+			Filename: iso.Name + ".ipxe",
+			Content:  fmt.Sprintf("#!ipxe\ndhcp\nsanboot --no-describe %s\n", url),
+		},
+	)
+}
+
+// knownSHA256 returns the SHA-256 of iso's file, or "" before it is known.
+func knownSHA256(iso *models.ISO) string {
+	switch {
+	case iso.SHA256 != "":
+		return iso.SHA256
+	case strings.EqualFold(iso.ChecksumType, "sha256") && iso.Checksum != "":
+		return strings.ToLower(iso.Checksum)
+	}
+	return ""
+}
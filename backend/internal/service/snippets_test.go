@@ -0,0 +1,44 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+func TestBuildSnippets(t *testing.T) {
+	iso := &models.ISO{Name: "alpine", Version: "3.19.1", Arch: "x86_64", FileType: "iso", SHA256: strings.Repeat("ab", 32)}
+	iso.ComputeFields()
+	url := "https://isos.example.com/images/alpine/3.19.1/x86_64/alpine-3.19.1-x86_64.iso"
+
+	snippets := BuildSnippets(iso, "https://isos.example.com/")
+	byType := make(map[string]models.Snippet, len(snippets))
+	for _, snippet := range snippets {
+		byType[snippet.Type] = snippet
+	}
+	if len(byType) != 4 {
+		t.Fatalf("Expected wget, curl, proxmox, and ipxe snippets, got %+v", snippets)
+	}
+
+	for _, want := range []struct{ snippetType, contains string }{
+		{models.SnippetWget, "wget -c -O 'alpine-3.19.1-x86_64.iso' '" + url + "'"},
+		{models.SnippetWget, "echo '" + iso.SHA256 + "  alpine-3.19.1-x86_64.iso' | sha256sum -c -"},
+		{models.SnippetCurl, "curl -fL -C - -o 'alpine-3.19.1-x86_64.iso' '" + url + "'"},
+		{models.SnippetProxmox, "dir: isoman\n"},
+		{models.SnippetProxmox, "--url '" + url + "' --checksum " + iso.SHA256 + " --checksum-algorithm sha256"},
+		{models.SnippetIPXE, "sanboot --no-describe " + url + "\n"},
+	} {
+		if content := byType[want.snippetType].Content; !strings.Contains(content, want.contains) {
+			t.Errorf("%s snippet lacks %q:\n%s", want.snippetType, want.contains, content)
+		}
+	}
+
+	// Before the hash is known there is nothing to check; VM disks only get fetch scripts
+	disk := &models.ISO{Name: "debian", Version: "12", Arch: "x86_64", FileType: "qcow2"}
+	disk.ComputeFields()
+	snippets = BuildSnippets(disk, "http://localhost:8080")
+	if len(snippets) != 2 || strings.Contains(snippets[0].Content, "sha256sum") {
+		t.Errorf("Expected unchecked fetch scripts only, got %+v", snippets)
+	}
+}
@@ -1,25 +1,170 @@
 package service
 
 import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"time"
 
+	"github.com/aloks98/isoman/backend/internal/clock"
 	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/download"
+	"github.com/aloks98/isoman/backend/internal/geoip"
+	"github.com/aloks98/isoman/backend/internal/logger"
 	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/throughput"
 )
 
 // StatsService handles statistics-related business logic.
 type StatsService struct {
-	db *db.DB
+	db      *db.DB
+	manager *download.Manager  // nil leaves queue stats at zero
+	gauge   *throughput.Gauge  // nil reports zero throughput
+	health  *HealthMonitor     // nil leaves Status without problems
+	locator *geoip.Locator     // nil records downloads without a location
+	replica *ReplicaService    // nil when this instance isn't a replica
+	logs    *logger.Controller // nil when the log level can't be changed
+	clock   clock.Clock        // Paces and dates the event retention sweeps
+	random  io.Reader          // Source of the analytics salt
+	policy  AnalyticsPolicy
+	salt    []byte // Key for hashing client IPs under AnalyticsClientIPHash
 }
 
 // NewStatsService creates a new statistics service.
 func NewStatsService(database *db.DB) *StatsService {
-	return &StatsService{db: database}
+	return &StatsService{db: database, clock: clock.Real(), random: rand.Reader}
 }
 
-// GetStats retrieves aggregated statistics.
+// SetDownloadManager sets the manager whose queue depth is reported in stats.
+func (s *StatsService) SetDownloadManager(manager *download.Manager) {
+	s.manager = manager
+}
+
+// SetThroughputGauge sets the gauge reported by LiveThroughput.
+func (s *StatsService) SetThroughputGauge(gauge *throughput.Gauge) {
+	s.gauge = gauge
+}
+
+// SetHealthMonitor sets the monitor whose findings Status reports.
+func (s *StatsService) SetHealthMonitor(monitor *HealthMonitor) {
+	s.health = monitor
+}
+
+// SetReplica sets the replica sync whose state ReplicaStatus and Status report.
+func (s *StatsService) SetReplica(replica *ReplicaService) {
+	s.replica = replica
+}
+
+// SetLogController sets the logger whose level and format SetLogSettings changes.
+func (s *StatsService) SetLogController(logs *logger.Controller) {
+	s.logs = logs
+}
+
+// ReplicaStatus returns the state of the replica sync, with Enabled false
+// when this instance isn't a replica.
+func (s *StatsService) ReplicaStatus() models.ReplicaStatus {
+	if s.replica == nil {
+		return models.ReplicaStatus{}
+	}
+	return s.replica.Status()
+}
+
+// SetLocator sets the locator that resolves download client IPs to a country and site.
+func (s *StatsService) SetLocator(locator *geoip.Locator) {
+	s.locator = locator
+}
+
+// ThroughputGauge returns the gauge set with SetThroughputGauge, or nil.
+func (s *StatsService) ThroughputGauge() *throughput.Gauge {
+	return s.gauge
+}
+
+// LiveThroughput returns the latest ingest and egress throughput sample.
+func (s *StatsService) LiveThroughput() models.LiveThroughput {
+	if s.gauge == nil {
+		return models.LiveThroughput{SampledAt: time.Now()}
+	}
+	return s.gauge.Current()
+}
+
+// ActiveDownloads returns the downloads workers are running or verifying.
+func (s *StatsService) ActiveDownloads() []models.ActiveDownload {
+	if s.manager == nil {
+		return []models.ActiveDownload{}
+	}
+	return s.manager.ActiveDownloads()
+}
+
+// ManagerState dumps the download manager's queues, workers, and in-flight
+// downloads. Without a manager it reports an empty, stopped one.
+func (s *StatsService) ManagerState() (*models.ManagerState, error) {
+	if s.manager == nil {
+		return &models.ManagerState{
+			CapturedAt: time.Now(),
+			Stopped:    true,
+			Workers:    []models.WorkerState{},
+			InFlight:   []models.InFlightDownload{},
+			Active:     []models.ActiveDownload{},
+			Locks:      []models.DownloadLock{},
+		}, nil
+	}
+	return s.manager.State()
+}
+
+// QueueForecast estimates when each queued download will start and finish.
+func (s *StatsService) QueueForecast() (*models.QueueForecast, error) {
+	if s.manager == nil {
+		return &models.QueueForecast{GeneratedAt: time.Now(), Queue: []models.QueueEstimate{}}, nil
+	}
+	return s.manager.QueueForecast()
+}
+
+// GetStats retrieves aggregated statistics with the default top 10.
 func (s *StatsService) GetStats() (*models.Stats, error) {
-	return s.db.GetStats()
+	return s.GetStatsWithParams(db.StatsParams{})
+}
+
+// GetStatsWithParams retrieves aggregated statistics with a custom top list and breakdown.
+func (s *StatsService) GetStatsWithParams(params db.StatsParams) (*models.Stats, error) {
+	stats, err := s.db.GetStatsWithParams(params)
+	if err != nil {
+		return nil, err
+	}
+	if s.manager != nil {
+		stats.QueueDepth = s.manager.QueueDepth()
+		stats.QueueCapacity = s.manager.QueueCapacity()
+		stats.WorkerPanics = s.manager.WorkerPanics()
+	}
+	return stats, nil
+}
+
+// Status summarizes the instance's health and catalog for the public status
+// endpoint. A database failure is reported as an error.
+func (s *StatsService) Status() (*models.InstanceStatus, error) {
+	complete, lastSync, err := s.db.GetSyncSummary()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.InstanceStatus{
+		Status:       models.InstanceStatusOK,
+		CompleteISOs: complete,
+		LastSyncAt:   lastSync,
+		Problems:     []string{},
+	}
+	if s.health != nil {
+		if problems := s.health.Problems(); len(problems) > 0 {
+			status.Status = models.InstanceStatusDegraded
+			status.Problems = problems
+		}
+	}
+	if replica := s.ReplicaStatus(); replica.LastError != "" {
+		status.Status = models.InstanceStatusDegraded
+		status.Problems = append(status.Problems, "replica sync failed: "+replica.LastError)
+	}
+	return status, nil
 }
 
 // GetDownloadTrends retrieves download trends.
@@ -35,13 +180,173 @@ func (s *StatsService) GetDownloadTrends(period string, days int) (*models.Downl
 	return s.db.GetDownloadTrends(period, days)
 }
 
+// ListMirrorHealth retrieves success, failure, and latency history for each upstream host.
+func (s *StatsService) ListMirrorHealth() ([]models.MirrorHealth, error) {
+	return s.db.ListMirrorHealth()
+}
+
 // RecordDownload records a download event and increments the counter.
 func (s *StatsService) RecordDownload(isoID string) error {
+	return s.RecordDownloadFrom(isoID, "", "")
+}
+
+// RecordDownloadFrom records a download by a client, resolving where the
+// client is when a locator is set. The event keeps only as much of the
+// client IP and User-Agent as the analytics policy allows.
+func (s *StatsService) RecordDownloadFrom(isoID, clientIP, userAgent string) error {
 	// Increment the counter
 	if err := s.db.IncrementDownloadCount(isoID); err != nil {
 		return err
 	}
 
+	event := &models.DownloadEvent{ISOID: isoID, DownloadedAt: time.Now()}
+	if s.locator != nil && clientIP != "" {
+		// Locate before anonymizing; a truncated IP may land in another site
+		loc := s.locator.Locate(clientIP)
+		event.Country, event.Site = loc.Country, loc.Site
+	}
+	event.ClientIP = s.anonymizeClientIP(clientIP)
+	if s.policy.UserAgent {
+		event.UserAgent = userAgent
+	}
+
 	// Record the event for time-based tracking
-	return s.db.RecordDownloadEvent(isoID, time.Now())
+	return s.db.CreateDownloadEvent(event)
+}
+
+// ListDownloadEvents retrieves an ISO's most recent download events, newest first.
+func (s *StatsService) ListDownloadEvents(isoID string, limit int) ([]models.DownloadEvent, error) {
+	if _, err := s.db.GetISO(isoID); err != nil {
+		return nil, err
+	}
+	return s.db.ListDownloadEvents(isoID, limit)
+}
+
+// RecordBytesServed adds n bytes sent to clients to an ISO's total.
+func (s *StatsService) RecordBytesServed(isoID string, n int64) error {
+	return s.db.AddBytesServed(isoID, n)
+}
+
+// ResetDownloadStats clears an ISO's download count, bytes served, and download
+// events, e.g. after a test storm, and records the change, made from
+// clientIP, in the audit log.
+func (s *StatsService) ResetDownloadStats(id, reason, clientIP string) (*models.ISO, error) {
+	iso, err := s.db.GetISO(id)
+	if err != nil {
+		return nil, err
+	}
+	previous := iso.DownloadCount
+
+	if err := s.db.ResetDownloadStats(id); err != nil {
+		return nil, err
+	}
+	iso.DownloadCount = 0
+
+	if err := s.audit(models.AuditActionStatsReset, id, fmt.Sprintf("download_count %d -> 0, download events cleared", previous), reason, clientIP); err != nil {
+		return nil, err
+	}
+	return iso, nil
+}
+
+// AdjustDownloadCount adds delta to an ISO's download count (clamped at zero)
+// and records the change, made from clientIP, in the audit log.
+func (s *StatsService) AdjustDownloadCount(id string, delta int64, reason, clientIP string) (*models.ISO, error) {
+	iso, err := s.db.GetISO(id)
+	if err != nil {
+		return nil, err
+	}
+	previous := iso.DownloadCount
+
+	if err := s.db.AdjustDownloadCount(id, delta); err != nil {
+		return nil, err
+	}
+	if iso, err = s.db.GetISO(id); err != nil {
+		return nil, err
+	}
+
+	details := fmt.Sprintf("download_count %d -> %d (delta %+d)", previous, iso.DownloadCount, delta)
+	if err := s.audit(models.AuditActionStatsAdjust, id, details, reason, clientIP); err != nil {
+		return nil, err
+	}
+	return iso, nil
+}
+
+// ErrLogSettingsFixed indicates that the server's logger can't be changed at runtime.
+var ErrLogSettingsFixed = errors.New("the log level can't be changed on this server")
+
+// InvalidLogSettingsError indicates an unknown log level or format.
+type InvalidLogSettingsError struct {
+	Message string
+}
+
+func (e *InvalidLogSettingsError) Error() string {
+	return e.Message
+}
+
+// LogSettings returns the current log level and format.
+func (s *StatsService) LogSettings() (*models.LogSettings, error) {
+	if s.logs == nil {
+		return nil, ErrLogSettingsFixed
+	}
+	return &models.LogSettings{Level: s.logs.Level(), Format: s.logs.Format()}, nil
+}
+
+// SetLogSettings changes the log level and format until the next restart, and
+// records the change, made from clientIP, in the audit log.
+func (s *StatsService) SetLogSettings(req *models.LogSettingsRequest, clientIP string) (*models.LogSettings, error) {
+	if s.logs == nil {
+		return nil, ErrLogSettingsFixed
+	}
+	if req.Level == "" && req.Format == "" {
+		return nil, &InvalidLogSettingsError{Message: "level or format is required"}
+	}
+
+	previous := models.LogSettings{Level: s.logs.Level(), Format: s.logs.Format()}
+	if err := s.logs.Set(req.Level, req.Format); err != nil {
+		return nil, &InvalidLogSettingsError{Message: err.Error()}
+	}
+	current := &models.LogSettings{Level: s.logs.Level(), Format: s.logs.Format()}
+
+	details := fmt.Sprintf("level %s -> %s, format %s -> %s", previous.Level, current.Level, previous.Format, current.Format)
+	if err := s.audit(models.AuditActionLogLevel, "", details, req.Reason, clientIP); err != nil {
+		return nil, err
+	}
+	slog.Info("log settings changed",
+		slog.String("level", current.Level),
+		slog.String("format", current.Format),
+		slog.String("client_ip", clientIP),
+	)
+	return current, nil
+}
+
+// ListAuditEvents retrieves the most recent audit events, newest first.
+func (s *StatsService) ListAuditEvents(limit int) ([]models.AuditEvent, error) {
+	return s.db.ListAuditEvents(limit)
+}
+
+// ListSystemEvents retrieves recorded health state changes, newest first.
+func (s *StatsService) ListSystemEvents(params db.SystemEventsParams) ([]models.SystemEvent, error) {
+	return s.db.ListSystemEvents(params)
+}
+
+// ListGCReports retrieves reports of files deleted by garbage collection, newest first.
+func (s *StatsService) ListGCReports(params db.GCReportsParams) ([]models.GCReport, error) {
+	return s.db.ListGCReports(params)
+}
+
+// GetGCReport retrieves a garbage collection report by ID.
+func (s *StatsService) GetGCReport(id int64) (*models.GCReport, error) {
+	return s.db.GetGCReport(id)
+}
+
+// audit records an administrative change in the audit log.
+func (s *StatsService) audit(action, targetID, details, reason, clientIP string) error {
+	return s.db.RecordAuditEvent(&models.AuditEvent{
+		Action:    action,
+		TargetID:  targetID,
+		Details:   details,
+		Reason:    reason,
+		ClientIP:  clientIP,
+		CreatedAt: time.Now(),
+	})
 }
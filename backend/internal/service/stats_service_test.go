@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aloks98/isoman/backend/internal/geoip"
 	"github.com/aloks98/isoman/backend/internal/models"
 	"github.com/aloks98/isoman/backend/internal/testutil"
 )
@@ -170,6 +171,44 @@ func TestStatsService_RecordDownload(t *testing.T) {
 	}
 }
 
+func TestStatsService_RecordDownloadFrom(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	iso := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{
+		Name:   "alpine",
+		Status: models.StatusComplete,
+	})
+
+	service := NewStatsService(env.DB)
+	service.RecordDownloadFrom(iso.ID, "10.0.0.5", "") // Before a locator is set
+
+	sites, err := geoip.ParseSites([]string{"hq=10.0.0.0/16", "lab=192.168.0.0/24"})
+	if err != nil {
+		t.Fatalf("ParseSites() failed: %v", err)
+	}
+	service.SetLocator(geoip.NewLocator(nil, sites))
+	for _, ip := range []string{"10.0.0.5", "10.0.9.9", "192.168.0.2", "203.0.113.1"} {
+		if err := service.RecordDownloadFrom(iso.ID, ip, ""); err != nil {
+			t.Fatalf("RecordDownloadFrom() failed: %v", err)
+		}
+	}
+
+	stats, err := service.GetStats()
+	if err != nil {
+		t.Fatalf("GetStats() failed: %v", err)
+	}
+	if stats.TotalDownloads != 5 {
+		t.Errorf("Expected TotalDownloads 5, got %d", stats.TotalDownloads)
+	}
+	if len(stats.DownloadsBySite) != 2 || stats.DownloadsBySite["hq"] != 2 || stats.DownloadsBySite["lab"] != 1 {
+		t.Errorf("Expected 2 hq and 1 lab downloads, got %v", stats.DownloadsBySite)
+	}
+	if len(stats.DownloadsByCountry) != 0 {
+		t.Errorf("Expected no countries without a GeoIP database, got %v", stats.DownloadsByCountry)
+	}
+}
+
 func TestStatsService_RecordDownload_Multiple(t *testing.T) {
 	env := testutil.SetupTestEnvironment(t)
 	defer env.Cleanup()
@@ -308,3 +347,75 @@ func TestStatsService_GetStats_TopDownloaded(t *testing.T) {
 		t.Errorf("Expected top download count 5, got %d", stats.TopDownloaded[0].DownloadCount)
 	}
 }
+
+func TestStatsService_ResetAndAdjustDownloadStats(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	service := NewStatsService(env.DB)
+	iso := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Status: models.StatusComplete})
+	for i := 0; i < 4; i++ {
+		service.RecordDownload(iso.ID)
+	}
+
+	adjusted, err := service.AdjustDownloadCount(iso.ID, -3, "bot traffic", "203.0.113.7")
+	if err != nil {
+		t.Fatalf("AdjustDownloadCount() failed: %v", err)
+	}
+	if adjusted.DownloadCount != 1 {
+		t.Errorf("Expected download count 1, got %d", adjusted.DownloadCount)
+	}
+
+	reset, err := service.ResetDownloadStats(iso.ID, "test storm", "203.0.113.7")
+	if err != nil {
+		t.Fatalf("ResetDownloadStats() failed: %v", err)
+	}
+	if reset.DownloadCount != 0 {
+		t.Errorf("Expected download count 0, got %d", reset.DownloadCount)
+	}
+
+	events, err := service.ListAuditEvents(10)
+	if err != nil {
+		t.Fatalf("ListAuditEvents() failed: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 audit events, got %d", len(events))
+	}
+	if events[0].Action != models.AuditActionStatsReset || events[0].TargetID != iso.ID || events[0].Reason != "test storm" || events[0].ClientIP != "203.0.113.7" {
+		t.Errorf("Unexpected reset event: %+v", events[0])
+	}
+	if events[1].Action != models.AuditActionStatsAdjust || events[1].Details != "download_count 4 -> 1 (delta -3)" {
+		t.Errorf("Unexpected adjust event: %+v", events[1])
+	}
+
+	if _, err := service.ResetDownloadStats("missing", "", ""); err == nil {
+		t.Error("Expected error for unknown ISO")
+	}
+}
+
+func TestStatsService_Status(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Status: models.StatusComplete})
+	svc := NewStatsService(env.DB)
+
+	status, err := svc.Status()
+	if err != nil {
+		t.Fatalf("Status() failed: %v", err)
+	}
+	if status.Status != models.InstanceStatusOK || status.CompleteISOs != 1 || len(status.Problems) != 0 {
+		t.Errorf("Status() = %+v, want ok with 1 complete ISO", status)
+	}
+
+	monitor := NewHealthMonitor(env.DB, nil, env.ISODir, 0)
+	monitor.storageLow = true
+	svc.SetHealthMonitor(monitor)
+	status, err = svc.Status()
+	if err != nil {
+		t.Fatalf("Status() failed: %v", err)
+	}
+	if status.Status != models.InstanceStatusDegraded || len(status.Problems) != 1 || status.Problems[0] != "storage low" {
+		t.Errorf("Status() = %+v, want degraded by low storage", status)
+	}
+}
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/download"
+	"github.com/aloks98/isoman/backend/internal/fileutil"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/pathutil"
+)
+
+// ErrRelocationRunning is returned when a relocation is started while another is running.
+var ErrRelocationRunning = errors.New("a storage relocation is already running")
+
+// StorageService copies the ISO directory to a new location while the server
+// keeps serving from the current one. The switch happens at the next start
+// with ISO_DIR pointing at the copy, when ReconcileISODir copies whatever
+// changed in the meantime.
+type StorageService struct {
+	relocation *models.Relocation // Last or running relocation; nil before the first
+	isoDir     string
+	mu         sync.Mutex
+}
+
+// NewStorageService creates a storage service for the ISO directory.
+func NewStorageService(isoDir string) *StorageService {
+	return &StorageService{isoDir: isoDir}
+}
+
+// StartRelocation starts copying the ISO directory into target in the
+// background and returns the relocation as started.
+func (s *StorageService) StartRelocation(target string) (*models.Relocation, error) {
+	source, target, err := relocationPaths(s.isoDir, target)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.relocation != nil && s.relocation.State == models.RelocationRunning {
+		return nil, ErrRelocationRunning
+	}
+	rel := &models.Relocation{
+		StartedAt: time.Now(),
+		Source:    source,
+		Target:    target,
+		State:     models.RelocationRunning,
+	}
+	s.relocation = rel
+
+	go func() {
+		_, err := copyTree(rel, &s.mu)
+		s.mu.Lock()
+		finishRelocation(rel, err)
+		s.mu.Unlock()
+	}()
+
+	started := *rel
+	return &started, nil
+}
+
+// Relocation returns the last or running relocation, or nil before the first.
+func (s *StorageService) Relocation() *models.Relocation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.relocation == nil {
+		return nil
+	}
+	rel := *s.relocation
+	return &rel
+}
+
+// ReconcileISODir compares isoDir with the ISO directory the database last
+// recorded, and records isoDir when there is none yet. When they differ, mode
+// decides what happens: copy or move the files into isoDir, or skip and
+// accept isoDir as is. Any other mode is an error, since starting anyway
+// would fail every complete ISO whose file is missing. It returns the
+// relocation it ran, if any.
+func ReconcileISODir(database *db.DB, isoDir, mode string) (*models.Relocation, error) {
+	current, err := filepath.Abs(isoDir)
+	if err != nil {
+		return nil, err
+	}
+	recorded, err := database.GetSetting(db.SettingISODir)
+	if errors.Is(err, db.ErrSettingNotFound) {
+		return nil, database.SetSetting(db.SettingISODir, current)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if recorded == current {
+		return nil, nil
+	}
+
+	switch mode {
+	case constants.StorageRelocateSkip:
+		slog.Warn("ISO directory changed, accepting it without relocating files",
+			slog.String("from", recorded),
+			slog.String("to", current),
+		)
+		return nil, database.SetSetting(db.SettingISODir, current)
+	case constants.StorageRelocateCopy, constants.StorageRelocateMove:
+	default:
+		return nil, &InvalidRelocationError{Message: fmt.Sprintf(
+			"ISO directory changed from %s to %s: set STORAGE_RELOCATE to copy or move to relocate the files, or to skip if they are already in place",
+			recorded, current)}
+	}
+
+	if _, _, err := relocationPaths(recorded, current); err != nil {
+		return nil, err
+	}
+	rel := &models.Relocation{
+		StartedAt: time.Now(),
+		Source:    recorded,
+		Target:    current,
+		State:     models.RelocationRunning,
+	}
+	slog.Info("relocating ISO directory", slog.String("from", recorded), slog.String("to", current), slog.String("mode", mode))
+	files, err := copyTree(rel, &sync.Mutex{})
+	finishRelocation(rel, err)
+	if err != nil {
+		return rel, err
+	}
+
+	// Copies keep their modification time but not their inode
+	inodes := make(map[string]uint64)
+	isos, err := database.ListISOs()
+	if err != nil {
+		return rel, err
+	}
+	for _, iso := range isos {
+		if iso.Status != models.StatusComplete {
+			continue
+		}
+		if fi, err := os.Stat(pathutil.ConstructISOPath(current, iso.FilePath)); err == nil {
+			inodes[iso.ID] = fileutil.FileInode(fi)
+		}
+	}
+	if err := database.SwitchISODir(current, inodes); err != nil {
+		return rel, err
+	}
+
+	if mode == constants.StorageRelocateMove {
+		for _, file := range files {
+			fileutil.DeleteFileSilently(filepath.Join(recorded, file))
+		}
+		if _, err := fileutil.PruneEmptyDirs(recorded, 0); err != nil {
+			slog.Warn("failed to remove empty directories after relocation", slog.String("dir", recorded), slog.Any("error", err))
+		}
+	}
+	return rel, nil
+}
+
+// relocationPaths returns source and target as absolute paths, checking that
+// neither lies inside the other.
+func relocationPaths(source, target string) (string, string, error) {
+	if strings.TrimSpace(target) == "" {
+		return "", "", &InvalidRelocationError{Message: "target is required"}
+	}
+	absSource, err := filepath.Abs(source)
+	if err != nil {
+		return "", "", err
+	}
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return "", "", err
+	}
+	if pathutil.IsWithin(absSource, absTarget) || pathutil.IsWithin(absTarget, absSource) {
+		return "", "", &InvalidRelocationError{Message: fmt.Sprintf("target %s must not contain or lie inside the ISO directory %s", absTarget, absSource)}
+	}
+	return absSource, absTarget, nil
+}
+
+// copyTree copies every file under rel.Source into rel.Target, updating the
+// counters of rel under mu. In-progress downloads are left out; they restart
+// after a switch. It returns the relative paths of the files now in place.
+func copyTree(rel *models.Relocation, mu *sync.Mutex) ([]string, error) {
+	tmpDir := pathutil.GetTempDir(rel.Source)
+	var files []string
+	var total int64
+	err := filepath.WalkDir(rel.Source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path == tmpDir {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() || (strings.HasPrefix(d.Name(), ".") && strings.HasSuffix(d.Name(), ".partial")) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(rel.Source, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, relPath)
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files in %s: %w", rel.Source, err)
+	}
+
+	mu.Lock()
+	rel.FilesTotal = len(files)
+	rel.BytesTotal = total
+	mu.Unlock()
+
+	for _, file := range files {
+		size, copied, err := syncFile(filepath.Join(rel.Source, file), filepath.Join(rel.Target, file))
+		if err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		if copied {
+			rel.FilesCopied++
+			rel.BytesCopied += size
+		} else {
+			rel.FilesSkipped++
+		}
+		mu.Unlock()
+	}
+	return files, nil
+}
+
+// syncFile copies src to dst unless dst already has the same size and
+// modification time. The copy is written next to dst, verified by comparing
+// its SHA-256 digest with the source's, and renamed into place with the
+// source's modification time.
+func syncFile(src, dst string) (int64, bool, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return 0, false, err
+	}
+	if existing, err := os.Stat(dst); err == nil && existing.Size() == info.Size() && existing.ModTime().Equal(info.ModTime()) {
+		return info.Size(), false, nil
+	}
+
+	if err := fileutil.EnsureParentDirectory(dst); err != nil {
+		return 0, false, err
+	}
+	partial := filepath.Join(filepath.Dir(dst), "."+filepath.Base(dst)+".partial")
+	sum, err := copyWithDigest(src, partial)
+	if err != nil {
+		fileutil.DeleteFileSilently(partial)
+		return 0, false, err
+	}
+	copySum, err := download.ComputeHash(partial, "sha256")
+	if err != nil || copySum != sum {
+		fileutil.DeleteFileSilently(partial)
+		return 0, false, fmt.Errorf("copy of %s failed verification", src)
+	}
+	if err := os.Chtimes(partial, info.ModTime(), info.ModTime()); err != nil {
+		fileutil.DeleteFileSilently(partial)
+		return 0, false, err
+	}
+	if err := os.Rename(partial, dst); err != nil {
+		fileutil.DeleteFileSilently(partial)
+		return 0, false, fmt.Errorf("failed to move file from %s to %s: %w", partial, dst, err)
+	}
+	slog.Debug("relocated file", slog.String("from", src), slog.String("to", dst))
+	return info.Size(), true, nil
+}
+
+// copyWithDigest copies src to a new file at dst, syncs it, and returns the
+// hex SHA-256 digest of what was read.
+func copyWithDigest(src, dst string) (string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to open source file %s: %w", src, err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file %s: %w", dst, err)
+	}
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(out, hasher), in); err != nil {
+		out.Close()
+		return "", fmt.Errorf("failed to copy file from %s to %s: %w", src, dst, err)
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return "", fmt.Errorf("failed to sync file %s: %w", dst, err)
+	}
+	if err := out.Close(); err != nil {
+		return "", fmt.Errorf("failed to close file %s: %w", dst, err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// finishRelocation records the outcome of a relocation.
+func finishRelocation(rel *models.Relocation, err error) {
+	now := time.Now()
+	rel.FinishedAt = &now
+	rel.State = models.RelocationComplete
+	if err != nil {
+		rel.State = models.RelocationFailed
+		rel.Error = err.Error()
+		slog.Error("storage relocation failed", slog.String("target", rel.Target), slog.Any("error", err))
+		return
+	}
+	slog.Info("storage relocation complete",
+		slog.String("target", rel.Target),
+		slog.Int("files_copied", rel.FilesCopied),
+		slog.Int("files_skipped", rel.FilesSkipped),
+		slog.Int64("bytes_copied", rel.BytesCopied),
+	)
+}
+
+// InvalidRelocationError indicates that a relocation target is unusable, or
+// that ISO_DIR changed without STORAGE_RELOCATE saying what to do.
+type InvalidRelocationError struct {
+	Message string
+}
+
+func (e *InvalidRelocationError) Error() string {
+	return e.Message
+}
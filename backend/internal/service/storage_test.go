@@ -0,0 +1,110 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+)
+
+func writeStorageFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReconcileISODir(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+
+	iso := &models.ISO{ID: "iso-1", Name: "alpine", Version: "3.19.1", Arch: "x86_64", FileType: "iso", DownloadURL: "https://example.com/alpine.iso", Status: models.StatusComplete, CreatedAt: time.Now()}
+	iso.ComputeFields()
+	env.DB.CreateISO(iso)
+	writeStorageFile(t, filepath.Join(env.ISODir, iso.FilePath), "alpine")
+	writeStorageFile(t, filepath.Join(env.ISODir, ".versions", iso.FilePath+".20240101T000000Z"), "old")
+	writeStorageFile(t, filepath.Join(env.ISODir, ".tmp", "partial.iso"), "partial")
+
+	// The first start records the directory
+	if rel, err := ReconcileISODir(env.DB, env.ISODir, ""); err != nil || rel != nil {
+		t.Fatalf("ReconcileISODir() = %v, %v on first start", rel, err)
+	}
+	if recorded, _ := env.DB.GetSetting(db.SettingISODir); recorded != env.ISODir {
+		t.Fatalf("Expected %s recorded, got %q", env.ISODir, recorded)
+	}
+
+	target := filepath.Join(env.TmpDir, "bulk")
+	var invalidErr *InvalidRelocationError
+	if _, err := ReconcileISODir(env.DB, target, ""); !errors.As(err, &invalidErr) {
+		t.Fatalf("Expected InvalidRelocationError without STORAGE_RELOCATE, got: %v", err)
+	}
+
+	rel, err := ReconcileISODir(env.DB, target, constants.StorageRelocateMove)
+	if err != nil {
+		t.Fatalf("ReconcileISODir() failed: %v", err)
+	}
+	if rel.State != models.RelocationComplete || rel.FilesCopied != 2 {
+		t.Errorf("Expected 2 files copied, got %+v", rel)
+	}
+	if content, _ := os.ReadFile(filepath.Join(target, iso.FilePath)); string(content) != "alpine" {
+		t.Errorf("Expected the ISO in the target, got %q", content)
+	}
+	if _, err := os.Stat(filepath.Join(target, ".tmp", "partial.iso")); err == nil {
+		t.Error("Expected in-progress downloads left behind")
+	}
+	if _, err := os.Stat(filepath.Join(env.ISODir, iso.FilePath)); err == nil {
+		t.Error("Expected move to remove the source file")
+	}
+	if recorded, _ := env.DB.GetSetting(db.SettingISODir); recorded != target {
+		t.Errorf("Expected %s recorded, got %q", target, recorded)
+	}
+
+	if _, err := ReconcileISODir(env.DB, filepath.Join(target, "nested"), constants.StorageRelocateCopy); !errors.As(err, &invalidErr) {
+		t.Errorf("Expected InvalidRelocationError for a nested target, got: %v", err)
+	}
+}
+
+func TestStorageService_StartRelocation(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := NewStorageService(env.ISODir)
+
+	writeStorageFile(t, filepath.Join(env.ISODir, "alpine", "alpine.iso"), "alpine")
+	writeStorageFile(t, filepath.Join(env.ISODir, "rocky", "rocky.iso"), "rocky")
+
+	if svc.Relocation() != nil {
+		t.Fatal("Expected no relocation before the first")
+	}
+	var invalidErr *InvalidRelocationError
+	if _, err := svc.StartRelocation(filepath.Join(env.ISODir, "copy")); !errors.As(err, &invalidErr) {
+		t.Errorf("Expected InvalidRelocationError for a target inside the ISO directory, got: %v", err)
+	}
+
+	target := filepath.Join(env.TmpDir, "bulk")
+	for _, wantCopied := range []int{2, 0} {
+		if _, err := svc.StartRelocation(target); err != nil {
+			t.Fatalf("StartRelocation() failed: %v", err)
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for svc.Relocation().State == models.RelocationRunning && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		rel := svc.Relocation()
+		if rel.State != models.RelocationComplete || rel.FilesCopied != wantCopied || rel.FilesTotal != 2 {
+			t.Errorf("Expected %d of 2 files copied, got %+v", wantCopied, rel)
+		}
+	}
+	if content, _ := os.ReadFile(filepath.Join(target, "rocky", "rocky.iso")); string(content) != "rocky" {
+		t.Errorf("Expected the file copied, got %q", content)
+	}
+}
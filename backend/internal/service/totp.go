@@ -0,0 +1,196 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/totp"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// totpIssuer labels the account in authenticator apps.
+	totpIssuer = "ISOMan"
+	// totpSkew accepts codes one step either side of now to absorb clock drift.
+	totpSkew = 1
+	// recoveryCodeCount is how many recovery codes are issued at a time.
+	recoveryCodeCount = 10
+)
+
+var (
+	// ErrTOTPRequired is returned by Login when the password is right but a
+	// two-factor code is needed.
+	ErrTOTPRequired = errors.New("two-factor code required")
+	// ErrInvalidTOTPCode is returned when a TOTP or recovery code is wrong or
+	// was already used.
+	ErrInvalidTOTPCode = errors.New("invalid two-factor code")
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// BeginTOTP generates a new TOTP secret for a user. It isn't enforced until
+// ConfirmTOTP sees a code from it, so a failed enrollment never locks anyone out.
+func (s *AuthService) BeginTOTP(userID string) (*models.TOTPEnrollment, error) {
+	user, err := s.db.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, &TOTPStateError{Message: "two-factor authentication is already enabled"}
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.SetUserTOTP(user.ID, secret, false); err != nil {
+		return nil, err
+	}
+	return &models.TOTPEnrollment{Secret: secret, URL: totp.URL(totpIssuer, user.Username, secret)}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication once code matches the secret
+// from BeginTOTP, and returns a fresh set of recovery codes.
+func (s *AuthService) ConfirmTOTP(userID, code string) (*models.RecoveryCodes, error) {
+	user, err := s.db.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, &TOTPStateError{Message: "two-factor authentication is already enabled"}
+	}
+	if user.TOTPSecret == "" {
+		return nil, &TOTPStateError{Message: "start enrollment first"}
+	}
+
+	step, ok := totp.Validate(user.TOTPSecret, code, s.now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidTOTPCode
+	}
+	if err := s.db.SetUserTOTP(user.ID, user.TOTPSecret, true); err != nil {
+		return nil, err
+	}
+	s.cache.evictUser(user.ID)
+	if _, err := s.db.UseTOTPStep(user.ID, step); err != nil {
+		return nil, err
+	}
+	return s.issueRecoveryCodes(user.ID)
+}
+
+// DisableTOTP turns two-factor authentication off after checking the user's
+// password and a TOTP or recovery code.
+func (s *AuthService) DisableTOTP(userID, password, code string) error {
+	user, err := s.db.GetUser(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return &TOTPStateError{Message: "two-factor authentication is not enabled"}
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return ErrInvalidLogin
+	}
+	if err := s.verifySecondFactor(user, code); err != nil {
+		return err
+	}
+
+	if err := s.db.SetUserTOTP(user.ID, "", false); err != nil {
+		return err
+	}
+	s.cache.evictUser(user.ID)
+	return s.db.ReplaceRecoveryCodes(user.ID, nil)
+}
+
+// RegenerateRecoveryCodes replaces a user's recovery codes after checking a
+// TOTP code. Recovery codes aren't accepted here, so a leaked one can't be
+// used to mint more.
+func (s *AuthService) RegenerateRecoveryCodes(userID, code string) (*models.RecoveryCodes, error) {
+	user, err := s.db.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, &TOTPStateError{Message: "two-factor authentication is not enabled"}
+	}
+	if err := s.verifyTOTP(user, code); err != nil {
+		return nil, err
+	}
+	return s.issueRecoveryCodes(user.ID)
+}
+
+// verifySecondFactor accepts a current TOTP code or an unused recovery code.
+func (s *AuthService) verifySecondFactor(user *models.User, code string) error {
+	if err := s.verifyTOTP(user, code); !errors.Is(err, ErrInvalidTOTPCode) {
+		return err
+	}
+
+	used, err := s.db.UseRecoveryCode(user.ID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTOTPCode
+	}
+	return nil
+}
+
+// verifyTOTP accepts a TOTP code from a time step newer than the last one used.
+func (s *AuthService) verifyTOTP(user *models.User, code string) error {
+	step, ok := totp.Validate(user.TOTPSecret, code, s.now(), totpSkew)
+	if !ok {
+		return ErrInvalidTOTPCode
+	}
+	fresh, err := s.db.UseTOTPStep(user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidTOTPCode
+	}
+	return nil
+}
+
+// issueRecoveryCodes replaces a user's recovery codes and returns the new
+// ones in plaintext; only their hashes are stored.
+func (s *AuthService) issueRecoveryCodes(userID string) (*models.RecoveryCodes, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 8)
+		if _, err := io.ReadFull(s.random, b); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(b))[:10]
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	if err := s.db.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return &models.RecoveryCodes{Codes: codes}, nil
+}
+
+// hashRecoveryCode hashes a recovery code, ignoring case, spaces, and dashes.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// TOTPStateError indicates that a two-factor operation doesn't fit the
+// user's enrollment state.
+type TOTPStateError struct {
+	Message string
+}
+
+func (e *TOTPStateError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/testutil"
+	"github.com/aloks98/isoman/backend/internal/totp"
+)
+
+func TestAuthService_TOTPEnrollmentAndLogin(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := newTestAuthService(env)
+
+	now := time.Unix(1_800_000_000, 0)
+	svc.now = func() time.Time { return now }
+
+	user, err := svc.CreateUser("admin", "correct horse")
+	if err != nil {
+		t.Fatalf("CreateUser() failed: %v", err)
+	}
+
+	enrollment, err := svc.BeginTOTP(user.ID)
+	if err != nil {
+		t.Fatalf("BeginTOTP() failed: %v", err)
+	}
+	// Enrollment isn't enforced until confirmed
+	if _, err := svc.Login("admin", "correct horse", "", "", ""); err != nil {
+		t.Fatalf("Login() during enrollment failed: %v", err)
+	}
+
+	if _, err := svc.ConfirmTOTP(user.ID, "000000"); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Errorf("Expected ErrInvalidTOTPCode for a wrong code, got: %v", err)
+	}
+	code, _ := totp.Code(enrollment.Secret, totp.Step(now))
+	codes, err := svc.ConfirmTOTP(user.ID, code)
+	if err != nil {
+		t.Fatalf("ConfirmTOTP() failed: %v", err)
+	}
+	if len(codes.Codes) != recoveryCodeCount {
+		t.Fatalf("Expected %d recovery codes, got %d", recoveryCodeCount, len(codes.Codes))
+	}
+
+	if _, err := svc.Login("admin", "correct horse", "", "", ""); !errors.Is(err, ErrTOTPRequired) {
+		t.Errorf("Expected ErrTOTPRequired without a code, got: %v", err)
+	}
+	// The code that confirmed enrollment can't be replayed
+	if _, err := svc.Login("admin", "correct horse", code, "", ""); !errors.Is(err, ErrInvalidLogin) {
+		t.Errorf("Expected ErrInvalidLogin for a replayed code, got: %v", err)
+	}
+
+	now = now.Add(totp.Period)
+	code, _ = totp.Code(enrollment.Secret, totp.Step(now))
+	if _, err := svc.Login("admin", "correct horse", code, "", ""); err != nil {
+		t.Errorf("Login() with a fresh code failed: %v", err)
+	}
+
+	// Recovery codes work once, in any case and with or without the dash
+	recovery := codes.Codes[0]
+	if _, err := svc.Login("admin", "correct horse", recovery[:5]+recovery[6:], "", ""); err != nil {
+		t.Errorf("Login() with a recovery code failed: %v", err)
+	}
+	if _, err := svc.Login("admin", "correct horse", recovery, "", ""); !errors.Is(err, ErrInvalidLogin) {
+		t.Errorf("Expected a used recovery code to be rejected, got: %v", err)
+	}
+	if left, _ := env.DB.CountRecoveryCodes(user.ID); left != recoveryCodeCount-1 {
+		t.Errorf("Expected %d recovery codes left, got %d", recoveryCodeCount-1, left)
+	}
+
+	var stateErr *TOTPStateError
+	if _, err := svc.BeginTOTP(user.ID); !errors.As(err, &stateErr) {
+		t.Errorf("Expected TOTPStateError when already enrolled, got: %v", err)
+	}
+}
+
+func TestAuthService_DisableTOTP(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := newTestAuthService(env)
+
+	now := time.Unix(1_800_000_000, 0)
+	svc.now = func() time.Time { return now }
+
+	user, _ := svc.CreateUser("admin", "correct horse")
+	enrollment, _ := svc.BeginTOTP(user.ID)
+	code, _ := totp.Code(enrollment.Secret, totp.Step(now))
+	codes, err := svc.ConfirmTOTP(user.ID, code)
+	if err != nil {
+		t.Fatalf("ConfirmTOTP() failed: %v", err)
+	}
+
+	if err := svc.DisableTOTP(user.ID, "wrong password", codes.Codes[0]); !errors.Is(err, ErrInvalidLogin) {
+		t.Errorf("Expected ErrInvalidLogin for a wrong password, got: %v", err)
+	}
+	if _, err := svc.RegenerateRecoveryCodes(user.ID, codes.Codes[0]); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Errorf("Expected recovery codes to be refused for regeneration, got: %v", err)
+	}
+	if err := svc.DisableTOTP(user.ID, "correct horse", codes.Codes[0]); err != nil {
+		t.Fatalf("DisableTOTP() failed: %v", err)
+	}
+
+	if _, err := svc.Login("admin", "correct horse", "", "", ""); err != nil {
+		t.Errorf("Login() after disabling two-factor failed: %v", err)
+	}
+	if left, _ := env.DB.CountRecoveryCodes(user.ID); left != 0 {
+		t.Errorf("Expected recovery codes to be discarded, got %d", left)
+	}
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/httputil"
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// CheckUpstream compares the upstream validators of a complete ISO against the
+// ones recorded at download time and flags the ISO when they differ. With
+// refresh set, a changed ISO is queued for re-download.
+func (s *ISOService) CheckUpstream(ctx context.Context, id string, refresh bool) (*models.ISO, error) {
+	iso, err := s.db.GetISO(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if iso.Status != models.StatusComplete {
+		return nil, &InvalidStateError{
+			CurrentStatus: string(iso.Status),
+			Message:       "Only complete ISOs can be checked for upstream changes",
+		}
+	}
+	if !isHTTPURL(iso.DownloadURL) {
+		return nil, &InvalidStateError{
+			CurrentStatus: string(iso.Status),
+			Message:       "Only ISOs downloaded over HTTP can be checked for upstream changes",
+		}
+	}
+
+	upstreamCtx := s.credentials.WithCredentials(httputil.WithIPFamily(ctx, iso.IPFamily), iso)
+	validators, err := httputil.HeadValidators(upstreamCtx, iso.DownloadURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check upstream: %w", err)
+	}
+
+	now := s.clock.Now()
+	iso.UpstreamChanged = validators.Differs(iso.UpstreamETag, iso.UpstreamLastModified, iso.SizeBytes)
+	iso.UpstreamCheckedAt = &now
+	if err := s.db.UpdateISOUpstreamCheck(iso.ID, iso.UpstreamChanged, now); err != nil {
+		return nil, err
+	}
+
+	if iso.UpstreamChanged && refresh {
+		if err := s.requeue(ctx, iso); err != nil {
+			return nil, err
+		}
+	}
+
+	return iso, nil
+}
+
+// RefreshISO re-downloads a complete ISO into the same record when its upstream
+// changed. With force set, the download is queued without checking upstream.
+// The returned ISO's status tells whether a download was queued.
+func (s *ISOService) RefreshISO(ctx context.Context, id string, force bool) (*models.ISO, error) {
+	if !force {
+		return s.CheckUpstream(ctx, id, true)
+	}
+
+	iso, err := s.db.GetISO(id)
+	if err != nil {
+		return nil, err
+	}
+	if iso.Status != models.StatusComplete {
+		return nil, &InvalidStateError{
+			CurrentStatus: string(iso.Status),
+			Message:       "Only complete ISOs can be refreshed",
+		}
+	}
+	if !isHTTPURL(iso.DownloadURL) {
+		return nil, &InvalidStateError{
+			CurrentStatus: string(iso.Status),
+			Message:       "Only ISOs downloaded over HTTP can be refreshed",
+		}
+	}
+
+	if err := s.requeue(ctx, iso); err != nil {
+		return nil, err
+	}
+	return iso, nil
+}
+
+// RedownloadISO downloads a complete ISO again into the same record, e.g. to
+// replace a corrupted file. The new file is downloaded and verified in the
+// temp directory before it replaces the old one in a single rename, so the
+// old file is served until then. If the download fails, the ISO goes back to
+// complete with the old file.
+func (s *ISOService) RedownloadISO(ctx context.Context, id string) (*models.ISO, error) {
+	iso, err := s.db.GetISO(id)
+	if err != nil {
+		return nil, err
+	}
+	if iso.Status != models.StatusComplete {
+		return nil, &InvalidStateError{
+			CurrentStatus: string(iso.Status),
+			Message:       "Only complete ISOs can be redownloaded",
+		}
+	}
+	if !isHTTPURL(iso.DownloadURL) {
+		return nil, &InvalidStateError{
+			CurrentStatus: string(iso.Status),
+			Message:       "Only ISOs downloaded over HTTP can be redownloaded",
+		}
+	}
+	if err := s.checkQueueCapacity(); err != nil {
+		return nil, err
+	}
+
+	// CompletedAt and the size keep describing the served file until it's replaced
+	previous := *iso
+	iso.Status = models.StatusPending
+	iso.Progress = 0
+	iso.ErrorMessage = ""
+	iso.ErrorReason = models.ErrorReasonNone
+	iso.UpstreamChanged = false
+	iso.Redownload = true
+	if err := s.db.UpdateISO(iso); err != nil {
+		return nil, fmt.Errorf("failed to update ISO: %w", err)
+	}
+
+	if err := s.queueDownload(ctx, iso); err != nil {
+		if restoreErr := s.db.UpdateISO(&previous); restoreErr != nil {
+			slog.Warn("failed to restore ISO after redownload was refused", slog.String("iso_id", id), slog.Any("error", restoreErr))
+		}
+		return nil, err
+	}
+	return iso, nil
+}
+
+// StartUpstreamChecker checks every complete HTTP ISO for upstream changes once
+// per interval until ctx is canceled. A zero interval disables the checker.
+func (s *ISOService) StartUpstreamChecker(ctx context.Context, interval time.Duration, autoRefresh bool) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := s.clock.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				s.checkAllUpstream(ctx, autoRefresh)
+			}
+		}
+	}()
+}
+
+// checkAllUpstream runs CheckUpstream for every eligible ISO, logging failures.
+func (s *ISOService) checkAllUpstream(ctx context.Context, refresh bool) {
+	isos, err := s.db.ListISOs()
+	if err != nil {
+		slog.Warn("failed to list ISOs for upstream check", slog.Any("error", err))
+		return
+	}
+
+	changed := 0
+	for _, iso := range isos {
+		if ctx.Err() != nil {
+			return
+		}
+		if iso.Status != models.StatusComplete || !isHTTPURL(iso.DownloadURL) {
+			continue
+		}
+
+		checked, err := s.CheckUpstream(ctx, iso.ID, refresh)
+		if err != nil {
+			slog.Warn("upstream check failed", slog.String("iso_id", iso.ID), slog.Any("error", err))
+			continue
+		}
+		if checked.UpstreamChanged {
+			changed++
+			slog.Info("upstream changed since download",
+				slog.String("iso_id", iso.ID),
+				slog.String("name", iso.Name),
+				slog.Bool("refresh_queued", refresh),
+			)
+		}
+	}
+
+	slog.Debug("upstream check finished", slog.Int("changed", changed))
+}
+
+// requeue resets a complete ISO to pending and queues it for download again.
+// The existing file keeps being served until the new download replaces it.
+func (s *ISOService) requeue(ctx context.Context, iso *models.ISO) error {
+	if err := s.checkQueueCapacity(); err != nil {
+		return err
+	}
+
+	iso.Status = models.StatusPending
+	iso.Progress = 0
+	iso.ErrorMessage = ""
+	iso.ErrorReason = models.ErrorReasonNone
+	iso.CompletedAt = nil
+	iso.SizeBytes = 0 // Re-recorded from the new response
+	iso.UpstreamChanged = false
+
+	if err := s.db.UpdateISO(iso); err != nil {
+		return fmt.Errorf("failed to update ISO: %w", err)
+	}
+
+	return s.queueDownload(ctx, iso)
+}
+
+// isHTTPURL reports whether rawURL is fetched over HTTP(S) rather than, e.g., an adopted file:// path.
+func isHTTPURL(rawURL string) bool {
+	lower := strings.ToLower(rawURL)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
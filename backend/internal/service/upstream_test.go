@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+)
+
+func TestISOService_CheckUpstream(t *testing.T) {
+	etag := `"v1"`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+	}))
+	defer server.Close()
+
+	service, env := setupTestISOService(t)
+	defer env.Cleanup()
+
+	iso := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{
+		DownloadURL: server.URL + "/nightly.iso",
+		Status:      models.StatusComplete,
+	})
+	iso.UpstreamETag = `"v1"`
+	if err := env.DB.UpdateISO(iso); err != nil {
+		t.Fatalf("UpdateISO() failed: %v", err)
+	}
+
+	t.Run("Unchanged", func(t *testing.T) {
+		checked, err := service.CheckUpstream(context.Background(), iso.ID, false)
+		if err != nil {
+			t.Fatalf("CheckUpstream() failed: %v", err)
+		}
+		if checked.UpstreamChanged {
+			t.Error("UpstreamChanged should be false for matching ETag")
+		}
+		if checked.UpstreamCheckedAt == nil {
+			t.Error("UpstreamCheckedAt should be set")
+		}
+	})
+
+	t.Run("Changed", func(t *testing.T) {
+		etag = `"v2"`
+
+		checked, err := service.CheckUpstream(context.Background(), iso.ID, false)
+		if err != nil {
+			t.Fatalf("CheckUpstream() failed: %v", err)
+		}
+		if !checked.UpstreamChanged {
+			t.Error("UpstreamChanged should be true for a new ETag")
+		}
+
+		stored, _ := service.GetISO(iso.ID)
+		if !stored.UpstreamChanged || stored.Status != models.StatusComplete {
+			t.Errorf("Expected stored ISO flagged and still complete, got changed=%v status=%s", stored.UpstreamChanged, stored.Status)
+		}
+	})
+
+	t.Run("Refresh", func(t *testing.T) {
+		checked, err := service.CheckUpstream(context.Background(), iso.ID, true)
+		if err != nil {
+			t.Fatalf("CheckUpstream() failed: %v", err)
+		}
+		if !checked.Status.IsActive() {
+			t.Errorf("ISO should be queued for download after refresh, got: %s", checked.Status)
+		}
+
+		// Queued ISOs can't be checked or refreshed again until they complete
+		if _, err := service.RefreshISO(context.Background(), iso.ID, true); err == nil {
+			t.Error("Expected RefreshISO to reject a queued ISO")
+		}
+		_, err = service.CheckUpstream(context.Background(), iso.ID, false)
+		var stateErr *InvalidStateError
+		if !errors.As(err, &stateErr) {
+			t.Errorf("Expected InvalidStateError, got: %v", err)
+		}
+	})
+}
+
+func TestISOService_RefreshISO(t *testing.T) {
+	service, env := setupTestISOService(t)
+	defer env.Cleanup()
+
+	t.Run("ForceQueuesDownload", func(t *testing.T) {
+		iso := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{Status: models.StatusComplete})
+
+		refreshed, err := service.RefreshISO(context.Background(), iso.ID, true)
+		if err != nil {
+			t.Fatalf("RefreshISO() failed: %v", err)
+		}
+		if !refreshed.Status.IsActive() {
+			t.Errorf("ISO should be queued for download, got: %s", refreshed.Status)
+		}
+		if refreshed.ID != iso.ID {
+			t.Error("Refresh should reuse the existing record")
+		}
+	})
+
+	t.Run("AdoptedFile", func(t *testing.T) {
+		iso := testutil.CreateAndInsertTestISO(t, env.DB, &testutil.TestISO{
+			Name:        "adopted",
+			DownloadURL: "file:///srv/mirror/adopted.iso",
+			Status:      models.StatusComplete,
+		})
+
+		_, err := service.RefreshISO(context.Background(), iso.ID, true)
+		var stateErr *InvalidStateError
+		if !errors.As(err, &stateErr) {
+			t.Errorf("Expected InvalidStateError for file:// ISO, got: %v", err)
+		}
+	})
+}
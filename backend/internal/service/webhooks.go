@@ -0,0 +1,318 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/db"
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+var (
+	// ErrWebhooksDisabled is returned when a webhook is created or verified
+	// without a master key to seal its secret with.
+	ErrWebhooksDisabled = errors.New("webhooks are disabled: set CREDENTIALS_KEY or CREDENTIALS_KEY_FILE")
+	// ErrWebhookSignature is returned for a delivery whose signature is
+	// missing or does not match the body.
+	ErrWebhookSignature = errors.New("webhook signature is missing or invalid")
+	// ErrWebhookFiltered is returned for a delivery the webhook's filter rejects.
+	ErrWebhookFiltered = errors.New("delivery ignored by the webhook filter")
+)
+
+// webhookFuncs are available to webhook templates in addition to the
+// text/template builtins. Argument order suits pipelines, e.g.
+// {{ .release.tag_name | trimPrefix "v" }}.
+var webhookFuncs = template.FuncMap{
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+}
+
+// WebhookService manages inbound webhooks and turns their deliveries into
+// ISO requests. Secrets are sealed with the credentials master key.
+type WebhookService struct {
+	db          *db.DB
+	credentials *CredentialService
+}
+
+// NewWebhookService creates a webhook service that seals secrets with the
+// master key of credentials.
+func NewWebhookService(database *db.DB, credentials *CredentialService) *WebhookService {
+	return &WebhookService{
+		db:          database,
+		credentials: credentials,
+	}
+}
+
+// ListWebhooks retrieves all webhooks without their secrets.
+func (s *WebhookService) ListWebhooks() ([]models.Webhook, error) {
+	return s.db.ListWebhooks()
+}
+
+// GetWebhook retrieves a webhook by name without its secret.
+func (s *WebhookService) GetWebhook(name string) (*models.Webhook, error) {
+	return s.db.GetWebhook(name)
+}
+
+// CreateWebhook stores a new webhook with a generated secret, which the
+// returned webhook carries in NewSecret.
+func (s *WebhookService) CreateWebhook(req models.CreateWebhookRequest) (*models.Webhook, error) {
+	if s.credentials.box == nil {
+		return nil, ErrWebhooksDisabled
+	}
+	if !models.IsValidWebhookName(req.Name) {
+		return nil, &InvalidWebhookError{Message: "name must be 1-64 lowercase letters, digits, '.', '_' or '-'"}
+	}
+	if err := validateWebhookTemplates(&req.Template, req.Filter); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.db.GetWebhook(req.Name); err == nil {
+		return nil, &WebhookExistsError{Name: req.Name}
+	} else if !errors.Is(err, db.ErrWebhookNotFound) {
+		return nil, err
+	}
+
+	now := time.Now()
+	hook := &models.Webhook{
+		Name:      req.Name,
+		Filter:    req.Filter,
+		Template:  req.Template,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.newSecret(hook); err != nil {
+		return nil, err
+	}
+	if err := s.db.CreateWebhook(hook); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// UpdateWebhook replaces the template or filter of a webhook, or rotates
+// its secret.
+func (s *WebhookService) UpdateWebhook(name string, req models.UpdateWebhookRequest) (*models.Webhook, error) {
+	hook, err := s.db.GetWebhook(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Template != nil {
+		hook.Template = *req.Template
+	}
+	if req.Filter != nil {
+		hook.Filter = *req.Filter
+	}
+	if err := validateWebhookTemplates(&hook.Template, hook.Filter); err != nil {
+		return nil, err
+	}
+	if req.RotateSecret {
+		if s.credentials.box == nil {
+			return nil, ErrWebhooksDisabled
+		}
+		if err := s.newSecret(hook); err != nil {
+			return nil, err
+		}
+	}
+
+	hook.UpdatedAt = time.Now()
+	if err := s.db.UpdateWebhook(hook); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// DeleteWebhook removes a webhook.
+func (s *WebhookService) DeleteWebhook(name string) error {
+	return s.db.DeleteWebhook(name)
+}
+
+// Verify checks a "sha256=<hex>" HMAC-SHA256 signature of body, as sent in
+// the X-Hub-Signature-256 header by GitHub, against the webhook's secret.
+func (s *WebhookService) Verify(hook *models.Webhook, body []byte, signature string) error {
+	if s.credentials.box == nil {
+		return ErrWebhooksDisabled
+	}
+	secret, err := s.credentials.box.Open(hook.Secret)
+	if err != nil {
+		return err
+	}
+
+	hexSum, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return ErrWebhookSignature
+	}
+	got, err := hex.DecodeString(hexSum)
+	if err != nil {
+		return ErrWebhookSignature
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return ErrWebhookSignature
+	}
+	return nil
+}
+
+// Render decodes a JSON payload and renders the webhook's templates against
+// it. It returns ErrWebhookFiltered when the filter does not render "true".
+func (s *WebhookService) Render(hook *models.Webhook, body []byte) (*CreateISORequest, error) {
+	var payload any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber() // Keep build numbers and IDs as written
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, &InvalidWebhookError{Message: fmt.Sprintf("payload is not valid JSON: %v", err)}
+	}
+
+	if hook.Filter != "" {
+		matched, err := renderWebhookTemplate("filter", hook.Filter, payload)
+		if err != nil {
+			return nil, err
+		}
+		if matched != "true" {
+			return nil, ErrWebhookFiltered
+		}
+	}
+
+	fields := webhookTemplateFields(&hook.Template)
+	values := make(map[string]string, len(fields))
+	for _, field := range fields {
+		value, err := renderWebhookTemplate("template."+field.name, *field.text, payload)
+		if err != nil {
+			return nil, err
+		}
+		values[field.name] = value
+	}
+
+	return &CreateISORequest{
+		Name:         values["name"],
+		Version:      values["version"],
+		Arch:         values["arch"],
+		Edition:      values["edition"],
+		DownloadURL:  values["download_url"],
+		ChecksumURL:  values["checksum_url"],
+		ChecksumType: values["checksum_type"],
+		IPFamily:     values["ip_family"],
+		Credential:   values["credential"],
+	}, nil
+}
+
+// RecordDelivery audits an ISO queued by a webhook delivery from clientIP.
+func (s *WebhookService) RecordDelivery(hook *models.Webhook, iso *models.ISO, clientIP string) {
+	slog.Info("webhook queued ISO", slog.String("webhook", hook.Name), slog.String("iso_id", iso.ID))
+	err := s.db.RecordAuditEvent(&models.AuditEvent{
+		Action:    models.AuditActionWebhook,
+		TargetID:  iso.ID,
+		Details:   fmt.Sprintf("webhook %s queued %s", hook.Name, iso.Filename),
+		ClientIP:  clientIP,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		slog.Warn("failed to record audit event", slog.String("action", models.AuditActionWebhook), slog.Any("error", err))
+	}
+}
+
+// newSecret generates and seals a new secret for hook, leaving the
+// plaintext in NewSecret.
+func (s *WebhookService) newSecret(hook *models.Webhook) error {
+	secret, err := newSessionToken(rand.Reader)
+	if err != nil {
+		return err
+	}
+	if hook.Secret, err = s.credentials.box.Seal([]byte(secret)); err != nil {
+		return err
+	}
+	hook.NewSecret = secret
+	return nil
+}
+
+type webhookTemplateField struct {
+	text     *string
+	name     string
+	required bool
+}
+
+func webhookTemplateFields(t *models.WebhookTemplate) []webhookTemplateField {
+	return []webhookTemplateField{
+		{name: "name", text: &t.Name, required: true},
+		{name: "version", text: &t.Version, required: true},
+		{name: "arch", text: &t.Arch, required: true},
+		{name: "edition", text: &t.Edition},
+		{name: "download_url", text: &t.DownloadURL, required: true},
+		{name: "checksum_url", text: &t.ChecksumURL},
+		{name: "checksum_type", text: &t.ChecksumType},
+		{name: "ip_family", text: &t.IPFamily},
+		{name: "credential", text: &t.Credential},
+	}
+}
+
+// validateWebhookTemplates checks that the required templates are set and
+// that every template parses.
+func validateWebhookTemplates(t *models.WebhookTemplate, filter string) error {
+	if _, err := parseWebhookTemplate("filter", filter); err != nil {
+		return err
+	}
+	for _, field := range webhookTemplateFields(t) {
+		if field.required && strings.TrimSpace(*field.text) == "" {
+			return &InvalidWebhookError{Message: fmt.Sprintf("template.%s is required", field.name)}
+		}
+		if _, err := parseWebhookTemplate("template."+field.name, *field.text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseWebhookTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(webhookFuncs).Parse(text)
+	if err != nil {
+		return nil, &InvalidWebhookError{Message: fmt.Sprintf("%s: %v", name, err)}
+	}
+	return tmpl, nil
+}
+
+// renderWebhookTemplate executes a template against a payload. Fields the
+// payload lacks are errors rather than empty strings.
+func renderWebhookTemplate(name, text string, payload any) (string, error) {
+	tmpl, err := parseWebhookTemplate(name, text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, payload); err != nil {
+		return "", &InvalidWebhookError{Message: fmt.Sprintf("%s: %v", name, err)}
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// InvalidWebhookError indicates that a webhook or one of its deliveries is
+// malformed.
+type InvalidWebhookError struct {
+	Message string
+}
+
+func (e *InvalidWebhookError) Error() string {
+	return e.Message
+}
+
+// WebhookExistsError indicates that a webhook name is taken.
+type WebhookExistsError struct {
+	Name string
+}
+
+func (e *WebhookExistsError) Error() string {
+	return fmt.Sprintf("webhook %q already exists", e.Name)
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+	"github.com/aloks98/isoman/backend/internal/testutil"
+)
+
+const testReleasePayload = `{
+	"action": "published",
+	"release": {"tag_name": "v2.4.0", "id": 123456789},
+	"repository": {"name": "Platform-Image"}
+}`
+
+func testWebhookRequest() models.CreateWebhookRequest {
+	return models.CreateWebhookRequest{
+		Name:   "platform",
+		Filter: `{{ eq .action "published" }}`,
+		Template: models.WebhookTemplate{
+			Name:        "{{ .repository.name | lower }}",
+			Version:     `{{ .release.tag_name | trimPrefix "v" }}`,
+			Arch:        "x86_64",
+			DownloadURL: "https://builds.example.com/{{ .release.id }}/image.iso",
+		},
+	}
+}
+
+func signWebhook(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhookService_CreateVerifyRender(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := NewWebhookService(env.DB, newTestCredentialService(t, env))
+
+	hook, err := svc.CreateWebhook(testWebhookRequest())
+	if err != nil {
+		t.Fatalf("CreateWebhook() failed: %v", err)
+	}
+	if hook.NewSecret == "" || !strings.HasPrefix(hook.Secret, "v1:") {
+		t.Fatalf("Expected a generated secret stored sealed, got %+v", hook)
+	}
+	if _, err := svc.CreateWebhook(testWebhookRequest()); err == nil {
+		t.Error("CreateWebhook() should reject a duplicate name")
+	}
+
+	stored, err := svc.GetWebhook("platform")
+	if err != nil {
+		t.Fatalf("GetWebhook() failed: %v", err)
+	}
+	if err := svc.Verify(stored, []byte(testReleasePayload), signWebhook(hook.NewSecret, testReleasePayload)); err != nil {
+		t.Errorf("Verify() rejected a valid signature: %v", err)
+	}
+	for _, signature := range []string{"", "sha256=zz", signWebhook("wrong", testReleasePayload), strings.TrimPrefix(signWebhook(hook.NewSecret, testReleasePayload), "sha256=")} {
+		if err := svc.Verify(stored, []byte(testReleasePayload), signature); !errors.Is(err, ErrWebhookSignature) {
+			t.Errorf("Verify(%q) = %v, want ErrWebhookSignature", signature, err)
+		}
+	}
+
+	req, err := svc.Render(stored, []byte(testReleasePayload))
+	if err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	want := CreateISORequest{Name: "platform-image", Version: "2.4.0", Arch: "x86_64", DownloadURL: "https://builds.example.com/123456789/image.iso"}
+	if *req != want {
+		t.Errorf("Render() = %+v, want %+v", *req, want)
+	}
+
+	if _, err := svc.Render(stored, []byte(strings.Replace(testReleasePayload, "published", "created", 1))); !errors.Is(err, ErrWebhookFiltered) {
+		t.Errorf("Expected ErrWebhookFiltered for another action, got: %v", err)
+	}
+	var invalidErr *InvalidWebhookError
+	if _, err := svc.Render(stored, []byte(`{"action": "published"}`)); !errors.As(err, &invalidErr) {
+		t.Errorf("Expected InvalidWebhookError for a payload missing fields, got: %v", err)
+	}
+	if _, err := svc.Render(stored, []byte(`not json`)); !errors.As(err, &invalidErr) {
+		t.Errorf("Expected InvalidWebhookError for a malformed payload, got: %v", err)
+	}
+}
+
+func TestWebhookService_Validation(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := NewWebhookService(env.DB, newTestCredentialService(t, env))
+
+	tests := []struct {
+		name   string
+		modify func(*models.CreateWebhookRequest)
+	}{
+		{"invalid name", func(r *models.CreateWebhookRequest) { r.Name = "Bad Name" }},
+		{"missing required template", func(r *models.CreateWebhookRequest) { r.Template.DownloadURL = "" }},
+		{"unparsable template", func(r *models.CreateWebhookRequest) { r.Template.Version = "{{ .release.tag_name" }},
+		{"unknown function", func(r *models.CreateWebhookRequest) { r.Filter = "{{ nope .action }}" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := testWebhookRequest()
+			tt.modify(&req)
+			var invalidErr *InvalidWebhookError
+			if _, err := svc.CreateWebhook(req); !errors.As(err, &invalidErr) {
+				t.Errorf("Expected InvalidWebhookError, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestWebhookService_RotateSecret(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := NewWebhookService(env.DB, newTestCredentialService(t, env))
+
+	hook, err := svc.CreateWebhook(testWebhookRequest())
+	if err != nil {
+		t.Fatalf("CreateWebhook() failed: %v", err)
+	}
+	rotated, err := svc.UpdateWebhook("platform", models.UpdateWebhookRequest{RotateSecret: true})
+	if err != nil {
+		t.Fatalf("UpdateWebhook() failed: %v", err)
+	}
+	if rotated.NewSecret == "" || rotated.NewSecret == hook.NewSecret {
+		t.Fatalf("Expected a new secret, got %q", rotated.NewSecret)
+	}
+	if err := svc.Verify(rotated, []byte("{}"), signWebhook(hook.NewSecret, "{}")); !errors.Is(err, ErrWebhookSignature) {
+		t.Errorf("Expected the old secret to stop working, got: %v", err)
+	}
+	if err := svc.Verify(rotated, []byte("{}"), signWebhook(rotated.NewSecret, "{}")); err != nil {
+		t.Errorf("Verify() rejected the rotated secret: %v", err)
+	}
+}
+
+func TestWebhookService_Disabled(t *testing.T) {
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup()
+	svc := NewWebhookService(env.DB, NewCredentialService(env.DB, nil))
+
+	if _, err := svc.CreateWebhook(testWebhookRequest()); !errors.Is(err, ErrWebhooksDisabled) {
+		t.Errorf("Expected ErrWebhooksDisabled, got: %v", err)
+	}
+}
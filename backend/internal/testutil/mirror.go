@@ -0,0 +1,325 @@
+package testutil
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Checksum file formats published by a FakeMirror. For a file at
+// /dir/name.iso they are served at:
+//
+//	ChecksumGNU   /dir/name.iso.sha256  "hash  name.iso"
+//	ChecksumSums  /dir/SHA256SUMS       "hash *name.iso" for every file in /dir
+//	ChecksumBSD   /dir/CHECKSUM         "SHA256 (name.iso) = hash" for every file in /dir
+//	ChecksumBare  /dir/name.iso.sha256.bare  the hash alone
+//
+// with sha512 and md5 in place of sha256 for the other checksum types, except
+// that CHECKSUM only lists SHA-256, like Fedora's.
+type ChecksumFormat int
+
+const (
+	ChecksumGNU ChecksumFormat = iota
+	ChecksumSums
+	ChecksumBSD
+	ChecksumBare
+)
+
+// MirrorOptions shapes how a FakeMirror responds. The zero value answers
+// every request at full speed.
+type MirrorOptions struct {
+	Latency        time.Duration // Before each response's headers
+	BytesPerSecond int           // Throttles file bodies; 0 is unlimited
+	FlakyResponses int           // Responses per file that break off after FailAfterBytes
+	FailAfterBytes int64         // Body bytes a flaky response sends before breaking off
+	Stall          bool          // Flaky responses hang until the client gives up instead of dropping the connection
+}
+
+// FakeMirror is an upstream mirror for download tests. It serves the files
+// added with AddFile, with Range and conditional requests, and checksum files
+// in several formats beside them. MirrorOptions make it slow or flaky, to
+// exercise the paths real mirrors trigger.
+type FakeMirror struct {
+	Server *httptest.Server
+
+	mu       sync.Mutex
+	options  MirrorOptions
+	files    map[string][]byte // By path, e.g. "/alpine/alpine.iso"
+	modTime  time.Time
+	flaky    map[string]int // Flaky responses left by path
+	requests []string       // Paths requested, in order
+}
+
+// NewFakeMirror starts a fake mirror that is closed when the test ends.
+func NewFakeMirror(t *testing.T, options MirrorOptions) *FakeMirror {
+	t.Helper()
+
+	m := &FakeMirror{
+		options: options,
+		files:   make(map[string][]byte),
+		modTime: time.Date(2024, 1, 26, 14, 45, 0, 0, time.UTC),
+		flaky:   make(map[string]int),
+	}
+	m.Server = httptest.NewServer(http.HandlerFunc(m.serve))
+	t.Cleanup(m.Server.Close)
+	return m
+}
+
+// AddFile publishes content at filePath, replacing any earlier content, and
+// resets its flaky responses.
+func (m *FakeMirror) AddFile(filePath string, content []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[filePath] = content
+	m.flaky[filePath] = m.options.FlakyResponses
+}
+
+// SetOptions changes how later requests are answered.
+func (m *FakeMirror) SetOptions(options MirrorOptions) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.options = options
+	for filePath := range m.files {
+		m.flaky[filePath] = options.FlakyResponses
+	}
+}
+
+// URL returns the mirror URL of filePath.
+func (m *FakeMirror) URL(filePath string) string {
+	return m.Server.URL + filePath
+}
+
+// ChecksumURL returns the URL of filePath's checksumType checksum in format.
+func (m *FakeMirror) ChecksumURL(filePath, checksumType string, format ChecksumFormat) string {
+	switch format {
+	case ChecksumSums:
+		return m.URL(path.Join(path.Dir(filePath), strings.ToUpper(checksumType)+"SUMS"))
+	case ChecksumBSD:
+		return m.URL(path.Join(path.Dir(filePath), "CHECKSUM"))
+	case ChecksumBare:
+		return m.URL(filePath + "." + checksumType + ".bare")
+	default:
+		return m.URL(filePath + "." + checksumType)
+	}
+}
+
+// Requests returns the paths requested so far, in order.
+func (m *FakeMirror) Requests() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.requests...)
+}
+
+// RequestCount returns how many times filePath was requested.
+func (m *FakeMirror) RequestCount(filePath string) int {
+	count := 0
+	for _, requested := range m.Requests() {
+		if requested == filePath {
+			count++
+		}
+	}
+	return count
+}
+
+// Transport returns a round tripper that sends every request to the mirror,
+// whatever its host, so tests can use realistic upstream URLs. Responses keep
+// the original request, so the URLs a client records are the ones it asked for.
+func (m *FakeMirror) Transport() http.RoundTripper {
+	target, _ := url.Parse(m.Server.URL) //nolint:errcheck // httptest URLs always parse
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		routed := req.Clone(req.Context())
+		routed.URL.Scheme = target.Scheme
+		routed.URL.Host = target.Host
+		routed.Host = ""
+		resp, err := m.Server.Client().Transport.RoundTrip(routed)
+		if resp != nil {
+			resp.Request = req
+		}
+		return resp, err
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func (m *FakeMirror) serve(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	m.requests = append(m.requests, r.URL.Path)
+	options := m.options
+	content, isFile := m.files[r.URL.Path]
+	flaky := isFile && m.flaky[r.URL.Path] > 0
+	if flaky {
+		m.flaky[r.URL.Path]--
+	}
+	if !isFile {
+		content, isFile = m.checksumFile(r.URL.Path), false
+	}
+	m.mu.Unlock()
+
+	if options.Latency > 0 {
+		select {
+		case <-time.After(options.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if content == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Server", "fake-mirror")
+	if !isFile {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write(content) //nolint:errcheck // Test utility, errors will cause test failures anyway
+		return
+	}
+
+	sum := sha256.Sum256(content)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:8])+`"`)
+	w.Header().Set("Content-Type", "application/octet-stream")
+
+	writer := &mirrorWriter{ResponseWriter: w, request: r, failAfter: -1}
+	if options.BytesPerSecond > 0 {
+		writer.chunk = max(options.BytesPerSecond/10, 1)
+	}
+	if flaky {
+		writer.failAfter = options.FailAfterBytes
+		writer.stall = options.Stall
+	}
+	http.ServeContent(writer, r, path.Base(r.URL.Path), m.modTime, bytes.NewReader(content))
+}
+
+// checksumFile returns the checksum file published at urlPath, or nil if
+// there is none. Callers hold m.mu.
+func (m *FakeMirror) checksumFile(urlPath string) []byte {
+	dir, name := path.Split(urlPath)
+
+	if name == "CHECKSUM" {
+		var buf bytes.Buffer
+		buf.WriteString("# Generated by fake-mirror\n")
+		for _, filePath := range m.filesIn(dir) {
+			fmt.Fprintf(&buf, "SHA256 (%s) = %s\n", path.Base(filePath), digest("sha256", m.files[filePath]))
+		}
+		return buf.Bytes()
+	}
+
+	for _, checksumType := range []string{"sha256", "sha512", "md5"} {
+		switch {
+		case name == strings.ToUpper(checksumType)+"SUMS":
+			var buf bytes.Buffer
+			for _, filePath := range m.filesIn(dir) {
+				fmt.Fprintf(&buf, "%s *%s\n", digest(checksumType, m.files[filePath]), path.Base(filePath))
+			}
+			return buf.Bytes()
+		case strings.HasSuffix(urlPath, "."+checksumType):
+			filePath := strings.TrimSuffix(urlPath, "."+checksumType)
+			if content, ok := m.files[filePath]; ok {
+				return fmt.Appendf(nil, "%s  %s\n", digest(checksumType, content), path.Base(filePath))
+			}
+		case strings.HasSuffix(urlPath, "."+checksumType+".bare"):
+			filePath := strings.TrimSuffix(urlPath, "."+checksumType+".bare")
+			if content, ok := m.files[filePath]; ok {
+				return []byte(digest(checksumType, content) + "\n")
+			}
+		}
+	}
+	return nil
+}
+
+// filesIn returns the paths of the files directly in dir, sorted. Callers hold m.mu.
+func (m *FakeMirror) filesIn(dir string) []string {
+	var paths []string
+	for filePath := range m.files {
+		if path.Dir(filePath)+"/" == dir {
+			paths = append(paths, filePath)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// digest returns the hex checksumType digest of content.
+func digest(checksumType string, content []byte) string {
+	var h hash.Hash
+	switch checksumType {
+	case "sha512":
+		h = sha512.New()
+	case "md5":
+		h = md5.New()
+	default:
+		h = sha256.New()
+	}
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// mirrorWriter throttles a response body and breaks it off after failAfter
+// bytes, by dropping the connection or hanging until the client gives up.
+type mirrorWriter struct {
+	http.ResponseWriter
+	request   *http.Request
+	chunk     int   // Bytes written per tenth of a second; 0 is unthrottled
+	failAfter int64 // -1 never breaks off
+	stall     bool
+	sent      int64
+}
+
+func (w *mirrorWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := len(p)
+		if w.chunk > 0 {
+			n = min(n, w.chunk)
+		}
+		if w.failAfter >= 0 {
+			n = int(min(int64(n), w.failAfter-w.sent))
+			if n == 0 {
+				w.breakOff()
+			}
+		}
+
+		n, err := w.ResponseWriter.Write(p[:n])
+		written += n
+		w.sent += int64(n)
+		p = p[n:]
+		if err != nil {
+			return written, err
+		}
+		if w.chunk > 0 {
+			http.NewResponseController(w.ResponseWriter).Flush() //nolint:errcheck // Best effort; the body still arrives unflushed
+			select {
+			case <-time.After(100 * time.Millisecond):
+			case <-w.request.Context().Done():
+				return written, w.request.Context().Err()
+			}
+		}
+	}
+	return written, nil
+}
+
+// breakOff ends a flaky response: the bytes sent so far are flushed, then the
+// connection hangs until the client gives up, or is dropped.
+func (w *mirrorWriter) breakOff() {
+	http.NewResponseController(w.ResponseWriter).Flush() //nolint:errcheck // Best effort; the client sees the break either way
+	if w.stall {
+		<-w.request.Context().Done()
+	}
+	panic(http.ErrAbortHandler)
+}
@@ -0,0 +1,113 @@
+// Package throughput measures aggregate download (ingest) and serve (egress) rates.
+package throughput
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+// Meter counts bytes moved in one direction. A nil Meter ignores updates.
+type Meter struct {
+	total atomic.Int64
+}
+
+// Add records n transferred bytes.
+func (m *Meter) Add(n int64) {
+	if m == nil || n <= 0 {
+		return
+	}
+	m.total.Add(n)
+}
+
+// Total returns the bytes recorded since the meter was created.
+func (m *Meter) Total() int64 {
+	if m == nil {
+		return 0
+	}
+	return m.total.Load()
+}
+
+// Gauge turns the ingest and egress meters into rates, sampled periodically.
+type Gauge struct {
+	Ingest Meter
+	Egress Meter
+
+	mu         sync.RWMutex
+	current    models.LiveThroughput
+	lastIngest int64
+	lastEgress int64
+	lastAt     time.Time
+}
+
+// NewGauge creates a gauge whose first sample covers the time since creation.
+func NewGauge() *Gauge {
+	return &Gauge{lastAt: time.Now()}
+}
+
+// Sample computes the rates since the previous sample and stores the result.
+func (g *Gauge) Sample(now time.Time) models.LiveThroughput {
+	ingest, egress := g.Ingest.Total(), g.Egress.Total()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	elapsed := now.Sub(g.lastAt)
+	sample := models.LiveThroughput{
+		SampledAt:        now,
+		IngestBytesTotal: ingest,
+		EgressBytesTotal: egress,
+	}
+	if elapsed > 0 {
+		sample.IngestBytesPerSec = bytesPerSec(ingest-g.lastIngest, elapsed)
+		sample.EgressBytesPerSec = bytesPerSec(egress-g.lastEgress, elapsed)
+	}
+
+	g.current = sample
+	g.lastIngest, g.lastEgress, g.lastAt = ingest, egress, now
+	return sample
+}
+
+// Current returns the latest sample. Totals are always up to date.
+func (g *Gauge) Current() models.LiveThroughput {
+	g.mu.RLock()
+	sample := g.current
+	g.mu.RUnlock()
+
+	sample.IngestBytesTotal = g.Ingest.Total()
+	sample.EgressBytesTotal = g.Egress.Total()
+	return sample
+}
+
+// Start samples the gauge every interval until ctx is canceled, passing each
+// sample to onSample when it is non-nil. A zero interval disables sampling.
+func (g *Gauge) Start(ctx context.Context, interval time.Duration, onSample func(models.LiveThroughput)) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				sample := g.Sample(now)
+				if onSample != nil {
+					onSample(sample)
+				}
+			}
+		}
+	}()
+}
+
+// bytesPerSec converts a byte count over elapsed into a per-second rate.
+func bytesPerSec(n int64, elapsed time.Duration) int64 {
+	return int64(float64(n) / elapsed.Seconds())
+}
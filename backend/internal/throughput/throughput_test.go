@@ -0,0 +1,73 @@
+package throughput
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aloks98/isoman/backend/internal/models"
+)
+
+func TestGaugeSample(t *testing.T) {
+	g := NewGauge()
+	start := time.Now()
+	g.Sample(start)
+
+	g.Ingest.Add(4000)
+	g.Egress.Add(1000)
+	g.Egress.Add(-5) // Ignored
+
+	sample := g.Sample(start.Add(2 * time.Second))
+	if sample.IngestBytesPerSec != 2000 {
+		t.Errorf("Expected ingest 2000 B/s, got %d", sample.IngestBytesPerSec)
+	}
+	if sample.EgressBytesPerSec != 500 {
+		t.Errorf("Expected egress 500 B/s, got %d", sample.EgressBytesPerSec)
+	}
+
+	// Idle interval drops the rates back to zero but keeps the totals
+	sample = g.Sample(start.Add(4 * time.Second))
+	if sample.IngestBytesPerSec != 0 || sample.EgressBytesPerSec != 0 {
+		t.Errorf("Expected zero rates when idle, got %+v", sample)
+	}
+	if sample.IngestBytesTotal != 4000 || sample.EgressBytesTotal != 1000 {
+		t.Errorf("Expected totals to be kept, got %+v", sample)
+	}
+
+	g.Ingest.Add(10)
+	if current := g.Current(); current.IngestBytesTotal != 4010 || current.IngestBytesPerSec != 0 {
+		t.Errorf("Current() should report live totals with the last rates, got %+v", current)
+	}
+}
+
+func TestNilMeter(t *testing.T) {
+	var m *Meter
+	m.Add(100)
+	if m.Total() != 0 {
+		t.Error("Nil meter should report zero")
+	}
+}
+
+func TestGaugeStart(t *testing.T) {
+	g := NewGauge()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	samples := make(chan models.LiveThroughput, 1)
+	g.Start(ctx, 10*time.Millisecond, func(s models.LiveThroughput) {
+		select {
+		case samples <- s:
+		default:
+		}
+	})
+	g.Ingest.Add(1024)
+
+	select {
+	case s := <-samples:
+		if s.SampledAt.IsZero() {
+			t.Error("Sample should be timestamped")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a sample from the running gauge")
+	}
+}
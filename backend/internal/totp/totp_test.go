@@ -0,0 +1,79 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors, base32-encoded.
+var rfcSecret = encoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeRFC6238(t *testing.T) {
+	// RFC 6238 Appendix B lists 8-digit codes; the last 6 digits are the 6-digit codes
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code() failed: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("Code(T=%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current, _ := Code(rfcSecret, Step(now))
+	previous, _ := Code(rfcSecret, Step(now)-1)
+	old, _ := Code(rfcSecret, Step(now)-3)
+
+	if step, ok := Validate(rfcSecret, current, now, 1); !ok || step != Step(now) {
+		t.Errorf("Validate(current) = %d, %v; want %d, true", step, ok, Step(now))
+	}
+	if step, ok := Validate(rfcSecret, previous, now, 1); !ok || step != Step(now)-1 {
+		t.Errorf("Validate(previous) = %d, %v; want the previous step within skew", step, ok)
+	}
+	if _, ok := Validate(rfcSecret, old, now, 1); ok {
+		t.Error("Validate() accepted a code outside the skew window")
+	}
+	if _, ok := Validate(rfcSecret, current[:3]+" "+current[3:], now, 0); !ok {
+		t.Error("Validate() should ignore spaces")
+	}
+	if _, ok := Validate(rfcSecret, "12345", now, 1); ok {
+		t.Error("Validate() accepted a short code")
+	}
+	if _, ok := Validate("not base32!", current, now, 1); ok {
+		t.Error("Validate() accepted an invalid secret")
+	}
+}
+
+func TestGenerateSecretAndURL(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() failed: %v", err)
+	}
+	if len(secret) != 32 {
+		t.Errorf("Expected a 32-character base32 secret, got %q", secret)
+	}
+	if _, err := Code(secret, 1); err != nil {
+		t.Errorf("Generated secret doesn't decode: %v", err)
+	}
+
+	u := URL("ISOMan", "admin", secret)
+	if !strings.HasPrefix(u, "otpauth://totp/ISOMan:admin?") || !strings.Contains(u, "secret="+secret) {
+		t.Errorf("Unexpected otpauth URL: %s", u)
+	}
+}
@@ -1,22 +1,42 @@
 package validation
 
 import (
+	"context"
+	"encoding/hex"
 	"fmt"
 	"net/url"
 	"strings"
 
 	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/models"
 )
 
 // ISOCreateRequest validation.
 type ISOCreateRequest struct {
-	Name         string `json:"name"`
-	Version      string `json:"version"`
-	Arch         string `json:"arch"`
-	Edition      string `json:"edition"`
-	DownloadURL  string `json:"download_url"`
-	ChecksumURL  string `json:"checksum_url"`
-	ChecksumType string `json:"checksum_type"`
+	Name             string `json:"name"`
+	Version          string `json:"version"`
+	Arch             string `json:"arch"`
+	Edition          string `json:"edition"`
+	DownloadURL      string `json:"download_url"`
+	ChecksumURL      string `json:"checksum_url"`
+	ExpectedChecksum string `json:"expected_checksum"`
+	SignatureURL     string `json:"signature_url"`
+	ChecksumType     string `json:"checksum_type"`
+	IPFamily         string `json:"ip_family"`
+	Credential       string `json:"credential"`
+	Preset           string `json:"-"` // Set when expanded from a preset, never by clients
+	Overwrite        bool   `json:"-"` // Set from the overwrite query parameter
+}
+
+// ISOPreviewRequest validation. The file type comes from FileType when set,
+// otherwise from the extension of DownloadURL.
+type ISOPreviewRequest struct {
+	Name        string
+	Version     string
+	Arch        string
+	Edition     string
+	DownloadURL string
+	FileType    string
 }
 
 // ValidationError represents a validation error.
@@ -56,39 +76,21 @@ func (e *ValidationErrors) HasErrors() bool {
 	return len(e.Errors) > 0
 }
 
-// ValidateISOCreateRequest validates an ISO create request.
+// ValidateISOCreateRequest validates an ISO create request without live URL checks.
 func ValidateISOCreateRequest(req *ISOCreateRequest) error {
+	return ValidateISOCreateRequestWithChecks(context.Background(), req, nil)
+}
+
+// ValidateISOCreateRequestWithChecks validates an ISO create request and runs
+// the URL checks enabled in opts on syntactically valid URLs. A nil opts
+// skips them.
+func ValidateISOCreateRequestWithChecks(ctx context.Context, req *ISOCreateRequest, opts *URLCheckOptions) error {
 	if req == nil {
 		return fmt.Errorf("request cannot be nil")
 	}
 
 	errs := &ValidationErrors{}
-
-	// Validate name
-	if strings.TrimSpace(req.Name) == "" {
-		errs.Add("name", "name is required")
-	} else if len(req.Name) > 100 {
-		errs.Add("name", "name must be 100 characters or less")
-	}
-
-	// Validate version
-	if strings.TrimSpace(req.Version) == "" {
-		errs.Add("version", "version is required")
-	} else if len(req.Version) > 50 {
-		errs.Add("version", "version must be 50 characters or less")
-	}
-
-	// Validate arch
-	if strings.TrimSpace(req.Arch) == "" {
-		errs.Add("arch", "arch is required")
-	} else if len(req.Arch) > 20 {
-		errs.Add("arch", "arch must be 20 characters or less")
-	}
-
-	// Validate edition (optional)
-	if len(req.Edition) > 50 {
-		errs.Add("edition", "edition must be 50 characters or less")
-	}
+	validateIdentity(errs, req.Name, req.Version, req.Arch, req.Edition)
 
 	// Validate download URL
 	if strings.TrimSpace(req.DownloadURL) == "" {
@@ -97,6 +99,8 @@ func ValidateISOCreateRequest(req *ISOCreateRequest) error {
 		errs.Add("download_url", "download_url must be 2048 characters or less")
 	} else if !isValidHTTPURL(req.DownloadURL) {
 		errs.Add("download_url", "download_url must be a valid HTTP or HTTPS URL")
+	} else {
+		CheckURLField(ctx, errs, "download_url", req.DownloadURL, opts)
 	}
 
 	// Validate checksum URL (optional)
@@ -105,6 +109,19 @@ func ValidateISOCreateRequest(req *ISOCreateRequest) error {
 			errs.Add("checksum_url", "checksum_url must be 2048 characters or less")
 		} else if !isValidHTTPURL(req.ChecksumURL) {
 			errs.Add("checksum_url", "checksum_url must be a valid HTTP or HTTPS URL")
+		} else {
+			CheckURLField(ctx, errs, "checksum_url", req.ChecksumURL, opts)
+		}
+	}
+
+	// Validate signature URL (optional)
+	if req.SignatureURL != "" {
+		if len(req.SignatureURL) > 2048 {
+			errs.Add("signature_url", "signature_url must be 2048 characters or less")
+		} else if !isValidHTTPURL(req.SignatureURL) {
+			errs.Add("signature_url", "signature_url must be a valid HTTP or HTTPS URL")
+		} else {
+			CheckURLField(ctx, errs, "signature_url", req.SignatureURL, opts)
 		}
 	}
 
@@ -113,6 +130,24 @@ func ValidateISOCreateRequest(req *ISOCreateRequest) error {
 		errs.Add("checksum_type", fmt.Sprintf("checksum_type must be one of: %v", constants.ChecksumTypes))
 	}
 
+	// Validate expected checksum (optional)
+	if req.ExpectedChecksum != "" {
+		if req.ChecksumURL != "" {
+			errs.Add("expected_checksum", "set either expected_checksum or checksum_url, not both")
+		} else {
+			CheckExpectedChecksum(errs, req.ExpectedChecksum, req.ChecksumType)
+		}
+	}
+
+	// Validate IP family (optional)
+	if req.IPFamily != "" && !constants.IsValidIPFamily(req.IPFamily) {
+		errs.Add("ip_family", fmt.Sprintf("ip_family must be one of: %v", constants.IPFamilies))
+	}
+
+	if req.Credential != "" && !models.IsValidCredentialName(req.Credential) {
+		errs.Add("credential", "credential must be the name of a stored credential")
+	}
+
 	if errs.HasErrors() {
 		return errs
 	}
@@ -120,7 +155,79 @@ func ValidateISOCreateRequest(req *ISOCreateRequest) error {
 	return nil
 }
 
+// ValidateISOPreviewRequest validates an ISO preview request. It takes
+// either a download URL or a file type, and runs no live URL checks.
+func ValidateISOPreviewRequest(req *ISOPreviewRequest) error {
+	if req == nil {
+		return fmt.Errorf("request cannot be nil")
+	}
+
+	errs := &ValidationErrors{}
+	validateIdentity(errs, req.Name, req.Version, req.Arch, req.Edition)
+
+	switch {
+	case req.FileType != "":
+		if !constants.IsSupportedFileType(req.FileType) {
+			errs.Add("file_type", fmt.Sprintf("file_type must be one of: %v", constants.SupportedFileTypes))
+		}
+	case strings.TrimSpace(req.DownloadURL) == "":
+		errs.Add("download_url", "download_url or file_type is required")
+	case len(req.DownloadURL) > 2048:
+		errs.Add("download_url", "download_url must be 2048 characters or less")
+	case !isValidHTTPURL(req.DownloadURL):
+		errs.Add("download_url", "download_url must be a valid HTTP or HTTPS URL")
+	}
+
+	if errs.HasErrors() {
+		return errs
+	}
+	return nil
+}
+
+// validateIdentity validates the fields that make up an ISO's unique key.
+func validateIdentity(errs *ValidationErrors, name, version, arch, edition string) {
+	if strings.TrimSpace(name) == "" {
+		errs.Add("name", "name is required")
+	} else if len(name) > 100 {
+		errs.Add("name", "name must be 100 characters or less")
+	}
+
+	if strings.TrimSpace(version) == "" {
+		errs.Add("version", "version is required")
+	} else if len(version) > 50 {
+		errs.Add("version", "version must be 50 characters or less")
+	}
+
+	if strings.TrimSpace(arch) == "" {
+		errs.Add("arch", "arch is required")
+	} else if len(arch) > 20 {
+		errs.Add("arch", "arch must be 20 characters or less")
+	}
+
+	// Edition is optional
+	if len(edition) > 50 {
+		errs.Add("edition", "edition must be 50 characters or less")
+	}
+}
+
 // isValidHTTPURL checks if a string is a valid HTTP or HTTPS URL.
+// CheckExpectedChecksum adds an error to errs unless checksum is a hex digest
+// of checksumType, or of any supported type when checksumType is empty.
+func CheckExpectedChecksum(errs *ValidationErrors, checksum, checksumType string) {
+	checksum = strings.TrimSpace(checksum)
+	if _, err := hex.DecodeString(checksum); err != nil {
+		errs.Add("expected_checksum", "expected_checksum must be a hexadecimal digest")
+		return
+	}
+	detected := constants.ChecksumTypeForDigest(checksum)
+	switch {
+	case detected == "":
+		errs.Add("expected_checksum", "expected_checksum must be an MD5, SHA-256, or SHA-512 digest")
+	case checksumType != "" && !strings.EqualFold(checksumType, detected):
+		errs.Add("expected_checksum", fmt.Sprintf("expected_checksum is a %s digest, not %s", detected, strings.ToLower(checksumType)))
+	}
+}
+
 func isValidHTTPURL(urlStr string) bool {
 	u, err := url.Parse(urlStr)
 	if err != nil {
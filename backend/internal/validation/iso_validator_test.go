@@ -1,6 +1,7 @@
 package validation
 
 import (
+	"errors"
 	"strings"
 	"testing"
 )
@@ -135,6 +136,18 @@ func TestValidateISOCreateRequest(t *testing.T) {
 			wantErr: true,
 			errMsg:  "checksum_url",
 		},
+		{
+			name: "invalid signature URL",
+			req: &ISOCreateRequest{
+				Name:         "Test",
+				Version:      "1.0",
+				Arch:         "x86_64",
+				DownloadURL:  "https://example.com/test.iso",
+				SignatureURL: "ftp://example.com/test.iso.sig",
+			},
+			wantErr: true,
+			errMsg:  "signature_url",
+		},
 		{
 			name: "invalid checksum type",
 			req: &ISOCreateRequest{
@@ -148,6 +161,67 @@ func TestValidateISOCreateRequest(t *testing.T) {
 			wantErr: true,
 			errMsg:  "checksum_type",
 		},
+		{
+			name: "valid expected checksum",
+			req: &ISOCreateRequest{
+				Name:             "Test",
+				Version:          "1.0",
+				Arch:             "x86_64",
+				DownloadURL:      "https://example.com/test.iso",
+				ExpectedChecksum: "E3B0C44298FC1C149AFBF4C8996FB92427AE41E4649B934CA495991B7852B855",
+			},
+			wantErr: false,
+		},
+		{
+			name: "expected checksum with checksum URL",
+			req: &ISOCreateRequest{
+				Name:             "Test",
+				Version:          "1.0",
+				Arch:             "x86_64",
+				DownloadURL:      "https://example.com/test.iso",
+				ChecksumURL:      "https://example.com/test.iso.sha256",
+				ExpectedChecksum: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			},
+			wantErr: true,
+			errMsg:  "expected_checksum",
+		},
+		{
+			name: "expected checksum not hex",
+			req: &ISOCreateRequest{
+				Name:             "Test",
+				Version:          "1.0",
+				Arch:             "x86_64",
+				DownloadURL:      "https://example.com/test.iso",
+				ExpectedChecksum: "https://example.com/test.iso.sha256",
+			},
+			wantErr: true,
+			errMsg:  "hexadecimal",
+		},
+		{
+			name: "expected checksum of another type",
+			req: &ISOCreateRequest{
+				Name:             "Test",
+				Version:          "1.0",
+				Arch:             "x86_64",
+				DownloadURL:      "https://example.com/test.iso",
+				ExpectedChecksum: "d41d8cd98f00b204e9800998ecf8427e",
+				ChecksumType:     "sha256",
+			},
+			wantErr: true,
+			errMsg:  "md5 digest",
+		},
+		{
+			name: "invalid ip family",
+			req: &ISOCreateRequest{
+				Name:        "Test",
+				Version:     "1.0",
+				Arch:        "x86_64",
+				DownloadURL: "https://example.com/test.iso",
+				IPFamily:    "ipv5",
+			},
+			wantErr: true,
+			errMsg:  "ip_family",
+		},
 		{
 			name: "very long name",
 			req: &ISOCreateRequest{
@@ -313,6 +387,37 @@ func TestValidationErrors(t *testing.T) {
 	}
 }
 
+func TestValidateISOPreviewRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       ISOPreviewRequest
+		wantField string // Empty when the request is valid
+	}{
+		{"download URL", ISOPreviewRequest{Name: "alpine", Version: "3.19.1", Arch: "x86_64", DownloadURL: "https://example.com/alpine.iso"}, ""},
+		{"file type", ISOPreviewRequest{Name: "alpine", Version: "3.19.1", Arch: "x86_64", FileType: "qcow2"}, ""},
+		{"neither", ISOPreviewRequest{Name: "alpine", Version: "3.19.1", Arch: "x86_64"}, "download_url"},
+		{"unsupported file type", ISOPreviewRequest{Name: "alpine", Version: "3.19.1", Arch: "x86_64", FileType: "exe"}, "file_type"},
+		{"invalid URL", ISOPreviewRequest{Name: "alpine", Version: "3.19.1", Arch: "x86_64", DownloadURL: "ftp://example.com/a.iso"}, "download_url"},
+		{"missing arch", ISOPreviewRequest{Name: "alpine", Version: "3.19.1", FileType: "iso"}, "arch"},
+		{"long edition", ISOPreviewRequest{Name: "alpine", Version: "3.19.1", Arch: "x86_64", FileType: "iso", Edition: strings.Repeat("e", 51)}, "edition"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateISOPreviewRequest(&tt.req)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			var errs *ValidationErrors
+			if !errors.As(err, &errs) || len(errs.Errors) != 1 || errs.Errors[0].Field != tt.wantField {
+				t.Errorf("Expected one %s error, got: %v", tt.wantField, err)
+			}
+		})
+	}
+}
+
 func TestValidateISOCreateRequestNilRequest(t *testing.T) {
 	err := ValidateISOCreateRequest(nil)
 	if err == nil {
@@ -0,0 +1,164 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// Resolver looks up the addresses of a host. *net.Resolver satisfies it.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// URLCheckOptions enables optional checks of user-supplied URLs beyond their
+// syntax. The zero value performs none.
+type URLCheckOptions struct {
+	Resolver        Resolver       // nil uses net.DefaultResolver
+	AllowedSchemes  []string       // Empty allows http and https
+	ResolveHost     bool           // Require the hostname to resolve
+	BlockPrivateIPs bool           // Reject hosts with a loopback, private, or link-local address; implies ResolveHost
+	AllowedNetworks []netip.Prefix // Exempt from BlockPrivateIPs
+}
+
+// blockedPrefixes are address ranges that must not be reachable through a
+// user-supplied URL on top of the ones net.IP classifies as private or local.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("100.64.0.0/10"), // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // Benchmarking
+}
+
+// IsBlockedIP reports whether ip is loopback, private, link-local, unspecified,
+// or otherwise not a public unicast address.
+func IsBlockedIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return true
+	}
+	addr = addr.Unmap()
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// InNetworks reports whether ip falls inside any of networks.
+func InNetworks(ip net.IP, networks []netip.Prefix) bool {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, network := range networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseNetworks parses CIDR ranges and bare IP addresses. Invalid entries are
+// skipped and reported together in the returned error.
+func ParseNetworks(entries []string) ([]netip.Prefix, error) {
+	var networks []netip.Prefix
+	var errs []error
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			networks = append(networks, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		addr = addr.Unmap()
+		networks = append(networks, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return networks, errors.Join(errs...)
+}
+
+// CheckURL runs the checks enabled in opts against rawURL, which must already
+// be a syntactically valid HTTP(S) URL. It returns a message for the client
+// when the URL is rejected, or an empty string.
+func CheckURL(ctx context.Context, rawURL string, opts *URLCheckOptions) string {
+	if opts == nil {
+		return ""
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "must be a valid URL"
+	}
+
+	if len(opts.AllowedSchemes) > 0 && !schemeAllowed(u.Scheme, opts.AllowedSchemes) {
+		return fmt.Sprintf("scheme must be one of: %v", opts.AllowedSchemes)
+	}
+
+	if !opts.ResolveHost && !opts.BlockPrivateIPs {
+		return ""
+	}
+
+	host := u.Hostname()
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		resolver := opts.Resolver
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+		addrs, err := resolver.LookupIPAddr(ctx, host)
+		if err != nil || len(addrs) == 0 {
+			return fmt.Sprintf("host %q does not resolve", host)
+		}
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+	}
+
+	if opts.BlockPrivateIPs {
+		for _, ip := range ips {
+			if IsBlockedIP(ip) && !InNetworks(ip, opts.AllowedNetworks) {
+				return fmt.Sprintf("host %q resolves to a private or reserved address", host)
+			}
+		}
+	}
+	return ""
+}
+
+// CheckURLField adds a validation error for field when CheckURL rejects rawURL.
+func CheckURLField(ctx context.Context, errs *ValidationErrors, field, rawURL string, opts *URLCheckOptions) {
+	if msg := CheckURL(ctx, rawURL, opts); msg != "" {
+		errs.Add(field, field+" "+msg)
+	}
+}
+
+func schemeAllowed(scheme string, allowed []string) bool {
+	for _, s := range allowed {
+		if strings.EqualFold(strings.TrimSpace(s), scheme) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+// fakeResolver resolves hosts from a fixed table.
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	addrs := make([]net.IPAddr, len(ips))
+	for i, ip := range ips {
+		addrs[i] = net.IPAddr{IP: net.ParseIP(ip)}
+	}
+	return addrs, nil
+}
+
+func TestIsBlockedIP(t *testing.T) {
+	tests := []struct {
+		ip      string
+		blocked bool
+	}{
+		{"93.184.216.34", false},
+		{"2606:2800:220:1:248:1893:25c8:1946", false},
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true}, // Cloud metadata
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"::1", true},
+		{"fd00::1", true},
+		{"fe80::1", true},
+		{"::ffff:127.0.0.1", true},
+	}
+
+	for _, tt := range tests {
+		if got := IsBlockedIP(net.ParseIP(tt.ip)); got != tt.blocked {
+			t.Errorf("IsBlockedIP(%s) = %v, want %v", tt.ip, got, tt.blocked)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	resolver := fakeResolver{
+		"mirror.example.com":   {"93.184.216.34"},
+		"internal.example.com": {"93.184.216.34", "10.0.0.5"},
+	}
+
+	tests := []struct {
+		name    string
+		url     string
+		opts    *URLCheckOptions
+		wantMsg string
+	}{
+		{"NilOptions", "http://localhost/a.iso", nil, ""},
+		{"ZeroOptions", "http://localhost/a.iso", &URLCheckOptions{}, ""},
+		{"SchemeAllowed", "https://mirror.example.com/a.iso", &URLCheckOptions{AllowedSchemes: []string{"https"}}, ""},
+		{"SchemeRejected", "http://mirror.example.com/a.iso", &URLCheckOptions{AllowedSchemes: []string{"https"}}, "scheme must be one of"},
+		{"Resolves", "https://mirror.example.com/a.iso", &URLCheckOptions{ResolveHost: true, Resolver: resolver}, ""},
+		{"DoesNotResolve", "https://missing.example.com/a.iso", &URLCheckOptions{ResolveHost: true, Resolver: resolver}, "does not resolve"},
+		{"PublicAddress", "https://mirror.example.com/a.iso", &URLCheckOptions{BlockPrivateIPs: true, Resolver: resolver}, ""},
+		{"AnyPrivateAddress", "https://internal.example.com/a.iso", &URLCheckOptions{BlockPrivateIPs: true, Resolver: resolver}, "private or reserved"},
+		{"LiteralPrivateIP", "http://169.254.169.254/latest/meta-data", &URLCheckOptions{BlockPrivateIPs: true, Resolver: resolver}, "private or reserved"},
+		{"LiteralIPv6Loopback", "http://[::1]:8080/a.iso", &URLCheckOptions{BlockPrivateIPs: true, Resolver: resolver}, "private or reserved"},
+		{"AllowedNetwork", "https://internal.example.com/a.iso", &URLCheckOptions{BlockPrivateIPs: true, Resolver: resolver, AllowedNetworks: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckURL(context.Background(), tt.url, tt.opts)
+			if tt.wantMsg == "" && got != "" {
+				t.Errorf("CheckURL() = %q, want no error", got)
+			}
+			if tt.wantMsg != "" && !strings.Contains(got, tt.wantMsg) {
+				t.Errorf("CheckURL() = %q, want it to contain %q", got, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestParseNetworks(t *testing.T) {
+	networks, err := ParseNetworks([]string{"10.0.0.0/8", " 192.168.1.7 ", "", "fd00::/8", "bogus", "10.0.0.0/99"})
+	if err == nil {
+		t.Error("Expected an error for the invalid entries")
+	}
+
+	want := []string{"10.0.0.0/8", "192.168.1.7/32", "fd00::/8"}
+	if len(networks) != len(want) {
+		t.Fatalf("Expected %d networks, got: %v", len(want), networks)
+	}
+	for i, network := range networks {
+		if network.String() != want[i] {
+			t.Errorf("networks[%d] = %s, want %s", i, network, want[i])
+		}
+	}
+
+	if !InNetworks(net.ParseIP("10.1.2.3"), networks) || !InNetworks(net.ParseIP("::ffff:192.168.1.7"), networks) {
+		t.Error("Expected addresses inside the networks to match")
+	}
+	if InNetworks(net.ParseIP("192.168.1.8"), networks) {
+		t.Error("Expected an address outside the networks not to match")
+	}
+}
+
+func TestValidateISOCreateRequestWithChecks(t *testing.T) {
+	opts := &URLCheckOptions{BlockPrivateIPs: true, Resolver: fakeResolver{}}
+	req := &ISOCreateRequest{
+		Name:        "Alpine",
+		Version:     "3.19.1",
+		Arch:        "x86_64",
+		DownloadURL: "http://127.0.0.1/alpine.iso",
+		ChecksumURL: "http://10.0.0.1/alpine.iso.sha256",
+	}
+
+	err := ValidateISOCreateRequestWithChecks(context.Background(), req, opts)
+	var errs *ValidationErrors
+	if !errors.As(err, &errs) || len(errs.Errors) != 2 {
+		t.Fatalf("Expected download_url and checksum_url errors, got: %v", err)
+	}
+	if errs.Errors[0].Field != "download_url" || errs.Errors[1].Field != "checksum_url" {
+		t.Errorf("Unexpected fields: %+v", errs.Errors)
+	}
+
+	if err := ValidateISOCreateRequest(req); err != nil {
+		t.Errorf("ValidateISOCreateRequest() should skip live checks, got: %v", err)
+	}
+}
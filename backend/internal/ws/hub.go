@@ -10,8 +10,10 @@ import (
 
 // Message types for WebSocket communication.
 const (
-	MessageTypeProgress = "progress"
-	MessageTypeStatus   = "status"
+	MessageTypeProgress   = "progress"
+	MessageTypeStatus     = "status"
+	MessageTypeThroughput = "throughput"
+	MessageTypeQueue      = "queue"
 )
 
 // Message represents a WebSocket message.
@@ -125,6 +127,54 @@ func (h *Hub) BroadcastProgress(isoID string, progress int, status models.ISOSta
 	}
 }
 
+// BroadcastThroughput sends a live throughput sample to all connected clients.
+// Samples are skipped while nobody is connected.
+func (h *Hub) BroadcastThroughput(sample models.LiveThroughput) {
+	if h.ClientCount() == 0 {
+		return
+	}
+
+	data, err := json.Marshal(Message{
+		Type:    MessageTypeThroughput,
+		Payload: sample,
+	})
+	if err != nil {
+		slog.Error("failed to marshal throughput message", slog.Any("error", err))
+		return
+	}
+
+	select {
+	case h.broadcast <- data:
+	default:
+		// The next sample supersedes this one anyway
+		slog.Debug("broadcast channel full, skipping throughput sample")
+	}
+}
+
+// BroadcastQueue sends a queue forecast to all connected clients. Forecasts
+// are skipped while nobody is connected.
+func (h *Hub) BroadcastQueue(forecast *models.QueueForecast) {
+	if h.ClientCount() == 0 {
+		return
+	}
+
+	data, err := json.Marshal(Message{
+		Type:    MessageTypeQueue,
+		Payload: forecast,
+	})
+	if err != nil {
+		slog.Error("failed to marshal queue message", slog.Any("error", err))
+		return
+	}
+
+	select {
+	case h.broadcast <- data:
+	default:
+		// The next queue event sends a fresher forecast
+		slog.Debug("broadcast channel full, skipping queue forecast")
+	}
+}
+
 // ClientCount returns the number of connected clients.
 func (h *Hub) ClientCount() int {
 	h.mu.RLock()
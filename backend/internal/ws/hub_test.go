@@ -1,6 +1,7 @@
 package ws
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -177,6 +178,75 @@ func TestHubBroadcastToClients(t *testing.T) {
 	}
 }
 
+// TestHubBroadcastThroughput tests that throughput samples reach clients and are skipped without any.
+func TestHubBroadcastThroughput(t *testing.T) {
+	hub := NewHub()
+
+	// No clients: nothing is queued
+	hub.BroadcastThroughput(models.LiveThroughput{IngestBytesPerSec: 1})
+	if len(hub.broadcast) != 0 {
+		t.Fatal("Throughput should not be broadcast without clients")
+	}
+
+	go hub.Run()
+	client := &Client{hub: hub, send: make(chan []byte, 256)}
+	hub.register <- client
+	time.Sleep(10 * time.Millisecond)
+
+	hub.BroadcastThroughput(models.LiveThroughput{IngestBytesPerSec: 2048, EgressBytesPerSec: 512})
+	time.Sleep(10 * time.Millisecond)
+
+	select {
+	case msg := <-client.send:
+		var decoded struct {
+			Type    string                `json:"type"`
+			Payload models.LiveThroughput `json:"payload"`
+		}
+		if err := json.Unmarshal(msg, &decoded); err != nil {
+			t.Fatalf("Failed to decode message: %v", err)
+		}
+		if decoded.Type != MessageTypeThroughput || decoded.Payload.IngestBytesPerSec != 2048 {
+			t.Errorf("Unexpected throughput message: %s", msg)
+		}
+	default:
+		t.Error("Client did not receive throughput message")
+	}
+}
+
+// TestHubBroadcastQueue tests that queue forecasts reach clients and are skipped without any.
+func TestHubBroadcastQueue(t *testing.T) {
+	hub := NewHub()
+
+	hub.BroadcastQueue(&models.QueueForecast{})
+	if len(hub.broadcast) != 0 {
+		t.Fatal("Queue forecast should not be broadcast without clients")
+	}
+
+	go hub.Run()
+	client := &Client{hub: hub, send: make(chan []byte, 256)}
+	hub.register <- client
+	time.Sleep(10 * time.Millisecond)
+
+	hub.BroadcastQueue(&models.QueueForecast{BytesPerSec: 1024, Queue: []models.QueueEstimate{{ISOID: "abc", Position: 1}}})
+	time.Sleep(10 * time.Millisecond)
+
+	select {
+	case msg := <-client.send:
+		var decoded struct {
+			Type    string               `json:"type"`
+			Payload models.QueueForecast `json:"payload"`
+		}
+		if err := json.Unmarshal(msg, &decoded); err != nil {
+			t.Fatalf("Failed to decode message: %v", err)
+		}
+		if decoded.Type != MessageTypeQueue || len(decoded.Payload.Queue) != 1 || decoded.Payload.Queue[0].ISOID != "abc" {
+			t.Errorf("Unexpected queue message: %s", msg)
+		}
+	default:
+		t.Error("Client did not receive queue message")
+	}
+}
+
 // TestHubRemoveSlowConsumer tests that slow consumers are removed.
 func TestHubRemoveSlowConsumer(t *testing.T) {
 	hub := NewHub()
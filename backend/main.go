@@ -2,21 +2,36 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 
 	"github.com/aloks98/isoman/backend/internal/api"
+	"github.com/aloks98/isoman/backend/internal/clamav"
 	"github.com/aloks98/isoman/backend/internal/config"
+	"github.com/aloks98/isoman/backend/internal/constants"
+	"github.com/aloks98/isoman/backend/internal/cron"
 	"github.com/aloks98/isoman/backend/internal/db"
 	"github.com/aloks98/isoman/backend/internal/download"
 	"github.com/aloks98/isoman/backend/internal/fileutil"
+	"github.com/aloks98/isoman/backend/internal/geoip"
+	"github.com/aloks98/isoman/backend/internal/httputil"
+	"github.com/aloks98/isoman/backend/internal/i18n"
 	"github.com/aloks98/isoman/backend/internal/logger"
+	"github.com/aloks98/isoman/backend/internal/mail"
 	"github.com/aloks98/isoman/backend/internal/models"
 	"github.com/aloks98/isoman/backend/internal/pathutil"
+	"github.com/aloks98/isoman/backend/internal/repometa"
+	"github.com/aloks98/isoman/backend/internal/secrets"
+	"github.com/aloks98/isoman/backend/internal/selftest"
 	"github.com/aloks98/isoman/backend/internal/service"
+	"github.com/aloks98/isoman/backend/internal/throughput"
 	"github.com/aloks98/isoman/backend/internal/ws"
 )
 
@@ -26,9 +41,18 @@ var Version = "dev"
 func main() {
 	// Load configuration from environment variables
 	cfg := config.Load()
+	cfg.Version = Version
 
-	// Initialize structured logger
-	log := logger.New(cfg.Log.Level, cfg.Log.Format)
+	// `server --self-test` checks that the server could start, printing the
+	// result as JSON on stdout; logs go to stderr
+	if len(os.Args) > 1 && os.Args[1] == "--self-test" {
+		slog.SetDefault(logger.NewTo(os.Stderr, cfg.Log.Level, cfg.Log.Format))
+		os.Exit(runSelfTest(cfg, os.Args[2:]))
+	}
+
+	// Initialize structured logger; PATCH /api/admin/log-level changes it at runtime
+	logs := logger.NewController(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+	log := logs.Logger()
 	slog.SetDefault(log)
 
 	log.Info("starting ISO Manager server",
@@ -37,20 +61,41 @@ func main() {
 		slog.String("log_format", cfg.Log.Format),
 	)
 
-	// Create directory structure
-	isoDir := pathutil.GetISODir(cfg.Download.DataDir)
-	dbDir := pathutil.GetDBDir(cfg.Download.DataDir)
-	tmpDir := pathutil.GetTempDir(isoDir)
+	// Create directory structure; ISO_DIR and DB_PATH may each live on their own volume
+	isoDir := pathutil.ResolveISODir(cfg.Download.DataDir, cfg.Download.ISODir)
+	dbPath := pathutil.ResolveDBPath(cfg.Download.DataDir, cfg.Database.Path)
+	tmpDir := pathutil.ResolveTempDir(isoDir, cfg.Download.TempDir)
+
+	// Everything under the ISO directory can be served, so the database must not be
+	if pathutil.IsWithin(isoDir, dbPath) {
+		log.Error("database must not be inside the ISO directory",
+			slog.String("db_path", dbPath),
+			slog.String("iso_dir", isoDir),
+		)
+		os.Exit(1)
+	}
 
-	if err := fileutil.EnsureDirectories(isoDir, dbDir, tmpDir); err != nil {
+	if err := fileutil.EnsureDirectories(isoDir, filepath.Dir(dbPath), tmpDir); err != nil {
 		log.Error("failed to create directories", slog.Any("error", err))
 		os.Exit(1)
 	}
-	log.Info("directories initialized", slog.String("data_dir", cfg.Download.DataDir))
+	log.Info("directories initialized",
+		slog.String("data_dir", cfg.Download.DataDir),
+		slog.String("iso_dir", isoDir),
+	)
+
+	// `server migrate` upgrades the schema as an explicit step, for operators
+	// who run with DB_AUTO_MIGRATE=false
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(dbPath, &cfg.Database, os.Args[2:], log))
+	}
 
 	// Initialize database
-	dbPath := pathutil.GetDBPath(cfg.Download.DataDir)
 	database, err := db.New(dbPath, &cfg.Database)
+	if errors.Is(err, db.ErrMigrationsPending) {
+		log.Error("database schema is out of date; run `server migrate` or set DB_AUTO_MIGRATE=true", slog.Any("error", err))
+		os.Exit(1)
+	}
 	if err != nil {
 		log.Error("failed to initialize database", slog.Any("error", err))
 		os.Exit(1)
@@ -58,40 +103,375 @@ func main() {
 	defer database.Close()
 	log.Info("database initialized", slog.String("db_path", dbPath))
 
+	// Move the files over when ISO_DIR changed since the last start
+	if rel, err := service.ReconcileISODir(database, isoDir, cfg.Download.StorageRelocate); err != nil {
+		log.Error("failed to reconcile ISO directory", slog.Any("error", err))
+		os.Exit(1)
+	} else if rel != nil {
+		log.Info("ISO directory relocated",
+			slog.String("from", rel.Source),
+			slog.String("to", rel.Target),
+			slog.Int("files_copied", rel.FilesCopied),
+		)
+	}
+
 	// Backfill missing ISO sizes from actual files on disk
 	backfillISOSizes(database, isoDir, log)
 
+	// Catch files truncated or replaced since they were marked complete
+	checkISOFiles(database, isoDir, log)
+
+	// Configure shared HTTP client for upstream downloads
+	httputil.Configure(httputil.NewClientConfig(&cfg.Download))
+
 	// Initialize WebSocket hub
 	wsHub := ws.NewHub()
 	go wsHub.Run()
 	log.Info("websocket hub started")
 
-	// Initialize download manager with progress callback
-	manager := download.NewManager(database, isoDir, cfg.Download.WorkerCount)
-	manager.SetProgressCallback(func(isoID string, progress int, status models.ISOStatus) {
-		// Broadcast progress to WebSocket clients
-		wsHub.BroadcastProgress(isoID, progress, status)
+	// Scanning fails closed, so warn early when clamd is unreachable
+	if cfg.Download.ClamAVAddress != "" {
+		scanner := clamav.New(cfg.Download.ClamAVAddress, cfg.Download.ClamAVTimeout)
+		if err := scanner.Ping(context.Background()); err != nil {
+			log.Warn("clamd is not reachable, downloads will fail until it is",
+				slog.String("address", cfg.Download.ClamAVAddress),
+				slog.Any("error", err),
+			)
+		} else {
+			log.Info("antivirus scanning enabled", slog.String("address", cfg.Download.ClamAVAddress))
+		}
+	}
+
+	// Meter download and serve throughput for the live bandwidth gauge
+	gauge := throughput.NewGauge()
+
+	// Upstream credentials are sealed with the master key; without one they can't be stored or used
+	masterKey, err := secrets.LoadKey(cfg.Download.CredentialsKey, cfg.Download.CredentialsKeyFile)
+	if err != nil {
+		log.Error("failed to load credentials master key", slog.Any("error", err))
+		os.Exit(1)
+	}
+	var box *secrets.Box
+	if masterKey != nil {
+		if box, err = secrets.NewBox(masterKey); err != nil {
+			log.Error("failed to initialize credentials master key", slog.Any("error", err))
+			os.Exit(1)
+		}
+		log.Info("upstream credentials enabled")
+	}
+	credentialService := service.NewCredentialService(database, box)
+
+	// Create the first user from AUTH_ADMIN_USERNAME/AUTH_ADMIN_PASSWORD on an empty users table
+	authService := service.NewAuthService(database, cfg.Auth.SessionTTL)
+	authService.SetLockout(cfg.Auth.MaxFailures, cfg.Auth.Lockout)
+	created, err := authService.EnsureAdmin(cfg.Auth.AdminUsername, cfg.Auth.AdminPassword)
+	if err != nil {
+		log.Error("failed to create admin user", slog.Any("error", err))
+		os.Exit(1)
+	}
+	if created {
+		log.Info("admin user created", slog.String("username", cfg.Auth.AdminUsername))
+	}
+	if cfg.Auth.Enabled {
+		if count, err := database.CountUsers(); err == nil && count == 0 {
+			log.Warn("authentication is enabled but no user exists; set AUTH_ADMIN_USERNAME and AUTH_ADMIN_PASSWORD")
+		}
+		log.Info("authentication enabled", slog.Duration("session_ttl", authService.SessionTTL()))
+	}
+
+	// Messages for directory listings and notifications in LOCALE, plus any in LOCALE_DIR
+	catalog, err := i18n.Load(cfg.Server.Locale, cfg.Server.LocaleDir)
+	if err != nil {
+		log.Error("failed to load message catalogs", slog.Any("error", err))
+		os.Exit(1)
+	}
+	log.Info("message catalogs loaded",
+		slog.String("locale", cfg.Server.Locale),
+		slog.Any("languages", catalog.Languages()),
+	)
+
+	// Email download failures and health problems, batched into digests
+	var notifier *service.Notifier
+	if rc, nc := cfg.Report, cfg.Notify; rc.SMTPHost != "" && len(nc.Recipients) > 0 {
+		sender := mail.New(rc.SMTPHost, rc.SMTPPort, rc.SMTPUsername, rc.SMTPPassword, rc.SMTPFrom, rc.SMTPTimeout)
+		notifier = service.NewNotifier(database, sender, nc.Recipients, nc.DigestWindow)
+		notifier.SetLocalizer(catalog.Localizer(cfg.Server.Locale))
+		log.Info("failure notifications enabled",
+			slog.Duration("digest_window", nc.DigestWindow),
+			slog.Int("recipients", len(nc.Recipients)),
+		)
+	}
+
+	// Tell a CDN or caching proxy in front of /images/ about replaced and deleted files
+	var purger *service.Purger
+	if cfg.CDN.PurgeURL != "" {
+		purger = service.NewPurger(database, cfg.CDN.PurgeURL, cfg.CDN.PurgeToken)
+		purger.SetSidecarExtensions(cfg.Download.SidecarExtensions)
+		log.Info("CDN purge hook enabled")
+	}
+
+	// Initialize download manager. The UI follows progress and queue
+	// forecasts; notifications and CDN purges follow queue events.
+	manager := download.NewManagerWithConfig(database, isoDir, &cfg.Download)
+	manager.SetIngestMeter(&gauge.Ingest)
+	manager.SetCredentialResolver(credentialService)
+
+	// Downloads under a MIRROR_GROUPS mirror go to the one MIRROR_ROTATION picks
+	mirrorGroups, err := download.ParseMirrorGroups(cfg.Download.MirrorGroups)
+	if err != nil {
+		log.Error("invalid MIRROR_GROUPS", slog.Any("error", err))
+		os.Exit(1)
+	}
+	if policy := cfg.Download.MirrorRotation; policy != "" && !constants.IsValidMirrorRotation(policy) {
+		log.Error("invalid MIRROR_ROTATION", slog.String("policy", cfg.Download.MirrorRotation))
+		os.Exit(1)
+	}
+	manager.SetMirrors(mirrorGroups, cfg.Download.MirrorRotation)
+	if len(mirrorGroups) > 0 {
+		log.Info("mirror groups configured",
+			slog.String("policy", cfg.Download.MirrorRotation),
+			slog.Int("groups", len(mirrorGroups)),
+		)
+	}
 
-		// Also log progress
+	manager.AddProgressObserver(wsHub.BroadcastProgress)
+	manager.AddQueueEventHook(func(event models.QueueEvent) {
+		if event.Type == models.QueueEventFailed && event.Status == models.StatusFailed {
+			notifier.NotifyDownloadFailed(event.ISOID)
+		}
+	})
+	manager.AddQueueEventHook(func(event models.QueueEvent) {
+		if event.Type == models.QueueEventFinished {
+			purger.PurgeCompleted(event.ISOID)
+		}
+	})
+	manager.AddQueueEventHook(func(models.QueueEvent) {
+		if wsHub.ClientCount() == 0 {
+			return
+		}
+		// Off the worker, since the forecast reads the database
+		go func() {
+			forecast, err := manager.QueueForecast()
+			if err != nil {
+				log.Warn("failed to estimate queue wait times", slog.Any("error", err))
+				return
+			}
+			wsHub.BroadcastQueue(forecast)
+		}()
+	})
+	manager.AddProgressObserver(func(isoID string, progress int, status models.ISOStatus) {
 		log.Debug("download progress",
 			slog.String("iso_id", isoID),
 			slog.Int("progress", progress),
 			slog.String("status", string(status)),
 		)
 	})
-	manager.Start()
-	log.Info("download manager started", slog.Int("worker_count", cfg.Download.WorkerCount))
+
+	// A bundled checksum database lets downloads verify after upstream
+	// checksum files go away
+	if cfg.Download.ChecksumDBFile != "" {
+		result, err := service.NewChecksumService(database).ImportChecksumFile(cfg.Download.ChecksumDBFile)
+		if err != nil {
+			log.Warn("failed to import checksum database", slog.String("path", cfg.Download.ChecksumDBFile), slog.Any("error", err))
+		} else {
+			log.Info("checksum database imported", slog.String("path", cfg.Download.ChecksumDBFile), slog.Int("checksums", result.Imported))
+		}
+	}
+
+	// A read-only replica never downloads; its catalog follows the primary
+	replicaMode := cfg.Replica.PrimaryURL != ""
+	if !replicaMode {
+		manager.Start()
+		log.Info("download manager started",
+			slog.Int("worker_count", cfg.Download.WorkerCount),
+			slog.String("node", manager.Node()),
+		)
+	}
 
 	// Initialize ISO service
 	isoService := service.NewISOService(database, manager, isoDir)
+	isoService.SetCredentials(credentialService)
+	isoService.SetPurger(purger)
+	isoService.SetSidecarExtensions(cfg.Download.SidecarExtensions)
 	log.Info("iso service initialized")
 
+	// Repair paths and links that drifted after a restore or manual database edit
+	if cfg.Download.ReconcileOnStartup && !replicaMode {
+		if _, err := isoService.ReconcilePaths(false); err != nil {
+			log.Warn("failed to reconcile ISO paths", slog.Any("error", err))
+		}
+	}
+
+	// Sign repository metadata covering the whole mirror when REPO_SIGNING_KEY is set
+	if cfg.Repo.SigningKey != "" {
+		signer, err := repometa.LoadSigner(cfg.Repo.SigningKey)
+		if err != nil {
+			log.Error("failed to load repository signing key", slog.Any("error", err))
+			os.Exit(1)
+		}
+		repo, err := service.NewRepoMetadataService(database, signer, cfg.Repo.RootExpiry, cfg.Repo.TargetsExpiry)
+		if err != nil {
+			log.Error("failed to sign repository metadata", slog.Any("error", err))
+			os.Exit(1)
+		}
+		isoService.SetRepoMetadata(repo)
+		log.Info("repository metadata signing enabled", slog.String("key_id", signer.KeyID()))
+	}
+
+	// Periodically check upstream for in-place republished files
+	checkerCtx, stopChecker := context.WithCancel(context.Background())
+	defer stopChecker()
+	if !replicaMode {
+		isoService.StartUpstreamChecker(checkerCtx, cfg.Download.UpstreamCheckInterval, cfg.Download.UpstreamAutoRefresh)
+		if cfg.Download.UpstreamCheckInterval > 0 {
+			log.Info("upstream checker started",
+				slog.Duration("interval", cfg.Download.UpstreamCheckInterval),
+				slog.Bool("auto_refresh", cfg.Download.UpstreamAutoRefresh),
+			)
+		}
+	}
+
+	// Share the download queue with other instances using the same database
+	pollerCtx, stopPoller := context.WithCancel(context.Background())
+	defer stopPoller()
+	if !replicaMode {
+		manager.StartQueuePoller(pollerCtx, cfg.Download.QueuePollInterval)
+		if cfg.Download.QueuePollInterval > 0 {
+			log.Info("queue poller started", slog.Duration("interval", cfg.Download.QueuePollInterval))
+		}
+	}
+
+	// Fail or re-queue downloads an instance stopped running without finishing
+	watchdogCtx, stopWatchdog := context.WithCancel(context.Background())
+	defer stopWatchdog()
+	if !replicaMode {
+		manager.StartWatchdog(watchdogCtx, cfg.Download.StaleDownloadTimeout, cfg.Download.StaleDownloadRequeue)
+		if cfg.Download.StaleDownloadTimeout > 0 {
+			log.Info("stale download watchdog started",
+				slog.Duration("timeout", cfg.Download.StaleDownloadTimeout),
+				slog.Bool("requeue", cfg.Download.StaleDownloadRequeue),
+			)
+		}
+	}
+
+	// Verify ISOs that completed while their checksum file was unavailable
+	checksumCtx, stopChecksumRetrier := context.WithCancel(context.Background())
+	defer stopChecksumRetrier()
+	if !replicaMode {
+		manager.StartChecksumRetrier(checksumCtx, cfg.Download.ChecksumRetryInterval)
+		if cfg.Download.ChecksumRetryInterval > 0 {
+			log.Info("checksum retrier started", slog.Duration("interval", cfg.Download.ChecksumRetryInterval))
+		}
+	}
+
+	// Remove partial downloads orphaned by crashes
+	janitorCtx, stopJanitor := context.WithCancel(context.Background())
+	defer stopJanitor()
+	manager.StartTempJanitor(janitorCtx, cfg.Download.TempCleanupInterval, cfg.Download.TempMaxAge)
+
 	// Initialize Stats service
 	statsService := service.NewStatsService(database)
+	statsService.SetLogController(logs)
+	statsService.SetDownloadManager(manager)
+	statsService.SetThroughputGauge(gauge)
 	log.Info("stats service initialized")
 
+	// Sync a replica's catalog from its primary's manifest
+	replicaCtx, stopReplica := context.WithCancel(context.Background())
+	defer stopReplica()
+	if replicaMode {
+		replica := service.NewReplicaService(database, isoDir, cfg.Replica.PrimaryURL, cfg.Replica.PrimaryToken)
+		replica.Start(replicaCtx, cfg.Replica.SyncInterval)
+		statsService.SetReplica(replica)
+		log.Info("running as a read-only replica",
+			slog.String("primary", cfg.Replica.PrimaryURL),
+			slog.Duration("sync_interval", cfg.Replica.SyncInterval),
+		)
+	}
+
+	// Resolve where downloads come from when GEOIP_DB or GEOIP_SITES is set
+	if cfg.Server.GeoIPDB != "" || len(cfg.Server.GeoIPSites) > 0 {
+		sites, err := geoip.ParseSites(cfg.Server.GeoIPSites)
+		if err != nil {
+			log.Error("invalid GEOIP_SITES", slog.Any("error", err))
+			os.Exit(1)
+		}
+		var reader *geoip.Reader
+		if cfg.Server.GeoIPDB != "" {
+			if reader, err = geoip.Open(cfg.Server.GeoIPDB); err != nil {
+				log.Error("failed to load GeoIP database", slog.Any("error", err))
+				os.Exit(1)
+			}
+		}
+		statsService.SetLocator(geoip.NewLocator(reader, sites))
+		log.Info("download locations enabled",
+			slog.String("geoip_db", cfg.Server.GeoIPDB),
+			slog.Int("sites", len(sites)),
+		)
+	}
+
+	// Keep only as much about download clients as ANALYTICS_* allows
+	if err := statsService.SetAnalyticsPolicy(service.AnalyticsPolicy{
+		ClientIP:  cfg.Server.AnalyticsClientIP,
+		UserAgent: cfg.Server.AnalyticsUserAgent,
+		Retention: cfg.Server.AnalyticsRetention,
+	}); err != nil {
+		log.Error("failed to apply analytics settings", slog.Any("error", err))
+		os.Exit(1)
+	}
+	retentionCtx, stopRetention := context.WithCancel(context.Background())
+	defer stopRetention()
+	statsService.StartRetention(retentionCtx)
+
+	// Mail the summary report on its schedule
+	reportCtx, stopReports := context.WithCancel(context.Background())
+	defer stopReports()
+	if rc := cfg.Report; rc.SMTPHost != "" && len(rc.Recipients) > 0 && rc.Schedule != "" {
+		schedule, err := cron.Parse(rc.Schedule)
+		if err != nil {
+			log.Warn("invalid REPORT_SCHEDULE, reports disabled", slog.Any("error", err))
+		} else {
+			sender := mail.New(rc.SMTPHost, rc.SMTPPort, rc.SMTPUsername, rc.SMTPPassword, rc.SMTPFrom, rc.SMTPTimeout)
+			service.NewReportService(database, sender, rc.Recipients).Start(reportCtx, schedule)
+			log.Info("report emails scheduled",
+				slog.String("schedule", rc.Schedule),
+				slog.Int("recipients", len(rc.Recipients)),
+			)
+		}
+	}
+
+	// Snapshot the catalog on its schedule, for GET /api/changes
+	snapshotCtx, stopSnapshots := context.WithCancel(context.Background())
+	defer stopSnapshots()
+	if sc := cfg.Snapshot; sc.Schedule != "" {
+		schedule, err := cron.Parse(sc.Schedule)
+		if err != nil {
+			log.Warn("invalid CATALOG_SNAPSHOT_SCHEDULE, catalog snapshots disabled", slog.Any("error", err))
+		} else {
+			service.NewSnapshotService(database, sc.Retention).Start(snapshotCtx, schedule)
+			log.Info("catalog snapshots scheduled",
+				slog.String("schedule", sc.Schedule),
+				slog.Duration("retention", sc.Retention),
+			)
+		}
+	}
+
+	// Record storage, database, and queue state changes in the system event log
+	healthMonitor := service.NewHealthMonitor(database, manager, isoDir, cfg.Server.StorageLowThreshold)
+	healthMonitor.SetNotifier(notifier)
+	healthMonitor.Check()
+	healthCtx, stopHealth := context.WithCancel(context.Background())
+	defer stopHealth()
+	healthMonitor.Start(healthCtx, cfg.Server.HealthCheckInterval)
+	statsService.SetHealthMonitor(healthMonitor)
+
+	// Sample throughput and push it to WebSocket clients
+	gaugeCtx, stopGauge := context.WithCancel(context.Background())
+	defer stopGauge()
+	gauge.Start(gaugeCtx, cfg.Server.ThroughputSampleInterval, wsHub.BroadcastThroughput)
+
 	// Setup routes
-	router := api.SetupRoutes(isoService, statsService, database, isoDir, wsHub, cfg)
+	router := api.SetupRoutes(isoService, statsService, authService, database, isoDir, wsHub, cfg)
 	log.Info("api routes configured")
 
 	// Create HTTP server
@@ -103,6 +483,8 @@ func main() {
 		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
+	healthMonitor.Record(models.EventServerStarted, models.SeverityInfo, "version "+Version)
+
 	// Start server in a goroutine
 	go func() {
 		log.Info("server starting",
@@ -116,12 +498,41 @@ func main() {
 		}
 	}()
 
+	// Admin listener for pprof and runtime diagnostics, kept off the public
+	// port. No write timeout: CPU profiles and traces stream for as long as asked
+	var adminServer *http.Server
+	if cfg.Server.AdminAddr != "" {
+		adminServer = &http.Server{
+			Addr:        cfg.Server.AdminAddr,
+			Handler:     api.SetupAdminRoutes(authService, cfg),
+			ReadTimeout: cfg.Server.ReadTimeout,
+			IdleTimeout: cfg.Server.IdleTimeout,
+		}
+		go func() {
+			log.Info("admin listener starting", slog.String("address", cfg.Server.AdminAddr))
+			if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error("admin listener failed to start", slog.Any("error", err))
+				os.Exit(1)
+			}
+		}()
+	}
+
 	// Wait for interrupt signal for graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
 	log.Info("shutdown signal received, starting graceful shutdown")
+	healthMonitor.Record(models.EventServerStopping, models.SeverityInfo, "shutdown signal received")
+
+	// Stop background checks before the download manager goes away
+	stopChecker()
+	stopPoller()
+	stopWatchdog()
+	stopReplica()
+	stopHealth()
+	stopReports()
+	stopSnapshots()
 
 	// Stop download manager (cancels active downloads)
 	log.Info("stopping download manager")
@@ -135,10 +546,85 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		log.Warn("server forced to shutdown", slog.Any("error", err))
 	}
+	if adminServer != nil {
+		if err := adminServer.Shutdown(ctx); err != nil {
+			log.Warn("admin listener forced to shutdown", slog.Any("error", err))
+		}
+	}
+
+	// Mail whatever is still waiting for its digest window
+	notifier.Flush()
+	purger.Wait()
 
 	log.Info("server stopped successfully")
 }
 
+// runMigrate implements the migrate command and returns its exit status.
+// With no arguments it applies pending migrations; "status" only reports
+// the schema version, exiting 1 while migrations are pending.
+func runMigrate(dbPath string, cfg *config.DatabaseConfig, args []string, log *slog.Logger) int {
+	statusOnly := len(args) == 1 && args[0] == "status"
+	if len(args) > 0 && !statusOnly {
+		log.Error("usage: server migrate [status]")
+		return 2
+	}
+
+	database, err := db.Open(dbPath, cfg)
+	if err != nil {
+		log.Error("failed to open database", slog.Any("error", err))
+		return 1
+	}
+	defer database.Close()
+
+	if !statusOnly {
+		if err := database.Migrate(); err != nil {
+			log.Error("migration failed", slog.Any("error", err))
+			return 1
+		}
+	}
+
+	status, err := database.SchemaStatus()
+	if err != nil {
+		log.Error("failed to read schema version", slog.Any("error", err))
+		return 1
+	}
+	log.Info("database schema",
+		slog.String("db_path", dbPath),
+		slog.Uint64("version", uint64(status.Version)),
+		slog.Uint64("latest", uint64(status.Latest)),
+		slog.Bool("dirty", status.Dirty),
+		slog.Bool("pending", status.Pending()),
+	)
+	if status.Pending() {
+		return 1
+	}
+	return 0
+}
+
+// runSelfTest implements --self-test and returns its exit status: 0 when no
+// check failed, 1 when one did.
+func runSelfTest(cfg *config.Config, args []string) int {
+	if len(args) > 0 {
+		slog.Error("usage: server --self-test")
+		return 2
+	}
+
+	// The connectivity probe goes through the upstream client, as downloads do
+	httputil.Configure(httputil.NewClientConfig(&cfg.Download))
+	result := selftest.Run(context.Background(), cfg)
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(result); err != nil {
+		slog.Error("failed to write self-test result", slog.Any("error", err))
+		return 1
+	}
+	if !result.OK {
+		return 1
+	}
+	return 0
+}
+
 // backfillISOSizes updates size_bytes for complete ISOs that have size_bytes = 0
 // by reading the actual file size from disk. This handles ISOs that were downloaded
 // when the server didn't send a Content-Length header.
@@ -189,3 +675,58 @@ func backfillISOSizes(database *db.DB, isoDir string, log *slog.Logger) {
 		log.Info("backfilled ISO sizes", slog.Int("updated", updated))
 	}
 }
+
+// checkISOFiles compares complete ISOs against the file identity recorded when
+// they were finalized. Missing or truncated files are marked failed; files that
+// were replaced with the same size are only logged, since they may have been
+// restored from a backup.
+func checkISOFiles(database *db.DB, isoDir string, log *slog.Logger) {
+	isos, err := database.ListISOs()
+	if err != nil {
+		log.Warn("failed to list ISOs for file check", slog.Any("error", err))
+		return
+	}
+
+	failed := 0
+	for _, iso := range isos {
+		// ISOs finalized before file identities were recorded have nothing to compare
+		if iso.Status != models.StatusComplete || iso.FileModTime == nil {
+			continue
+		}
+
+		filePath := pathutil.ConstructISOPath(isoDir, iso.FilePath)
+		fi, err := os.Stat(filePath)
+		var reason string
+		switch {
+		case err != nil:
+			reason = "file missing on disk"
+		case iso.SizeBytes > 0 && fi.Size() != iso.SizeBytes:
+			reason = fmt.Sprintf("file size changed on disk: expected %d, got %d", iso.SizeBytes, fi.Size())
+		}
+
+		if reason != "" {
+			log.Warn("complete ISO failed file check",
+				slog.String("iso_id", iso.ID),
+				slog.String("path", filePath),
+				slog.String("reason", reason),
+			)
+			if err := database.UpdateISOStatus(iso.ID, models.StatusFailed, reason); err != nil {
+				log.Warn("failed to mark ISO as failed", slog.String("iso_id", iso.ID), slog.Any("error", err))
+				continue
+			}
+			failed++
+			continue
+		}
+
+		if fileutil.FileInode(fi) != iso.FileInode || !fi.ModTime().Equal(*iso.FileModTime) {
+			log.Warn("ISO file was replaced since download, verify it to check its contents",
+				slog.String("iso_id", iso.ID),
+				slog.String("path", filePath),
+			)
+		}
+	}
+
+	if failed > 0 {
+		log.Warn("marked ISOs with missing or truncated files as failed", slog.Int("count", failed))
+	}
+}
@@ -0,0 +1,2 @@
+-- Add error_reason column to classify why a download failed (e.g. stalled, timeout)
+ALTER TABLE isos ADD COLUMN error_reason TEXT DEFAULT '';
@@ -0,0 +1,2 @@
+-- Add ip_family column to pin upstream fetches for an ISO to IPv4 or IPv6
+ALTER TABLE isos ADD COLUMN ip_family TEXT DEFAULT '';
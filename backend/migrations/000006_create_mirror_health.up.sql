@@ -0,0 +1,11 @@
+-- Create mirror_health table to track reliability of upstream hosts
+CREATE TABLE IF NOT EXISTS mirror_health (
+    host TEXT PRIMARY KEY,
+    success_count INTEGER NOT NULL DEFAULT 0,
+    failure_count INTEGER NOT NULL DEFAULT 0,
+    total_latency_ms INTEGER NOT NULL DEFAULT 0,
+    last_latency_ms INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    last_success_at TIMESTAMP,
+    last_failure_at TIMESTAMP
+);
@@ -0,0 +1,5 @@
+-- Track upstream validators so in-place republished files can be detected
+ALTER TABLE isos ADD COLUMN upstream_etag TEXT DEFAULT '';
+ALTER TABLE isos ADD COLUMN upstream_last_modified TEXT DEFAULT '';
+ALTER TABLE isos ADD COLUMN upstream_changed INTEGER DEFAULT 0;
+ALTER TABLE isos ADD COLUMN upstream_checked_at TIMESTAMP;
@@ -0,0 +1,4 @@
+-- Store every digest computed while downloading so clients can use their preferred one
+ALTER TABLE isos ADD COLUMN sha256 TEXT DEFAULT '';
+ALTER TABLE isos ADD COLUMN sha512 TEXT DEFAULT '';
+ALTER TABLE isos ADD COLUMN md5 TEXT DEFAULT '';
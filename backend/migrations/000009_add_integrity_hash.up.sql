@@ -0,0 +1,2 @@
+-- Add integrity_hash column ("algorithm:hex") used to scrub files on disk
+ALTER TABLE isos ADD COLUMN integrity_hash TEXT DEFAULT '';
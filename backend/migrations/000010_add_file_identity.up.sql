@@ -0,0 +1,3 @@
+-- Record the identity of the finished file so startup can spot files changed or truncated on disk
+ALTER TABLE isos ADD COLUMN file_inode INTEGER DEFAULT 0;
+ALTER TABLE isos ADD COLUMN file_mtime TIMESTAMP;
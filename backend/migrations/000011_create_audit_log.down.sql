@@ -0,0 +1,4 @@
+-- Drop audit_log table and indexes
+DROP INDEX IF EXISTS idx_audit_log_target_id;
+DROP INDEX IF EXISTS idx_audit_log_created_at;
+DROP TABLE IF EXISTS audit_log;
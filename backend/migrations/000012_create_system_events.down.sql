@@ -0,0 +1,3 @@
+-- Drop system_events table and indexes
+DROP INDEX IF EXISTS idx_system_events_created_at;
+DROP TABLE IF EXISTS system_events;
@@ -0,0 +1,54 @@
+-- Drop credentials table and indexes
+DROP INDEX IF EXISTS idx_credentials_host;
+DROP TABLE IF EXISTS credentials;
+
+-- SQLite doesn't support DROP COLUMN directly, need to recreate the table
+-- Create backup without credential
+CREATE TABLE isos_backup AS SELECT
+    id, name, version, arch, edition, file_type, filename, file_path, download_link,
+    size_bytes, checksum, checksum_type, download_url, checksum_url,
+    status, progress, error_message, created_at, completed_at, download_count, error_reason,
+    ip_family, upstream_etag, upstream_last_modified, upstream_changed, upstream_checked_at,
+    sha256, sha512, md5, integrity_hash, file_inode, file_mtime
+FROM isos;
+
+DROP TABLE isos;
+
+CREATE TABLE isos (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    version TEXT NOT NULL,
+    arch TEXT NOT NULL,
+    edition TEXT NOT NULL DEFAULT '',
+    file_type TEXT NOT NULL,
+    filename TEXT NOT NULL,
+    file_path TEXT NOT NULL,
+    download_link TEXT NOT NULL,
+    size_bytes INTEGER DEFAULT 0,
+    checksum TEXT DEFAULT '',
+    checksum_type TEXT DEFAULT '',
+    download_url TEXT NOT NULL,
+    checksum_url TEXT DEFAULT '',
+    status TEXT NOT NULL,
+    progress INTEGER DEFAULT 0,
+    error_message TEXT DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP,
+    download_count INTEGER DEFAULT 0,
+    error_reason TEXT DEFAULT '',
+    ip_family TEXT DEFAULT '',
+    upstream_etag TEXT DEFAULT '',
+    upstream_last_modified TEXT DEFAULT '',
+    upstream_changed INTEGER DEFAULT 0,
+    upstream_checked_at TIMESTAMP,
+    sha256 TEXT DEFAULT '',
+    sha512 TEXT DEFAULT '',
+    md5 TEXT DEFAULT '',
+    integrity_hash TEXT DEFAULT '',
+    file_inode INTEGER DEFAULT 0,
+    file_mtime TIMESTAMP,
+    UNIQUE(name, version, arch, edition, file_type)
+);
+
+INSERT INTO isos SELECT * FROM isos_backup;
+DROP TABLE isos_backup;
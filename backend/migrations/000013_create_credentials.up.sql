@@ -0,0 +1,14 @@
+-- Create credentials table holding upstream secrets sealed with the master key
+CREATE TABLE IF NOT EXISTS credentials (
+    name TEXT PRIMARY KEY,
+    type TEXT NOT NULL,
+    host TEXT NOT NULL DEFAULT '',
+    secret TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_credentials_host ON credentials(host);
+
+-- Let an ISO reference a credential by name
+ALTER TABLE isos ADD COLUMN credential TEXT DEFAULT '';
@@ -0,0 +1,18 @@
+-- Drop recovery codes
+DROP TABLE IF EXISTS recovery_codes;
+
+-- SQLite doesn't support DROP COLUMN directly, need to recreate the table
+CREATE TABLE users_backup (
+    id TEXT PRIMARY KEY,
+    username TEXT NOT NULL UNIQUE COLLATE NOCASE,
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    last_login_at TIMESTAMP
+);
+
+INSERT INTO users_backup SELECT id, username, password_hash, created_at, updated_at, last_login_at FROM users;
+
+DROP TABLE users;
+
+ALTER TABLE users_backup RENAME TO users;
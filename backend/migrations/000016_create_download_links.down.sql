@@ -0,0 +1,3 @@
+-- Drop download_links table and index
+DROP INDEX IF EXISTS idx_download_links_expires_at;
+DROP TABLE IF EXISTS download_links;
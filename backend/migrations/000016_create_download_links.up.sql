@@ -0,0 +1,12 @@
+-- Create download_links table; id is the SHA-256 of the link token, which is never stored
+CREATE TABLE IF NOT EXISTS download_links (
+    id TEXT PRIMARY KEY,
+    path TEXT NOT NULL,
+    single_use INTEGER NOT NULL DEFAULT 0,
+    created_by TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX idx_download_links_expires_at ON download_links(expires_at);
@@ -0,0 +1,9 @@
+-- Create report_runs table; each row remembers the storage total a report saw
+-- so the next one can show the change
+CREATE TABLE IF NOT EXISTS report_runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    period_start TIMESTAMP NOT NULL,
+    period_end TIMESTAMP NOT NULL,
+    storage_bytes INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL
+);
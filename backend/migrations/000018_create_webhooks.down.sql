@@ -0,0 +1,2 @@
+-- Drop webhooks table
+DROP TABLE IF EXISTS webhooks;
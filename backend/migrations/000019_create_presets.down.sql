@@ -0,0 +1,2 @@
+-- Drop presets table
+DROP TABLE IF EXISTS presets;
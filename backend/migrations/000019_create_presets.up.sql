@@ -0,0 +1,12 @@
+-- Create presets table for templated ISO creation
+CREATE TABLE IF NOT EXISTS presets (
+    name TEXT PRIMARY KEY,
+    iso_name TEXT NOT NULL,
+    arch TEXT NOT NULL DEFAULT '',
+    edition TEXT NOT NULL DEFAULT '',
+    download_url TEXT NOT NULL,
+    checksum_url TEXT NOT NULL DEFAULT '',
+    checksum_type TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,2 @@
+-- Remember the preset an ISO was created from, for version bumps
+ALTER TABLE isos ADD COLUMN preset TEXT DEFAULT '';
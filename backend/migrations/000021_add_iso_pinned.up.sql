@@ -0,0 +1,2 @@
+-- Pinned ISOs sort first and keep every archived version
+ALTER TABLE isos ADD COLUMN pinned INTEGER DEFAULT 0;
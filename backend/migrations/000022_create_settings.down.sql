@@ -0,0 +1,2 @@
+-- Drop settings table
+DROP TABLE IF EXISTS settings;
@@ -0,0 +1,7 @@
+-- Create settings table for server state that outlives a restart, such as
+-- the ISO directory the files were last stored in
+CREATE TABLE IF NOT EXISTS settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
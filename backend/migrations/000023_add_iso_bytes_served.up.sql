@@ -0,0 +1,2 @@
+-- Bytes of the ISO's file actually sent to clients, including partial and range responses
+ALTER TABLE isos ADD COLUMN bytes_served INTEGER DEFAULT 0;
@@ -0,0 +1,3 @@
+-- Where a download came from, resolved with GEOIP_DB and GEOIP_SITES; empty when unknown
+ALTER TABLE download_events ADD COLUMN country TEXT DEFAULT '';
+ALTER TABLE download_events ADD COLUMN site TEXT DEFAULT '';
@@ -0,0 +1,21 @@
+-- SQLite doesn't support DROP COLUMN directly, need to recreate the table
+CREATE TABLE download_events_backup AS SELECT id, iso_id, downloaded_at, country, site FROM download_events;
+
+DROP INDEX IF EXISTS idx_download_events_downloaded_at;
+DROP INDEX IF EXISTS idx_download_events_iso_id;
+DROP TABLE download_events;
+
+CREATE TABLE download_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    iso_id TEXT NOT NULL,
+    downloaded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    country TEXT DEFAULT '',
+    site TEXT DEFAULT '',
+    FOREIGN KEY (iso_id) REFERENCES isos(id) ON DELETE CASCADE
+);
+
+INSERT INTO download_events SELECT * FROM download_events_backup;
+DROP TABLE download_events_backup;
+
+CREATE INDEX idx_download_events_downloaded_at ON download_events(downloaded_at);
+CREATE INDEX idx_download_events_iso_id ON download_events(iso_id);
@@ -0,0 +1,3 @@
+-- Who downloaded, kept as ANALYTICS_CLIENT_IP and ANALYTICS_USER_AGENT allow; empty when not kept
+ALTER TABLE download_events ADD COLUMN client_ip TEXT DEFAULT '';
+ALTER TABLE download_events ADD COLUMN user_agent TEXT DEFAULT '';
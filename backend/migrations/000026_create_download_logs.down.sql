@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_download_logs_iso_id;
+DROP TABLE IF EXISTS download_logs;
@@ -0,0 +1,11 @@
+-- Per-download log of the latest download run of each ISO
+CREATE TABLE IF NOT EXISTS download_logs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    iso_id TEXT NOT NULL,
+    logged_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    level TEXT NOT NULL DEFAULT 'info',
+    message TEXT NOT NULL,
+    FOREIGN KEY (iso_id) REFERENCES isos(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_download_logs_iso_id ON download_logs(iso_id);
@@ -0,0 +1,9 @@
+-- Leases on in-flight downloads, so instances sharing the database never
+-- download the same ISO at once
+CREATE TABLE IF NOT EXISTS download_locks (
+    iso_id TEXT PRIMARY KEY,
+    node TEXT NOT NULL,
+    acquired_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (iso_id) REFERENCES isos(id) ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_isos_pinned_created_at;
@@ -0,0 +1,2 @@
+-- Keyset pagination walks ISOs in (pinned, created_at, id) order
+CREATE INDEX idx_isos_pinned_created_at ON isos(pinned, created_at, id);
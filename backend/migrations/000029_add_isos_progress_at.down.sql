@@ -0,0 +1 @@
+ALTER TABLE isos DROP COLUMN progress_at;
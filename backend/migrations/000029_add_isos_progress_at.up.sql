@@ -0,0 +1,3 @@
+-- When a running download last wrote its status or progress, so downloads
+-- left running by an unclean shutdown can be told apart from slow ones
+ALTER TABLE isos ADD COLUMN progress_at TIMESTAMP;
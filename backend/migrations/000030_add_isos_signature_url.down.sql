@@ -0,0 +1 @@
+ALTER TABLE isos DROP COLUMN signature_url;
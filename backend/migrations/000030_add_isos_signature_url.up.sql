@@ -0,0 +1,2 @@
+-- Detached OpenPGP signature over the image itself, verified with GPG_KEYRING
+ALTER TABLE isos ADD COLUMN signature_url TEXT DEFAULT '';
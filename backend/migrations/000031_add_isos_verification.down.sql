@@ -0,0 +1,2 @@
+ALTER TABLE isos DROP COLUMN verified_at;
+ALTER TABLE isos DROP COLUMN signature_signer;
@@ -0,0 +1,3 @@
+-- Who signed the file and when its checksum or signature last verified, for GET /api/isos/:id/verification
+ALTER TABLE isos ADD COLUMN signature_signer TEXT DEFAULT '';
+ALTER TABLE isos ADD COLUMN verified_at TIMESTAMP;
@@ -0,0 +1,3 @@
+ALTER TABLE isos DROP COLUMN download_started_at;
+ALTER TABLE isos DROP COLUMN source_headers;
+ALTER TABLE isos DROP COLUMN source_url;
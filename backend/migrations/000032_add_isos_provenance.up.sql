@@ -0,0 +1,5 @@
+-- Where and when the file was fetched: the URL that served it after redirects,
+-- selected response headers as JSON, and when the transfer started
+ALTER TABLE isos ADD COLUMN source_url TEXT DEFAULT '';
+ALTER TABLE isos ADD COLUMN source_headers TEXT DEFAULT '';
+ALTER TABLE isos ADD COLUMN download_started_at TIMESTAMP;
@@ -0,0 +1,3 @@
+-- Set while a complete ISO is downloaded again; its file stays served until
+-- the new one is verified, and a failed download puts it back to complete
+ALTER TABLE isos ADD COLUMN redownload INTEGER DEFAULT 0;
@@ -0,0 +1,2 @@
+-- Drop known_checksums table
+DROP TABLE IF EXISTS known_checksums;
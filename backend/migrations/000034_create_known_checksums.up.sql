@@ -0,0 +1,10 @@
+-- Published checksums kept locally, so a download can still be verified
+-- after its upstream checksum file goes away
+CREATE TABLE IF NOT EXISTS known_checksums (
+    checksum_url TEXT NOT NULL,
+    filename TEXT NOT NULL,
+    checksum TEXT NOT NULL,
+    source TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (checksum_url, filename)
+);
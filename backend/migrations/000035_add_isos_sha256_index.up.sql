@@ -0,0 +1,2 @@
+-- Look up ISOs by the SHA-256 of their file
+CREATE INDEX IF NOT EXISTS idx_isos_sha256 ON isos(sha256);
@@ -0,0 +1,3 @@
+-- Address of the client that made the change, as resolved through
+-- TRUSTED_PROXIES; empty for events recorded before it was kept
+ALTER TABLE audit_log ADD COLUMN client_ip TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- Set on a complete ISO whose checksum file couldn't be fetched when it
+-- finished downloading; the checksum is retried until it can be verified
+ALTER TABLE isos ADD COLUMN checksum_pending INTEGER DEFAULT 0;
@@ -0,0 +1,3 @@
+-- Drop catalog_snapshots table
+DROP INDEX IF EXISTS idx_catalog_snapshots_taken_at;
+DROP TABLE IF EXISTS catalog_snapshots;
@@ -0,0 +1,10 @@
+-- Create catalog_snapshots table; each row holds the catalog as it was when
+-- taken, as JSON, so GET /api/changes can tell what changed since then
+CREATE TABLE IF NOT EXISTS catalog_snapshots (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    taken_at TIMESTAMP NOT NULL,
+    iso_count INTEGER NOT NULL DEFAULT 0,
+    entries TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_catalog_snapshots_taken_at ON catalog_snapshots(taken_at);
@@ -0,0 +1,3 @@
+-- Drop gc_reports table
+DROP INDEX IF EXISTS idx_gc_reports_created_at;
+DROP TABLE IF EXISTS gc_reports;
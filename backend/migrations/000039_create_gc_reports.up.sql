@@ -0,0 +1,13 @@
+-- Create gc_reports table; each row records one automated deletion pass and
+-- the files it removed, as JSON, for GET /api/gc/reports
+CREATE TABLE IF NOT EXISTS gc_reports (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reason TEXT NOT NULL,
+    iso_id TEXT NOT NULL DEFAULT '',
+    file_count INTEGER NOT NULL DEFAULT 0,
+    bytes_reclaimed INTEGER NOT NULL DEFAULT 0,
+    files TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_gc_reports_created_at ON gc_reports(created_at);
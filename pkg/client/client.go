@@ -17,6 +17,7 @@ type Client struct {
 	baseURL    string
 	httpClient *http.Client
 	userAgent  string
+	token      string
 }
 
 // Option configures a Client.
@@ -37,6 +38,12 @@ func WithUserAgent(ua string) Option {
 	return func(c *Client) { c.userAgent = ua }
 }
 
+// WithToken sets the session token sent as a Bearer token with every request.
+// Required when the server runs with AUTH_ENABLED.
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
 // NewClient creates a new ISOMan API client.
 // baseURL is the root URL of the ISOMan server (e.g. "http://localhost:8080").
 func NewClient(baseURL string, opts ...Option) *Client {
@@ -52,11 +59,20 @@ func NewClient(baseURL string, opts ...Option) *Client {
 }
 
 // apiResponse is the raw JSON envelope returned by all ISOMan API endpoints.
+// Error responses additionally carry RFC 7807 problem members.
 type apiResponse struct {
 	Success bool              `json:"success"`
 	Data    json.RawMessage   `json:"data,omitempty"`
 	Error   *apiResponseError `json:"error,omitempty"`
 	Message string            `json:"message,omitempty"`
+
+	Type      string       `json:"type,omitempty"`
+	Title     string       `json:"title,omitempty"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
 }
 
 type apiResponseError struct {
@@ -79,6 +95,9 @@ func (c *Client) do(ctx context.Context, method, path string, body io.Reader) (*
 	if c.userAgent != "" {
 		req.Header.Set("User-Agent", c.userAgent)
 	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("isoman: %s %s: %w", method, path, err)
@@ -101,12 +120,28 @@ func (c *Client) doJSON(ctx context.Context, method, path string, body io.Reader
 	}
 
 	if !envelope.Success {
-		apiErr := &APIError{StatusCode: resp.StatusCode}
+		apiErr := &APIError{
+			StatusCode:  resp.StatusCode,
+			Code:        envelope.Code,
+			Message:     envelope.Detail,
+			Type:        envelope.Type,
+			Instance:    envelope.Instance,
+			RequestID:   envelope.RequestID,
+			FieldErrors: envelope.Errors,
+		}
 		if envelope.Error != nil {
 			apiErr.Code = envelope.Error.Code
 			apiErr.Message = envelope.Error.Message
 			apiErr.Details = envelope.Error.Details
 		}
+		if len(envelope.Data) > 0 {
+			var conflict struct {
+				Options []ConflictOption `json:"options"`
+			}
+			if json.Unmarshal(envelope.Data, &conflict) == nil {
+				apiErr.Options = conflict.Options
+			}
+		}
 		return apiErr
 	}
 
@@ -127,6 +162,147 @@ func encodeBody(v any) (io.Reader, error) {
 	return strings.NewReader(string(b)), nil
 }
 
+// Login signs in with a username and password. On success the client sends
+// the returned session token with every later request. Login must not be
+// called concurrently with other requests.
+func (c *Client) Login(ctx context.Context, username, password string) (*LoginResponse, error) {
+	return c.LoginWithCode(ctx, username, password, "")
+}
+
+// LoginWithCode signs in a user with two-factor authentication. code is a
+// TOTP code or a recovery code. Without one, such users fail with TOTP_REQUIRED.
+func (c *Client) LoginWithCode(ctx context.Context, username, password, code string) (*LoginResponse, error) {
+	body, err := encodeBody(map[string]string{"username": username, "password": password, "code": code})
+	if err != nil {
+		return nil, err
+	}
+	var resp LoginResponse
+	if err := c.doJSON(ctx, http.MethodPost, "/api/auth/login", body, &resp); err != nil {
+		return nil, err
+	}
+	c.token = resp.Token
+	return &resp, nil
+}
+
+// Logout ends the client's session and forgets its token.
+func (c *Client) Logout(ctx context.Context) error {
+	if err := c.doJSON(ctx, http.MethodPost, "/api/auth/logout", nil, nil); err != nil {
+		return err
+	}
+	c.token = ""
+	return nil
+}
+
+// Me returns the signed-in user.
+func (c *Client) Me(ctx context.Context) (*User, error) {
+	var user User
+	if err := c.doJSON(ctx, http.MethodGet, "/api/auth/me", nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// BeginTOTP starts two-factor enrollment for the signed-in user.
+func (c *Client) BeginTOTP(ctx context.Context) (*TOTPEnrollment, error) {
+	var enrollment TOTPEnrollment
+	if err := c.doJSON(ctx, http.MethodPost, "/api/auth/totp", nil, &enrollment); err != nil {
+		return nil, err
+	}
+	return &enrollment, nil
+}
+
+// ConfirmTOTP enables two-factor authentication with a code from the
+// authenticator and returns the recovery codes.
+func (c *Client) ConfirmTOTP(ctx context.Context, code string) ([]string, error) {
+	return c.postTOTPCode(ctx, "/api/auth/totp/confirm", code)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes. code must be a TOTP code.
+func (c *Client) RegenerateRecoveryCodes(ctx context.Context, code string) ([]string, error) {
+	return c.postTOTPCode(ctx, "/api/auth/totp/recovery-codes", code)
+}
+
+// DisableTOTP turns two-factor authentication off. code may be a recovery code.
+func (c *Client) DisableTOTP(ctx context.Context, password, code string) error {
+	body, err := encodeBody(map[string]string{"password": password, "code": code})
+	if err != nil {
+		return err
+	}
+	return c.doJSON(ctx, http.MethodPost, "/api/auth/totp/disable", body, nil)
+}
+
+func (c *Client) postTOTPCode(ctx context.Context, path, code string) ([]string, error) {
+	body, err := encodeBody(map[string]string{"code": code})
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Codes []string `json:"recovery_codes"`
+	}
+	if err := c.doJSON(ctx, http.MethodPost, path, body, &result); err != nil {
+		return nil, err
+	}
+	return result.Codes, nil
+}
+
+// CreateDownloadLink creates a link to one file under /images. The returned
+// URL is absolute, ready to share, and holds the token; it isn't shown again.
+func (c *Client) CreateDownloadLink(ctx context.Context, req CreateDownloadLinkRequest) (*DownloadLink, error) {
+	body, err := encodeBody(req)
+	if err != nil {
+		return nil, err
+	}
+	var link DownloadLink
+	if err := c.doJSON(ctx, http.MethodPost, "/api/download-links", body, &link); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// ListDownloadLinks returns the unexpired download links without their tokens.
+func (c *Client) ListDownloadLinks(ctx context.Context) ([]DownloadLink, error) {
+	var links []DownloadLink
+	if err := c.doJSON(ctx, http.MethodGet, "/api/download-links", nil, &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// RevokeDownloadLink deletes a download link so it stops working.
+func (c *Client) RevokeDownloadLink(ctx context.Context, id string) error {
+	return c.doJSON(ctx, http.MethodDelete, "/api/download-links/"+url.PathEscape(id), nil, nil)
+}
+
+// ListSessions returns the active sessions of every user.
+func (c *Client) ListSessions(ctx context.Context) ([]Session, error) {
+	var sessions []Session
+	if err := c.doJSON(ctx, http.MethodGet, "/api/sessions", nil, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RevokeSession ends a session by ID. It stops working immediately.
+func (c *Client) RevokeSession(ctx context.Context, id string) error {
+	return c.doJSON(ctx, http.MethodDelete, "/api/sessions/"+url.PathEscape(id), nil, nil)
+}
+
+// RevokeSessions ends every session except the client's own, limited to one
+// user when userID is set, and returns how many were ended.
+func (c *Client) RevokeSessions(ctx context.Context, userID string) (int64, error) {
+	path := "/api/sessions"
+	if userID != "" {
+		path += "?" + url.Values{"user_id": {userID}}.Encode()
+	}
+	var result struct {
+		Revoked int64 `json:"revoked"`
+	}
+	if err := c.doJSON(ctx, http.MethodDelete, path, nil, &result); err != nil {
+		return 0, err
+	}
+	return result.Revoked, nil
+}
+
 // ListISOs returns a paginated list of ISOs.
 // Pass nil for default options (page 1, page_size 10, sorted by created_at desc).
 func (c *Client) ListISOs(ctx context.Context, opts *ListISOsOptions) (*ListISOsResponse, error) {
@@ -145,6 +321,12 @@ func (c *Client) ListISOs(ctx context.Context, opts *ListISOsOptions) (*ListISOs
 		if opts.SortDir != "" {
 			q.Set("sort_dir", opts.SortDir)
 		}
+		if opts.Cursor != "" {
+			q.Set("cursor", opts.Cursor)
+		}
+		if len(opts.Fields) > 0 {
+			q.Set("fields", strings.Join(opts.Fields, ","))
+		}
 		if encoded := q.Encode(); encoded != "" {
 			path += "?" + encoded
 		}
@@ -166,19 +348,110 @@ func (c *Client) GetISO(ctx context.Context, id string) (*ISO, error) {
 	return &iso, nil
 }
 
-// CreateISO queues a new ISO download and returns the created ISO.
+// WaitForISO blocks until the ISO's download settles (complete, failed,
+// canceled, or quarantined) or ctx is done, and returns the ISO. Each request
+// is held by the server for a little less than the client timeout, and
+// repeated until the ISO settles.
+func (c *Client) WaitForISO(ctx context.Context, id string) (*ISO, error) {
+	hold := 300 * time.Second
+	if timeout := c.httpClient.Timeout; timeout > 0 {
+		hold = max(timeout-5*time.Second, time.Second)
+	}
+	path := "/api/isos/" + id + "/wait?timeout=" + strconv.Itoa(int(hold/time.Second))
+
+	for {
+		var iso ISO
+		if err := c.doJSON(ctx, http.MethodGet, path, nil, &iso); err != nil {
+			return nil, err
+		}
+		if !iso.Status.IsActive() {
+			return &iso, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// FindISOsBySHA256 returns the complete ISOs whose file has the given SHA-256.
+// When none does, the error satisfies IsNotFound, so callers such as CI jobs
+// can tell an image that still has to be uploaded from one already mirrored.
+func (c *Client) FindISOsBySHA256(ctx context.Context, sum string) ([]*ISO, error) {
+	var isos []*ISO
+	if err := c.doJSON(ctx, http.MethodGet, "/api/isos/by-checksum/"+url.PathEscape(sum), nil, &isos); err != nil {
+		return nil, err
+	}
+	return isos, nil
+}
+
+// GetISOSnippets returns wget, curl, Proxmox, and iPXE configuration for
+// fetching or booting an ISO from this server. Proxmox and iPXE snippets are
+// only offered for ISO and raw disk images.
+func (c *Client) GetISOSnippets(ctx context.Context, id string) (*ISOSnippets, error) {
+	var snippets ISOSnippets
+	if err := c.doJSON(ctx, http.MethodGet, "/api/isos/"+id+"/snippets", nil, &snippets); err != nil {
+		return nil, err
+	}
+	return &snippets, nil
+}
+
+// GetDownloadLog returns the steps of an ISO's latest download run, oldest first.
+func (c *Client) GetDownloadLog(ctx context.Context, id string) ([]DownloadLogEntry, error) {
+	var entries []DownloadLogEntry
+	if err := c.doJSON(ctx, http.MethodGet, "/api/isos/"+id+"/log", nil, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetVerificationReport returns how an ISO's file was verified: its source,
+// checksum and signature status, computed hashes, and timestamps.
+func (c *Client) GetVerificationReport(ctx context.Context, id string) (*VerificationReport, error) {
+	var report VerificationReport
+	if err := c.doJSON(ctx, http.MethodGet, "/api/isos/"+id+"/verification", nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// CreateISO queues a new ISO download and returns the created ISO. On a
+// conflict, the returned APIError's Options say how to resolve it.
 func (c *Client) CreateISO(ctx context.Context, req CreateISORequest) (*ISO, error) {
 	body, err := encodeBody(req)
 	if err != nil {
 		return nil, err
 	}
+	path := "/api/isos"
+	if req.Overwrite {
+		path += "?overwrite=true"
+	}
 	var iso ISO
-	if err := c.doJSON(ctx, http.MethodPost, "/api/isos", body, &iso); err != nil {
+	if err := c.doJSON(ctx, http.MethodPost, path, body, &iso); err != nil {
 		return nil, err
 	}
 	return &iso, nil
 }
 
+// PreviewISO returns the normalized name, filename, path, and download link
+// CreateISO would give req, without creating anything. Only the name,
+// version, arch, edition, and download URL of req are used.
+func (c *Client) PreviewISO(ctx context.Context, req CreateISORequest) (*ISOPreview, error) {
+	q := url.Values{
+		"name":         {req.Name},
+		"version":      {req.Version},
+		"arch":         {req.Arch},
+		"download_url": {req.DownloadURL},
+	}
+	if req.Edition != "" {
+		q.Set("edition", req.Edition)
+	}
+	var preview ISOPreview
+	if err := c.doJSON(ctx, http.MethodGet, "/api/isos/preview?"+q.Encode(), nil, &preview); err != nil {
+		return nil, err
+	}
+	return &preview, nil
+}
+
 // UpdateISO updates an existing ISO and returns the updated ISO.
 func (c *Client) UpdateISO(ctx context.Context, id string, req UpdateISORequest) (*ISO, error) {
 	body, err := encodeBody(req)
@@ -192,6 +465,36 @@ func (c *Client) UpdateISO(ctx context.Context, id string, req UpdateISORequest)
 	return &iso, nil
 }
 
+// CloneISO queues a new ISO download from an existing ISO's definition. Set
+// fields of req override the source's; a new Version is also substituted
+// into URLs req leaves unset. Errors match CreateISO.
+func (c *Client) CloneISO(ctx context.Context, id string, req UpdateISORequest) (*ISO, error) {
+	body, err := encodeBody(req)
+	if err != nil {
+		return nil, err
+	}
+	var iso ISO
+	if err := c.doJSON(ctx, http.MethodPost, "/api/isos/"+id+"/clone", body, &iso); err != nil {
+		return nil, err
+	}
+	return &iso, nil
+}
+
+// BumpVersion queues version of each ISO in ids. ISOs created from a preset
+// are expanded from it again; others are cloned as by CloneISO. Failures are
+// reported per ISO in the result rather than as an error.
+func (c *Client) BumpVersion(ctx context.Context, ids []string, version string) (*VersionBumpResult, error) {
+	body, err := encodeBody(VersionBumpRequest{IDs: ids, Version: version})
+	if err != nil {
+		return nil, err
+	}
+	var result VersionBumpResult
+	if err := c.doJSON(ctx, http.MethodPost, "/api/isos/bump", body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
 // DeleteISO deletes an ISO by ID, removing the file and database record.
 func (c *Client) DeleteISO(ctx context.Context, id string) error {
 	return c.doJSON(ctx, http.MethodDelete, "/api/isos/"+id, nil, nil)
@@ -206,45 +509,818 @@ func (c *Client) RetryISO(ctx context.Context, id string) (*ISO, error) {
 	return &iso, nil
 }
 
-// GetStats returns aggregated statistics.
-func (c *Client) GetStats(ctx context.Context) (*Stats, error) {
-	var stats Stats
-	if err := c.doJSON(ctx, http.MethodGet, "/api/stats", nil, &stats); err != nil {
+// CancelISO takes a queued ISO off the queue, or stops a downloading or
+// verifying one, and returns it canceled.
+func (c *Client) CancelISO(ctx context.Context, id string) (*ISO, error) {
+	var iso ISO
+	if err := c.doJSON(ctx, http.MethodPost, "/api/isos/"+id+"/cancel", nil, &iso); err != nil {
 		return nil, err
 	}
-	return &stats, nil
+	return &iso, nil
 }
 
-// GetDownloadTrends returns download trend data over time.
-// Pass nil for default options (daily period, 30 days).
-func (c *Client) GetDownloadTrends(ctx context.Context, opts *DownloadTrendsOptions) (*DownloadTrends, error) {
-	path := "/api/stats/trends"
+// ListActions returns the actions the caller can take on the given ISOs, or
+// on all of them when no IDs are given.
+func (c *Client) ListActions(ctx context.Context, ids ...string) (*ActionSet, error) {
+	path := "/api/actions"
+	if len(ids) > 0 {
+		path += "?ids=" + url.QueryEscape(strings.Join(ids, ","))
+	}
+	var set ActionSet
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &set); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// CheckUpstream checks whether an ISO's upstream file changed since it was
+// downloaded. With refresh set, a changed ISO is queued for re-download.
+func (c *Client) CheckUpstream(ctx context.Context, id string, refresh bool) (*ISO, error) {
+	path := "/api/isos/" + id + "/check-upstream"
+	if refresh {
+		path += "?refresh=true"
+	}
+	var iso ISO
+	if err := c.doJSON(ctx, http.MethodPost, path, nil, &iso); err != nil {
+		return nil, err
+	}
+	return &iso, nil
+}
+
+// RefreshISO re-downloads a complete ISO into the same record when its upstream
+// changed. With force set, the download is queued without checking upstream.
+func (c *Client) RefreshISO(ctx context.Context, id string, force bool) (*ISO, error) {
+	path := "/api/isos/" + id + "/refresh"
+	if force {
+		path += "?force=true"
+	}
+	var iso ISO
+	if err := c.doJSON(ctx, http.MethodPost, path, nil, &iso); err != nil {
+		return nil, err
+	}
+	return &iso, nil
+}
+
+// RedownloadISO downloads a complete ISO again into the same record. The
+// server keeps serving the old file until the new one is verified, and keeps
+// it if the download fails.
+func (c *Client) RedownloadISO(ctx context.Context, id string) (*ISO, error) {
+	var iso ISO
+	if err := c.doJSON(ctx, http.MethodPost, "/api/isos/"+id+"/redownload", nil, &iso); err != nil {
+		return nil, err
+	}
+	return &iso, nil
+}
+
+// VerifyISO re-hashes an ISO's file on the server and compares it with the
+// stored integrity hash. A mismatch is reported in the result, not as an error.
+func (c *Client) VerifyISO(ctx context.Context, id string) (*IntegrityCheckResult, error) {
+	var result IntegrityCheckResult
+	if err := c.doJSON(ctx, http.MethodPost, "/api/isos/"+id+"/verify", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ReleaseISO moves a quarantined ISO into place and marks it complete.
+func (c *Client) ReleaseISO(ctx context.Context, id string) (*ISO, error) {
+	var iso ISO
+	if err := c.doJSON(ctx, http.MethodPost, "/api/isos/"+id+"/release", nil, &iso); err != nil {
+		return nil, err
+	}
+	return &iso, nil
+}
+
+// PinISO pins an ISO to the top of the list and exempts it from version
+// pruning, and returns the updated ISO.
+func (c *Client) PinISO(ctx context.Context, id string) (*ISO, error) {
+	var iso ISO
+	if err := c.doJSON(ctx, http.MethodPut, "/api/isos/"+id+"/pin", nil, &iso); err != nil {
+		return nil, err
+	}
+	return &iso, nil
+}
+
+// UnpinISO removes the pin from an ISO and returns the updated ISO.
+func (c *Client) UnpinISO(ctx context.Context, id string) (*ISO, error) {
+	var iso ISO
+	if err := c.doJSON(ctx, http.MethodDelete, "/api/isos/"+id+"/pin", nil, &iso); err != nil {
+		return nil, err
+	}
+	return &iso, nil
+}
+
+// AdoptDirectory registers image files from an existing mirror tree on the server
+// as complete ISOs without downloading them.
+func (c *Client) AdoptDirectory(ctx context.Context, req AdoptDirectoryRequest) (*AdoptResult, error) {
+	body, err := encodeBody(req)
+	if err != nil {
+		return nil, err
+	}
+	var result AdoptResult
+	if err := c.doJSON(ctx, http.MethodPost, "/api/isos/adopt", body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ReconcilePaths recomputes every ISO's filename, path, and download link from
+// its metadata and fixes those that drifted, moving files to match. With
+// dryRun the changes are only reported.
+func (c *Client) ReconcilePaths(ctx context.Context, dryRun bool) (*ReconcileResult, error) {
+	body, err := encodeBody(ReconcileRequest{DryRun: dryRun})
+	if err != nil {
+		return nil, err
+	}
+	var result ReconcileResult
+	if err := c.doJSON(ctx, http.MethodPost, "/api/isos/reconcile", body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ExportManifest returns a manifest of the server's ISO directory for offline transfer.
+func (c *Client) ExportManifest(ctx context.Context) (*Manifest, error) {
+	var manifest Manifest
+	if err := c.doJSON(ctx, http.MethodGet, "/api/manifest", nil, &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// ImportManifest verifies files copied to the server against a manifest and
+// registers them as complete ISOs.
+func (c *Client) ImportManifest(ctx context.Context, req ManifestImportRequest) (*ManifestImportResult, error) {
+	body, err := encodeBody(req)
+	if err != nil {
+		return nil, err
+	}
+	var result ManifestImportResult
+	if err := c.doJSON(ctx, http.MethodPost, "/api/manifest/import", body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListKnownChecksums returns the published checksums kept on the server, all
+// of them or, when checksumURL is set, those of one checksum file.
+func (c *Client) ListKnownChecksums(ctx context.Context, checksumURL string) ([]KnownChecksum, error) {
+	path := "/api/checksums"
+	if checksumURL != "" {
+		path += "?" + url.Values{"checksum_url": {checksumURL}}.Encode()
+	}
+	var checksums []KnownChecksum
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &checksums); err != nil {
+		return nil, err
+	}
+	return checksums, nil
+}
+
+// ImportChecksums stores published checksums on the server, so downloads can
+// be verified after their checksum files go away.
+func (c *Client) ImportChecksums(ctx context.Context, req ChecksumImportRequest) (*ChecksumImportResult, error) {
+	body, err := encodeBody(req)
+	if err != nil {
+		return nil, err
+	}
+	var result ChecksumImportResult
+	if err := c.doJSON(ctx, http.MethodPost, "/api/checksums/import", body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ExportBundle downloads a tar bundle of the given complete ISOs, their checksum
+// files, and a manifest. The caller is responsible for closing the returned ReadCloser.
+func (c *Client) ExportBundle(ctx context.Context, ids []string) (io.ReadCloser, error) {
+	body, err := encodeBody(map[string][]string{"ids": ids})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(ctx, http.MethodPost, "/api/bundles/export", body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Code:       "BUNDLE_EXPORT_FAILED",
+			Message:    fmt.Sprintf("unexpected status %d for bundle export", resp.StatusCode),
+		}
+	}
+	return resp.Body, nil
+}
+
+// ImportBundle uploads a tar bundle produced by ExportBundle and registers its ISOs.
+// With dryRun set, files are verified but nothing is changed.
+func (c *Client) ImportBundle(ctx context.Context, bundle io.Reader, dryRun bool) (*ManifestImportResult, error) {
+	path := "/api/bundles/import"
+	if dryRun {
+		path += "?dry_run=true"
+	}
+	var result ManifestImportResult
+	if err := c.doJSON(ctx, http.MethodPost, path, bundle, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetStats returns aggregated statistics.
+func (c *Client) GetStats(ctx context.Context) (*Stats, error) {
+	return c.GetStatsWithOptions(ctx, nil)
+}
+
+// GetStatsWithOptions returns aggregated statistics with a custom top list and breakdown.
+// Pass nil for default options (top 10, no breakdown).
+func (c *Client) GetStatsWithOptions(ctx context.Context, opts *StatsOptions) (*Stats, error) {
+	path := "/api/stats"
 	if opts != nil {
 		q := url.Values{}
-		if opts.Period != "" {
-			q.Set("period", opts.Period)
+		if opts.Top > 0 {
+			q.Set("top", strconv.Itoa(opts.Top))
 		}
-		if opts.Days > 0 {
-			q.Set("days", strconv.Itoa(opts.Days))
+		if opts.GroupBy != "" {
+			q.Set("group_by", opts.GroupBy)
+		}
+		if opts.Status != "" {
+			q.Set("status", opts.Status)
 		}
 		if encoded := q.Encode(); encoded != "" {
 			path += "?" + encoded
 		}
 	}
 
-	var trends DownloadTrends
-	if err := c.doJSON(ctx, http.MethodGet, path, nil, &trends); err != nil {
+	var stats Stats
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// GetLiveThroughput returns the latest aggregate ingest/egress throughput sample.
+func (c *Client) GetLiveThroughput(ctx context.Context) (*LiveThroughput, error) {
+	var sample LiveThroughput
+	if err := c.doJSON(ctx, http.MethodGet, "/api/stats/live", nil, &sample); err != nil {
+		return nil, err
+	}
+	return &sample, nil
+}
+
+// ListActiveDownloads returns the downloads workers are running or verifying, oldest first.
+func (c *Client) ListActiveDownloads(ctx context.Context) ([]ActiveDownload, error) {
+	var downloads []ActiveDownload
+	if err := c.doJSON(ctx, http.MethodGet, "/api/downloads/active", nil, &downloads); err != nil {
+		return nil, err
+	}
+	return downloads, nil
+}
+
+// GetQueueForecast returns the estimated start and completion times of queued
+// downloads, next in line first.
+func (c *Client) GetQueueForecast(ctx context.Context) (*QueueForecast, error) {
+	var forecast QueueForecast
+	if err := c.doJSON(ctx, http.MethodGet, "/api/downloads/queue", nil, &forecast); err != nil {
+		return nil, err
+	}
+	return &forecast, nil
+}
+
+// GetManagerState dumps the download manager's queues, workers, and in-flight
+// downloads, e.g. to find out why a download isn't moving.
+func (c *Client) GetManagerState(ctx context.Context) (*ManagerState, error) {
+	var state ManagerState
+	if err := c.doJSON(ctx, http.MethodGet, "/api/downloads/state", nil, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// GetReplicaStatus returns how a read-only replica's catalog compares with
+// its primary's. Enabled is false when the instance isn't a replica.
+func (c *Client) GetReplicaStatus(ctx context.Context) (*ReplicaStatus, error) {
+	var status ReplicaStatus
+	if err := c.doJSON(ctx, http.MethodGet, "/api/replica", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// GetDownloadTrends returns download trend data over time.
+// Pass nil for default options (daily period, 30 days).
+func (c *Client) GetDownloadTrends(ctx context.Context, opts *DownloadTrendsOptions) (*DownloadTrends, error) {
+	path := "/api/stats/trends"
+	if opts != nil {
+		q := url.Values{}
+		if opts.Period != "" {
+			q.Set("period", opts.Period)
+		}
+		if opts.Days > 0 {
+			q.Set("days", strconv.Itoa(opts.Days))
+		}
+		if encoded := q.Encode(); encoded != "" {
+			path += "?" + encoded
+		}
+	}
+
+	var trends DownloadTrends
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &trends); err != nil {
 		return nil, err
 	}
 	return &trends, nil
 }
 
+// ListMirrors returns health information for each upstream host.
+func (c *Client) ListMirrors(ctx context.Context) ([]MirrorHealth, error) {
+	var mirrors []MirrorHealth
+	if err := c.doJSON(ctx, http.MethodGet, "/api/mirrors", nil, &mirrors); err != nil {
+		return nil, err
+	}
+	return mirrors, nil
+}
+
+// StartRelocation starts copying the server's ISO directory to target. The
+// server switches over when restarted with ISO_DIR set to target.
+func (c *Client) StartRelocation(ctx context.Context, target string) (*Relocation, error) {
+	body, err := encodeBody(map[string]string{"target": target})
+	if err != nil {
+		return nil, err
+	}
+	var rel Relocation
+	if err := c.doJSON(ctx, http.MethodPost, "/api/storage/relocation", body, &rel); err != nil {
+		return nil, err
+	}
+	return &rel, nil
+}
+
+// GetRelocation returns the progress of the last or running storage relocation.
+func (c *Client) GetRelocation(ctx context.Context) (*Relocation, error) {
+	var rel Relocation
+	if err := c.doJSON(ctx, http.MethodGet, "/api/storage/relocation", nil, &rel); err != nil {
+		return nil, err
+	}
+	return &rel, nil
+}
+
+// ListCredentials returns the stored upstream credentials without their secrets.
+func (c *Client) ListCredentials(ctx context.Context) ([]Credential, error) {
+	var credentials []Credential
+	if err := c.doJSON(ctx, http.MethodGet, "/api/credentials", nil, &credentials); err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+// GetCredential returns a single credential by name.
+func (c *Client) GetCredential(ctx context.Context, name string) (*Credential, error) {
+	var credential Credential
+	if err := c.doJSON(ctx, http.MethodGet, "/api/credentials/"+url.PathEscape(name), nil, &credential); err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+// CreateCredential stores a new credential. The server must have a master key configured.
+func (c *Client) CreateCredential(ctx context.Context, req CreateCredentialRequest) (*Credential, error) {
+	body, err := encodeBody(req)
+	if err != nil {
+		return nil, err
+	}
+	var credential Credential
+	if err := c.doJSON(ctx, http.MethodPost, "/api/credentials", body, &credential); err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+// UpdateCredential updates a credential and returns it.
+func (c *Client) UpdateCredential(ctx context.Context, name string, req UpdateCredentialRequest) (*Credential, error) {
+	body, err := encodeBody(req)
+	if err != nil {
+		return nil, err
+	}
+	var credential Credential
+	if err := c.doJSON(ctx, http.MethodPut, "/api/credentials/"+url.PathEscape(name), body, &credential); err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+// DeleteCredential deletes a credential. It fails with a conflict while ISOs reference it.
+func (c *Client) DeleteCredential(ctx context.Context, name string) error {
+	return c.doJSON(ctx, http.MethodDelete, "/api/credentials/"+url.PathEscape(name), nil, nil)
+}
+
+// ListPresets returns the ISO creation presets.
+func (c *Client) ListPresets(ctx context.Context) ([]Preset, error) {
+	var presets []Preset
+	if err := c.doJSON(ctx, http.MethodGet, "/api/presets", nil, &presets); err != nil {
+		return nil, err
+	}
+	return presets, nil
+}
+
+// GetPreset returns a single preset by name.
+func (c *Client) GetPreset(ctx context.Context, name string) (*Preset, error) {
+	var preset Preset
+	if err := c.doJSON(ctx, http.MethodGet, "/api/presets/"+url.PathEscape(name), nil, &preset); err != nil {
+		return nil, err
+	}
+	return &preset, nil
+}
+
+// CreatePreset stores a new preset.
+func (c *Client) CreatePreset(ctx context.Context, req CreatePresetRequest) (*Preset, error) {
+	body, err := encodeBody(req)
+	if err != nil {
+		return nil, err
+	}
+	var preset Preset
+	if err := c.doJSON(ctx, http.MethodPost, "/api/presets", body, &preset); err != nil {
+		return nil, err
+	}
+	return &preset, nil
+}
+
+// UpdatePreset updates a preset and returns it.
+func (c *Client) UpdatePreset(ctx context.Context, name string, req UpdatePresetRequest) (*Preset, error) {
+	body, err := encodeBody(req)
+	if err != nil {
+		return nil, err
+	}
+	var preset Preset
+	if err := c.doJSON(ctx, http.MethodPut, "/api/presets/"+url.PathEscape(name), body, &preset); err != nil {
+		return nil, err
+	}
+	return &preset, nil
+}
+
+// DeletePreset deletes a preset. ISOs created from it are kept.
+func (c *Client) DeletePreset(ctx context.Context, name string) error {
+	return c.doJSON(ctx, http.MethodDelete, "/api/presets/"+url.PathEscape(name), nil, nil)
+}
+
+// ApplyPreset queues an ISO download from a preset. Errors match CreateISO.
+func (c *Client) ApplyPreset(ctx context.Context, name string, req ApplyPresetRequest) (*ISO, error) {
+	body, err := encodeBody(req)
+	if err != nil {
+		return nil, err
+	}
+	var iso ISO
+	if err := c.doJSON(ctx, http.MethodPost, "/api/presets/"+url.PathEscape(name)+"/isos", body, &iso); err != nil {
+		return nil, err
+	}
+	return &iso, nil
+}
+
+// ExportPresets returns the named presets, or all of them when none are
+// given, as a YAML preset pack for ImportPresets.
+func (c *Client) ExportPresets(ctx context.Context, names ...string) ([]byte, error) {
+	path := "/api/presets/export"
+	if len(names) > 0 {
+		path += "?names=" + url.QueryEscape(strings.Join(names, ","))
+	}
+	resp, err := c.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Code:       "PRESET_EXPORT_FAILED",
+			Message:    fmt.Sprintf("unexpected status %d for preset export", resp.StatusCode),
+		}
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// ImportPresets stores the presets of a YAML preset pack. Presets whose name
+// is taken are skipped, or overwritten with replace set.
+func (c *Client) ImportPresets(ctx context.Context, pack io.Reader, replace bool) (*PresetImportResult, error) {
+	path := "/api/presets/import"
+	if replace {
+		path += "?existing=replace"
+	}
+	var result PresetImportResult
+	if err := c.doJSON(ctx, http.MethodPost, path, pack, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetListingBranding returns the branding of the /images directory listing.
+func (c *Client) GetListingBranding(ctx context.Context) (*ListingBranding, error) {
+	var branding ListingBranding
+	if err := c.doJSON(ctx, http.MethodGet, "/api/branding", nil, &branding); err != nil {
+		return nil, err
+	}
+	return &branding, nil
+}
+
+// UpdateListingBranding changes the listing branding and returns it.
+func (c *Client) UpdateListingBranding(ctx context.Context, req UpdateListingBrandingRequest) (*ListingBranding, error) {
+	body, err := encodeBody(req)
+	if err != nil {
+		return nil, err
+	}
+	var branding ListingBranding
+	if err := c.doJSON(ctx, http.MethodPut, "/api/branding", body, &branding); err != nil {
+		return nil, err
+	}
+	return &branding, nil
+}
+
+// GetListingColumns returns the columns the /images directory listing shows.
+func (c *Client) GetListingColumns(ctx context.Context) ([]string, error) {
+	var resp struct {
+		Columns []string `json:"columns"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/api/listing/columns", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Columns, nil
+}
+
+// SetListingColumns chooses the columns the listing shows and returns them in
+// display order. An empty set shows names only.
+func (c *Client) SetListingColumns(ctx context.Context, columns []string) ([]string, error) {
+	if columns == nil {
+		columns = []string{}
+	}
+	body, err := encodeBody(map[string][]string{"columns": columns})
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Columns []string `json:"columns"`
+	}
+	if err := c.doJSON(ctx, http.MethodPut, "/api/listing/columns", body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Columns, nil
+}
+
+// GetChanges lists the ISOs added, removed, and updated since a time, as
+// recorded by the server's catalog snapshots. It fails with a 404 APIError
+// until the first snapshot has been taken.
+func (c *Client) GetChanges(ctx context.Context, since time.Time) (*CatalogChanges, error) {
+	q := url.Values{}
+	q.Set("since", since.Format(time.RFC3339))
+	var changes CatalogChanges
+	if err := c.doJSON(ctx, http.MethodGet, "/api/changes?"+q.Encode(), nil, &changes); err != nil {
+		return nil, err
+	}
+	return &changes, nil
+}
+
+// ListWebhooks returns the inbound webhooks without their secrets.
+func (c *Client) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	var webhooks []Webhook
+	if err := c.doJSON(ctx, http.MethodGet, "/api/hooks", nil, &webhooks); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// GetWebhook returns a single webhook by name.
+func (c *Client) GetWebhook(ctx context.Context, name string) (*Webhook, error) {
+	var webhook Webhook
+	if err := c.doJSON(ctx, http.MethodGet, "/api/hooks/"+url.PathEscape(name), nil, &webhook); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// CreateWebhook creates a webhook. The returned webhook carries the signing
+// secret, which can't be retrieved later. The server must have a master key configured.
+func (c *Client) CreateWebhook(ctx context.Context, req CreateWebhookRequest) (*Webhook, error) {
+	body, err := encodeBody(req)
+	if err != nil {
+		return nil, err
+	}
+	var webhook Webhook
+	if err := c.doJSON(ctx, http.MethodPost, "/api/hooks", body, &webhook); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// UpdateWebhook updates a webhook and returns it, with the new secret when
+// req rotates it.
+func (c *Client) UpdateWebhook(ctx context.Context, name string, req UpdateWebhookRequest) (*Webhook, error) {
+	body, err := encodeBody(req)
+	if err != nil {
+		return nil, err
+	}
+	var webhook Webhook
+	if err := c.doJSON(ctx, http.MethodPut, "/api/hooks/"+url.PathEscape(name), body, &webhook); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// DeleteWebhook deletes a webhook.
+func (c *Client) DeleteWebhook(ctx context.Context, name string) error {
+	return c.doJSON(ctx, http.MethodDelete, "/api/hooks/"+url.PathEscape(name), nil, nil)
+}
+
+// ResetDownloadStats clears an ISO's download count and download events.
+// The reason is recorded in the audit log.
+func (c *Client) ResetDownloadStats(ctx context.Context, id, reason string) (*ISO, error) {
+	body, err := encodeBody(map[string]string{"reason": reason})
+	if err != nil {
+		return nil, err
+	}
+	var iso ISO
+	if err := c.doJSON(ctx, http.MethodPost, "/api/isos/"+id+"/stats/reset", body, &iso); err != nil {
+		return nil, err
+	}
+	return &iso, nil
+}
+
+// AdjustDownloadCount adds delta, which may be negative, to an ISO's download count.
+// The count never drops below zero. The reason is recorded in the audit log.
+func (c *Client) AdjustDownloadCount(ctx context.Context, id string, delta int64, reason string) (*ISO, error) {
+	body, err := encodeBody(map[string]any{"delta": delta, "reason": reason})
+	if err != nil {
+		return nil, err
+	}
+	var iso ISO
+	if err := c.doJSON(ctx, http.MethodPost, "/api/isos/"+id+"/stats/adjust", body, &iso); err != nil {
+		return nil, err
+	}
+	return &iso, nil
+}
+
+// ListDownloadEvents returns up to limit recent download events of an ISO,
+// newest first. A limit of zero uses the server default.
+func (c *Client) ListDownloadEvents(ctx context.Context, id string, limit int) ([]DownloadEvent, error) {
+	path := "/api/isos/" + id + "/stats/downloads"
+	if limit > 0 {
+		path += "?limit=" + strconv.Itoa(limit)
+	}
+	var events []DownloadEvent
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ListAuditEvents returns up to limit recent audit events, newest first.
+// A limit of zero uses the server default.
+func (c *Client) ListAuditEvents(ctx context.Context, limit int) ([]AuditEvent, error) {
+	path := "/api/audit"
+	if limit > 0 {
+		path += "?limit=" + strconv.Itoa(limit)
+	}
+	var events []AuditEvent
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ListSystemEvents returns recorded health state changes, newest first.
+// Pass nil for default options (last 100 events of any severity).
+func (c *Client) ListSystemEvents(ctx context.Context, opts *SystemEventsOptions) ([]SystemEvent, error) {
+	path := "/api/system/events"
+	if opts != nil {
+		q := url.Values{}
+		if !opts.Since.IsZero() {
+			q.Set("since", opts.Since.Format(time.RFC3339))
+		}
+		if opts.Severity != "" {
+			q.Set("severity", opts.Severity)
+		}
+		if opts.Limit > 0 {
+			q.Set("limit", strconv.Itoa(opts.Limit))
+		}
+		if encoded := q.Encode(); encoded != "" {
+			path += "?" + encoded
+		}
+	}
+
+	var events []SystemEvent
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ListGCReports returns reports of files deleted by garbage collection, newest first.
+// Pass nil for default options (last 100 reports of any reason).
+func (c *Client) ListGCReports(ctx context.Context, opts *GCReportsOptions) ([]GCReport, error) {
+	path := "/api/gc/reports"
+	if opts != nil {
+		q := url.Values{}
+		if !opts.Since.IsZero() {
+			q.Set("since", opts.Since.Format(time.RFC3339))
+		}
+		if opts.Reason != "" {
+			q.Set("reason", opts.Reason)
+		}
+		if opts.ISOID != "" {
+			q.Set("iso_id", opts.ISOID)
+		}
+		if opts.Limit > 0 {
+			q.Set("limit", strconv.Itoa(opts.Limit))
+		}
+		if encoded := q.Encode(); encoded != "" {
+			path += "?" + encoded
+		}
+	}
+
+	var reports []GCReport
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &reports); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// GetGCReport returns a garbage collection report with the files it removed.
+func (c *Client) GetGCReport(ctx context.Context, id int64) (*GCReport, error) {
+	var report GCReport
+	if err := c.doJSON(ctx, http.MethodGet, "/api/gc/reports/"+strconv.FormatInt(id, 10), nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// GetLogSettings returns the server's current log level and format.
+func (c *Client) GetLogSettings(ctx context.Context) (*LogSettings, error) {
+	var settings LogSettings
+	if err := c.doJSON(ctx, http.MethodGet, "/api/admin/log-level", nil, &settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// SetLogSettings changes the server's log level, format, or both until it
+// restarts; pass an empty string to leave one as it is. The reason is
+// recorded in the audit log.
+func (c *Client) SetLogSettings(ctx context.Context, level, format, reason string) (*LogSettings, error) {
+	body, err := encodeBody(map[string]string{"level": level, "format": format, "reason": reason})
+	if err != nil {
+		return nil, err
+	}
+	var settings LogSettings
+	if err := c.doJSON(ctx, http.MethodPatch, "/api/admin/log-level", body, &settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
 // Health checks whether the ISOMan server is healthy.
 // Returns nil if healthy, or an error otherwise.
 func (c *Client) Health(ctx context.Context) error {
 	return c.doJSON(ctx, http.MethodGet, "/health", nil, nil)
 }
 
+// Version returns the server's build version and how its database schema
+// compares, e.g. to check every replica runs the release that migrated it.
+func (c *Client) Version(ctx context.Context) (*VersionInfo, error) {
+	var info VersionInfo
+	if err := c.doJSON(ctx, http.MethodGet, "/api/version", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Status returns the public status summary: health, the number of complete
+// ISOs, and when the last download completed.
+func (c *Client) Status(ctx context.Context) (*InstanceStatus, error) {
+	var status InstanceStatus
+	if err := c.doJSON(ctx, http.MethodGet, "/status", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Discover fetches the instance's /.well-known/isoman.json document, which
+// needs no session, e.g. to find its API base or whether it requires auth.
+func (c *Client) Discover(ctx context.Context) (*Discovery, error) {
+	const path = "/.well-known/isoman.json"
+	resp, err := c.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Code:       "DISCOVERY_FAILED",
+			Message:    fmt.Sprintf("unexpected status %d for %s", resp.StatusCode, path),
+		}
+	}
+
+	var doc Discovery
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("isoman: decode discovery document: %w", err)
+	}
+	return &doc, nil
+}
+
 // DownloadFile downloads a file from the /images/ endpoint.
 // filePath is the path relative to /images/ (e.g. "alpine/3.19.1/x86_64/alpine-3.19.1-x86_64.iso").
 // The caller is responsible for closing the returned ReadCloser.
@@ -1,12 +1,14 @@
 package client
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -148,25 +150,36 @@ func TestListISOsWithOptions(t *testing.T) {
 		if q.Get("sort_dir") != "asc" {
 			t.Errorf("sort_dir = %q, want %q", q.Get("sort_dir"), "asc")
 		}
+		if q.Get("fields") != "id,status" {
+			t.Errorf("fields = %q, want %q", q.Get("fields"), "id,status")
+		}
+		if q.Get("cursor") != "abc" {
+			t.Errorf("cursor = %q, want %q", q.Get("cursor"), "abc")
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		w.Write(envelope(map[string]any{
 			"isos":       []any{},
-			"pagination": map[string]any{"page": 2, "page_size": 5, "total": 0, "total_pages": 0},
+			"pagination": map[string]any{"page_size": 5, "next_cursor": "def"},
 		}))
 	}))
 	defer ts.Close()
 
 	c := NewClient(ts.URL)
-	_, err := c.ListISOs(context.Background(), &ListISOsOptions{
+	result, err := c.ListISOs(context.Background(), &ListISOsOptions{
 		Page:     2,
 		PageSize: 5,
 		SortBy:   "name",
 		SortDir:  "asc",
+		Cursor:   "abc",
+		Fields:   []string{"id", "status"},
 	})
 	if err != nil {
 		t.Fatalf("ListISOs() error: %v", err)
 	}
+	if result.Pagination.NextCursor != "def" {
+		t.Errorf("NextCursor = %q, want %q", result.Pagination.NextCursor, "def")
+	}
 }
 
 func TestGetISO(t *testing.T) {
@@ -192,6 +205,88 @@ func TestGetISO(t *testing.T) {
 	}
 }
 
+func TestFindISOsBySHA256(t *testing.T) {
+	sum := strings.Repeat("ab", 32)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/api/isos/by-checksum/"+sum {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write(envelopeError("NOT_FOUND", "No complete ISO has this checksum"))
+			return
+		}
+		w.Write(envelope([]any{sampleISO()}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	isos, err := c.FindISOsBySHA256(context.Background(), sum)
+	if err != nil {
+		t.Fatalf("FindISOsBySHA256() error: %v", err)
+	}
+	if len(isos) != 1 || isos[0].Name != "alpine" {
+		t.Errorf("isos = %+v, want alpine", isos)
+	}
+
+	if _, err := c.FindISOsBySHA256(context.Background(), strings.Repeat("cd", 32)); !IsNotFound(err) {
+		t.Errorf("FindISOsBySHA256() error = %v, want not found", err)
+	}
+}
+
+func TestGetISOSnippets(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/isos/iso-1/snippets" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope(map[string]any{
+			"iso_id":        "iso-1",
+			"url":           "https://isos.example.com/images/alpine/3.19.1/x86_64/alpine-3.19.1-x86_64.iso",
+			"requires_auth": true,
+			"snippets": []map[string]any{
+				{"type": "ipxe", "filename": "alpine.ipxe", "content": "#!ipxe\n"},
+			},
+		}))
+	}))
+	defer ts.Close()
+
+	snippets, err := NewClient(ts.URL).GetISOSnippets(context.Background(), "iso-1")
+	if err != nil {
+		t.Fatalf("GetISOSnippets() error: %v", err)
+	}
+	if !snippets.RequiresAuth || len(snippets.Snippets) != 1 || snippets.Snippets[0].Type != "ipxe" {
+		t.Errorf("snippets = %+v", snippets)
+	}
+}
+
+func TestPreviewISO(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.Method != http.MethodGet || r.URL.Path != "/api/isos/preview" || q.Get("name") != "Alpine Linux" || q.Get("download_url") != "https://example.com/alpine.iso" {
+			t.Errorf("got %s %s, want GET /api/isos/preview with the request fields", r.Method, r.URL.String())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope(map[string]any{
+			"name":      "alpine-linux",
+			"file_path": "alpine-linux/3.19.1/x86_64/alpine-linux-3.19.1-x86_64.iso",
+		}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	preview, err := c.PreviewISO(context.Background(), CreateISORequest{
+		Name:        "Alpine Linux",
+		Version:     "3.19.1",
+		Arch:        "x86_64",
+		DownloadURL: "https://example.com/alpine.iso",
+	})
+	if err != nil {
+		t.Fatalf("PreviewISO() error: %v", err)
+	}
+	if preview.Name != "alpine-linux" || preview.ExistingID != "" {
+		t.Errorf("preview = %+v, want alpine-linux with no existing ISO", preview)
+	}
+}
+
 func TestGetISONotFound(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -210,6 +305,55 @@ func TestGetISONotFound(t *testing.T) {
 	}
 }
 
+func TestGetDownloadLog(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/isos/abc/log" {
+			t.Errorf("path = %s, want /api/isos/abc/log", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope([]map[string]any{
+			{"id": float64(1), "iso_id": "abc", "level": "info", "message": "Downloading https://example.com/a.iso", "logged_at": "2024-01-01T00:00:00Z"},
+			{"id": float64(2), "iso_id": "abc", "level": "error", "message": "Failed: server returned 404", "logged_at": "2024-01-01T00:00:01Z"},
+		}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	entries, err := c.GetDownloadLog(context.Background(), "abc")
+	if err != nil {
+		t.Fatalf("GetDownloadLog() error: %v", err)
+	}
+	if len(entries) != 2 || entries[1].Level != "error" {
+		t.Errorf("entries = %+v, want two ending in an error", entries)
+	}
+}
+
+func TestGetVerificationReport(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/isos/abc/verification" {
+			t.Errorf("path = %s, want /api/isos/abc/verification", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope(map[string]any{
+			"iso_id":    "abc",
+			"verified":  true,
+			"checksum":  map[string]any{"status": "verified", "method": "checksum_file", "type": "sha256"},
+			"signature": map[string]any{"status": "none"},
+			"hashes":    map[string]any{"sha256": "e3b0c442", "size_bytes": float64(1024)},
+		}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	report, err := c.GetVerificationReport(context.Background(), "abc")
+	if err != nil {
+		t.Fatalf("GetVerificationReport() error: %v", err)
+	}
+	if !report.Verified || report.Checksum.Method != "checksum_file" || report.Signature.Status != "none" || report.Hashes.SizeBytes != 1024 {
+		t.Errorf("report = %+v, want a verified checksum and no signature", report)
+	}
+}
+
 func TestCreateISO(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -255,6 +399,199 @@ func TestCreateISO(t *testing.T) {
 	}
 }
 
+func TestReconcilePaths(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/isos/reconcile" {
+			t.Errorf("request = %s %s, want POST /api/isos/reconcile", r.Method, r.URL.Path)
+		}
+		var req ReconcileRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if !req.DryRun {
+			t.Error("want dry_run")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope(map[string]any{
+			"checked": 2,
+			"fixed":   []any{map[string]any{"iso_id": "abc", "fields": []string{"file_path"}, "file_moved": true}},
+			"skipped": []any{map[string]any{"iso_id": "def", "reason": "download in progress"}},
+			"dry_run": true,
+		}))
+	}))
+	defer ts.Close()
+
+	result, err := NewClient(ts.URL).ReconcilePaths(context.Background(), true)
+	if err != nil {
+		t.Fatalf("ReconcilePaths() error: %v", err)
+	}
+	if result.Checked != 2 || len(result.Fixed) != 1 || !result.Fixed[0].FileMoved || len(result.Skipped) != 1 || !result.DryRun {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestAdoptDirectory(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/isos/adopt" {
+			t.Errorf("request = %s %s, want POST /api/isos/adopt", r.Method, r.URL.Path)
+		}
+
+		var req AdoptDirectoryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if req.SourceDir != "/srv/mirror" || !req.DryRun {
+			t.Errorf("req = %+v, want source_dir /srv/mirror with dry_run", req)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope(map[string]any{
+			"adopted": []any{map[string]any{"source_path": "alpine/3.19.1/x86_64/a.iso", "iso": sampleISO()}},
+			"skipped": []any{},
+			"dry_run": true,
+		}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	result, err := c.AdoptDirectory(context.Background(), AdoptDirectoryRequest{SourceDir: "/srv/mirror", DryRun: true})
+	if err != nil {
+		t.Fatalf("AdoptDirectory() error: %v", err)
+	}
+	if len(result.Adopted) != 1 || result.Adopted[0].ISO == nil {
+		t.Fatalf("Adopted = %+v, want one entry with ISO", result.Adopted)
+	}
+	if !result.DryRun {
+		t.Error("DryRun = false, want true")
+	}
+}
+
+func TestImportChecksums(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/checksums/import":
+			var req ChecksumImportRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("decode request: %v", err)
+			}
+			if req.Source != "bundle" || len(req.Checksums) != 1 || req.Checksums[0].Filename != "a.iso" {
+				t.Errorf("req = %+v, want one checksum from bundle", req)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(envelope(map[string]any{"imported": float64(1)}))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/checksums":
+			if got := r.URL.Query().Get("checksum_url"); got != "https://example.com/SHA256SUMS" {
+				t.Errorf("checksum_url = %q", got)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(envelope([]map[string]any{{
+				"checksum_url": "https://example.com/SHA256SUMS",
+				"filename":     "a.iso",
+				"checksum":     "abc",
+				"source":       "bundle",
+				"updated_at":   "2024-01-01T00:00:00Z",
+			}}))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	result, err := c.ImportChecksums(context.Background(), ChecksumImportRequest{
+		Source:    "bundle",
+		Checksums: []KnownChecksum{{ChecksumURL: "https://example.com/SHA256SUMS", Filename: "a.iso", Checksum: "abc"}},
+	})
+	if err != nil {
+		t.Fatalf("ImportChecksums() error: %v", err)
+	}
+	if result.Imported != 1 {
+		t.Errorf("Imported = %d, want 1", result.Imported)
+	}
+
+	checksums, err := c.ListKnownChecksums(context.Background(), "https://example.com/SHA256SUMS")
+	if err != nil {
+		t.Fatalf("ListKnownChecksums() error: %v", err)
+	}
+	if len(checksums) != 1 || checksums[0].Source != "bundle" {
+		t.Errorf("checksums = %+v, want one from bundle", checksums)
+	}
+}
+
+func TestImportManifest(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/manifest/import" {
+			t.Errorf("request = %s %s, want POST /api/manifest/import", r.Method, r.URL.Path)
+		}
+
+		var req ManifestImportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if req.Manifest == nil || len(req.Manifest.Entries) != 1 {
+			t.Errorf("req.Manifest = %+v, want one entry", req.Manifest)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope(map[string]any{
+			"imported": []any{sampleISO()},
+			"skipped":  []any{},
+			"invalid":  []any{},
+			"dry_run":  false,
+		}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	result, err := c.ImportManifest(context.Background(), ManifestImportRequest{
+		SourceDir: "/mnt/usb/isos",
+		Manifest: &Manifest{
+			Version: 1,
+			Entries: []ManifestEntry{{Path: "alpine/3.19.1/x86_64/a.iso", SHA256: "abc", SizeBytes: 1}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ImportManifest() error: %v", err)
+	}
+	if len(result.Imported) != 1 {
+		t.Errorf("Imported = %d, want 1", len(result.Imported))
+	}
+}
+
+func TestImportBundle(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/bundles/import" {
+			t.Errorf("request = %s %s, want POST /api/bundles/import", r.Method, r.URL.Path)
+		}
+		if r.URL.Query().Get("dry_run") != "true" {
+			t.Errorf("dry_run = %q, want true", r.URL.Query().Get("dry_run"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "tar-bytes" {
+			t.Errorf("body = %q, want tar-bytes", body)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope(map[string]any{
+			"imported": []any{sampleISO()},
+			"skipped":  []any{},
+			"invalid":  []any{},
+			"dry_run":  true,
+		}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	result, err := c.ImportBundle(context.Background(), strings.NewReader("tar-bytes"), true)
+	if err != nil {
+		t.Fatalf("ImportBundle() error: %v", err)
+	}
+	if len(result.Imported) != 1 || !result.DryRun {
+		t.Errorf("result = %+v, want one imported ISO in dry run", result)
+	}
+}
+
 func TestCreateISOConflict(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -278,6 +615,42 @@ func TestCreateISOConflict(t *testing.T) {
 	}
 }
 
+func TestCreateISOOverwrite(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("overwrite") != "true" {
+			w.WriteHeader(http.StatusConflict)
+			resp, _ := json.Marshal(map[string]any{
+				"success": false,
+				"error":   map[string]any{"code": "CONFLICT", "message": "ISO already exists"},
+				"data": map[string]any{
+					"existing": sampleISO(),
+					"options":  []map[string]any{{"action": "overwrite", "method": "POST", "path": "/api/isos?overwrite=true"}},
+				},
+			})
+			w.Write(resp)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		resp, _ := json.Marshal(map[string]any{"success": true, "data": sampleISO()})
+		w.Write(resp)
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	req := CreateISORequest{Name: "Alpine Linux", Version: "3.19.1", Arch: "x86_64", DownloadURL: "https://example.com/alpine.iso"}
+	_, err := c.CreateISO(context.Background(), req)
+	apiErr, ok := err.(*APIError)
+	if !ok || len(apiErr.Options) != 1 || apiErr.Options[0].Action != "overwrite" {
+		t.Fatalf("expected an overwrite option, got %v", err)
+	}
+
+	req.Overwrite = true
+	if _, err := c.CreateISO(context.Background(), req); err != nil {
+		t.Fatalf("CreateISO() with overwrite: %v", err)
+	}
+}
+
 func TestUpdateISO(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPut {
@@ -358,6 +731,38 @@ func TestRetryISO(t *testing.T) {
 	}
 }
 
+func TestListActions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/api/actions" {
+			t.Errorf("request = %s %s, want GET /api/actions", r.Method, r.URL.Path)
+		}
+		if got := r.URL.Query().Get("ids"); got != "a,b" {
+			t.Errorf("ids = %q, want a,b", got)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope(map[string]any{
+			"role":      "operator",
+			"read_only": false,
+			"routes":    map[string]any{"retry": map[string]any{"method": "POST", "path": "/api/isos/{id}/retry"}},
+			"isos": []map[string]any{
+				{"id": "a", "status": "failed", "actions": []string{"retry", "delete"}},
+				{"id": "b", "status": "pending", "actions": []string{"delete"}},
+			},
+		}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	set, err := c.ListActions(context.Background(), "a", "b")
+	if err != nil {
+		t.Fatalf("ListActions() error: %v", err)
+	}
+	if len(set.ISOs) != 2 || set.ISOs[0].Actions[0] != ActionRetry || set.Routes[ActionRetry].Path != "/api/isos/{id}/retry" {
+		t.Errorf("set = %+v, want a retryable first ISO", set)
+	}
+}
+
 func TestRetryISOInvalidState(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -387,17 +792,20 @@ func TestGetStats(t *testing.T) {
 		}
 		w.Header().Set("Content-Type", "application/json")
 		w.Write(envelope(map[string]any{
-			"total_isos":       float64(10),
-			"completed_isos":   float64(8),
-			"failed_isos":      float64(1),
-			"pending_isos":     float64(1),
-			"total_size_bytes": float64(1073741824),
-			"total_downloads":  float64(42),
-			"bandwidth_saved":  float64(536870912),
-			"isos_by_arch":     map[string]any{"x86_64": float64(7), "aarch64": float64(3)},
-			"isos_by_edition":  map[string]any{"": float64(5), "server": float64(5)},
-			"isos_by_status":   map[string]any{"complete": float64(8), "failed": float64(1), "pending": float64(1)},
-			"top_downloaded":   []any{},
+			"total_isos":            float64(10),
+			"completed_isos":        float64(8),
+			"failed_isos":           float64(1),
+			"pending_isos":          float64(1),
+			"verified_isos":         float64(6),
+			"unverified_isos":       float64(3),
+			"unverified_size_bytes": float64(268435456),
+			"total_size_bytes":      float64(1073741824),
+			"total_downloads":       float64(42),
+			"bandwidth_saved":       float64(536870912),
+			"isos_by_arch":          map[string]any{"x86_64": float64(7), "aarch64": float64(3)},
+			"isos_by_edition":       map[string]any{"": float64(5), "server": float64(5)},
+			"isos_by_status":        map[string]any{"complete": float64(8), "failed": float64(1), "pending": float64(1)},
+			"top_downloaded":        []any{},
 		}))
 	}))
 	defer ts.Close()
@@ -416,87 +824,907 @@ func TestGetStats(t *testing.T) {
 	if stats.ISOsByArch["x86_64"] != 7 {
 		t.Errorf("ISOsByArch[x86_64] = %d, want 7", stats.ISOsByArch["x86_64"])
 	}
+	if stats.UnverifiedISOs != 3 || stats.UnverifiedSizeBytes != 268435456 {
+		t.Errorf("UnverifiedISOs = %d, UnverifiedSizeBytes = %d, want 3 and 268435456", stats.UnverifiedISOs, stats.UnverifiedSizeBytes)
+	}
 }
 
-func TestGetDownloadTrends(t *testing.T) {
+func TestGetStatsWithOptions(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/api/stats/trends" {
-			t.Errorf("path = %s, want /api/stats/trends", r.URL.Path)
-		}
 		q := r.URL.Query()
-		if q.Get("period") != "weekly" {
-			t.Errorf("period = %q, want %q", q.Get("period"), "weekly")
-		}
-		if q.Get("days") != "7" {
-			t.Errorf("days = %q, want %q", q.Get("days"), "7")
+		if q.Get("top") != "25" || q.Get("group_by") != "arch" || q.Get("status") != "complete" {
+			t.Errorf("query = %s, want top=25&group_by=arch&status=complete", r.URL.RawQuery)
 		}
-
 		w.Header().Set("Content-Type", "application/json")
 		w.Write(envelope(map[string]any{
-			"period": "weekly",
-			"data": []any{
-				map[string]any{"date": "2024-01-01", "count": float64(5)},
+			"total_isos": float64(2),
+			"group_by":   "arch",
+			"groups": []any{
+				map[string]any{"key": "x86_64", "iso_count": float64(2), "download_count": float64(9)},
 			},
 		}))
 	}))
 	defer ts.Close()
 
 	c := NewClient(ts.URL)
-	trends, err := c.GetDownloadTrends(context.Background(), &DownloadTrendsOptions{
-		Period: "weekly",
-		Days:   7,
-	})
+	stats, err := c.GetStatsWithOptions(context.Background(), &StatsOptions{Top: 25, GroupBy: "arch", Status: "complete"})
 	if err != nil {
-		t.Fatalf("GetDownloadTrends() error: %v", err)
-	}
-	if trends.Period != "weekly" {
-		t.Errorf("Period = %q, want %q", trends.Period, "weekly")
-	}
-	if len(trends.Data) != 1 {
-		t.Fatalf("len(Data) = %d, want 1", len(trends.Data))
+		t.Fatalf("GetStatsWithOptions() error: %v", err)
 	}
-	if trends.Data[0].Count != 5 {
-		t.Errorf("Data[0].Count = %d, want 5", trends.Data[0].Count)
+	if stats.GroupBy != "arch" || len(stats.Groups) != 1 || stats.Groups[0].DownloadCount != 9 {
+		t.Errorf("Groups = %+v, want one x86_64 group with 9 downloads", stats.Groups)
 	}
 }
 
-func TestHealth(t *testing.T) {
+func TestListActiveDownloads(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/health" {
-			t.Errorf("path = %s, want /health", r.URL.Path)
+		if r.URL.Path != "/api/downloads/active" {
+			t.Errorf("path = %s, want /api/downloads/active", r.URL.Path)
 		}
 		w.Header().Set("Content-Type", "application/json")
-		w.Write(envelope(map[string]any{
-			"status": "ok",
-			"time":   "2024-01-01T00:00:00Z",
-		}))
+		w.Write(envelope([]map[string]any{{
+			"iso_id":           "abc",
+			"name":             "alpine",
+			"node":             "isoman-0",
+			"worker_id":        float64(1),
+			"status":           "downloading",
+			"bytes_downloaded": float64(1024),
+			"bytes_total":      float64(4096),
+			"bytes_per_sec":    float64(512),
+			"started_at":       "2024-01-01T00:00:00Z",
+		}}))
 	}))
 	defer ts.Close()
 
 	c := NewClient(ts.URL)
-	err := c.Health(context.Background())
+	downloads, err := c.ListActiveDownloads(context.Background())
 	if err != nil {
-		t.Fatalf("Health() error: %v", err)
+		t.Fatalf("ListActiveDownloads() error: %v", err)
+	}
+	if len(downloads) != 1 || downloads[0].Node != "isoman-0" || downloads[0].WorkerID != 1 || downloads[0].Status != StatusDownloading || downloads[0].BytesPerSec != 512 {
+		t.Errorf("downloads = %+v, want one downloading on worker 1", downloads)
 	}
 }
 
-func TestHealthUnhealthy(t *testing.T) {
+func TestGetQueueForecast(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/downloads/queue" {
+			t.Errorf("path = %s, want /api/downloads/queue", r.URL.Path)
+		}
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusServiceUnavailable)
-		w.Write(envelopeError("INTERNAL_ERROR", "database unreachable"))
+		w.Write(envelope(map[string]any{
+			"generated_at":  "2024-01-01T00:00:00Z",
+			"bytes_per_sec": float64(1048576),
+			"queue": []map[string]any{{
+				"iso_id":                  "abc",
+				"name":                    "alpine",
+				"position":                float64(1),
+				"estimated_start_at":      "2024-01-01T00:05:00Z",
+				"estimated_completion_at": "2024-01-01T00:10:00Z",
+			}, {
+				"iso_id":   "def",
+				"name":     "debian",
+				"position": float64(2),
+			}},
+		}))
 	}))
 	defer ts.Close()
 
 	c := NewClient(ts.URL)
-	err := c.Health(context.Background())
-	if err == nil {
-		t.Fatal("expected error, got nil")
+	forecast, err := c.GetQueueForecast(context.Background())
+	if err != nil {
+		t.Fatalf("GetQueueForecast() error: %v", err)
+	}
+	if forecast.BytesPerSec != 1048576 || len(forecast.Queue) != 2 {
+		t.Fatalf("forecast = %+v, want two queued downloads", forecast)
+	}
+	if start := forecast.Queue[0].EstimatedStartAt; start == nil || start.Minute() != 5 {
+		t.Errorf("Queue[0].EstimatedStartAt = %v, want 00:05", start)
+	}
+	if forecast.Queue[1].EstimatedStartAt != nil {
+		t.Errorf("Queue[1].EstimatedStartAt = %v, want nil", forecast.Queue[1].EstimatedStartAt)
 	}
 }
 
-func TestDownloadFile(t *testing.T) {
-	content := "fake-iso-content"
+func TestGetManagerState(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/downloads/state" {
+			t.Errorf("path = %s, want /api/downloads/state", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope(map[string]any{
+			"node":      "isoman-1",
+			"queues":    map[string]any{"queued": 1, "capacity": 100},
+			"workers":   []map[string]any{{"id": 0, "lane": "regular", "state": "downloading", "iso_id": "abc"}},
+			"in_flight": []map[string]any{{"iso_id": "abc", "temp_file": "alpine.iso", "status": "downloading"}, {"iso_id": "def", "status": "queued"}},
+			"locks":     []map[string]any{{"iso_id": "abc", "node": "isoman-1"}},
+		}))
+	}))
+	defer ts.Close()
+
+	state, err := NewClient(ts.URL).GetManagerState(context.Background())
+	if err != nil {
+		t.Fatalf("GetManagerState() error: %v", err)
+	}
+	if state.Queues.Queued != 1 || len(state.Workers) != 1 || state.Workers[0].ISOID != "abc" || len(state.InFlight) != 2 || state.InFlight[1].Status != StatusQueued || len(state.Locks) != 1 {
+		t.Errorf("state = %+v", state)
+	}
+}
+
+func TestGetReplicaStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/replica" {
+			t.Errorf("path = %s, want /api/replica", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope(map[string]any{
+			"enabled":        true,
+			"primary_url":    "https://isos.example.com",
+			"last_synced_at": "2024-01-01T00:00:00Z",
+			"last_error":     "",
+			"isos":           float64(12),
+			"waiting":        float64(1),
+		}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	status, err := c.GetReplicaStatus(context.Background())
+	if err != nil {
+		t.Fatalf("GetReplicaStatus() error: %v", err)
+	}
+	if !status.Enabled || status.ISOs != 12 || status.Waiting != 1 || status.LastSyncedAt == nil {
+		t.Errorf("status = %+v, want an enabled replica with 12 ISOs and 1 waiting", status)
+	}
+}
+
+func TestGetLiveThroughput(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/stats/live" {
+			t.Errorf("path = %s, want /api/stats/live", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope(map[string]any{
+			"sampled_at":           "2024-01-01T00:00:00Z",
+			"ingest_bytes_per_sec": float64(2048),
+			"egress_bytes_per_sec": float64(512),
+			"ingest_bytes_total":   float64(4096),
+			"egress_bytes_total":   float64(1024),
+		}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	sample, err := c.GetLiveThroughput(context.Background())
+	if err != nil {
+		t.Fatalf("GetLiveThroughput() error: %v", err)
+	}
+	if sample.IngestBytesPerSec != 2048 || sample.EgressBytesPerSec != 512 {
+		t.Errorf("rates = %d/%d, want 2048/512", sample.IngestBytesPerSec, sample.EgressBytesPerSec)
+	}
+	if sample.SampledAt.IsZero() {
+		t.Error("SampledAt is zero")
+	}
+}
+
+func TestLoginSendsToken(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/auth/login":
+			w.Write(envelope(map[string]any{
+				"token": "session-token",
+				"user":  map[string]any{"id": "u1", "username": "admin"},
+			}))
+		case "/api/auth/me":
+			if got := r.Header.Get("Authorization"); got != "Bearer session-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(envelopeError("UNAUTHORIZED", "Authentication required"))
+				return
+			}
+			w.Write(envelope(map[string]any{"id": "u1", "username": "admin"}))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	if _, err := c.Me(context.Background()); !IsUnauthorized(err) {
+		t.Errorf("IsUnauthorized() = false before login; err = %v", err)
+	}
+	resp, err := c.Login(context.Background(), "admin", "correct horse")
+	if err != nil {
+		t.Fatalf("Login() error: %v", err)
+	}
+	if resp.User == nil || resp.User.Username != "admin" {
+		t.Errorf("User = %+v, want admin", resp.User)
+	}
+	user, err := c.Me(context.Background())
+	if err != nil {
+		t.Fatalf("Me() error: %v", err)
+	}
+	if user.ID != "u1" {
+		t.Errorf("ID = %q, want u1", user.ID)
+	}
+}
+
+func TestConfirmTOTP(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/auth/totp/confirm" {
+			t.Errorf("path = %s, want /api/auth/totp/confirm", r.URL.Path)
+		}
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["code"] != "123456" {
+			t.Errorf("code = %q, want 123456", body["code"])
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope(map[string]any{"recovery_codes": []string{"abcde-fghij", "klmno-pqrst"}}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	codes, err := c.ConfirmTOTP(context.Background(), "123456")
+	if err != nil {
+		t.Fatalf("ConfirmTOTP() error: %v", err)
+	}
+	if len(codes) != 2 || codes[0] != "abcde-fghij" {
+		t.Errorf("codes = %v, want 2 recovery codes", codes)
+	}
+}
+
+func TestCreateCredential(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+		if r.URL.Path != "/api/credentials" {
+			t.Errorf("path = %s, want /api/credentials", r.URL.Path)
+		}
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		secret, _ := body["secret"].(map[string]any)
+		if secret["token"] != "abc123" {
+			t.Errorf("secret.token = %v, want abc123", secret["token"])
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(envelope(map[string]any{
+			"name": body["name"],
+			"type": body["type"],
+			"host": body["host"],
+		}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	credential, err := c.CreateCredential(context.Background(), CreateCredentialRequest{
+		Name:   "vendor",
+		Type:   "token",
+		Host:   "downloads.vendor.example",
+		Secret: CredentialSecret{Token: "abc123"},
+	})
+	if err != nil {
+		t.Fatalf("CreateCredential() error: %v", err)
+	}
+	if credential.Name != "vendor" || credential.Host != "downloads.vendor.example" {
+		t.Errorf("credential = %+v, want vendor on downloads.vendor.example", credential)
+	}
+}
+
+func TestApplyPreset(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/presets/rocky-minimal/isos" {
+			t.Errorf("request = %s %s, want POST /api/presets/rocky-minimal/isos", r.Method, r.URL.Path)
+		}
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["version"] != "9.4" {
+			t.Errorf("version = %v, want 9.4", body["version"])
+		}
+		if _, ok := body["arch"]; ok {
+			t.Error("arch should be omitted when empty")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(envelope(map[string]any{"id": "abc", "name": "rocky", "version": "9.4", "status": "pending"}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	iso, err := c.ApplyPreset(context.Background(), "rocky-minimal", ApplyPresetRequest{Version: "9.4"})
+	if err != nil {
+		t.Fatalf("ApplyPreset() error: %v", err)
+	}
+	if iso.Name != "rocky" || iso.Version != "9.4" {
+		t.Errorf("iso = %+v, want rocky 9.4", iso)
+	}
+}
+
+func TestPresetPackRoundTrip(t *testing.T) {
+	const pack = "version: 1\npresets:\n  - name: alpine\n    iso_name: alpine\n    download_url: https://example.com/alpine-{version}.iso\n"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/presets/export":
+			if got := r.URL.Query().Get("names"); got != "alpine,rocky" {
+				t.Errorf("names = %q, want alpine,rocky", got)
+			}
+			w.Header().Set("Content-Type", "application/yaml")
+			w.Write([]byte(pack))
+		case "/api/presets/import":
+			if got := r.URL.Query().Get("existing"); got != "replace" {
+				t.Errorf("existing = %q, want replace", got)
+			}
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != pack {
+				t.Errorf("body = %q, want the exported pack", body)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(envelope(map[string]any{"created": []string{}, "replaced": []string{"alpine"}, "skipped": []string{}}))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	data, err := c.ExportPresets(context.Background(), "alpine", "rocky")
+	if err != nil {
+		t.Fatalf("ExportPresets() error: %v", err)
+	}
+	result, err := c.ImportPresets(context.Background(), bytes.NewReader(data), true)
+	if err != nil {
+		t.Fatalf("ImportPresets() error: %v", err)
+	}
+	if len(result.Replaced) != 1 || result.Replaced[0] != "alpine" {
+		t.Errorf("result = %+v, want alpine replaced", result)
+	}
+}
+
+func TestUpdateListingBranding(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/api/branding" {
+			t.Errorf("request = %s %s, want PUT /api/branding", r.Method, r.URL.Path)
+		}
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["title"] != "Example Corp Mirror" || body["logo_url"] != "" {
+			t.Errorf("body = %v, want the title set and the logo reset", body)
+		}
+		if _, ok := body["footer_text"]; ok {
+			t.Error("footer_text should be omitted when nil")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope(map[string]any{"updated_at": "2024-01-01T00:00:00Z", "title": "Example Corp Mirror", "primary_color": "#b91c1c"}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	title, logo := "Example Corp Mirror", ""
+	branding, err := c.UpdateListingBranding(context.Background(), UpdateListingBrandingRequest{Title: &title, LogoURL: &logo})
+	if err != nil {
+		t.Fatalf("UpdateListingBranding() error: %v", err)
+	}
+	if branding.Title != title || branding.PrimaryColor != "#b91c1c" || branding.UpdatedAt == nil {
+		t.Errorf("branding = %+v, want the updated branding", branding)
+	}
+}
+
+func TestSetListingColumns(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/api/listing/columns" {
+			t.Errorf("request = %s %s, want PUT /api/listing/columns", r.Method, r.URL.Path)
+		}
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if columns, ok := body["columns"].([]any); !ok || len(columns) != 0 {
+			t.Errorf("columns = %v, want an empty list", body["columns"])
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope(map[string]any{"columns": []string{}}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	columns, err := c.SetListingColumns(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("SetListingColumns() error: %v", err)
+	}
+	if len(columns) != 0 {
+		t.Errorf("columns = %v, want none", columns)
+	}
+}
+
+func TestGetChanges(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/api/changes" {
+			t.Errorf("request = %s %s, want GET /api/changes", r.Method, r.URL.Path)
+		}
+		if since := r.URL.Query().Get("since"); since != "2026-10-01T00:00:00Z" {
+			t.Errorf("since = %q, want 2026-10-01T00:00:00Z", since)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope(map[string]any{
+			"since":   "2026-10-01T00:00:00Z",
+			"from":    "2026-10-01T00:00:00Z",
+			"to":      "2026-10-15T09:30:00Z",
+			"added":   []map[string]any{{"id": "iso-1", "name": "alpine", "version": "3.22.0"}},
+			"removed": []map[string]any{},
+			"updated": []map[string]any{{"fields": []string{"sha256"}, "before": map[string]any{"id": "iso-2"}, "after": map[string]any{"id": "iso-2"}}},
+		}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	changes, err := c.GetChanges(context.Background(), time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("GetChanges() error: %v", err)
+	}
+	if len(changes.Added) != 1 || changes.Added[0].Version != "3.22.0" {
+		t.Errorf("added = %+v, want alpine 3.22.0", changes.Added)
+	}
+	if len(changes.Updated) != 1 || changes.Updated[0].After.ID != "iso-2" || changes.Updated[0].Fields[0] != "sha256" {
+		t.Errorf("updated = %+v, want iso-2's sha256", changes.Updated)
+	}
+}
+
+func TestCreateWebhook(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/hooks" {
+			t.Errorf("request = %s %s, want POST /api/hooks", r.Method, r.URL.Path)
+		}
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		template, _ := body["template"].(map[string]any)
+		if template["version"] != "{{ .release.tag_name }}" {
+			t.Errorf("template.version = %v, want {{ .release.tag_name }}", template["version"])
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(envelope(map[string]any{
+			"name":     body["name"],
+			"template": template,
+			"secret":   "s3cret",
+		}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	webhook, err := c.CreateWebhook(context.Background(), CreateWebhookRequest{
+		Name: "builds",
+		Template: WebhookTemplate{
+			Name:        "internal",
+			Version:     "{{ .release.tag_name }}",
+			Arch:        "x86_64",
+			DownloadURL: "https://builds.example.com/{{ .release.tag_name }}.iso",
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhook() error: %v", err)
+	}
+	if webhook.Name != "builds" || webhook.Secret != "s3cret" || webhook.Template.Arch != "x86_64" {
+		t.Errorf("webhook = %+v, want builds with its secret", webhook)
+	}
+}
+
+func TestDeleteCredentialInUse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("method = %s, want DELETE", r.Method)
+		}
+		if r.URL.Path != "/api/credentials/vendor" {
+			t.Errorf("path = %s, want /api/credentials/vendor", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		w.Write(envelopeError("CONFLICT", "credential is used by 2 ISO(s)"))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	err := c.DeleteCredential(context.Background(), "vendor")
+	if !IsConflict(err) {
+		t.Errorf("IsConflict() = false, want true; err = %v", err)
+	}
+}
+
+func TestCreateDownloadLink(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/download-links" {
+			t.Errorf("request = %s %s, want POST /api/download-links", r.Method, r.URL.Path)
+		}
+		var req CreateDownloadLinkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Path != "alpine/alpine.iso" || !req.SingleUse {
+			t.Errorf("body = %+v (%v)", req, err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(envelope(map[string]any{
+			"id":         "abc",
+			"path":       "alpine/alpine.iso",
+			"single_use": true,
+			"url":        "/images/alpine/alpine.iso?token=xyz",
+		}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	link, err := c.CreateDownloadLink(context.Background(), CreateDownloadLinkRequest{Path: "alpine/alpine.iso", SingleUse: true})
+	if err != nil {
+		t.Fatalf("CreateDownloadLink() error: %v", err)
+	}
+	if link.URL != "/images/alpine/alpine.iso?token=xyz" || !link.SingleUse {
+		t.Errorf("link = %+v", link)
+	}
+}
+
+func TestRevokeSessions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("method = %s, want DELETE", r.Method)
+		}
+		if r.URL.Path != "/api/sessions" || r.URL.Query().Get("user_id") != "user-1" {
+			t.Errorf("url = %s, want /api/sessions?user_id=user-1", r.URL)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope(map[string]any{"revoked": 2}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	n, err := c.RevokeSessions(context.Background(), "user-1")
+	if err != nil {
+		t.Fatalf("RevokeSessions() error: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("revoked = %d, want 2", n)
+	}
+}
+
+func TestListMirrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/mirrors" {
+			t.Errorf("path = %s, want /api/mirrors", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope([]any{
+			map[string]any{
+				"host":          "mirror.example.com",
+				"success_count": float64(3),
+				"failure_count": float64(1),
+				"success_rate":  0.75,
+			},
+		}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	mirrors, err := c.ListMirrors(context.Background())
+	if err != nil {
+		t.Fatalf("ListMirrors() error: %v", err)
+	}
+	if len(mirrors) != 1 {
+		t.Fatalf("len(mirrors) = %d, want 1", len(mirrors))
+	}
+	if mirrors[0].Host != "mirror.example.com" {
+		t.Errorf("Host = %q, want %q", mirrors[0].Host, "mirror.example.com")
+	}
+	if mirrors[0].SuccessRate != 0.75 {
+		t.Errorf("SuccessRate = %f, want 0.75", mirrors[0].SuccessRate)
+	}
+}
+
+func TestAdjustDownloadCount(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/isos/abc/stats/adjust" {
+			t.Errorf("got %s %s, want POST /api/isos/abc/stats/adjust", r.Method, r.URL.Path)
+		}
+		var req map[string]any
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["delta"] != float64(-5) || req["reason"] != "bots" {
+			t.Errorf("body = %v, want delta -5 and reason bots", req)
+		}
+		iso := sampleISO()
+		iso["download_count"] = float64(10)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope(iso))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	iso, err := c.AdjustDownloadCount(context.Background(), "abc", -5, "bots")
+	if err != nil {
+		t.Fatalf("AdjustDownloadCount() error: %v", err)
+	}
+	if iso.DownloadCount != 10 {
+		t.Errorf("DownloadCount = %d, want 10", iso.DownloadCount)
+	}
+}
+
+func TestListDownloadEvents(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/isos/abc/stats/downloads" || r.URL.Query().Get("limit") != "20" {
+			t.Errorf("got %s, want /api/isos/abc/stats/downloads?limit=20", r.URL.String())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope([]any{
+			map[string]any{"id": float64(7), "iso_id": "abc", "client_ip": "192.0.2.0", "user_agent": "curl/8.5.0"},
+		}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	events, err := c.ListDownloadEvents(context.Background(), "abc", 20)
+	if err != nil {
+		t.Fatalf("ListDownloadEvents() error: %v", err)
+	}
+	if len(events) != 1 || events[0].ClientIP != "192.0.2.0" || events[0].UserAgent != "curl/8.5.0" {
+		t.Errorf("events = %+v, want one event from 192.0.2.0", events)
+	}
+}
+
+func TestListAuditEvents(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/audit" || r.URL.Query().Get("limit") != "5" {
+			t.Errorf("got %s, want /api/audit?limit=5", r.URL.String())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope([]any{
+			map[string]any{"id": float64(1), "action": "stats.reset", "target_id": "abc"},
+		}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	events, err := c.ListAuditEvents(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("ListAuditEvents() error: %v", err)
+	}
+	if len(events) != 1 || events[0].Action != "stats.reset" {
+		t.Errorf("events = %+v, want one stats.reset event", events)
+	}
+}
+
+func TestListSystemEvents(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/api/system/events" || q.Get("severity") != "warning" || q.Get("since") != "2024-01-01T00:00:00Z" {
+			t.Errorf("got %s, want /api/system/events?severity=warning&since=2024-01-01T00:00:00Z", r.URL.String())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope([]any{
+			map[string]any{"id": float64(3), "type": "storage.low", "severity": "warning", "message": "512 MB free"},
+		}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	events, err := c.ListSystemEvents(context.Background(), &SystemEventsOptions{Since: since, Severity: "warning"})
+	if err != nil {
+		t.Fatalf("ListSystemEvents() error: %v", err)
+	}
+	if len(events) != 1 || events[0].Type != "storage.low" {
+		t.Errorf("events = %+v, want one storage.low event", events)
+	}
+}
+
+func TestListGCReports(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/api/gc/reports" || q.Get("reason") != "version_retention" || q.Get("iso_id") != "iso-1" {
+			t.Errorf("got %s, want /api/gc/reports?iso_id=iso-1&reason=version_retention", r.URL.String())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope([]any{
+			map[string]any{
+				"id": float64(7), "reason": "version_retention", "iso_id": "iso-1", "file_count": 1, "bytes_reclaimed": 700,
+				"files": []any{map[string]any{"path": "/data/isos/.versions/a.iso.20261001T000000Z", "size_bytes": 700}},
+			},
+		}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	reports, err := c.ListGCReports(context.Background(), &GCReportsOptions{Reason: "version_retention", ISOID: "iso-1"})
+	if err != nil {
+		t.Fatalf("ListGCReports() error: %v", err)
+	}
+	if len(reports) != 1 || reports[0].BytesReclaimed != 700 || reports[0].Files[0].SizeBytes != 700 {
+		t.Errorf("reports = %+v, want one 700-byte report", reports)
+	}
+}
+
+func TestGetGCReport(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/gc/reports/7" {
+			t.Errorf("path = %s, want /api/gc/reports/7", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope(map[string]any{"id": float64(7), "reason": "temp_expired", "files": []any{}}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	report, err := c.GetGCReport(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("GetGCReport() error: %v", err)
+	}
+	if report.ID != 7 || report.Reason != "temp_expired" {
+		t.Errorf("report = %+v, want report 7", report)
+	}
+}
+
+func TestGetDownloadTrends(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/stats/trends" {
+			t.Errorf("path = %s, want /api/stats/trends", r.URL.Path)
+		}
+		q := r.URL.Query()
+		if q.Get("period") != "weekly" {
+			t.Errorf("period = %q, want %q", q.Get("period"), "weekly")
+		}
+		if q.Get("days") != "7" {
+			t.Errorf("days = %q, want %q", q.Get("days"), "7")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope(map[string]any{
+			"period": "weekly",
+			"data": []any{
+				map[string]any{"date": "2024-01-01", "count": float64(5)},
+			},
+		}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	trends, err := c.GetDownloadTrends(context.Background(), &DownloadTrendsOptions{
+		Period: "weekly",
+		Days:   7,
+	})
+	if err != nil {
+		t.Fatalf("GetDownloadTrends() error: %v", err)
+	}
+	if trends.Period != "weekly" {
+		t.Errorf("Period = %q, want %q", trends.Period, "weekly")
+	}
+	if len(trends.Data) != 1 {
+		t.Fatalf("len(Data) = %d, want 1", len(trends.Data))
+	}
+	if trends.Data[0].Count != 5 {
+		t.Errorf("Data[0].Count = %d, want 5", trends.Data[0].Count)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			t.Errorf("path = %s, want /health", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope(map[string]any{
+			"status": "ok",
+			"time":   "2024-01-01T00:00:00Z",
+		}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	err := c.Health(context.Background())
+	if err != nil {
+		t.Fatalf("Health() error: %v", err)
+	}
+}
+
+func TestHealthUnhealthy(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write(envelopeError("INTERNAL_ERROR", "database unreachable"))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	err := c.Health(context.Background())
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/isoman.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"name":      "isoman",
+			"version":   "1.4.0",
+			"api_base":  "https://mirror.example.com/api",
+			"websocket": "wss://mirror.example.com/ws",
+			"auth": map[string]any{
+				"enabled":       true,
+				"modes":         []string{"session", "bearer", "download_token"},
+				"login_url":     "https://mirror.example.com/api/auth/login",
+				"public_scopes": []string{"images"},
+			},
+			"links":     map[string]any{"images": "https://mirror.example.com/images/", "feed": "https://mirror.example.com/feed.xml"},
+			"read_only": false,
+		})
+	}))
+	defer ts.Close()
+
+	doc, err := NewClient(ts.URL).Discover(context.Background())
+	if err != nil {
+		t.Fatalf("Discover() error: %v", err)
+	}
+	if doc.APIBase != "https://mirror.example.com/api" || !doc.Auth.Enabled || len(doc.Auth.Modes) != 3 || doc.Links.Feed != "https://mirror.example.com/feed.xml" {
+		t.Errorf("doc = %+v", doc)
+	}
+
+	if _, err := NewClient(ts.URL + "/missing").Discover(context.Background()); !IsNotFound(err) {
+		t.Errorf("Discover() error = %v, want not found", err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/status" {
+			t.Errorf("path = %s, want /status", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope(map[string]any{
+			"status":        "degraded",
+			"problems":      []string{"storage low"},
+			"complete_isos": 42,
+			"last_sync_at":  "2024-01-15T10:35:00Z",
+		}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	status, err := c.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status() error: %v", err)
+	}
+	if status.Status != "degraded" || status.CompleteISOs != 42 || status.LastSyncAt == nil || len(status.Problems) != 1 {
+		t.Errorf("Status() = %+v", status)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/version" {
+			t.Errorf("path = %s, want /api/version", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope(map[string]any{
+			"version": "1.4.0",
+			"schema":  map[string]any{"version": 34, "latest": 36, "dirty": false, "pending": 2, "compatible": false},
+		}))
+	}))
+	defer ts.Close()
+
+	info, err := NewClient(ts.URL).Version(context.Background())
+	if err != nil {
+		t.Fatalf("Version() error: %v", err)
+	}
+	if info.Version != "1.4.0" || info.Schema.Pending != 2 || info.Schema.Compatible {
+		t.Errorf("Version() = %+v", info)
+	}
+}
+
+func TestDownloadFile(t *testing.T) {
+	content := "fake-iso-content"
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/images/alpine/3.19.1/x86_64/alpine.iso" {
 			t.Errorf("path = %s, want /images/alpine/3.19.1/x86_64/alpine.iso", r.URL.Path)
@@ -538,6 +1766,42 @@ func TestDownloadFileNotFound(t *testing.T) {
 	}
 }
 
+func TestProblemError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{
+			"type": "urn:isoman:error:validation-failed",
+			"title": "Bad Request",
+			"status": 400,
+			"detail": "Validation failed: name: name is required",
+			"instance": "/api/isos",
+			"code": "VALIDATION_FAILED",
+			"request_id": "req-42",
+			"errors": [{"field": "name", "message": "name is required"}],
+			"success": false,
+			"error": {"code": "VALIDATION_FAILED", "message": "Validation failed", "details": "name: name is required"}
+		}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	_, err := c.CreateISO(context.Background(), CreateISORequest{})
+	if !HasCode(err, "VALIDATION_FAILED") {
+		t.Fatalf("HasCode(VALIDATION_FAILED) = false for %v", err)
+	}
+	apiErr := err.(*APIError)
+	if apiErr.Type != "urn:isoman:error:validation-failed" || apiErr.Instance != "/api/isos" || apiErr.RequestID != "req-42" {
+		t.Errorf("Type/Instance/RequestID = %q/%q/%q", apiErr.Type, apiErr.Instance, apiErr.RequestID)
+	}
+	if len(apiErr.FieldErrors) != 1 || apiErr.FieldErrors[0].Field != "name" {
+		t.Errorf("FieldErrors = %+v, want one name error", apiErr.FieldErrors)
+	}
+	if apiErr.Message != "Validation failed" {
+		t.Errorf("Message = %q, want the envelope message", apiErr.Message)
+	}
+}
+
 func TestAPIErrorFormat(t *testing.T) {
 	err := &APIError{
 		StatusCode: 404,
@@ -574,3 +1838,54 @@ func TestUserAgentHeader(t *testing.T) {
 	c := NewClient(ts.URL, WithUserAgent("my-app/1.0"))
 	_ = c.Health(context.Background())
 }
+
+func TestSetLogSettings(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Path != "/api/admin/log-level" {
+			t.Errorf("request = %s %s, want PATCH /api/admin/log-level", r.Method, r.URL.Path)
+		}
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["level"] != "debug" || body["format"] != "" || body["reason"] != "incident" {
+			t.Errorf("body = %v, want level debug and reason incident", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(envelope(map[string]any{"level": "debug", "format": "text"}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	settings, err := c.SetLogSettings(context.Background(), "debug", "", "incident")
+	if err != nil {
+		t.Fatalf("SetLogSettings() error: %v", err)
+	}
+	if settings.Level != "debug" || settings.Format != "text" {
+		t.Errorf("settings = %+v, want debug, text", settings)
+	}
+}
+
+func TestWaitForISO(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/isos/iso-1/wait" || r.URL.Query().Get("timeout") != "5" {
+			t.Errorf("got %s, want /api/isos/iso-1/wait?timeout=5", r.URL.String())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusAccepted)
+			w.Write(envelope(map[string]any{"id": "iso-1", "status": "downloading"}))
+			return
+		}
+		w.Write(envelope(map[string]any{"id": "iso-1", "status": "complete"}))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, WithTimeout(10*time.Second))
+	iso, err := c.WaitForISO(context.Background(), "iso-1")
+	if err != nil {
+		t.Fatalf("WaitForISO() error: %v", err)
+	}
+	if iso.Status != StatusComplete || calls.Load() != 2 {
+		t.Errorf("status = %s after %d requests, want complete after 2", iso.Status, calls.Load())
+	}
+}
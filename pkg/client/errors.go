@@ -12,6 +12,31 @@ type APIError struct {
 	Message string
 	// Details contains optional additional error details.
 	Details string
+	// Type is the RFC 7807 problem type URI (e.g. "urn:isoman:error:not-found").
+	Type string
+	// Instance is the request path that produced the error.
+	Instance string
+	// RequestID identifies the request in server logs; quote it when reporting a problem.
+	RequestID string
+	// FieldErrors lists the rejected request fields of a VALIDATION_FAILED error.
+	FieldErrors []FieldError
+	// Options lists requests that resolve a CONFLICT from CreateISO.
+	Options []ConflictOption
+}
+
+// ConflictOption is a request that resolves a create conflict, e.g. retrying
+// or overwriting the existing ISO.
+type ConflictOption struct {
+	Action      string `json:"action"` // retry, overwrite, refresh, or edition
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	Description string `json:"description"`
+}
+
+// FieldError describes why a single request field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 // Error implements the error interface.
@@ -37,3 +62,21 @@ func IsConflict(err error) bool {
 	}
 	return false
 }
+
+// IsUnauthorized reports whether err is an ISOMan API 401 Unauthorized error,
+// i.e. the session is missing or expired.
+func IsUnauthorized(err error) bool {
+	if e, ok := err.(*APIError); ok {
+		return e.StatusCode == 401
+	}
+	return false
+}
+
+// HasCode reports whether err is an ISOMan API error with the given code
+// (e.g. "QUEUE_FULL"), so callers can branch without matching messages.
+func HasCode(err error, code string) bool {
+	if e, ok := err.(*APIError); ok {
+		return e.Code == code
+	}
+	return false
+}
@@ -8,34 +8,99 @@ type ISOStatus string
 
 const (
 	StatusPending     ISOStatus = "pending"
+	StatusQueued      ISOStatus = "queued"
 	StatusDownloading ISOStatus = "downloading"
 	StatusVerifying   ISOStatus = "verifying"
 	StatusComplete    ISOStatus = "complete"
 	StatusFailed      ISOStatus = "failed"
+	StatusCanceled    ISOStatus = "canceled"
+	StatusQuarantined ISOStatus = "quarantined"
+)
+
+// IsActive reports whether the ISO is waiting for or being processed by a worker.
+func (s ISOStatus) IsActive() bool {
+	switch s {
+	case StatusPending, StatusQueued, StatusDownloading, StatusVerifying:
+		return true
+	}
+	return false
+}
+
+// ErrorReason classifies why a download failed.
+type ErrorReason string
+
+const (
+	ErrorReasonNone         ErrorReason = ""
+	ErrorReasonStalled      ErrorReason = "stalled"
+	ErrorReasonTimeout      ErrorReason = "timeout"
+	ErrorReasonPanic        ErrorReason = "panic"
+	ErrorReasonInterrupted  ErrorReason = "interrupted"
+	ErrorReasonVerification ErrorReason = "verification"
+)
+
+// VerificationState says whether an ISO's file can be trusted.
+type VerificationState string
+
+const (
+	VerificationStateVerified   VerificationState = "verified"
+	VerificationStateUnverified VerificationState = "unverified"
+	VerificationStateFailed     VerificationState = "failed"
 )
 
 // ISO represents an ISO file managed by ISOMan.
 type ISO struct {
-	CreatedAt     time.Time  `json:"created_at"`
-	CompletedAt   *time.Time `json:"completed_at"`
-	DownloadLink  string     `json:"download_link"`
-	ChecksumType  string     `json:"checksum_type"`
-	Edition       string     `json:"edition"`
-	FileType      string     `json:"file_type"`
-	Filename      string     `json:"filename"`
-	FilePath      string     `json:"file_path"`
-	ID            string     `json:"id"`
-	Name          string     `json:"name"`
-	Checksum      string     `json:"checksum"`
-	Arch          string     `json:"arch"`
-	DownloadURL   string     `json:"download_url"`
-	ChecksumURL   string     `json:"checksum_url"`
-	Status        ISOStatus  `json:"status"`
-	Version       string     `json:"version"`
-	ErrorMessage  string     `json:"error_message"`
-	Progress      int        `json:"progress"`
-	SizeBytes     int64      `json:"size_bytes"`
-	DownloadCount int64      `json:"download_count"`
+	CreatedAt            time.Time   `json:"created_at"`
+	CompletedAt          *time.Time  `json:"completed_at"`
+	UpstreamCheckedAt    *time.Time  `json:"upstream_checked_at"`
+	FileModTime          *time.Time  `json:"file_mtime"`
+	VerifiedAt           *time.Time  `json:"verified_at"`
+	DownloadStartedAt    *time.Time  `json:"download_started_at"`
+	DownloadLink         string      `json:"download_link"`
+	ChecksumType         string      `json:"checksum_type"`
+	Edition              string      `json:"edition"`
+	FileType             string      `json:"file_type"`
+	Filename             string      `json:"filename"`
+	FilePath             string      `json:"file_path"`
+	ID                   string      `json:"id"`
+	Name                 string      `json:"name"`
+	Checksum             string      `json:"checksum"`
+	Arch                 string      `json:"arch"`
+	DownloadURL          string      `json:"download_url"`
+	ChecksumURL          string      `json:"checksum_url"`
+	SignatureURL         string      `json:"signature_url"`
+	SignatureSigner      string      `json:"signature_signer"`
+	IPFamily             string      `json:"ip_family"`
+	Credential           string      `json:"credential"`
+	Preset               string      `json:"preset"` // Preset the ISO was created from, if any
+	Status               ISOStatus   `json:"status"`
+	Version              string      `json:"version"`
+	ErrorMessage         string      `json:"error_message"`
+	ErrorReason          ErrorReason `json:"error_reason"`
+	UpstreamETag         string      `json:"upstream_etag"`
+	UpstreamLastModified string      `json:"upstream_last_modified"`
+	SourceURL            string      `json:"source_url"` // URL that served the file, after redirects
+	SHA256               string      `json:"sha256"`
+	SHA512               string      `json:"sha512"`
+	MD5                  string      `json:"md5"`
+	IntegrityHash        string      `json:"integrity_hash"`
+	Progress             int         `json:"progress"`
+	SizeBytes            int64       `json:"size_bytes"`
+	DownloadCount        int64       `json:"download_count"`
+	BytesServed          int64       `json:"bytes_served"`
+	FileInode            uint64      `json:"file_inode"`
+	// SourceHeaders are the server, CDN, and mirror headers of the response that served the file.
+	SourceHeaders map[string]string `json:"source_headers"`
+	// UpstreamChanged is set when the last upstream check found the file republished.
+	UpstreamChanged bool `json:"upstream_changed"`
+	// Pinned ISOs are listed first, and refreshes keep all of their previous files.
+	Pinned bool `json:"pinned"`
+	// Redownload is set while a complete ISO downloads again; the previous file stays served.
+	Redownload bool `json:"redownload"`
+	// ChecksumPending is set on a complete ISO whose checksum file couldn't be fetched yet.
+	ChecksumPending bool `json:"checksum_pending"`
+	// Verification is verified once every configured check passed, failed when
+	// the last download failed one, and unverified otherwise.
+	Verification VerificationState `json:"verification"`
 }
 
 // CreateISORequest is the request body for creating a new ISO download.
@@ -52,35 +117,404 @@ type CreateISORequest struct {
 	DownloadURL string `json:"download_url"`
 	// ChecksumURL is an optional URL to a checksum file.
 	ChecksumURL string `json:"checksum_url,omitempty"`
+	// ExpectedChecksum is an optional hex digest, for sources that publish
+	// the hash rather than a checksum file. It excludes ChecksumURL.
+	ExpectedChecksum string `json:"expected_checksum,omitempty"`
+	// SignatureURL is an optional URL to a detached GPG signature (.sig or
+	// .asc) over the file itself, checked against the server's GPG_KEYRING.
+	SignatureURL string `json:"signature_url,omitempty"`
 	// ChecksumType is the hash type: "sha256", "sha512", or "md5" (default "sha256").
 	ChecksumType string `json:"checksum_type,omitempty"`
+	// IPFamily optionally pins upstream fetches to "ipv4" or "ipv6" ("any" disables the server default).
+	IPFamily string `json:"ip_family,omitempty"`
+	// Credential optionally names a stored credential for upstream requests
+	// (default: the credential bound to the download URL's host, if any).
+	Credential string `json:"credential,omitempty"`
+	// Overwrite replaces an existing failed or canceled ISO with the same
+	// name, version, arch, edition, and file type instead of conflicting.
+	Overwrite bool `json:"-"`
+}
+
+// ISOPreview is where an ISO would land if it were created.
+type ISOPreview struct {
+	Name         string `json:"name"` // Normalized
+	Version      string `json:"version"`
+	Arch         string `json:"arch"`
+	Edition      string `json:"edition"`
+	FileType     string `json:"file_type"`
+	Filename     string `json:"filename"`
+	FilePath     string `json:"file_path"`
+	DownloadLink string `json:"download_link"`
+	// ExistingID is the ISO a create would conflict with; empty when there is none.
+	ExistingID string `json:"existing_id,omitempty"`
 }
 
 // UpdateISORequest is the request body for updating an ISO.
 // All fields are optional — only non-nil fields are applied.
 type UpdateISORequest struct {
-	Name         *string `json:"name,omitempty"`
-	Version      *string `json:"version,omitempty"`
-	Arch         *string `json:"arch,omitempty"`
-	Edition      *string `json:"edition,omitempty"`
-	DownloadURL  *string `json:"download_url,omitempty"`
-	ChecksumURL  *string `json:"checksum_url,omitempty"`
-	ChecksumType *string `json:"checksum_type,omitempty"`
+	Name             *string `json:"name,omitempty"`
+	Version          *string `json:"version,omitempty"`
+	Arch             *string `json:"arch,omitempty"`
+	Edition          *string `json:"edition,omitempty"`
+	DownloadURL      *string `json:"download_url,omitempty"`
+	ChecksumURL      *string `json:"checksum_url,omitempty"`
+	ExpectedChecksum *string `json:"expected_checksum,omitempty"`
+	SignatureURL     *string `json:"signature_url,omitempty"`
+	ChecksumType     *string `json:"checksum_type,omitempty"`
+	IPFamily         *string `json:"ip_family,omitempty"`
+	Credential       *string `json:"credential,omitempty"`
+}
+
+// AdoptRule maps a file path (relative to the source directory) onto ISO metadata.
+// Pattern must define the named groups name, version, and arch; edition is optional.
+type AdoptRule struct {
+	Pattern string `json:"pattern"`
+}
+
+// AdoptDirectoryRequest is the request body for adopting an existing mirror tree.
+type AdoptDirectoryRequest struct {
+	// SourceDir is the directory on the server to walk.
+	SourceDir string `json:"source_dir"`
+	// Mode is "link" (default), "copy", or "move".
+	Mode string `json:"mode,omitempty"`
+	// Rules are tried in order; empty uses the server's default layout rule.
+	Rules []AdoptRule `json:"rules,omitempty"`
+	// DryRun reports what would be adopted without changing anything.
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// AdoptedFile describes a file registered as a complete ISO.
+type AdoptedFile struct {
+	ISO        *ISO   `json:"iso"`
+	SourcePath string `json:"source_path"`
+}
+
+// AdoptSkippedFile describes a file that was not adopted and why.
+type AdoptSkippedFile struct {
+	SourcePath string `json:"source_path"`
+	Reason     string `json:"reason"`
+}
+
+// AdoptResult summarizes an adopt directory run.
+type AdoptResult struct {
+	Adopted []AdoptedFile      `json:"adopted"`
+	Skipped []AdoptSkippedFile `json:"skipped"`
+	DryRun  bool               `json:"dry_run"`
+}
+
+// ReconcileRequest is the body of POST /api/isos/reconcile.
+type ReconcileRequest struct {
+	DryRun bool `json:"dry_run"`
+}
+
+// ReconcileChange is an ISO whose derived fields were recomputed.
+type ReconcileChange struct {
+	ISOID       string   `json:"iso_id"`
+	Fields      []string `json:"fields"` // name, filename, file_path, download_link
+	OldFilePath string   `json:"old_file_path"`
+	NewFilePath string   `json:"new_file_path"`
+	FileMoved   bool     `json:"file_moved"`
+	FileMissing bool     `json:"file_missing"` // A complete ISO's file is at neither path
+}
+
+// ReconcileSkipped is an ISO that needs fixing but was left alone.
+type ReconcileSkipped struct {
+	ISOID  string `json:"iso_id"`
+	Reason string `json:"reason"`
+}
+
+// ReconcileResult summarizes a reconciliation run; on a dry run Fixed lists
+// the changes that would be made.
+type ReconcileResult struct {
+	Checked int                `json:"checked"`
+	Fixed   []ReconcileChange  `json:"fixed"`
+	Skipped []ReconcileSkipped `json:"skipped"`
+	DryRun  bool               `json:"dry_run"`
+}
+
+// Manifest lists every file in the server's ISO directory with its size and SHA-256.
+type Manifest struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	Entries     []ManifestEntry `json:"entries"`
+	Version     int             `json:"version"`
+}
+
+// ManifestEntry describes one file in a manifest. ISO is nil for checksum sidecar files.
+type ManifestEntry struct {
+	ISO       *ISO   `json:"iso,omitempty"`
+	Path      string `json:"path"`
+	SHA256    string `json:"sha256"`
+	SizeBytes int64  `json:"size_bytes"`
+}
+
+// ManifestImportRequest is the request body for importing a copied data dir.
+type ManifestImportRequest struct {
+	// Manifest is the manifest exported from the source instance.
+	Manifest *Manifest `json:"manifest"`
+	// SourceDir is the directory on the server holding the copied files.
+	SourceDir string `json:"source_dir"`
+	// Mode is "link" (default), "copy", or "move".
+	Mode string `json:"mode,omitempty"`
+	// DryRun verifies files without changing anything.
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// ManifestImportResult summarizes a manifest import.
+type ManifestImportResult struct {
+	Imported []*ISO             `json:"imported"`
+	Skipped  []AdoptSkippedFile `json:"skipped"`
+	Invalid  []AdoptSkippedFile `json:"invalid"`
+	DryRun   bool               `json:"dry_run"`
+}
+
+// KnownChecksum is a published checksum kept on the server, used to verify
+// downloads once their checksum file can no longer be fetched.
+type KnownChecksum struct {
+	ChecksumURL string    `json:"checksum_url"`
+	Filename    string    `json:"filename"` // As named in the checksum file
+	Checksum    string    `json:"checksum"`
+	Source      string    `json:"source,omitempty"` // "upstream" for checksums kept from fetched files
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ChecksumImportRequest is a batch of published checksums to keep on the server.
+type ChecksumImportRequest struct {
+	// Source is recorded on checksums that don't name their own.
+	Source    string          `json:"source,omitempty"`
+	Checksums []KnownChecksum `json:"checksums"`
+}
+
+// ChecksumImportResult reports how many checksums an import stored.
+type ChecksumImportResult struct {
+	Imported int `json:"imported"`
+}
+
+// IntegrityCheckResult is the outcome of re-hashing an ISO's file on the server.
+type IntegrityCheckResult struct {
+	ISO       *ISO   `json:"iso"`
+	Algorithm string `json:"algorithm"`
+	Expected  string `json:"expected"`
+	Actual    string `json:"actual"`
+	Valid     bool   `json:"valid"`
+	// Baseline is set when no hash was stored and Actual was recorded instead.
+	Baseline bool `json:"baseline"`
+}
+
+// VerificationReport traces how an ISO's file was verified, for auditing its provenance.
+type VerificationReport struct {
+	GeneratedAt  time.Time `json:"generated_at"`
+	ISOID        string    `json:"iso_id"`
+	Filename     string    `json:"filename"`
+	DownloadLink string    `json:"download_link"`
+	Status       ISOStatus `json:"status"`
+	Source       struct {
+		DownloadURL          string            `json:"download_url"`
+		SourceURL            string            `json:"source_url"`
+		SourceHeaders        map[string]string `json:"source_headers"`
+		UpstreamETag         string            `json:"upstream_etag"`
+		UpstreamLastModified string            `json:"upstream_last_modified"`
+		UpstreamChanged      bool              `json:"upstream_changed"`
+	} `json:"source"`
+	// Checksum and Signature statuses are "verified", "unverified", or "none".
+	Checksum struct {
+		Status      string `json:"status"`
+		Method      string `json:"method"` // "checksum_file" or "expected_checksum"
+		URL         string `json:"url"`
+		Type        string `json:"type"`
+		Expected    string `json:"expected"`
+		SidecarLink string `json:"sidecar_link"`
+	} `json:"checksum"`
+	Signature struct {
+		Status      string `json:"status"`
+		URL         string `json:"url"`
+		Signer      string `json:"signer"`
+		SidecarLink string `json:"sidecar_link"`
+	} `json:"signature"`
+	Hashes struct {
+		SHA256        string `json:"sha256"`
+		SHA512        string `json:"sha512"`
+		MD5           string `json:"md5"`
+		IntegrityHash string `json:"integrity_hash"`
+		SizeBytes     int64  `json:"size_bytes"`
+	} `json:"hashes"`
+	Timestamps struct {
+		CreatedAt         time.Time  `json:"created_at"`
+		DownloadStartedAt *time.Time `json:"download_started_at"`
+		CompletedAt       *time.Time `json:"completed_at"`
+		VerifiedAt        *time.Time `json:"verified_at"`
+		FileModTime       *time.Time `json:"file_mtime"`
+		UpstreamCheckedAt *time.Time `json:"upstream_checked_at"`
+	} `json:"timestamps"`
+	// Verified is set when at least one check is configured and all of them passed.
+	Verified bool `json:"verified"`
+}
+
+// Snippet is a ready-made piece of client configuration for fetching or booting an ISO.
+type Snippet struct {
+	// Type is "wget", "curl", "proxmox", or "ipxe".
+	Type     string `json:"type"`
+	Filename string `json:"filename"`
+	Content  string `json:"content"`
+}
+
+// ISOSnippets lists the snippets available for an ISO.
+type ISOSnippets struct {
+	ISOID string `json:"iso_id"`
+	URL   string `json:"url"`
+	// RequiresAuth is set when /images needs a session or download link
+	// token, which the snippets don't carry.
+	RequiresAuth bool      `json:"requires_auth"`
+	Snippets     []Snippet `json:"snippets"`
 }
 
 // Stats represents aggregated statistics from the ISOMan dashboard.
 type Stats struct {
-	TotalISOs      int64             `json:"total_isos"`
-	CompletedISOs  int64             `json:"completed_isos"`
-	FailedISOs     int64             `json:"failed_isos"`
-	PendingISOs    int64             `json:"pending_isos"`
-	TotalSizeBytes int64             `json:"total_size_bytes"`
-	TotalDownloads int64             `json:"total_downloads"`
-	BandwidthSaved int64             `json:"bandwidth_saved"`
-	ISOsByArch     map[string]int64  `json:"isos_by_arch"`
-	ISOsByEdition  map[string]int64  `json:"isos_by_edition"`
-	ISOsByStatus   map[string]int64  `json:"isos_by_status"`
-	TopDownloaded  []ISODownloadStat `json:"top_downloaded"`
+	TotalISOs              int64             `json:"total_isos"`
+	CompletedISOs          int64             `json:"completed_isos"`
+	FailedISOs             int64             `json:"failed_isos"`
+	CanceledISOs           int64             `json:"canceled_isos"`
+	PendingISOs            int64             `json:"pending_isos"`
+	VerifiedISOs           int64             `json:"verified_isos"` // Verification coverage; these three add up to TotalISOs
+	UnverifiedISOs         int64             `json:"unverified_isos"`
+	VerificationFailedISOs int64             `json:"verification_failed_isos"`
+	UnverifiedSizeBytes    int64             `json:"unverified_size_bytes"` // Complete ISOs without a passed checksum or signature
+	TotalSizeBytes         int64             `json:"total_size_bytes"`
+	TotalDownloads         int64             `json:"total_downloads"`
+	BandwidthSaved         int64             `json:"bandwidth_saved"`
+	TotalBytesServed       int64             `json:"total_bytes_served"` // What /images actually sent, to check BandwidthSaved against
+	ISOsByArch             map[string]int64  `json:"isos_by_arch"`
+	ISOsByEdition          map[string]int64  `json:"isos_by_edition"`
+	ISOsByStatus           map[string]int64  `json:"isos_by_status"`
+	ISOsByVerification     map[string]int64  `json:"isos_by_verification"` // Keyed by VerificationState
+	TopDownloaded          []ISODownloadStat `json:"top_downloaded"`
+	DownloadsByCountry     map[string]int64  `json:"downloads_by_country"` // Needs GEOIP_DB on the server
+	DownloadsBySite        map[string]int64  `json:"downloads_by_site"`    // Needs GEOIP_SITES on the server
+	QueueDepth             int               `json:"queue_depth"`          // Downloads waiting for a free worker
+	QueueCapacity          int               `json:"queue_capacity"`       // QUEUE_BUFFER; new downloads are rejected when full
+	WorkerPanics           int64             `json:"worker_panics"`        // Panics download workers recovered from since startup
+	GroupBy                string            `json:"group_by,omitempty"`
+	Groups                 []StatsGroup      `json:"groups,omitempty"` // Set when StatsOptions.GroupBy is used
+}
+
+// StatsGroup aggregates the ISOs sharing one value of the group_by column.
+type StatsGroup struct {
+	Key           string `json:"key"`
+	ISOCount      int64  `json:"iso_count"`
+	DownloadCount int64  `json:"download_count"`
+	SizeBytes     int64  `json:"size_bytes"` // Complete ISOs only
+	BytesServed   int64  `json:"bytes_served"`
+}
+
+// LiveThroughput is the latest sample of aggregate download (ingest) and serve (egress) rates.
+type LiveThroughput struct {
+	SampledAt         time.Time `json:"sampled_at"`
+	IngestBytesPerSec int64     `json:"ingest_bytes_per_sec"`
+	EgressBytesPerSec int64     `json:"egress_bytes_per_sec"`
+	IngestBytesTotal  int64     `json:"ingest_bytes_total"`
+	EgressBytesTotal  int64     `json:"egress_bytes_total"`
+}
+
+// ActiveDownload is a download a worker is running or verifying.
+type ActiveDownload struct {
+	ISOID           string    `json:"iso_id"`
+	Name            string    `json:"name"`
+	Version         string    `json:"version"`
+	Arch            string    `json:"arch"`
+	Edition         string    `json:"edition"`
+	Node            string    `json:"node"`      // Instance holding the download lock
+	WorkerID        int       `json:"worker_id"` // -1 on another node
+	Status          ISOStatus `json:"status"`    // "downloading" or "verifying"
+	BytesDownloaded int64     `json:"bytes_downloaded"`
+	BytesTotal      int64     `json:"bytes_total"`   // 0 when upstream sent no length
+	BytesPerSec     int64     `json:"bytes_per_sec"` // Average over the current attempt
+	StartedAt       time.Time `json:"started_at"`
+}
+
+// QueueForecast estimates when each queued download will start and finish.
+type QueueForecast struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	BytesPerSec int64           `json:"bytes_per_sec"` // Per download, over recent completed downloads
+	Queue       []QueueEstimate `json:"queue"`
+}
+
+// QueueEstimate is the forecast for one queued download.
+type QueueEstimate struct {
+	ISOID                 string     `json:"iso_id"`
+	Name                  string     `json:"name"`
+	Version               string     `json:"version"`
+	Arch                  string     `json:"arch"`
+	Edition               string     `json:"edition"`
+	Position              int        `json:"position"` // 1 is next in line
+	SizeBytes             int64      `json:"size_bytes"`
+	FastLane              bool       `json:"fast_lane"`
+	EstimatedStartAt      *time.Time `json:"estimated_start_at"` // nil without download history
+	EstimatedCompletionAt *time.Time `json:"estimated_completion_at"`
+}
+
+// ManagerState is a point-in-time dump of an instance's download manager.
+type ManagerState struct {
+	CapturedAt    time.Time          `json:"captured_at"`
+	Node          string             `json:"node"`
+	Stopped       bool               `json:"stopped"`
+	Queues        ManagerQueues      `json:"queues"`
+	Workers       []WorkerState      `json:"workers"`
+	VerifyWorkers int                `json:"verify_workers"`
+	InFlight      []InFlightDownload `json:"in_flight"`
+	Active        []ActiveDownload   `json:"active"` // On this node only
+	Locks         []DownloadLock     `json:"locks"`  // Of every node sharing the database
+	WorkerPanics  int64              `json:"worker_panics"`
+}
+
+// ManagerQueues are the lengths and capacities of the manager's queues.
+type ManagerQueues struct {
+	Queued         int `json:"queued"`
+	Capacity       int `json:"capacity"`
+	FastQueued     int `json:"fast_queued"`
+	FastCapacity   int `json:"fast_capacity"`
+	VerifyQueued   int `json:"verify_queued"`
+	VerifyCapacity int `json:"verify_capacity"`
+}
+
+// WorkerState is what a download worker is doing.
+type WorkerState struct {
+	ID    int    `json:"id"`
+	Lane  string `json:"lane"`             // regular, fast
+	State string `json:"state"`            // idle, downloading
+	ISOID string `json:"iso_id,omitempty"` // Set while downloading
+}
+
+// InFlightDownload is a download the manager holds, from queuing until finalized.
+type InFlightDownload struct {
+	ISOID    string    `json:"iso_id"`
+	TempFile string    `json:"temp_file"`
+	Status   ISOStatus `json:"status"` // queued, downloading, verifying
+}
+
+// DownloadLock is a node's lease on an in-flight download.
+type DownloadLock struct {
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	ISOID      string    `json:"iso_id"`
+	Node       string    `json:"node"`
+}
+
+// ReplicaStatus reports a read-only replica's last sync with its primary.
+type ReplicaStatus struct {
+	LastSyncedAt *time.Time `json:"last_synced_at"` // nil before the first successful sync
+	PrimaryURL   string     `json:"primary_url"`
+	LastError    string     `json:"last_error"` // Empty when the last sync succeeded
+	ISOs         int        `json:"isos"`       // Primary ISOs listed on the replica
+	Waiting      int        `json:"waiting"`    // Primary ISOs whose files haven't arrived yet
+	Enabled      bool       `json:"enabled"`
+}
+
+// DownloadLogEntry is one step of an ISO's latest download run.
+type DownloadLogEntry struct {
+	ID       int64     `json:"id"`
+	ISOID    string    `json:"iso_id"`
+	LoggedAt time.Time `json:"logged_at"`
+	Level    string    `json:"level"` // "info", "warn", or "error"
+	Message  string    `json:"message"`
 }
 
 // ISODownloadStat represents download statistics for a single ISO.
@@ -91,6 +525,7 @@ type ISODownloadStat struct {
 	Arch          string `json:"arch"`
 	DownloadCount int64  `json:"download_count"`
 	SizeBytes     int64  `json:"size_bytes"`
+	BytesServed   int64  `json:"bytes_served"`
 }
 
 // DownloadTrends represents download trend data over a time period.
@@ -105,12 +540,477 @@ type TrendDataPoint struct {
 	Count int64  `json:"count"`
 }
 
+// MirrorHealth summarizes download outcomes for a single upstream host.
+type MirrorHealth struct {
+	LastSuccessAt *time.Time `json:"last_success_at"`
+	LastFailureAt *time.Time `json:"last_failure_at"`
+	Host          string     `json:"host"`
+	LastError     string     `json:"last_error"`
+	SuccessCount  int64      `json:"success_count"`
+	FailureCount  int64      `json:"failure_count"`
+	AvgLatencyMs  int64      `json:"avg_latency_ms"`
+	LastLatencyMs int64      `json:"last_latency_ms"`
+	SuccessRate   float64    `json:"success_rate"`
+}
+
+// Relocation reports the progress of copying the ISO directory to a new location.
+type Relocation struct {
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at"`
+	Source       string     `json:"source"`
+	Target       string     `json:"target"`
+	State        string     `json:"state"` // running, complete, or failed
+	Error        string     `json:"error,omitempty"`
+	FilesTotal   int        `json:"files_total"`
+	FilesCopied  int        `json:"files_copied"`
+	FilesSkipped int        `json:"files_skipped"` // Already in the target with the same size and mtime
+	BytesTotal   int64      `json:"bytes_total"`
+	BytesCopied  int64      `json:"bytes_copied"`
+}
+
+// Credential is a named set of upstream credentials. Secret material is
+// never returned by the server.
+type Credential struct {
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"` // "basic", "token", or "s3"
+	Host      string    `json:"host"`
+}
+
+// CredentialSecret holds the secret material of a credential. Which fields
+// are required depends on the credential type.
+type CredentialSecret struct {
+	Username        string `json:"username,omitempty"`
+	Password        string `json:"password,omitempty"`
+	Token           string `json:"token,omitempty"`
+	AccessKeyID     string `json:"access_key_id,omitempty"`
+	SecretAccessKey string `json:"secret_access_key,omitempty"`
+	SessionToken    string `json:"session_token,omitempty"`
+	Region          string `json:"region,omitempty"`
+}
+
+// CreateCredentialRequest is the request body for storing a credential.
+type CreateCredentialRequest struct {
+	Name string `json:"name"`
+	// Type is "basic", "token", or "s3".
+	Type string `json:"type"`
+	// Host optionally binds the credential to every ISO on that host.
+	Host   string           `json:"host,omitempty"`
+	Secret CredentialSecret `json:"secret"`
+}
+
+// UpdateCredentialRequest is the request body for updating a credential.
+// All fields are optional — only non-nil fields are applied.
+type UpdateCredentialRequest struct {
+	Type   *string           `json:"type,omitempty"`
+	Host   *string           `json:"host,omitempty"`
+	Secret *CredentialSecret `json:"secret,omitempty"`
+}
+
+// VersionBumpRequest is the request body for BumpVersion.
+type VersionBumpRequest struct {
+	IDs     []string `json:"ids"`
+	Version string   `json:"version"`
+}
+
+// VersionBumpResult reports what BumpVersion queued and what it couldn't.
+type VersionBumpResult struct {
+	Queued []ISO                `json:"queued"`
+	Failed []VersionBumpFailure `json:"failed"`
+}
+
+// VersionBumpFailure is a source ISO BumpVersion couldn't queue a new version of.
+type VersionBumpFailure struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+// Preset is a named recipe for creating ISOs of one distribution. Its URLs
+// may contain {version}, {major}, {arch}, and {edition} placeholders.
+type Preset struct {
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Name         string    `json:"name"`
+	ISOName      string    `json:"iso_name"`
+	Arch         string    `json:"arch"`
+	Edition      string    `json:"edition"`
+	DownloadURL  string    `json:"download_url"`
+	ChecksumURL  string    `json:"checksum_url"`
+	ChecksumType string    `json:"checksum_type"`
+}
+
+// CreatePresetRequest is the request body for creating a preset.
+type CreatePresetRequest struct {
+	Name    string `json:"name"`
+	ISOName string `json:"iso_name"`
+	// Arch and Edition are defaults for requests that don't name one.
+	Arch         string `json:"arch,omitempty"`
+	Edition      string `json:"edition,omitempty"`
+	DownloadURL  string `json:"download_url"`
+	ChecksumURL  string `json:"checksum_url,omitempty"`
+	ChecksumType string `json:"checksum_type,omitempty"`
+}
+
+// UpdatePresetRequest is the request body for updating a preset.
+// All fields are optional — only non-nil fields are applied.
+type UpdatePresetRequest struct {
+	ISOName      *string `json:"iso_name,omitempty"`
+	Arch         *string `json:"arch,omitempty"`
+	Edition      *string `json:"edition,omitempty"`
+	DownloadURL  *string `json:"download_url,omitempty"`
+	ChecksumURL  *string `json:"checksum_url,omitempty"`
+	ChecksumType *string `json:"checksum_type,omitempty"`
+}
+
+// ApplyPresetRequest is the request body for creating an ISO from a preset.
+// Empty fields use the preset's defaults.
+type ApplyPresetRequest struct {
+	Version    string `json:"version"`
+	Arch       string `json:"arch,omitempty"`
+	Edition    string `json:"edition,omitempty"`
+	IPFamily   string `json:"ip_family,omitempty"`
+	Credential string `json:"credential,omitempty"`
+}
+
+// Actions on an ISO, as listed by ListActions.
+const (
+	ActionRetry  = "retry"
+	ActionCancel = "cancel"
+	ActionVerify = "verify"
+	ActionShare  = "share"
+	ActionDelete = "delete"
+)
+
+// ActionRoute is the request that performs an action. {id} and {file_path}
+// stand for the ISO's fields.
+type ActionRoute struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Body   string `json:"body,omitempty"` // JSON body template, if the request needs one
+}
+
+// ISOActions lists the actions open to the caller on one ISO.
+type ISOActions struct {
+	ID      string    `json:"id"`
+	Status  ISOStatus `json:"status"`
+	Actions []string  `json:"actions"`
+}
+
+// ActionSet lists the actions the caller can take on each ISO, given its
+// status, the caller's role, and whether the server is a read-only replica.
+type ActionSet struct {
+	Role     string                 `json:"role"`      // "operator", or "guest" without a session
+	ReadOnly bool                   `json:"read_only"` // A replica, which refuses catalog changes
+	Routes   map[string]ActionRoute `json:"routes"`    // By action, for every action listed
+	ISOs     []ISOActions           `json:"isos"`
+}
+
+// PresetImportResult lists the presets of an imported pack by outcome.
+type PresetImportResult struct {
+	Created  []string `json:"created"`
+	Replaced []string `json:"replaced"`
+	Skipped  []string `json:"skipped"`
+}
+
+// ListingBranding customizes the server's /images directory listing. Empty
+// fields keep the built-in look.
+type ListingBranding struct {
+	UpdatedAt       *time.Time `json:"updated_at,omitempty"` // Nil until first set
+	Title           string     `json:"title"`
+	LogoURL         string     `json:"logo_url"`
+	FooterText      string     `json:"footer_text"`
+	PrimaryColor    string     `json:"primary_color"`    // #rgb or #rrggbb
+	BackgroundColor string     `json:"background_color"` // #rgb or #rrggbb
+}
+
+// UpdateListingBrandingRequest is the request body for changing the listing
+// branding. Only non-nil fields are applied; an empty string restores a
+// field's default.
+type UpdateListingBrandingRequest struct {
+	Title           *string `json:"title,omitempty"`
+	LogoURL         *string `json:"logo_url,omitempty"`
+	FooterText      *string `json:"footer_text,omitempty"`
+	PrimaryColor    *string `json:"primary_color,omitempty"`
+	BackgroundColor *string `json:"background_color,omitempty"`
+}
+
+// Columns of the /images directory listing.
+const (
+	ListingColumnStatus    = "status"
+	ListingColumnChecksum  = "checksum"
+	ListingColumnDownloads = "downloads"
+	ListingColumnSize      = "size"
+	ListingColumnModified  = "modified"
+)
+
+// CatalogEntry is what a catalog snapshot keeps of one complete ISO.
+type CatalogEntry struct {
+	CompletedAt *time.Time `json:"completed_at"`
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Version     string     `json:"version"`
+	Arch        string     `json:"arch"`
+	Edition     string     `json:"edition"`
+	FileType    string     `json:"file_type"`
+	FilePath    string     `json:"file_path"`
+	SHA256      string     `json:"sha256"`
+	SizeBytes   int64      `json:"size_bytes"`
+}
+
+// CatalogChanges lists how the complete ISOs changed between the catalog
+// snapshot taken at or before Since and now.
+type CatalogChanges struct {
+	Since   time.Time       `json:"since"`
+	From    time.Time       `json:"from"` // When the snapshot compared against was taken; after Since when none is older
+	To      time.Time       `json:"to"`
+	Added   []CatalogEntry  `json:"added"`
+	Removed []CatalogEntry  `json:"removed"`
+	Updated []CatalogUpdate `json:"updated"`
+}
+
+// CatalogUpdate is an ISO whose file changed since the snapshot.
+type CatalogUpdate struct {
+	Fields []string     `json:"fields"` // JSON names of the entry fields that differ
+	Before CatalogEntry `json:"before"`
+	After  CatalogEntry `json:"after"`
+}
+
+// Webhook turns inbound release announcements into ISO downloads.
+type Webhook struct {
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Template  WebhookTemplate `json:"template"`
+	Name      string          `json:"name"`
+	Filter    string          `json:"filter"`
+	// Secret signs deliveries. It is only returned on creation or rotation.
+	Secret string `json:"secret,omitempty"`
+}
+
+// WebhookTemplate holds one Go text/template per ISO field, rendered
+// against the JSON payload of each delivery.
+type WebhookTemplate struct {
+	Name         string `json:"name"`
+	Version      string `json:"version"`
+	Arch         string `json:"arch"`
+	Edition      string `json:"edition,omitempty"`
+	DownloadURL  string `json:"download_url"`
+	ChecksumURL  string `json:"checksum_url,omitempty"`
+	ChecksumType string `json:"checksum_type,omitempty"`
+	IPFamily     string `json:"ip_family,omitempty"`
+	Credential   string `json:"credential,omitempty"`
+}
+
+// CreateWebhookRequest is the request body for creating a webhook.
+type CreateWebhookRequest struct {
+	Name string `json:"name"`
+	// Filter optionally must render "true" for a delivery to queue an ISO.
+	Filter   string          `json:"filter,omitempty"`
+	Template WebhookTemplate `json:"template"`
+}
+
+// UpdateWebhookRequest is the request body for updating a webhook.
+// All fields are optional — only non-nil fields are applied.
+type UpdateWebhookRequest struct {
+	Template     *WebhookTemplate `json:"template,omitempty"`
+	Filter       *string          `json:"filter,omitempty"`
+	RotateSecret bool             `json:"rotate_secret,omitempty"`
+}
+
+// User is a local account of the ISOMan server.
+type User struct {
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	ID          string     `json:"id"`
+	Username    string     `json:"username"`
+	TOTPEnabled bool       `json:"totp_enabled"`
+}
+
+// TOTPEnrollment is returned by BeginTOTP. Show URL as a QR code or the
+// secret for manual entry.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URL    string `json:"url"`
+}
+
+// Session is an active sign-in. ID is a hash of the session token, not the
+// token itself.
+type Session struct {
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Current   bool      `json:"current"`
+}
+
+// DownloadLink opens one file under /images without a session until it
+// expires. URL is only set by CreateDownloadLink and holds the token.
+type DownloadLink struct {
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	ID        string     `json:"id"`
+	Path      string     `json:"path"`
+	CreatedBy string     `json:"created_by"`
+	URL       string     `json:"url,omitempty"`
+	SingleUse bool       `json:"single_use"`
+}
+
+// CreateDownloadLinkRequest is the request body for CreateDownloadLink.
+type CreateDownloadLinkRequest struct {
+	// Path is the file relative to the ISO directory
+	Path string `json:"path"`
+	// ExpiresInHours defaults to 24 when zero
+	ExpiresInHours int `json:"expires_in_hours,omitempty"`
+	// SingleUse burns the link after the first complete download
+	SingleUse bool `json:"single_use,omitempty"`
+}
+
+// LoginResponse is returned by Login.
+type LoginResponse struct {
+	User      *User     `json:"user"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Token     string    `json:"token"`
+	CSRFToken string    `json:"csrf_token"` // Only needed with the session cookie; the client sends Token instead
+}
+
+// AuditEvent records an administrative change such as a download count adjustment.
+type AuditEvent struct {
+	CreatedAt time.Time `json:"created_at"`
+	Action    string    `json:"action"`
+	TargetID  string    `json:"target_id"`
+	Details   string    `json:"details"`
+	Reason    string    `json:"reason"`
+	ClientIP  string    `json:"client_ip"`
+	ID        int64     `json:"id"`
+}
+
+// DownloadEvent is a single download of an ISO. The client fields hold only
+// what the server's analytics settings keep, and may be empty.
+type DownloadEvent struct {
+	DownloadedAt time.Time `json:"downloaded_at"`
+	ISOID        string    `json:"iso_id"`
+	Country      string    `json:"country"`
+	Site         string    `json:"site"`
+	ClientIP     string    `json:"client_ip"`
+	UserAgent    string    `json:"user_agent"`
+	ID           int64     `json:"id"`
+}
+
+// SystemEvent records a change in the server's health, such as storage running low.
+type SystemEvent struct {
+	CreatedAt time.Time `json:"created_at"`
+	Type      string    `json:"type"`     // e.g. storage.low, database.recovered
+	Severity  string    `json:"severity"` // info, warning, error
+	Message   string    `json:"message"`
+	ID        int64     `json:"id"`
+}
+
+// GCReport records one automated deletion pass: the files it removed, how
+// much space they took, and why.
+type GCReport struct {
+	CreatedAt      time.Time `json:"created_at"`
+	Reason         string    `json:"reason"`           // temp_expired, version_retention
+	ISOID          string    `json:"iso_id,omitempty"` // ISO whose files were removed, when they belong to one
+	Files          []GCFile  `json:"files"`
+	ID             int64     `json:"id"`
+	FileCount      int       `json:"file_count"`
+	BytesReclaimed int64     `json:"bytes_reclaimed"`
+}
+
+// GCFile is a file removed by garbage collection.
+type GCFile struct {
+	Path      string `json:"path"` // Absolute path on the server
+	SizeBytes int64  `json:"size_bytes"`
+}
+
+// LogSettings is a server's log level and format.
+type LogSettings struct {
+	Level  string `json:"level"`  // debug, info, warn, error
+	Format string `json:"format"` // text, json
+}
+
+// InstanceStatus is the public status summary returned by GET /status.
+type InstanceStatus struct {
+	LastSyncAt   *time.Time `json:"last_sync_at"` // When the most recent download completed
+	Status       string     `json:"status"`       // ok, degraded
+	Problems     []string   `json:"problems"`
+	CompleteISOs int64      `json:"complete_isos"`
+}
+
+// SchemaInfo compares an instance's database schema with the migrations its
+// build ships.
+type SchemaInfo struct {
+	Version    uint `json:"version"`
+	Latest     uint `json:"latest"`
+	Dirty      bool `json:"dirty"`      // A migration failed partway
+	Pending    uint `json:"pending"`    // Migrations the build has that the database lacks
+	Compatible bool `json:"compatible"` // False while pending, dirty, or migrated by a newer build
+}
+
+// VersionInfo is returned by GET /api/version.
+type VersionInfo struct {
+	Version string     `json:"version"`
+	Schema  SchemaInfo `json:"schema"`
+}
+
+// Discovery is the document served at /.well-known/isoman.json describing
+// where an instance's API, catalog, and feeds live and how to authenticate.
+type Discovery struct {
+	Name       string         `json:"name"`
+	Version    string         `json:"version"`
+	APIBase    string         `json:"api_base"`
+	WebSocket  string         `json:"websocket"`
+	Auth       DiscoveryAuth  `json:"auth"`
+	Links      DiscoveryLinks `json:"links"`
+	ReadOnly   bool           `json:"read_only"`
+	PrimaryURL string         `json:"primary_url,omitempty"` // Set on read-only replicas
+}
+
+// DiscoveryAuth describes how clients authenticate against an instance.
+type DiscoveryAuth struct {
+	Enabled      bool     `json:"enabled"`
+	Modes        []string `json:"modes"` // session, bearer, download_token
+	LoginURL     string   `json:"login_url,omitempty"`
+	PublicScopes []string `json:"public_scopes"`
+}
+
+// DiscoveryLinks are the absolute URLs of an instance's catalog and feeds.
+type DiscoveryLinks struct {
+	Images       string `json:"images"`
+	Feed         string `json:"feed"`
+	Status       string `json:"status"`
+	Health       string `json:"health"`
+	RepoMetadata string `json:"repo_metadata,omitempty"`
+}
+
+// SystemEventsOptions filters ListSystemEvents. Zero values use the server defaults.
+type SystemEventsOptions struct {
+	Since    time.Time
+	Severity string
+	Limit    int
+}
+
+// GCReportsOptions filters ListGCReports. Zero values use the server defaults.
+type GCReportsOptions struct {
+	Since  time.Time
+	Reason string
+	ISOID  string
+	Limit  int
+}
+
 // Pagination contains pagination metadata from list responses.
+// Page, Total, and TotalPages are zero on pages fetched with a cursor.
 type Pagination struct {
-	Page       int `json:"page"`
-	PageSize   int `json:"page_size"`
-	Total      int `json:"total"`
-	TotalPages int `json:"total_pages"`
+	NextCursor string `json:"next_cursor"` // Pass as ListISOsOptions.Cursor for the next page; empty on the last
+	Page       int    `json:"page"`
+	PageSize   int    `json:"page_size"`
+	Total      int    `json:"total"`
+	TotalPages int    `json:"total_pages"`
 }
 
 // ListISOsResponse is the unwrapped response from ListISOs,
@@ -126,10 +1026,29 @@ type ListISOsOptions struct {
 	Page int
 	// PageSize is the number of results per page. Default: 10.
 	PageSize int
-	// SortBy is the field to sort by (e.g. "created_at", "name"). Default: "created_at".
+	// SortBy is the key to sort by: "created_at", "name", "version", "status",
+	// "size", "download_count", or "last_downloaded_at". Default: "created_at".
 	SortBy string
 	// SortDir is the sort direction: "asc" or "desc". Default: "desc".
 	SortDir string
+	// Cursor continues from the NextCursor of a page sorted by created_at,
+	// in place of Page; deep pages cost the same as the first. Sorting
+	// options are ignored, since the cursor carries them.
+	Cursor string
+	// Fields trims each ISO to these JSON fields (e.g. "id", "status");
+	// the rest are left zero. Default: all fields.
+	Fields []string
+}
+
+// StatsOptions configures the GetStatsWithOptions request.
+// Totals always cover every ISO; the options only shape TopDownloaded and Groups.
+type StatsOptions struct {
+	// Top is the number of most downloaded ISOs to return (1-100). Default: 10.
+	Top int
+	// GroupBy is "name", "arch", "edition", or "file_type". Default: no breakdown.
+	GroupBy string
+	// Status limits the top list and groups to one status. Default: complete for the top list, all for groups.
+	Status string
 }
 
 // DownloadTrendsOptions configures the GetDownloadTrends request.
@@ -19,6 +19,7 @@ import {
   SelectTrigger,
   SelectValue,
 } from '@/components/ui/select';
+import { isRetryableStatus } from '@/lib/status-config';
 import type { ISO, UpdateISORequest } from '../types/iso';
 
 // Schema for updating ISOs - all fields are optional
@@ -55,7 +56,7 @@ export function EditIsoModal({
   onOpenChange,
   onSubmit,
 }: EditIsoModalProps) {
-  const canEditURLs = iso.status === 'failed';
+  const canEditURLs = isRetryableStatus(iso.status);
 
   const {
     register,
@@ -132,7 +133,7 @@ export function EditIsoModal({
           </DialogTitle>
           <DialogDescription>
             {canEditURLs
-              ? 'All fields can be modified for failed downloads. Saving will retry the download.'
+              ? 'All fields can be modified for failed or canceled downloads. Saving will retry the download.'
               : 'Only metadata can be changed for completed downloads.'}
           </DialogDescription>
         </DialogHeader>
@@ -19,9 +19,11 @@ import {
 import { useCopyWithFeedback } from '@/hooks/useCopyWithFeedback';
 import { formatBytes, formatDate } from '@/lib/format';
 import { getFullChecksumUrl, getFullDownloadUrl } from '@/lib/iso-utils';
+import { isRetryableStatus } from '@/lib/status-config';
 import type { ISO } from '../types/iso';
 import { ProgressBar } from './ProgressBar';
 import { StatusBadge } from './StatusBadge';
+import { VerificationBadge } from './VerificationBadge';
 
 interface IsoCardProps {
   iso: ISO;
@@ -44,6 +46,7 @@ export function IsoCard({ iso, onDelete, onRetry, onEdit }: IsoCardProps) {
               {iso.name}
             </h3>
             <StatusBadge status={iso.status} />
+            <VerificationBadge verification={iso.verification} />
           </div>
           <div className="flex gap-2 text-sm text-muted-foreground font-mono">
             <span>{iso.version}</span>
@@ -99,7 +102,7 @@ export function IsoCard({ iso, onDelete, onRetry, onEdit }: IsoCardProps) {
         </DropdownMenu>
       </CardHeader>
       <CardContent>
-        {iso.status !== 'complete' && iso.status !== 'failed' && (
+        {iso.status !== 'complete' && !isRetryableStatus(iso.status) && (
           <div className="mb-4">
             <ProgressBar progress={iso.progress} status={iso.status} />
           </div>
@@ -167,7 +170,7 @@ export function IsoCard({ iso, onDelete, onRetry, onEdit }: IsoCardProps) {
                 </a>
               </Button>
             )}
-            {iso.status === 'failed' && (
+            {isRetryableStatus(iso.status) && (
               <>
                 <Button onClick={() => onRetry(iso.id)} className="flex-1">
                   <RefreshCw />
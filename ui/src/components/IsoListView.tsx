@@ -33,9 +33,10 @@ import { Skeleton } from '@/components/ui/skeleton';
 import { useCopyWithFeedback } from '@/hooks/useCopyWithFeedback';
 import { formatBytes, formatDateShort } from '@/lib/format';
 import { getFullChecksumUrl, getFullDownloadUrl } from '@/lib/iso-utils';
-import { getStatusColor } from '@/lib/status-config';
+import { getStatusColor, isRetryableStatus } from '@/lib/status-config';
 import type { ISO, PaginationInfo } from '../types/iso';
 import { StatusBadge } from './StatusBadge';
+import { VerificationBadge } from './VerificationBadge';
 
 interface IsoListViewProps {
   isos: ISO[];
@@ -115,7 +116,12 @@ export function IsoListView({
         header: ({ column }) => (
           <DataGridColumnHeader column={column} title="Status" />
         ),
-        cell: ({ row }) => <StatusBadge status={row.original.status} />,
+        cell: ({ row }) => (
+          <div className="flex items-center gap-1.5">
+            <StatusBadge status={row.original.status} />
+            <VerificationBadge verification={row.original.verification} />
+          </div>
+        ),
         meta: {
           skeleton: <Skeleton className="h-5 w-20 rounded-full" />,
         },
@@ -165,7 +171,8 @@ export function IsoListView({
         enableSorting: false,
         cell: ({ row }) => {
           const iso = row.original;
-          if (iso.status === 'complete' || iso.status === 'failed') return null;
+          if (iso.status === 'complete' || isRetryableStatus(iso.status))
+            return null;
           return (
             <div className="flex items-center gap-2">
               <div className="w-24 h-1.5 bg-secondary rounded-full overflow-hidden">
@@ -230,7 +237,7 @@ export function IsoListView({
                   </a>
                 </Button>
               )}
-              {iso.status === 'failed' && (
+              {isRetryableStatus(iso.status) && (
                 <Button
                   onClick={() => onRetry(iso.id)}
                   variant="ghost"
@@ -0,0 +1,32 @@
+import { Badge } from '@/components/ui/badge';
+import { VERIFICATION_CONFIG } from '@/lib/status-config';
+import type { VerificationState } from '../types/iso';
+
+interface VerificationBadgeProps {
+  verification: VerificationState;
+  className?: string;
+}
+
+/**
+ * Badge showing whether an ISO's file has been verified by its checksum or
+ * signature, as reported by the backend
+ */
+export function VerificationBadge({
+  verification,
+  className = '',
+}: VerificationBadgeProps) {
+  const config = VERIFICATION_CONFIG[verification];
+  if (!config) {
+    return null;
+  }
+
+  return (
+    <Badge
+      variant={config.badgeVariant}
+      appearance={config.badgeAppearance}
+      className={className}
+    >
+      {config.label}
+    </Badge>
+  );
+}
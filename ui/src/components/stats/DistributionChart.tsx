@@ -33,6 +33,9 @@ const STATUS_COLORS: Record<string, string> = {
   downloading: 'var(--chart-1)',
   in_progress: 'var(--chart-1)',
   verifying: 'var(--chart-5)',
+  canceled: 'var(--chart-4)',
+  verified: 'var(--chart-2)',
+  unverified: 'var(--chart-3)',
 };
 
 export function DistributionChart({
@@ -1,6 +1,6 @@
 import { useCallback, useEffect, useRef } from 'react';
 import { useAppStore } from '@/stores';
-import type { WSProgressMessage } from '../types/iso';
+import type { WSMessage } from '../types/iso';
 
 /**
  * WebSocket URL - defaults to same origin in production
@@ -21,7 +21,7 @@ const getWebSocketURL = () => {
 };
 
 interface UseWebSocketOptions {
-  onMessage?: (message: WSProgressMessage) => void;
+  onMessage?: (message: WSMessage) => void;
   reconnectInterval?: number;
   maxReconnectAttempts?: number;
 }
@@ -65,7 +65,7 @@ export function useWebSocket(options: UseWebSocketOptions = {}) {
 
       ws.onmessage = (event) => {
         try {
-          const message: WSProgressMessage = JSON.parse(event.data);
+          const message: WSMessage = JSON.parse(event.data);
           onMessageRef.current?.(message);
         } catch (error) {
           console.error('[WebSocket] Failed to parse message:', error);
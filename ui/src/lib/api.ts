@@ -14,6 +14,14 @@ import type { DownloadTrend, Stats } from '../types/stats';
  */
 const API_BASE_URL = import.meta.env.PUBLIC_API_URL || '';
 
+/**
+ * CSRF token set by the server at sign-in; echoed on mutating requests
+ */
+function csrfToken(): string | undefined {
+  const match = document.cookie.match(/(?:^|;\s*)isoman_csrf=([^;]*)/);
+  return match ? decodeURIComponent(match[1]) : undefined;
+}
+
 /**
  * Generic fetch wrapper with error handling and JSON parsing
  */
@@ -21,11 +29,13 @@ async function apiFetch<T>(
   endpoint: string,
   options?: RequestInit,
 ): Promise<APIResponse<T>> {
+  const csrf = csrfToken();
   try {
     const response = await fetch(`${API_BASE_URL}${endpoint}`, {
       ...options,
       headers: {
         'Content-Type': 'application/json',
+        ...(csrf ? { 'X-CSRF-Token': csrf } : {}),
         ...options?.headers,
       },
     });
@@ -123,6 +133,38 @@ export async function retryISO(id: string): Promise<ISO> {
   return response.data;
 }
 
+/**
+ * Create a new ISO download from an existing one; set fields override the
+ * source's, and a new version is substituted into unchanged URLs
+ */
+export async function cloneISO(
+  id: string,
+  request: UpdateISORequest,
+): Promise<ISO> {
+  const response = await apiFetch<ISO>(`/api/isos/${id}/clone`, {
+    method: 'POST',
+    body: JSON.stringify(request),
+  });
+  if (!response.data) {
+    throw new Error('Failed to clone ISO');
+  }
+  return response.data;
+}
+
+/**
+ * Pin or unpin an ISO; pinned ISOs are listed first and keep all previous
+ * files on refresh
+ */
+export async function setISOPinned(id: string, pinned: boolean): Promise<ISO> {
+  const response = await apiFetch<ISO>(`/api/isos/${id}/pin`, {
+    method: pinned ? 'PUT' : 'DELETE',
+  });
+  if (!response.data) {
+    throw new Error('Failed to update ISO pin');
+  }
+  return response.data;
+}
+
 /**
  * Update an existing ISO
  */
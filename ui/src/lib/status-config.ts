@@ -1,5 +1,5 @@
 import type { BadgeProps } from '@/components/ui/badge';
-import type { ISOStatus } from '../types/iso';
+import type { ISOStatus, VerificationState } from '../types/iso';
 
 export interface StatusConfig {
   label: string;
@@ -43,8 +43,59 @@ export const STATUS_CONFIG: Record<ISOStatus, StatusConfig> = {
     badgeAppearance: 'light',
     progressColor: 'bg-zinc-400',
   },
+  queued: {
+    label: 'Queued',
+    badgeVariant: 'secondary',
+    badgeAppearance: 'light',
+    progressColor: 'bg-zinc-400',
+  },
+  canceled: {
+    label: 'Canceled',
+    badgeVariant: 'secondary',
+    badgeAppearance: 'light',
+    progressColor: 'bg-zinc-400',
+  },
+  quarantined: {
+    label: 'Quarantined',
+    badgeVariant: 'destructive',
+    badgeAppearance: 'light',
+    progressColor: 'bg-red-500',
+  },
 };
 
+/**
+ * Badge configuration for the verification state of an ISO's file
+ */
+export const VERIFICATION_CONFIG: Record<
+  VerificationState,
+  Omit<StatusConfig, 'progressColor'>
+> = {
+  verified: {
+    label: 'Verified',
+    badgeVariant: 'success',
+    badgeAppearance: 'light',
+  },
+  unverified: {
+    label: 'Unverified',
+    badgeVariant: 'secondary',
+    badgeAppearance: 'light',
+  },
+  failed: {
+    label: 'Verification failed',
+    badgeVariant: 'destructive',
+    badgeAppearance: 'light',
+  },
+};
+
+/**
+ * Checks whether a download in the given status can be retried
+ * @param status - ISO status
+ * @returns True for failed or canceled downloads
+ */
+export function isRetryableStatus(status: ISOStatus): boolean {
+  return status === 'failed' || status === 'canceled';
+}
+
 /**
  * Gets the progress bar color class for a status
  * @param status - ISO status
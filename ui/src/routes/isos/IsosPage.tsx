@@ -23,7 +23,7 @@ import type {
   ISO,
   PaginationInfo,
   UpdateISORequest,
-  WSProgressMessage,
+  WSMessage,
 } from '@/types/iso';
 
 export function IsosPage() {
@@ -68,7 +68,7 @@ export function IsosPage() {
 
   // Handle WebSocket progress updates
   const handleWebSocketMessage = useCallback(
-    (message: WSProgressMessage) => {
+    (message: WSMessage) => {
       if (message.type === 'progress') {
         // Update the ISO in the current page's data
         queryClient.setQueryData(
@@ -88,10 +88,11 @@ export function IsosPage() {
                 : iso,
             );
 
-            // If status is complete or failed, refetch to get updated fields
+            // If status is terminal, refetch to get updated fields
             if (
               message.payload.status === 'complete' ||
-              message.payload.status === 'failed'
+              message.payload.status === 'failed' ||
+              message.payload.status === 'canceled'
             ) {
               queryClient.invalidateQueries({ queryKey: ['isos'] });
             }
@@ -1,10 +1,13 @@
 import { useQuery } from '@tanstack/react-query';
 import {
   BarChart3,
+  Building2,
   Download,
+  Globe,
   HardDrive,
   Loader2,
   Package,
+  ShieldCheck,
   TrendingUp,
 } from 'lucide-react';
 import { DistributionChart } from '@/components/stats/DistributionChart';
@@ -83,7 +86,11 @@ export function StatsPage() {
                 aria-hidden="true"
               />
             }
-            description="Used by completed ISOs"
+            description={
+              stats.unverified_size_bytes > 0
+                ? `Used by completed ISOs, ${formatBytes(stats.unverified_size_bytes)} unverified`
+                : 'Used by completed ISOs'
+            }
           />
         </div>
         <div className="animate-fade-in-up" style={{ animationDelay: '150ms' }}>
@@ -115,7 +122,7 @@ export function StatsPage() {
       </div>
 
       {/* Charts Row */}
-      <div className="grid gap-4 md:grid-cols-2">
+      <div className="grid gap-4 md:grid-cols-2 xl:grid-cols-3">
         <Card>
           <CardHeader>
             <CardTitle className="flex items-center gap-2">
@@ -143,8 +150,57 @@ export function StatsPage() {
             />
           </CardContent>
         </Card>
+
+        <Card>
+          <CardHeader>
+            <CardTitle className="flex items-center gap-2">
+              <ShieldCheck className="h-5 w-5 text-green-500" />
+              ISOs by Verification
+            </CardTitle>
+          </CardHeader>
+          <CardContent>
+            <DistributionChart
+              data={stats.isos_by_verification}
+              title="Verification"
+              colorByKey
+            />
+          </CardContent>
+        </Card>
       </div>
 
+      {/* Download locations, when GEOIP_DB or GEOIP_SITES is set */}
+      {(Object.keys(stats.downloads_by_country).length > 0 ||
+        Object.keys(stats.downloads_by_site).length > 0) && (
+        <div className="grid gap-4 md:grid-cols-2">
+          <Card>
+            <CardHeader>
+              <CardTitle className="flex items-center gap-2">
+                <Globe className="h-5 w-5 text-sky-500" />
+                Downloads by Country
+              </CardTitle>
+            </CardHeader>
+            <CardContent>
+              <DistributionChart
+                data={stats.downloads_by_country}
+                title="Country"
+              />
+            </CardContent>
+          </Card>
+
+          <Card>
+            <CardHeader>
+              <CardTitle className="flex items-center gap-2">
+                <Building2 className="h-5 w-5 text-amber-500" />
+                Downloads by Site
+              </CardTitle>
+            </CardHeader>
+            <CardContent>
+              <DistributionChart data={stats.downloads_by_site} title="Site" />
+            </CardContent>
+          </Card>
+        </div>
+      )}
+
       {/* Trends Chart */}
       <Card>
         <CardHeader>
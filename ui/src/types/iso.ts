@@ -1,3 +1,5 @@
+import type { LiveThroughput, QueueForecast } from './stats';
+
 /**
  * ISO model matching backend structure
  */
@@ -16,12 +18,37 @@ export interface ISO {
   checksum_type: string;
   download_url: string;
   checksum_url: string;
+  signature_url: string;
+  signature_signer: string;
+  sha256: string;
+  sha512: string;
+  md5: string;
+  integrity_hash: string;
+  ip_family: IPFamily | '';
+  credential: string;
+  preset: string;
   status: ISOStatus;
   progress: number;
   error_message: string;
+  error_reason: ErrorReason;
   created_at: string;
   completed_at: string | null;
   download_count: number;
+  bytes_served: number;
+  upstream_etag: string;
+  upstream_last_modified: string;
+  upstream_changed: boolean;
+  source_url: string;
+  source_headers: Record<string, string> | null;
+  download_started_at: string | null;
+  upstream_checked_at: string | null;
+  verified_at: string | null;
+  file_inode: number;
+  file_mtime: string | null;
+  pinned: boolean;
+  redownload: boolean;
+  checksum_pending: boolean;
+  verification: VerificationState;
 }
 
 /**
@@ -29,10 +56,34 @@ export interface ISO {
  */
 export type ISOStatus =
   | 'pending'
+  | 'queued'
   | 'downloading'
   | 'verifying'
   | 'complete'
-  | 'failed';
+  | 'failed'
+  | 'canceled'
+  | 'quarantined';
+
+/**
+ * IP family preference for upstream fetches
+ */
+export type IPFamily = 'any' | 'ipv4' | 'ipv6';
+
+/**
+ * Classified failure reason matching backend
+ */
+export type ErrorReason =
+  | ''
+  | 'stalled'
+  | 'timeout'
+  | 'panic'
+  | 'interrupted'
+  | 'verification';
+
+/**
+ * Whether an ISO's file can be trusted, derived by the backend
+ */
+export type VerificationState = 'verified' | 'unverified' | 'failed';
 
 /**
  * Request payload for creating a new ISO download
@@ -44,7 +95,11 @@ export interface CreateISORequest {
   edition?: string;
   download_url: string;
   checksum_url?: string;
+  expected_checksum?: string;
+  signature_url?: string;
   checksum_type?: 'sha256' | 'sha512' | 'md5';
+  ip_family?: IPFamily;
+  credential?: string;
 }
 
 /**
@@ -58,7 +113,171 @@ export interface UpdateISORequest {
   edition?: string;
   download_url?: string;
   checksum_url?: string;
+  expected_checksum?: string;
+  signature_url?: string;
   checksum_type?: 'sha256' | 'sha512' | 'md5';
+  ip_family?: IPFamily;
+  credential?: string;
+}
+
+export type CredentialType = 'basic' | 'token' | 's3';
+
+/**
+ * Stored upstream credential; secret material is never returned
+ */
+export interface Credential {
+  name: string;
+  type: CredentialType;
+  host: string;
+  created_at: string;
+  updated_at: string;
+}
+
+/**
+ * Secret material of a credential; required fields depend on the type
+ */
+export interface CredentialSecret {
+  username?: string;
+  password?: string;
+  token?: string;
+  access_key_id?: string;
+  secret_access_key?: string;
+  session_token?: string;
+  region?: string;
+}
+
+/**
+ * Request payload for storing a credential
+ */
+export interface CreateCredentialRequest {
+  name: string;
+  type: CredentialType;
+  host?: string;
+  secret: CredentialSecret;
+}
+
+/**
+ * Named recipe for creating ISOs; URLs may contain {version}, {major}, {arch}, {edition}
+ */
+export interface Preset {
+  name: string;
+  iso_name: string;
+  arch: string;
+  edition: string;
+  download_url: string;
+  checksum_url: string;
+  checksum_type: string;
+  created_at: string;
+  updated_at: string;
+}
+
+/**
+ * Result of queueing a new version of several ISOs
+ */
+export interface VersionBumpResult {
+  queued: ISO[];
+  failed: { id: string; error: string }[];
+}
+
+/**
+ * Request payload for creating an ISO from a preset; empty fields use its defaults
+ */
+export interface ApplyPresetRequest {
+  version: string;
+  arch?: string;
+  edition?: string;
+}
+
+/**
+ * Go text/template per ISO field, rendered against each webhook delivery
+ */
+export interface WebhookTemplate {
+  name: string;
+  version: string;
+  arch: string;
+  edition?: string;
+  download_url: string;
+  checksum_url?: string;
+  checksum_type?: string;
+  ip_family?: string;
+  credential?: string;
+}
+
+/**
+ * Inbound webhook; the signing secret is only returned on creation or rotation
+ */
+export interface Webhook {
+  name: string;
+  filter: string;
+  template: WebhookTemplate;
+  secret?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+/**
+ * Local user account
+ */
+export interface User {
+  id: string;
+  username: string;
+  created_at: string;
+  updated_at: string;
+  last_login_at: string | null;
+  totp_enabled: boolean;
+}
+
+/**
+ * Response of POST /api/auth/totp; url is an otpauth:// URI for a QR code
+ */
+export interface TOTPEnrollment {
+  secret: string;
+  url: string;
+}
+
+/**
+ * Response of POST /api/auth/login; the token is also set as an HttpOnly cookie
+ */
+export interface LoginResponse {
+  user: User;
+  token: string;
+  csrf_token: string;
+  expires_at: string;
+}
+
+/**
+ * Link to one /images file from /api/download-links; url is only set on creation
+ */
+export interface DownloadLink {
+  id: string;
+  path: string;
+  single_use: boolean;
+  created_by: string;
+  created_at: string;
+  expires_at: string;
+  used_at: string | null;
+  url?: string;
+}
+
+/**
+ * Active session from GET /api/sessions; id is a hash, not the token
+ */
+export interface InstanceStatus {
+  status: 'ok' | 'degraded';
+  problems: string[];
+  complete_isos: number;
+  last_sync_at: string | null;
+}
+
+export interface Session {
+  id: string;
+  user_id: string;
+  username: string;
+  ip: string;
+  user_agent: string;
+  current: boolean;
+  created_at: string;
+  expires_at: string;
 }
 
 /**
@@ -80,6 +299,29 @@ export interface APIError {
   details?: string;
 }
 
+/**
+ * A rejected request field in a VALIDATION_FAILED problem
+ */
+export interface FieldError {
+  field: string;
+  message: string;
+}
+
+/**
+ * RFC 7807 problem body sent with every error response (application/problem+json).
+ * It also carries the `success: false` envelope and its `error` object.
+ */
+export interface Problem extends APIResponse {
+  type: string;
+  title: string;
+  status: number;
+  detail?: string;
+  instance?: string;
+  code: string;
+  request_id?: string;
+  errors?: FieldError[];
+}
+
 /**
  * WebSocket message format for progress updates
  */
@@ -92,6 +334,27 @@ export interface WSProgressMessage {
   };
 }
 
+/**
+ * WebSocket message format for live throughput samples
+ */
+export interface WSThroughputMessage {
+  type: 'throughput';
+  payload: LiveThroughput;
+}
+
+/**
+ * WebSocket message format for queue wait estimates
+ */
+export interface WSQueueMessage {
+  type: 'queue';
+  payload: QueueForecast;
+}
+
+/**
+ * Any message pushed over the WebSocket
+ */
+export type WSMessage = WSProgressMessage | WSThroughputMessage | WSQueueMessage;
+
 /**
  * Pagination info returned from API
  */
@@ -100,6 +363,8 @@ export interface PaginationInfo {
   page_size: number;
   total: number;
   total_pages: number;
+  /** Continues after this page via ?cursor=; empty on the last page */
+  next_cursor?: string;
 }
 
 /**
@@ -5,14 +5,43 @@ export interface Stats {
   total_isos: number;
   completed_isos: number;
   failed_isos: number;
+  canceled_isos: number;
   pending_isos: number;
+  verified_isos: number;
+  unverified_isos: number;
+  verification_failed_isos: number;
+  unverified_size_bytes: number;
   total_size_bytes: number;
   total_downloads: number;
   bandwidth_saved: number;
+  total_bytes_served: number;
   isos_by_arch: Record<string, number>;
   isos_by_edition: Record<string, number>;
   isos_by_status: Record<string, number>;
+  isos_by_verification: Record<string, number>;
   top_downloaded: ISODownloadStat[];
+  downloads_by_country: Record<string, number>;
+  downloads_by_site: Record<string, number>;
+  queue_depth: number;
+  queue_capacity: number;
+  group_by?: StatsGroupBy;
+  groups?: StatsGroup[];
+}
+
+/**
+ * Columns /api/stats can break totals down by
+ */
+export type StatsGroupBy = 'name' | 'arch' | 'edition' | 'file_type';
+
+/**
+ * Totals for the ISOs sharing one group_by value
+ */
+export interface StatsGroup {
+  key: string;
+  iso_count: number;
+  download_count: number;
+  size_bytes: number;
+  bytes_served: number;
 }
 
 /**
@@ -25,6 +54,7 @@ export interface ISODownloadStat {
   arch: string;
   download_count: number;
   size_bytes: number;
+  bytes_served: number;
 }
 
 /**
@@ -42,3 +72,80 @@ export interface TrendDataPoint {
   date: string;
   count: number;
 }
+
+/**
+ * Administrative change recorded in the audit log
+ */
+export interface AuditEvent {
+  id: number;
+  action: 'stats.reset' | 'stats.adjust';
+  target_id: string;
+  details: string;
+  reason: string;
+  client_ip: string;
+  created_at: string;
+}
+
+/**
+ * Recorded health state change (GET /api/system/events)
+ */
+export interface SystemEvent {
+  id: number;
+  type: string;
+  severity: 'info' | 'warning' | 'error';
+  message: string;
+  created_at: string;
+}
+
+/**
+ * Live aggregate transfer rates (GET /api/stats/live and WebSocket "throughput" messages)
+ */
+export interface LiveThroughput {
+  sampled_at: string;
+  ingest_bytes_per_sec: number;
+  egress_bytes_per_sec: number;
+  ingest_bytes_total: number;
+  egress_bytes_total: number;
+}
+
+/**
+ * Estimated start and completion of one queued download
+ */
+export interface QueueEstimate {
+  iso_id: string;
+  name: string;
+  version: string;
+  arch: string;
+  edition: string;
+  position: number;
+  size_bytes: number;
+  fast_lane: boolean;
+  estimated_start_at: string | null;
+  estimated_completion_at: string | null;
+}
+
+/**
+ * Queue wait estimates (GET /api/downloads/queue and WebSocket "queue" messages)
+ */
+export interface QueueForecast {
+  generated_at: string;
+  bytes_per_sec: number;
+  queue: QueueEstimate[];
+}
+
+/**
+ * Progress of copying the ISO directory to a new location (GET /api/storage/relocation)
+ */
+export interface Relocation {
+  started_at: string;
+  finished_at: string | null;
+  source: string;
+  target: string;
+  state: 'running' | 'complete' | 'failed';
+  error?: string;
+  files_total: number;
+  files_copied: number;
+  files_skipped: number;
+  bytes_total: number;
+  bytes_copied: number;
+}